@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// aliases are memorable names for container handles, kept in
+// ~/.gaol/aliases.yml by target address and then by name, since handles
+// cannot be renamed on the server.
+type aliases map[string]map[string]string
+
+func aliasesPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".gaol", "aliases.yml")
+}
+
+// loadAliases reads the aliases file. A missing file has no aliases.
+func loadAliases() (aliases, error) {
+	a := aliases{}
+
+	contents, err := ioutil.ReadFile(aliasesPath())
+	if os.IsNotExist(err) {
+		return a, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(contents, &a); err != nil {
+		return nil, fmt.Errorf("%s: %s", aliasesPath(), err)
+	}
+
+	return a, nil
+}
+
+func (a aliases) save() error {
+	contents, err := yaml.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(aliasesPath()), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(aliasesPath(), contents, 0600)
+}
+
+// resolveAlias returns the handle the name is an alias for on the target,
+// or the name itself if it is not an alias.
+func resolveAlias(address string, name string) (string, error) {
+	a, err := loadAliases()
+	if err != nil {
+		return "", err
+	}
+
+	if handle, found := a[address][name]; found {
+		return handle, nil
+	}
+
+	return name, nil
+}
+
+func addAlias(address string, name string, handle string) error {
+	a, err := loadAliases()
+	if err != nil {
+		return err
+	}
+
+	if a[address] == nil {
+		a[address] = map[string]string{}
+	}
+
+	a[address][name] = handle
+
+	return a.save()
+}
+
+func removeAlias(address string, name string) error {
+	a, err := loadAliases()
+	if err != nil {
+		return err
+	}
+
+	if _, found := a[address][name]; !found {
+		return fmt.Errorf("unknown alias %q", name)
+	}
+
+	delete(a[address], name)
+	if len(a[address]) == 0 {
+		delete(a, address)
+	}
+
+	return a.save()
+}
+
+// removeAliasesFor removes the aliases on the target for any of the handles
+// and returns their names, in order.
+func removeAliasesFor(address string, handles []string) ([]string, error) {
+	a, err := loadAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	destroyed := map[string]bool{}
+	for _, handle := range handles {
+		destroyed[handle] = true
+	}
+
+	removed := []string{}
+	for name, handle := range a[address] {
+		if destroyed[handle] {
+			removed = append(removed, name)
+			delete(a[address], name)
+		}
+	}
+
+	if len(removed) == 0 {
+		return removed, nil
+	}
+
+	if len(a[address]) == 0 {
+		delete(a, address)
+	}
+
+	sort.Strings(removed)
+	return removed, a.save()
+}
+
+// listAliases returns a line for each alias on the target, in order of name.
+func listAliases(address string) ([]string, error) {
+	a, err := loadAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for name := range a[address] {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	lines := []string{}
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("%s\t%s", name, a[address][name]))
+	}
+
+	return lines, nil
+}
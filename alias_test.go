@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestAliases(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	container := fakeContainer("0f3c9a1e")
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	res := runGaolIn(t, home, fakeClient, "alias", "add", "web", "0f3c9a1e")
+	if res.code != 0 {
+		t.Fatalf("exited %d: %s", res.code, res.stderr)
+	}
+
+	runGaolIn(t, home, fakeClient, "stop", "web")
+	if fakeClient.LookupArgsForCall(0) != "0f3c9a1e" {
+		t.Errorf("looked up %q, want the aliased handle", fakeClient.LookupArgsForCall(0))
+	}
+
+	// handles which are not aliases are left alone
+	runGaolIn(t, home, fakeClient, "stop", "db")
+	if fakeClient.LookupArgsForCall(1) != "db" {
+		t.Errorf("looked up %q, want db", fakeClient.LookupArgsForCall(1))
+	}
+
+	// aliases belong to the target they were made on
+	runGaolIn(t, home, fakeClient, "--target", "10.0.0.1:7777", "stop", "web")
+	if fakeClient.LookupArgsForCall(2) != "web" {
+		t.Errorf("looked up %q on another target, want web", fakeClient.LookupArgsForCall(2))
+	}
+
+	res = runGaolIn(t, home, fakeClient, "alias", "list")
+	if res.stdout != "web\t0f3c9a1e\n" {
+		t.Errorf("listed %q", res.stdout)
+	}
+
+	runGaolIn(t, home, fakeClient, "alias", "remove", "web")
+
+	res = runGaolIn(t, home, fakeClient, "alias", "list")
+	if res.stdout != "" {
+		t.Errorf("listed %q after removing the alias", res.stdout)
+	}
+
+	res = runGaolIn(t, home, fakeClient, "alias", "remove", "web")
+	if res.code == 0 {
+		t.Error("removed an unknown alias")
+	}
+}
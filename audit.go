@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	gconn "github.com/cloudfoundry-incubator/garden/client/connection"
+)
+
+// auditPath is where the audit log is kept unless --audit-file says
+// otherwise.
+func auditPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".gaol", "audit.log")
+}
+
+// auditingConnection appends a line to the audit log for every call which
+// creates, destroys, stops or limits a container, saying who made it, from
+// which command and how it turned out.
+type auditingConnection struct {
+	gconn.Connection
+
+	log   io.Writer
+	entry auditEntry
+	mu    sync.Mutex
+}
+
+// auditEntry is a single line of the audit log.
+type auditEntry struct {
+	Time      time.Time `json:"time"`
+	User      string    `json:"user"`
+	Target    string    `json:"target"`
+	Command   string    `json:"command"`
+	Call      string    `json:"call"`
+	Handle    string    `json:"handle"`
+	Result    string    `json:"result"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+func newAuditingConnection(conn gconn.Connection, log io.Writer, target string, stamp *requestStamp) gconn.Connection {
+	entry := auditEntry{
+		User:    creator(),
+		Target:  target,
+		Command: strings.Join(redactArgs(os.Args), " "),
+	}
+
+	if stamp != nil {
+		entry.RequestID = stamp.ID
+	}
+
+	return &auditingConnection{
+		Connection: conn,
+		log:        log,
+		entry:      entry,
+	}
+}
+
+// redacted replaces secrets in what is recorded of a command line.
+const redacted = "REDACTED"
+
+// secretFlags are the flags whose values, or the secret parts of them, are
+// left out of the audit log and history.
+var secretFlags = map[string]func(string) string{
+	"registry-password": func(string) string { return redacted },
+	"header":            redactHeader,
+	"H":                 redactHeader,
+}
+
+// redactHeader leaves out the value of a header carrying credentials.
+func redactHeader(header string) string {
+	i := strings.Index(header, ":")
+	if i < 0 {
+		return header
+	}
+
+	switch strings.ToLower(strings.TrimSpace(header[:i])) {
+	case "authorization", "proxy-authorization", "cookie":
+		return header[:i+1] + " " + redacted
+	}
+
+	return header
+}
+
+// redactArgs returns the command line with the values of secret flags,
+// given as --flag value or --flag=value, redacted.
+func redactArgs(args []string) []string {
+	result := []string{}
+
+	var redact func(string) string
+	for _, arg := range args {
+		if redact != nil {
+			result = append(result, redact(arg))
+			redact = nil
+			continue
+		}
+
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			result = append(result, arg)
+			continue
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		if i := strings.Index(name, "="); i >= 0 {
+			if r, secret := secretFlags[name[:i]]; secret {
+				arg = arg[:len(arg)-len(name)+i+1] + r(name[i+1:])
+			}
+		} else {
+			redact = secretFlags[name]
+		}
+
+		result = append(result, arg)
+	}
+
+	return result
+}
+
+func (a *auditingConnection) record(call string, handle string, err error) {
+	entry := a.entry
+	entry.Time = time.Now().UTC()
+	entry.Call = call
+	entry.Handle = handle
+	entry.Result = "ok"
+
+	if err != nil {
+		entry.Result = "error: " + strings.TrimSpace(err.Error())
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	json.NewEncoder(a.log).Encode(entry)
+}
+
+func (a *auditingConnection) Create(spec garden.ContainerSpec) (string, error) {
+	handle, err := a.Connection.Create(spec)
+	if err != nil {
+		handle = spec.Handle
+	}
+
+	a.record("Create", handle, err)
+	return handle, err
+}
+
+func (a *auditingConnection) Destroy(handle string) error {
+	err := a.Connection.Destroy(handle)
+	a.record("Destroy", handle, err)
+	return err
+}
+
+func (a *auditingConnection) Stop(handle string, kill bool) error {
+	err := a.Connection.Stop(handle, kill)
+
+	call := "Stop"
+	if kill {
+		call = "Kill"
+	}
+
+	a.record(call, handle, err)
+	return err
+}
+
+func (a *auditingConnection) LimitBandwidth(handle string, limits garden.BandwidthLimits) (garden.BandwidthLimits, error) {
+	limits, err := a.Connection.LimitBandwidth(handle, limits)
+	a.record("LimitBandwidth", handle, err)
+	return limits, err
+}
+
+func (a *auditingConnection) LimitCPU(handle string, limits garden.CPULimits) (garden.CPULimits, error) {
+	limits, err := a.Connection.LimitCPU(handle, limits)
+	a.record("LimitCPU", handle, err)
+	return limits, err
+}
+
+func (a *auditingConnection) LimitDisk(handle string, limits garden.DiskLimits) (garden.DiskLimits, error) {
+	limits, err := a.Connection.LimitDisk(handle, limits)
+	a.record("LimitDisk", handle, err)
+	return limits, err
+}
+
+func (a *auditingConnection) LimitMemory(handle string, limits garden.MemoryLimits) (garden.MemoryLimits, error) {
+	limits, err := a.Connection.LimitMemory(handle, limits)
+	a.record("LimitMemory", handle, err)
+	return limits, err
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/client/connection/fakes"
+)
+
+func TestAuditsChanges(t *testing.T) {
+	fakeConn := new(fakes.FakeConnection)
+	fakeConn.CreateReturns("web", nil)
+	fakeConn.DestroyReturns(errors.New("unknown handle: db\n"))
+
+	var log bytes.Buffer
+	conn := newAuditingConnection(fakeConn, &log, "localhost:7777", &requestStamp{ID: "abc123"})
+
+	conn.List(nil)
+	conn.Create(garden.ContainerSpec{})
+	conn.Destroy("db")
+	conn.Stop("web", true)
+	conn.LimitMemory("web", garden.MemoryLimits{LimitInBytes: 1024})
+
+	want := []auditEntry{
+		{Call: "Create", Handle: "web", Result: "ok"},
+		{Call: "Destroy", Handle: "db", Result: "error: unknown handle: db"},
+		{Call: "Kill", Handle: "web", Result: "ok"},
+		{Call: "LimitMemory", Handle: "web", Result: "ok"},
+	}
+
+	decoder := json.NewDecoder(&log)
+	for _, w := range want {
+		var entry auditEntry
+		if err := decoder.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+
+		if entry.Call != w.Call || entry.Handle != w.Handle || entry.Result != w.Result {
+			t.Errorf("audited %s %s %q, want %s %s %q", entry.Call, entry.Handle, entry.Result, w.Call, w.Handle, w.Result)
+		}
+
+		if entry.Target != "localhost:7777" || entry.RequestID != "abc123" || entry.User == "" || entry.Time.IsZero() {
+			t.Errorf("audited %+v", entry)
+		}
+	}
+
+	if decoder.More() {
+		t.Error("audited more than the changes")
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	args := []string{
+		"gaol", "create", "--docker-image", "registry.example.com/app",
+		"--registry-user", "ci", "--registry-password", "hunter2",
+		"-registry-password=hunter2",
+		"curl", "-H", "Authorization: Bearer abc", "--header=cookie:session=abc", "-H", "Accept: text/plain",
+	}
+
+	want := []string{
+		"gaol", "create", "--docker-image", "registry.example.com/app",
+		"--registry-user", "ci", "--registry-password", "REDACTED",
+		"-registry-password=REDACTED",
+		"curl", "-H", "Authorization: REDACTED", "--header=cookie: REDACTED", "-H", "Accept: text/plain",
+	}
+
+	if redacted := redactArgs(args); !reflect.DeepEqual(redacted, want) {
+		t.Errorf("redacted %q, want %q", redacted, want)
+	}
+}
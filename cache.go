@@ -0,0 +1,125 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/xoebus/gaol/commands"
+)
+
+// defaultCacheTTL is how long the cached handles of a target are used
+// before they are refreshed.
+const defaultCacheTTL = 30 * time.Second
+
+// handleCachePath is where the handles of the containers on the target at
+// address are cached for completion.
+func handleCachePath(address string) string {
+	return filepath.Join(os.Getenv("HOME"), ".gaol", "cache", "handles-"+url.QueryEscape(address))
+}
+
+// rootfsCachePath is where the rootfses listed on the target at address
+// are cached for completion.
+func rootfsCachePath(address string) string {
+	return filepath.Join(os.Getenv("HOME"), ".gaol", "cache", "rootfses-"+url.QueryEscape(address))
+}
+
+func writeHandleCache(address string, handles []string) error {
+	return writeCache(handleCachePath(address), handles)
+}
+
+// writeCache writes the lines to the cache file at path.
+func writeCache(path string, lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	// written to a temporary file and renamed into place, as completion may
+	// be reading it
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		tmp.WriteString(line + "\n")
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// readHandleCache returns the cached handles and when they were cached.
+func readHandleCache(address string) ([]string, time.Time, error) {
+	return readCache(handleCachePath(address))
+}
+
+// readCache returns the lines of the cache file at path, none of which have
+// spaces, and when they were written.
+func readCache(path string) ([]string, time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return strings.Fields(string(contents)), info.ModTime(), nil
+}
+
+// forgetHandles removes the cached handles of the target, after containers
+// have been created or destroyed.
+func forgetHandles(c *cli.Context) {
+	os.Remove(handleCachePath(currentTarget(c).Address))
+}
+
+// cachedHandles returns the handles of the containers on the target from the
+// cache, so that completion never waits for a server with many containers
+// to list them. If nothing is cached the containers are listed and cached;
+// if the cache is older than ttl it is used anyway and refreshed in the
+// background.
+func cachedHandles(c *cli.Context, ttl time.Duration) ([]string, error) {
+	address := currentTarget(c).Address
+
+	handles, cached, err := readHandleCache(address)
+	if err != nil {
+		handles, err := commands.ListHandles(client(c))
+		if err != nil {
+			return nil, err
+		}
+
+		writeHandleCache(address, handles)
+		return handles, nil
+	}
+
+	if time.Since(cached) > ttl {
+		refreshHandleCache(c, address)
+	}
+
+	return handles, nil
+}
+
+// refreshHandleCache lists the containers again in a separate gaol, which
+// outlives this one, to update the cache.
+func refreshHandleCache(c *cli.Context, address string) {
+	// claim the refresh so that completions while it runs do not start more
+	now := time.Now()
+	os.Chtimes(handleCachePath(address), now, now)
+
+	cmd := exec.Command(os.Args[0], "list")
+	cmd.Env = gaolEnv(c)
+	cmd.Start()
+}
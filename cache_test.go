@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestCachedHandles(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersReturns([]garden.Container{fakeContainer("a")}, nil)
+
+	// nothing is cached yet, so the containers are listed
+	res := runGaolIn(t, home, fakeClient, "list", "--cached")
+	if res.stdout != "a\n" || fakeClient.ContainersCallCount() != 1 {
+		t.Fatalf("printed %q after listing %d times", res.stdout, fakeClient.ContainersCallCount())
+	}
+
+	fakeClient.ContainersReturns([]garden.Container{fakeContainer("a"), fakeContainer("b")}, nil)
+
+	res = runGaolIn(t, home, fakeClient, "list", "--cached", "--cache-ttl", "1h")
+	if res.stdout != "a\n" || fakeClient.ContainersCallCount() != 1 {
+		t.Errorf("printed %q after listing %d times, want the cached handles", res.stdout, fakeClient.ContainersCallCount())
+	}
+
+	// listing refreshes the cache
+	runGaolIn(t, home, fakeClient, "list")
+
+	res = runGaolIn(t, home, fakeClient, "list", "--cached", "--cache-ttl", "1h")
+	if res.stdout != "a\nb\n" {
+		t.Errorf("printed %q, want the refreshed handles", res.stdout)
+	}
+
+	// creating a container forgets them
+	fakeClient.CreateReturns(fakeContainer("c"), nil)
+	runGaolIn(t, home, fakeClient, "create")
+
+	if _, err := os.Stat(filepath.Join(home, ".gaol", "cache", "handles-"+url.QueryEscape(defaultTarget))); err == nil {
+		t.Error("still have cached handles after creating a container")
+	}
+}
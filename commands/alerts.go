@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// Thresholds are the percentages of their limits at which a container's
+// memory and disk use are alerted on. Zero leaves that resource alone.
+type Thresholds struct {
+	Memory float64 `yaml:"memory"`
+	Disk   float64 `yaml:"disk"`
+}
+
+// IsZero reports whether nothing is alerted on.
+func (t Thresholds) IsZero() bool {
+	return t.Memory <= 0 && t.Disk <= 0
+}
+
+// Alert is a resource of which a container uses more of its limit than the
+// threshold allows.
+type Alert struct {
+	Resource string
+	Used     uint64
+	Limit    uint64
+}
+
+// Percent is how much of its limit is used.
+func (a Alert) Percent() float64 {
+	return float64(a.Used) / float64(a.Limit) * 100
+}
+
+func (a Alert) String() string {
+	return fmt.Sprintf("%s at %.0f%% of %s limit", a.Resource, a.Percent(), HumanBytes(a.Limit))
+}
+
+// CheckThresholds returns an alert for each resource the container, whose
+// info is given, uses too much of. Resident memory counts against the memory
+// limit; the disk limit is looked up only if disk is alerted on. Resources
+// without limits are never alerted on.
+func CheckThresholds(container garden.Container, info garden.ContainerInfo, thresholds Thresholds) []Alert {
+	alerts := []Alert{}
+
+	if thresholds.Memory > 0 {
+		alert := Alert{"memory", info.MemoryStat.TotalRss, info.MemoryStat.HierarchicalMemoryLimit}
+		if alert.Limit > 0 && alert.Percent() >= thresholds.Memory {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	if thresholds.Disk > 0 {
+		if limits, err := container.CurrentDiskLimits(); err == nil {
+			alert := Alert{"disk", info.DiskStat.BytesUsed, limits.ByteHard}
+			if alert.Limit > 0 && alert.Percent() >= thresholds.Disk {
+				alerts = append(alerts, alert)
+			}
+		}
+	}
+
+	return alerts
+}
@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+func TestCheckThresholds(t *testing.T) {
+	info := garden.ContainerInfo{
+		MemoryStat: garden.ContainerMemoryStat{TotalRss: 50 << 20, HierarchicalMemoryLimit: 100 << 20},
+		DiskStat:   garden.ContainerDiskStat{BytesUsed: 950 << 20},
+	}
+
+	container := fakeContainer("web")
+	container.CurrentDiskLimitsReturns(garden.DiskLimits{ByteHard: 1000 << 20}, nil)
+
+	alerts := CheckThresholds(container, info, Thresholds{Memory: 50, Disk: 96})
+	if len(alerts) != 1 || alerts[0].String() != "memory at 50% of 100.0 MiB limit" {
+		t.Errorf("alerted %v", alerts)
+	}
+
+	alerts = CheckThresholds(container, info, Thresholds{Disk: 90})
+	if len(alerts) != 1 || alerts[0].String() != "disk at 95% of 1000.0 MiB limit" {
+		t.Errorf("alerted %v", alerts)
+	}
+
+	alerts = CheckThresholds(container, info, Thresholds{})
+	if len(alerts) != 0 || container.CurrentDiskLimitsCallCount() != 2 {
+		t.Errorf("alerted %v without thresholds", alerts)
+	}
+}
+
+func TestCheckThresholdsWithoutLimits(t *testing.T) {
+	info := garden.ContainerInfo{
+		MemoryStat: garden.ContainerMemoryStat{TotalRss: 50 << 20},
+		DiskStat:   garden.ContainerDiskStat{BytesUsed: 950 << 20},
+	}
+
+	alerts := CheckThresholds(fakeContainer("web"), info, Thresholds{Memory: 1, Disk: 1})
+	if len(alerts) != 0 {
+		t.Errorf("alerted %v", alerts)
+	}
+}
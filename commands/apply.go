@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// Change is a single action taken (or not) while reconciling a manifest
+// against the live containers.
+type Change struct {
+	Action string
+	Name   string
+	Handle string
+}
+
+const (
+	actionCreated   = "created"
+	actionUnchanged = "unchanged"
+	actionChanged   = "changed"
+	actionDestroyed = "destroyed"
+)
+
+// Apply creates the containers in the manifest which do not exist yet
+// and, when pruning, destroys the containers which were created from the
+// manifest but are no longer described by it. Containers are matched by
+// handle first and then by the name property set when they were created.
+// A container whose rootfs, limits, env or privilege differ from the
+// manifest is reported as changed but left as it is; one created before
+// gaol recorded these is taken to be unchanged. The output of the run steps
+// of created containers is written to output.
+func Apply(client garden.Client, manifest *Manifest, prune bool, output io.Writer) ([]Change, error) {
+	live, err := client.Containers(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	liveHandles := map[string]garden.Container{}
+	for _, container := range live {
+		liveHandles[container.Handle()] = container
+	}
+
+	owned, err := client.Containers(garden.Properties{
+		manifestProperty: manifest.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ownedByName := map[string]garden.Container{}
+	for _, container := range owned {
+		name, err := container.GetProperty(nameProperty)
+		if err != nil {
+			return nil, err
+		}
+
+		ownedByName[name] = container
+	}
+
+	changes := []Change{}
+	matched := map[string]bool{}
+
+	for _, name := range manifest.Names() {
+		mc := manifest.Containers[name]
+
+		container, found := liveHandles[mc.Handle]
+		if !found {
+			container, found = ownedByName[name]
+		}
+
+		if found {
+			matched[container.Handle()] = true
+			changes = append(changes, Change{existingAction(container, mc), name, container.Handle()})
+			continue
+		}
+
+		container, err := createFromManifest(client, manifest, name, output, nil)
+		if err != nil {
+			return changes, err
+		}
+
+		changes = append(changes, Change{actionCreated, name, container.Handle()})
+	}
+
+	if !prune {
+		return changes, nil
+	}
+
+	for name, container := range ownedByName {
+		if _, wanted := manifest.Containers[name]; wanted || matched[container.Handle()] {
+			continue
+		}
+
+		err := client.Destroy(container.Handle())
+		if err != nil {
+			return changes, fmt.Errorf("container %s: %s", name, err)
+		}
+
+		changes = append(changes, Change{actionDestroyed, name, container.Handle()})
+	}
+
+	return changes, nil
+}
+
+// existingAction is whether a live container has changed from the manifest
+// container it was created as.
+func existingAction(container garden.Container, mc ManifestContainer) string {
+	digest, err := container.GetProperty(specProperty)
+	if err != nil || digest == "" || digest == mc.digest() {
+		return actionUnchanged
+	}
+
+	return actionChanged
+}
+
+// PrintChanges writes one tab-separated line per change.
+func PrintChanges(w io.Writer, changes []Change) {
+	for _, change := range changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", change.Action, change.Name, change.Handle)
+	}
+}
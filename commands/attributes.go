@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"archive/tar"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// Attributes override the owner and mode of what is streamed into a
+// container.
+type Attributes struct {
+	// User and Group are names or numeric ids; empty leaves them alone.
+	User  string
+	Group string
+
+	// Mode is given to files, and to directories with execute permission
+	// added wherever they can be read; negative leaves modes alone.
+	Mode int64
+}
+
+// KeepAttributes leaves the owner and mode of what is streamed in alone.
+var KeepAttributes = Attributes{Mode: -1}
+
+// ParseChown parses an owner given as user:group, where either may be a name
+// or a numeric id and either may be left out, as in user or :group.
+func ParseChown(raw string) (string, string, error) {
+	user, group := raw, ""
+	if i := strings.Index(raw, ":"); i >= 0 {
+		user, group = raw[:i], raw[i+1:]
+	}
+
+	if user == "" && group == "" || strings.Contains(group, ":") {
+		return "", "", fmt.Errorf("invalid owner %q: must be user:group, user or :group", raw)
+	}
+
+	return user, group, nil
+}
+
+// ParseChmod parses a mode given in octal, such as 0640.
+func ParseChmod(raw string) (int64, error) {
+	mode, err := strconv.ParseInt(raw, 8, 64)
+	if err != nil || mode < 0 || mode > 07777 {
+		return 0, fmt.Errorf("invalid mode %q: must be octal, such as 0640", raw)
+	}
+
+	return mode, nil
+}
+
+// IsZero reports whether the attributes leave everything alone.
+func (a Attributes) IsZero() bool {
+	return a.User == "" && a.Group == "" && a.Mode < 0
+}
+
+// apply overrides the owner and mode of an entry of an archive streamed in.
+// A name replaces the entry's name for its owner, which the container's tar
+// prefers to its id, whereas an id replaces its id and drops its name.
+func (a Attributes) apply(header *tar.Header) {
+	if a.User != "" {
+		if uid, err := strconv.Atoi(a.User); err == nil {
+			header.Uid, header.Uname = uid, ""
+		} else {
+			header.Uname = a.User
+		}
+	}
+
+	if a.Group != "" {
+		if gid, err := strconv.Atoi(a.Group); err == nil {
+			header.Gid, header.Gname = gid, ""
+		} else {
+			header.Gname = a.Group
+		}
+	}
+
+	if a.Mode >= 0 {
+		mode := a.Mode
+		if header.Typeflag == tar.TypeDir {
+			mode |= (mode & 0444) >> 2
+		}
+
+		header.Mode = header.Mode&^07777 | mode
+	}
+}
+
+// applyIn overrides the owner and mode of the file in the container, for
+// files joined together there rather than given them by their tar headers.
+func (a Attributes) applyIn(container garden.Container, file string) error {
+	if a.User != "" || a.Group != "" {
+		owner := a.User
+		if a.Group != "" {
+			owner += ":" + a.Group
+		}
+
+		if err := runScript(container, "changing the owner of "+file, nil, `chown "$1" "$2"`, owner, file); err != nil {
+			return err
+		}
+	}
+
+	if a.Mode >= 0 {
+		return runScript(container, "changing the mode of "+file, nil, `chmod "$1" "$2"`, fmt.Sprintf("%04o", a.Mode), file)
+	}
+
+	return nil
+}
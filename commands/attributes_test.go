@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"archive/tar"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestParseChown(t *testing.T) {
+	for raw, want := range map[string][2]string{
+		"app:staff": {"app", "staff"},
+		"1000:50":   {"1000", "50"},
+		"app":       {"app", ""},
+		"app:":      {"app", ""},
+		":staff":    {"", "staff"},
+	} {
+		user, group, err := ParseChown(raw)
+		if err != nil || user != want[0] || group != want[1] {
+			t.Errorf("%s: got %q, %q (%v)", raw, user, group, err)
+		}
+	}
+
+	for _, raw := range []string{"", ":", "app:staff:x"} {
+		if _, _, err := ParseChown(raw); err == nil {
+			t.Errorf("expected %q to be refused", raw)
+		}
+	}
+}
+
+func TestParseChmod(t *testing.T) {
+	for raw, want := range map[string]int64{"0640": 0640, "755": 0755, "4755": 04755, "0": 0} {
+		if mode, err := ParseChmod(raw); err != nil || mode != want {
+			t.Errorf("%s: got %o (%v)", raw, mode, err)
+		}
+	}
+
+	for _, raw := range []string{"", "rwx", "0999", "17777", "-1"} {
+		if _, err := ParseChmod(raw); err == nil {
+			t.Errorf("expected %q to be refused", raw)
+		}
+	}
+}
+
+func TestAttributesApply(t *testing.T) {
+	attrs := Attributes{User: "1000", Group: "staff", Mode: 0640}
+
+	file := &tar.Header{Name: "app.conf", Mode: 0600, Uid: 0, Uname: "root", Gid: 0, Gname: "root", Typeflag: tar.TypeReg}
+	attrs.apply(file)
+
+	if file.Uid != 1000 || file.Uname != "" || file.Gid != 0 || file.Gname != "staff" || file.Mode != 0640 {
+		t.Errorf("got %#v", file)
+	}
+
+	dir := &tar.Header{Name: "conf.d", Mode: 0700, Typeflag: tar.TypeDir}
+	attrs.apply(dir)
+
+	if dir.Mode != 0750 {
+		t.Errorf("gave the directory mode %o, want 0750", dir.Mode)
+	}
+
+	untouched := &tar.Header{Name: "app.conf", Mode: 0600, Uid: 5, Uname: "app"}
+	KeepAttributes.apply(untouched)
+
+	if untouched.Mode != 0600 || untouched.Uid != 5 || untouched.Uname != "app" {
+		t.Errorf("changed %#v", untouched)
+	}
+}
+
+func TestStreamInAttributes(t *testing.T) {
+	container := fakeContainer("web")
+
+	var header *tar.Header
+	container.StreamInStub = func(dst string, r io.Reader) error {
+		var err error
+		header, err = tar.NewReader(r).Next()
+		return err
+	}
+
+	attrs := Attributes{User: "app", Group: "staff", Mode: 0600}
+
+	if err := StreamIn(streamInClient(container), "web", "/etc/secret", strings.NewReader("hush"), 4, attrs); err != nil {
+		t.Fatal(err)
+	}
+
+	if header.Uname != "app" || header.Gname != "staff" || header.Mode != 0600 {
+		t.Errorf("streamed in %#v", header)
+	}
+
+	if err := StreamInArchive(streamInClient(container), "web", "/srv", artifactTar(map[string]string{"app/run.sh": "#!/bin/sh"}), attrs); err != nil {
+		t.Fatal(err)
+	}
+
+	if header.Name != "app/run.sh" || header.Uname != "app" || header.Mode != 0600 {
+		t.Errorf("extracted %#v", header)
+	}
+}
+
+func TestStreamInChunksAttributes(t *testing.T) {
+	container := fakeContainer("web")
+	recordEntries(container)
+
+	scripts := []string{}
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		scripts = append(scripts, spec.Args[1]+" "+strings.Join(spec.Args[3:], " "))
+		return new(fakes.FakeProcess), nil
+	}
+
+	input := strings.NewReader(strings.Repeat("x", streamChunkSize+1))
+	if err := StreamIn(streamInClient(container), "web", "/data/rootfs.tar", input, -1, Attributes{Group: "staff", Mode: 0640}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`chown "$1" "$2" :staff /data/rootfs.tar`,
+		`chmod "$1" "$2" 0640 /data/rootfs.tar`,
+	}
+
+	if len(scripts) != 3 || scripts[1] != want[0] || scripts[2] != want[1] {
+		t.Errorf("ran %q, want the join followed by %q", scripts, want)
+	}
+}
@@ -0,0 +1,254 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/mattn/go-shellwords"
+)
+
+// BenchResult summarizes the iterations of a benchmark.
+type BenchResult struct {
+	Iterations int
+	Failures   int
+
+	// Elapsed is the time taken by all of the iterations together.
+	Elapsed time.Duration
+
+	// Latencies are those of the iterations which succeeded, fastest first.
+	Latencies []time.Duration
+
+	// FirstErr is the error of the first iteration which failed.
+	FirstErr error
+}
+
+// Percentile is the latency which p percent of the successful iterations
+// beat or matched.
+func (r BenchResult) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+
+	i := int(float64(len(r.Latencies))*p/100+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	if i >= len(r.Latencies) {
+		i = len(r.Latencies) - 1
+	}
+
+	return r.Latencies[i]
+}
+
+// Throughput is the number of successful iterations per second.
+func (r BenchResult) Throughput() float64 {
+	if r.Elapsed == 0 {
+		return 0
+	}
+
+	return float64(len(r.Latencies)) / r.Elapsed.Seconds()
+}
+
+// bench runs op iterations times, at most concurrency at a time. op returns
+// the latency of the operation it measures, leaving out any setup and
+// cleanup it does.
+func bench(iterations int, concurrency int, op func(i int) (time.Duration, error)) BenchResult {
+	latencies := make([]time.Duration, iterations)
+
+	start := time.Now()
+	results := parallel(iterations, concurrency, func(i int) Result {
+		latency, err := op(i)
+		latencies[i] = latency
+		return Result{Err: err}
+	})
+
+	result := BenchResult{
+		Iterations: iterations,
+		Elapsed:    time.Since(start),
+		Latencies:  []time.Duration{},
+	}
+
+	for i, r := range results {
+		if r.Err != nil {
+			if result.FirstErr == nil {
+				result.FirstErr = r.Err
+			}
+
+			result.Failures++
+			continue
+		}
+
+		result.Latencies = append(result.Latencies, latencies[i])
+	}
+
+	sort.Sort(durations(result.Latencies))
+
+	return result
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// timed returns how long f took.
+func timed(f func() error) (time.Duration, error) {
+	start := time.Now()
+	err := f()
+	return time.Since(start), err
+}
+
+// benchHandle names the containers made by benchmarks apart from those of
+// other benchmarks running at the same time.
+func benchHandle(suffix string) string {
+	return fmt.Sprintf("gaol-bench-%d-%s", os.Getpid(), suffix)
+}
+
+// BenchCreate measures creating containers from spec. Each container is
+// destroyed once created, which is not counted.
+func BenchCreate(client garden.Client, spec garden.ContainerSpec, iterations int, concurrency int) BenchResult {
+	return bench(iterations, concurrency, func(i int) (time.Duration, error) {
+		spec := spec
+		spec.Handle = benchHandle(fmt.Sprintf("%d", i+1))
+
+		latency, err := timed(func() error {
+			_, err := client.Create(spec)
+			return err
+		})
+		if err != nil {
+			return latency, err
+		}
+
+		return latency, client.Destroy(spec.Handle)
+	})
+}
+
+// BenchRun measures running command in the container until it exits.
+func BenchRun(container garden.Container, command string, iterations int, concurrency int) (BenchResult, error) {
+	args, err := shellwords.Parse(command)
+	if err != nil {
+		return BenchResult{}, err
+	}
+
+	if len(args) == 0 {
+		return BenchResult{}, errors.New("missing command to run")
+	}
+
+	return bench(iterations, concurrency, func(i int) (time.Duration, error) {
+		return timed(func() error {
+			process, err := container.Run(garden.ProcessSpec{
+				Path: args[0],
+				Args: args[1:],
+			}, garden.ProcessIO{Stdout: ioutil.Discard, Stderr: ioutil.Discard})
+			if err != nil {
+				return err
+			}
+
+			status, err := process.Wait()
+			if err != nil {
+				return err
+			}
+
+			if status != 0 {
+				return ProcessExitError{command, status}
+			}
+
+			return nil
+		})
+	}), nil
+}
+
+// BenchStream measures streaming a file of size bytes into the container and
+// back out again.
+func BenchStream(container garden.Container, size int64, iterations int, concurrency int) BenchResult {
+	contents := bytes.Repeat([]byte{'g'}, int(size))
+
+	return bench(iterations, concurrency, func(i int) (time.Duration, error) {
+		name := benchHandle(fmt.Sprintf("%d", i+1))
+
+		var archive bytes.Buffer
+		tw := tar.NewWriter(&archive)
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: size})
+		tw.Write(contents)
+		tw.Close()
+
+		return timed(func() error {
+			if err := container.StreamIn("/tmp", &archive); err != nil {
+				return err
+			}
+
+			output, err := container.StreamOut("/tmp/" + name)
+			if err != nil {
+				return err
+			}
+			defer output.Close()
+
+			tr := tar.NewReader(output)
+			if _, err := tr.Next(); err != nil {
+				return err
+			}
+
+			n, err := io.Copy(ioutil.Discard, tr)
+			if err != nil {
+				return err
+			}
+
+			if n != size {
+				return fmt.Errorf("streamed out %d bytes after streaming in %d", n, size)
+			}
+
+			return nil
+		})
+	})
+}
+
+// PrintBench writes the latency percentiles and throughput of a benchmark.
+func PrintBench(w io.Writer, result BenchResult) {
+	fmt.Fprintf(w, "iterations: %d (%d failed)\n", result.Iterations, result.Failures)
+	fmt.Fprintf(w, "elapsed: %s\n", HumanDuration(result.Elapsed))
+	fmt.Fprintf(w, "throughput: %.1f/s\n", result.Throughput())
+
+	if len(result.Latencies) > 0 {
+		fmt.Fprintf(w, "min: %s\n", HumanDuration(result.Latencies[0]))
+
+		for _, p := range []float64{50, 90, 99} {
+			fmt.Fprintf(w, "p%g: %s\n", p, HumanDuration(result.Percentile(p)))
+		}
+
+		fmt.Fprintf(w, "max: %s\n", HumanDuration(result.Latencies[len(result.Latencies)-1]))
+	}
+
+	if result.FirstErr != nil {
+		fmt.Fprintf(w, "first error: %s\n", result.FirstErr)
+	}
+}
+
+// BenchContainer looks up the container with the handle for benchmarks which
+// run in one or, if no handle is given, creates one from the rootfs. The
+// returned function destroys any container it created.
+func BenchContainer(client garden.Client, handle string, rootfs string) (garden.Container, func() error, error) {
+	if handle != "" {
+		container, err := client.Lookup(handle)
+		return container, func() error { return nil }, err
+	}
+
+	container, err := client.Create(garden.ContainerSpec{
+		Handle:     benchHandle("canary"),
+		RootFSPath: rootfs,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return container, func() error { return client.Destroy(container.Handle()) }, nil
+}
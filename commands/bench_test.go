@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestBenchPercentiles(t *testing.T) {
+	result := bench(10, 3, func(i int) (time.Duration, error) {
+		if i == 9 {
+			return 0, errors.New("out of space")
+		}
+
+		return time.Duration(9-i) * time.Millisecond, nil
+	})
+
+	if result.Failures != 1 || result.FirstErr == nil || result.FirstErr.Error() != "out of space" {
+		t.Errorf("counted %d failures, first %v", result.Failures, result.FirstErr)
+	}
+
+	tests := map[float64]time.Duration{
+		0:   1 * time.Millisecond,
+		50:  5 * time.Millisecond,
+		90:  8 * time.Millisecond,
+		100: 9 * time.Millisecond,
+	}
+
+	for p, want := range tests {
+		if got := result.Percentile(p); got != want {
+			t.Errorf("p%g is %s, want %s", p, got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	PrintBench(&buf, result)
+
+	for _, line := range []string{"iterations: 10 (1 failed)", "p50: 5ms", "max: 9ms", "first error: out of space"} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("expected %q in\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestBenchCreate(t *testing.T) {
+	var mutex sync.Mutex
+	created := map[string]bool{}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.CreateStub = func(spec garden.ContainerSpec) (garden.Container, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		created[spec.Handle] = true
+		return fakeContainer(spec.Handle), nil
+	}
+
+	result := BenchCreate(fakeClient, garden.ContainerSpec{RootFSPath: "/rootfs"}, 5, 2)
+
+	if len(result.Latencies) != 5 || len(created) != 5 {
+		t.Errorf("measured %d creations of %d containers", len(result.Latencies), len(created))
+	}
+
+	if fakeClient.DestroyCallCount() != 5 {
+		t.Errorf("destroyed %d containers, want every one created", fakeClient.DestroyCallCount())
+	}
+}
+
+func TestBenchRun(t *testing.T) {
+	container := fakeContainer("canary")
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		if spec.Path != "echo" || len(spec.Args) != 1 || spec.Args[0] != "hello world" {
+			t.Errorf("ran %s %q", spec.Path, spec.Args)
+		}
+
+		return new(fakes.FakeProcess), nil
+	}
+
+	result, err := BenchRun(container, "echo 'hello world'", 3, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Failures != 0 || container.RunCallCount() != 3 {
+		t.Errorf("ran %d times with %d failures", container.RunCallCount(), result.Failures)
+	}
+
+	if _, err := BenchRun(container, "", 3, 3); err == nil {
+		t.Error("expected an empty command to be refused")
+	}
+}
@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// maxInflightInfo bounds the number of concurrent info requests made by
+// bulkInfo.
+const maxInflightInfo = 16
+
+// bulkInfo fetches the info of every container concurrently. The info of
+// containers which could not be fetched (e.g. because they were destroyed
+// in the meantime) is returned as an error instead.
+func bulkInfo(containers []garden.Container) (map[string]garden.ContainerInfo, map[string]error) {
+	infos := map[string]garden.ContainerInfo{}
+	errs := map[string]error{}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	inflight := make(chan struct{}, maxInflightInfo)
+
+	for _, container := range containers {
+		wg.Add(1)
+		inflight <- struct{}{}
+
+		go func(container garden.Container) {
+			defer wg.Done()
+			defer func() { <-inflight }()
+
+			info, err := container.Info()
+
+			mu.Lock()
+			if err != nil {
+				errs[container.Handle()] = err
+			} else {
+				infos[container.Handle()] = info
+			}
+			mu.Unlock()
+		}(container)
+	}
+
+	wg.Wait()
+
+	return infos, errs
+}
+
+// Result is the outcome of doing something to a single container.
+type Result struct {
+	Handle string
+	Err    error
+}
+
+// forEach calls f with each of the handles, at most concurrency at a time,
+// and returns the outcomes in the order of the handles.
+func forEach(handles []string, concurrency int, f func(handle string) error) []Result {
+	return parallel(len(handles), concurrency, func(i int) Result {
+		return Result{handles[i], f(handles[i])}
+	})
+}
+
+// parallel calls f with 0 to n-1, at most concurrency at a time, and returns
+// the outcomes in order.
+func parallel(n int, concurrency int, f func(i int) Result) []Result {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]Result, n)
+
+	var wg sync.WaitGroup
+	inflight := make(chan struct{}, concurrency)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		inflight <- struct{}{}
+
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-inflight }()
+
+			results[i] = f(i)
+		}(i)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// Failures returns the results which failed.
+func Failures(results []Result) []Result {
+	failures := []Result{}
+	for _, result := range results {
+		if result.Err != nil {
+			failures = append(failures, result)
+		}
+	}
+
+	return failures
+}
+
+// PrintResults writes one tab-separated line per result: the action taken
+// and the handle or, if the action failed, "failed", the handle and the
+// error.
+func PrintResults(w io.Writer, action string, results []Result) {
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(w, "failed\t%s\t%s\n", result.Handle, result.Err)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\n", action, result.Handle)
+		}
+	}
+}
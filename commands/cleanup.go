@@ -0,0 +1,45 @@
+package commands
+
+import "sync"
+
+// cleanups are run when gaol exits, however it exits, so that it does not
+// leave the terminal raw or temporary files and containers behind.
+var cleanups struct {
+	sync.Mutex
+	funcs []*func()
+}
+
+// AtExit registers f to be run by RunCleanups. The returned function
+// unregisters f, for cleanups which have already been done.
+func AtExit(f func()) func() {
+	cleanups.Lock()
+	defer cleanups.Unlock()
+
+	registered := &f
+	cleanups.funcs = append(cleanups.funcs, registered)
+
+	return func() {
+		cleanups.Lock()
+		defer cleanups.Unlock()
+
+		*registered = nil
+	}
+}
+
+// RunCleanups runs the registered cleanups, most recent first. Each is run
+// only once, even if RunCleanups is called again.
+func RunCleanups() {
+	cleanups.Lock()
+	pending := []func(){}
+	for _, f := range cleanups.funcs {
+		if *f != nil {
+			pending = append(pending, *f)
+		}
+	}
+	cleanups.funcs = nil
+	cleanups.Unlock()
+
+	for i := len(pending) - 1; i >= 0; i-- {
+		pending[i]()
+	}
+}
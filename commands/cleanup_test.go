@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCleanups(t *testing.T) {
+	ran := []string{}
+
+	AtExit(func() { ran = append(ran, "first") })
+	done := AtExit(func() { ran = append(ran, "done already") })
+	AtExit(func() { ran = append(ran, "last") })
+
+	done()
+
+	RunCleanups()
+	RunCleanups()
+
+	if !reflect.DeepEqual(ran, []string{"last", "first"}) {
+		t.Errorf("ran %q, want the cleanups still registered, most recent first, once", ran)
+	}
+}
@@ -0,0 +1,290 @@
+package commands
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// Collection is a path in a container to be streamed out into a local
+// directory once a process has exited.
+type Collection struct {
+	Src string
+	Dst string
+}
+
+// ParseCollections parses collections given as /path/in/container=./dir.
+func ParseCollections(specs []string) ([]Collection, error) {
+	collections := []Collection{}
+	for _, spec := range specs {
+		i := strings.Index(spec, "=")
+		if i < 0 || i == len(spec)-1 {
+			return nil, fmt.Errorf("invalid collection %q: must be /path/in/container=./dir", spec)
+		}
+
+		if !strings.HasPrefix(spec, "/") {
+			return nil, fmt.Errorf("invalid collection %q: %s is not an absolute path", spec, spec[:i])
+		}
+
+		collections = append(collections, Collection{spec[:i], spec[i+1:]})
+	}
+
+	return collections, nil
+}
+
+// Collect streams each path out of the container into its directory, where
+// it keeps its name, writing what it collects to progress. It carries on
+// past paths which cannot be collected and fails with the first.
+func Collect(client garden.Client, handle string, collections []Collection, progress io.Writer) error {
+	if len(collections) == 0 {
+		return nil
+	}
+
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	return collect(container, collections, progress)
+}
+
+func collect(container garden.Container, collections []Collection, progress io.Writer) error {
+	var first error
+
+	for _, collection := range collections {
+		err := collectPath(container, collection, progress)
+		if err != nil {
+			fmt.Fprintf(progress, "failed to collect %s: %s\n", collection.Src, err)
+
+			if first == nil {
+				first = fmt.Errorf("collecting %s: %s", collection.Src, err)
+			}
+
+			continue
+		}
+
+		fmt.Fprintf(progress, "collected %s into %s\n", collection.Src, filepath.Join(collection.Dst, path.Base(collection.Src)))
+	}
+
+	return first
+}
+
+func collectPath(container garden.Container, collection Collection, warnings io.Writer) error {
+	output, err := container.StreamOut(collection.Src)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	if err := os.MkdirAll(collection.Dst, 0755); err != nil {
+		return err
+	}
+
+	return extractTar(output, collection.Dst, false, warnings)
+}
+
+// extractTar writes the files, directories, links, fifos and device nodes of
+// the archive into dir. Entries are kept inside it: symlinks which would
+// point out of it are refused, as are entries which would be written through
+// symlinks extracted before them. If preserve is set, entries keep their
+// whole mode, setuid bits and all, and, when run as root, their owners' ids.
+// Device nodes are only created when preserving as root; otherwise they are
+// skipped with a warning to warnings.
+func extractTar(r io.Reader, dir string, preserve bool, warnings io.Writer) error {
+	tr := tar.NewReader(r)
+
+	// directories are given their modes last, so that those which cannot be
+	// written do not stop their contents being extracted
+	dirs := []*tar.Header{}
+	paths := []string{}
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			if !preserve {
+				return nil
+			}
+
+			for i := len(dirs) - 1; i >= 0; i-- {
+				// a later entry may have put something else in its place
+				if info, err := os.Lstat(paths[i]); err != nil || !info.IsDir() {
+					continue
+				}
+
+				if err := preserveAttributes(dirs[i], paths[i]); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+		if name == "" {
+			continue
+		}
+
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+
+		// symlinks extracted earlier must not take later entries out of dir,
+		// either through a directory above them or in place of them
+		if err := checkNoSymlinks(dir, name); err != nil {
+			return err
+		}
+
+		if info, err := os.Lstat(dst); err == nil && info.Mode()&os.ModeSymlink != 0 && header.Typeflag != tar.TypeSymlink {
+			if err := os.Remove(dst); err != nil {
+				return err
+			}
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dst, os.FileMode(header.Mode).Perm()|0700); err != nil {
+				return err
+			}
+
+			dirs = append(dirs, header)
+			paths = append(paths, dst)
+		case tar.TypeReg:
+			if err := writeArtifactFile(tr, dst, os.FileMode(header.Mode).Perm()); err != nil {
+				return err
+			}
+
+			if preserve {
+				if err := preserveAttributes(header, dst); err != nil {
+					return err
+				}
+			}
+		case tar.TypeSymlink:
+			target := path.Join(path.Dir(name), header.Linkname)
+			if path.IsAbs(header.Linkname) || target == ".." || strings.HasPrefix(target, "../") {
+				return errors.New("symlink points outside the artifact: " + header.Name)
+			}
+
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return err
+			}
+
+			os.Remove(dst)
+			if err := os.Symlink(header.Linkname, dst); err != nil {
+				return err
+			}
+
+			if preserve && os.Geteuid() == 0 {
+				if err := os.Lchown(dst, header.Uid, header.Gid); err != nil {
+					return err
+				}
+			}
+		case tar.TypeLink:
+			// hardlinks are named from the root of the archive, and so are
+			// kept inside it as other entries are
+			target := strings.TrimPrefix(path.Clean("/"+header.Linkname), "/")
+			if err := checkNoSymlinks(dir, target); err != nil {
+				return err
+			}
+
+			// some systems link what a symlink points to rather than the link
+			src := filepath.Join(dir, filepath.FromSlash(target))
+			if info, err := os.Lstat(src); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				return errors.New("hardlink to a symlink: " + header.Name)
+			}
+
+			os.Remove(dst)
+			if err := os.Link(src, dst); err != nil {
+				return err
+			}
+		case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+			if header.Typeflag != tar.TypeFifo && (!preserve || os.Geteuid() != 0) {
+				fmt.Fprintf(warnings, "warning: skipping device node %s, which is only created when preserving as root\n", header.Name)
+				continue
+			}
+
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return err
+			}
+
+			os.Remove(dst)
+			if err := makeNode(dst, header); err != nil {
+				return fmt.Errorf("creating %s: %s", header.Name, err)
+			}
+
+			if preserve {
+				if err := preserveAttributes(header, dst); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// checkNoSymlinks fails if any of the directories leading from dir to name,
+// a path inside it, is a symlink.
+func checkNoSymlinks(dir string, name string) error {
+	parent := dir
+	for _, part := range strings.Split(path.Dir(name), "/") {
+		if part == "." {
+			break
+		}
+
+		parent = filepath.Join(parent, part)
+
+		info, err := os.Lstat(parent)
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return errors.New("entry is under a symlink: " + name)
+		}
+	}
+
+	return nil
+}
+
+// preserveAttributes gives the extracted file or directory the mode of its
+// entry and, when run as root, its owner. The owner is set first, as
+// changing it clears setuid bits.
+func preserveAttributes(header *tar.Header, dst string) error {
+	if os.Geteuid() == 0 {
+		if err := os.Lchown(dst, header.Uid, header.Gid); err != nil {
+			return err
+		}
+	}
+
+	mode := header.FileInfo().Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	return os.Chmod(dst, mode)
+}
+
+func writeArtifactFile(r io.Reader, dst string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
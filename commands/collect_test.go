@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestParseCollections(t *testing.T) {
+	collections, err := ParseCollections([]string{"/var/log/app=./out", "/src/a=b=c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Collection{{"/var/log/app", "./out"}, {"/src/a", "b=c"}}
+	if !reflect.DeepEqual(collections, want) {
+		t.Errorf("parsed %v, want %v", collections, want)
+	}
+
+	for _, invalid := range []string{"/var/log", "/var/log=", "var/log=./out"} {
+		if _, err := ParseCollections([]string{invalid}); err == nil {
+			t.Errorf("parsed %q", invalid)
+		}
+	}
+}
+
+// artifactTar returns a tar of the files, named as the server names the
+// entries of a path it streams out.
+func artifactTar(files map[string]string) io.ReadCloser {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, contents := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(contents))
+	}
+	tw.Close()
+
+	return ioutil.NopCloser(&buf)
+}
+
+func TestCollect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gaol-collect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	container := fakeContainer("a")
+	container.StreamOutStub = func(src string) (io.ReadCloser, error) {
+		if src == "/missing" {
+			return nil, errors.New("no such file")
+		}
+		return artifactTar(map[string]string{"log/app.log": "started"}), nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	var progress bytes.Buffer
+	err = Collect(fakeClient, "a", []Collection{{"/missing", dir}, {"/var/log", filepath.Join(dir, "out")}}, &progress)
+	if err == nil || !strings.Contains(err.Error(), "/missing") {
+		t.Errorf("got %v, want the path which could not be collected", err)
+	}
+
+	// the rest are collected all the same
+	if contents, _ := ioutil.ReadFile(filepath.Join(dir, "out", "log", "app.log")); string(contents) != "started" {
+		t.Errorf("collected %q", contents)
+	}
+
+	if !strings.Contains(progress.String(), "collected /var/log into "+filepath.Join(dir, "out", "log")) {
+		t.Errorf("printed %q", progress.String())
+	}
+}
+
+func TestExtractTarRefusesEscapingSymlinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gaol-task")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, link := range []string{"/etc/passwd", "../../etc/passwd"} {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		tw.WriteHeader(&tar.Header{Name: "out/link", Linkname: link, Typeflag: tar.TypeSymlink})
+		tw.Close()
+
+		if err := extractTar(&buf, dir, false, ioutil.Discard); err == nil {
+			t.Errorf("extracted a symlink to %s", link)
+		}
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "../../escaped", Mode: 0644, Typeflag: tar.TypeReg})
+	tw.Close()
+
+	if err := extractTar(&buf, dir, false, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "escaped")); err != nil {
+		t.Errorf("did not keep an entry naming its way out inside: %s", err)
+	}
+}
+
+func TestExtractTarRefusesWritingThroughSymlinks(t *testing.T) {
+	parent, err := ioutil.TempDir("", "gaol-task")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(parent)
+
+	dir := filepath.Join(parent, "out")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "a", Linkname: ".", Typeflag: tar.TypeSymlink})
+	tw.WriteHeader(&tar.Header{Name: "a/b/", Mode: 0755, Typeflag: tar.TypeDir})
+	tw.WriteHeader(&tar.Header{Name: "a/b/link", Linkname: "../../", Typeflag: tar.TypeSymlink})
+	tw.WriteHeader(&tar.Header{Name: "a/b/link/pwned", Mode: 0644, Size: 5, Typeflag: tar.TypeReg})
+	tw.Write([]byte("owned"))
+	tw.Close()
+
+	if err := extractTar(&buf, dir, false, ioutil.Discard); err == nil {
+		t.Error("extracted entries through a symlink")
+	}
+
+	if _, err := os.Lstat(filepath.Join(parent, "pwned")); err == nil {
+		t.Error("wrote a file outside the directory")
+	}
+}
+
+func TestExtractTarSkipsDeviceNodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gaol-task")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "dev/null", Mode: 0666, Devmajor: 1, Devminor: 3, Typeflag: tar.TypeChar})
+	tw.WriteHeader(&tar.Header{Name: "dev/sda", Mode: 0660, Devmajor: 8, Typeflag: tar.TypeBlock})
+	tw.WriteHeader(&tar.Header{Name: "etc/hosts", Mode: 0644, Typeflag: tar.TypeReg})
+	tw.Close()
+
+	var warnings bytes.Buffer
+	if err := extractTar(&buf, dir, false, &warnings); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Lstat(filepath.Join(dir, "dev", "null")); !os.IsNotExist(err) {
+		t.Errorf("created a device node without preserving: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "etc", "hosts")); err != nil {
+		t.Errorf("stopped at the device nodes: %s", err)
+	}
+
+	if lines := strings.Split(strings.TrimSpace(warnings.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[0], "dev/null") {
+		t.Errorf("warned %q", warnings.String())
+	}
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// ContainerProcess is a process running in a container, as the helper's ps
+// sees it. Name is what gaol started it, or its parent, as, if anything.
+type ContainerProcess struct {
+	PID     int
+	User    string
+	CPU     float64
+	RSS     uint64
+	Name    string
+	Command string
+}
+
+// ContainerProcesses lists the processes running in the container with the
+// helper. Named leaves out those gaol did not start by name, nor their
+// children.
+func ContainerProcesses(client garden.Client, handle string, helper *Helper, named bool) ([]ContainerProcess, error) {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	process, err := RunHelper(container, helper, []string{"ps"}, garden.ProcessIO{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := process.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	if status != 0 {
+		return nil, fmt.Errorf("listing processes failed: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	processes := []ContainerProcess{}
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		p, err := parseContainerProcess(line)
+		if err != nil {
+			return nil, err
+		}
+
+		if named && p.Name == "" {
+			continue
+		}
+
+		processes = append(processes, p)
+	}
+
+	return processes, nil
+}
+
+func parseContainerProcess(line string) (ContainerProcess, error) {
+	fields := strings.SplitN(line, "\t", 6)
+	if len(fields) != 6 {
+		return ContainerProcess{}, fmt.Errorf("unexpected line from ps: %q", line)
+	}
+
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return ContainerProcess{}, fmt.Errorf("unexpected line from ps: %q", line)
+	}
+
+	cpu, _ := strconv.ParseFloat(fields[2], 64)
+	rss, _ := strconv.ParseUint(fields[3], 10, 64)
+
+	return ContainerProcess{
+		PID:     pid,
+		User:    fields[1],
+		CPU:     cpu,
+		RSS:     rss,
+		Name:    fields[4],
+		Command: fields[5],
+	}, nil
+}
+
+// PrintContainerProcesses writes a tab-separated line per process: its pid,
+// user, cpu usage, resident memory, name (- if it has none) and command.
+func PrintContainerProcesses(w io.Writer, processes []ContainerProcess) {
+	for _, p := range processes {
+		name := p.Name
+		if name == "" {
+			name = "-"
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%.1f%%\t%s\t%s\t%s\n", p.PID, p.User, p.CPU, HumanBytes(p.RSS), name, p.Command)
+	}
+}
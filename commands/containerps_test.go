@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestContainerProcesses(t *testing.T) {
+	container := fakeContainer("web")
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		io.WriteString(processIO.Stdout, "1\troot\t0.0\t1048576\t\t/sbin/init\n")
+		io.WriteString(processIO.Stdout, "12\tvcap\t20.5\t3145728\tweb\tnginx -g daemon off;\n")
+		return new(fakes.FakeProcess), nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	helper := &Helper{Binary: []byte("x")}
+
+	processes, err := ContainerProcesses(fakeClient, "web", helper, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ContainerProcess{
+		{PID: 1, User: "root", RSS: 1 << 20, Command: "/sbin/init"},
+		{PID: 12, User: "vcap", CPU: 20.5, RSS: 3 << 20, Name: "web", Command: "nginx -g daemon off;"},
+	}
+
+	if !reflect.DeepEqual(processes, want) {
+		t.Errorf("listed %#v, want %#v", processes, want)
+	}
+
+	if spec, _ := container.RunArgsForCall(0); spec.Path != HelperPath || !reflect.DeepEqual(spec.Args, []string{"ps"}) {
+		t.Errorf("ran %#v", spec)
+	}
+
+	var buf bytes.Buffer
+	PrintContainerProcesses(&buf, processes)
+
+	if buf.String() != "1\troot\t0.0%\t1.0 MiB\t-\t/sbin/init\n12\tvcap\t20.5%\t3.0 MiB\tweb\tnginx -g daemon off;\n" {
+		t.Errorf("printed %q", buf.String())
+	}
+
+	processes, err = ContainerProcesses(fakeClient, "web", helper, true)
+	if err != nil || len(processes) != 1 || processes[0].Name != "web" {
+		t.Errorf("listed %v (%v), want only the named process", processes, err)
+	}
+}
+
+func TestContainerProcessesFailure(t *testing.T) {
+	process := new(fakes.FakeProcess)
+	process.WaitReturns(1, nil)
+
+	container := fakeContainer("web")
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		io.WriteString(processIO.Stderr, "gaol-helper ps: open /proc: no such file or directory\n")
+		return process, nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	_, err := ContainerProcesses(fakeClient, "web", &Helper{Binary: []byte("x")}, false)
+	if err == nil || err.Error() != "listing processes failed: gaol-helper ps: open /proc: no such file or directory" {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,416 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// The properties in which gaol records how it created a container.
+const (
+	// CreatedAtProperty holds when, in RFC 3339 format.
+	CreatedAtProperty = "gaol:created-at"
+
+	// CreatedByProperty holds who, as user@host.
+	CreatedByProperty = "gaol:created-by"
+
+	// RootFSProperty holds the rootfs asked for, if any.
+	RootFSProperty = "gaol:rootfs"
+)
+
+// IfExists says what creating a container does when one already has the
+// handle asked for.
+type IfExists int
+
+const (
+	// FailIfExists leaves the server to refuse to create the container.
+	FailIfExists IfExists = iota
+
+	// ReuseIfExists keeps the existing container instead.
+	ReuseIfExists
+
+	// ReplaceIfExists destroys the existing container and creates another.
+	ReplaceIfExists
+)
+
+// handleAttempts is how many random handles are tried for a container
+// created with a handle prefix before giving up.
+const handleAttempts = 5
+
+// handleSuffix returns the random part of a handle made from a prefix;
+// tests replace it.
+var handleSuffix = func() string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return hex.EncodeToString(suffix)
+}
+
+// CreateOptions describe how containers are created.
+type CreateOptions struct {
+	IfExists IfExists
+
+	// HandlePrefix, if given, has containers created without a handle
+	// given handles starting with it and ending in random characters,
+	// trying others if one is taken.
+	HandlePrefix string
+
+	// Limits are applied to each container created. A container which
+	// cannot be limited is destroyed.
+	Limits ManifestLimits
+
+	// Populate, if given, is called with each container created, for
+	// example to stream files into it. A container which cannot be
+	// populated is destroyed.
+	Populate func(garden.Container) error
+}
+
+// Create creates a container and writes its handle to w.
+func Create(client garden.Client, spec garden.ContainerSpec, opts CreateOptions, w io.Writer) error {
+	container, err := create(client, spec, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, container.Handle())
+	return nil
+}
+
+func create(client garden.Client, spec garden.ContainerSpec, opts CreateOptions) (garden.Container, error) {
+	if spec.Handle == "" && opts.HandlePrefix != "" {
+		return createWithPrefix(client, spec, opts)
+	}
+
+	if spec.Handle != "" && opts.IfExists != FailIfExists {
+		existing, err := client.Lookup(spec.Handle)
+		switch err.(type) {
+		case nil:
+			if opts.IfExists == ReuseIfExists {
+				return existing, nil
+			}
+
+			if err := client.Destroy(spec.Handle); err != nil {
+				return nil, err
+			}
+		case garden.ContainerNotFoundError:
+		default:
+			return nil, err
+		}
+	}
+
+	container, err := client.Create(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyLimits(container, opts.Limits); err != nil {
+		client.Destroy(container.Handle())
+		return nil, fmt.Errorf("limiting %s: %s", container.Handle(), err)
+	}
+
+	if opts.Populate != nil {
+		if err := opts.Populate(container); err != nil {
+			client.Destroy(container.Handle())
+			return nil, fmt.Errorf("populating %s: %s", container.Handle(), err)
+		}
+	}
+
+	return container, nil
+}
+
+// createWithPrefix creates a container with a random handle starting with
+// the prefix. A handle which is taken, whether before the container is
+// created or while it is, is given up for another.
+func createWithPrefix(client garden.Client, spec garden.ContainerSpec, opts CreateOptions) (garden.Container, error) {
+	prefix := opts.HandlePrefix
+	opts.HandlePrefix = ""
+
+	for attempt := 0; attempt < handleAttempts; attempt++ {
+		spec.Handle = prefix + handleSuffix()
+
+		_, err := client.Lookup(spec.Handle)
+		switch err.(type) {
+		case nil:
+			continue
+		case garden.ContainerNotFoundError:
+		default:
+			return nil, err
+		}
+
+		container, err := create(client, spec, opts)
+		if err == nil {
+			return container, nil
+		}
+
+		// another container was created with the handle in the meantime
+		if _, lookupErr := client.Lookup(spec.Handle); lookupErr == nil {
+			continue
+		}
+
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("no free handle starting with %s after %d tries", prefix, handleAttempts)
+}
+
+// StreamArchive extracts a tar archive, which may be gzipped, into the root
+// of the container.
+func StreamArchive(container garden.Container, archive string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r, err := gunzipped(file)
+	if err != nil {
+		return fmt.Errorf("reading %s: %s", archive, err)
+	}
+
+	return container.StreamIn("/", r)
+}
+
+// HandleTemplate renders the handles of containers created together from a
+// text/template such as "worker-{{.Index}}", in which Index counts from 1.
+// The template must give every container a different handle; an empty
+// template leaves the server to pick them.
+func HandleTemplate(text string, count int) ([]string, error) {
+	handles := make([]string, count)
+	if text == "" {
+		return handles, nil
+	}
+
+	tmpl, err := template.New("handle").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid handle template: %s", err)
+	}
+
+	seen := map[string]bool{}
+	for i := range handles {
+		var handle bytes.Buffer
+		if err := tmpl.Execute(&handle, struct{ Index int }{i + 1}); err != nil {
+			return nil, fmt.Errorf("invalid handle template: %s", err)
+		}
+
+		if seen[handle.String()] {
+			return nil, fmt.Errorf("handle template %q gives several containers the handle %q", text, handle.String())
+		}
+
+		seen[handle.String()] = true
+		handles[i] = handle.String()
+	}
+
+	return handles, nil
+}
+
+// CreateMany creates a container with each of the handles, at most
+// concurrency at a time, from an otherwise identical spec. The results hold
+// the handles of the containers created, which are made from the prefix or
+// picked by the server when the handle asked for is empty.
+func CreateMany(client garden.Client, spec garden.ContainerSpec, handles []string, opts CreateOptions, concurrency int) []Result {
+	return parallel(len(handles), concurrency, func(i int) Result {
+		spec := spec
+		spec.Handle = handles[i]
+
+		container, err := create(client, spec, opts)
+		if err != nil {
+			return Result{handles[i], err}
+		}
+
+		return Result{container.Handle(), nil}
+	})
+}
+
+// Destroy destroys the containers, stopping at the first which cannot be
+// destroyed.
+func Destroy(client garden.Client, handles []string) error {
+	for _, handle := range handles {
+		if err := client.Destroy(handle); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DestroyConcurrently destroys the containers, at most concurrency at a
+// time, and returns whether each was destroyed.
+func DestroyConcurrently(client garden.Client, handles []string, concurrency int) []Result {
+	return forEach(handles, concurrency, client.Destroy)
+}
+
+// Stop stops every process in the containers, stopping at the first which
+// cannot be stopped. Killing sends SIGKILL straight away rather than giving
+// the processes a chance to exit.
+func Stop(client garden.Client, handles []string, kill bool) error {
+	for _, handle := range handles {
+		container, err := client.Lookup(handle)
+		if err != nil {
+			return err
+		}
+
+		if err := container.Stop(kill); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// List writes the handle of every container to w, one per line.
+func List(client garden.Client, w io.Writer) error {
+	handles, err := ListHandles(client)
+	if err != nil {
+		return err
+	}
+
+	for _, handle := range handles {
+		fmt.Fprintln(w, handle)
+	}
+
+	return nil
+}
+
+// ListWide writes a tab-separated line for each of the handles: the handle,
+// the container's state and the events the server has recorded for it. The
+// handles of containers with events, such as running out of memory, are
+// shown in red. Containers which have gone in the meantime are left out.
+func ListWide(client garden.Client, handles []string, w io.Writer, format Format) error {
+	containers, err := client.Containers(nil)
+	if err != nil {
+		return err
+	}
+
+	wanted := map[string]bool{}
+	for _, handle := range handles {
+		wanted[handle] = true
+	}
+
+	listed := []garden.Container{}
+	for _, container := range containers {
+		if wanted[container.Handle()] {
+			listed = append(listed, container)
+		}
+	}
+
+	infos, _ := bulkInfo(listed)
+
+	for _, handle := range handles {
+		info, found := infos[handle]
+		if !found {
+			continue
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", format.Problem(handle, info.Events), format.State(info.State), strings.Join(info.Events, ", "))
+	}
+
+	return nil
+}
+
+// WatchList keeps the list of containers on w up to date, refreshing it
+// every interval. With lines it writes the containers which come and go as
+// JSON lines instead.
+func WatchList(client garden.Client, w io.Writer, interval time.Duration, format Format, lines *JSONLines) error {
+	render := func() ([]string, error) {
+		return ListHandles(client)
+	}
+
+	if lines != nil {
+		return watchJSON(lines, interval, func(line string) string { return line }, render)
+	}
+
+	return watch(w, interval, "gaol list", format, render)
+}
+
+// Info writes the information about a container to w, one field per line.
+func Info(client garden.Client, handle string, w io.Writer, format Format) error {
+	lines, err := renderInfo(client, handle, format)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+
+	return nil
+}
+
+// WatchInfo keeps the information about a container on w up to date,
+// refreshing it every interval. With lines it writes the fields which change
+// as JSON lines instead.
+func WatchInfo(client garden.Client, handle string, w io.Writer, interval time.Duration, format Format, lines *JSONLines) error {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	if lines != nil {
+		// colors have no place in JSON
+		format = Format{}
+	}
+
+	render := func() ([]string, error) {
+		info, err := container.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		return infoLines(info, format), nil
+	}
+
+	if lines != nil {
+		return watchJSON(lines, interval, func(string) string { return handle }, render)
+	}
+
+	return watch(w, interval, "gaol info "+handle, format, render)
+}
+
+func renderInfo(client garden.Client, handle string, format Format) ([]string, error) {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := container.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	if format.Porcelain {
+		return porcelainInfoLines(handle, info), nil
+	}
+
+	return infoLines(info, format), nil
+}
+
+// NetIn maps a port on the Garden host to the container port and writes
+// the address it can be reached on, given the host's name, to w. Without a
+// host name only the port is written.
+func NetIn(client garden.Client, handle string, gardenHost string, containerPort uint32, w io.Writer) error {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	hostPort, _, err := container.NetIn(0, containerPort)
+	if err != nil {
+		return err
+	}
+
+	if gardenHost == "" {
+		fmt.Fprintln(w, hostPort)
+		return nil
+	}
+
+	fmt.Fprintln(w, net.JoinHostPort(gardenHost, fmt.Sprintf("%d", hostPort)))
+	return nil
+}
@@ -0,0 +1,426 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func fakeContainer(handle string) *fakes.FakeContainer {
+	container := new(fakes.FakeContainer)
+	container.HandleReturns(handle)
+	return container
+}
+
+func TestHandleTemplate(t *testing.T) {
+	handles, err := HandleTemplate("worker-{{.Index}}", 3)
+	if err != nil || !reflect.DeepEqual(handles, []string{"worker-1", "worker-2", "worker-3"}) {
+		t.Errorf("rendered %v (%v)", handles, err)
+	}
+
+	handles, err = HandleTemplate("", 2)
+	if err != nil || !reflect.DeepEqual(handles, []string{"", ""}) {
+		t.Errorf("rendered %v (%v), want the server to pick", handles, err)
+	}
+
+	for _, text := range []string{"worker", "worker-{{.Index", "worker-{{.Name}}"} {
+		if _, err := HandleTemplate(text, 2); err == nil {
+			t.Errorf("expected %q to be rejected", text)
+		}
+	}
+}
+
+func TestCreateMany(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+
+	var mutex sync.Mutex
+	created := 0
+	fakeClient.CreateStub = func(spec garden.ContainerSpec) (garden.Container, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		created++
+		return fakeContainer(fmt.Sprintf("generated-%d", created)), nil
+	}
+
+	results := CreateMany(fakeClient, garden.ContainerSpec{Privileged: true}, []string{"", ""}, CreateOptions{}, 2)
+	if len(Failures(results)) != 0 {
+		t.Fatalf("failed %v", Failures(results))
+	}
+
+	handles := []string{results[0].Handle, results[1].Handle}
+	sort.Strings(handles)
+	if !reflect.DeepEqual(handles, []string{"generated-1", "generated-2"}) {
+		t.Errorf("created %v, want the handles the server picked", handles)
+	}
+
+	if !fakeClient.CreateArgsForCall(0).Privileged || !fakeClient.CreateArgsForCall(1).Privileged {
+		t.Error("expected every container to be created from the spec")
+	}
+}
+
+func TestCreateIfExists(t *testing.T) {
+	tests := []struct {
+		ifExists  IfExists
+		exists    bool
+		destroyed bool
+		created   bool
+	}{
+		{FailIfExists, true, false, true},
+		{ReuseIfExists, true, false, false},
+		{ReuseIfExists, false, false, true},
+		{ReplaceIfExists, true, true, true},
+		{ReplaceIfExists, false, false, true},
+	}
+
+	for _, test := range tests {
+		fakeClient := new(fakes.FakeClient)
+		fakeClient.CreateReturns(fakeContainer("web"), nil)
+
+		if test.exists {
+			fakeClient.LookupReturns(fakeContainer("web"), nil)
+		} else {
+			fakeClient.LookupReturns(nil, garden.ContainerNotFoundError{Handle: "web"})
+		}
+
+		var buf bytes.Buffer
+		if err := Create(fakeClient, garden.ContainerSpec{Handle: "web"}, CreateOptions{IfExists: test.ifExists}, &buf); err != nil {
+			t.Errorf("%+v: %s", test, err)
+			continue
+		}
+
+		if buf.String() != "web\n" {
+			t.Errorf("%+v: printed %q", test, buf.String())
+		}
+
+		if destroyed := fakeClient.DestroyCallCount() == 1; destroyed != test.destroyed {
+			t.Errorf("%+v: destroyed %t", test, destroyed)
+		}
+
+		if created := fakeClient.CreateCallCount() == 1; created != test.created {
+			t.Errorf("%+v: created %t", test, created)
+		}
+	}
+}
+
+func TestCreateWithHandlePrefix(t *testing.T) {
+	defer func(suffix func() string) { handleSuffix = suffix }(handleSuffix)
+
+	suffixes := []string{"aaaa", "bbbb", "cccc"}
+	handleSuffix = func() string {
+		suffix := suffixes[0]
+		suffixes = suffixes[1:]
+		return suffix
+	}
+
+	// ci-aaaa is taken, and ci-bbbb is taken while creating it
+	existing := map[string]bool{"ci-aaaa": true}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupStub = func(handle string) (garden.Container, error) {
+		if existing[handle] {
+			return fakeContainer(handle), nil
+		}
+
+		return nil, garden.ContainerNotFoundError{Handle: handle}
+	}
+	fakeClient.CreateStub = func(spec garden.ContainerSpec) (garden.Container, error) {
+		if spec.Handle == "ci-bbbb" {
+			existing[spec.Handle] = true
+			return nil, errors.New("handle already exists: ci-bbbb")
+		}
+
+		return fakeContainer(spec.Handle), nil
+	}
+
+	var buf bytes.Buffer
+	if err := Create(fakeClient, garden.ContainerSpec{}, CreateOptions{HandlePrefix: "ci-"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != "ci-cccc\n" {
+		t.Errorf("printed %q", buf.String())
+	}
+
+	if fakeClient.CreateCallCount() != 2 {
+		t.Errorf("created %d containers, want 2 tries", fakeClient.CreateCallCount())
+	}
+}
+
+func TestCreateWithHandlePrefixGivesUp(t *testing.T) {
+	defer func(suffix func() string) { handleSuffix = suffix }(handleSuffix)
+	handleSuffix = func() string { return "aaaa" }
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(fakeContainer("ci-aaaa"), nil)
+
+	err := Create(fakeClient, garden.ContainerSpec{}, CreateOptions{HandlePrefix: "ci-"}, new(bytes.Buffer))
+	if err == nil || fakeClient.CreateCallCount() != 0 {
+		t.Errorf("failed with %v after %d creates, want to give up", err, fakeClient.CreateCallCount())
+	}
+
+	if fakeClient.LookupCallCount() != handleAttempts {
+		t.Errorf("tried %d handles", fakeClient.LookupCallCount())
+	}
+}
+
+func TestCreateWithHandlePrefixFailure(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(nil, garden.ContainerNotFoundError{})
+	fakeClient.CreateReturns(nil, errors.New("out of memory"))
+
+	err := Create(fakeClient, garden.ContainerSpec{}, CreateOptions{HandlePrefix: "ci-"}, new(bytes.Buffer))
+	if err == nil || err.Error() != "out of memory" || fakeClient.CreateCallCount() != 1 {
+		t.Errorf("failed with %v after %d creates, want not to retry", err, fakeClient.CreateCallCount())
+	}
+
+	if handle := fakeClient.CreateArgsForCall(0).Handle; len(handle) != len("ci-")+8 || handle[:3] != "ci-" {
+		t.Errorf("created %q", handle)
+	}
+}
+
+func TestCreatePopulateFailure(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.CreateReturns(fakeContainer("web"), nil)
+
+	opts := CreateOptions{
+		Populate: func(container garden.Container) error {
+			return errors.New("no space left")
+		},
+	}
+
+	var buf bytes.Buffer
+	err := Create(fakeClient, garden.ContainerSpec{Handle: "web"}, opts, &buf)
+	if err == nil || err.Error() != "populating web: no space left" {
+		t.Errorf("failed with %v", err)
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("printed %q for a container which was not populated", buf.String())
+	}
+
+	if fakeClient.DestroyCallCount() != 1 || fakeClient.DestroyArgsForCall(0) != "web" {
+		t.Error("expected the half-made container to be destroyed")
+	}
+}
+
+func TestStreamArchiveGunzips(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gaol-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "rootfs.tar.gz")
+	ioutil.WriteFile(archive, layer(t, layerEntry{name: "etc/motd", contents: "hello"}), 0644)
+
+	var streamed []byte
+	container := fakeContainer("web")
+	container.StreamInStub = func(dst string, r io.Reader) error {
+		if dst != "/" {
+			t.Errorf("streamed into %q", dst)
+		}
+
+		streamed, _ = ioutil.ReadAll(r)
+		return nil
+	}
+
+	if err := StreamArchive(container, archive); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := archiveContents(t, streamed); !reflect.DeepEqual(got, []string{"etc/motd=hello"}) {
+		t.Errorf("streamed %q", got)
+	}
+}
+
+func TestDestroyStopsAtFirstFailure(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.DestroyStub = func(handle string) error {
+		if handle == "b" {
+			return errors.New("boom")
+		}
+
+		return nil
+	}
+
+	if err := Destroy(fakeClient, []string{"a", "b", "c"}); err == nil {
+		t.Error("expected the failure to destroy b")
+	}
+
+	if fakeClient.DestroyCallCount() != 2 {
+		t.Errorf("destroyed %d containers, want 2", fakeClient.DestroyCallCount())
+	}
+}
+
+func TestDestroyConcurrently(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+
+	var mutex sync.Mutex
+	inflight, most := 0, 0
+	fakeClient.DestroyStub = func(handle string) error {
+		mutex.Lock()
+		inflight++
+		if inflight > most {
+			most = inflight
+		}
+		mutex.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mutex.Lock()
+		inflight--
+		mutex.Unlock()
+
+		if handle == "c" {
+			return errors.New("boom")
+		}
+
+		return nil
+	}
+
+	handles := []string{"a", "b", "c", "d", "e"}
+	results := DestroyConcurrently(fakeClient, handles, 2)
+
+	if most > 2 {
+		t.Errorf("destroyed %d containers at once, want at most 2", most)
+	}
+
+	for i, result := range results {
+		if result.Handle != handles[i] {
+			t.Errorf("result %d is for %q, want %q", i, result.Handle, handles[i])
+		}
+	}
+
+	failures := Failures(results)
+	if len(failures) != 1 || failures[0].Handle != "c" {
+		t.Errorf("failed %v, want only c", failures)
+	}
+
+	var out bytes.Buffer
+	PrintResults(&out, "destroyed", results[1:3])
+	if out.String() != "destroyed\tb\nfailed\tc\tboom\n" {
+		t.Errorf("printed %q", out.String())
+	}
+}
+
+func TestInfo(t *testing.T) {
+	container := fakeContainer("a")
+	container.InfoReturns(garden.ContainerInfo{
+		State:       "active",
+		HostIP:      "10.0.0.1",
+		ContainerIP: "10.0.0.2",
+		ProcessIDs:  []uint32{3, 4},
+		MemoryStat: garden.ContainerMemoryStat{
+			TotalRss: 2 << 20,
+		},
+		CPUStat: garden.ContainerCPUStat{
+			Usage: uint64(90 * time.Second),
+			User:  uint64(1500 * time.Millisecond),
+		},
+		DiskStat: garden.ContainerDiskStat{
+			BytesUsed:  1536,
+			InodesUsed: 12,
+		},
+		MappedPorts: []garden.PortMapping{{HostPort: 61001, ContainerPort: 8080}},
+		Properties:  garden.Properties{"b": "2", "a": "1"},
+	}, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	var buf bytes.Buffer
+	if err := Info(fakeClient, "a", &buf, Format{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `state: active
+host ip: 10.0.0.1
+container ip: 10.0.0.2
+external ip: 
+container path: 
+processes: 3, 4
+events: 
+memory: 2.0 MiB rss, 0 B cache, 0 B limit
+cpu: 1m30s usage, 1.5s user, 0ms system
+disk: 1.5 KiB used, 12 inodes
+bandwidth: 0 B/s in rate, 0 B in burst, 0 B/s out rate, 0 B out burst
+port: 61001 -> 8080
+property: a=1
+property: b=2
+`
+
+	if buf.String() != want {
+		t.Errorf("printed:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestListWide(t *testing.T) {
+	container := func(handle string, state string, events ...string) garden.Container {
+		c := fakeContainer(handle)
+		c.InfoReturns(garden.ContainerInfo{State: state, Events: events}, nil)
+		return c
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersReturns([]garden.Container{
+		container("web", "active"),
+		container("db", "active", "out of memory", "oom killed"),
+		container("new", "born"),
+	}, nil)
+
+	var buf bytes.Buffer
+	if err := ListWide(fakeClient, []string{"new", "db", "gone"}, &buf, Format{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "new\tborn\t\ndb\tactive\tout of memory, oom killed\n"; buf.String() != want {
+		t.Errorf("listed %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	ListWide(fakeClient, []string{"db"}, &buf, Format{Color: true})
+
+	if want := "\033[31mdb\033[0m\t\033[32mactive\033[0m\tout of memory, oom killed\n"; buf.String() != want {
+		t.Errorf("listed %q, want %q", buf.String(), want)
+	}
+}
+
+func TestInfoNotFound(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(nil, garden.ContainerNotFoundError{Handle: "a"})
+
+	err := Info(fakeClient, "a", new(bytes.Buffer), Format{})
+	if _, ok := err.(garden.ContainerNotFoundError); !ok {
+		t.Errorf("got %v, want the container not to be found", err)
+	}
+}
+
+func TestNetIn(t *testing.T) {
+	container := fakeContainer("a")
+	container.NetInReturns(61001, 8080, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	var buf bytes.Buffer
+	if err := NetIn(fakeClient, "a", "::1", 8080, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != "[::1]:61001\n" {
+		t.Errorf("printed %q", buf.String())
+	}
+}
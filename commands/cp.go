@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// CopyIn streams the local file or directory src to dst in every one of the
+// containers at once, reading src only once however many there are. A file
+// is written to dst, or into dst keeping its name if dst ends in a slash,
+// and a directory is streamed into the directory dst as StreamInTree does.
+// Containers which fail to take their copy do not hold up the rest, but a
+// slow one slows them all, as they are fed together.
+func CopyIn(client garden.Client, handles []string, src string, dst string, opts TreeOptions, attrs Attributes) []Result {
+	info, err := os.Stat(src)
+	if err != nil {
+		return failAll(handles, err)
+	}
+
+	dir := dst
+	write := func(w io.Writer) error {
+		return tarTree(w, src, opts, attrs)
+	}
+
+	if !info.IsDir() {
+		name := filepath.Base(src)
+		if !strings.HasSuffix(dst, "/") {
+			dir, name = path.Dir(dst), path.Base(dst)
+		}
+
+		write = func(w io.Writer) error {
+			file, err := os.Open(src)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			return tarFile(w, name, file, info.Size(), attrs)
+		}
+	}
+
+	results := make([]Result, len(handles))
+	writers := []io.Writer{}
+	closers := []*io.PipeWriter{}
+
+	var wg sync.WaitGroup
+	for i, handle := range handles {
+		results[i].Handle = handle
+
+		container, err := client.Lookup(handle)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		reader, writer := io.Pipe()
+		writers = append(writers, writer)
+		closers = append(closers, writer)
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			results[i].Err = container.StreamIn(dir, reader)
+
+			// a container which gave up early must not stop the rest being
+			// fed
+			io.Copy(ioutil.Discard, reader)
+		}(i)
+	}
+
+	if len(writers) > 0 {
+		err := write(io.MultiWriter(writers...))
+		for _, closer := range closers {
+			closer.CloseWithError(err)
+		}
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// failAll fails every container with the error.
+func failAll(handles []string, err error) []Result {
+	results := []Result{}
+	for _, handle := range handles {
+		results = append(results, Result{handle, err})
+	}
+
+	return results
+}
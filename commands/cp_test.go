@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestCopyIn(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gaol-cp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "build.tgz")
+	ioutil.WriteFile(src, []byte(strings.Repeat("x", 1<<20)), 0644)
+
+	var mu sync.Mutex
+	received := map[string]string{}
+
+	containers := map[string]*fakes.FakeContainer{}
+	for _, handle := range []string{"web-1", "web-2", "web-3"} {
+		container := fakeContainer(handle)
+		containers[handle] = container
+
+		handle := handle
+		container.StreamInStub = func(dst string, r io.Reader) error {
+			// this one gives up without reading anything
+			if handle == "web-2" {
+				return errors.New("disk full")
+			}
+
+			tr := tar.NewReader(r)
+			header, err := tr.Next()
+			if err != nil {
+				return err
+			}
+
+			n, err := io.Copy(ioutil.Discard, tr)
+
+			mu.Lock()
+			received[handle] = dst + " " + header.Name + " " + HumanBytes(uint64(n))
+			mu.Unlock()
+
+			return err
+		}
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupStub = func(handle string) (garden.Container, error) {
+		if container, found := containers[handle]; found {
+			return container, nil
+		}
+
+		return nil, errors.New("container not found: " + handle)
+	}
+
+	results := CopyIn(fakeClient, []string{"web-1", "web-2", "web-3", "gone"}, src, "/app/", TreeOptions{}, KeepAttributes)
+
+	errs := []string{}
+	for _, result := range results {
+		errs = append(errs, result.Handle+"="+errString(result.Err))
+	}
+
+	if strings.Join(errs, " ") != "web-1= web-2=disk full web-3= gone=container not found: gone" {
+		t.Errorf("got %v", errs)
+	}
+
+	for _, handle := range []string{"web-1", "web-3"} {
+		if received[handle] != "/app/ build.tgz 1.0 MiB" {
+			t.Errorf("%s received %q", handle, received[handle])
+		}
+	}
+
+	CopyIn(fakeClient, []string{"web-1"}, src, "/app/release.tgz", TreeOptions{}, KeepAttributes)
+	if received["web-1"] != "/app release.tgz 1.0 MiB" {
+		t.Errorf("received %q, want the file renamed", received["web-1"])
+	}
+}
+
+func TestCopyInMissingSource(t *testing.T) {
+	container := fakeContainer("web-1")
+
+	results := CopyIn(streamInClient(container), []string{"web-1", "web-2"}, "/nonexistent/build.tgz", "/app/", TreeOptions{}, KeepAttributes)
+	if len(Failures(results)) != 2 || container.StreamInCallCount() != 0 {
+		t.Errorf("got %v", results)
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// CurlRequest is an HTTP request made to a server in a container.
+type CurlRequest struct {
+	Method  string
+	Headers []string
+	Data    string
+
+	// Include writes the status line and headers of the response before
+	// its body.
+	Include bool
+
+	// Insecure skips verifying the certificate of an https server.
+	Insecure bool
+
+	Timeout time.Duration
+}
+
+// Curl makes an HTTP request to a server listening in the container, given a
+// URL as it would be from inside it, such as http://localhost:8080/health.
+// The request is sent to the host port mapped to the server's port on
+// gardenHost, mapping one if there is none, so that nothing needs to be
+// installed in the container. The response is written to w and returned,
+// with its body already read.
+func Curl(container garden.Container, gardenHost string, rawURL string, request CurlRequest, w io.Writer) (*http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %q: must be http or https", u.Scheme)
+	}
+
+	info, err := container.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	host, portString, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		host = u.Host
+		portString = "80"
+		if u.Scheme == "https" {
+			portString = "443"
+		}
+	}
+
+	if host != "localhost" && host != info.ContainerIP && !isLoopback(host) {
+		return nil, fmt.Errorf("cannot reach %s: only the container's own servers can be reached", host)
+	}
+
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port in %s", rawURL)
+	}
+
+	hostPort, err := mappedPort(container, info, uint32(port))
+	if err != nil {
+		return nil, err
+	}
+
+	upstream := *u
+	upstream.Host = net.JoinHostPort(gardenHost, strconv.FormatUint(uint64(hostPort), 10))
+
+	method := request.Method
+	if method == "" {
+		method = "GET"
+		if request.Data != "" {
+			method = "POST"
+		}
+	}
+
+	var body io.Reader
+	if request.Data != "" {
+		body = strings.NewReader(request.Data)
+	}
+
+	req, err := http.NewRequest(method, upstream.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	// the server sees the request as it was given
+	req.Host = u.Host
+
+	for _, header := range request.Headers {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid header %q: must be Name: value", header)
+		}
+
+		req.Header.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+
+	if request.Data != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	client := &http.Client{
+		Timeout: request.Timeout,
+		Transport: &http.Transport{
+			// the certificate is checked against the name in the url
+			// rather than the garden host the request goes to
+			TLSClientConfig: &tls.Config{
+				ServerName:         host,
+				InsecureSkipVerify: request.Insecure,
+			},
+		},
+	}
+
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if request.Include {
+		fmt.Fprintf(w, "%s %s\r\n", response.Proto, response.Status)
+		response.Header.Write(w)
+		fmt.Fprint(w, "\r\n")
+	}
+
+	_, err = io.Copy(w, response.Body)
+	return response, err
+}
+
+func isLoopback(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// mappedPort returns the host port mapped to the port in the container,
+// mapping one if there is none.
+func mappedPort(container garden.Container, info garden.ContainerInfo, port uint32) (uint32, error) {
+	for _, mapping := range info.MappedPorts {
+		if mapping.ContainerPort == port {
+			return mapping.HostPort, nil
+		}
+	}
+
+	hostPort, _, err := container.NetIn(0, port)
+	return hostPort, err
+}
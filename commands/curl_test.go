@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// serverPort is the port an httptest server listens on.
+func serverPort(t *testing.T, server *httptest.Server) uint32 {
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, _ := strconv.ParseUint(port, 10, 16)
+	return uint32(p)
+}
+
+func TestCurl(t *testing.T) {
+	var received *http.Request
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		contents, _ := ioutil.ReadAll(r.Body)
+		body = string(contents)
+
+		w.Header().Set("X-Served-By", "web")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("healthy\n"))
+	}))
+	defer server.Close()
+
+	container := fakeContainer("web")
+	container.InfoReturns(garden.ContainerInfo{
+		MappedPorts: []garden.PortMapping{{HostPort: serverPort(t, server), ContainerPort: 8080}},
+	}, nil)
+
+	request := CurlRequest{
+		Headers: []string{"Accept: text/plain"},
+		Data:    "check=deep",
+		Include: true,
+		Timeout: time.Second,
+	}
+
+	var out bytes.Buffer
+	response, err := Curl(container, "127.0.0.1", "http://localhost:8080/health?v=1", request, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.StatusCode != http.StatusCreated {
+		t.Errorf("responded %s", response.Status)
+	}
+
+	if received.Method != "POST" || received.Host != "localhost:8080" || received.URL.String() != "/health?v=1" {
+		t.Errorf("requested %s %s%s", received.Method, received.Host, received.URL)
+	}
+
+	if received.Header.Get("Accept") != "text/plain" || body != "check=deep" {
+		t.Errorf("sent %v with %q", received.Header, body)
+	}
+
+	if !strings.HasPrefix(out.String(), "HTTP/1.1 201 Created\r\n") || !strings.Contains(out.String(), "X-Served-By: web\r\n") || !strings.HasSuffix(out.String(), "\r\n\r\nhealthy\n") {
+		t.Errorf("printed %q", out.String())
+	}
+
+	if container.NetInCallCount() != 0 {
+		t.Error("mapped a port which was already mapped")
+	}
+}
+
+func TestCurlMapsPort(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	container := fakeContainer("web")
+	container.NetInReturns(serverPort(t, server), 80, nil)
+
+	var out bytes.Buffer
+	if _, err := Curl(container, "127.0.0.1", "http://127.0.0.1/", CurlRequest{}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if hostPort, containerPort := container.NetInArgsForCall(0); hostPort != 0 || containerPort != 80 {
+		t.Errorf("mapped %d -> %d", hostPort, containerPort)
+	}
+
+	if out.String() != "ok" {
+		t.Errorf("printed %q", out.String())
+	}
+}
+
+func TestCurlErrors(t *testing.T) {
+	container := fakeContainer("web")
+	container.InfoReturns(garden.ContainerInfo{ContainerIP: "10.254.0.2"}, nil)
+
+	for _, rawURL := range []string{
+		"ftp://localhost/",
+		"http://example.com/",
+		"http://localhost:http/",
+	} {
+		if _, err := Curl(container, "127.0.0.1", rawURL, CurlRequest{}, ioutil.Discard); err == nil {
+			t.Errorf("%s: expected an error", rawURL)
+		}
+	}
+
+	if _, err := Curl(container, "127.0.0.1", "http://localhost/", CurlRequest{Headers: []string{"no colon"}}, ioutil.Discard); err == nil {
+		t.Error("expected an invalid header to be rejected")
+	}
+}
@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// DiskUsage is how much disk a container uses, as the server counts it, and
+// how that is spread over the entries of a directory in it.
+type DiskUsage struct {
+	Used   uint64
+	Inodes uint64
+
+	// Limit is the container's hard disk limit, or zero if it has none.
+	Limit uint64
+
+	// Entries are the largest first.
+	Entries []DiskUsageEntry
+}
+
+// DiskUsageEntry is the bytes of the files under a path in the container.
+type DiskUsageEntry struct {
+	Path  string
+	Bytes uint64
+}
+
+// DiskFree reports the disk usage of the container, broken down by the
+// entries of dir with the helper's du.
+func DiskFree(client garden.Client, handle string, helper *Helper, dir string) (DiskUsage, error) {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return DiskUsage{}, err
+	}
+
+	info, err := container.Info()
+	if err != nil {
+		return DiskUsage{}, err
+	}
+
+	usage := DiskUsage{
+		Used:   info.DiskStat.BytesUsed,
+		Inodes: info.DiskStat.InodesUsed,
+	}
+
+	if limits, err := container.CurrentDiskLimits(); err == nil {
+		usage.Limit = limits.ByteHard
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	process, err := RunHelper(container, helper, []string{"du", dir}, garden.ProcessIO{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return usage, err
+	}
+
+	status, err := process.Wait()
+	if err != nil {
+		return usage, err
+	}
+
+	if status != 0 {
+		return usage, fmt.Errorf("measuring %s failed: %s", dir, strings.TrimSpace(stderr.String()))
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		fields := strings.SplitN(line, "\t", 2)
+		if len(fields) != 2 {
+			return usage, fmt.Errorf("unexpected line from du: %q", line)
+		}
+
+		size, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return usage, fmt.Errorf("unexpected line from du: %q", line)
+		}
+
+		usage.Entries = append(usage.Entries, DiskUsageEntry{Path: fields[1], Bytes: size})
+	}
+
+	sort.Stable(byBytes(usage.Entries))
+
+	return usage, nil
+}
+
+type byBytes []DiskUsageEntry
+
+func (e byBytes) Len() int           { return len(e) }
+func (e byBytes) Less(i, j int) bool { return e[i].Bytes > e[j].Bytes }
+func (e byBytes) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+
+// PrintDiskUsage writes the container's usage, against its limit if it has
+// one, and then a tab-separated line per entry.
+func PrintDiskUsage(w io.Writer, usage DiskUsage) {
+	if usage.Limit > 0 {
+		fmt.Fprintf(w, "used: %s of %s (%.0f%%), %d inodes\n", HumanBytes(usage.Used), HumanBytes(usage.Limit), float64(usage.Used)/float64(usage.Limit)*100, usage.Inodes)
+	} else {
+		fmt.Fprintf(w, "used: %s, %d inodes\n", HumanBytes(usage.Used), usage.Inodes)
+	}
+
+	for _, entry := range usage.Entries {
+		fmt.Fprintf(w, "%s\t%s\n", HumanBytes(entry.Bytes), entry.Path)
+	}
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestDiskFree(t *testing.T) {
+	container := fakeContainer("web")
+	container.InfoReturns(garden.ContainerInfo{
+		DiskStat: garden.ContainerDiskStat{BytesUsed: 768 << 20, InodesUsed: 4096},
+	}, nil)
+	container.CurrentDiskLimitsReturns(garden.DiskLimits{ByteHard: 1 << 30}, nil)
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		io.WriteString(processIO.Stdout, "1048576\t/etc\n536870912\t/var\n0\t/tmp\n")
+		return new(fakes.FakeProcess), nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	usage, err := DiskFree(fakeClient, "web", &Helper{Binary: []byte("x")}, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec, _ := container.RunArgsForCall(0); !reflect.DeepEqual(spec.Args, []string{"du", "/"}) {
+		t.Errorf("ran %#v", spec)
+	}
+
+	var buf bytes.Buffer
+	PrintDiskUsage(&buf, usage)
+
+	want := "used: 768.0 MiB of 1.0 GiB (75%), 4096 inodes\n512.0 MiB\t/var\n1.0 MiB\t/etc\n0 B\t/tmp\n"
+	if buf.String() != want {
+		t.Errorf("printed %q, want %q", buf.String(), want)
+	}
+}
+
+func TestPrintDiskUsageWithoutLimit(t *testing.T) {
+	var buf bytes.Buffer
+	PrintDiskUsage(&buf, DiskUsage{Used: 2048, Inodes: 3})
+
+	if buf.String() != "used: 2.0 KiB, 3 inodes\n" {
+		t.Errorf("printed %q", buf.String())
+	}
+}
@@ -0,0 +1,237 @@
+package commands
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// Kinds of difference between two trees.
+const (
+	diffAdded   = "added"
+	diffRemoved = "removed"
+	diffChanged = "changed"
+)
+
+// treeEntry is what is compared of a file, directory or link.
+type treeEntry struct {
+	kind string
+	mode os.FileMode
+	size int64
+	sum  string
+	link string
+}
+
+// Difference is a path which is only in one of two trees, or differs
+// between them. Detail says how a changed path differs.
+type Difference struct {
+	Change string
+	Path   string
+	Detail string
+}
+
+// Tree is a directory to be compared, either in a container or, when
+// Container is nil, on this machine.
+type Tree struct {
+	Container garden.Container
+	Dir       string
+}
+
+func (t Tree) read() (map[string]treeEntry, error) {
+	if t.Container == nil {
+		return localTree(t.Dir)
+	}
+
+	return containerTree(t.Container, t.Dir)
+}
+
+// Diff compares the files under two directories, reporting the paths, under
+// the second, which it added, removed or changed relative to the first.
+func Diff(a Tree, b Tree) ([]Difference, error) {
+	before, err := a.read()
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := b.read()
+	if err != nil {
+		return nil, err
+	}
+
+	differences := []Difference{}
+
+	for name, entry := range before {
+		other, found := after[name]
+		if !found {
+			differences = append(differences, Difference{diffRemoved, path.Join(b.Dir, name), ""})
+			continue
+		}
+
+		if detail := compareEntries(entry, other); detail != "" {
+			differences = append(differences, Difference{diffChanged, path.Join(b.Dir, name), detail})
+		}
+	}
+
+	for name := range after {
+		if _, found := before[name]; !found {
+			differences = append(differences, Difference{diffAdded, path.Join(b.Dir, name), ""})
+		}
+	}
+
+	sort.Sort(byDifferencePath(differences))
+
+	return differences, nil
+}
+
+// compareEntries describes how two entries differ, if they do.
+func compareEntries(a, b treeEntry) string {
+	switch {
+	case a.kind != b.kind:
+		return fmt.Sprintf("%s -> %s", a.kind, b.kind)
+	case a.link != b.link:
+		return fmt.Sprintf("target %s -> %s", a.link, b.link)
+	case a.size != b.size:
+		return fmt.Sprintf("size %d -> %d", a.size, b.size)
+	case a.sum != b.sum:
+		return "content"
+	case a.mode.Perm() != b.mode.Perm():
+		return fmt.Sprintf("mode %04o -> %04o", a.mode.Perm(), b.mode.Perm())
+	}
+
+	return ""
+}
+
+type byDifferencePath []Difference
+
+func (d byDifferencePath) Len() int           { return len(d) }
+func (d byDifferencePath) Less(i, j int) bool { return d[i].Path < d[j].Path }
+func (d byDifferencePath) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// containerTree streams out the contents of dir, checksumming its files as
+// they go past.
+func containerTree(container garden.Container, dir string) (map[string]treeEntry, error) {
+	// a trailing slash streams out what is in the directory rather than
+	// the directory itself
+	output, err := container.StreamOut(strings.TrimSuffix(dir, "/") + "/")
+	if err != nil {
+		return nil, err
+	}
+	defer output.Close()
+
+	entries := map[string]treeEntry{}
+
+	tr := tar.NewReader(output)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%s in %s: %s", dir, container.Handle(), err)
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if name == "." {
+			continue
+		}
+
+		entry := treeEntry{mode: os.FileMode(header.Mode)}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			entry.kind = "dir"
+		case tar.TypeSymlink:
+			entry.kind = "link"
+			entry.link = header.Linkname
+		case tar.TypeReg, tar.TypeRegA:
+			entry.kind = "file"
+			entry.size = header.Size
+
+			entry.sum, err = checksum(tr)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			entry.kind = "other"
+		}
+
+		entries[name] = entry
+	}
+}
+
+// localTree walks dir on this machine, checksumming its files.
+func localTree(dir string) (map[string]treeEntry, error) {
+	entries := map[string]treeEntry{}
+
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		entry := treeEntry{mode: info.Mode()}
+
+		switch {
+		case info.IsDir():
+			entry.kind = "dir"
+		case info.Mode()&os.ModeSymlink != 0:
+			entry.kind = "link"
+			entry.link, err = os.Readlink(p)
+		case info.Mode().IsRegular():
+			entry.kind = "file"
+			entry.size = info.Size()
+			entry.sum, err = checksumFile(p)
+		default:
+			entry.kind = "other"
+		}
+
+		entries[filepath.ToSlash(rel)] = entry
+		return err
+	})
+
+	return entries, err
+}
+
+func checksum(r io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+func checksumFile(p string) (string, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	return checksum(file)
+}
+
+// PrintDifferences writes a tab-separated line per difference: how the path
+// differs, the path, and for changes what changed.
+func PrintDifferences(w io.Writer, differences []Difference) {
+	for _, d := range differences {
+		if d.Detail == "" {
+			fmt.Fprintf(w, "%s\t%s\n", d.Change, d.Path)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", d.Change, d.Path, d.Detail)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+type tarEntry struct {
+	name     string
+	contents string
+	link     string
+	mode     int64
+}
+
+// streamsOut makes the container stream out a tar of the entries.
+func streamsOut(container *fakes.FakeContainer, entries ...tarEntry) {
+	var buf bytes.Buffer
+
+	tw := tar.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: entry.mode, Size: int64(len(entry.contents))}
+
+		switch {
+		case entry.link != "":
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = entry.link
+		case entry.name[len(entry.name)-1] == '/':
+			header.Typeflag = tar.TypeDir
+		default:
+			header.Typeflag = tar.TypeReg
+		}
+
+		tw.WriteHeader(header)
+		tw.Write([]byte(entry.contents))
+	}
+	tw.Close()
+
+	container.StreamOutReturns(ioutil.NopCloser(&buf), nil)
+}
+
+func TestDiff(t *testing.T) {
+	before := fakeContainer("a")
+	streamsOut(before,
+		tarEntry{name: "./", mode: 0755},
+		tarEntry{name: "./app/", mode: 0755},
+		tarEntry{name: "./app/server", contents: "v1", mode: 0755},
+		tarEntry{name: "./app/config", contents: "port: 80", mode: 0644},
+		tarEntry{name: "./app/old.log", contents: "", mode: 0644},
+		tarEntry{name: "./current", link: "app", mode: 0777},
+		tarEntry{name: "./secret", contents: "x", mode: 0644},
+	)
+
+	after := fakeContainer("b")
+	streamsOut(after,
+		tarEntry{name: "./", mode: 0755},
+		tarEntry{name: "./app/", mode: 0755},
+		tarEntry{name: "./app/server", contents: "v2", mode: 0755},
+		tarEntry{name: "./app/config", contents: "port: 8080", mode: 0644},
+		tarEntry{name: "./app/new.log", contents: "", mode: 0644},
+		tarEntry{name: "./current", link: "app2", mode: 0777},
+		tarEntry{name: "./secret", contents: "x", mode: 0600},
+	)
+
+	differences, err := Diff(Tree{Container: before, Dir: "/srv"}, Tree{Container: after, Dir: "/srv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dir := before.StreamOutArgsForCall(0); dir != "/srv/" {
+		t.Errorf("streamed out %q, want the directory's contents", dir)
+	}
+
+	want := []Difference{
+		{"changed", "/srv/app/config", "size 8 -> 10"},
+		{"added", "/srv/app/new.log", ""},
+		{"removed", "/srv/app/old.log", ""},
+		{"changed", "/srv/app/server", "content"},
+		{"changed", "/srv/current", "target app -> app2"},
+		{"changed", "/srv/secret", "mode 0644 -> 0600"},
+	}
+
+	if !reflect.DeepEqual(differences, want) {
+		t.Errorf("found %#v, want %#v", differences, want)
+	}
+
+	var buf bytes.Buffer
+	PrintDifferences(&buf, differences[:2])
+
+	if buf.String() != "changed\t/srv/app/config\tsize 8 -> 10\nadded\t/srv/app/new.log\n" {
+		t.Errorf("printed %q", buf.String())
+	}
+}
+
+func TestDiffLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Mkdir(filepath.Join(dir, "app"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "app", "server"), []byte("v1"), 0755)
+
+	container := fakeContainer("web")
+	streamsOut(container,
+		tarEntry{name: "./", mode: 0755},
+		tarEntry{name: "./app/", mode: 0755},
+		tarEntry{name: "./app/server", contents: "v1", mode: 0755},
+	)
+
+	differences, err := Diff(Tree{Dir: dir}, Tree{Container: container, Dir: "/srv/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(differences) != 0 {
+		t.Errorf("found %v, want none", differences)
+	}
+
+	if path := container.StreamOutArgsForCall(0); path != "/srv/" {
+		t.Errorf("streamed out %q", path)
+	}
+}
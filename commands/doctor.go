@@ -0,0 +1,299 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// canaryFile is the file streamed into and out of canary containers.
+const canaryFile = "/tmp/gaol-canary"
+
+// Check is the outcome of one of the checks doctor makes.
+type Check struct {
+	Name     string
+	Duration time.Duration
+
+	// Detail is what the check found out when it passed.
+	Detail string
+
+	Err error
+
+	// Skipped checks were not made because an earlier check they depend
+	// on failed.
+	Skipped bool
+}
+
+// DoctorOptions tune the checks doctor makes.
+type DoctorOptions struct {
+	// Reach, if given, opens and closes a connection to the server without
+	// talking garden, telling network problems from server problems.
+	Reach func() error
+
+	// RootFS is the rootfs of the canary container; empty uses the
+	// server's default.
+	RootFS string
+}
+
+// checker makes checks one after another, skipping those which depend on
+// one which failed.
+type checker struct {
+	checks []Check
+	failed bool
+}
+
+// check makes the check unless an earlier one failed, reporting whether it
+// passed.
+func (c *checker) check(name string, f func() (string, error)) bool {
+	if c.failed {
+		c.checks = append(c.checks, Check{Name: name, Skipped: true})
+		return false
+	}
+
+	start := time.Now()
+	detail, err := f()
+	c.checks = append(c.checks, Check{Name: name, Duration: time.Since(start), Detail: detail, Err: err})
+
+	c.failed = err != nil
+	return !c.failed
+}
+
+// Doctor checks, one after another, that the server can be reached, answers
+// pings and reports its capacity, and that a canary container can be
+// created, have a file streamed in and out of it, have a port mapped and be
+// destroyed. Checks which depend on one which failed are skipped.
+func Doctor(client garden.Client, opts DoctorOptions) []Check {
+	c := &checker{}
+
+	if opts.Reach != nil {
+		c.check("connect", func() (string, error) {
+			return "", opts.Reach()
+		})
+	}
+
+	c.check("ping", func() (string, error) {
+		return "", client.Ping()
+	})
+
+	c.check("capacity", func() (string, error) {
+		capacity, err := client.Capacity()
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%s memory, %s disk, %d containers", HumanBytes(capacity.MemoryInBytes), HumanBytes(capacity.DiskInBytes), capacity.MaxContainers), nil
+	})
+
+	checkCanary(c, client, opts.RootFS, false)
+
+	return c.checks
+}
+
+// Smoke takes a canary container created from the rootfs through its whole
+// life: it is created, runs echo, has a file streamed in and out of it and a
+// port mapped, and is destroyed.
+func Smoke(client garden.Client, rootfs string) []Check {
+	c := &checker{}
+	checkCanary(c, client, rootfs, true)
+	return c.checks
+}
+
+// checkCanary creates a canary container, checks what can be done with it,
+// running a process if run is given, and destroys it.
+func checkCanary(c *checker, client garden.Client, rootfs string, run bool) {
+	var container garden.Container
+	created := c.check("create", func() (string, error) {
+		var err error
+		container, err = client.Create(garden.ContainerSpec{
+			Handle:     fmt.Sprintf("gaol-canary-%d", time.Now().UnixNano()),
+			RootFSPath: rootfs,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		return container.Handle(), nil
+	})
+
+	if run {
+		c.check("run", func() (string, error) {
+			return "", echo(container)
+		})
+
+		// the rest only need the canary to exist
+		c.failed = !created
+	}
+
+	c.check("stream", func() (string, error) {
+		return "", streamRoundTrip(container)
+	})
+
+	c.failed = !created
+
+	c.check("net-in", func() (string, error) {
+		hostPort, containerPort, err := container.NetIn(0, 8080)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%d:%d", hostPort, containerPort), nil
+	})
+
+	c.failed = !created
+
+	c.check("destroy", func() (string, error) {
+		return "", client.Destroy(container.Handle())
+	})
+}
+
+// echo checks that the container runs processes and returns their output.
+func echo(container garden.Container) error {
+	var stdout bytes.Buffer
+
+	process, err := container.Run(garden.ProcessSpec{
+		Path: "echo",
+		Args: []string{"gaol"},
+	}, garden.ProcessIO{Stdout: &stdout, Stderr: ioutil.Discard})
+	if err != nil {
+		return err
+	}
+
+	status, err := process.Wait()
+	if err != nil {
+		return err
+	}
+
+	if status != 0 {
+		return ProcessExitError{"echo gaol", status}
+	}
+
+	if stdout.String() != "gaol\n" {
+		return fmt.Errorf("echo printed %q", stdout.String())
+	}
+
+	return nil
+}
+
+// streamRoundTrip streams a file into the container and checks that the
+// same contents stream back out.
+func streamRoundTrip(container garden.Container) error {
+	contents := []byte("gaol canary\n")
+
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	tw.WriteHeader(&tar.Header{
+		Name: path.Base(canaryFile),
+		Mode: 0644,
+		Size: int64(len(contents)),
+	})
+	tw.Write(contents)
+	tw.Close()
+
+	if err := container.StreamIn(path.Dir(canaryFile), &archive); err != nil {
+		return fmt.Errorf("streaming in: %s", err)
+	}
+
+	output, err := container.StreamOut(canaryFile)
+	if err != nil {
+		return fmt.Errorf("streaming out: %s", err)
+	}
+	defer output.Close()
+
+	tr := tar.NewReader(output)
+	if _, err := tr.Next(); err != nil {
+		return fmt.Errorf("streaming out: %s", err)
+	}
+
+	streamed, err := ioutil.ReadAll(tr)
+	if err != nil {
+		return fmt.Errorf("streaming out: %s", err)
+	}
+
+	if !bytes.Equal(streamed, contents) {
+		return fmt.Errorf("streamed out %q after streaming in %q", streamed, contents)
+	}
+
+	return nil
+}
+
+// PrintChecks writes a line for each check: whether it passed, how long it
+// took and what it found.
+func PrintChecks(w io.Writer, checks []Check, format Format) {
+	for _, check := range checks {
+		switch {
+		case check.Skipped:
+			fmt.Fprintf(w, "%s\t%s\n", format.paint(yellow, "skip"), check.Name)
+		case check.Err != nil:
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", format.paint(red, "fail"), check.Name, HumanDuration(check.Duration), check.Err)
+		case check.Detail == "":
+			fmt.Fprintf(w, "%s\t%s\t%s\n", format.paint(green, "pass"), check.Name, HumanDuration(check.Duration))
+		default:
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", format.paint(green, "pass"), check.Name, HumanDuration(check.Duration), check.Detail)
+		}
+	}
+}
+
+// FailedChecks counts the checks which failed.
+func FailedChecks(checks []Check) int {
+	failed := 0
+	for _, check := range checks {
+		if check.Err != nil {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+// checkJSON is the machine-readable form of a check.
+type checkJSON struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	DurationMS int64  `json:"duration_ms"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// PrintChecksJSON writes the checks as a single JSON object, saying whether
+// every check passed, how long they took together and the outcome of each.
+func PrintChecksJSON(w io.Writer, checks []Check) error {
+	report := struct {
+		OK         bool        `json:"ok"`
+		DurationMS int64       `json:"duration_ms"`
+		Checks     []checkJSON `json:"checks"`
+	}{
+		OK:     true,
+		Checks: []checkJSON{},
+	}
+
+	for _, check := range checks {
+		c := checkJSON{
+			Name:       check.Name,
+			Status:     "pass",
+			DurationMS: int64(check.Duration / time.Millisecond),
+			Detail:     check.Detail,
+		}
+
+		switch {
+		case check.Skipped:
+			c.Status = "skip"
+			report.OK = false
+		case check.Err != nil:
+			c.Status = "fail"
+			c.Error = check.Err.Error()
+			report.OK = false
+		}
+
+		report.DurationMS += c.DurationMS
+		report.Checks = append(report.Checks, c)
+	}
+
+	return json.NewEncoder(w).Encode(report)
+}
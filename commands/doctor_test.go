@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func checkStatuses(checks []Check) string {
+	var buf bytes.Buffer
+	PrintChecks(&buf, checks, Format{})
+
+	statuses := ""
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		fields := bytes.SplitN(line, []byte("\t"), 3)
+		statuses += string(fields[0]) + " " + string(fields[1]) + "\n"
+	}
+
+	return statuses
+}
+
+func TestDoctor(t *testing.T) {
+	var streamed bytes.Buffer
+
+	container := fakeContainer("canary")
+	container.StreamInStub = func(dst string, r io.Reader) error {
+		_, err := io.Copy(&streamed, r)
+		return err
+	}
+	container.StreamOutStub = func(src string) (io.ReadCloser, error) {
+		if src != canaryFile {
+			t.Errorf("streamed out %q", src)
+		}
+
+		return ioutil.NopCloser(bytes.NewReader(streamed.Bytes())), nil
+	}
+	container.NetInReturns(60001, 8080, errors.New("no ports left"))
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.CreateReturns(container, nil)
+
+	checks := Doctor(fakeClient, DoctorOptions{Reach: func() error { return nil }})
+
+	want := "pass connect\npass ping\npass capacity\npass create\npass stream\nfail net-in\npass destroy\n"
+	if got := checkStatuses(checks); got != want {
+		t.Errorf("reported\n%s\nwant\n%s", got, want)
+	}
+
+	if FailedChecks(checks) != 1 {
+		t.Errorf("counted %d failures", FailedChecks(checks))
+	}
+
+	if fakeClient.DestroyArgsForCall(0) != "canary" {
+		t.Error("expected the canary to be destroyed")
+	}
+}
+
+func TestDoctorSkipsChecksAfterFailures(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.CreateReturns(nil, errors.New("no rootfs"))
+
+	checks := Doctor(fakeClient, DoctorOptions{})
+
+	want := "pass ping\npass capacity\nfail create\nskip stream\nskip net-in\nskip destroy\n"
+	if got := checkStatuses(checks); got != want {
+		t.Errorf("reported\n%s\nwant\n%s", got, want)
+	}
+
+	if fakeClient.DestroyCallCount() != 0 {
+		t.Error("expected nothing to be destroyed")
+	}
+}
+
+func TestSmoke(t *testing.T) {
+	container := fakeContainer("canary")
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		return nil, errors.New("no such file: echo")
+	}
+	container.StreamOutReturns(nil, errors.New("no such file"))
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.CreateReturns(container, nil)
+
+	checks := Smoke(fakeClient, "/rootfs")
+
+	want := "pass create\nfail run\nfail stream\npass net-in\npass destroy\n"
+	if got := checkStatuses(checks); got != want {
+		t.Errorf("reported\n%s\nwant\n%s", got, want)
+	}
+
+	if fakeClient.CreateArgsForCall(0).RootFSPath != "/rootfs" {
+		t.Error("expected the canary to be created from the rootfs")
+	}
+
+	var buf bytes.Buffer
+	if err := PrintChecksJSON(&buf, checks); err != nil {
+		t.Fatal(err)
+	}
+
+	var report struct {
+		OK     bool
+		Checks []map[string]interface{}
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if report.OK || len(report.Checks) != 5 {
+		t.Errorf("reported %s", buf.String())
+	}
+
+	if run := report.Checks[1]; run["status"] != "fail" || run["error"] != "no such file: echo" {
+		t.Errorf("reported run as %v", run)
+	}
+}
@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// EditConflictError is returned by Edit when the file changed in the
+// container while it was being edited, rather than overwrite the change.
+type EditConflictError struct {
+	Path string
+
+	// Local is where the edited version was left.
+	Local string
+}
+
+func (err EditConflictError) Error() string {
+	return fmt.Sprintf("%s changed in the container while it was being edited, so was left alone; the edited version is in %s", err.Path, err.Local)
+}
+
+// Edit streams the file out of the container into a temporary file, which
+// edit is called with, and streams it back in if it was changed, keeping its
+// mode and owner. If the file changed in the container meanwhile it is left
+// alone, as is the edited copy, and an EditConflictError is returned.
+func Edit(client garden.Client, handle string, file string, edit func(local string) error, progress io.Writer) error {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	original, contents, err := readFile(container, file)
+	if err != nil {
+		return err
+	}
+
+	dir, err := ioutil.TempDir("", "gaol-edit")
+	if err != nil {
+		return err
+	}
+
+	// the editor is given the file's own name, for its syntax highlighting
+	local := filepath.Join(dir, path.Base(file))
+	if err := ioutil.WriteFile(local, contents, 0600); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+
+	keep := false
+	defer func() {
+		if !keep {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	if err := edit(local); err != nil {
+		return err
+	}
+
+	edited, err := ioutil.ReadFile(local)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(edited, contents) {
+		fmt.Fprintf(progress, "%s unchanged\n", file)
+		return nil
+	}
+
+	current, currentContents, err := readFile(container, file)
+	if err != nil {
+		return err
+	}
+
+	// a change within the same second, to the same size, only shows in what
+	// the file holds
+	if !current.ModTime.Equal(original.ModTime) || !bytes.Equal(currentContents, contents) {
+		keep = true
+		return EditConflictError{file, local}
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeEditedFile(writer, original, edited))
+	}()
+
+	if err := container.StreamIn(path.Dir(file), reader); err != nil {
+		keep = true
+		return fmt.Errorf("failed to stream %s back in, the edited version is in %s: %s", file, local, err)
+	}
+
+	fmt.Fprintf(progress, "updated %s\n", file)
+	return nil
+}
+
+// readFile streams the regular file src out of the container.
+func readFile(container garden.Container, src string) (*tar.Header, []byte, error) {
+	header, contents, closer, err := openFile(container, src)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer closer.Close()
+
+	data, err := ioutil.ReadAll(contents)
+	return header, data, err
+}
+
+// writeEditedFile tars the edited contents with the original's name, mode
+// and owner.
+func writeEditedFile(w io.Writer, original *tar.Header, contents []byte) error {
+	tw := tar.NewWriter(w)
+
+	err := tw.WriteHeader(&tar.Header{
+		Name:     path.Base(original.Name),
+		Mode:     original.Mode,
+		Uid:      original.Uid,
+		Gid:      original.Gid,
+		Uname:    original.Uname,
+		Gname:    original.Gname,
+		Size:     int64(len(contents)),
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := tw.Write(contents); err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
@@ -0,0 +1,179 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+// editableContainer holds a single file, /etc/app.conf, which is modified
+// each time it is streamed out if changing is set.
+func editableContainer(changing bool) (*fakes.FakeContainer, *tar.Header) {
+	modified := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+	contents := "debug = false\n"
+
+	container := fakeContainer("web")
+	container.StreamOutStub = func(src string) (io.ReadCloser, error) {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		tw.WriteHeader(&tar.Header{Name: "app.conf", Mode: 0640, Uid: 1000, Gid: 1000, Size: int64(len(contents)), ModTime: modified, Typeflag: tar.TypeReg})
+		tw.Write([]byte(contents))
+		tw.Close()
+
+		if changing {
+			modified = modified.Add(time.Minute)
+		}
+
+		return ioutil.NopCloser(&buf), nil
+	}
+
+	streamedIn := &tar.Header{}
+	container.StreamInStub = func(dst string, r io.Reader) error {
+		tr := tar.NewReader(r)
+		header, err := tr.Next()
+		if err != nil {
+			return err
+		}
+
+		*streamedIn = *header
+		data, _ := ioutil.ReadAll(tr)
+		contents = string(data)
+		return nil
+	}
+
+	return container, streamedIn
+}
+
+func TestEdit(t *testing.T) {
+	container, streamedIn := editableContainer(false)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	var edited string
+	edit := func(local string) error {
+		edited = local
+		return ioutil.WriteFile(local, []byte("debug = true\n"), 0600)
+	}
+
+	var progress bytes.Buffer
+	if err := Edit(fakeClient, "web", "/etc/app.conf", edit, &progress); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(edited, "/app.conf") {
+		t.Errorf("edited %s, want the file's own name", edited)
+	}
+
+	if _, err := os.Stat(edited); !os.IsNotExist(err) {
+		t.Error("left the edited copy behind")
+	}
+
+	if container.StreamInCallCount() != 1 {
+		t.Fatal("did not stream the file back in")
+	}
+
+	if dst, _ := container.StreamInArgsForCall(0); dst != "/etc" {
+		t.Errorf("streamed into %s, want /etc", dst)
+	}
+
+	if streamedIn.Name != "app.conf" || streamedIn.Mode != 0640 || streamedIn.Uid != 1000 || streamedIn.Gid != 1000 || streamedIn.Size != 13 {
+		t.Errorf("streamed in %#v, want the mode and owner kept", streamedIn)
+	}
+
+	if progress.String() != "updated /etc/app.conf\n" {
+		t.Errorf("printed %q", progress.String())
+	}
+}
+
+func TestEditUnchanged(t *testing.T) {
+	container, _ := editableContainer(false)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	var progress bytes.Buffer
+	err := Edit(fakeClient, "web", "/etc/app.conf", func(string) error { return nil }, &progress)
+	if err != nil || progress.String() != "/etc/app.conf unchanged\n" {
+		t.Errorf("printed %q (%v)", progress.String(), err)
+	}
+
+	if container.StreamInCallCount() != 0 {
+		t.Error("streamed an unchanged file back in")
+	}
+}
+
+func TestEditConflict(t *testing.T) {
+	container, _ := editableContainer(true)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	edit := func(local string) error {
+		return ioutil.WriteFile(local, []byte("debug = true\n"), 0600)
+	}
+
+	err := Edit(fakeClient, "web", "/etc/app.conf", edit, new(bytes.Buffer))
+
+	conflict, ok := err.(EditConflictError)
+	if !ok {
+		t.Fatalf("got %v, want a conflict", err)
+	}
+	defer os.RemoveAll(conflict.Local)
+
+	if contents, _ := ioutil.ReadFile(conflict.Local); string(contents) != "debug = true\n" {
+		t.Errorf("kept %q, want the edited version", contents)
+	}
+
+	if container.StreamInCallCount() != 0 {
+		t.Error("overwrote the file changed in the container")
+	}
+}
+
+func TestEditConflictWithinTheSameSecond(t *testing.T) {
+	container, _ := editableContainer(false)
+
+	// changed to the same size without the modification time moving on
+	streamOut := container.StreamOutStub
+	container.StreamOutStub = func(src string) (io.ReadCloser, error) {
+		if container.StreamOutCallCount() == 1 {
+			return streamOut(src)
+		}
+
+		contents := "debug = FALSE\n"
+
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		tw.WriteHeader(&tar.Header{Name: "app.conf", Mode: 0640, Size: int64(len(contents)), ModTime: time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC), Typeflag: tar.TypeReg})
+		tw.Write([]byte(contents))
+		tw.Close()
+
+		return ioutil.NopCloser(&buf), nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	edit := func(local string) error {
+		return ioutil.WriteFile(local, []byte("debug = true\n"), 0600)
+	}
+
+	err := Edit(fakeClient, "web", "/etc/app.conf", edit, new(bytes.Buffer))
+
+	conflict, ok := err.(EditConflictError)
+	if !ok {
+		t.Fatalf("got %v, want a conflict", err)
+	}
+	defer os.RemoveAll(conflict.Local)
+
+	if container.StreamInCallCount() != 0 {
+		t.Error("overwrote the file changed in the container")
+	}
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// Events writes the events the server has recorded for the container, such
+// as running out of memory, one per line, oldest first.
+func Events(client garden.Client, handle string, w io.Writer) error {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	info, err := container.Info()
+	if err != nil {
+		return err
+	}
+
+	for _, event := range info.Events {
+		fmt.Fprintln(w, event)
+	}
+
+	return nil
+}
+
+// WatchEvents polls the container's events, writing a tab-separated line
+// with the time it was first seen and the event for each new one. The server
+// does not say when events happened, so those recorded before watching
+// began are written with - for their time. With lines each is written as a
+// JSON line instead, of type recorded for those from before watching began
+// and event for the rest. It returns once the container cannot be looked
+// at, such as when it has been destroyed.
+func WatchEvents(client garden.Client, handle string, w io.Writer, interval time.Duration, lines *JSONLines) error {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	var seen []string
+
+	for first := true; ; first = false {
+		info, err := container.Info()
+		if err != nil {
+			return err
+		}
+
+		stamp, kind := time.Now().Format(time.RFC3339), "event"
+		if first {
+			stamp, kind = "-", "recorded"
+		}
+
+		for _, event := range newEvents(seen, info.Events) {
+			if lines != nil {
+				lines.Write(handle, kind, event)
+			} else {
+				fmt.Fprintf(w, "%s\t%s\n", stamp, event)
+			}
+		}
+
+		seen = info.Events
+		time.Sleep(interval)
+	}
+}
+
+// newEvents returns the events in current which were not in seen. The server
+// only ever appends events, so they are those past the ones already seen;
+// if current does not start with them all of it is new.
+func newEvents(seen []string, current []string) []string {
+	if len(current) < len(seen) {
+		return current
+	}
+
+	for i, event := range seen {
+		if current[i] != event {
+			return current
+		}
+	}
+
+	return current[len(seen):]
+}
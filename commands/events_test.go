@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestEvents(t *testing.T) {
+	container := fakeContainer("web")
+	container.InfoReturns(garden.ContainerInfo{Events: []string{"out of memory", "restarted"}}, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	var buf bytes.Buffer
+	if err := Events(fakeClient, "web", &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != "out of memory\nrestarted\n" {
+		t.Errorf("printed %q", buf.String())
+	}
+}
+
+func TestWatchEvents(t *testing.T) {
+	polls := [][]string{
+		{"out of memory"},
+		{"out of memory"},
+		{"out of memory", "out of memory"},
+	}
+
+	container := fakeContainer("web")
+	container.InfoStub = func() (garden.ContainerInfo, error) {
+		if len(polls) == 0 {
+			return garden.ContainerInfo{}, errors.New("container not found")
+		}
+
+		events := polls[0]
+		polls = polls[1:]
+		return garden.ContainerInfo{Events: events}, nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	var buf bytes.Buffer
+	err := WatchEvents(fakeClient, "web", &buf, time.Millisecond, nil)
+	if err == nil || err.Error() != "container not found" {
+		t.Errorf("returned %v, want the container to have gone", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || lines[0] != "-\tout of memory" {
+		t.Fatalf("printed %q", buf.String())
+	}
+
+	if stamp := strings.SplitN(lines[1], "\t", 2); len(stamp) != 2 || stamp[1] != "out of memory" {
+		t.Errorf("printed %q for the new event", lines[1])
+	} else if _, err := time.Parse(time.RFC3339, stamp[0]); err != nil {
+		t.Errorf("stamped the new event %q", stamp[0])
+	}
+}
+
+func TestNewEvents(t *testing.T) {
+	tests := []struct {
+		seen, current, want []string
+	}{
+		{nil, []string{"a"}, []string{"a"}},
+		{[]string{"a"}, []string{"a", "b"}, []string{"b"}},
+		{[]string{"a", "b"}, []string{"a", "b"}, []string{}},
+		// the container was replaced by one with the same handle
+		{[]string{"a", "b"}, []string{"c"}, []string{"c"}},
+		{[]string{"a"}, []string{"c", "d"}, []string{"c", "d"}},
+	}
+
+	for _, test := range tests {
+		if got := newEvents(test.seen, test.current); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("new events in %v after %v: %v, want %v", test.current, test.seen, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// exporter periodically collects the usage of the containers on the server
+// and serves it in the Prometheus text format.
+type exporter struct {
+	client          garden.Client
+	filter          garden.Properties
+	labelProperties []string
+	labelNames      []string
+
+	mu       sync.RWMutex
+	snapshot []byte
+}
+
+// metric is a single value derived from the info of a container, along with
+// the names it is given by each of the ways metrics are published.
+type metric struct {
+	name   string
+	statsd string
+	kind   string
+	help   string
+	get    func(garden.ContainerInfo) float64
+}
+
+var containerMetrics = []metric{
+	{"garden_container_memory_rss_bytes", "memory.rss", "gauge", "Resident set size of the container.", func(i garden.ContainerInfo) float64 { return float64(i.MemoryStat.TotalRss) }},
+	{"garden_container_memory_cache_bytes", "memory.cache", "gauge", "Page cache used by the container.", func(i garden.ContainerInfo) float64 { return float64(i.MemoryStat.TotalCache) }},
+	{"garden_container_memory_swap_bytes", "memory.swap", "gauge", "Swap used by the container.", func(i garden.ContainerInfo) float64 { return float64(i.MemoryStat.TotalSwap) }},
+	{"garden_container_memory_limit_bytes", "memory.limit", "gauge", "Memory limit of the container.", func(i garden.ContainerInfo) float64 { return float64(i.MemoryStat.HierarchicalMemoryLimit) }},
+	{"garden_container_cpu_usage_seconds_total", "cpu.usage", "counter", "CPU time used by the container.", func(i garden.ContainerInfo) float64 { return float64(i.CPUStat.Usage) / 1e9 }},
+	{"garden_container_disk_used_bytes", "disk.used", "gauge", "Disk space used by the container.", func(i garden.ContainerInfo) float64 { return float64(i.DiskStat.BytesUsed) }},
+	{"garden_container_disk_used_inodes", "disk.inodes", "gauge", "Inodes used by the container.", func(i garden.ContainerInfo) float64 { return float64(i.DiskStat.InodesUsed) }},
+	{"garden_container_bandwidth_in_rate_bytes", "bandwidth.in_rate", "gauge", "Inbound bandwidth limit of the container.", func(i garden.ContainerInfo) float64 { return float64(i.BandwidthStat.InRate) }},
+	{"garden_container_bandwidth_out_rate_bytes", "bandwidth.out_rate", "gauge", "Outbound bandwidth limit of the container.", func(i garden.ContainerInfo) float64 { return float64(i.BandwidthStat.OutRate) }},
+	{"garden_container_processes", "processes", "gauge", "Number of processes running in the container.", func(i garden.ContainerInfo) float64 { return float64(len(i.ProcessIDs)) }},
+}
+
+func (e *exporter) run(interval time.Duration) {
+	for {
+		e.collect()
+		time.Sleep(interval)
+	}
+}
+
+func (e *exporter) collect() {
+	var out bytes.Buffer
+
+	start := time.Now()
+
+	containers, err := e.client.Containers(e.filter)
+	if err != nil {
+		fmt.Fprintln(&out, "# HELP garden_up Whether the Garden server could be reached.")
+		fmt.Fprintln(&out, "# TYPE garden_up gauge")
+		fmt.Fprintln(&out, "garden_up 0")
+		e.store(out.Bytes())
+		return
+	}
+
+	infos, errs := bulkInfo(containers)
+
+	handles := make([]string, 0, len(infos))
+	for handle := range infos {
+		handles = append(handles, handle)
+	}
+	sort.Strings(handles)
+
+	fmt.Fprintln(&out, "# HELP garden_up Whether the Garden server could be reached.")
+	fmt.Fprintln(&out, "# TYPE garden_up gauge")
+	fmt.Fprintln(&out, "garden_up 1")
+
+	fmt.Fprintln(&out, "# HELP garden_containers Number of containers matching the filter.")
+	fmt.Fprintln(&out, "# TYPE garden_containers gauge")
+	fmt.Fprintf(&out, "garden_containers %d\n", len(containers))
+
+	for _, m := range containerMetrics {
+		fmt.Fprintf(&out, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(&out, "# TYPE %s %s\n", m.name, m.kind)
+
+		for _, handle := range handles {
+			info := infos[handle]
+			fmt.Fprintf(&out, "%s{%s} %g\n", m.name, e.labels(handle, info), m.get(info))
+		}
+	}
+
+	fmt.Fprintln(&out, "# HELP gaol_collect_errors Number of containers whose info could not be fetched.")
+	fmt.Fprintln(&out, "# TYPE gaol_collect_errors gauge")
+	fmt.Fprintf(&out, "gaol_collect_errors %d\n", len(errs))
+
+	fmt.Fprintln(&out, "# HELP gaol_collect_duration_seconds Time taken to collect the metrics.")
+	fmt.Fprintln(&out, "# TYPE gaol_collect_duration_seconds gauge")
+	fmt.Fprintf(&out, "gaol_collect_duration_seconds %g\n", time.Since(start).Seconds())
+
+	e.store(out.Bytes())
+}
+
+func (e *exporter) store(snapshot []byte) {
+	e.mu.Lock()
+	e.snapshot = snapshot
+	e.mu.Unlock()
+}
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// labelNames turns each property into a valid label name which no other
+// label has, leaving out properties given more than once.
+func labelNames(properties []string) ([]string, []string) {
+	taken := map[string]bool{"handle": true}
+	seen := map[string]bool{}
+
+	kept := []string{}
+	names := []string{}
+	for _, property := range properties {
+		if seen[property] {
+			continue
+		}
+		seen[property] = true
+
+		name := invalidLabelChars.ReplaceAllString(property, "_")
+		if name == "" || (name[0] >= '0' && name[0] <= '9') {
+			name = "_" + name
+		}
+
+		unique := name
+		for i := 2; taken[unique]; i++ {
+			unique = fmt.Sprintf("%s_%d", name, i)
+		}
+		taken[unique] = true
+
+		kept = append(kept, property)
+		names = append(names, unique)
+	}
+
+	return kept, names
+}
+
+func (e *exporter) labels(handle string, info garden.ContainerInfo) string {
+	labels := []string{fmt.Sprintf("handle=\"%s\"", escapeLabel(handle))}
+
+	for i, property := range e.labelProperties {
+		labels = append(labels, fmt.Sprintf("%s=\"%s\"", e.labelNames[i], escapeLabel(info.Properties[property])))
+	}
+
+	return strings.Join(labels, ",")
+}
+
+func escapeLabel(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `"`, `\"`, -1)
+	return strings.Replace(value, "\n", `\n`, -1)
+}
+
+func (e *exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.RLock()
+	snapshot := e.snapshot
+	e.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(snapshot)
+}
+
+// ExportMetrics collects the metrics of the containers every interval and
+// serves the latest collection on listen until the server fails.
+func ExportMetrics(client garden.Client, listen string, interval time.Duration, filter garden.Properties, labelProperties []string) error {
+	e := &exporter{
+		client: client,
+		filter: filter,
+	}
+	e.labelProperties, e.labelNames = labelNames(labelProperties)
+
+	e.collect()
+	go e.run(interval)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+
+	return http.ListenAndServe(listen, mux)
+}
@@ -0,0 +1,18 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLabelNames(t *testing.T) {
+	properties, names := labelNames([]string{"team", "handle", "app.tier", "app-tier", "team", "2fa", ""})
+
+	if want := []string{"team", "handle", "app.tier", "app-tier", "2fa", ""}; !reflect.DeepEqual(properties, want) {
+		t.Errorf("kept %q, want %q", properties, want)
+	}
+
+	if want := []string{"team", "handle_2", "app_tier", "app_tier_2", "_2fa", "_"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("named %q, want %q", names, want)
+	}
+}
@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// RunEach runs command in every container, at most concurrency at a time,
+// and waits for each process to exit. Every line the processes write is
+// prefixed with the handle of the container it came from. A command which
+// exits unsuccessfully fails with a ProcessExitError.
+func RunEach(client garden.Client, handles []string, command string, opts RunOptions, stdout io.Writer, stderr io.Writer, concurrency int) []Result {
+	opts.Attach = true
+
+	width := 0
+	for _, handle := range handles {
+		if len(handle) > width {
+			width = len(handle)
+		}
+	}
+
+	// lines from different processes must not be interleaved mid-line
+	var mu sync.Mutex
+
+	return forEach(handles, concurrency, func(handle string) error {
+		prefix := fmt.Sprintf("%-*s | ", width, handle)
+
+		out := &prefixWriter{w: stdout, mu: &mu, prefix: prefix}
+		errOut := &prefixWriter{w: stderr, mu: &mu, prefix: prefix}
+
+		err := Run(client, handle, command, opts, garden.ProcessIO{
+			Stdout: out,
+			Stderr: errOut,
+		}, nil)
+
+		out.Flush()
+		errOut.Flush()
+
+		return err
+	})
+}
+
+// RunEachJSON runs command in every container like RunEach, writing each
+// line of output as a JSON line of type stdout or stderr and, once the
+// command has exited, a JSON line of type exit with its status or why it
+// could not be run.
+func RunEachJSON(client garden.Client, handles []string, command string, opts RunOptions, lines *JSONLines, concurrency int) []Result {
+	opts.Attach = true
+
+	var mu sync.Mutex
+
+	return forEach(handles, concurrency, func(handle string) error {
+		out := &prefixWriter{w: streamWriter{lines, handle, "stdout"}, mu: &mu}
+		errOut := &prefixWriter{w: streamWriter{lines, handle, "stderr"}, mu: &mu}
+
+		err := Run(client, handle, command, opts, garden.ProcessIO{
+			Stdout: out,
+			Stderr: errOut,
+		}, nil)
+
+		out.Flush()
+		errOut.Flush()
+
+		lines.Write(handle, "exit", exitPayload(err))
+
+		return err
+	})
+}
+
+// exitStatus is the payload of the JSON line written when a command exits.
+type exitStatus struct {
+	Status *int   `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func exitPayload(err error) exitStatus {
+	status := 0
+
+	switch e := err.(type) {
+	case nil:
+	case ProcessExitError:
+		status = e.Status
+	default:
+		return exitStatus{Error: err.Error()}
+	}
+
+	return exitStatus{Status: &status}
+}
+
+// PrintExitStatuses writes the exit status of the command run in each
+// container, or why it could not be run, one tab-separated line per
+// container.
+func PrintExitStatuses(w io.Writer, results []Result) {
+	for _, result := range results {
+		switch err := result.Err.(type) {
+		case nil:
+			fmt.Fprintf(w, "%s\t0\n", result.Handle)
+		case ProcessExitError:
+			fmt.Fprintf(w, "%s\t%d\n", result.Handle, err.Status)
+		default:
+			fmt.Fprintf(w, "%s\terror: %s\n", result.Handle, err)
+		}
+	}
+}
+
+// prefixWriter writes whole lines to w, each starting with prefix, holding
+// on to partial lines until they are finished or flushed.
+type prefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	prefix string
+
+	partial []byte
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.partial = append(p.partial, data...)
+
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i < 0 {
+			break
+		}
+
+		if err := p.writeLine(p.partial[:i+1]); err != nil {
+			return 0, err
+		}
+
+		p.partial = p.partial[i+1:]
+	}
+
+	return len(data), nil
+}
+
+// Flush writes out the last line, if it was not finished.
+func (p *prefixWriter) Flush() error {
+	if len(p.partial) == 0 {
+		return nil
+	}
+
+	line := append(p.partial, '\n')
+	p.partial = nil
+
+	return p.writeLine(line)
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err := io.WriteString(p.w, p.prefix+string(line))
+	return err
+}
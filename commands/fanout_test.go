@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &prefixWriter{w: &buf, mu: new(sync.Mutex), prefix: "a | "}
+
+	io.WriteString(w, "one\ntw")
+	io.WriteString(w, "o\nthr")
+
+	if buf.String() != "a | one\na | two\n" {
+		t.Errorf("wrote %q before flushing, want only whole lines", buf.String())
+	}
+
+	w.Flush()
+
+	if buf.String() != "a | one\na | two\na | thr\n" {
+		t.Errorf("wrote %q", buf.String())
+	}
+}
+
+func TestRunEach(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupStub = func(handle string) (garden.Container, error) {
+		if handle == "missing" {
+			return nil, errors.New("container not found")
+		}
+
+		container := fakeContainer(handle)
+		container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+			io.WriteString(processIO.Stdout, "hello from "+handle+"\n")
+
+			process := new(fakes.FakeProcess)
+			if handle == "db" {
+				process.WaitReturns(2, nil)
+			}
+
+			return process, nil
+		}
+
+		return container, nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	results := RunEach(fakeClient, []string{"web", "db", "missing"}, "hostname", RunOptions{}, &stdout, &stderr, 2)
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	sort.Strings(lines)
+
+	want := []string{"db      | hello from db", "web     | hello from web"}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("printed %q, want %q", lines, want)
+	}
+
+	var statuses bytes.Buffer
+	PrintExitStatuses(&statuses, results)
+
+	if statuses.String() != "web\t0\ndb\t2\nmissing\terror: container not found\n" {
+		t.Errorf("summarized %q", statuses.String())
+	}
+}
+
+func TestRunEachJSON(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupStub = func(handle string) (garden.Container, error) {
+		if handle == "missing" {
+			return nil, errors.New("container not found")
+		}
+
+		container := fakeContainer(handle)
+		container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+			io.WriteString(processIO.Stdout, "hello\nfrom "+handle)
+			io.WriteString(processIO.Stderr, "oops\n")
+
+			process := new(fakes.FakeProcess)
+			process.WaitReturns(2, nil)
+			return process, nil
+		}
+
+		return container, nil
+	}
+
+	var buf bytes.Buffer
+	results := RunEachJSON(fakeClient, []string{"web", "missing"}, "hostname", RunOptions{}, NewJSONLines(&buf), 1)
+
+	if len(Failures(results)) != 2 {
+		t.Errorf("returned %v", results)
+	}
+
+	got := []string{}
+	for _, line := range readJSONLines(t, &buf) {
+		payload, _ := json.Marshal(line.Payload)
+		got = append(got, line.Handle+" "+line.Type+" "+string(payload))
+	}
+
+	want := []string{
+		`web stdout "hello"`,
+		`web stderr "oops"`,
+		`web stdout "from web"`,
+		`web exit {"status":2}`,
+		`missing exit {"error":"container not found"}`,
+	}
+
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("wrote\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
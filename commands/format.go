@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+)
+
+// ANSI colors used to highlight output.
+const (
+	red    = "31"
+	green  = "32"
+	yellow = "33"
+	blue   = "34"
+)
+
+// Format describes how values are rendered for people to read.
+type Format struct {
+	// Color highlights container states and changes with ANSI colors.
+	Color bool
+
+	// Porcelain renders values for scripts instead, in a stable format;
+	// see PorcelainVersions.
+	Porcelain bool
+}
+
+// paint colors s when color is on.
+func (f Format) paint(color string, s string) string {
+	if !f.Color {
+		return s
+	}
+
+	return "\033[" + color + "m" + s + "\033[0m"
+}
+
+// State renders the state of a container: active is green, stopped yellow
+// and born, not yet started, blue.
+func (f Format) State(state string) string {
+	switch state {
+	case "active":
+		return f.paint(green, state)
+	case "stopped":
+		return f.paint(yellow, state)
+	case "born":
+		return f.paint(blue, state)
+	}
+
+	return state
+}
+
+// Problem renders s in red when the container has recorded events, such as
+// running out of memory, which are worth a look.
+func (f Format) Problem(s string, events []string) string {
+	if len(events) == 0 {
+		return s
+	}
+
+	return f.paint(red, s)
+}
+
+// HumanBytes renders n in binary units, e.g. 1.5 GiB.
+func HumanBytes(n uint64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+
+	value := float64(n)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%d %s", n, units[unit])
+	}
+
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+// HumanDuration renders d as precisely as is worth reading: milliseconds
+// below a second, tenths of a second below a minute, whole seconds below an
+// hour and whole minutes above.
+func HumanDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d/time.Millisecond)
+	case d < time.Minute:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", d/time.Minute, d%time.Minute/time.Second)
+	}
+
+	return fmt.Sprintf("%dh%02dm", d/time.Hour, d%time.Hour/time.Minute)
+}
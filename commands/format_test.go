@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHumanBytes(t *testing.T) {
+	tests := map[uint64]string{
+		0:             "0 B",
+		1023:          "1023 B",
+		1536:          "1.5 KiB",
+		2 << 20:       "2.0 MiB",
+		5 << 30:       "5.0 GiB",
+		3 << 40:       "3.0 TiB",
+		1<<50 + 1<<49: "1.5 PiB",
+	}
+
+	for n, want := range tests {
+		if got := HumanBytes(n); got != want {
+			t.Errorf("%d rendered as %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestHumanDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		250 * time.Millisecond:                     "250ms",
+		1500 * time.Millisecond:                    "1.5s",
+		90 * time.Second:                           "1m30s",
+		3*time.Hour + 12*time.Minute + time.Second: "3h12m",
+		30 * time.Hour:                             "30h00m",
+	}
+
+	for d, want := range tests {
+		if got := HumanDuration(d); got != want {
+			t.Errorf("%s rendered as %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestFormatState(t *testing.T) {
+	if got := (Format{}).State("active"); got != "active" {
+		t.Errorf("rendered %q without color", got)
+	}
+
+	if got := (Format{Color: true}).State("active"); got != "\033[32mactive\033[0m" {
+		t.Errorf("rendered %q, want green", got)
+	}
+
+	if got := (Format{Color: true}).State("stopped"); got != "\033[33mstopped\033[0m" {
+		t.Errorf("rendered %q, want yellow", got)
+	}
+
+	if got := (Format{Color: true}).State("born"); got != "\033[34mborn\033[0m" {
+		t.Errorf("rendered %q, want blue", got)
+	}
+}
+
+func TestFormatProblem(t *testing.T) {
+	if got := (Format{Color: true}).Problem("web", nil); got != "web" {
+		t.Errorf("rendered %q for a container without events", got)
+	}
+
+	if got := (Format{Color: true}).Problem("web", []string{"out of memory"}); got != "\033[31mweb\033[0m" {
+		t.Errorf("rendered %q, want red", got)
+	}
+
+	if got := (Format{}).Problem("web", []string{"out of memory"}); got != "web" {
+		t.Errorf("rendered %q without color", got)
+	}
+}
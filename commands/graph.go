@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// sparks are the bars of a sparkline, lowest first.
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// graphMetrics render the values of each metric which can be graphed.
+var graphMetrics = map[string]func(float64) string{
+	"cpu":  func(v float64) string { return fmt.Sprintf("%.1f%%", v) },
+	"mem":  func(v float64) string { return HumanBytes(uint64(v)) },
+	"disk": func(v float64) string { return HumanBytes(uint64(v)) },
+}
+
+// Graph polls the container's info every interval, redrawing a sparkline of
+// the last width samples of the metric (cpu, mem or disk) until the
+// container cannot be looked at. The cpu is the percentage of a core used
+// since the previous sample, so the first is only taken on the second poll.
+func Graph(client garden.Client, handle string, metric string, interval time.Duration, width int, w io.Writer) error {
+	render, found := graphMetrics[metric]
+	if !found {
+		return fmt.Errorf("cannot graph %s: must be cpu, mem or disk", metric)
+	}
+
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	var samples []float64
+	var previous *garden.ContainerInfo
+	var previousAt time.Time
+
+	for {
+		info, err := container.Info()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+
+		if value, ok := sampleMetric(metric, info, previous, now.Sub(previousAt)); ok {
+			samples = append(samples, value)
+			if len(samples) > width {
+				samples = samples[len(samples)-width:]
+			}
+		}
+
+		previous, previousAt = &info, now
+
+		fmt.Fprint(w, "\033[H\033[2J")
+		fmt.Fprintf(w, "Every %s: gaol graph %s %s\t%s\n\n", interval, handle, metric, now.Format(time.RFC1123))
+
+		if len(samples) > 0 {
+			low, high := bounds(samples)
+
+			fmt.Fprintln(w, Sparkline(samples))
+			fmt.Fprintf(w, "\nnow %s, min %s, max %s\n", render(samples[len(samples)-1]), render(low), render(high))
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// sampleMetric returns the metric's value in info, reporting false if there
+// is not yet one. Previous is the info sampled elapsed before, if any.
+func sampleMetric(metric string, info garden.ContainerInfo, previous *garden.ContainerInfo, elapsed time.Duration) (float64, bool) {
+	switch metric {
+	case "cpu":
+		// usage goes backwards if the container was replaced
+		if previous == nil || elapsed <= 0 || info.CPUStat.Usage < previous.CPUStat.Usage {
+			return 0, false
+		}
+
+		return float64(info.CPUStat.Usage-previous.CPUStat.Usage) / float64(elapsed.Nanoseconds()) * 100, true
+	case "mem":
+		return float64(info.MemoryStat.TotalRss), true
+	case "disk":
+		return float64(info.DiskStat.BytesUsed), true
+	}
+
+	return 0, false
+}
+
+// Sparkline renders the values as a line of bars, scaled from zero to the
+// highest of them.
+func Sparkline(values []float64) string {
+	_, high := bounds(values)
+
+	line := make([]rune, len(values))
+	for i, value := range values {
+		bar := 0
+		if high > 0 && value > 0 {
+			bar = int(value / high * float64(len(sparks)-1))
+		}
+
+		line[i] = sparks[bar]
+	}
+
+	return string(line)
+}
+
+func bounds(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+
+	low, high := values[0], values[0]
+	for _, value := range values[1:] {
+		if value < low {
+			low = value
+		}
+
+		if value > high {
+			high = value
+		}
+	}
+
+	return low, high
+}
@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		values []float64
+		line   string
+	}{
+		{[]float64{0, 1, 2, 3, 4, 5, 6, 7}, "▁▂▃▄▅▆▇█"},
+		{[]float64{10, 5, 0}, "█▄▁"},
+		{[]float64{0, 0}, "▁▁"},
+		{nil, ""},
+	}
+
+	for _, test := range tests {
+		if line := Sparkline(test.values); line != test.line {
+			t.Errorf("%v: drew %q, want %q", test.values, line, test.line)
+		}
+	}
+}
+
+func TestSampleMetric(t *testing.T) {
+	previous := garden.ContainerInfo{CPUStat: garden.ContainerCPUStat{Usage: uint64(time.Second)}}
+	info := garden.ContainerInfo{
+		CPUStat:    garden.ContainerCPUStat{Usage: uint64(2 * time.Second)},
+		MemoryStat: garden.ContainerMemoryStat{TotalRss: 1024},
+		DiskStat:   garden.ContainerDiskStat{BytesUsed: 2048},
+	}
+
+	if _, ok := sampleMetric("cpu", info, nil, 0); ok {
+		t.Error("sampled cpu without a previous sample")
+	}
+
+	// a second of cpu over two seconds is half a core
+	if value, ok := sampleMetric("cpu", info, &previous, 2*time.Second); !ok || value != 50 {
+		t.Errorf("sampled cpu %v (%v)", value, ok)
+	}
+
+	if _, ok := sampleMetric("cpu", previous, &info, time.Second); ok {
+		t.Error("sampled cpu when the usage went backwards")
+	}
+
+	if value, _ := sampleMetric("mem", info, nil, 0); value != 1024 {
+		t.Errorf("sampled mem %v", value)
+	}
+
+	if value, _ := sampleMetric("disk", info, nil, 0); value != 2048 {
+		t.Errorf("sampled disk %v", value)
+	}
+}
+
+func TestGraph(t *testing.T) {
+	used := []uint64{1 << 20, 2 << 20, 4 << 20}
+
+	container := fakeContainer("web")
+	container.InfoStub = func() (garden.ContainerInfo, error) {
+		if len(used) == 0 {
+			return garden.ContainerInfo{}, errors.New("container not found")
+		}
+
+		info := garden.ContainerInfo{MemoryStat: garden.ContainerMemoryStat{TotalRss: used[0]}}
+		used = used[1:]
+		return info, nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	var buf bytes.Buffer
+	err := Graph(fakeClient, "web", "mem", time.Millisecond, 2, &buf)
+	if err == nil || err.Error() != "container not found" {
+		t.Errorf("returned %v, want the container to have gone", err)
+	}
+
+	screens := strings.Split(buf.String(), "\033[H\033[2J")
+	last := screens[len(screens)-1]
+
+	// only the last two samples fit
+	if !strings.Contains(last, "\n\n▄█\n\nnow 4.0 MiB, min 2.0 MiB, max 4.0 MiB\n") {
+		t.Errorf("drew %q", last)
+	}
+}
+
+func TestGraphUnknownMetric(t *testing.T) {
+	err := Graph(new(fakes.FakeClient), "web", "network", time.Second, 10, new(bytes.Buffer))
+	if err == nil {
+		t.Error("expected an unknown metric to fail")
+	}
+}
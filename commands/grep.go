@@ -0,0 +1,236 @@
+package commands
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// GrepOptions describe how containers are searched. Helper, if given, is
+// used to search those without grep of their own.
+type GrepOptions struct {
+	IgnoreCase  bool
+	Helper      *Helper
+	Concurrency int
+}
+
+// GrepMatch is a line of a file in a container which matches the pattern.
+type GrepMatch struct {
+	Path string
+	Line int
+	Text string
+}
+
+// grepLine is a line printed by grep -rnH: path:line:text.
+var grepLine = regexp.MustCompile(`^(.*?):(\d+):(.*)$`)
+
+// Grep searches the files under dir in each of the containers for lines
+// matching the pattern, an extended regular expression, writing a
+// tab-separated line for each match in order of handle: the handle, the
+// path, the line number and the line. Each container is searched with its
+// own grep if it has one, else with the helper, else by streaming the files
+// out and searching them here. It returns the number of matches and what
+// happened to each container.
+func Grep(client garden.Client, handles []string, pattern string, dir string, opts GrepOptions, w io.Writer) (int, []Result) {
+	prefix := ""
+	if opts.IgnoreCase {
+		prefix = "(?i)"
+	}
+
+	compiled, err := regexp.Compile(prefix + pattern)
+	if err != nil {
+		results := []Result{}
+		for _, handle := range handles {
+			results = append(results, Result{handle, err})
+		}
+
+		return 0, results
+	}
+
+	var mu sync.Mutex
+	found := map[string][]GrepMatch{}
+
+	results := forEach(handles, opts.Concurrency, func(handle string) error {
+		container, err := client.Lookup(handle)
+		if err != nil {
+			return err
+		}
+
+		matches, err := grepContainer(container, pattern, compiled, dir, opts)
+
+		mu.Lock()
+		found[handle] = matches
+		mu.Unlock()
+
+		return err
+	})
+
+	total := 0
+	for _, handle := range handles {
+		for _, match := range found[handle] {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", handle, match.Path, match.Line, match.Text)
+			total++
+		}
+	}
+
+	return total, results
+}
+
+func grepContainer(container garden.Container, pattern string, compiled *regexp.Regexp, dir string, opts GrepOptions) ([]GrepMatch, error) {
+	args := []string{"-rnH", "-E"}
+	if opts.IgnoreCase {
+		args = append(args, "-i")
+	}
+
+	matches, ran, err := grepWith(container, garden.ProcessSpec{
+		Path: "grep",
+		Args: append(args, "-e", pattern, "--", dir),
+	}, parseGrepLine)
+	if ran {
+		return matches, err
+	}
+
+	if opts.Helper != nil {
+		if err := InstallHelper(container, opts.Helper); err != nil {
+			return nil, err
+		}
+
+		args := []string{"grep"}
+		if opts.IgnoreCase {
+			args = append(args, "-i")
+		}
+
+		matches, ran, err := grepWith(container, garden.ProcessSpec{
+			Path: HelperPath,
+			Args: append(args, pattern, dir),
+		}, parseHelperGrepLine)
+		if !ran {
+			return nil, errors.New("the helper could not be run")
+		}
+
+		return matches, err
+	}
+
+	return grepStreamed(container, compiled, dir)
+}
+
+// grepWith runs a grep in the container and parses the matches it prints.
+// It reports whether the grep could be run at all: as grep, it exits 0 when
+// it matched, 1 when it did not and 2 when it failed, and anything else is
+// taken to mean the container has no such program.
+func grepWith(container garden.Container, spec garden.ProcessSpec, parse func(string) (GrepMatch, bool)) ([]GrepMatch, bool, error) {
+	var stdout, stderr bytes.Buffer
+
+	process, err := container.Run(spec, garden.ProcessIO{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return nil, false, nil
+	}
+
+	status, err := process.Wait()
+	if err != nil {
+		return nil, true, err
+	}
+
+	if status != 0 && status != 1 && status != 2 {
+		return nil, false, nil
+	}
+
+	matches := []GrepMatch{}
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if match, ok := parse(line); ok {
+			matches = append(matches, match)
+		}
+	}
+
+	// files which could not be read do not spoil the matches in the rest
+	if status == 2 && len(matches) == 0 {
+		return nil, true, fmt.Errorf("grep failed: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	return matches, true, nil
+}
+
+func parseGrepLine(line string) (GrepMatch, bool) {
+	parts := grepLine.FindStringSubmatch(line)
+	if parts == nil {
+		return GrepMatch{}, false
+	}
+
+	n, _ := strconv.Atoi(parts[2])
+	return GrepMatch{Path: parts[1], Line: n, Text: parts[3]}, true
+}
+
+func parseHelperGrepLine(line string) (GrepMatch, bool) {
+	parts := strings.SplitN(line, "\t", 3)
+	if len(parts) != 3 {
+		return GrepMatch{}, false
+	}
+
+	n, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return GrepMatch{}, false
+	}
+
+	return GrepMatch{Path: parts[0], Line: n, Text: parts[2]}, true
+}
+
+// grepStreamed streams the files under dir out of the container and
+// searches them here, skipping those which look binary.
+func grepStreamed(container garden.Container, pattern *regexp.Regexp, dir string) ([]GrepMatch, error) {
+	output, err := container.StreamOut(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer output.Close()
+
+	matches := []GrepMatch{}
+
+	tr := tar.NewReader(output)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return matches, nil
+		}
+
+		if err != nil {
+			return matches, err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// the archive's entries are named from the base of dir
+		filePath := path.Join(path.Dir(path.Clean(dir)), path.Clean("/"+header.Name))
+
+		reader := bufio.NewReader(tr)
+		for n := 1; ; n++ {
+			line, err := reader.ReadBytes('\n')
+			if len(line) == 0 && err != nil || bytes.IndexByte(line, 0) >= 0 {
+				break
+			}
+
+			line = bytes.TrimRight(line, "\r\n")
+			if pattern.Match(line) {
+				matches = append(matches, GrepMatch{Path: filePath, Line: n, Text: string(line)})
+			}
+
+			if err != nil {
+				break
+			}
+		}
+	}
+}
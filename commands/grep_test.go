@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+// grepProcess is what a grep run in a container prints and exits with.
+func grepProcess(output string, status int) func(garden.ProcessSpec, garden.ProcessIO) (garden.Process, error) {
+	return func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		processIO.Stdout.Write([]byte(output))
+
+		process := new(fakes.FakeProcess)
+		process.WaitReturns(status, nil)
+		return process, nil
+	}
+}
+
+func TestGrep(t *testing.T) {
+	// web has grep of its own
+	web := fakeContainer("web")
+	web.RunStub = grepProcess("/etc/app/app.conf:3:debug = true\nBinary file /etc/app/app.db matches\n", 0)
+
+	// api has none, so is searched with the helper
+	api := fakeContainer("api")
+	api.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		if spec.Path == "grep" {
+			return nil, errors.New("executable file not found")
+		}
+
+		return grepProcess("/etc/app/app.conf\t7\tDEBUG = true\n", 0)(spec, processIO)
+	}
+
+	// db has nothing to match in
+	db := fakeContainer("db")
+	db.RunStub = grepProcess("", 1)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupStub = func(handle string) (garden.Container, error) {
+		return map[string]garden.Container{"web": web, "api": api, "db": db}[handle], nil
+	}
+
+	var out bytes.Buffer
+	matched, results := Grep(fakeClient, []string{"web", "api", "db"}, "debug", "/etc/app", GrepOptions{
+		IgnoreCase: true,
+		Helper:     &Helper{Binary: []byte("helper")},
+	}, &out)
+
+	if len(Failures(results)) != 0 {
+		t.Fatalf("failed: %v", results)
+	}
+
+	if want := "web\t/etc/app/app.conf\t3\tdebug = true\napi\t/etc/app/app.conf\t7\tDEBUG = true\n"; matched != 2 || out.String() != want {
+		t.Errorf("found %d: %q, want %q", matched, out.String(), want)
+	}
+
+	spec, _ := web.RunArgsForCall(0)
+	if want := []string{"-rnH", "-E", "-i", "-e", "debug", "--", "/etc/app"}; !reflect.DeepEqual(spec.Args, want) {
+		t.Errorf("ran grep with %v, want %v", spec.Args, want)
+	}
+
+	if spec, _ := api.RunArgsForCall(1); spec.Path != HelperPath || !reflect.DeepEqual(spec.Args, []string{"grep", "-i", "debug", "/etc/app"}) {
+		t.Errorf("ran %s %v", spec.Path, spec.Args)
+	}
+}
+
+func TestGrepStreamsFilesOutWithoutGrepOrHelper(t *testing.T) {
+	container := fakeContainer("web")
+	container.RunReturns(nil, errors.New("executable file not found"))
+	container.StreamOutStub = func(src string) (io.ReadCloser, error) {
+		return artifactTar(map[string]string{
+			"app/app.conf":   "port = 80\ndebug = true\n",
+			"app/bin/server": "\x7fELF\x00debug",
+		}), nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	var out bytes.Buffer
+	matched, results := Grep(fakeClient, []string{"web"}, "debug|port = [0-9]+", "/etc/app/", GrepOptions{}, &out)
+
+	if len(Failures(results)) != 0 {
+		t.Fatalf("failed: %v", results)
+	}
+
+	if want := "web\t/etc/app/app.conf\t1\tport = 80\nweb\t/etc/app/app.conf\t2\tdebug = true\n"; matched != 2 || out.String() != want {
+		t.Errorf("found %d: %q, want %q", matched, out.String(), want)
+	}
+
+	if container.StreamOutArgsForCall(0) != "/etc/app/" {
+		t.Errorf("streamed out %s", container.StreamOutArgsForCall(0))
+	}
+}
+
+func TestGrepReportsFailures(t *testing.T) {
+	container := fakeContainer("web")
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		processIO.Stderr.Write([]byte("grep: /missing: No such file or directory\n"))
+		return grepProcess("", 2)(spec, processIO)
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	_, results := Grep(fakeClient, []string{"web"}, "debug", "/missing", GrepOptions{}, new(bytes.Buffer))
+	if err := results[0].Err; err == nil || err.Error() != "grep failed: grep: /missing: No such file or directory" {
+		t.Errorf("got %v", err)
+	}
+
+	_, results = Grep(fakeClient, []string{"web"}, "(", "/etc", GrepOptions{}, new(bytes.Buffer))
+	if results[0].Err == nil {
+		t.Error("searched with an invalid pattern")
+	}
+}
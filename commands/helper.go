@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// HelperPath is where the helper is kept in a container, out of the way of
+// anything in its rootfs.
+const HelperPath = "/tmp/.gaol/helper"
+
+// helperProperty records the version of the helper streamed into a
+// container, so that it is only streamed in again when it changes.
+const helperProperty = "gaol:helper"
+
+// Helper is the static gaol-helper binary, which is streamed into
+// containers to do what their rootfs may have no tools for.
+type Helper struct {
+	Binary []byte
+}
+
+// Version identifies the helper by its contents.
+func (h *Helper) Version() string {
+	return fmt.Sprintf("%x", sha256.Sum256(h.Binary))[:16]
+}
+
+// InstallHelper streams the helper into the container unless the same
+// version is already there.
+func InstallHelper(container garden.Container, helper *Helper) error {
+	version, err := container.GetProperty(helperProperty)
+	if err == nil && version == helper.Version() {
+		return nil
+	}
+
+	reader, writer := io.Pipe()
+
+	go func() {
+		writer.CloseWithError(writeHelperTar(helper, writer))
+	}()
+
+	if err := container.StreamIn(path.Dir(HelperPath), reader); err != nil {
+		return fmt.Errorf("failed to stream in the helper: %s", err)
+	}
+
+	return container.SetProperty(helperProperty, helper.Version())
+}
+
+func writeHelperTar(helper *Helper, w io.Writer) error {
+	tw := tar.NewWriter(w)
+
+	err := tw.WriteHeader(&tar.Header{
+		Name:    path.Base(HelperPath),
+		Mode:    0755,
+		Size:    int64(len(helper.Binary)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := tw.Write(helper.Binary); err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// RunHelper runs one of the helper's tools in the container, streaming the
+// helper in first if it is not there yet.
+func RunHelper(container garden.Container, helper *Helper, args []string, processIO garden.ProcessIO) (garden.Process, error) {
+	if err := InstallHelper(container, helper); err != nil {
+		return nil, err
+	}
+
+	return container.Run(garden.ProcessSpec{
+		Path: HelperPath,
+		Args: args,
+	}, processIO)
+}
+
+// RunHelperTool runs one of the helper's tools in the container connected to
+// processIO, waiting for it to exit.
+func RunHelperTool(client garden.Client, handle string, helper *Helper, args []string, processIO garden.ProcessIO) error {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	process, err := RunHelper(container, helper, args, processIO)
+	if err != nil {
+		return err
+	}
+
+	return waitForExit(process, "gaol-helper "+args[0])
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestRunHelper(t *testing.T) {
+	helper := &Helper{Binary: []byte("#!/bin/sh\n")}
+
+	properties := map[string]string{}
+
+	container := fakeContainer("web")
+	container.GetPropertyStub = func(key string) (string, error) {
+		value, found := properties[key]
+		if !found {
+			return "", errors.New("no such property")
+		}
+
+		return value, nil
+	}
+	container.SetPropertyStub = func(key string, value string) error {
+		properties[key] = value
+		return nil
+	}
+
+	var streamed []byte
+	var mode int64
+	container.StreamInStub = func(dst string, r io.Reader) error {
+		if dst != "/tmp/.gaol" {
+			t.Errorf("streamed into %s", dst)
+		}
+
+		tr := tar.NewReader(r)
+		header, err := tr.Next()
+		if err != nil {
+			return err
+		}
+
+		mode = header.Mode
+		streamed, err = ioutil.ReadAll(tr)
+		return err
+	}
+
+	container.RunReturns(new(fakes.FakeProcess), nil)
+
+	for i := 0; i < 2; i++ {
+		if _, err := RunHelper(container, helper, []string{"ps"}, garden.ProcessIO{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the second run finds the same version already there
+	if container.StreamInCallCount() != 1 {
+		t.Errorf("streamed the helper in %d times, want once", container.StreamInCallCount())
+	}
+
+	if string(streamed) != "#!/bin/sh\n" || mode != 0755 {
+		t.Errorf("streamed %q with mode %o", streamed, mode)
+	}
+
+	if properties["gaol:helper"] != helper.Version() {
+		t.Errorf("recorded version %q", properties["gaol:helper"])
+	}
+
+	spec, _ := container.RunArgsForCall(1)
+	if !reflect.DeepEqual(spec, garden.ProcessSpec{Path: "/tmp/.gaol/helper", Args: []string{"ps"}}) {
+		t.Errorf("ran %#v", spec)
+	}
+
+	// a new helper replaces the old one
+	helper.Binary = []byte("#!/bin/sh\nexit 0\n")
+	if _, err := RunHelper(container, helper, []string{"ps"}, garden.ProcessIO{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if container.StreamInCallCount() != 2 {
+		t.Errorf("streamed the helper in %d times, want it streamed again", container.StreamInCallCount())
+	}
+}
+
+func TestRunHelperTool(t *testing.T) {
+	process := new(fakes.FakeProcess)
+	process.WaitReturns(22, nil)
+
+	container := fakeContainer("web")
+	container.RunReturns(process, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	err := RunHelperTool(fakeClient, "web", &Helper{Binary: []byte("x")}, []string{"curl", "-f", "http://localhost/"}, garden.ProcessIO{})
+	if err != (ProcessExitError{"gaol-helper curl", 22}) {
+		t.Errorf("got %v, want the exit status", err)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package commands
+
+import "os/exec"
+
+// shellCommand runs command with the shell.
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("/bin/sh", "-c", command)
+}
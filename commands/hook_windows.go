@@ -0,0 +1,8 @@
+package commands
+
+import "os/exec"
+
+// shellCommand runs command with the command interpreter.
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("cmd", "/C", command)
+}
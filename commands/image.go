@@ -0,0 +1,458 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	dockerHub       = "registry-1.docker.io"
+	defaultImageTag = "latest"
+)
+
+// The media types of the manifests gaol understands.
+const (
+	dockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	dockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	ociManifest        = "application/vnd.oci.image.manifest.v1+json"
+	ociIndex           = "application/vnd.oci.image.index.v1+json"
+)
+
+// ImageRef names an image in a registry.
+type ImageRef struct {
+	// Registry is the host[:port] of the registry.
+	Registry string
+
+	Repository string
+
+	// Reference is a tag or a digest.
+	Reference string
+}
+
+func (ref ImageRef) String() string {
+	separator := ":"
+	if strings.Contains(ref.Reference, ":") {
+		separator = "@"
+	}
+
+	return ref.Registry + "/" + ref.Repository + separator + ref.Reference
+}
+
+var repositoryPattern = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*(?:/[a-z0-9]+(?:[._-][a-z0-9]+)*)*$`)
+
+// ParseImageRef parses image names as docker does, such as nginx,
+// nginx:1.9, quay.io/coreos/etcd:v2 or busybox@sha256:..., and garden's
+// docker rootfs URLs, such as docker:///nginx#1.9.
+func ParseImageRef(name string) (ImageRef, error) {
+	if strings.HasPrefix(name, "docker:") {
+		return parseDockerURL(name)
+	}
+
+	ref := ImageRef{Registry: dockerHub, Reference: defaultImageTag}
+
+	remainder := name
+	if i := strings.Index(remainder, "@"); i >= 0 {
+		remainder, ref.Reference = remainder[:i], remainder[i+1:]
+	} else if i := strings.LastIndex(remainder, ":"); i >= 0 && !strings.Contains(remainder[i:], "/") {
+		remainder, ref.Reference = remainder[:i], remainder[i+1:]
+	}
+
+	// the first component is a registry if it looks like a host
+	parts := strings.SplitN(remainder, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.Registry, remainder = parts[0], parts[1]
+	}
+
+	return ref.withRepository(name, remainder)
+}
+
+func parseDockerURL(name string) (ImageRef, error) {
+	u, err := url.Parse(name)
+	if err != nil {
+		return ImageRef{}, err
+	}
+
+	ref := ImageRef{Registry: u.Host, Reference: u.Fragment}
+	if ref.Registry == "" {
+		ref.Registry = dockerHub
+	}
+
+	if ref.Reference == "" {
+		ref.Reference = defaultImageTag
+	}
+
+	return ref.withRepository(name, strings.TrimPrefix(u.Path, "/"))
+}
+
+func (ref ImageRef) withRepository(name string, repository string) (ImageRef, error) {
+	switch ref.Registry {
+	case "docker.io", "index.docker.io":
+		ref.Registry = dockerHub
+	}
+
+	// official images on the hub live in library/
+	if ref.Registry == dockerHub && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+
+	if !repositoryPattern.MatchString(repository) || ref.Reference == "" {
+		return ImageRef{}, fmt.Errorf("invalid image name %q", name)
+	}
+
+	ref.Repository = repository
+
+	return ref, nil
+}
+
+// RegistryOptions describe how to talk to a registry.
+type RegistryOptions struct {
+	// Username and Password authenticate to the registry, which otherwise
+	// is used anonymously.
+	Username string
+	Password string
+
+	// Insecure skips verifying the registry's certificate, and falls back to
+	// plain http for registries which do not speak https.
+	Insecure bool
+}
+
+// PulledImage is an image, pulled from a registry or loaded from disk, whose
+// layers have been flattened into a single tar archive of its filesystem.
+type PulledImage struct {
+	// Path is the tar archive.
+	Path string
+
+	// Env is the environment the image asks for.
+	Env []string
+}
+
+// Remove removes the archive.
+func (image *PulledImage) Remove() error {
+	return os.RemoveAll(filepath.Dir(image.Path))
+}
+
+// PullImage downloads the layers of an image for linux/amd64, checking their
+// digests, and flattens them into a single archive. Progress is written to
+// progress.
+func PullImage(ref ImageRef, opts RegistryOptions, progress io.Writer) (*PulledImage, error) {
+	r := &registry{
+		base: "https://" + ref.Registry,
+		ref:  ref,
+		opts: opts,
+	}
+
+	if opts.Insecure {
+		r.client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+
+	return pullImage(r, progress)
+}
+
+func pullImage(r *registry, progress io.Writer) (*PulledImage, error) {
+	fmt.Fprintf(progress, "pulling %s\n", r.ref)
+
+	m, err := r.manifest(r.ref.Reference)
+	if err != nil {
+		return nil, err
+	}
+
+	var config struct {
+		Config struct {
+			Env []string
+		} `json:"config"`
+	}
+
+	if err := r.blobJSON(m.Config, &config); err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "gaol-image")
+	if err != nil {
+		return nil, err
+	}
+
+	layers := []string{}
+	for i, layer := range m.Layers {
+		fmt.Fprintf(progress, "layer %d/%d %s (%s)\n", i+1, len(m.Layers), layer.Digest, HumanBytes(uint64(layer.Size)))
+
+		path := filepath.Join(dir, fmt.Sprintf("layer-%d", i))
+		if err := r.downloadBlob(layer, path); err != nil {
+			os.RemoveAll(dir)
+			return nil, err
+		}
+
+		layers = append(layers, path)
+	}
+
+	image := &PulledImage{
+		Path: filepath.Join(dir, "rootfs.tar"),
+		Env:  config.Config.Env,
+	}
+
+	if err := flattenLayersToFile(layers, image.Path); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	for _, layer := range layers {
+		os.Remove(layer)
+	}
+
+	return image, nil
+}
+
+// descriptor points at a blob in a registry.
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	Platform  *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+	} `json:"platform,omitempty"`
+}
+
+type imageManifest struct {
+	MediaType string       `json:"mediaType"`
+	Config    descriptor   `json:"config"`
+	Layers    []descriptor `json:"layers"`
+	Manifests []descriptor `json:"manifests"`
+}
+
+// registry talks the docker registry v2 API about a single repository.
+type registry struct {
+	base string
+	ref  ImageRef
+	opts RegistryOptions
+
+	client http.Client
+
+	// authorization is sent with every request once the registry has
+	// asked for it.
+	authorization string
+}
+
+// manifest fetches the manifest of the image, choosing linux/amd64 from
+// manifest lists.
+func (r *registry) manifest(reference string) (*imageManifest, error) {
+	resp, err := r.get("manifests/"+reference, dockerManifest, dockerManifestList, ociManifest, ociIndex)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	m := &imageManifest{}
+	if err := json.NewDecoder(resp.Body).Decode(m); err != nil {
+		return nil, fmt.Errorf("invalid manifest for %s: %s", r.ref, err)
+	}
+
+	if m.MediaType == "" {
+		m.MediaType = resp.Header.Get("Content-Type")
+	}
+
+	switch m.MediaType {
+	case dockerManifest, ociManifest:
+		return m, nil
+	case dockerManifestList, ociIndex:
+		for _, platform := range m.Manifests {
+			if platform.Platform != nil && platform.Platform.OS == "linux" && platform.Platform.Architecture == "amd64" {
+				return r.manifest(platform.Digest)
+			}
+		}
+
+		return nil, fmt.Errorf("%s has no image for linux/amd64", r.ref)
+	}
+
+	return nil, fmt.Errorf("%s has a manifest of unsupported type %q", r.ref, m.MediaType)
+}
+
+func (r *registry) blobJSON(blob descriptor, v interface{}) error {
+	resp, err := r.get("blobs/" + blob.Digest)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// downloadBlob saves a blob to path, checking that its contents match its
+// digest.
+func (r *registry) downloadBlob(blob descriptor, path string) error {
+	if !strings.HasPrefix(blob.Digest, "sha256:") {
+		return fmt.Errorf("unsupported digest %q", blob.Digest)
+	}
+
+	resp, err := r.get("blobs/" + blob.Digest)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(file, hash), resp.Body); err != nil {
+		return err
+	}
+
+	if digest := "sha256:" + hex.EncodeToString(hash.Sum(nil)); digest != blob.Digest {
+		return fmt.Errorf("blob %s downloaded with digest %s", blob.Digest, digest)
+	}
+
+	return file.Close()
+}
+
+// get requests a path under the repository, authenticating if the registry
+// asks for it.
+func (r *registry) get(path string, accept ...string) (*http.Response, error) {
+	resp, err := r.do(path, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && r.authorization == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		if err := r.authorize(challenge); err != nil {
+			return nil, err
+		}
+
+		resp, err = r.do(path, accept)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching %s of %s: %s", path, r.ref, resp.Status)
+	}
+
+	return resp, nil
+}
+
+func (r *registry) do(path string, accept []string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", r.base+"/v2/"+r.ref.Repository+"/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(accept) > 0 {
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+
+	if r.authorization != "" {
+		req.Header.Set("Authorization", r.authorization)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil && r.opts.Insecure && strings.HasPrefix(r.base, "https://") {
+		r.base = "http://" + strings.TrimPrefix(r.base, "https://")
+		return r.do(path, accept)
+	}
+
+	return resp, err
+}
+
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// authorize answers a WWW-Authenticate challenge, fetching a token from the
+// registry's token service for bearer challenges.
+func (r *registry) authorize(challenge string) error {
+	switch {
+	case strings.HasPrefix(challenge, "Basic"):
+		if r.opts.Username == "" {
+			return fmt.Errorf("%s needs a registry username and password", r.ref.Registry)
+		}
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.SetBasicAuth(r.opts.Username, r.opts.Password)
+		r.authorization = req.Header.Get("Authorization")
+
+		return nil
+	case strings.HasPrefix(challenge, "Bearer"):
+	default:
+		return fmt.Errorf("%s asked for unsupported authentication %q", r.ref.Registry, challenge)
+	}
+
+	params := map[string]string{}
+	for _, match := range challengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[match[1]] = match[2]
+	}
+
+	if params["realm"] == "" {
+		return fmt.Errorf("%s asked for a token without saying where to get one", r.ref.Registry)
+	}
+
+	query := url.Values{}
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + r.ref.Repository + ":pull"
+	}
+	query.Set("scope", scope)
+
+	req, err := http.NewRequest("GET", params["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	if r.opts.Username != "" {
+		req.SetBasicAuth(r.opts.Username, r.opts.Password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("authenticating to %s: %s", r.ref.Registry, resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return err
+	}
+
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+
+	if token.Token == "" {
+		return errors.New("the registry's token service gave no token")
+	}
+
+	r.authorization = "Bearer " + token.Token
+
+	return nil
+}
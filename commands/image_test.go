@@ -0,0 +1,313 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseImageRef(t *testing.T) {
+	tests := map[string]ImageRef{
+		"nginx":                           {dockerHub, "library/nginx", "latest"},
+		"nginx:1.9":                       {dockerHub, "library/nginx", "1.9"},
+		"docker.io/someone/app":           {dockerHub, "someone/app", "latest"},
+		"quay.io/coreos/etcd:v2":          {"quay.io", "coreos/etcd", "v2"},
+		"localhost:5000/app":              {"localhost:5000", "app", "latest"},
+		"localhost:5000/app:dev":          {"localhost:5000", "app", "dev"},
+		"busybox@sha256:abc":              {dockerHub, "library/busybox", "sha256:abc"},
+		"docker:///ubuntu#14.04":          {dockerHub, "library/ubuntu", "14.04"},
+		"docker://quay.io/coreos/etcd#v2": {"quay.io", "coreos/etcd", "v2"},
+	}
+
+	for name, want := range tests {
+		ref, err := ParseImageRef(name)
+		if err != nil {
+			t.Errorf("%q: %s", name, err)
+			continue
+		}
+
+		if ref != want {
+			t.Errorf("%q parsed as %#v, want %#v", name, ref, want)
+		}
+	}
+
+	for _, name := range []string{"", "UPPER", "nginx:", "a//b"} {
+		if _, err := ParseImageRef(name); err == nil {
+			t.Errorf("expected %q to be rejected", name)
+		}
+	}
+}
+
+type layerEntry struct {
+	name     string
+	contents string
+	typeflag byte
+	linkname string
+}
+
+// layer writes a gzipped layer with the entries.
+func layer(t *testing.T, entries ...layerEntry) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for _, entry := range entries {
+		typeflag := entry.typeflag
+		if typeflag == 0 {
+			typeflag = tar.TypeReg
+		}
+
+		err := tw.WriteHeader(&tar.Header{
+			Name:     entry.name,
+			Typeflag: typeflag,
+			Linkname: entry.linkname,
+			Mode:     0644,
+			Size:     int64(len(entry.contents)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tw.Write([]byte(entry.contents))
+	}
+
+	tw.Close()
+	gz.Close()
+
+	return buf.Bytes()
+}
+
+// archiveContents lists the entries of a tar archive, with the contents of
+// files.
+func archiveContents(t *testing.T, archive []byte) []string {
+	entries := []string{}
+
+	tr := tar.NewReader(bytes.NewReader(archive))
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+
+		contents, _ := ioutil.ReadAll(tr)
+
+		entry := header.Name
+		switch header.Typeflag {
+		case tar.TypeReg:
+			entry += "=" + string(contents)
+		case tar.TypeLink:
+			entry += "->" + header.Linkname
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Strings(entries)
+
+	return entries
+}
+
+func TestFlattenLayers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gaol-layers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	layers := [][]byte{
+		layer(t,
+			layerEntry{name: "./", typeflag: tar.TypeDir},
+			layerEntry{name: "etc/", typeflag: tar.TypeDir},
+			layerEntry{name: "etc/hostname", contents: "base"},
+			layerEntry{name: "etc/motd", contents: "hello"},
+			layerEntry{name: "cache/", typeflag: tar.TypeDir},
+			layerEntry{name: "cache/old", contents: "stale"},
+			layerEntry{name: "tmp/", typeflag: tar.TypeDir},
+			layerEntry{name: "tmp/junk", contents: "junk"},
+		),
+		layer(t,
+			layerEntry{name: "etc/hostname", contents: "app"},
+			layerEntry{name: "etc/.wh.motd"},
+			layerEntry{name: "cache/.wh..wh..opq"},
+			layerEntry{name: "cache/new", contents: "fresh"},
+			layerEntry{name: ".wh.tmp"},
+			layerEntry{name: "etc/alias", typeflag: tar.TypeLink, linkname: "etc/hostname"},
+		),
+	}
+
+	paths := []string{}
+	for i, contents := range layers {
+		path := filepath.Join(dir, fmt.Sprintf("layer-%d", i))
+		if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		paths = append(paths, path)
+	}
+
+	var flattened bytes.Buffer
+	if err := flattenLayers(paths, &flattened); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"cache/",
+		"cache/new=fresh",
+		"etc/",
+		"etc/alias->etc/hostname",
+		"etc/hostname=app",
+	}
+
+	if got := archiveContents(t, flattened.Bytes()); !reflect.DeepEqual(got, want) {
+		t.Errorf("flattened to %q, want %q", got, want)
+	}
+}
+
+func digest(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestPullImage(t *testing.T) {
+	layerBlob := layer(t, layerEntry{name: "bin/app", contents: "binary"})
+	config := []byte(`{"config": {"Env": ["PATH=/bin"]}}`)
+
+	manifest, _ := json.Marshal(imageManifest{
+		MediaType: dockerManifest,
+		Config:    descriptor{Digest: digest(config)},
+		Layers:    []descriptor{{Digest: digest(layerBlob), Size: int64(len(layerBlob))}},
+	})
+
+	list, _ := json.Marshal(imageManifest{
+		MediaType: dockerManifestList,
+		Manifests: []descriptor{
+			{Digest: "sha256:arm", Platform: &struct {
+				OS           string `json:"os"`
+				Architecture string `json:"architecture"`
+			}{"linux", "arm64"}},
+			{Digest: digest(manifest), Platform: &struct {
+				OS           string `json:"os"`
+				Architecture string `json:"architecture"`
+			}{"linux", "amd64"}},
+		},
+	})
+
+	blobs := map[string][]byte{
+		"manifests/latest":              list,
+		"manifests/" + digest(manifest): manifest,
+		"blobs/" + digest(config):       config,
+		"blobs/" + digest(layerBlob):    layerBlob,
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") != "repository:library/app:pull" {
+				http.Error(w, "wrong scope", http.StatusForbidden)
+				return
+			}
+
+			w.Write([]byte(`{"token": "secret"}`))
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		blob, found := blobs[strings.TrimPrefix(r.URL.Path, "/v2/library/app/")]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write(blob)
+	}))
+	defer server.Close()
+
+	ref, _ := ParseImageRef("app")
+
+	image, err := pullImage(&registry{base: server.URL, ref: ref}, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer image.Remove()
+
+	if !reflect.DeepEqual(image.Env, []string{"PATH=/bin"}) {
+		t.Errorf("pulled env %q", image.Env)
+	}
+
+	archive, err := ioutil.ReadFile(image.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := archiveContents(t, archive); !reflect.DeepEqual(got, []string{"bin/app=binary"}) {
+		t.Errorf("pulled %q", got)
+	}
+
+	// a corrupted layer is refused
+	blobs["blobs/"+digest(layerBlob)] = []byte("corrupt")
+	if _, err := pullImage(&registry{base: server.URL, ref: ref}, ioutil.Discard); err == nil {
+		t.Error("expected a layer which does not match its digest to be refused")
+	}
+}
+
+func TestPullImageInsecure(t *testing.T) {
+	layerBlob := layer(t, layerEntry{name: "bin/app", contents: "binary"})
+	config := []byte(`{}`)
+
+	manifest, _ := json.Marshal(imageManifest{
+		MediaType: dockerManifest,
+		Config:    descriptor{Digest: digest(config)},
+		Layers:    []descriptor{{Digest: digest(layerBlob)}},
+	})
+
+	blobs := map[string][]byte{
+		"manifests/latest":           manifest,
+		"blobs/" + digest(config):    config,
+		"blobs/" + digest(layerBlob): layerBlob,
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		blob, found := blobs[strings.TrimPrefix(r.URL.Path, "/v2/app/")]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write(blob)
+	})
+
+	for _, server := range []*httptest.Server{httptest.NewServer(handler), httptest.NewTLSServer(handler)} {
+		ref := ImageRef{Registry: server.Listener.Addr().String(), Repository: "app", Reference: "latest"}
+
+		if _, err := PullImage(ref, RegistryOptions{}, ioutil.Discard); err == nil {
+			t.Errorf("expected pulling from %s to fail without --insecure-registry", server.URL)
+		}
+
+		image, err := PullImage(ref, RegistryOptions{Insecure: true}, ioutil.Discard)
+		if err != nil {
+			t.Errorf("pulling from %s: %s", server.URL, err)
+		} else {
+			image.Remove()
+		}
+
+		server.Close()
+	}
+}
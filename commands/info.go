@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// ListHandles returns the handles of every container on the server.
+func ListHandles(client garden.Client) ([]string, error) {
+	containers, err := client.Containers(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	handles := make([]string, len(containers))
+	for i, container := range containers {
+		handles[i] = container.Handle()
+	}
+
+	return handles, nil
+}
+
+// infoLines renders the info of a container as one field per line.
+func infoLines(info garden.ContainerInfo, format Format) []string {
+	lines := []string{
+		"state: " + format.State(info.State),
+		"host ip: " + info.HostIP,
+		"container ip: " + info.ContainerIP,
+		"external ip: " + info.ExternalIP,
+		"container path: " + info.ContainerPath,
+	}
+
+	pids := make([]string, len(info.ProcessIDs))
+	for i, pid := range info.ProcessIDs {
+		pids[i] = fmt.Sprintf("%d", pid)
+	}
+
+	lines = append(lines,
+		"processes: "+strings.Join(pids, ", "),
+		"events: "+format.Problem(strings.Join(info.Events, ", "), info.Events),
+		fmt.Sprintf("memory: %s rss, %s cache, %s limit", HumanBytes(info.MemoryStat.TotalRss), HumanBytes(info.MemoryStat.TotalCache), HumanBytes(info.MemoryStat.HierarchicalMemoryLimit)),
+		// the CPU stats are nanoseconds of CPU time
+		fmt.Sprintf("cpu: %s usage, %s user, %s system", HumanDuration(time.Duration(info.CPUStat.Usage)), HumanDuration(time.Duration(info.CPUStat.User)), HumanDuration(time.Duration(info.CPUStat.System))),
+		fmt.Sprintf("disk: %s used, %d inodes", HumanBytes(info.DiskStat.BytesUsed), info.DiskStat.InodesUsed),
+		fmt.Sprintf("bandwidth: %s/s in rate, %s in burst, %s/s out rate, %s out burst", HumanBytes(info.BandwidthStat.InRate), HumanBytes(info.BandwidthStat.InBurst), HumanBytes(info.BandwidthStat.OutRate), HumanBytes(info.BandwidthStat.OutBurst)),
+	)
+
+	for _, mapping := range info.MappedPorts {
+		lines = append(lines, fmt.Sprintf("port: %d -> %d", mapping.HostPort, mapping.ContainerPort))
+	}
+
+	keys := make([]string, 0, len(info.Properties))
+	for key := range info.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		lines = append(lines, fmt.Sprintf("property: %s=%s", key, info.Properties[key]))
+	}
+
+	return lines
+}
+
+// Capacity writes the capacity of the server's machine to w, one field per
+// line.
+func Capacity(client garden.Client, w io.Writer) error {
+	capacity, err := client.Capacity()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "memory: %s\n", HumanBytes(capacity.MemoryInBytes))
+	fmt.Fprintf(w, "disk: %s\n", HumanBytes(capacity.DiskInBytes))
+	fmt.Fprintf(w, "max containers: %d\n", capacity.MaxContainers)
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// inventoryProperty holds, as JSON, the processes gaol has started in a
+// container, so that they can be found again from any machine for as long
+// as the container lives.
+const inventoryProperty = "gaol:processes"
+
+// inventorySize is how many processes the inventory remembers.
+const inventorySize = 20
+
+// ProcessRecord is a process gaol started in a container, and whether it
+// was run attached to a terminal or left in the background.
+type ProcessRecord struct {
+	PID      uint32    `json:"pid"`
+	Command  string    `json:"command"`
+	User     string    `json:"user,omitempty"`
+	Started  time.Time `json:"started"`
+	Attached bool      `json:"attached,omitempty"`
+}
+
+// inventory returns the processes recorded in the container's properties,
+// oldest first.
+func inventory(properties garden.Properties) ([]ProcessRecord, error) {
+	value, found := properties[inventoryProperty]
+	if !found {
+		return nil, nil
+	}
+
+	var records []ProcessRecord
+	if err := json.Unmarshal([]byte(value), &records); err != nil {
+		return nil, fmt.Errorf("invalid %s property: %s", inventoryProperty, err)
+	}
+
+	return records, nil
+}
+
+// recordProcess adds the process to the container's inventory, forgetting
+// the oldest once it is full.
+func recordProcess(container garden.Container, record ProcessRecord) error {
+	info, err := container.Info()
+	if err != nil {
+		return err
+	}
+
+	records, err := inventory(info.Properties)
+	if err != nil {
+		return err
+	}
+
+	records = append(records, record)
+	if len(records) > inventorySize {
+		records = records[len(records)-inventorySize:]
+	}
+
+	value, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	return container.SetProperty(inventoryProperty, string(value))
+}
+
+// Processes returns the processes gaol has started in the container, oldest
+// first.
+func Processes(client garden.Client, handle string) ([]ProcessRecord, error) {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := container.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	return inventory(info.Properties)
+}
+
+// LastProcess returns the PID of the process gaol most recently started in
+// the background in the container.
+func LastProcess(client garden.Client, handle string) (uint32, error) {
+	records, err := Processes(client, handle)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := len(records) - 1; i >= 0; i-- {
+		if !records[i].Attached {
+			return records[i].PID, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no processes started by gaol in the background in %s", handle)
+}
+
+// FindProcess returns the PID of the process gaol most recently started in
+// the container whose command contains the text.
+func FindProcess(client garden.Client, handle string, text string) (uint32, error) {
+	records, err := Processes(client, handle)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := len(records) - 1; i >= 0; i-- {
+		if strings.Contains(records[i].Command, text) {
+			return records[i].PID, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no process started by gaol in %s has a command containing %q", handle, text)
+}
+
+// PrintProcesses writes a tab-separated line for each process with its PID,
+// when it started, whether it was attached or detached, the user it ran as
+// (or - for the default) and its command.
+func PrintProcesses(w io.Writer, records []ProcessRecord) {
+	for _, record := range records {
+		mode := "detached"
+		if record.Attached {
+			mode = "attached"
+		}
+
+		user := record.User
+		if user == "" {
+			user = "-"
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", record.PID, record.Started.Format(time.RFC3339), mode, user, record.Command)
+	}
+}
+
+// KnownProcess is a process in a container which gaol knows the PID of,
+// with the name or command it is known by.
+type KnownProcess struct {
+	PID         uint32
+	Description string
+}
+
+// KnownProcesses returns the processes gaol has started in the container
+// and the named processes of manifests and supervision, most recent first.
+func KnownProcesses(client garden.Client, handle string) ([]KnownProcess, error) {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := container.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := inventory(info.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	known := []KnownProcess{}
+	seen := map[uint32]bool{}
+
+	for i := len(records) - 1; i >= 0; i-- {
+		if !seen[records[i].PID] {
+			known = append(known, KnownProcess{records[i].PID, records[i].Command})
+			seen[records[i].PID] = true
+		}
+	}
+
+	names := []string{}
+	for key := range info.Properties {
+		if strings.HasPrefix(key, processPropertyPrefix) {
+			names = append(names, strings.TrimPrefix(key, processPropertyPrefix))
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		pid, err := strconv.ParseUint(info.Properties[processPropertyPrefix+name], 10, 32)
+		if err != nil || seen[uint32(pid)] {
+			continue
+		}
+
+		known = append(known, KnownProcess{uint32(pid), name})
+		seen[uint32(pid)] = true
+	}
+
+	return known, nil
+}
@@ -0,0 +1,202 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+// withInventory makes the container's properties hold the records.
+func withInventory(container *fakes.FakeContainer, properties garden.Properties, records ...ProcessRecord) {
+	value, _ := json.Marshal(records)
+
+	all := garden.Properties{inventoryProperty: string(value)}
+	for key, value := range properties {
+		all[key] = value
+	}
+
+	container.InfoReturns(garden.ContainerInfo{Properties: all}, nil)
+}
+
+func TestRunRecordsBackgroundProcesses(t *testing.T) {
+	process := new(fakes.FakeProcess)
+	process.IDReturns(7)
+
+	container := fakeContainer("a")
+	container.RunReturns(process, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	var buf bytes.Buffer
+	if err := Run(fakeClient, "a", "sleep 60", RunOptions{}, garden.ProcessIO{}, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if container.SetPropertyCallCount() != 1 {
+		t.Fatalf("set %d properties, want the process recorded", container.SetPropertyCallCount())
+	}
+
+	name, value := container.SetPropertyArgsForCall(0)
+	records, err := inventory(garden.Properties{name: value})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 1 || records[0].PID != 7 || records[0].Command != "sleep 60" || records[0].Started.IsZero() {
+		t.Errorf("recorded %#v", records)
+	}
+}
+
+func TestRunRecordsAttachedProcesses(t *testing.T) {
+	process := new(fakes.FakeProcess)
+	process.IDReturns(8)
+
+	container := fakeContainer("a")
+	container.RunReturns(process, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	err := Run(fakeClient, "a", "make test", RunOptions{Attach: true, User: "vcap"}, garden.ProcessIO{}, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, value := container.SetPropertyArgsForCall(0)
+	records, _ := inventory(garden.Properties{name: value})
+
+	if len(records) != 1 || !records[0].Attached || records[0].User != "vcap" {
+		t.Errorf("recorded %#v", records)
+	}
+}
+
+func TestRecordProcessForgetsTheOldest(t *testing.T) {
+	records := []ProcessRecord{}
+	for pid := uint32(1); pid <= inventorySize; pid++ {
+		records = append(records, ProcessRecord{PID: pid, Command: "true"})
+	}
+
+	container := fakeContainer("a")
+	withInventory(container, nil, records...)
+
+	if err := recordProcess(container, ProcessRecord{PID: 100, Command: "make"}); err != nil {
+		t.Fatal(err)
+	}
+
+	name, value := container.SetPropertyArgsForCall(0)
+	saved, err := inventory(garden.Properties{name: value})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(saved) != inventorySize || saved[0].PID != 2 || saved[len(saved)-1].PID != 100 {
+		t.Errorf("saved %v", saved)
+	}
+}
+
+func TestLastProcess(t *testing.T) {
+	container := fakeContainer("a")
+	withInventory(container, nil,
+		ProcessRecord{PID: 4, Command: "server", Started: time.Now()},
+		ProcessRecord{PID: 9, Command: "worker", Started: time.Now()},
+		ProcessRecord{PID: 11, Command: "bash", Started: time.Now(), Attached: true},
+	)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	pid, err := LastProcess(fakeClient, "a")
+	if err != nil || pid != 9 {
+		t.Errorf("returned %d (%v), want the most recent in the background", pid, err)
+	}
+
+	container.InfoReturns(garden.ContainerInfo{}, nil)
+	if _, err := LastProcess(fakeClient, "a"); err == nil {
+		t.Error("found a process in a container gaol started none in")
+	}
+}
+
+func TestFindProcess(t *testing.T) {
+	container := fakeContainer("a")
+	withInventory(container, nil,
+		ProcessRecord{PID: 4, Command: "server --port 80"},
+		ProcessRecord{PID: 9, Command: "server --port 8080"},
+		ProcessRecord{PID: 11, Command: "worker"},
+	)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	tests := map[string]uint32{
+		"server":  9,
+		"port 80": 9,
+		"work":    11,
+		"deploy":  0,
+	}
+
+	for text, want := range tests {
+		pid, err := FindProcess(fakeClient, "a", text)
+		if want == 0 {
+			if err == nil {
+				t.Errorf("%q: found %d", text, pid)
+			}
+			continue
+		}
+
+		if err != nil || pid != want {
+			t.Errorf("%q: found %d (%v), want %d", text, pid, err, want)
+		}
+	}
+}
+
+func TestPrintProcesses(t *testing.T) {
+	started := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	PrintProcesses(&buf, []ProcessRecord{
+		{PID: 4, Command: "server", Started: started},
+		{PID: 9, Command: "bash", User: "root", Started: started, Attached: true},
+	})
+
+	want := "4\t2026-10-16T09:00:00Z\tdetached\t-\tserver\n9\t2026-10-16T09:00:00Z\tattached\troot\tbash\n"
+	if buf.String() != want {
+		t.Errorf("printed %q, want %q", buf.String(), want)
+	}
+}
+
+func TestKnownProcesses(t *testing.T) {
+	container := fakeContainer("a")
+	withInventory(container, garden.Properties{
+		processPropertyPrefix + "web":    "12",
+		processPropertyPrefix + "worker": "9",
+		processPropertyPrefix + "broken": "x",
+	},
+		ProcessRecord{PID: 4, Command: "make test"},
+		ProcessRecord{PID: 9, Command: "worker --queue a"},
+	)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	known, err := KnownProcesses(fakeClient, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []KnownProcess{
+		{9, "worker --queue a"},
+		{4, "make test"},
+		{12, "web"},
+	}
+
+	if !reflect.DeepEqual(known, want) {
+		t.Errorf("returned %v, want %v", known, want)
+	}
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JSONLine is what a long-running command writes, one to a line, with
+// --output jsonl: something of a type which happened to a container, and
+// when.
+type JSONLine struct {
+	Time    time.Time   `json:"time"`
+	Handle  string      `json:"handle,omitempty"`
+	Type    string      `json:"type"`
+	Payload interface{} `json:"payload"`
+}
+
+// JSONLines writes JSON lines to w. It is safe to use from several
+// goroutines.
+type JSONLines struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// NewJSONLines writes JSON lines to w.
+func NewJSONLines(w io.Writer) *JSONLines {
+	return &JSONLines{w: w}
+}
+
+// Write writes a line, timed now, of the type about the container.
+func (j *JSONLines) Write(handle string, kind string, payload interface{}) error {
+	line, err := json.Marshal(JSONLine{
+		Time:    time.Now().UTC(),
+		Handle:  handle,
+		Type:    kind,
+		Payload: payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	_, err = j.w.Write(append(line, '\n'))
+	return err
+}
+
+// streamWriter writes the lines of a process's output stream as JSON
+// lines, with the stream as their type. Each write must be a whole line.
+type streamWriter struct {
+	lines  *JSONLines
+	handle string
+	stream string
+}
+
+func (s streamWriter) Write(line []byte) (int, error) {
+	err := s.lines.Write(s.handle, s.stream, strings.TrimSuffix(string(line), "\n"))
+	if err != nil {
+		return 0, err
+	}
+
+	return len(line), nil
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+// readJSONLines decodes what was written with JSONLines, leaving out the
+// times.
+func readJSONLines(t *testing.T, buf *bytes.Buffer) []JSONLine {
+	lines := []JSONLine{}
+
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var line JSONLine
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			t.Fatalf("wrote %q: %s", raw, err)
+		}
+
+		if line.Time.IsZero() {
+			t.Errorf("wrote %q without a time", raw)
+		}
+
+		line.Time = time.Time{}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+func TestJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	lines := NewJSONLines(&buf)
+
+	lines.Write("web", "stdout", "hello")
+	lines.Write("", "added", map[string]int{"status": 1})
+
+	if strings.Count(buf.String(), "\n") != 2 {
+		t.Fatalf("wrote %q, want a line each", buf.String())
+	}
+
+	got := readJSONLines(t, &buf)
+	if got[0].Handle != "web" || got[0].Type != "stdout" || got[0].Payload != "hello" {
+		t.Errorf("wrote %#v", got[0])
+	}
+
+	if payload, ok := got[1].Payload.(map[string]interface{}); !ok || payload["status"] != 1.0 {
+		t.Errorf("wrote %#v", got[1])
+	}
+
+	if strings.Contains(buf.String(), `"handle":""`) {
+		t.Errorf("wrote %q, want no handle when there is none", buf.String())
+	}
+}
+
+func TestWatchListJSON(t *testing.T) {
+	polls := [][]string{{"web", "db"}, {"web", "worker"}}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersStub = func(garden.Properties) ([]garden.Container, error) {
+		if len(polls) == 0 {
+			return nil, errors.New("connection refused")
+		}
+
+		containers := []garden.Container{}
+		for _, handle := range polls[0] {
+			containers = append(containers, fakeContainer(handle))
+		}
+
+		polls = polls[1:]
+		return containers, nil
+	}
+
+	var buf bytes.Buffer
+	err := WatchList(fakeClient, new(bytes.Buffer), time.Millisecond, Format{}, NewJSONLines(&buf))
+	if err == nil {
+		t.Error("expected watching to stop when the server went away")
+	}
+
+	want := []JSONLine{
+		{Handle: "web", Type: "added", Payload: "web"},
+		{Handle: "db", Type: "added", Payload: "db"},
+		{Handle: "worker", Type: "added", Payload: "worker"},
+		{Handle: "db", Type: "removed", Payload: "db"},
+	}
+
+	got := readJSONLines(t, &buf)
+	if len(got) != len(want) {
+		t.Fatalf("wrote %v, want %v", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("wrote %v, want %v", got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// Report collects the commands gaol runs to completion as test cases, so
+// that they can be written out as JUnit XML for CI dashboards. A nil report
+// collects nothing.
+type Report struct {
+	mu    sync.Mutex
+	cases []TestCase
+}
+
+// TestCase is a command run to completion in a container, how long it took,
+// why it failed, if it did, and the end of its output.
+type TestCase struct {
+	Container string
+	Command   string
+	Duration  time.Duration
+	Err       error
+	Output    string
+}
+
+// Add records the test case.
+func (r *Report) Add(tc TestCase) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	r.cases = append(r.cases, tc)
+	r.mu.Unlock()
+}
+
+// Cases returns the test cases recorded so far, in the order they finished.
+func (r *Report) Cases() []TestCase {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]TestCase{}, r.cases...)
+}
+
+// run runs the attached command as Run does, recording it as a test case.
+func (r *Report) run(client garden.Client, handle string, command string, opts RunOptions, processIO garden.ProcessIO, w io.Writer) error {
+	opts.Report = nil
+
+	output := new(OutputTail)
+	processIO.Stdout = teeOutput(processIO.Stdout, output)
+	processIO.Stderr = teeOutput(processIO.Stderr, output)
+
+	started := time.Now()
+	err := Run(client, handle, command, opts, processIO, w)
+
+	r.Add(TestCase{handle, command, time.Since(started), err, output.String()})
+
+	return err
+}
+
+// teeOutput writes to both w, if there is one, and the tail.
+func teeOutput(w io.Writer, tail *OutputTail) io.Writer {
+	if w == nil {
+		return tail
+	}
+
+	return io.MultiWriter(w, tail)
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// WriteJUnit writes the report to path as JUnit XML: a suite of the given
+// name with a test case for each command, named after the command and
+// classed by its container. Commands which exit unsuccessfully or time out
+// are failures; those which could not be run at all are errors.
+func (r *Report) WriteJUnit(path string, name string) error {
+	suite := junitSuite{Name: name, Cases: []junitCase{}}
+
+	var total time.Duration
+	for _, tc := range r.Cases() {
+		jc := junitCase{
+			ClassName: tc.Container,
+			Name:      tc.Command,
+			Time:      junitTime(tc.Duration),
+			SystemOut: tc.Output,
+		}
+
+		switch err := tc.Err.(type) {
+		case nil:
+		case ProcessExitError:
+			jc.Failure = &junitFailure{err.Error(), "exit_status"}
+			suite.Failures++
+		case ProcessTimeoutError:
+			jc.Failure = &junitFailure{err.Error(), "timeout"}
+			suite.Failures++
+		default:
+			jc.Error = &junitFailure{err.Error(), "error"}
+			suite.Errors++
+		}
+
+		suite.Cases = append(suite.Cases, jc)
+		suite.Tests++
+		total += tc.Duration
+	}
+
+	suite.Time = junitTime(total)
+
+	contents, err := xml.MarshalIndent(junitSuites{Suites: []junitSuite{suite}}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append([]byte(xml.Header), append(contents, '\n')...), 0644)
+}
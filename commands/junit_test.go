@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestRunRecordsTestCases(t *testing.T) {
+	process := new(fakes.FakeProcess)
+	process.WaitReturns(1, nil)
+
+	container := fakeContainer("a")
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		if processIO.Stderr != nil {
+			fmt.Fprintln(processIO.Stderr, "2 tests failed")
+		}
+		return process, nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	report := new(Report)
+	opts := RunOptions{Report: report}
+
+	// only attached processes run to completion
+	Run(fakeClient, "a", "sleep 60", opts, garden.ProcessIO{}, ioutil.Discard)
+
+	opts.Attach = true
+	err := Run(fakeClient, "a", "make test", opts, garden.ProcessIO{}, nil)
+
+	cases := report.Cases()
+	if len(cases) != 1 {
+		t.Fatalf("recorded %#v", cases)
+	}
+
+	if cases[0].Container != "a" || cases[0].Command != "make test" || cases[0].Err != err || cases[0].Output != "2 tests failed\n" {
+		t.Errorf("recorded %#v", cases[0])
+	}
+}
+
+func TestUpRecordsRunSteps(t *testing.T) {
+	passing := new(fakes.FakeProcess)
+	failing := new(fakes.FakeProcess)
+	failing.WaitReturns(2, nil)
+
+	container := fakeContainer("web")
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		if spec.Path == "false" {
+			return failing, nil
+		}
+		return passing, nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.CreateStub = func(spec garden.ContainerSpec) (garden.Container, error) {
+		if spec.Handle == "db" {
+			return nil, errors.New("out of space")
+		}
+		return container, nil
+	}
+
+	manifest := &Manifest{
+		Containers: map[string]ManifestContainer{
+			"web": {Handle: "web", Run: []ManifestRun{{Command: "true"}, {Command: "false"}}},
+		},
+	}
+
+	report := new(Report)
+	if err := Up(fakeClient, manifest, ioutil.Discard, ioutil.Discard, report); err == nil {
+		t.Error("brought up a container whose run step failed")
+	}
+
+	manifest.Containers = map[string]ManifestContainer{"db": {Handle: "db"}}
+	if err := Up(fakeClient, manifest, ioutil.Discard, ioutil.Discard, report); err == nil {
+		t.Error("brought up a container which could not be created")
+	}
+
+	got := []string{}
+	for _, tc := range report.Cases() {
+		got = append(got, fmt.Sprintf("%s %s %t", tc.Container, tc.Command, tc.Err == nil))
+	}
+
+	want := "web true true, web false false, db up false"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("recorded %s, want %s", strings.Join(got, ", "), want)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gaol-junit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	report := new(Report)
+	report.Add(TestCase{"web", "true", 1500 * time.Millisecond, nil, "ok\n"})
+	report.Add(TestCase{"web", "make test", time.Second, ProcessExitError{"make test", 2}, "<failed>\n"})
+	report.Add(TestCase{"db", "true", 0, errors.New("unknown handle: db"), ""})
+
+	path := filepath.Join(dir, "report.xml")
+	if err := report.WriteJUnit(path, "gaol run"); err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`<?xml version="1.0" encoding="UTF-8"?>`,
+		`<testsuite name="gaol run" tests="3" failures="1" errors="1" time="2.500">`,
+		`<testcase classname="web" name="true" time="1.500">`,
+		`<failure message="make test exited with status 2" type="exit_status"></failure>`,
+		`<system-out>&lt;failed&gt;&#xA;</system-out>`,
+		`<error message="unknown handle: db" type="error"></error>`,
+	} {
+		if !strings.Contains(string(contents), want) {
+			t.Errorf("wrote %s, want it to contain %s", contents, want)
+		}
+	}
+}
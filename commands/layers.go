@@ -0,0 +1,180 @@
+package commands
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// The names with which layers mark files and directories deleted from the
+// layers below them.
+const (
+	whiteoutPrefix = ".wh."
+	opaqueWhiteout = ".wh..wh..opq"
+)
+
+func flattenLayersToFile(layers []string, dst string) error {
+	file, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := flattenLayers(layers, file); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+// flattenLayers writes a single tar archive of the filesystem made by
+// applying the layers, each a tar archive which may be gzipped, in order.
+// Files are taken from the highest layer which has them and files deleted
+// by a layer's whiteouts are left out.
+//
+// The layers are read from the top down, so that a file only needs to be
+// remembered by name to know that the layers below cannot replace it.
+// Hard links are written last, after the files they link to.
+func flattenLayers(layers []string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+
+	written := map[string]bool{}
+	notDirs := map[string]bool{}
+	deleted := map[string]bool{}
+	opaque := map[string]bool{}
+	links := []*tar.Header{}
+
+	// hidden reports whether the layers above delete or replace the file
+	hidden := func(name string) bool {
+		if written[name] || deleted[name] {
+			return true
+		}
+
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if deleted[dir] || opaque[dir] || notDirs[dir] {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for i := len(layers) - 1; i >= 0; i-- {
+		// whiteouts only apply to the layers below
+		layerDeleted := map[string]bool{}
+		layerOpaque := map[string]bool{}
+
+		err := readLayer(layers[i], func(header *tar.Header, tr *tar.Reader) error {
+			name := cleanLayerPath(header.Name)
+			if name == "" {
+				return nil
+			}
+
+			dir, base := path.Split(name)
+			switch {
+			case base == opaqueWhiteout:
+				layerOpaque[path.Clean(dir)] = true
+				return nil
+			case strings.HasPrefix(base, whiteoutPrefix):
+				layerDeleted[path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix))] = true
+				return nil
+			}
+
+			if hidden(name) {
+				return nil
+			}
+
+			written[name] = true
+			if header.Typeflag != tar.TypeDir {
+				notDirs[name] = true
+			}
+
+			header.Name = name
+			if header.Typeflag == tar.TypeDir {
+				header.Name += "/"
+			}
+
+			if header.Typeflag == tar.TypeLink {
+				header.Linkname = cleanLayerPath(header.Linkname)
+				links = append(links, header)
+				return nil
+			}
+
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+
+			_, err := io.Copy(tw, tr)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		for name := range layerDeleted {
+			deleted[name] = true
+		}
+
+		for name := range layerOpaque {
+			opaque[name] = true
+		}
+	}
+
+	for _, link := range links {
+		if err := tw.WriteHeader(link); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+// readLayer calls f with each entry of a layer, ungzipping it if need be.
+func readLayer(layer string, f func(*tar.Header, *tar.Reader) error) error {
+	file, err := os.Open(layer)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r, err := gunzipped(file)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := f(header, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// gunzipped ungzips r if it is gzipped, going by its first bytes.
+func gunzipped(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+
+	if magic, _ := buffered.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(buffered)
+	}
+
+	return buffered, nil
+}
+
+// cleanLayerPath makes a path in a layer relative to its root.
+func cleanLayerPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
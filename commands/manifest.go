@@ -0,0 +1,614 @@
+package commands
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-shellwords"
+	"github.com/pivotal-golang/archiver/compressor"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// Properties set on every container created from a manifest so that the
+// live containers can be matched back to it.
+const (
+	manifestProperty = "gaol:manifest"
+	nameProperty     = "gaol:name"
+)
+
+// specProperty holds a digest of what a manifest container was created with,
+// so that apply can tell when the manifest has changed since.
+const specProperty = "gaol:spec"
+
+// Manifest describes a set of containers which should exist together. The
+// name defaults to the name of the directory holding the manifest, followed
+// by the manifest's own name unless it is gaol.yml, so that manifests kept
+// side by side do not take each other's containers for their own.
+type Manifest struct {
+	Name       string                       `yaml:"name"`
+	Containers map[string]ManifestContainer `yaml:"containers"`
+	Monitor    ManifestMonitor              `yaml:"monitor"`
+}
+
+// ManifestMonitor says how often gaol monitor checks the containers, how
+// much of their limits they may use, and what it does when one has gone or a
+// process has died, beyond restarting processes as their restart policies
+// say. Hook is a shell command run for each problem.
+type ManifestMonitor struct {
+	Interval time.Duration `yaml:"interval"`
+	Recreate bool          `yaml:"recreate"`
+	Alert    Thresholds    `yaml:"alert"`
+	Webhook  string        `yaml:"webhook"`
+	Hook     string        `yaml:"hook"`
+}
+
+// ManifestContainer describes a single container in a manifest. The handle
+// defaults to the name the container is given in the manifest.
+type ManifestContainer struct {
+	Handle     string            `yaml:"handle"`
+	RootFS     string            `yaml:"rootfs"`
+	Grace      time.Duration     `yaml:"grace"`
+	Privileged bool              `yaml:"privileged"`
+	Network    string            `yaml:"network"`
+	Env        []string          `yaml:"env"`
+	Properties map[string]string `yaml:"properties"`
+	Limits     ManifestLimits    `yaml:"limits"`
+	BindMounts []ManifestMount   `yaml:"bind_mounts"`
+	NetIn      []ManifestNetIn   `yaml:"net_in"`
+	Files      []ManifestFile    `yaml:"files"`
+	Run        []ManifestRun     `yaml:"run"`
+	Processes  []ManifestProcess `yaml:"processes"`
+}
+
+type ManifestLimits struct {
+	Memory    ByteSize `yaml:"memory"`
+	Disk      ByteSize `yaml:"disk"`
+	CPU       uint64   `yaml:"cpu"`
+	Bandwidth ByteSize `yaml:"bandwidth"`
+	Burst     ByteSize `yaml:"burst"`
+}
+
+type ManifestMount struct {
+	Src    string `yaml:"src"`
+	Dst    string `yaml:"dst"`
+	Mode   string `yaml:"mode"`
+	Origin string `yaml:"origin"`
+}
+
+type ManifestNetIn struct {
+	Host      uint32 `yaml:"host"`
+	Container uint32 `yaml:"container"`
+}
+
+type ManifestFile struct {
+	Src string `yaml:"src"`
+	Dst string `yaml:"dst"`
+}
+
+// ManifestRun is a command which is run to completion after the container
+// has been created and its files have been streamed in.
+type ManifestRun struct {
+	Command    string   `yaml:"command"`
+	User       string   `yaml:"user"`
+	Dir        string   `yaml:"dir"`
+	Privileged bool     `yaml:"privileged"`
+	Env        []string `yaml:"env"`
+}
+
+// ManifestProcess is a long-running process which is started once the
+// container has been provisioned. Its PID is recorded in the container's
+// properties so that it can be found again later.
+type ManifestProcess struct {
+	Name       string        `yaml:"name"`
+	Command    string        `yaml:"command"`
+	User       string        `yaml:"user"`
+	Dir        string        `yaml:"dir"`
+	Privileged bool          `yaml:"privileged"`
+	Env        []string      `yaml:"env"`
+	Restart    string        `yaml:"restart"`
+	Check      ManifestCheck `yaml:"check"`
+}
+
+// ManifestCheck is how gaol monitor tells that a process is healthy. A port
+// must accept connections on its mapped host port; without one the process
+// need only be running.
+type ManifestCheck struct {
+	Port uint32 `yaml:"port"`
+}
+
+// Restart policies for manifest processes.
+const (
+	restartNever     = "no"
+	restartOnFailure = "on-failure"
+	restartAlways    = "always"
+)
+
+// ByteSize is a number of bytes which may be written with a unit suffix
+// (e.g. 512M or 2G) in a manifest.
+type ByteSize uint64
+
+func (b *ByteSize) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw string
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	size, err := ParseByteSize(raw)
+	if err != nil {
+		return err
+	}
+
+	*b = ByteSize(size)
+	return nil
+}
+
+// ParseByteSize parses a number of bytes with an optional unit suffix, such
+// as 512M or 2G.
+func ParseByteSize(raw string) (uint64, error) {
+	s := strings.ToUpper(strings.TrimSpace(raw))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+
+	multiplier := uint64(1)
+	if len(s) > 0 {
+		switch s[len(s)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier != 1 {
+			s = s[:len(s)-1]
+		}
+	}
+
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size: %q", raw)
+	}
+
+	return n * multiplier, nil
+}
+
+// LoadManifest reads the manifest at path, rendering it with the data, and
+// validates it, filling in the defaults which depend on where it is.
+func LoadManifest(path string, data TemplateData) (*Manifest, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err = render(path, contents, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := yaml.Unmarshal(contents, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %s", path, err)
+	}
+
+	if len(manifest.Containers) == 0 {
+		return nil, fmt.Errorf("manifest %s does not describe any containers", path)
+	}
+
+	// files are relative to the manifest rather than the working directory
+	base := filepath.Dir(path)
+
+	if manifest.Name == "" {
+		abs, err := filepath.Abs(base)
+		if err != nil {
+			return nil, err
+		}
+
+		manifest.Name = filepath.Base(abs)
+
+		if file := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)); file != "gaol" {
+			manifest.Name += "-" + file
+		}
+	}
+
+	for name, mc := range manifest.Containers {
+		if mc.Handle == "" {
+			mc.Handle = name
+		}
+
+		for i, file := range mc.Files {
+			if file.Src == "" || file.Dst == "" {
+				return nil, fmt.Errorf("container %s: files need both a src and a dst", name)
+			}
+
+			if !filepath.IsAbs(file.Src) {
+				mc.Files[i].Src = filepath.Join(base, file.Src)
+			}
+		}
+
+		seen := map[string]bool{}
+		for i, process := range mc.Processes {
+			if process.Name == "" || process.Command == "" {
+				return nil, fmt.Errorf("container %s: processes need both a name and a command", name)
+			}
+
+			if seen[process.Name] {
+				return nil, fmt.Errorf("container %s: duplicate process %s", name, process.Name)
+			}
+			seen[process.Name] = true
+
+			switch process.Restart {
+			case "":
+				mc.Processes[i].Restart = restartNever
+			case restartNever, restartOnFailure, restartAlways:
+			default:
+				return nil, fmt.Errorf("container %s: unknown restart policy: %s", name, process.Restart)
+			}
+		}
+
+		manifest.Containers[name] = mc
+	}
+
+	return &manifest, nil
+}
+
+// Names returns the names of the containers in the manifest in a stable
+// order.
+func (m *Manifest) Names() []string {
+	names := make([]string, 0, len(m.Containers))
+	for name := range m.Containers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Privileged reports whether any container in the manifest, or any command
+// or process run in one, is privileged.
+func (m *Manifest) Privileged() bool {
+	for _, mc := range m.Containers {
+		if mc.Privileged {
+			return true
+		}
+
+		for _, run := range mc.Run {
+			if run.Privileged {
+				return true
+			}
+		}
+
+		for _, mp := range mc.Processes {
+			if mp.Privileged {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// BindMounts turns the bind mounts of a manifest into Garden's, which are
+// read-only and from the host unless their mode and origin say otherwise.
+func BindMounts(manifestMounts []ManifestMount) ([]garden.BindMount, error) {
+	mounts := make([]garden.BindMount, 0, len(manifestMounts))
+	for _, bm := range manifestMounts {
+		mount := garden.BindMount{
+			SrcPath: bm.Src,
+			DstPath: bm.Dst,
+		}
+
+		switch bm.Mode {
+		case "", "ro":
+			mount.Mode = garden.BindMountModeRO
+		case "rw":
+			mount.Mode = garden.BindMountModeRW
+		default:
+			return nil, fmt.Errorf("unknown bind mount mode: %s", bm.Mode)
+		}
+
+		switch bm.Origin {
+		case "", "host":
+			mount.Origin = garden.BindMountOriginHost
+		case "container":
+			mount.Origin = garden.BindMountOriginContainer
+		default:
+			return nil, fmt.Errorf("unknown bind mount origin: %s", bm.Origin)
+		}
+
+		mounts = append(mounts, mount)
+	}
+
+	return mounts, nil
+}
+
+func (m *Manifest) spec(name string) (garden.ContainerSpec, error) {
+	mc := m.Containers[name]
+
+	mounts, err := BindMounts(mc.BindMounts)
+	if err != nil {
+		return garden.ContainerSpec{}, err
+	}
+
+	properties := garden.Properties{}
+	for key, value := range mc.Properties {
+		properties[key] = value
+	}
+	properties[manifestProperty] = m.Name
+	properties[nameProperty] = name
+	properties[specProperty] = mc.digest()
+
+	return garden.ContainerSpec{
+		Handle:     mc.Handle,
+		GraceTime:  mc.Grace,
+		RootFSPath: mc.RootFS,
+		BindMounts: mounts,
+		Network:    mc.Network,
+		Properties: properties,
+		Env:        mc.Env,
+		Privileged: mc.Privileged,
+	}, nil
+}
+
+// digest sums up the parts of the container which apply compares with the
+// live container: its rootfs, limits, env and privilege.
+func (mc ManifestContainer) digest() string {
+	compared, _ := json.Marshal(struct {
+		RootFS     string
+		Limits     ManifestLimits
+		Env        []string
+		Privileged bool
+	}{mc.RootFS, mc.Limits, mc.Env, mc.Privileged})
+
+	return fmt.Sprintf("%x", sha256.Sum256(compared))[:16]
+}
+
+// Up creates every container in the manifest, writing their handles to w.
+// The output of the manifest's run steps is written to output, and the steps
+// are recorded in the report, as is a container which could not be brought
+// up for any other reason.
+func Up(client garden.Client, manifest *Manifest, w io.Writer, output io.Writer, report *Report) error {
+	for _, name := range manifest.Names() {
+		started := time.Now()
+
+		container, err := createFromManifest(client, manifest, name, output, report)
+		if _, ok := err.(stepError); !ok && err != nil {
+			report.Add(TestCase{Container: name, Command: "up", Duration: time.Since(started), Err: err})
+		}
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(w, container.Handle())
+	}
+
+	return nil
+}
+
+// stepError is a manifest container's run step failing, which has been
+// recorded in the report.
+type stepError struct {
+	error
+}
+
+func createFromManifest(client garden.Client, manifest *Manifest, name string, output io.Writer, report *Report) (garden.Container, error) {
+	spec, err := manifest.spec(name)
+	if err != nil {
+		return nil, fmt.Errorf("container %s: %s", name, err)
+	}
+
+	container, err := client.Create(spec)
+	if err != nil {
+		return nil, fmt.Errorf("container %s: %s", name, err)
+	}
+
+	err = provision(container, name, manifest.Containers[name], output, report)
+	if step, ok := err.(stepError); ok {
+		return nil, stepError{fmt.Errorf("container %s: %s", name, step.error)}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("container %s: %s", name, err)
+	}
+
+	return container, nil
+}
+
+func provision(container garden.Container, name string, mc ManifestContainer, output io.Writer, report *Report) error {
+	if err := applyLimits(container, mc.Limits); err != nil {
+		return err
+	}
+
+	for _, mapping := range mc.NetIn {
+		if _, _, err := container.NetIn(mapping.Host, mapping.Container); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range mc.Files {
+		if err := streamInPath(container, file.Src, file.Dst); err != nil {
+			return err
+		}
+	}
+
+	for _, run := range mc.Run {
+		runOutput := new(OutputTail)
+		started := time.Now()
+
+		err := runToCompletion(container, run, teeOutput(output, runOutput))
+		report.Add(TestCase{name, run.Command, time.Since(started), err, runOutput.String()})
+
+		if err != nil {
+			return stepError{err}
+		}
+	}
+
+	for _, process := range mc.Processes {
+		if _, err := startProcess(container, process); err != nil {
+			return fmt.Errorf("process %s: %s", process.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func applyLimits(container garden.Container, limits ManifestLimits) error {
+	if limits.Memory > 0 {
+		err := container.LimitMemory(garden.MemoryLimits{
+			LimitInBytes: uint64(limits.Memory),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if limits.Disk > 0 {
+		err := container.LimitDisk(garden.DiskLimits{
+			ByteHard: uint64(limits.Disk),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if limits.CPU > 0 {
+		err := container.LimitCPU(garden.CPULimits{
+			LimitInShares: limits.CPU,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if limits.Bandwidth > 0 {
+		err := container.LimitBandwidth(garden.BandwidthLimits{
+			RateInBytesPerSecond:      uint64(limits.Bandwidth),
+			BurstRateInBytesPerSecond: uint64(limits.Burst),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// streamInPath copies a local file or directory to dst inside the container.
+// A file is renamed to the base name of dst, while the contents of a
+// directory are placed inside dst.
+func streamInPath(container garden.Container, src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	reader, writer := io.Pipe()
+
+	if info.IsDir() {
+		go func() {
+			writer.CloseWithError(compressor.WriteTar(src+string(os.PathSeparator), writer))
+		}()
+
+		return container.StreamIn(dst, reader)
+	}
+
+	go func() {
+		writer.CloseWithError(writeFileTar(src, filepath.Base(dst), info, writer))
+	}()
+
+	return container.StreamIn(filepath.Dir(dst), reader)
+}
+
+func writeFileTar(src, name string, info os.FileInfo, w io.Writer) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+
+	tw := tar.NewWriter(w)
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(tw, file); err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+func runToCompletion(container garden.Container, run ManifestRun, output io.Writer) error {
+	args, err := shellwords.Parse(run.Command)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return errors.New("missing command to run")
+	}
+
+	process, err := container.Run(garden.ProcessSpec{
+		Path:       args[0],
+		Args:       args[1:],
+		Dir:        run.Dir,
+		User:       run.User,
+		Privileged: run.Privileged,
+		Env:        run.Env,
+	}, garden.ProcessIO{
+		Stdout: output,
+		Stderr: output,
+	})
+	if err != nil {
+		return err
+	}
+
+	status, err := process.Wait()
+	if err != nil {
+		return err
+	}
+
+	if status != 0 {
+		return ProcessExitError{run.Command, status}
+	}
+
+	return nil
+}
+
+// Down destroys every container in the manifest which exists.
+func Down(client garden.Client, manifest *Manifest) error {
+	for _, name := range manifest.Names() {
+		handle := manifest.Containers[name].Handle
+
+		err := client.Destroy(handle)
+		if _, ok := err.(garden.ContainerNotFoundError); ok {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("container %s: %s", name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,313 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func writeManifest(t *testing.T, contents string) (string, func()) {
+	dir, err := ioutil.TempDir("", "gaol-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "gaol.yml")
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]uint64{
+		"512":   512,
+		"512B":  512,
+		"4k":    4 << 10,
+		"512M":  512 << 20,
+		"2G":    2 << 30,
+		"2GiB":  2 << 30,
+		"1T":    1 << 40,
+		" 64m ": 64 << 20,
+	}
+
+	for raw, want := range tests {
+		size, err := ParseByteSize(raw)
+		if err != nil || size != want {
+			t.Errorf("%q parsed as %d (%v), want %d", raw, size, err, want)
+		}
+	}
+
+	for _, invalid := range []string{"", "M", "lots", "-1G"} {
+		if _, err := ParseByteSize(invalid); err == nil {
+			t.Errorf("parsed invalid size %q", invalid)
+		}
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	path, cleanup := writeManifest(t, `
+name: app
+containers:
+  web:
+    rootfs: docker:///nginx
+    grace: 1m
+    env: [PORT=8080]
+    properties: {team: core}
+    limits: {memory: 512M}
+    bind_mounts:
+    - {src: /data, dst: /data, mode: rw}
+    files:
+    - {src: nginx.conf, dst: /etc/nginx}
+    processes:
+    - {name: server, command: nginx}
+  db:
+    handle: app-db
+`)
+	defer cleanup()
+
+	manifest, err := LoadManifest(path, TemplateData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(manifest.Names(), []string{"db", "web"}) {
+		t.Errorf("loaded containers %v", manifest.Names())
+	}
+
+	web := manifest.Containers["web"]
+	if web.Handle != "web" || manifest.Containers["db"].Handle != "app-db" {
+		t.Errorf("handles are %q and %q", web.Handle, manifest.Containers["db"].Handle)
+	}
+
+	if web.Limits.Memory != 512<<20 {
+		t.Errorf("memory limit is %d", web.Limits.Memory)
+	}
+
+	if web.Files[0].Src != filepath.Join(filepath.Dir(path), "nginx.conf") {
+		t.Errorf("file source %q is not relative to the manifest", web.Files[0].Src)
+	}
+
+	if web.Processes[0].Restart != restartNever {
+		t.Errorf("restart policy is %q", web.Processes[0].Restart)
+	}
+
+	spec, err := manifest.spec("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := garden.ContainerSpec{
+		Handle:     "web",
+		GraceTime:  time.Minute,
+		RootFSPath: "docker:///nginx",
+		BindMounts: []garden.BindMount{{
+			SrcPath: "/data",
+			DstPath: "/data",
+			Mode:    garden.BindMountModeRW,
+			Origin:  garden.BindMountOriginHost,
+		}},
+		Properties: garden.Properties{
+			"team":           "core",
+			manifestProperty: "app",
+			nameProperty:     "web",
+			specProperty:     manifest.Containers["web"].digest(),
+		},
+		Env: []string{"PORT=8080"},
+	}
+
+	if !reflect.DeepEqual(spec, want) {
+		t.Errorf("spec is %#v, want %#v", spec, want)
+	}
+}
+
+func TestLoadManifestDefaultName(t *testing.T) {
+	path, cleanup := writeManifest(t, "containers: {web: {}}")
+	defer cleanup()
+
+	staging := filepath.Join(filepath.Dir(path), "staging.yml")
+	if err := ioutil.WriteFile(staging, []byte("containers: {web: {}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Base(filepath.Dir(path))
+	for path, want := range map[string]string{path: dir, staging: dir + "-staging"} {
+		manifest, err := LoadManifest(path, TemplateData{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if manifest.Name != want {
+			t.Errorf("%s is named %q, want %q", filepath.Base(path), manifest.Name, want)
+		}
+	}
+}
+
+func TestLoadManifestRendersTemplates(t *testing.T) {
+	path, cleanup := writeManifest(t, `
+name: ci-{{ .Vars.branch }}
+containers:
+  web:
+    handle: web-{{ .Vars.branch }}-{{ .Index }}
+    env: [HOME={{ .Env.HOME }}, DEBUG={{ index .Env "GAOL_UNSET_VARIABLE" }}]
+`)
+	defer cleanup()
+
+	data, err := NewTemplateData(3, []string{"branch=fix-login"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := LoadManifest(path, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	web := manifest.Containers["web"]
+	if manifest.Name != "ci-fix-login" || web.Handle != "web-fix-login-3" {
+		t.Errorf("loaded %q with handle %q", manifest.Name, web.Handle)
+	}
+
+	if !reflect.DeepEqual(web.Env, []string{"HOME=" + os.Getenv("HOME"), "DEBUG="}) {
+		t.Errorf("env is %q", web.Env)
+	}
+
+	if _, err := LoadManifest(path, TemplateData{}); err == nil {
+		t.Error("loaded a manifest referring to a variable which is not set")
+	}
+}
+
+func TestLoadManifestErrors(t *testing.T) {
+	tests := []string{
+		`containers: {}`,
+		`containers: {web: {files: [{src: a}]}}`,
+		`containers: {web: {processes: [{name: a}]}}`,
+		`containers: {web: {processes: [{name: a, command: x}, {name: a, command: y}]}}`,
+		`containers: {web: {processes: [{name: a, command: x, restart: sometimes}]}}`,
+		`containers: {web: {limits: {memory: lots}}}`,
+	}
+
+	for _, contents := range tests {
+		path, cleanup := writeManifest(t, contents)
+
+		if _, err := LoadManifest(path, TemplateData{}); err == nil {
+			t.Errorf("loaded invalid manifest %q", contents)
+		}
+
+		cleanup()
+	}
+}
+
+func TestApply(t *testing.T) {
+	manifest := &Manifest{
+		Name: "app",
+		Containers: map[string]ManifestContainer{
+			"web": {Handle: "web"},
+			"db":  {Handle: "db"},
+		},
+	}
+
+	old := fakeContainer("old")
+	old.GetPropertyReturns("worker", nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersStub = func(properties garden.Properties) ([]garden.Container, error) {
+		if properties[manifestProperty] == "app" {
+			return []garden.Container{old}, nil
+		}
+
+		return []garden.Container{fakeContainer("web"), old}, nil
+	}
+	fakeClient.CreateReturns(fakeContainer("db"), nil)
+
+	changes, err := Apply(fakeClient, manifest, true, new(bytes.Buffer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	PrintChanges(&buf, changes)
+
+	want := "created\tdb\tdb\nunchanged\tweb\tweb\ndestroyed\tworker\told\n"
+	if buf.String() != want {
+		t.Errorf("printed %q, want %q", buf.String(), want)
+	}
+
+	if spec := fakeClient.CreateArgsForCall(0); spec.Handle != "db" || spec.Properties[manifestProperty] != "app" {
+		t.Errorf("created %#v", spec)
+	}
+
+	if fakeClient.DestroyArgsForCall(0) != "old" {
+		t.Errorf("destroyed %q", fakeClient.DestroyArgsForCall(0))
+	}
+}
+
+func TestApplyReportsChangedContainers(t *testing.T) {
+	manifest := &Manifest{
+		Name: "app",
+		Containers: map[string]ManifestContainer{
+			"web": {Handle: "web", RootFS: "docker:///nginx"},
+			"db":  {Handle: "db", Env: []string{"PGDATA=/data"}},
+		},
+	}
+
+	web := fakeContainer("web")
+	web.GetPropertyReturns(manifest.Containers["web"].digest(), nil)
+
+	db := fakeContainer("db")
+	db.GetPropertyReturns(ManifestContainer{Handle: "db"}.digest(), nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersReturns([]garden.Container{web, db}, nil)
+
+	changes, err := Apply(fakeClient, manifest, false, new(bytes.Buffer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	PrintChanges(&buf, changes)
+
+	if want := "changed\tdb\tdb\nunchanged\tweb\tweb\n"; buf.String() != want {
+		t.Errorf("printed %q, want %q", buf.String(), want)
+	}
+
+	if fakeClient.CreateCallCount() != 0 {
+		t.Error("recreated a container")
+	}
+}
+
+func TestDownSkipsMissingContainers(t *testing.T) {
+	manifest := &Manifest{
+		Containers: map[string]ManifestContainer{
+			"web": {Handle: "web"},
+			"db":  {Handle: "db"},
+		},
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.DestroyReturns(garden.ContainerNotFoundError{Handle: "db"})
+
+	if err := Down(fakeClient, manifest); err != nil {
+		t.Errorf("failed on a missing container: %s", err)
+	}
+
+	if fakeClient.DestroyCallCount() != 2 {
+		t.Errorf("destroyed %d containers, want 2", fakeClient.DestroyCallCount())
+	}
+
+	fakeClient.DestroyReturns(errors.New("boom"))
+
+	if err := Down(fakeClient, manifest); err == nil {
+		t.Error("expected the failure to destroy")
+	}
+}
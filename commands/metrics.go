@@ -0,0 +1,242 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// usageMetric is a column of the table printed by Metrics: a value derived
+// from the info of a container, and whether it is a number of bytes, of
+// seconds or a plain count, which says how it is printed and how the values
+// it is compared with are given.
+type usageMetric struct {
+	name string
+	kind string
+	get  func(garden.ContainerInfo) float64
+}
+
+var usageMetrics = []usageMetric{
+	{"memory", "bytes", func(i garden.ContainerInfo) float64 { return float64(i.MemoryStat.TotalRss) }},
+	{"cache", "bytes", func(i garden.ContainerInfo) float64 { return float64(i.MemoryStat.TotalCache) }},
+	{"swap", "bytes", func(i garden.ContainerInfo) float64 { return float64(i.MemoryStat.TotalSwap) }},
+	{"disk", "bytes", func(i garden.ContainerInfo) float64 { return float64(i.DiskStat.BytesUsed) }},
+	{"inodes", "count", func(i garden.ContainerInfo) float64 { return float64(i.DiskStat.InodesUsed) }},
+	{"cpu", "seconds", func(i garden.ContainerInfo) float64 { return float64(i.CPUStat.Usage) / 1e9 }},
+	{"processes", "count", func(i garden.ContainerInfo) float64 { return float64(len(i.ProcessIDs)) }},
+}
+
+// lookupMetric finds the metric by name, taking mem as short for memory.
+func lookupMetric(name string) (int, bool) {
+	if name == "mem" {
+		name = "memory"
+	}
+
+	for i, m := range usageMetrics {
+		if m.name == name {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+func (m usageMetric) parse(raw string) (float64, error) {
+	switch m.kind {
+	case "bytes":
+		n, err := ParseByteSize(raw)
+		return float64(n), err
+	case "seconds":
+		if d, err := time.ParseDuration(raw); err == nil {
+			return d.Seconds(), nil
+		}
+	}
+
+	n, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %q", m.name, raw)
+	}
+
+	return n, nil
+}
+
+func (m usageMetric) format(value float64) string {
+	switch m.kind {
+	case "bytes":
+		return HumanBytes(uint64(value))
+	case "seconds":
+		return HumanDuration(time.Duration(value * 1e9))
+	default:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+}
+
+// Condition keeps the containers whose metric compares with the value as
+// the operator says, such as mem>500M.
+type Condition struct {
+	Metric   string
+	Operator string
+	Value    float64
+}
+
+var conditionPattern = regexp.MustCompile(`^\s*([a-z]+)\s*(>=|<=|!=|>|<|=)\s*(\S+)\s*$`)
+
+// ParseCondition parses a condition given as <metric><operator><value>,
+// where the operator is one of >, >=, <, <=, = and !=, and the value is a
+// size such as 500M for memory, cache, swap and disk, a duration or a number
+// of seconds for cpu, and a number for inodes and processes.
+func ParseCondition(raw string) (Condition, error) {
+	parts := conditionPattern.FindStringSubmatch(raw)
+	if parts == nil {
+		return Condition{}, fmt.Errorf("invalid condition %q: must be <metric><operator><value>, e.g. mem>500M", raw)
+	}
+
+	i, found := lookupMetric(parts[1])
+	if !found {
+		return Condition{}, fmt.Errorf("invalid condition %q: unknown metric %s", raw, parts[1])
+	}
+
+	value, err := usageMetrics[i].parse(parts[3])
+	if err != nil {
+		return Condition{}, fmt.Errorf("invalid condition %q: %s", raw, err)
+	}
+
+	return Condition{Metric: usageMetrics[i].name, Operator: parts[2], Value: value}, nil
+}
+
+func (c Condition) holds(values []float64) bool {
+	i, _ := lookupMetric(c.Metric)
+	value := values[i]
+
+	switch c.Operator {
+	case ">":
+		return value > c.Value
+	case ">=":
+		return value >= c.Value
+	case "<":
+		return value < c.Value
+	case "<=":
+		return value <= c.Value
+	case "=":
+		return value == c.Value
+	default:
+		return value != c.Value
+	}
+}
+
+// MetricsQuery sorts and narrows down the containers whose metrics are
+// printed.
+type MetricsQuery struct {
+	// Sort is handle, or a metric to put the containers with the most of
+	// first; empty sorts by handle.
+	Sort string
+
+	// Where keeps the containers for which every condition holds.
+	Where []Condition
+
+	// Top keeps only the first this many containers, after sorting.
+	Top int
+}
+
+// ParseMetricsSort checks the key to sort metrics by.
+func ParseMetricsSort(key string) (string, error) {
+	if key == "" || key == "handle" {
+		return key, nil
+	}
+
+	i, found := lookupMetric(key)
+	if !found {
+		return "", fmt.Errorf("cannot sort by %s", key)
+	}
+
+	return usageMetrics[i].name, nil
+}
+
+type metricsRow struct {
+	handle string
+	values []float64
+}
+
+// Metrics writes one tab-separated line per container chosen by the
+// selector: its handle followed by its memory, cache, swap, disk, inodes,
+// cpu and processes, narrowed down and sorted as the query says. Containers
+// whose info cannot be fetched, such as those destroyed in the meantime, are
+// left out. In porcelain, the values are given without units, as in
+// porcelainValue.
+func Metrics(client garden.Client, selector Selector, query MetricsQuery, w io.Writer, format Format) error {
+	containers, err := selectContainers(client, selector)
+	if err != nil {
+		return err
+	}
+
+	infos, _ := bulkInfo(containers)
+
+	rows := []metricsRow{}
+	for handle, info := range infos {
+		row := metricsRow{handle: handle, values: make([]float64, len(usageMetrics))}
+		for i, m := range usageMetrics {
+			row.values[i] = m.get(info)
+		}
+
+		keep := true
+		for _, condition := range query.Where {
+			if !condition.holds(row.values) {
+				keep = false
+				break
+			}
+		}
+
+		if keep {
+			rows = append(rows, row)
+		}
+	}
+
+	sort.Sort(metricsByHandle(rows))
+
+	if column, found := lookupMetric(query.Sort); found {
+		sort.Stable(metricsByValue{rows, column})
+	}
+
+	if query.Top > 0 && len(rows) > query.Top {
+		rows = rows[:query.Top]
+	}
+
+	for _, row := range rows {
+		fields := []string{row.handle}
+		for i, m := range usageMetrics {
+			if format.Porcelain {
+				fields = append(fields, porcelainValue(row.values[i]))
+			} else {
+				fields = append(fields, m.format(row.values[i]))
+			}
+		}
+
+		fmt.Fprintln(w, strings.Join(fields, "\t"))
+	}
+
+	return nil
+}
+
+type metricsByHandle []metricsRow
+
+func (r metricsByHandle) Len() int           { return len(r) }
+func (r metricsByHandle) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r metricsByHandle) Less(i, j int) bool { return r[i].handle < r[j].handle }
+
+// metricsByValue puts the containers with the most of the metric first.
+type metricsByValue struct {
+	rows   []metricsRow
+	column int
+}
+
+func (r metricsByValue) Len() int      { return len(r.rows) }
+func (r metricsByValue) Swap(i, j int) { r.rows[i], r.rows[j] = r.rows[j], r.rows[i] }
+func (r metricsByValue) Less(i, j int) bool {
+	return r.rows[i].values[r.column] > r.rows[j].values[r.column]
+}
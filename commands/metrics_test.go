@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestParseCondition(t *testing.T) {
+	tests := map[string]Condition{
+		"mem>500M":        {"memory", ">", 500 << 20},
+		"disk <= 2G":      {"disk", "<=", 2 << 30},
+		"cpu>=1m":         {"cpu", ">=", 60},
+		"cpu>90.5":        {"cpu", ">", 90.5},
+		"processes!=0":    {"processes", "!=", 0},
+		"inodes=1000":     {"inodes", "=", 1000},
+		"  swap < 1K  ":   {"swap", "<", 1024},
+		"cache>=512MiB":   {"cache", ">=", 512 << 20},
+		"memory>0":        {"memory", ">", 0},
+		"processes>=1000": {"processes", ">=", 1000},
+	}
+
+	for raw, want := range tests {
+		condition, err := ParseCondition(raw)
+		if err != nil || condition != want {
+			t.Errorf("parsed %q as %#v (%v), want %#v", raw, condition, err, want)
+		}
+	}
+
+	for _, invalid := range []string{"mem", "mem>", "network>1M", "mem>lots", "processes>many", "mem=>1M"} {
+		if _, err := ParseCondition(invalid); err == nil {
+			t.Errorf("parsed %q", invalid)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	container := func(handle string, rss uint64, disk uint64, processes int) garden.Container {
+		c := fakeContainer(handle)
+		c.InfoReturns(garden.ContainerInfo{
+			MemoryStat: garden.ContainerMemoryStat{TotalRss: rss},
+			DiskStat:   garden.ContainerDiskStat{BytesUsed: disk},
+			CPUStat:    garden.ContainerCPUStat{Usage: 90e9},
+			ProcessIDs: make([]uint32, processes),
+		}, nil)
+		return c
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersReturns([]garden.Container{
+		container("a", 100<<20, 2<<30, 1),
+		container("b", 800<<20, 1<<30, 3),
+		container("c", 600<<20, 3<<30, 2),
+		container("d", 900<<20, 0, 0),
+	}, nil)
+
+	var out bytes.Buffer
+	err := Metrics(fakeClient, Selector{All: true}, MetricsQuery{}, &out, Format{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "a\t100.0 MiB\t0 B\t0 B\t2.0 GiB\t0\t1m30s\t1\n"; !strings.HasPrefix(out.String(), want) {
+		t.Errorf("printed %q, want it to start %q", out.String(), want)
+	}
+
+	mem500, _ := ParseCondition("mem>500M")
+	busy, _ := ParseCondition("processes>=1")
+
+	out.Reset()
+	err = Metrics(fakeClient, Selector{All: true}, MetricsQuery{Sort: "memory", Where: []Condition{mem500, busy}}, &out, Format{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := handlesOf(out.String()), "b c"; got != want {
+		t.Errorf("printed %s, want %s", got, want)
+	}
+
+	out.Reset()
+	err = Metrics(fakeClient, Selector{All: true}, MetricsQuery{Sort: "disk", Top: 2}, &out, Format{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := handlesOf(out.String()), "c a"; got != want {
+		t.Errorf("printed %s, want %s", got, want)
+	}
+}
+
+// handlesOf returns the first field of each line, separated by spaces.
+func handlesOf(output string) string {
+	handles := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		handles = append(handles, strings.SplitN(line, "\t", 2)[0])
+	}
+
+	return strings.Join(handles, " ")
+}
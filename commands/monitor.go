@@ -0,0 +1,428 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// defaultMonitorInterval is how often the containers are checked when the
+// manifest does not say.
+const defaultMonitorInterval = 10 * time.Second
+
+// portCheckTimeout is how long a process's port is given to accept a
+// connection.
+const portCheckTimeout = 2 * time.Second
+
+// webhookTimeout is how long the webhook is given to take an event.
+const webhookTimeout = 10 * time.Second
+
+// monitorEventVariable holds the event, as a line, for the monitor's hook.
+const monitorEventVariable = "GAOL_EVENT"
+
+// dialPort connects to the port a process is checked on; tests replace it.
+var dialPort = func(address string) (net.Conn, error) {
+	return net.DialTimeout("tcp", address, portCheckTimeout)
+}
+
+// What the monitor did about a problem.
+const (
+	actionNone     = "none"
+	actionRestart  = "restart"
+	actionRecreate = "recreate"
+)
+
+// MonitorEvent is a problem found with a container or one of its processes,
+// and what was done about it. Alert is the resource for problems which are
+// a container using too much of one; for an unreachable container, Result
+// holds why, as the error may differ each time. Cleared events report that
+// a problem reported before has gone.
+type MonitorEvent struct {
+	Time      time.Time `json:"time"`
+	Manifest  string    `json:"manifest"`
+	Container string    `json:"container"`
+	Handle    string    `json:"handle"`
+	Process   string    `json:"process,omitempty"`
+	Alert     string    `json:"alert,omitempty"`
+	Problem   string    `json:"problem"`
+	Action    string    `json:"action"`
+	Result    string    `json:"result,omitempty"`
+	Cleared   bool      `json:"cleared,omitempty"`
+}
+
+func (e MonitorEvent) String() string {
+	subject := e.Container
+	if e.Process != "" {
+		subject += "/" + e.Process
+	}
+
+	line := fmt.Sprintf("%s %s: %s", e.Time.Format(time.RFC3339), subject, e.Problem)
+	if e.Cleared {
+		line += ", cleared"
+	} else if e.Action != actionNone {
+		line += fmt.Sprintf(", %s: %s", e.Action, e.Result)
+	} else if e.Result != "" {
+		line += ": " + e.Result
+	}
+
+	return line
+}
+
+// Monitor checks the containers described by the manifest and their
+// processes on the manifest's interval, until it fails. Each problem found is
+// written to log, posted to the manifest's webhook and given to its hook, if
+// it has them. Problems nothing is done about, such as a container using
+// too much of a resource, are only reported when they are first found and
+// when they clear. Once only checks them once, failing if anything was
+// wrong. With lines the problems, and failures to report them, are logged as
+// JSON lines instead.
+func Monitor(client garden.Client, manifest *Manifest, once bool, log io.Writer, lines *JSONLines) error {
+	interval := manifest.Monitor.Interval
+	if interval <= 0 {
+		interval = defaultMonitorInterval
+	}
+
+	reported := map[string]MonitorEvent{}
+
+	for {
+		var events []MonitorEvent
+		events, reported = unreported(CheckManifest(client, manifest), reported)
+
+		for _, event := range events {
+			notify(manifest.Monitor, event, log, lines)
+		}
+
+		if once {
+			if len(events) > 0 {
+				return fmt.Errorf("found %d problems", len(events))
+			}
+
+			return nil
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// CheckManifest checks that every container in the manifest exists, that
+// its processes are running and healthy and that it is within the alert
+// thresholds, recreating containers and restarting processes as the
+// manifest says.
+func CheckManifest(client garden.Client, manifest *Manifest) []MonitorEvent {
+	events := []MonitorEvent{}
+
+	for _, name := range manifest.Names() {
+		mc := manifest.Containers[name]
+
+		problem := func(process string, description string) MonitorEvent {
+			return MonitorEvent{
+				Time:      time.Now().UTC(),
+				Manifest:  manifest.Name,
+				Container: name,
+				Handle:    mc.Handle,
+				Process:   process,
+				Problem:   description,
+				Action:    actionNone,
+			}
+		}
+
+		container, err := client.Lookup(mc.Handle)
+		if _, ok := err.(garden.ContainerNotFoundError); ok {
+			event := problem("", "missing")
+
+			if manifest.Monitor.Recreate {
+				_, err := createFromManifest(client, manifest, name, ioutil.Discard, nil)
+				event.Action = actionRecreate
+				event.Result = actionResult(err)
+			}
+
+			events = append(events, event)
+			continue
+		}
+
+		if err != nil {
+			event := problem("", stateUnreachable)
+			event.Result = err.Error()
+
+			events = append(events, event)
+			continue
+		}
+
+		for _, mp := range mc.Processes {
+			description, running := checkProcess(container, mp)
+			if description == "" {
+				continue
+			}
+
+			event := problem(mp.Name, description)
+
+			if shouldRestart(mp.Restart, description) {
+				event.Action = actionRestart
+				event.Result = actionResult(restartProcess(container, mp, running))
+			}
+
+			events = append(events, event)
+		}
+
+		if manifest.Monitor.Alert.IsZero() {
+			continue
+		}
+
+		info, err := container.Info()
+		if err != nil {
+			event := problem("", stateUnreachable)
+			event.Result = err.Error()
+
+			events = append(events, event)
+			continue
+		}
+
+		for _, alert := range CheckThresholds(container, info, manifest.Monitor.Alert) {
+			event := problem("", alert.String())
+			event.Alert = alert.Resource
+
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+// checkProcess describes what is wrong with a manifest process, if anything,
+// and reports whether it is still running.
+func checkProcess(container garden.Container, mp ManifestProcess) (string, bool) {
+	pid, err := processPID(container, mp.Name)
+	if err != nil {
+		return stateNotStarted, false
+	}
+
+	if state := probeProcess(container, pid); state != stateRunning {
+		return state, false
+	}
+
+	if mp.Check.Port != 0 {
+		if err := checkPort(container, mp.Check.Port); err != nil {
+			return fmt.Sprintf("port %d: %s", mp.Check.Port, err), true
+		}
+	}
+
+	return "", true
+}
+
+// checkPort connects to the host port mapped to a port in the container.
+func checkPort(container garden.Container, port uint32) error {
+	info, err := container.Info()
+	if err != nil {
+		return err
+	}
+
+	for _, mapping := range info.MappedPorts {
+		if mapping.ContainerPort != port {
+			continue
+		}
+
+		conn, err := dialPort(net.JoinHostPort(info.ExternalIP, strconv.FormatUint(uint64(mapping.HostPort), 10)))
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	}
+
+	return fmt.Errorf("not mapped to a host port")
+}
+
+// shouldRestart reports whether a process with the restart policy is
+// restarted for the problem found with it. A process which could not be
+// reached may well still be running, so it is never restarted.
+func shouldRestart(policy string, problem string) bool {
+	if problem == stateUnreachable {
+		return false
+	}
+
+	switch policy {
+	case restartAlways:
+		return true
+	case restartOnFailure:
+		return problem != fmt.Sprintf("%s (0)", stateExited)
+	default:
+		return false
+	}
+}
+
+// restartProcess starts a manifest process again, killing it first if it is
+// still running, and counts the restart as the supervisor does.
+func restartProcess(container garden.Container, mp ManifestProcess, running bool) error {
+	if running {
+		pid, err := processPID(container, mp.Name)
+		if err != nil {
+			return err
+		}
+
+		process, release, err := attachProcess(container, pid)
+		if err != nil {
+			return err
+		}
+
+		err = process.Signal(garden.SignalKill)
+		release()
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := startProcess(container, mp); err != nil {
+		return err
+	}
+
+	restarts := 0
+	if value, err := container.GetProperty(restartsPropertyPrefix + mp.Name); err == nil {
+		restarts, _ = strconv.Atoi(value)
+	}
+
+	return container.SetProperty(restartsPropertyPrefix+mp.Name, strconv.Itoa(restarts+1))
+}
+
+func actionResult(err error) string {
+	if err != nil {
+		return "error: " + err.Error()
+	}
+
+	return "ok"
+}
+
+// unreported leaves out of events the problems nothing was done about which
+// are in reported, having been reported already, and adds a cleared event
+// for each of those which has gone. It returns the problems to leave out
+// next time. Problems something was done about are reported every time.
+func unreported(events []MonitorEvent, reported map[string]MonitorEvent) ([]MonitorEvent, map[string]MonitorEvent) {
+	report := []MonitorEvent{}
+	stillReported := map[string]MonitorEvent{}
+
+	for _, event := range events {
+		if event.Action == actionNone {
+			key := event.key()
+			if previous, found := reported[key]; found {
+				stillReported[key] = previous
+				continue
+			}
+
+			stillReported[key] = event
+		}
+
+		report = append(report, event)
+	}
+
+	cleared := []string{}
+	for key := range reported {
+		if _, found := stillReported[key]; !found {
+			cleared = append(cleared, key)
+		}
+	}
+
+	sort.Strings(cleared)
+
+	for _, key := range cleared {
+		event := reported[key]
+		event.Time = time.Now().UTC()
+		event.Cleared = true
+
+		report = append(report, event)
+	}
+
+	return report, stillReported
+}
+
+// key identifies the problem from one check to the next. Alerts are told
+// apart by their resource, their descriptions changing with what is used.
+func (e MonitorEvent) key() string {
+	problem := e.Problem
+	if e.Alert != "" {
+		problem = e.Alert
+	}
+
+	return e.Container + "/" + e.Process + "/" + problem
+}
+
+// notify logs the event, to log or as a JSON line, and sends it to the
+// webhook and hook.
+func notify(monitor ManifestMonitor, event MonitorEvent, log io.Writer, lines *JSONLines) {
+	failed := func(what string, err error) {
+		if lines != nil {
+			lines.Write(event.Handle, "error", fmt.Sprintf("failed to %s: %s", what, err))
+		} else {
+			fmt.Fprintf(log, "failed to %s: %s\n", what, err)
+		}
+	}
+
+	if lines != nil {
+		lines.Write(event.Handle, "problem", event)
+	} else {
+		fmt.Fprintln(log, event)
+	}
+
+	if monitor.Webhook != "" {
+		if err := postEvent(monitor.Webhook, event); err != nil {
+			failed("post to webhook", err)
+		}
+	}
+
+	if monitor.Hook != "" {
+		if err := runHook(monitor.Hook, event); err != nil {
+			failed("run hook", err)
+		}
+	}
+}
+
+// runHook runs the hook with the shell, giving it the event as JSON on its
+// stdin and as a line in GAOL_EVENT.
+func runHook(hook string, event MonitorEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	cmd := shellCommand(hook)
+	cmd.Stdin = bytes.NewReader(append(body, '\n'))
+	cmd.Env = append(os.Environ(), monitorEventVariable+"="+event.String())
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// postEvent sends the event to the webhook as JSON.
+func postEvent(url string, event MonitorEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+
+	response, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("%s responded %s", url, response.Status)
+	}
+
+	return nil
+}
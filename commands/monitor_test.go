@@ -0,0 +1,494 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+// monitoredContainer has server and worker processes recorded, both of which have
+// exited with the status unless it is negative, when they are running.
+func monitoredContainer(handle string, status int) (*fakes.FakeContainer, map[string]string) {
+	properties := map[string]string{
+		processPropertyPrefix + "server": "7",
+		processPropertyPrefix + "worker": "8",
+	}
+
+	container := fakeContainer(handle)
+	container.GetPropertyStub = func(key string) (string, error) {
+		value, found := properties[key]
+		if !found {
+			return "", errors.New("no such property")
+		}
+
+		return value, nil
+	}
+	container.SetPropertyStub = func(key string, value string) error {
+		properties[key] = value
+		return nil
+	}
+
+	container.AttachStub = func(pid uint32, processIO garden.ProcessIO) (garden.Process, error) {
+		process := new(fakes.FakeProcess)
+		if status < 0 {
+			process.WaitStub = func() (int, error) { select {} }
+		} else {
+			process.WaitReturns(status, nil)
+		}
+
+		return process, nil
+	}
+
+	started := new(fakes.FakeProcess)
+	started.IDReturns(9)
+	container.RunReturns(started, nil)
+
+	return container, properties
+}
+
+func TestCheckManifestRestartsProcesses(t *testing.T) {
+	manifest := &Manifest{
+		Name: "app",
+		Containers: map[string]ManifestContainer{
+			"web": {
+				Handle: "web",
+				Processes: []ManifestProcess{
+					{Name: "server", Command: "/bin/server", Restart: "on-failure"},
+					{Name: "worker", Command: "/bin/worker", Restart: "no"},
+				},
+			},
+		},
+	}
+
+	container, properties := monitoredContainer("web", 1)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	events := CheckManifest(fakeClient, manifest)
+	if len(events) != 2 {
+		t.Fatalf("found %d problems, want 2: %v", len(events), events)
+	}
+
+	if e := events[0]; e.Process != "server" || e.Problem != "exited (1)" || e.Action != "restart" || e.Result != "ok" {
+		t.Errorf("reported %#v", e)
+	}
+
+	if e := events[1]; e.Process != "worker" || e.Action != "none" {
+		t.Errorf("reported %#v, want it left alone", e)
+	}
+
+	if container.RunCallCount() != 1 {
+		t.Errorf("started %d processes, want 1", container.RunCallCount())
+	}
+
+	if properties[processPropertyPrefix+"server"] != "9" || properties[restartsPropertyPrefix+"server"] != "1" {
+		t.Errorf("recorded %v", properties)
+	}
+}
+
+func TestCheckManifestRecreatesContainers(t *testing.T) {
+	manifest := &Manifest{
+		Name: "app",
+		Containers: map[string]ManifestContainer{
+			"web": {Handle: "web"},
+		},
+		Monitor: ManifestMonitor{Recreate: true},
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(nil, garden.ContainerNotFoundError{Handle: "web"})
+	fakeClient.CreateReturns(fakeContainer("web"), nil)
+
+	events := CheckManifest(fakeClient, manifest)
+	if len(events) != 1 || events[0].Problem != "missing" || events[0].Action != "recreate" || events[0].Result != "ok" {
+		t.Fatalf("reported %v", events)
+	}
+
+	if spec := fakeClient.CreateArgsForCall(0); spec.Handle != "web" || spec.Properties[manifestProperty] != "app" {
+		t.Errorf("created %#v", spec)
+	}
+}
+
+func TestCheckManifestPort(t *testing.T) {
+	defer func(dial func(string) (net.Conn, error)) { dialPort = dial }(dialPort)
+
+	dialed := ""
+	dialPort = func(address string) (net.Conn, error) {
+		dialed = address
+		return nil, errors.New("connection refused")
+	}
+
+	manifest := &Manifest{
+		Name: "app",
+		Containers: map[string]ManifestContainer{
+			"web": {
+				Handle: "web",
+				Processes: []ManifestProcess{
+					{Name: "server", Command: "/bin/server", Restart: "always", Check: ManifestCheck{Port: 8080}},
+				},
+			},
+		},
+	}
+
+	container, _ := monitoredContainer("web", -1)
+	container.InfoReturns(garden.ContainerInfo{
+		ExternalIP:  "10.0.0.1",
+		MappedPorts: []garden.PortMapping{{HostPort: 61001, ContainerPort: 8080}},
+	}, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	events := CheckManifest(fakeClient, manifest)
+	if len(events) != 1 || events[0].Problem != "port 8080: connection refused" || events[0].Result != "ok" {
+		t.Fatalf("reported %v", events)
+	}
+
+	if dialed != "10.0.0.1:61001" {
+		t.Errorf("dialed %s", dialed)
+	}
+
+	// the unhealthy process is killed before it is started again
+	if container.AttachCallCount() != 2 || container.RunCallCount() != 1 {
+		t.Errorf("attached %d times and ran %d processes", container.AttachCallCount(), container.RunCallCount())
+	}
+}
+
+func TestCheckManifestReleasesAttachments(t *testing.T) {
+	defer func(dial func(string) (net.Conn, error)) { dialPort = dial }(dialPort)
+	dialPort = func(address string) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	manifest := &Manifest{
+		Name: "app",
+		Containers: map[string]ManifestContainer{
+			"web": {
+				Handle: "web",
+				Processes: []ManifestProcess{
+					{Name: "server", Command: "/bin/server", Restart: "always", Check: ManifestCheck{Port: 8080}},
+				},
+			},
+		},
+	}
+
+	container, _ := monitoredContainer("web", -1)
+	container.InfoReturns(garden.ContainerInfo{
+		MappedPorts: []garden.PortMapping{{HostPort: 61001, ContainerPort: 8080}},
+	}, nil)
+
+	// like the client, a Wait lasts as long as the connection, which is
+	// dropped when copying stdin to it fails
+	released := make(chan error, 2)
+	container.AttachStub = func(pid uint32, processIO garden.ProcessIO) (garden.Process, error) {
+		process := new(fakes.FakeProcess)
+		process.WaitStub = func() (int, error) {
+			if processIO.Stdin == nil {
+				select {}
+			}
+
+			_, err := io.Copy(ioutil.Discard, processIO.Stdin)
+			released <- err
+			return 0, errors.New("connection closed")
+		}
+		go process.Wait()
+
+		return process, nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	events := CheckManifest(fakeClient, manifest)
+	if len(events) != 1 || events[0].Action != "restart" || events[0].Result != "ok" {
+		t.Fatalf("reported %v", events)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-released:
+			if err == nil {
+				t.Error("closed the stdin of the process")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("held on to %d of 2 attachments", 2-i)
+		}
+	}
+}
+
+func TestCheckManifestLeavesUnreachableProcesses(t *testing.T) {
+	manifest := &Manifest{
+		Name: "app",
+		Containers: map[string]ManifestContainer{
+			"web": {
+				Handle: "web",
+				Processes: []ManifestProcess{
+					{Name: "server", Command: "/bin/server", Restart: "always"},
+				},
+			},
+		},
+	}
+
+	container, _ := monitoredContainer("web", -1)
+	container.AttachReturns(nil, errors.New("connection refused"))
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	events := CheckManifest(fakeClient, manifest)
+	if len(events) != 1 || events[0].Problem != "unreachable" || events[0].Action != "none" {
+		t.Fatalf("reported %v", events)
+	}
+
+	if container.RunCallCount() != 0 {
+		t.Error("restarted a process which may still be running")
+	}
+}
+
+func TestMonitorPostsToWebhook(t *testing.T) {
+	posted := []MonitorEvent{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event MonitorEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		posted = append(posted, event)
+	}))
+	defer server.Close()
+
+	manifest := &Manifest{
+		Name: "app",
+		Containers: map[string]ManifestContainer{
+			"web": {Handle: "web"},
+		},
+		Monitor: ManifestMonitor{Webhook: server.URL},
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(nil, garden.ContainerNotFoundError{Handle: "web"})
+
+	var log bytes.Buffer
+	err := Monitor(fakeClient, manifest, true, &log, nil)
+	if err == nil {
+		t.Error("expected checking once to fail on the missing container")
+	}
+
+	if len(posted) != 1 || posted[0].Manifest != "app" || posted[0].Container != "web" || posted[0].Problem != "missing" {
+		t.Errorf("posted %v", posted)
+	}
+
+	if !strings.HasSuffix(log.String(), " web: missing\n") {
+		t.Errorf("logged %q", log.String())
+	}
+
+	if fakeClient.CreateCallCount() != 0 {
+		t.Error("recreated the container without being told to")
+	}
+}
+
+func TestShouldRestart(t *testing.T) {
+	tests := []struct {
+		policy  string
+		problem string
+		restart bool
+	}{
+		{"no", "exited (1)", false},
+		{"on-failure", "exited (1)", true},
+		{"on-failure", "exited (0)", false},
+		{"on-failure", "not-started", true},
+		{"always", "exited (0)", true},
+		{"always", "unreachable", false},
+	}
+
+	for _, test := range tests {
+		if restart := shouldRestart(test.policy, test.problem); restart != test.restart {
+			t.Errorf("%s, %s: got %v", test.policy, test.problem, restart)
+		}
+	}
+}
+
+func TestCheckManifestAlerts(t *testing.T) {
+	manifest := &Manifest{
+		Name: "app",
+		Containers: map[string]ManifestContainer{
+			"web": {Handle: "web"},
+		},
+		Monitor: ManifestMonitor{Alert: Thresholds{Memory: 90, Disk: 90}},
+	}
+
+	container := fakeContainer("web")
+	container.InfoReturns(garden.ContainerInfo{
+		MemoryStat: garden.ContainerMemoryStat{TotalRss: 95 << 20, HierarchicalMemoryLimit: 100 << 20},
+		DiskStat:   garden.ContainerDiskStat{BytesUsed: 1 << 30},
+	}, nil)
+	container.CurrentDiskLimitsReturns(garden.DiskLimits{ByteHard: 4 << 30}, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	events := CheckManifest(fakeClient, manifest)
+	if len(events) != 1 || events[0].Alert != "memory" || events[0].Problem != "memory at 95% of 100.0 MiB limit" {
+		t.Fatalf("found %v", events)
+	}
+
+	events, reported := unreported(events, map[string]MonitorEvent{})
+	if len(events) != 1 {
+		t.Errorf("left out %v when it was first found", events)
+	}
+
+	// still over the threshold, by more, along with a new problem
+	higher := events[0]
+	higher.Problem = "memory at 97% of 100.0 MiB limit"
+	missing := MonitorEvent{Container: "db", Problem: "missing", Action: actionNone}
+	events, reported = unreported([]MonitorEvent{higher, missing}, reported)
+	if len(events) != 1 || events[0] != missing {
+		t.Errorf("reported %v, want only the new problem", events)
+	}
+
+	// back under the threshold and over again
+	events, reported = unreported([]MonitorEvent{missing}, reported)
+	if len(events) != 1 || !events[0].Cleared || events[0].Alert != "memory" {
+		t.Errorf("reported %v, want the alert cleared", events)
+	}
+
+	if events, _ = unreported(CheckManifest(fakeClient, manifest), reported); len(events) != 2 || events[0].Alert != "memory" || !events[1].Cleared {
+		t.Errorf("reported %v, want the alert again and the missing container cleared", events)
+	}
+}
+
+func TestUnreportedProblems(t *testing.T) {
+	missing := MonitorEvent{Container: "db", Problem: "missing", Action: actionNone}
+	exited := MonitorEvent{Container: "web", Process: "app", Problem: "exited (1)", Action: actionNone}
+	restarted := MonitorEvent{Container: "web", Process: "worker", Problem: "exited (1)", Action: actionRestart, Result: "ok"}
+
+	events, reported := unreported([]MonitorEvent{missing, exited, restarted}, map[string]MonitorEvent{})
+	if len(events) != 3 {
+		t.Errorf("reported %v, want every problem found first", events)
+	}
+
+	// nothing was done about those left alone, so they are not news
+	for i := 0; i < 3; i++ {
+		events, reported = unreported([]MonitorEvent{missing, exited, restarted}, reported)
+		if len(events) != 1 || events[0] != restarted {
+			t.Errorf("reported %v, want only the restart", events)
+		}
+	}
+
+	events, reported = unreported([]MonitorEvent{exited}, reported)
+	if len(events) != 1 || !events[0].Cleared || events[0].Container != "db" {
+		t.Errorf("reported %v, want the missing container cleared", events)
+	}
+
+	if !strings.HasSuffix(events[0].String(), "db: missing, cleared") {
+		t.Errorf("logged %q", events[0].String())
+	}
+
+	if events, _ = unreported([]MonitorEvent{exited}, reported); len(events) != 0 {
+		t.Errorf("reported %v once cleared", events)
+	}
+}
+
+func TestUnreachableContainersReportedOnce(t *testing.T) {
+	manifest := &Manifest{
+		Name: "app",
+		Containers: map[string]ManifestContainer{
+			"web": {Handle: "web"},
+		},
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(nil, errors.New("dial tcp: connection refused"))
+
+	events, reported := unreported(CheckManifest(fakeClient, manifest), map[string]MonitorEvent{})
+	if len(events) != 1 || events[0].Problem != "unreachable" || events[0].Result != "dial tcp: connection refused" {
+		t.Fatalf("reported %v", events)
+	}
+
+	if !strings.HasSuffix(events[0].String(), " web: unreachable: dial tcp: connection refused") {
+		t.Errorf("logged %q", events[0].String())
+	}
+
+	fakeClient.LookupReturns(nil, errors.New("dial tcp: i/o timeout"))
+	if events, _ = unreported(CheckManifest(fakeClient, manifest), reported); len(events) != 0 {
+		t.Errorf("reported %v again as the error changed", events)
+	}
+}
+
+func TestMonitorRunsHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "event")
+
+	manifest := &Manifest{
+		Name: "app",
+		Containers: map[string]ManifestContainer{
+			"web": {Handle: "web"},
+		},
+		Monitor: ManifestMonitor{Hook: `cat > ` + output + `; echo "$GAOL_EVENT" >> ` + output},
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(nil, garden.ContainerNotFoundError{Handle: "web"})
+
+	var log bytes.Buffer
+	Monitor(fakeClient, manifest, true, &log, nil)
+
+	contents, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatalf("hook did not run: %s (logged %q)", err, log.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[1], " web: missing") {
+		t.Fatalf("hook got %q", contents)
+	}
+
+	var event MonitorEvent
+	if err := json.Unmarshal([]byte(lines[0]), &event); err != nil || event.Problem != "missing" {
+		t.Errorf("hook got %q on stdin", lines[0])
+	}
+}
+
+func TestMonitorJSONLines(t *testing.T) {
+	manifest := &Manifest{
+		Name: "app",
+		Containers: map[string]ManifestContainer{
+			"web": {Handle: "app-web"},
+		},
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(nil, garden.ContainerNotFoundError{Handle: "app-web"})
+
+	var log, buf bytes.Buffer
+	Monitor(fakeClient, manifest, true, &log, NewJSONLines(&buf))
+
+	if log.Len() != 0 {
+		t.Errorf("logged %q as text", log.String())
+	}
+
+	lines := readJSONLines(t, &buf)
+	if len(lines) != 1 || lines[0].Handle != "app-web" || lines[0].Type != "problem" {
+		t.Fatalf("wrote %v", lines)
+	}
+
+	if payload, _ := lines[0].Payload.(map[string]interface{}); payload["container"] != "web" || payload["problem"] != "missing" {
+		t.Errorf("wrote %#v", lines[0].Payload)
+	}
+}
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadOCILayout flattens the image in an OCI image layout directory, such
+// as one written by skopeo or buildah, into a single archive. Layouts
+// holding several images must have one for linux/amd64.
+func LoadOCILayout(dir string) (*PulledImage, error) {
+	var layout struct {
+		Version string `json:"imageLayoutVersion"`
+	}
+
+	if err := readJSON(filepath.Join(dir, "oci-layout"), &layout); err != nil {
+		return nil, fmt.Errorf("%s is not an OCI image layout: %s", dir, err)
+	}
+
+	if layout.Version != "1.0.0" {
+		return nil, fmt.Errorf("%s has unsupported image layout version %q", dir, layout.Version)
+	}
+
+	index := &imageManifest{}
+	if err := readJSON(filepath.Join(dir, "index.json"), index); err != nil {
+		return nil, err
+	}
+
+	m, err := ociManifestFor(dir, index)
+	if err != nil {
+		return nil, err
+	}
+
+	var config struct {
+		Config struct {
+			Env []string
+		} `json:"config"`
+	}
+
+	configPath, err := ociBlob(dir, m.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := readJSON(configPath, &config); err != nil {
+		return nil, err
+	}
+
+	layers := []string{}
+	for _, layer := range m.Layers {
+		path, err := ociBlob(dir, layer)
+		if err != nil {
+			return nil, err
+		}
+
+		layers = append(layers, path)
+	}
+
+	tmp, err := ioutil.TempDir("", "gaol-image")
+	if err != nil {
+		return nil, err
+	}
+
+	image := &PulledImage{
+		Path: filepath.Join(tmp, "rootfs.tar"),
+		Env:  config.Config.Env,
+	}
+
+	if err := flattenLayersToFile(layers, image.Path); err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+
+	return image, nil
+}
+
+// ociManifestFor follows an index down to the manifest of the image to use.
+func ociManifestFor(dir string, index *imageManifest) (*imageManifest, error) {
+	var chosen *descriptor
+	switch {
+	case len(index.Manifests) == 1:
+		chosen = &index.Manifests[0]
+	default:
+		for i, m := range index.Manifests {
+			if m.Platform != nil && m.Platform.OS == "linux" && m.Platform.Architecture == "amd64" {
+				chosen = &index.Manifests[i]
+				break
+			}
+		}
+	}
+
+	if chosen == nil {
+		return nil, fmt.Errorf("%s has %d images and none for linux/amd64", dir, len(index.Manifests))
+	}
+
+	path, err := ociBlob(dir, *chosen)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &imageManifest{}
+	if err := readJSON(path, m); err != nil {
+		return nil, err
+	}
+
+	if m.MediaType == "" {
+		m.MediaType = chosen.MediaType
+	}
+
+	switch m.MediaType {
+	case dockerManifest, ociManifest:
+		return m, nil
+	case dockerManifestList, ociIndex:
+		return ociManifestFor(dir, m)
+	}
+
+	return nil, fmt.Errorf("%s has a manifest of unsupported type %q", dir, m.MediaType)
+}
+
+// ociBlob returns the path of a blob in the layout.
+func ociBlob(dir string, blob descriptor) (string, error) {
+	parts := strings.SplitN(blob.Digest, ":", 2)
+	if len(parts) != 2 || parts[1] == "" || strings.ContainsAny(parts[1], `/\.`) {
+		return "", fmt.Errorf("invalid digest %q", blob.Digest)
+	}
+
+	path := filepath.Join(dir, "blobs", parts[0], parts[1])
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("%s is missing blob %s", dir, blob.Digest)
+	}
+
+	return path, nil
+}
+
+func readJSON(path string, v interface{}) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(contents, v); err != nil {
+		return fmt.Errorf("invalid %s: %s", filepath.Base(path), err)
+	}
+
+	return nil
+}
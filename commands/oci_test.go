@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeBlob adds a blob to the layout, returning its descriptor.
+func writeBlob(t *testing.T, dir string, mediaType string, contents []byte) descriptor {
+	d := descriptor{MediaType: mediaType, Digest: digest(contents), Size: int64(len(contents))}
+
+	path := filepath.Join(dir, "blobs", "sha256", strings.TrimPrefix(d.Digest, "sha256:"))
+	if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+func TestLoadOCILayout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gaol-layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(filepath.Join(dir, "blobs", "sha256"), 0755)
+
+	if _, err := LoadOCILayout(dir); err == nil {
+		t.Error("expected a directory without an oci-layout file to be refused")
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "oci-layout"), []byte(`{"imageLayoutVersion": "1.0.0"}`), 0644)
+
+	base := writeBlob(t, dir, "", layer(t, layerEntry{name: "etc/motd", contents: "hello"}))
+	top := writeBlob(t, dir, "", layer(t, layerEntry{name: "etc/.wh.motd"}, layerEntry{name: "bin/app", contents: "binary"}))
+	config := writeBlob(t, dir, "", []byte(`{"config": {"Env": ["PATH=/bin"]}}`))
+
+	manifest, _ := json.Marshal(imageManifest{Config: config, Layers: []descriptor{base, top}})
+	index, _ := json.Marshal(imageManifest{
+		Manifests: []descriptor{writeBlob(t, dir, ociManifest, manifest)},
+	})
+	ioutil.WriteFile(filepath.Join(dir, "index.json"), index, 0644)
+
+	image, err := LoadOCILayout(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer image.Remove()
+
+	if !reflect.DeepEqual(image.Env, []string{"PATH=/bin"}) {
+		t.Errorf("loaded env %q", image.Env)
+	}
+
+	archive, err := ioutil.ReadFile(image.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := archiveContents(t, archive); !reflect.DeepEqual(got, []string{"bin/app=binary"}) {
+		t.Errorf("loaded %q", got)
+	}
+}
@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// ListOrder sorts and narrows down the containers which are listed, by when
+// gaol created them and how much memory they use.
+type ListOrder struct {
+	// Sort is age (oldest first), handle or memory (most first); empty
+	// leaves the containers in the order the server gives them.
+	Sort string
+
+	// Since and Before keep the containers created after and before these
+	// times. Containers gaol did not create, which have no created-at
+	// property, are dropped by either.
+	Since  time.Time
+	Before time.Time
+
+	// Top keeps only the first this many containers, after sorting.
+	Top int
+}
+
+// IsZero reports whether the order leaves the list as it is.
+func (o ListOrder) IsZero() bool {
+	return o.Sort == "" && o.Since.IsZero() && o.Before.IsZero() && o.Top == 0
+}
+
+// ParseSort checks the key to sort by, taking mem as short for memory.
+func ParseSort(key string) (string, error) {
+	switch key {
+	case "", "age", "handle", "memory":
+		return key, nil
+	case "mem":
+		return "memory", nil
+	default:
+		return "", fmt.Errorf("cannot sort by %s", key)
+	}
+}
+
+// ParseAge turns a duration, meaning that long ago, or an RFC 3339 time into
+// a time.
+func ParseAge(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid age %q: must be a duration (e.g. 2h) or an RFC 3339 time", value)
+	}
+
+	return t, nil
+}
+
+// orderedContainer is a container along with what it is sorted by.
+type orderedContainer struct {
+	handle    string
+	createdAt time.Time
+	memory    uint64
+}
+
+// OrderedHandles returns the handles of the containers chosen by the
+// selector, narrowed down and sorted as the order says.
+func OrderedHandles(client garden.Client, selector Selector, order ListOrder) ([]string, error) {
+	containers, err := selectContainers(client, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := []orderedContainer{}
+
+	needInfo := order.Sort == "age" || order.Sort == "memory" || !order.Since.IsZero() || !order.Before.IsZero()
+	if needInfo {
+		infos, _ := bulkInfo(containers)
+
+		for _, container := range containers {
+			info, found := infos[container.Handle()]
+			if !found {
+				continue
+			}
+
+			// gaol's own stamp, as the server does not say when it
+			// created a container
+			createdAt, _ := time.Parse(time.RFC3339, info.Properties[CreatedAtProperty])
+
+			if !order.Since.IsZero() && (createdAt.IsZero() || !createdAt.After(order.Since)) {
+				continue
+			}
+
+			if !order.Before.IsZero() && (createdAt.IsZero() || !createdAt.Before(order.Before)) {
+				continue
+			}
+
+			ordered = append(ordered, orderedContainer{
+				handle:    container.Handle(),
+				createdAt: createdAt,
+				memory:    info.MemoryStat.TotalRss,
+			})
+		}
+	} else {
+		for _, container := range containers {
+			ordered = append(ordered, orderedContainer{handle: container.Handle()})
+		}
+	}
+
+	switch order.Sort {
+	case "age":
+		sort.Stable(byAge(ordered))
+	case "handle":
+		sort.Stable(byOrderedHandle(ordered))
+	case "memory":
+		sort.Stable(byMemory(ordered))
+	}
+
+	if order.Top > 0 && len(ordered) > order.Top {
+		ordered = ordered[:order.Top]
+	}
+
+	handles := make([]string, len(ordered))
+	for i, container := range ordered {
+		handles[i] = container.handle
+	}
+
+	return handles, nil
+}
+
+// byAge puts the oldest containers first and those of unknown age last.
+type byAge []orderedContainer
+
+func (c byAge) Len() int      { return len(c) }
+func (c byAge) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c byAge) Less(i, j int) bool {
+	if c[j].createdAt.IsZero() {
+		return !c[i].createdAt.IsZero()
+	}
+
+	return !c[i].createdAt.IsZero() && c[i].createdAt.Before(c[j].createdAt)
+}
+
+type byOrderedHandle []orderedContainer
+
+func (c byOrderedHandle) Len() int           { return len(c) }
+func (c byOrderedHandle) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byOrderedHandle) Less(i, j int) bool { return c[i].handle < c[j].handle }
+
+// byMemory puts the containers using the most memory first.
+type byMemory []orderedContainer
+
+func (c byMemory) Len() int           { return len(c) }
+func (c byMemory) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byMemory) Less(i, j int) bool { return c[i].memory > c[j].memory }
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestOrderedHandles(t *testing.T) {
+	now := time.Now()
+
+	container := func(handle string, age time.Duration, rss uint64) garden.Container {
+		info := garden.ContainerInfo{
+			Properties: garden.Properties{},
+			MemoryStat: garden.ContainerMemoryStat{TotalRss: rss},
+		}
+
+		if age > 0 {
+			info.Properties[CreatedAtProperty] = now.Add(-age).Format(time.RFC3339)
+		}
+
+		c := fakeContainer(handle)
+		c.InfoReturns(info, nil)
+		return c
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersReturns([]garden.Container{
+		container("web", 2*time.Hour, 300),
+		container("unstamped", 0, 900),
+		container("db", 48*time.Hour, 100),
+		container("ci", 10*time.Minute, 200),
+	}, nil)
+
+	tests := []struct {
+		order   ListOrder
+		handles []string
+	}{
+		{ListOrder{Sort: "age"}, []string{"db", "web", "ci", "unstamped"}},
+		{ListOrder{Sort: "handle"}, []string{"ci", "db", "unstamped", "web"}},
+		{ListOrder{Sort: "memory", Top: 2}, []string{"unstamped", "web"}},
+		{ListOrder{Since: now.Add(-3 * time.Hour)}, []string{"web", "ci"}},
+		{ListOrder{Before: now.Add(-time.Hour), Sort: "memory"}, []string{"web", "db"}},
+		{ListOrder{Top: 1}, []string{"web"}},
+	}
+
+	for _, test := range tests {
+		handles, err := OrderedHandles(fakeClient, Selector{All: true}, test.order)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(handles, test.handles) {
+			t.Errorf("%+v: listed %v, want %v", test.order, handles, test.handles)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	since, err := ParseAge("2h")
+	if err != nil || time.Since(since) < 2*time.Hour || time.Since(since) > 2*time.Hour+time.Minute {
+		t.Errorf("parsed 2h as %s (%v)", since, err)
+	}
+
+	at, err := ParseAge("2015-02-07T15:14:17Z")
+	if err != nil || !at.Equal(time.Date(2015, 2, 7, 15, 14, 17, 0, time.UTC)) {
+		t.Errorf("parsed a time as %s (%v)", at, err)
+	}
+
+	if _, err := ParseAge("yesterday"); err == nil {
+		t.Error("parsed yesterday")
+	}
+}
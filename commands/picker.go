@@ -0,0 +1,286 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// pickerHeight is the most containers the picker shows at once.
+const pickerHeight = 10
+
+// ErrNothingPicked is returned when the picker is left without picking.
+var ErrNothingPicked = errors.New("no container picked")
+
+// Candidate is a container which can be picked, along with its properties
+// as key=value pairs, which can be searched as well as its handle.
+type Candidate struct {
+	Handle string
+	Detail string
+}
+
+// PickContainer has the user pick one of the containers on the server on the
+// terminal, typing to narrow them down by handle and properties.
+func PickContainer(client garden.Client) (string, error) {
+	containers, err := client.Containers(nil)
+	if err != nil {
+		return "", err
+	}
+
+	if len(containers) == 0 {
+		return "", errors.New("no containers to pick from")
+	}
+
+	infos, _ := bulkInfo(containers)
+
+	candidates := []Candidate{}
+	for _, container := range containers {
+		handle := container.Handle()
+
+		pairs := []string{}
+		for key, value := range infos[handle].Properties {
+			pairs = append(pairs, key+"="+value)
+		}
+
+		sort.Strings(pairs)
+
+		candidates = append(candidates, Candidate{Handle: handle, Detail: strings.Join(pairs, " ")})
+	}
+
+	sort.Sort(byHandle(candidates))
+
+	term, err := openTerminal()
+	if err != nil {
+		return "", err
+	}
+
+	if err := term.SetRaw(); err != nil {
+		return "", err
+	}
+	defer term.Restore()
+	defer AtExit(func() { term.Restore() })()
+
+	width := 80
+	if _, cols, err := term.Size(); err == nil && cols > 0 {
+		width = cols
+	}
+
+	return pick(term, term, candidates, width)
+}
+
+type byHandle []Candidate
+
+func (c byHandle) Len() int           { return len(c) }
+func (c byHandle) Less(i, j int) bool { return c[i].Handle < c[j].Handle }
+func (c byHandle) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+
+// picker keys
+const (
+	keyInterrupt = 3
+	keyNext      = 14
+	keyPrevious  = 16
+	keyClear     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+	keyBackspace = 8
+)
+
+// pick reads keys from in and draws the picker below the cursor on out,
+// lines cut to width, until a candidate is picked with enter or the picker
+// is left with escape or ^C.
+func pick(in io.Reader, out io.Writer, candidates []Candidate, width int) (string, error) {
+	query := []rune{}
+	selected := 0
+	matches := matching(candidates, "")
+
+	draw := func() {
+		prompt := fmt.Sprintf("%d/%d > %s", len(matches), len(candidates), string(query))
+		fmt.Fprint(out, "\r\033[J"+prompt)
+
+		shown := matches
+		if len(shown) > pickerHeight {
+			shown = shown[:pickerHeight]
+		}
+
+		for i, candidate := range shown {
+			marker := "  "
+			if i == selected {
+				marker = "> "
+			}
+
+			line := marker + candidate.Handle
+			if candidate.Detail != "" {
+				line += "  " + candidate.Detail
+			}
+
+			if len(line) > width-1 {
+				line = line[:width-1]
+			}
+
+			fmt.Fprint(out, "\r\n"+line)
+		}
+
+		// back up to the end of the query
+		if len(shown) > 0 {
+			fmt.Fprintf(out, "\033[%dA", len(shown))
+		}
+
+		fmt.Fprintf(out, "\r\033[%dC", len(prompt))
+	}
+
+	clear := func() {
+		fmt.Fprint(out, "\r\033[J")
+	}
+
+	draw()
+
+	buf := make([]byte, 256)
+	for {
+		n, err := in.Read(buf)
+		if n == 0 && err != nil {
+			clear()
+			if err == io.EOF {
+				return "", ErrNothingPicked
+			}
+
+			return "", err
+		}
+
+		input := []rune(string(buf[:n]))
+		for i := 0; i < len(input); i++ {
+			switch key := input[i]; {
+			case key == '\r' || key == '\n':
+				clear()
+
+				if len(matches) == 0 {
+					return "", ErrNothingPicked
+				}
+
+				return matches[selected].Handle, nil
+			case key == keyInterrupt:
+				clear()
+				return "", ErrNothingPicked
+			case key == keyEscape:
+				// arrows arrive as escape sequences; escape alone leaves
+				if i+2 < len(input) && input[i+1] == '[' {
+					switch input[i+2] {
+					case 'A':
+						selected--
+					case 'B':
+						selected++
+					}
+
+					i += 2
+					break
+				}
+
+				clear()
+				return "", ErrNothingPicked
+			case key == keyNext:
+				selected++
+			case key == keyPrevious:
+				selected--
+			case key == keyDelete || key == keyBackspace:
+				if len(query) > 0 {
+					query = query[:len(query)-1]
+					matches = matching(candidates, string(query))
+					selected = 0
+				}
+			case key == keyClear:
+				query = query[:0]
+				matches = matching(candidates, "")
+				selected = 0
+			case unicode.IsPrint(key):
+				query = append(query, key)
+				matches = matching(candidates, string(query))
+				selected = 0
+			}
+
+			shown := len(matches)
+			if shown > pickerHeight {
+				shown = pickerHeight
+			}
+
+			if selected >= shown {
+				selected = shown - 1
+			}
+
+			if selected < 0 {
+				selected = 0
+			}
+		}
+
+		draw()
+	}
+}
+
+// matching returns the candidates which fuzzily match the query, best
+// matches first.
+func matching(candidates []Candidate, query string) []Candidate {
+	matches := byScore{}
+	for _, candidate := range candidates {
+		if score, ok := fuzzyScore(query, candidate.Handle+" "+candidate.Detail); ok {
+			matches.candidates = append(matches.candidates, candidate)
+			matches.scores = append(matches.scores, score)
+		}
+	}
+
+	sort.Stable(matches)
+
+	return matches.candidates
+}
+
+type byScore struct {
+	candidates []Candidate
+	scores     []int
+}
+
+func (s byScore) Len() int           { return len(s.candidates) }
+func (s byScore) Less(i, j int) bool { return s.scores[i] > s.scores[j] }
+func (s byScore) Swap(i, j int) {
+	s.candidates[i], s.candidates[j] = s.candidates[j], s.candidates[i]
+	s.scores[i], s.scores[j] = s.scores[j], s.scores[i]
+}
+
+// fuzzyScore reports whether the characters of the query appear in s in
+// order, ignoring case, and how well they do: characters which follow one
+// another in s, and a match at its start, score higher.
+func fuzzyScore(query string, s string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	r := []rune(strings.ToLower(s))
+
+	score := 0
+	last := -1
+
+	for _, c := range q {
+		found := -1
+		for i := last + 1; i < len(r); i++ {
+			if r[i] == c {
+				found = i
+				break
+			}
+		}
+
+		if found == -1 {
+			return 0, false
+		}
+
+		switch {
+		case found == 0:
+			score += 3
+		case found == last+1:
+			score += 2
+		default:
+			score++
+		}
+
+		last = found
+	}
+
+	return score, true
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+var pickerCandidates = []Candidate{
+	{Handle: "api-1", Detail: "team=web"},
+	{Handle: "db", Detail: "team=data"},
+	{Handle: "worker-1", Detail: "team=web"},
+}
+
+func TestPick(t *testing.T) {
+	tests := []struct {
+		keys   string
+		picked string
+	}{
+		{"\r", "api-1"},
+		{"db\r", "db"},
+		{"wk\r", "worker-1"},
+		{"\x1b[B\x1b[B\r", "worker-1"},
+		{"\x0e\x0e\x0e\x0e\x10\r", "db"},
+		{"team=data\r", "db"},
+		{"x\x7fdb\r", "db"},
+		{"zzz\x15db\r", "db"},
+	}
+
+	for _, test := range tests {
+		picked, err := pick(strings.NewReader(test.keys), ioutil.Discard, pickerCandidates, 80)
+		if err != nil || picked != test.picked {
+			t.Errorf("%q: picked %q (%v), want %q", test.keys, picked, err, test.picked)
+		}
+	}
+
+	for _, keys := range []string{"\x1b", "\x03", "zzz\r", ""} {
+		if picked, err := pick(strings.NewReader(keys), ioutil.Discard, pickerCandidates, 80); err != ErrNothingPicked {
+			t.Errorf("%q: picked %q (%v), want nothing", keys, picked, err)
+		}
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	if _, ok := fuzzyScore("wkr", "worker-1"); !ok {
+		t.Error("wkr did not match worker-1")
+	}
+
+	if _, ok := fuzzyScore("rkw", "worker-1"); ok {
+		t.Error("rkw matched worker-1 out of order")
+	}
+
+	// runs of characters beat scattered ones
+	run, _ := fuzzyScore("web", "web-1")
+	scattered, _ := fuzzyScore("web", "worker-eb")
+	if run <= scattered {
+		t.Errorf("scored a run %d and scattered characters %d", run, scattered)
+	}
+}
@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// waitForServer pings the server every interval until it responds, giving
+// up once timeout has passed.
+func waitForServer(client garden.Client, timeout, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		err := client.Ping()
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("server not available after %s: %s", timeout, err)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// Ping checks that the server is running. If wait is given, the server is
+// pinged every interval until it responds or wait has passed.
+func Ping(client garden.Client, wait, interval time.Duration) error {
+	if wait > 0 {
+		return waitForServer(client, wait, interval)
+	}
+
+	return client.Ping()
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// PorcelainVersions are the versions of the porcelain format gaol can
+// print. Output in a version never changes once released; anything a later
+// gaol needs to print differently goes in a new version.
+var PorcelainVersions = []string{"v1"}
+
+// porcelainInfoLines renders the info of a container in porcelain v1: one
+// tab-separated line per field, starting with the handle and the field's
+// name, with sizes in bytes and CPU time in nanoseconds. Fields holding
+// several values, such as process and port, are given one line per value.
+func porcelainInfoLines(handle string, info garden.ContainerInfo) []string {
+	lines := []string{}
+
+	field := func(name string, values ...interface{}) {
+		line := handle + "\t" + name
+		for _, value := range values {
+			line += fmt.Sprintf("\t%v", value)
+		}
+
+		lines = append(lines, line)
+	}
+
+	field("state", info.State)
+	field("host_ip", info.HostIP)
+	field("container_ip", info.ContainerIP)
+	field("external_ip", info.ExternalIP)
+	field("container_path", info.ContainerPath)
+
+	for _, pid := range info.ProcessIDs {
+		field("process", pid)
+	}
+
+	for _, event := range info.Events {
+		field("event", event)
+	}
+
+	field("memory_rss", info.MemoryStat.TotalRss)
+	field("memory_cache", info.MemoryStat.TotalCache)
+	field("memory_limit", info.MemoryStat.HierarchicalMemoryLimit)
+	field("cpu_usage", info.CPUStat.Usage)
+	field("cpu_user", info.CPUStat.User)
+	field("cpu_system", info.CPUStat.System)
+	field("disk_used", info.DiskStat.BytesUsed)
+	field("disk_inodes", info.DiskStat.InodesUsed)
+	field("bandwidth_in_rate", info.BandwidthStat.InRate)
+	field("bandwidth_in_burst", info.BandwidthStat.InBurst)
+	field("bandwidth_out_rate", info.BandwidthStat.OutRate)
+	field("bandwidth_out_burst", info.BandwidthStat.OutBurst)
+
+	for _, mapping := range info.MappedPorts {
+		field("port", mapping.HostPort, mapping.ContainerPort)
+	}
+
+	keys := make([]string, 0, len(info.Properties))
+	for key := range info.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field("property", key, info.Properties[key])
+	}
+
+	return lines
+}
+
+// porcelainValue renders a metric in porcelain v1: sizes and counts as
+// integers and CPU time in seconds, without units.
+func porcelainValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestPorcelainInfo(t *testing.T) {
+	container := fakeContainer("web")
+	container.InfoReturns(garden.ContainerInfo{
+		State:       "active",
+		ContainerIP: "10.254.0.2",
+		ProcessIDs:  []uint32{12, 14},
+		MemoryStat:  garden.ContainerMemoryStat{TotalRss: 1536},
+		CPUStat:     garden.ContainerCPUStat{Usage: 2500000000},
+		MappedPorts: []garden.PortMapping{{HostPort: 61001, ContainerPort: 8080}},
+		Properties:  garden.Properties{"app": "web site"},
+	}, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	var buf bytes.Buffer
+	if err := Info(fakeClient, "web", &buf, Format{Porcelain: true, Color: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "web\tstate\tactive\n" +
+		"web\thost_ip\t\n" +
+		"web\tcontainer_ip\t10.254.0.2\n" +
+		"web\texternal_ip\t\n" +
+		"web\tcontainer_path\t\n" +
+		"web\tprocess\t12\n" +
+		"web\tprocess\t14\n" +
+		"web\tmemory_rss\t1536\n" +
+		"web\tmemory_cache\t0\n" +
+		"web\tmemory_limit\t0\n" +
+		"web\tcpu_usage\t2500000000\n" +
+		"web\tcpu_user\t0\n" +
+		"web\tcpu_system\t0\n" +
+		"web\tdisk_used\t0\n" +
+		"web\tdisk_inodes\t0\n" +
+		"web\tbandwidth_in_rate\t0\n" +
+		"web\tbandwidth_in_burst\t0\n" +
+		"web\tbandwidth_out_rate\t0\n" +
+		"web\tbandwidth_out_burst\t0\n" +
+		"web\tport\t61001\t8080\n" +
+		"web\tproperty\tapp\tweb site\n"
+	if buf.String() != want {
+		t.Errorf("printed %q, want %q", buf.String(), want)
+	}
+}
+
+func TestPorcelainMetrics(t *testing.T) {
+	container := fakeContainer("web")
+	container.InfoReturns(garden.ContainerInfo{
+		MemoryStat: garden.ContainerMemoryStat{TotalRss: 600 << 20},
+		CPUStat:    garden.ContainerCPUStat{Usage: 1500000000},
+		ProcessIDs: []uint32{1},
+	}, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersReturns([]garden.Container{container}, nil)
+
+	var buf bytes.Buffer
+	if err := Metrics(fakeClient, Selector{All: true}, MetricsQuery{}, &buf, Format{Porcelain: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "web\t629145600\t0\t0\t0\t0\t1.5\t1\n"; buf.String() != want {
+		t.Errorf("printed %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// PortMapping is a local port which should be forwarded to a port in a
+// container.
+type PortMapping struct {
+	Local     uint32
+	Container uint32
+}
+
+// ParsePortMapping parses LOCAL:CONTAINER, or a single port which is used
+// for both.
+func ParsePortMapping(spec string) (PortMapping, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) == 1 {
+		parts = append(parts, parts[0])
+	}
+
+	local, err := strconv.ParseUint(parts[0], 10, 16)
+	if err != nil {
+		return PortMapping{}, fmt.Errorf("invalid local port in %q", spec)
+	}
+
+	container, err := strconv.ParseUint(parts[1], 10, 16)
+	if err != nil || container == 0 {
+		return PortMapping{}, fmt.Errorf("invalid container port in %q", spec)
+	}
+
+	return PortMapping{uint32(local), uint32(container)}, nil
+}
+
+// PortForward maps each container port to a port on the Garden host and
+// then proxies connections made to the local ports through to it. It only
+// returns if one of the listeners fails. Each forward is announced on w.
+func PortForward(container garden.Container, gardenHost, address string, mappings []PortMapping, w io.Writer) error {
+	errs := make(chan error, len(mappings))
+
+	for _, mapping := range mappings {
+		hostPort, _, err := container.NetIn(0, mapping.Container)
+		if err != nil {
+			return err
+		}
+
+		listener, err := net.Listen("tcp", net.JoinHostPort(address, fmt.Sprintf("%d", mapping.Local)))
+		if err != nil {
+			return err
+		}
+
+		upstream := net.JoinHostPort(gardenHost, fmt.Sprintf("%d", hostPort))
+		fmt.Fprintf(w, "forwarding %s -> %s -> %d\n", listener.Addr(), upstream, mapping.Container)
+
+		go func() {
+			errs <- proxyTCP(listener, func() (net.Conn, error) {
+				return net.Dial("tcp", upstream)
+			})
+		}()
+	}
+
+	return <-errs
+}
+
+// proxyTCP accepts connections from the listener and pipes each one to a
+// new connection from dial.
+func proxyTCP(listener net.Listener, dial func() (net.Conn, error)) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+
+			upstream, err := dial()
+			if err != nil {
+				return
+			}
+			defer upstream.Close()
+
+			pipe(conn, upstream)
+		}(conn)
+	}
+}
+
+// pipe copies data in both directions until either side is done.
+func pipe(a, b io.ReadWriter) {
+	done := make(chan struct{}, 2)
+
+	go func() {
+		io.Copy(a, b)
+		done <- struct{}{}
+	}()
+
+	go func() {
+		io.Copy(b, a)
+		done <- struct{}{}
+	}()
+
+	<-done
+}
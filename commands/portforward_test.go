@@ -0,0 +1,24 @@
+package commands
+
+import "testing"
+
+func TestParsePortMapping(t *testing.T) {
+	tests := map[string]PortMapping{
+		"8080":      {8080, 8080},
+		"9000:8080": {9000, 8080},
+		"0:8080":    {0, 8080},
+	}
+
+	for spec, want := range tests {
+		mapping, err := ParsePortMapping(spec)
+		if err != nil || mapping != want {
+			t.Errorf("%q parsed as %v (%v), want %v", spec, mapping, err, want)
+		}
+	}
+
+	for _, invalid := range []string{"", "http", "8080:0", "8080:http", "70000:80"} {
+		if _, err := ParsePortMapping(invalid); err == nil {
+			t.Errorf("parsed invalid mapping %q", invalid)
+		}
+	}
+}
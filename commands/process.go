@@ -0,0 +1,506 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-shellwords"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// ProcessExitError is a process in a container exiting unsuccessfully.
+type ProcessExitError struct {
+	Command string
+	Status  int
+}
+
+func (err ProcessExitError) Error() string {
+	return fmt.Sprintf("%s exited with status %d", err.Command, err.Status)
+}
+
+// ProcessTimeoutError is a process in a container outliving its timeout,
+// after which it was asked to terminate and, if it still did not exit,
+// killed.
+type ProcessTimeoutError struct {
+	Command string
+	Timeout time.Duration
+	Killed  bool
+}
+
+func (err ProcessTimeoutError) Error() string {
+	if err.Killed {
+		return fmt.Sprintf("%s did not exit within %s, so was killed", err.Command, err.Timeout)
+	}
+
+	return fmt.Sprintf("%s did not exit within %s, so was terminated", err.Command, err.Timeout)
+}
+
+// MissingDirError is a process's working directory not existing in the
+// container.
+type MissingDirError struct {
+	Handle string
+	Dir    string
+}
+
+func (err MissingDirError) Error() string {
+	return fmt.Sprintf("working directory %s does not exist in %s", err.Dir, err.Handle)
+}
+
+// RunOptions describe how a process is run. CreateDir makes the working
+// directory, and any parents, before running the process in it. Env holds
+// KEY=VALUE variables, of which a later one overrides an earlier one with the
+// same key. An attached process still running after Timeout is asked to
+// terminate, and killed if it is running KillAfter later. Report, if set,
+// records each attached process as a test case.
+type RunOptions struct {
+	Attach     bool
+	Dir        string
+	CreateDir  bool
+	User       string
+	Privileged bool
+	Env        []string
+	Timeout    time.Duration
+	KillAfter  time.Duration
+	Report     *Report
+}
+
+// Run starts command in the container. When attaching, the process is
+// connected to processIO and waited on; otherwise its ID is written to w.
+func Run(client garden.Client, handle string, command string, opts RunOptions, processIO garden.ProcessIO, w io.Writer) error {
+	if opts.Attach && opts.Report != nil {
+		return opts.Report.run(client, handle, command, opts, processIO, w)
+	}
+
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	args, err := shellwords.Parse(command)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return errors.New("missing command to run")
+	}
+
+	if !opts.Attach {
+		processIO = garden.ProcessIO{}
+	}
+
+	if opts.Dir != "" && opts.CreateDir {
+		if err := makeDir(container, opts); err != nil {
+			return err
+		}
+	}
+
+	process, err := container.Run(garden.ProcessSpec{
+		Path:       args[0],
+		Args:       args[1:],
+		Dir:        opts.Dir,
+		Privileged: opts.Privileged,
+		User:       opts.User,
+		Env:        mergeEnv(opts.Env),
+	}, processIO)
+	if err != nil {
+		// the server does not say why, so find out whether it was the
+		// directory
+		if opts.Dir != "" && !opts.CreateDir && !dirExists(container, opts) {
+			return MissingDirError{handle, opts.Dir}
+		}
+
+		return err
+	}
+
+	// the process has started whether or not it could be recorded
+	recordProcess(container, ProcessRecord{
+		PID:      process.ID(),
+		Command:  command,
+		User:     opts.User,
+		Started:  time.Now().UTC(),
+		Attached: opts.Attach,
+	})
+
+	if !opts.Attach {
+		fmt.Fprintln(w, process.ID())
+		return nil
+	}
+
+	if opts.Timeout > 0 {
+		return waitForExitWithin(process, command, opts.Timeout, opts.KillAfter)
+	}
+
+	return waitForExit(process, command)
+}
+
+// makeDir creates the working directory as the user the process is run as,
+// so that it can write there.
+func makeDir(container garden.Container, opts RunOptions) error {
+	var stderr bytes.Buffer
+
+	process, err := container.Run(garden.ProcessSpec{
+		Path:       "mkdir",
+		Args:       []string{"-p", opts.Dir},
+		Privileged: opts.Privileged,
+		User:       opts.User,
+	}, garden.ProcessIO{Stderr: &stderr})
+	if err != nil {
+		return fmt.Errorf("creating %s: %s", opts.Dir, err)
+	}
+
+	status, err := process.Wait()
+	if err != nil {
+		return fmt.Errorf("creating %s: %s", opts.Dir, err)
+	}
+
+	if status != 0 {
+		return fmt.Errorf("creating %s: %s", opts.Dir, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// dirExists reports whether the working directory exists, or whether it
+// cannot be told.
+func dirExists(container garden.Container, opts RunOptions) bool {
+	process, err := container.Run(garden.ProcessSpec{
+		Path:       "test",
+		Args:       []string{"-d", opts.Dir},
+		Privileged: opts.Privileged,
+		User:       opts.User,
+	}, garden.ProcessIO{})
+	if err != nil {
+		return true
+	}
+
+	status, err := process.Wait()
+	return err != nil || status == 0
+}
+
+// CommandResult is how a command run in a container exited.
+type CommandResult struct {
+	Command string
+	Err     error
+}
+
+// mergeEnv drops the variables in env which are overridden by later ones
+// with the same key, keeping the order of the rest.
+func mergeEnv(env []string) []string {
+	if len(env) == 0 {
+		return nil
+	}
+
+	last := map[string]int{}
+	for i, variable := range env {
+		last[strings.SplitN(variable, "=", 2)[0]] = i
+	}
+
+	merged := []string{}
+	for i, variable := range env {
+		if last[strings.SplitN(variable, "=", 2)[0]] == i {
+			merged = append(merged, variable)
+		}
+	}
+
+	return merged
+}
+
+// LoadCommands reads the commands in a file, one per line, skipping blank
+// lines and comments starting with #. A path of - reads stdin.
+func LoadCommands(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		r = file
+	}
+
+	commands := []string{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		commands = append(commands, line)
+	}
+
+	return commands, scanner.Err()
+}
+
+// RunCommands runs each command in the container in turn, attached to
+// processIO, and returns how each exited. It stops after the first command
+// which fails unless told to keep going.
+func RunCommands(client garden.Client, handle string, commands []string, opts RunOptions, processIO garden.ProcessIO, keepGoing bool) []CommandResult {
+	opts.Attach = true
+
+	results := []CommandResult{}
+	for _, command := range commands {
+		err := Run(client, handle, command, opts, processIO, nil)
+		results = append(results, CommandResult{command, err})
+
+		if err != nil && !keepGoing {
+			break
+		}
+	}
+
+	return results
+}
+
+// PrintCommandStatuses writes the exit status of each command, or why it
+// could not be run, and the command, one tab-separated line per command.
+func PrintCommandStatuses(w io.Writer, results []CommandResult) {
+	for _, result := range results {
+		switch err := result.Err.(type) {
+		case nil:
+			fmt.Fprintf(w, "0\t%s\n", result.Command)
+		case ProcessExitError:
+			fmt.Fprintf(w, "%d\t%s\n", err.Status, result.Command)
+		default:
+			fmt.Fprintf(w, "error: %s\t%s\n", err, result.Command)
+		}
+	}
+}
+
+// Attach connects to a running process and waits for it to exit,
+// re-attaching to it as reconnect says if the connection drops.
+func Attach(client garden.Client, handle string, pid uint32, processIO garden.ProcessIO, reconnect Reconnect) error {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	attachIO := func() garden.ProcessIO { return processIO }
+	if reconnect.Attempts > 0 && processIO.Stdin != nil {
+		relay := newStdinRelay(processIO.Stdin)
+		attachIO = func() garden.ProcessIO {
+			relayed := processIO
+			relayed.Stdin = relay.next()
+			return relayed
+		}
+	}
+
+	process, err := container.Attach(pid, attachIO())
+	if err != nil {
+		return err
+	}
+
+	status, err := waitReconnecting(process, reconnect, func(pid uint32) (garden.Process, error) {
+		return container.Attach(pid, attachIO())
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	if status != 0 {
+		return ProcessExitError{fmt.Sprintf("process %d", pid), status}
+	}
+
+	return nil
+}
+
+// Signal asks a process in the container to terminate or, with kill, kills
+// it.
+func Signal(client garden.Client, handle string, pid uint32, kill bool) error {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	process, err := container.Attach(pid, garden.ProcessIO{})
+	if err != nil {
+		return err
+	}
+
+	signal := garden.SignalTerminate
+	if kill {
+		signal = garden.SignalKill
+	}
+
+	return process.Signal(signal)
+}
+
+func waitForExit(process garden.Process, command string) error {
+	status, err := process.Wait()
+	if err != nil {
+		return err
+	}
+
+	if status != 0 {
+		return ProcessExitError{command, status}
+	}
+
+	return nil
+}
+
+// waitForExitWithin waits for the process to exit, terminating it once the
+// timeout passes and killing it if it is still running killAfter later.
+func waitForExitWithin(process garden.Process, command string, timeout time.Duration, killAfter time.Duration) error {
+	exited := make(chan error, 1)
+	go func() {
+		exited <- waitForExit(process, command)
+	}()
+
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(timeout):
+	}
+
+	process.Signal(garden.SignalTerminate)
+
+	select {
+	case <-exited:
+		return ProcessTimeoutError{command, timeout, false}
+	case <-time.After(killAfter):
+	}
+
+	process.Signal(garden.SignalKill)
+
+	// give the rest of its output a chance to arrive, but not forever
+	select {
+	case <-exited:
+	case <-time.After(killAfter):
+	}
+
+	return ProcessTimeoutError{command, timeout, true}
+}
+
+// Shell runs an interactive login shell in the container on the terminal
+// connected to stdin, re-attaching to it as reconnect says if the
+// connection drops. If command is not empty, it is run first by the shell
+// which then becomes the login shell, so that where it leaves the shell and
+// what it exports are kept. A shell which exits with a non-zero status
+// fails with a ProcessExitError.
+func Shell(container garden.Container, command string, reconnect Reconnect) error {
+	term, err := openTerminal()
+	if err != nil {
+		return err
+	}
+
+	err = term.SetRaw()
+	if err != nil {
+		return err
+	}
+	defer term.Restore()
+	defer AtExit(func() { term.Restore() })()
+
+	windowSize := func() (*garden.WindowSize, error) {
+		rows, cols, err := term.Size()
+		if err != nil {
+			return nil, err
+		}
+
+		return &garden.WindowSize{Rows: rows, Columns: cols}, nil
+	}
+
+	size, err := windowSize()
+	if err != nil {
+		return err
+	}
+
+	processIO := func() garden.ProcessIO {
+		return garden.ProcessIO{Stdin: term, Stdout: term, Stderr: term}
+	}
+
+	if reconnect.Attempts > 0 {
+		relay := newStdinRelay(term)
+		processIO = func() garden.ProcessIO {
+			return garden.ProcessIO{Stdin: relay.next(), Stdout: term, Stderr: term}
+		}
+	}
+
+	process, err := container.Run(garden.ProcessSpec{
+		Path: "/bin/sh",
+		Args: shellArgs(command),
+		Env:  []string{"TERM=" + os.Getenv("TERM")},
+		TTY: &garden.TTYSpec{
+			WindowSize: size,
+		},
+		Privileged: true,
+	}, processIO())
+	if err != nil {
+		return err
+	}
+
+	var current struct {
+		sync.Mutex
+		process garden.Process
+	}
+	current.process = process
+
+	resize := func() {
+		size, err := windowSize()
+		if err != nil {
+			return
+		}
+
+		current.Lock()
+		defer current.Unlock()
+
+		current.process.SetTTY(garden.TTYSpec{WindowSize: size})
+	}
+
+	resized := make(chan struct{}, 1)
+	defer term.NotifyResize(resized)()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			select {
+			case <-resized:
+				resize()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	status, err := waitReconnecting(process, reconnect, func(pid uint32) (garden.Process, error) {
+		return container.Attach(pid, processIO())
+	}, func(process garden.Process) {
+		current.Lock()
+		current.process = process
+		current.Unlock()
+
+		// the terminal may have changed size while disconnected
+		resize()
+	})
+	if err != nil {
+		return err
+	}
+
+	if status != 0 {
+		return ProcessExitError{"shell", status}
+	}
+
+	return nil
+}
+
+// shellArgs are the arguments of the shell run by Shell. The command is
+// ended by a newline rather than a semicolon, so that one ending in a
+// comment cannot swallow the exec.
+func shellArgs(command string) []string {
+	if command == "" {
+		return []string{"-l"}
+	}
+
+	return []string{"-c", command + "\nexec /bin/sh -l"}
+}
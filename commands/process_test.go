@@ -0,0 +1,430 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		command string
+		opts    RunOptions
+		spec    garden.ProcessSpec
+	}{
+		{
+			command: "ls -la /",
+			spec:    garden.ProcessSpec{Path: "ls", Args: []string{"-la", "/"}},
+		},
+		{
+			command: `sh -c "echo $HOME"`,
+			opts:    RunOptions{Dir: "/root", User: "root", Privileged: true},
+			spec: garden.ProcessSpec{
+				Path:       "sh",
+				Args:       []string{"-c", "echo $HOME"},
+				Dir:        "/root",
+				User:       "root",
+				Privileged: true,
+			},
+		},
+		{
+			command: "printenv A",
+			opts:    RunOptions{Env: []string{"A=1", "B=2", "A=3", "C"}},
+			spec:    garden.ProcessSpec{Path: "printenv", Args: []string{"A"}, Env: []string{"B=2", "A=3", "C"}},
+		},
+	}
+
+	for _, test := range tests {
+		process := new(fakes.FakeProcess)
+		process.IDReturns(7)
+
+		container := fakeContainer("a")
+		container.RunReturns(process, nil)
+
+		fakeClient := new(fakes.FakeClient)
+		fakeClient.LookupReturns(container, nil)
+
+		var buf bytes.Buffer
+		if err := Run(fakeClient, "a", test.command, test.opts, garden.ProcessIO{}, &buf); err != nil {
+			t.Errorf("%q: %s", test.command, err)
+			continue
+		}
+
+		if spec, _ := container.RunArgsForCall(0); !reflect.DeepEqual(spec, test.spec) {
+			t.Errorf("%q: ran %#v, want %#v", test.command, spec, test.spec)
+		}
+
+		if buf.String() != "7\n" {
+			t.Errorf("%q: printed %q", test.command, buf.String())
+		}
+	}
+}
+
+func TestRunAttached(t *testing.T) {
+	process := new(fakes.FakeProcess)
+	process.WaitReturns(2, nil)
+
+	container := fakeContainer("a")
+	container.RunReturns(process, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	var buf bytes.Buffer
+	err := Run(fakeClient, "a", "false", RunOptions{Attach: true}, garden.ProcessIO{Stdout: &buf}, &buf)
+	if err != (ProcessExitError{"false", 2}) {
+		t.Errorf("got %v, want the exit status", err)
+	}
+
+	if _, processIO := container.RunArgsForCall(0); processIO.Stdout != &buf {
+		t.Error("did not attach the process to stdout")
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("printed %q when attached", buf.String())
+	}
+}
+
+// stubbornProcess returns a process which runs until it is sent one of the
+// signals it exits on.
+func stubbornProcess(exitsOn ...garden.Signal) *fakes.FakeProcess {
+	exited := make(chan struct{})
+
+	process := new(fakes.FakeProcess)
+	process.WaitStub = func() (int, error) {
+		<-exited
+		return 143, nil
+	}
+	process.SignalStub = func(signal garden.Signal) error {
+		for _, s := range exitsOn {
+			if s == signal {
+				close(exited)
+			}
+		}
+		return nil
+	}
+
+	return process
+}
+
+func TestRunTimesOut(t *testing.T) {
+	tests := []struct {
+		exitsOn []garden.Signal
+		signals []garden.Signal
+		killed  bool
+	}{
+		{[]garden.Signal{garden.SignalTerminate}, []garden.Signal{garden.SignalTerminate}, false},
+		{[]garden.Signal{garden.SignalKill}, []garden.Signal{garden.SignalTerminate, garden.SignalKill}, true},
+	}
+
+	for _, test := range tests {
+		process := stubbornProcess(test.exitsOn...)
+
+		container := fakeContainer("a")
+		container.RunReturns(process, nil)
+
+		fakeClient := new(fakes.FakeClient)
+		fakeClient.LookupReturns(container, nil)
+
+		opts := RunOptions{Attach: true, Timeout: time.Millisecond, KillAfter: 50 * time.Millisecond}
+		err := Run(fakeClient, "a", "make test", opts, garden.ProcessIO{}, ioutil.Discard)
+		if err != (ProcessTimeoutError{"make test", time.Millisecond, test.killed}) {
+			t.Errorf("exiting on %v: got %v", test.exitsOn, err)
+		}
+
+		signals := []garden.Signal{}
+		for i := 0; i < process.SignalCallCount(); i++ {
+			signals = append(signals, process.SignalArgsForCall(i))
+		}
+
+		if !reflect.DeepEqual(signals, test.signals) {
+			t.Errorf("exiting on %v: sent %v, want %v", test.exitsOn, signals, test.signals)
+		}
+	}
+}
+
+func TestRunWithinTimeout(t *testing.T) {
+	process := new(fakes.FakeProcess)
+	process.WaitReturns(2, nil)
+
+	container := fakeContainer("a")
+	container.RunReturns(process, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	err := Run(fakeClient, "a", "false", RunOptions{Attach: true, Timeout: time.Minute, KillAfter: time.Second}, garden.ProcessIO{}, ioutil.Discard)
+	if err != (ProcessExitError{"false", 2}) {
+		t.Errorf("got %v, want the exit status", err)
+	}
+
+	if process.SignalCallCount() != 0 {
+		t.Error("signalled a process which exited in time")
+	}
+}
+
+func TestRunCreatesDir(t *testing.T) {
+	container := fakeContainer("a")
+	container.RunReturns(new(fakes.FakeProcess), nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	opts := RunOptions{Dir: "/srv/app", CreateDir: true, User: "vcap"}
+	if err := Run(fakeClient, "a", "ls", opts, garden.ProcessIO{}, new(bytes.Buffer)); err != nil {
+		t.Fatal(err)
+	}
+
+	if container.RunCallCount() != 2 {
+		t.Fatalf("ran %d processes, want mkdir and ls", container.RunCallCount())
+	}
+
+	want := garden.ProcessSpec{Path: "mkdir", Args: []string{"-p", "/srv/app"}, User: "vcap"}
+	if spec, _ := container.RunArgsForCall(0); !reflect.DeepEqual(spec, want) {
+		t.Errorf("ran %#v first, want %#v", spec, want)
+	}
+
+	if spec, _ := container.RunArgsForCall(1); spec.Path != "ls" || spec.Dir != "/srv/app" {
+		t.Errorf("ran %#v", spec)
+	}
+}
+
+func TestRunCannotCreateDir(t *testing.T) {
+	container := fakeContainer("a")
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		io.WriteString(processIO.Stderr, "mkdir: can't create directory '/proc/x': Permission denied\n")
+
+		process := new(fakes.FakeProcess)
+		process.WaitReturns(1, nil)
+		return process, nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	err := Run(fakeClient, "a", "ls", RunOptions{Dir: "/proc/x", CreateDir: true}, garden.ProcessIO{}, new(bytes.Buffer))
+	if err == nil || err.Error() != "creating /proc/x: mkdir: can't create directory '/proc/x': Permission denied" {
+		t.Errorf("failed with %v", err)
+	}
+
+	if container.RunCallCount() != 1 {
+		t.Error("ran the process without its directory")
+	}
+}
+
+func TestRunMissingDir(t *testing.T) {
+	for _, missing := range []bool{true, false} {
+		container := fakeContainer("a")
+		container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+			if spec.Path == "test" {
+				process := new(fakes.FakeProcess)
+				if missing {
+					process.WaitReturns(1, nil)
+				}
+
+				return process, nil
+			}
+
+			return nil, errors.New("exit status 1")
+		}
+
+		fakeClient := new(fakes.FakeClient)
+		fakeClient.LookupReturns(container, nil)
+
+		err := Run(fakeClient, "a", "ls", RunOptions{Dir: "/nonexistent"}, garden.ProcessIO{}, new(bytes.Buffer))
+
+		if missing && err != (MissingDirError{"a", "/nonexistent"}) {
+			t.Errorf("failed with %v, want the directory to be missing", err)
+		}
+
+		if !missing && (err == nil || err.Error() != "exit status 1") {
+			t.Errorf("failed with %v, want the server's error", err)
+		}
+	}
+}
+
+func TestRunEmptyCommand(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(fakeContainer("a"), nil)
+
+	if err := Run(fakeClient, "a", "  ", RunOptions{}, garden.ProcessIO{}, new(bytes.Buffer)); err == nil {
+		t.Error("ran an empty command")
+	}
+}
+
+func TestShellArgs(t *testing.T) {
+	if args := shellArgs(""); !reflect.DeepEqual(args, []string{"-l"}) {
+		t.Errorf("expected a login shell, got %q", args)
+	}
+
+	args := shellArgs("cd /app # the code")
+	if !reflect.DeepEqual(args, []string{"-c", "cd /app # the code\nexec /bin/sh -l"}) {
+		t.Errorf("expected the command and then a login shell, got %q", args)
+	}
+}
+
+func TestAttach(t *testing.T) {
+	process := new(fakes.FakeProcess)
+
+	container := fakeContainer("a")
+	container.AttachReturns(process, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	if err := Attach(fakeClient, "a", 9, garden.ProcessIO{}, Reconnect{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if pid, _ := container.AttachArgsForCall(0); pid != 9 {
+		t.Errorf("attached to %d, want 9", pid)
+	}
+}
+
+func TestAttachReconnects(t *testing.T) {
+	lost := new(fakes.FakeProcess)
+	lost.IDReturns(9)
+	lost.WaitReturns(0, io.ErrUnexpectedEOF)
+
+	exited := new(fakes.FakeProcess)
+	exited.WaitReturns(3, nil)
+
+	var stdin []byte
+	container := fakeContainer("a")
+	container.AttachStub = func(pid uint32, processIO garden.ProcessIO) (garden.Process, error) {
+		switch container.AttachCallCount() {
+		case 1:
+			return lost, nil
+		case 2:
+			return nil, errors.New("connection refused")
+		}
+
+		stdin, _ = ioutil.ReadAll(processIO.Stdin)
+		return exited, nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	err := Attach(fakeClient, "a", 9, garden.ProcessIO{Stdin: strings.NewReader("ls\n")}, Reconnect{Attempts: 2})
+	if exitErr, ok := err.(ProcessExitError); !ok || exitErr.Status != 3 {
+		t.Errorf("failed with %v, want the exit status after re-attaching", err)
+	}
+
+	if pid, _ := container.AttachArgsForCall(2); pid != 9 {
+		t.Errorf("re-attached to %d, want 9", pid)
+	}
+
+	if string(stdin) != "ls\n" {
+		t.Errorf("relayed %q to the new attachment", stdin)
+	}
+}
+
+func TestAttachGivesUpReconnecting(t *testing.T) {
+	lost := new(fakes.FakeProcess)
+	lost.WaitReturns(0, io.ErrUnexpectedEOF)
+
+	container := fakeContainer("a")
+	container.AttachStub = func(pid uint32, processIO garden.ProcessIO) (garden.Process, error) {
+		if container.AttachCallCount() == 1 {
+			return lost, nil
+		}
+
+		return nil, errors.New("connection refused")
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	err := Attach(fakeClient, "a", 9, garden.ProcessIO{}, Reconnect{Attempts: 2})
+	if err == nil || err.Error() != "connection refused" {
+		t.Errorf("failed with %v", err)
+	}
+
+	if container.AttachCallCount() != 3 {
+		t.Errorf("attached %d times, want once and 2 attempts to re-attach", container.AttachCallCount())
+	}
+}
+
+func TestAttachDoesNotReconnectAfterProcessErrors(t *testing.T) {
+	process := new(fakes.FakeProcess)
+	process.WaitReturns(0, errors.New("process error: no such process"))
+
+	container := fakeContainer("a")
+	container.AttachReturns(process, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	if err := Attach(fakeClient, "a", 9, garden.ProcessIO{}, Reconnect{Attempts: 2}); err == nil {
+		t.Error("expected the process error")
+	}
+
+	if container.AttachCallCount() != 1 {
+		t.Error("expected no attempt to re-attach")
+	}
+}
+
+func TestLoadCommands(t *testing.T) {
+	file, err := ioutil.TempFile("", "gaol-commands")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	file.WriteString("# set up\napt-get update\n\n  make install  \n")
+	file.Close()
+
+	commands, err := LoadCommands(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(commands, []string{"apt-get update", "make install"}) {
+		t.Errorf("loaded %q", commands)
+	}
+}
+
+func TestRunCommands(t *testing.T) {
+	for _, keepGoing := range []bool{false, true} {
+		container := fakeContainer("a")
+		container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+			process := new(fakes.FakeProcess)
+			if spec.Path == "false" {
+				process.WaitReturns(1, nil)
+			}
+
+			return process, nil
+		}
+
+		fakeClient := new(fakes.FakeClient)
+		fakeClient.LookupReturns(container, nil)
+
+		results := RunCommands(fakeClient, "a", []string{"true", "false", "true"}, RunOptions{}, garden.ProcessIO{}, keepGoing)
+
+		want := 2
+		if keepGoing {
+			want = 3
+		}
+
+		if container.RunCallCount() != want {
+			t.Errorf("keeping going %t: ran %d commands, want %d", keepGoing, container.RunCallCount(), want)
+		}
+
+		var buf bytes.Buffer
+		PrintCommandStatuses(&buf, results)
+
+		if !strings.HasPrefix(buf.String(), "0\ttrue\n1\tfalse\n") {
+			t.Errorf("keeping going %t: printed %q", keepGoing, buf.String())
+		}
+	}
+}
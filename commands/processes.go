@@ -0,0 +1,191 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/mattn/go-shellwords"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// processPropertyPrefix is prepended to the name of a manifest process to
+// give the property holding its PID.
+const processPropertyPrefix = "gaol:process:"
+
+// processNameVariable is set in the environment of named processes, and
+// inherited by their children, so that they can be told apart in the
+// container.
+const processNameVariable = "GAOL_PROCESS"
+
+// attachProbeTimeout is how long an attach is given to report that the
+// process has already exited before it is considered to be running.
+const attachProbeTimeout = 250 * time.Millisecond
+
+// startProcess runs a manifest process in the background and records its PID
+// on the container.
+func startProcess(container garden.Container, mp ManifestProcess) (garden.Process, error) {
+	args, err := shellwords.Parse(mp.Command)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("missing command to run")
+	}
+
+	process, err := container.Run(garden.ProcessSpec{
+		Path:       args[0],
+		Args:       args[1:],
+		Dir:        mp.Dir,
+		User:       mp.User,
+		Privileged: mp.Privileged,
+		Env:        append(append([]string{}, mp.Env...), processNameVariable+"="+mp.Name),
+	}, garden.ProcessIO{})
+	if err != nil {
+		return nil, err
+	}
+
+	err = container.SetProperty(processPropertyPrefix+mp.Name, strconv.FormatUint(uint64(process.ID()), 10))
+	if err != nil {
+		return nil, err
+	}
+
+	return process, nil
+}
+
+// ProcessStatus describes the state of a manifest process in a live
+// container.
+type ProcessStatus struct {
+	Container string
+	Process   string
+	PID       uint32
+	Restart   string
+	State     string
+}
+
+const (
+	stateRunning     = "running"
+	stateExited      = "exited"
+	stateNotStarted  = "not-started"
+	stateNoContainer = "no-container"
+	stateUnreachable = "unreachable"
+)
+
+// Ps reports the state of every process described by the manifest.
+func Ps(client garden.Client, manifest *Manifest) ([]ProcessStatus, error) {
+	statuses := []ProcessStatus{}
+
+	for _, name := range manifest.Names() {
+		mc := manifest.Containers[name]
+		if len(mc.Processes) == 0 {
+			continue
+		}
+
+		container, err := client.Lookup(mc.Handle)
+		if _, ok := err.(garden.ContainerNotFoundError); ok {
+			for _, mp := range mc.Processes {
+				statuses = append(statuses, ProcessStatus{name, mp.Name, 0, mp.Restart, stateNoContainer})
+			}
+			continue
+		}
+
+		if err != nil {
+			return statuses, err
+		}
+
+		for _, mp := range mc.Processes {
+			status := ProcessStatus{
+				Container: name,
+				Process:   mp.Name,
+				Restart:   mp.Restart,
+			}
+
+			pid, err := processPID(container, mp.Name)
+			if err != nil {
+				status.State = stateNotStarted
+			} else {
+				status.PID = pid
+				status.State = probeProcess(container, pid)
+			}
+
+			statuses = append(statuses, status)
+		}
+	}
+
+	return statuses, nil
+}
+
+// processPID returns the recorded PID of a named process in the container.
+func processPID(container garden.Container, name string) (uint32, error) {
+	value, err := container.GetProperty(processPropertyPrefix + name)
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid pid for process %s: %s", name, value)
+	}
+
+	return uint32(pid), nil
+}
+
+// errReleased ends the stdin of an attachment that is no longer wanted.
+var errReleased = errors.New("attachment released")
+
+// attachProcess attaches to a process without any output. Calling the
+// returned function drops the connection, which also ends any Wait on the
+// process, without closing the stdin of the process itself.
+func attachProcess(container garden.Container, pid uint32) (garden.Process, func(), error) {
+	stdin, release := io.Pipe()
+
+	process, err := container.Attach(pid, garden.ProcessIO{Stdin: stdin})
+	if err != nil {
+		release.Close()
+		return nil, nil, err
+	}
+
+	return process, func() { release.CloseWithError(errReleased) }, nil
+}
+
+// probeProcess attaches to a process to find out whether it is still
+// running. A process which reports an exit status straight away has exited;
+// one which can not be attached to is unreachable.
+func probeProcess(container garden.Container, pid uint32) string {
+	process, release, err := attachProcess(container, pid)
+	if err != nil {
+		return stateUnreachable
+	}
+	defer release()
+
+	exited := make(chan int, 1)
+	go func() {
+		status, err := process.Wait()
+		if err == nil {
+			exited <- status
+		}
+	}()
+
+	select {
+	case status := <-exited:
+		return fmt.Sprintf("%s (%d)", stateExited, status)
+	case <-time.After(attachProbeTimeout):
+		return stateRunning
+	}
+}
+
+// PrintProcessStatuses writes one tab-separated line per process.
+func PrintProcessStatuses(w io.Writer, statuses []ProcessStatus) {
+	for _, status := range statuses {
+		pid := "-"
+		if status.PID != 0 {
+			pid = strconv.FormatUint(uint64(status.PID), 10)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", status.Container, status.Process, pid, status.State, status.Restart)
+	}
+}
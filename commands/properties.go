@@ -0,0 +1,24 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// ParseProperties turns a list of key=value pairs into properties.
+func ParseProperties(pairs []string) (garden.Properties, error) {
+	properties := garden.Properties{}
+
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid property %q: must be key=value", pair)
+		}
+
+		properties[kv[0]] = kv[1]
+	}
+
+	return properties, nil
+}
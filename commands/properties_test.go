@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+func TestParseProperties(t *testing.T) {
+	properties, err := ParseProperties([]string{"owner=me", "query=a=b", "empty="})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := garden.Properties{"owner": "me", "query": "a=b", "empty": ""}
+	if !reflect.DeepEqual(properties, want) {
+		t.Errorf("parsed %v, want %v", properties, want)
+	}
+
+	for _, invalid := range []string{"owner", "=me"} {
+		if _, err := ParseProperties([]string{invalid}); err == nil {
+			t.Errorf("parsed invalid property %q", invalid)
+		}
+	}
+}
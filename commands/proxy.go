@@ -0,0 +1,264 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/mattn/go-shellwords"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+const socksVersion = 5
+
+// SOCKS5 address types and replies (RFC 1928).
+const (
+	socksIPv4   = 1
+	socksDomain = 3
+	socksIPv6   = 4
+
+	socksConnect = 1
+
+	socksSucceeded          = 0
+	socksGeneralFailure     = 1
+	socksCommandUnsupported = 7
+)
+
+// containerProxy accepts SOCKS5 and HTTP proxy connections locally and
+// tunnels each of them through a netcat process running in the container, so
+// that anything the container can reach can be reached through the proxy.
+type containerProxy struct {
+	container garden.Container
+	netcat    []string
+}
+
+// Proxy serves SOCKS5 and HTTP proxy connections on listen until the
+// listener fails, announcing the address it listens on to w.
+func Proxy(container garden.Container, listen string, netcat string, w io.Writer) error {
+	args, err := shellwords.Parse(netcat)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return errors.New("missing netcat command")
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "proxying through %s on %s\n", container.Handle(), listener.Addr())
+
+	proxy := &containerProxy{
+		container: container,
+		netcat:    args,
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go proxy.serve(conn)
+	}
+}
+
+func (p *containerProxy) serve(conn net.Conn) {
+	defer conn.Close()
+
+	br := bufio.NewReader(conn)
+
+	first, err := br.Peek(1)
+	if err != nil {
+		return
+	}
+
+	if first[0] == socksVersion {
+		p.serveSOCKS(conn, br)
+	} else {
+		p.serveHTTP(conn, br)
+	}
+}
+
+func (p *containerProxy) serveSOCKS(conn net.Conn, br *bufio.Reader) {
+	// greeting: version, number of methods, methods
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return
+	}
+
+	if _, err := io.ReadFull(br, make([]byte, header[1])); err != nil {
+		return
+	}
+
+	// we only support "no authentication required"
+	conn.Write([]byte{socksVersion, 0})
+
+	// request: version, command, reserved, address type
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(br, request); err != nil {
+		return
+	}
+
+	host, err := readSOCKSAddress(br, request[3])
+	if err != nil {
+		socksReply(conn, socksGeneralFailure)
+		return
+	}
+
+	portBytes := make([]byte, 2)
+	if _, err := io.ReadFull(br, portBytes); err != nil {
+		return
+	}
+	port := binary.BigEndian.Uint16(portBytes)
+
+	if request[1] != socksConnect {
+		socksReply(conn, socksCommandUnsupported)
+		return
+	}
+
+	out := newHeldWriter(conn)
+
+	process, err := p.dial(host, port, br, out)
+	if err != nil {
+		socksReply(conn, socksGeneralFailure)
+		return
+	}
+
+	socksReply(conn, socksSucceeded)
+	out.release()
+	process.Wait()
+}
+
+func readSOCKSAddress(r io.Reader, addressType byte) (string, error) {
+	switch addressType {
+	case socksIPv4:
+		ip := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return "", err
+		}
+		return net.IP(ip).String(), nil
+	case socksIPv6:
+		ip := make([]byte, net.IPv6len)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return "", err
+		}
+		return net.IP(ip).String(), nil
+	case socksDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(r, length); err != nil {
+			return "", err
+		}
+
+		domain := make([]byte, length[0])
+		if _, err := io.ReadFull(r, domain); err != nil {
+			return "", err
+		}
+		return string(domain), nil
+	default:
+		return "", fmt.Errorf("unknown address type %d", addressType)
+	}
+}
+
+func socksReply(w io.Writer, reply byte) {
+	// the bound address is meaningless as the connection is made from inside
+	// the container
+	w.Write([]byte{socksVersion, reply, 0, socksIPv4, 0, 0, 0, 0, 0, 0})
+}
+
+func (p *containerProxy) serveHTTP(conn net.Conn, br *bufio.Reader) {
+	req, err := http.ReadRequest(br)
+	if err != nil {
+		return
+	}
+
+	host, portString, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		host, portString = req.Host, "80"
+	}
+
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		fmt.Fprint(conn, "HTTP/1.1 400 Bad Request\r\n\r\n")
+		return
+	}
+
+	if req.Method == "CONNECT" {
+		out := newHeldWriter(conn)
+
+		process, err := p.dial(host, uint16(port), br, out)
+		if err != nil {
+			fmt.Fprint(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+			return
+		}
+
+		fmt.Fprint(conn, "HTTP/1.1 200 Connection Established\r\n\r\n")
+		out.release()
+		process.Wait()
+		return
+	}
+
+	// a plain request for an absolute URL is sent on as a single request
+	// for its path
+	reader, writer := io.Pipe()
+	go func() {
+		req.RequestURI = ""
+		req.Header.Del("Proxy-Connection")
+		req.Close = true
+		writer.CloseWithError(req.Write(writer))
+	}()
+
+	process, err := p.dial(host, uint16(port), reader, conn)
+	if err != nil {
+		fmt.Fprint(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+		return
+	}
+
+	process.Wait()
+}
+
+// dial starts netcat in the container connected to host:port, wiring its
+// standard input and output to the given streams.
+func (p *containerProxy) dial(host string, port uint16, in io.Reader, out io.Writer) (garden.Process, error) {
+	args := append([]string{}, p.netcat[1:]...)
+	args = append(args, host, strconv.Itoa(int(port)))
+
+	return p.container.Run(garden.ProcessSpec{
+		Path: p.netcat[0],
+		Args: args,
+	}, garden.ProcessIO{
+		Stdin:  in,
+		Stdout: out,
+		Stderr: ioutil.Discard,
+	})
+}
+
+// heldWriter holds back writes until it is released, so that what a server
+// which speaks first says cannot reach the client before the proxy's reply.
+type heldWriter struct {
+	w        io.Writer
+	released chan struct{}
+}
+
+func newHeldWriter(w io.Writer) *heldWriter {
+	return &heldWriter{w: w, released: make(chan struct{})}
+}
+
+func (h *heldWriter) Write(p []byte) (int, error) {
+	<-h.released
+	return h.w.Write(p)
+}
+
+func (h *heldWriter) release() {
+	close(h.released)
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+// bannerContainer runs a netcat connected to a server which speaks first.
+func bannerContainer(banner string) *fakes.FakeContainer {
+	container := fakeContainer("a")
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		written := make(chan struct{})
+		go func() {
+			io.WriteString(processIO.Stdout, banner)
+			close(written)
+		}()
+
+		// the server speaks as soon as it is connected to, before the proxy
+		// hears that netcat is running
+		select {
+		case <-written:
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		process := new(fakes.FakeProcess)
+		process.WaitStub = func() (int, error) {
+			<-written
+			return 0, nil
+		}
+
+		return process, nil
+	}
+
+	return container
+}
+
+func TestProxyRepliesBeforeTheServerSpeaks(t *testing.T) {
+	const banner = "SSH-2.0-OpenSSH_6.6\r\n"
+
+	tests := []struct {
+		request string
+		reply   string
+	}{
+		{
+			request: "\x05\x01\x00" + "\x05\x01\x00\x01\x0a\x00\x00\x01\x00\x16",
+			reply:   "\x05\x00" + "\x05\x00\x00\x01\x00\x00\x00\x00\x00\x00",
+		},
+		{
+			request: "CONNECT 10.0.0.1:22 HTTP/1.1\r\nHost: 10.0.0.1:22\r\n\r\n",
+			reply:   "HTTP/1.1 200 Connection Established\r\n\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		proxy := &containerProxy{container: bannerContainer(banner), netcat: []string{"nc"}}
+
+		client, server := net.Pipe()
+		go proxy.serve(server)
+
+		go io.WriteString(client, test.request)
+
+		received := make([]byte, len(test.reply)+len(banner))
+		if _, err := io.ReadFull(bufio.NewReader(client), received); err != nil {
+			t.Fatal(err)
+		}
+		client.Close()
+
+		if string(received) != test.reply+banner {
+			t.Errorf("received %q, want the reply before the banner", received)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// PruneCriteria describe the containers to prune. A container must meet
+// every criterion given to be pruned.
+type PruneCriteria struct {
+	// Selector narrows down the containers considered; a zero selector
+	// considers every container.
+	Selector Selector
+
+	// OlderThan prunes containers created longer ago than this. Containers
+	// gaol did not create, which have no created-at property, are kept.
+	OlderThan time.Duration
+
+	// IdleCPU prunes containers which used less than this percentage of a
+	// CPU while being sampled for Sample.
+	IdleCPU float64
+	Sample  time.Duration
+}
+
+// IsZero reports whether the criteria would prune every container.
+func (p PruneCriteria) IsZero() bool {
+	return p.Selector.IsZero() && p.OlderThan <= 0 && p.IdleCPU <= 0
+}
+
+// Prunable returns the handles of the containers which meet the criteria.
+func Prunable(client garden.Client, criteria PruneCriteria) ([]string, error) {
+	containers, err := selectContainers(client, criteria.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	if criteria.OlderThan > 0 {
+		containers = createdBefore(containers, time.Now().Add(-criteria.OlderThan))
+	}
+
+	if criteria.IdleCPU > 0 {
+		containers = idle(containers, criteria.IdleCPU, criteria.Sample)
+	}
+
+	prunable := []string{}
+	for _, container := range containers {
+		prunable = append(prunable, container.Handle())
+	}
+
+	return prunable, nil
+}
+
+func createdBefore(containers []garden.Container, before time.Time) []garden.Container {
+	infos, _ := bulkInfo(containers)
+
+	old := []garden.Container{}
+	for _, container := range containers {
+		info, found := infos[container.Handle()]
+		if !found {
+			continue
+		}
+
+		createdAt, err := time.Parse(time.RFC3339, info.Properties[CreatedAtProperty])
+		if err != nil {
+			continue
+		}
+
+		if createdAt.Before(before) {
+			old = append(old, container)
+		}
+	}
+
+	return old
+}
+
+// idle samples the CPU usage of the containers twice, sample apart, and
+// returns those which used less than threshold percent of a CPU in between.
+func idle(containers []garden.Container, threshold float64, sample time.Duration) []garden.Container {
+	before, _ := bulkInfo(containers)
+	start := time.Now()
+
+	time.Sleep(sample)
+
+	after, _ := bulkInfo(containers)
+	elapsed := time.Since(start)
+
+	idle := []garden.Container{}
+	for _, container := range containers {
+		first, foundBefore := before[container.Handle()]
+		last, foundAfter := after[container.Handle()]
+		if !foundBefore || !foundAfter || last.CPUStat.Usage < first.CPUStat.Usage {
+			continue
+		}
+
+		used := float64(last.CPUStat.Usage-first.CPUStat.Usage) / float64(elapsed.Nanoseconds()) * 100
+		if used < threshold {
+			idle = append(idle, container)
+		}
+	}
+
+	return idle
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestPrunableIdle(t *testing.T) {
+	// busy uses a whole CPU's worth of time between samples, idle none
+	busy := fakeContainer("busy")
+	busyUsage := uint64(0)
+	busy.InfoStub = func() (garden.ContainerInfo, error) {
+		busyUsage += uint64(time.Second)
+		return garden.ContainerInfo{CPUStat: garden.ContainerCPUStat{Usage: busyUsage}}, nil
+	}
+
+	idle := fakeContainer("idle")
+	idle.InfoReturns(garden.ContainerInfo{CPUStat: garden.ContainerCPUStat{Usage: 42}}, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersReturns([]garden.Container{busy, idle}, nil)
+
+	handles, err := Prunable(fakeClient, PruneCriteria{IdleCPU: 5, Sample: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(handles, []string{"idle"}) {
+		t.Errorf("pruned %v, want only the idle container", handles)
+	}
+}
+
+func TestPrunableSelects(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersReturns([]garden.Container{fakeContainer("ci-1"), fakeContainer("web")}, nil)
+
+	filter := garden.Properties{"team": "ci"}
+	handles, err := Prunable(fakeClient, PruneCriteria{Selector: Selector{Match: "ci-*", Filter: filter}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(handles, []string{"ci-1"}) {
+		t.Errorf("pruned %v", handles)
+	}
+
+	if got := fakeClient.ContainersArgsForCall(0); !reflect.DeepEqual(got, filter) {
+		t.Errorf("listed containers with %v, want %v", got, filter)
+	}
+}
+
+func TestPruneCriteriaWithNegativeValuesAreZero(t *testing.T) {
+	if !(PruneCriteria{OlderThan: -time.Hour, IdleCPU: -5}).IsZero() {
+		t.Error("negative criteria, which prune nothing out, are not zero")
+	}
+}
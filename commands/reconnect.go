@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// Reconnect describes how to re-attach to a process whose stream to the
+// server was lost.
+type Reconnect struct {
+	// Attempts is how many times in a row to try re-attaching before giving
+	// up; zero never re-attaches.
+	Attempts int
+
+	// Delay is the time between attempts.
+	Delay time.Duration
+}
+
+// streamLost reports whether a process stream ended because the connection
+// dropped rather than because the process exited or the server reported an
+// error.
+func streamLost(err error) bool {
+	return err != nil && !strings.HasPrefix(err.Error(), "process error:")
+}
+
+// waitReconnecting waits for the process to exit. Whenever its stream is
+// lost it is re-attached to with attach, and reattached is called with the
+// new process, until the attempts in a row run out.
+func waitReconnecting(process garden.Process, policy Reconnect, attach func(pid uint32) (garden.Process, error), reattached func(garden.Process)) (int, error) {
+	for {
+		status, err := process.Wait()
+		if !streamLost(err) {
+			return status, err
+		}
+
+		pid := process.ID()
+
+		lost := err
+		for attempt := 1; ; attempt++ {
+			if attempt > policy.Attempts {
+				return 0, lost
+			}
+
+			time.Sleep(policy.Delay)
+
+			process, err = attach(pid)
+			if err == nil {
+				break
+			}
+
+			lost = err
+		}
+
+		if reattached != nil {
+			reattached(process)
+		}
+	}
+}
+
+// errReattached ends the stdin of a stream which was replaced.
+var errReattached = errors.New("re-attached to the process")
+
+// stdinRelay copies one reader, such as the terminal, to whichever attachment
+// to a process is current, so that re-attaching does not lose the input
+// read for an attachment which was lost.
+type stdinRelay struct {
+	src io.Reader
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	w    *io.PipeWriter
+	eof  bool
+}
+
+func newStdinRelay(src io.Reader) *stdinRelay {
+	r := &stdinRelay{src: src}
+	r.cond = sync.NewCond(&r.mu)
+
+	go r.relay()
+
+	return r
+}
+
+// next returns the stdin of a new attachment, ending that of the last one.
+func (r *stdinRelay) next() io.Reader {
+	reader, writer := io.Pipe()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.w != nil {
+		r.w.CloseWithError(errReattached)
+	}
+
+	if r.eof {
+		writer.Close()
+	}
+
+	r.w = writer
+	r.cond.Broadcast()
+
+	return reader
+}
+
+func (r *stdinRelay) relay() {
+	buf := make([]byte, 32*1024)
+
+	for {
+		n, err := r.src.Read(buf)
+		if n > 0 {
+			r.write(buf[:n])
+		}
+
+		if err != nil {
+			r.mu.Lock()
+			r.eof = true
+			r.mu.Unlock()
+
+			r.current().Close()
+			return
+		}
+	}
+}
+
+// write writes p to the current attachment, waiting for the next one if the
+// current one has gone.
+func (r *stdinRelay) write(p []byte) {
+	for {
+		w := r.current()
+
+		n, err := w.Write(p)
+		if err == nil {
+			return
+		}
+
+		p = p[n:]
+
+		r.mu.Lock()
+		for r.w == w {
+			r.cond.Wait()
+		}
+		r.mu.Unlock()
+	}
+}
+
+// current waits for an attachment and returns its stdin.
+func (r *stdinRelay) current() *io.PipeWriter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for r.w == nil {
+		r.cond.Wait()
+	}
+
+	return r.w
+}
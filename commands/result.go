@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// resultOutputSize is how much of the end of each of a process's output
+// streams is kept in its result.
+const resultOutputSize = 64 << 10
+
+// RunResult is a record of a process run to completion, for build systems to
+// keep as evidence of each step. ExitStatus is missing when the process did
+// not exit by itself, and Error says why.
+type RunResult struct {
+	Handle          string    `json:"handle"`
+	Command         string    `json:"command"`
+	Started         time.Time `json:"started"`
+	Finished        time.Time `json:"finished"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	ExitStatus      *int      `json:"exit_status,omitempty"`
+	TimedOut        bool      `json:"timed_out,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	Stdout          string    `json:"stdout"`
+	Stderr          string    `json:"stderr"`
+	Truncated       bool      `json:"truncated,omitempty"`
+}
+
+// NewRunResult records how the command run in the container between started
+// and finished went, given the error Run returned and its captured output.
+func NewRunResult(handle string, command string, started time.Time, finished time.Time, err error, stdout *OutputTail, stderr *OutputTail) RunResult {
+	result := RunResult{
+		Handle:          handle,
+		Command:         command,
+		Started:         started.UTC(),
+		Finished:        finished.UTC(),
+		DurationSeconds: finished.Sub(started).Seconds(),
+		Stdout:          stdout.String(),
+		Stderr:          stderr.String(),
+		Truncated:       stdout.Truncated() || stderr.Truncated(),
+	}
+
+	status := 0
+
+	switch e := err.(type) {
+	case nil:
+		result.ExitStatus = &status
+	case ProcessExitError:
+		status = e.Status
+		result.ExitStatus = &status
+	case ProcessTimeoutError:
+		result.TimedOut = true
+		result.Error = e.Error()
+	default:
+		result.Error = e.Error()
+	}
+
+	return result
+}
+
+// WriteRunResult writes the result to path as JSON.
+func WriteRunResult(path string, result RunResult) error {
+	contents, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(contents, '\n'), 0644)
+}
+
+// OutputTail keeps the last resultOutputSize bytes written to it.
+type OutputTail struct {
+	mu        sync.Mutex
+	data      []byte
+	truncated bool
+}
+
+func (t *OutputTail) Write(data []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.data = append(t.data, data...)
+	if len(t.data) > resultOutputSize {
+		t.data = append([]byte{}, t.data[len(t.data)-resultOutputSize:]...)
+		t.truncated = true
+	}
+
+	return len(data), nil
+}
+
+func (t *OutputTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return string(t.data)
+}
+
+// Truncated is whether more was written than was kept.
+func (t *OutputTail) Truncated() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.truncated
+}
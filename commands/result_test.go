@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewRunResult(t *testing.T) {
+	started := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	finished := started.Add(1500 * time.Millisecond)
+
+	tests := []struct {
+		err      error
+		status   int
+		exited   bool
+		timedOut bool
+	}{
+		{nil, 0, true, false},
+		{ProcessExitError{"make test", 2}, 2, true, false},
+		{ProcessTimeoutError{"make test", time.Second, true}, 0, false, true},
+		{errors.New("unknown handle: a"), 0, false, false},
+	}
+
+	for _, test := range tests {
+		stdout := new(OutputTail)
+		stdout.Write([]byte("ok\n"))
+
+		result := NewRunResult("a", "make test", started, finished, test.err, stdout, new(OutputTail))
+
+		if result.DurationSeconds != 1.5 || result.Stdout != "ok\n" || result.Stderr != "" || result.Truncated {
+			t.Errorf("%v: returned %#v", test.err, result)
+		}
+
+		if test.exited != (result.ExitStatus != nil) || (test.exited && *result.ExitStatus != test.status) {
+			t.Errorf("%v: returned exit status %v, want %d", test.err, result.ExitStatus, test.status)
+		}
+
+		if result.TimedOut != test.timedOut || (test.err == nil || test.exited) != (result.Error == "") {
+			t.Errorf("%v: returned timed out %t and error %q", test.err, result.TimedOut, result.Error)
+		}
+	}
+}
+
+func TestOutputTail(t *testing.T) {
+	tail := new(OutputTail)
+	tail.Write([]byte("first line\n"))
+	tail.Write([]byte(strings.Repeat("x", resultOutputSize-1) + "\n"))
+
+	if !tail.Truncated() || len(tail.String()) != resultOutputSize || !strings.HasSuffix(tail.String(), "x\n") {
+		t.Errorf("kept %d bytes, truncated %t", len(tail.String()), tail.Truncated())
+	}
+}
+
+func TestWriteRunResult(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gaol-result")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	status := 0
+	path := filepath.Join(dir, "result.json")
+	if err := WriteRunResult(path, RunResult{Handle: "a", Command: "true", ExitStatus: &status}); err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var written map[string]interface{}
+	if err := json.Unmarshal(contents, &written); err != nil {
+		t.Fatal(err)
+	}
+
+	if written["handle"] != "a" || written["exit_status"] != 0.0 {
+		t.Errorf("wrote %s", contents)
+	}
+
+	if _, found := written["error"]; found {
+		t.Errorf("wrote an error for a command which succeeded: %s", contents)
+	}
+}
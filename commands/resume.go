@@ -0,0 +1,165 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// chunkAttempts is how many times a chunk is streamed in before giving up.
+const chunkAttempts = 3
+
+// chunkRetryDelay is how long to wait before streaming a chunk in again.
+var chunkRetryDelay = time.Second
+
+// StreamInResumable writes size bytes of r to the file dst in the container
+// in chunks of chunkSize, each streamed to a staging directory beside dst
+// and retried on failure, and then joins them with a shell in the container.
+// Chunks which a previous attempt completed, as their checksums show, are not
+// streamed again, so a transfer which failed carries on from where it
+// stopped when run again.
+// The file is given the attributes once joined. Progress is written to
+// progress.
+func StreamInResumable(client garden.Client, handle string, dst string, r io.ReaderAt, size int64, chunkSize int64, attrs Attributes, progress io.Writer) error {
+	if chunkSize < 1 {
+		return fmt.Errorf("invalid chunk size %d", chunkSize)
+	}
+
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	sum, chunkSums, err := checksumChunks(r, size, chunkSize)
+	if err != nil {
+		return err
+	}
+
+	// the staging directory is told apart by the file's contents, and the
+	// size of its chunks, so that a different file does not reuse the chunks
+	// of another
+	staging := path.Join(path.Dir(dst), fmt.Sprintf(".%s.gaol-resume-%s-%d", path.Base(dst), sum[:16], chunkSize))
+
+	completed, err := completedChunks(container, staging)
+	if err != nil {
+		return err
+	}
+
+	chunks := len(chunkSums)
+	for i := 0; i < chunks; i++ {
+		offset := int64(i) * chunkSize
+		n := chunkSize
+		if offset+n > size {
+			n = size - offset
+		}
+
+		name := fmt.Sprintf("part-%08d", i)
+		if completed[name] == chunkSums[i] {
+			fmt.Fprintf(progress, "chunk %d/%d already streamed\n", i+1, chunks)
+			continue
+		}
+
+		fmt.Fprintf(progress, "chunk %d/%d (%s)\n", i+1, chunks, HumanBytes(uint64(n)))
+
+		for attempt := 1; ; attempt++ {
+			err = streamFile(container, path.Join(staging, name), io.NewSectionReader(r, offset, n), n, KeepAttributes)
+			if err == nil {
+				break
+			}
+
+			if attempt == chunkAttempts {
+				return fmt.Errorf("streaming chunk %d of %d: %s (run again to resume)", i+1, chunks, err)
+			}
+
+			time.Sleep(chunkRetryDelay)
+		}
+	}
+
+	err = runScript(container, "joining the chunks of "+dst, nil, `cd "$1" && cat part-* > "$2" && cd / && rm -rf "$1"`, staging, dst)
+	if err != nil {
+		return err
+	}
+
+	return attrs.applyIn(container, dst)
+}
+
+// checksumChunks returns the sha256 checksums of the first size bytes of r
+// and of each chunk of them.
+func checksumChunks(r io.ReaderAt, size int64, chunkSize int64) (string, []string, error) {
+	whole := sha256.New()
+	sums := []string{}
+
+	for offset := int64(0); offset < size; offset += chunkSize {
+		n := chunkSize
+		if offset+n > size {
+			n = size - offset
+		}
+
+		chunk := sha256.New()
+		if _, err := io.Copy(io.MultiWriter(whole, chunk), io.NewSectionReader(r, offset, n)); err != nil {
+			return "", nil, err
+		}
+
+		sums = append(sums, hex.EncodeToString(chunk.Sum(nil)))
+	}
+
+	return hex.EncodeToString(whole.Sum(nil)), sums, nil
+}
+
+// completedChunks returns the sha256 checksums of the chunks in the staging
+// directory, creating it if need be. Without sha256sum in the container no
+// chunk counts as completed.
+func completedChunks(container garden.Container, staging string) (map[string]string, error) {
+	var stdout bytes.Buffer
+	err := runScript(container, "listing the chunks in "+staging, &stdout, `mkdir -p "$1" && cd "$1" && for f in part-*; do [ -f "$f" ] && sha256sum "$f"; done 2>/dev/null; true`, staging)
+	if err != nil {
+		return nil, err
+	}
+
+	completed := map[string]string{}
+
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+
+		completed[fields[1]] = fields[0]
+	}
+
+	return completed, nil
+}
+
+// runScript runs a shell script with the arguments in the container, writing
+// its output to stdout. If it exits unsuccessfully, what it was doing fails
+// with what it printed to stderr.
+func runScript(container garden.Container, what string, stdout io.Writer, script string, args ...string) error {
+	var stderr bytes.Buffer
+	process, err := container.Run(garden.ProcessSpec{
+		Path: "/bin/sh",
+		Args: append([]string{"-c", script, "sh"}, args...),
+	}, garden.ProcessIO{Stdout: stdout, Stderr: &stderr})
+	if err != nil {
+		return err
+	}
+
+	status, err := process.Wait()
+	if err != nil {
+		return err
+	}
+
+	if status != 0 {
+		return fmt.Errorf("%s failed: %s", what, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestStreamInResumable(t *testing.T) {
+	defer func(delay time.Duration) { chunkRetryDelay = delay }(chunkRetryDelay)
+	chunkRetryDelay = 0
+
+	container := fakeContainer("web")
+
+	// the second chunk fails the first time it is streamed
+	streamed := []string{}
+	container.StreamInStub = func(dst string, r io.Reader) error {
+		tr := tar.NewReader(r)
+		header, err := tr.Next()
+		if err != nil {
+			return err
+		}
+
+		contents, _ := ioutil.ReadAll(tr)
+		streamed = append(streamed, dst+"/"+header.Name+"="+string(contents))
+
+		if header.Name == "part-00000001" && len(streamed) == 1 {
+			return errors.New("connection reset by peer")
+		}
+
+		return nil
+	}
+
+	// an earlier attempt streamed all of the first chunk and some of the
+	// second, and left a chunk of another file of the same size
+	var joined garden.ProcessSpec
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		if strings.Contains(spec.Args[1], "sha256sum") {
+			io.WriteString(processIO.Stdout, sha256Line("part-00000000", "0123"))
+			io.WriteString(processIO.Stdout, sha256Line("part-00000001", "45"))
+			io.WriteString(processIO.Stdout, sha256Line("part-00000002", "98"))
+		} else {
+			joined = spec
+		}
+
+		return new(fakes.FakeProcess), nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	err := StreamInResumable(fakeClient, "web", "/data/rootfs.tar", strings.NewReader("0123456789"), 10, 4, KeepAttributes, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256([]byte("0123456789"))
+	staging := "/data/.rootfs.tar.gaol-resume-" + hex.EncodeToString(sum[:8]) + "-4"
+
+	want := []string{
+		staging + "/part-00000001=4567",
+		staging + "/part-00000001=4567",
+		staging + "/part-00000002=89",
+	}
+
+	if !reflect.DeepEqual(streamed, want) {
+		t.Errorf("streamed %q, want %q", streamed, want)
+	}
+
+	if args := joined.Args[len(joined.Args)-2:]; !reflect.DeepEqual(args, []string{staging, "/data/rootfs.tar"}) {
+		t.Errorf("joined the chunks with %q", joined.Args)
+	}
+}
+
+// sha256Line is a line of sha256sum's output for a file with the contents.
+func sha256Line(name string, contents string) string {
+	return fmt.Sprintf("%x  %s\n", sha256.Sum256([]byte(contents)), name)
+}
+
+func TestStreamInResumableGivesUp(t *testing.T) {
+	defer func(delay time.Duration) { chunkRetryDelay = delay }(chunkRetryDelay)
+	chunkRetryDelay = 0
+
+	container := fakeContainer("web")
+	container.StreamInReturns(errors.New("connection reset by peer"))
+	container.RunReturns(new(fakes.FakeProcess), nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	err := StreamInResumable(fakeClient, "web", "/data/rootfs.tar", strings.NewReader("0123456789"), 10, 4, KeepAttributes, ioutil.Discard)
+	if err == nil || !strings.Contains(err.Error(), "run again to resume") {
+		t.Errorf("failed with %v", err)
+	}
+
+	if container.StreamInCallCount() != chunkAttempts {
+		t.Errorf("streamed %d times, want %d attempts at the first chunk", container.StreamInCallCount(), chunkAttempts)
+	}
+}
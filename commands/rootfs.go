@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// rootfsMount is where the host's rootfs directory is mounted in the
+// container listing it.
+const rootfsMount = "/tmp/gaol-rootfses"
+
+// ListRootFSes returns the paths of the rootfses in the directory dir on the
+// host, ready to be given to create --rootfs. The server's containers cannot
+// see the host, so a privileged container, created from the rootfs
+// utilityRootFS (or the server's default if it is empty), is given the
+// directory as a read-only bind mount to list it, and destroyed afterwards.
+func ListRootFSes(client garden.Client, dir string, utilityRootFS string) ([]string, error) {
+	if !path.IsAbs(dir) {
+		return nil, fmt.Errorf("rootfs directory %s is not an absolute path", dir)
+	}
+
+	container, err := client.Create(garden.ContainerSpec{
+		RootFSPath: utilityRootFS,
+		Privileged: true,
+		BindMounts: []garden.BindMount{{
+			SrcPath: dir,
+			DstPath: rootfsMount,
+			Mode:    garden.BindMountModeRO,
+			Origin:  garden.BindMountOriginHost,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer client.Destroy(container.Handle())
+
+	var stdout bytes.Buffer
+	if err := runScript(container, "listing "+dir, &stdout, `ls -1 "$1"`, rootfsMount); err != nil {
+		return nil, err
+	}
+
+	rootfses := []string{}
+	for _, name := range strings.Split(stdout.String(), "\n") {
+		if name = strings.TrimSpace(name); name != "" && !strings.HasPrefix(name, ".") {
+			rootfses = append(rootfses, path.Join(dir, name))
+		}
+	}
+
+	sort.Strings(rootfses)
+
+	return rootfses, nil
+}
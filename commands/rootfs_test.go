@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestListRootFSes(t *testing.T) {
+	container := fakeContainer("lister")
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		io.WriteString(processIO.Stdout, "cflinuxfs2\nbusybox\n.staging\n")
+		return new(fakes.FakeProcess), nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.CreateReturns(container, nil)
+
+	rootfses, err := ListRootFSes(fakeClient, "/var/vcap/packages/rootfses", "docker:///busybox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"/var/vcap/packages/rootfses/busybox", "/var/vcap/packages/rootfses/cflinuxfs2"}
+	if !reflect.DeepEqual(rootfses, want) {
+		t.Errorf("got %v, want %v", rootfses, want)
+	}
+
+	spec := fakeClient.CreateArgsForCall(0)
+	if !spec.Privileged || spec.RootFSPath != "docker:///busybox" || len(spec.BindMounts) != 1 {
+		t.Fatalf("created %#v", spec)
+	}
+
+	if mount := spec.BindMounts[0]; mount.SrcPath != "/var/vcap/packages/rootfses" || mount.Mode != garden.BindMountModeRO {
+		t.Errorf("mounted %#v", mount)
+	}
+
+	if fakeClient.DestroyCallCount() != 1 || fakeClient.DestroyArgsForCall(0) != "lister" {
+		t.Error("did not destroy the container which listed them")
+	}
+}
+
+func TestListRootFSesRelativeDir(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+
+	if _, err := ListRootFSes(fakeClient, "rootfses", ""); err == nil || fakeClient.CreateCallCount() != 0 {
+		t.Errorf("got %v, want a relative directory refused", err)
+	}
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"path"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// Selector chooses containers by their handles and properties rather than
+// by naming them.
+type Selector struct {
+	// All selects every container; Match and Filter narrow it down.
+	All bool
+
+	// Match is a glob, as in path.Match, which handles must match.
+	Match string
+
+	// Filter holds properties which containers must have.
+	Filter garden.Properties
+}
+
+// IsZero reports whether the selector selects nothing.
+func (s Selector) IsZero() bool {
+	return !s.All && s.Match == "" && len(s.Filter) == 0
+}
+
+// SelectHandles returns the handles of the containers chosen by the
+// selector.
+func SelectHandles(client garden.Client, selector Selector) ([]string, error) {
+	containers, err := selectContainers(client, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	handles := []string{}
+	for _, container := range containers {
+		handles = append(handles, container.Handle())
+	}
+
+	return handles, nil
+}
+
+func selectContainers(client garden.Client, selector Selector) ([]garden.Container, error) {
+	if selector.Match != "" {
+		// check the pattern before going to the server
+		if _, err := path.Match(selector.Match, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	containers, err := client.Containers(selector.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := []garden.Container{}
+	for _, container := range containers {
+		if selector.Match != "" {
+			if matched, _ := path.Match(selector.Match, container.Handle()); !matched {
+				continue
+			}
+		}
+
+		selected = append(selected, container)
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestSelectHandles(t *testing.T) {
+	tests := []struct {
+		selector Selector
+		handles  []string
+	}{
+		{Selector{All: true}, []string{"ci-1", "ci-2", "web"}},
+		{Selector{Match: "ci-*"}, []string{"ci-1", "ci-2"}},
+		{Selector{Match: "ci-[2-9]"}, []string{"ci-2"}},
+		{Selector{Match: "db"}, []string{}},
+	}
+
+	for _, test := range tests {
+		fakeClient := new(fakes.FakeClient)
+		fakeClient.ContainersReturns([]garden.Container{fakeContainer("ci-1"), fakeContainer("ci-2"), fakeContainer("web")}, nil)
+
+		handles, err := SelectHandles(fakeClient, test.selector)
+		if err != nil {
+			t.Errorf("%#v: %s", test.selector, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(handles, test.handles) {
+			t.Errorf("%#v selected %v, want %v", test.selector, handles, test.handles)
+		}
+	}
+}
+
+func TestSelectHandlesFilter(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+
+	filter := garden.Properties{"team": "ci"}
+	if _, err := SelectHandles(fakeClient, Selector{Filter: filter}); err != nil {
+		t.Fatal(err)
+	}
+
+	if properties := fakeClient.ContainersArgsForCall(0); !reflect.DeepEqual(properties, filter) {
+		t.Errorf("listed containers with %v, want %v", properties, filter)
+	}
+}
+
+func TestSelectHandlesBadPattern(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+
+	if _, err := SelectHandles(fakeClient, Selector{Match: "ci-["}); err == nil {
+		t.Error("selected with an invalid pattern")
+	}
+
+	if fakeClient.ContainersCallCount() != 0 {
+		t.Error("listed containers with an invalid pattern")
+	}
+}
@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// Snapshot is the state of the containers on a server at a moment, kept to
+// be compared with a later one, such as before and after a load test.
+type Snapshot struct {
+	Target     string                       `json:"target"`
+	Taken      time.Time                    `json:"taken"`
+	Containers map[string]SnapshotContainer `json:"containers"`
+}
+
+// SnapshotContainer is the state of a container in a snapshot, with its
+// metrics by the names Metrics prints them under.
+type SnapshotContainer struct {
+	State      string             `json:"state"`
+	Properties garden.Properties  `json:"properties,omitempty"`
+	Metrics    map[string]float64 `json:"metrics"`
+}
+
+// TakeSnapshot records the state of the containers on the server. Those
+// whose info cannot be fetched, such as those destroyed in the meantime, are
+// left out.
+func TakeSnapshot(client garden.Client) (Snapshot, error) {
+	containers, err := client.Containers(nil)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	infos, _ := bulkInfo(containers)
+
+	snapshot := Snapshot{
+		Taken:      time.Now().UTC(),
+		Containers: map[string]SnapshotContainer{},
+	}
+
+	for handle, info := range infos {
+		container := SnapshotContainer{
+			State:      info.State,
+			Properties: info.Properties,
+			Metrics:    map[string]float64{},
+		}
+
+		for _, m := range usageMetrics {
+			container.Metrics[m.name] = m.get(info)
+		}
+
+		snapshot.Containers[handle] = container
+	}
+
+	return snapshot, nil
+}
+
+// DiffSnapshots writes what changed between the snapshots, one
+// tab-separated line per change in order of handle: "created" or
+// "destroyed" and the handle, or "changed", the handle, the state or metric,
+// its value before and after and, for metrics, the difference.
+func DiffSnapshots(before Snapshot, after Snapshot, w io.Writer) {
+	handles := []string{}
+	for handle := range before.Containers {
+		handles = append(handles, handle)
+	}
+
+	for handle := range after.Containers {
+		if _, found := before.Containers[handle]; !found {
+			handles = append(handles, handle)
+		}
+	}
+
+	sort.Strings(handles)
+
+	for _, handle := range handles {
+		was, existed := before.Containers[handle]
+		is, exists := after.Containers[handle]
+
+		switch {
+		case !existed:
+			fmt.Fprintf(w, "created\t%s\n", handle)
+			continue
+		case !exists:
+			fmt.Fprintf(w, "destroyed\t%s\n", handle)
+			continue
+		}
+
+		if was.State != is.State {
+			fmt.Fprintf(w, "changed\t%s\tstate\t%s\t%s\n", handle, was.State, is.State)
+		}
+
+		for _, m := range usageMetrics {
+			from, to := was.Metrics[m.name], is.Metrics[m.name]
+			if from == to {
+				continue
+			}
+
+			sign := "+"
+			delta := to - from
+			if delta < 0 {
+				sign = "-"
+				delta = -delta
+			}
+
+			fmt.Fprintf(w, "changed\t%s\t%s\t%s\t%s\t%s%s\n", handle, m.name, m.format(from), m.format(to), sign, m.format(delta))
+		}
+	}
+}
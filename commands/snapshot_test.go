@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestTakeSnapshot(t *testing.T) {
+	web := fakeContainer("web")
+	web.InfoReturns(garden.ContainerInfo{
+		State:      "active",
+		Properties: garden.Properties{"app": "web"},
+		MemoryStat: garden.ContainerMemoryStat{TotalRss: 1024},
+		ProcessIDs: []uint32{1, 2},
+	}, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersReturns([]garden.Container{web}, nil)
+
+	snapshot, err := TakeSnapshot(fakeClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	container := snapshot.Containers["web"]
+	if container.State != "active" || container.Properties["app"] != "web" || container.Metrics["memory"] != 1024 || container.Metrics["processes"] != 2 {
+		t.Errorf("took %#v", container)
+	}
+
+	if snapshot.Taken.IsZero() {
+		t.Error("did not say when it was taken")
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	metrics := func(memory float64, cpu float64) map[string]float64 {
+		return map[string]float64{"memory": memory, "cpu": cpu}
+	}
+
+	before := Snapshot{Containers: map[string]SnapshotContainer{
+		"db":  {State: "active", Metrics: metrics(512<<20, 60)},
+		"old": {State: "active", Metrics: metrics(1<<20, 1)},
+		"web": {State: "active", Metrics: metrics(100<<20, 10)},
+	}}
+
+	after := Snapshot{Containers: map[string]SnapshotContainer{
+		"db":  {State: "active", Metrics: metrics(512<<20, 60)},
+		"new": {State: "active", Metrics: metrics(1<<20, 1)},
+		"web": {State: "stopped", Metrics: metrics(50<<20, 25.5)},
+	}}
+
+	var out bytes.Buffer
+	DiffSnapshots(before, after, &out)
+
+	want := "created\tnew\n" +
+		"destroyed\told\n" +
+		"changed\tweb\tstate\tactive\tstopped\n" +
+		"changed\tweb\tmemory\t100.0 MiB\t50.0 MiB\t-50.0 MiB\n" +
+		"changed\tweb\tcpu\t10.0s\t25.5s\t+15.5s\n"
+	if out.String() != want {
+		t.Errorf("printed %q, want %q", out.String(), want)
+	}
+}
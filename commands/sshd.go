@@ -0,0 +1,312 @@
+package commands
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// sshBridge accepts SSH connections and runs each session in the container
+// named by the SSH user.
+type sshBridge struct {
+	client     garden.Client
+	config     *ssh.ServerConfig
+	sftpServer string
+	log        io.Writer
+}
+
+// SSHDOptions configure the SSH server.
+type SSHDOptions struct {
+	Listen         string
+	HostKey        string
+	AuthorizedKeys string
+	NoAuth         bool
+	SFTPServer     string
+}
+
+// SSHD serves SSH connections until the listener fails, logging what it
+// does to w.
+func SSHD(client garden.Client, opts SSHDOptions, w io.Writer) error {
+	config := &ssh.ServerConfig{}
+
+	if opts.NoAuth {
+		if !isLoopbackAddress(opts.Listen) {
+			return fmt.Errorf("refusing to allow connections without authenticating on %s: listen on a loopback address such as 127.0.0.1:2222", opts.Listen)
+		}
+
+		config.NoClientAuth = true
+	} else {
+		authorized, err := loadAuthorizedKeys(opts.AuthorizedKeys)
+		if err != nil {
+			return err
+		}
+
+		config.PublicKeyCallback = func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if authorized[string(key.Marshal())] {
+				return nil, nil
+			}
+
+			return nil, fmt.Errorf("unknown public key for %s", meta.User())
+		}
+	}
+
+	hostKey, err := loadHostKey(opts.HostKey)
+	if err != nil {
+		return err
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", opts.Listen)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "listening on %s with host key %s\n", listener.Addr(), ssh.FingerprintSHA256(hostKey.PublicKey()))
+
+	bridge := &sshBridge{
+		client:     client,
+		config:     config,
+		sftpServer: opts.SFTPServer,
+		log:        w,
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go bridge.serve(conn)
+	}
+}
+
+// isLoopbackAddress reports whether the address only listens for
+// connections from this machine.
+func isLoopbackAddress(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+
+	return host == "localhost" || isLoopback(host)
+}
+
+func loadAuthorizedKeys(path string) (map[string]bool, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	authorized := map[string]bool{}
+	for len(contents) > 0 {
+		key, _, _, rest, err := ssh.ParseAuthorizedKey(contents)
+		if err != nil {
+			break
+		}
+
+		authorized[string(key.Marshal())] = true
+		contents = rest
+	}
+
+	if len(authorized) == 0 {
+		return nil, fmt.Errorf("no keys found in %s", path)
+	}
+
+	return authorized, nil
+}
+
+// loadHostKey reads the host key from path or, if no path is given,
+// generates a key which lasts as long as the server.
+func loadHostKey(path string) (ssh.Signer, error) {
+	if path != "" {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return ssh.ParsePrivateKey(contents)
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return ssh.NewSignerFromKey(key)
+}
+
+func (b *sshBridge) serve(netConn net.Conn) {
+	conn, channels, requests, err := ssh.NewServerConn(netConn, b.config)
+	if err != nil {
+		netConn.Close()
+		return
+	}
+	defer conn.Close()
+
+	go ssh.DiscardRequests(requests)
+
+	container, err := b.client.Lookup(conn.User())
+	if err != nil {
+		fmt.Fprintln(b.log, "rejecting connection:", err)
+		return
+	}
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+
+		session := &sshSession{
+			container:  container,
+			channel:    channel,
+			sftpServer: b.sftpServer,
+		}
+
+		go session.handle(requests)
+	}
+}
+
+// sshSession bridges a single SSH session channel to a process in the
+// container.
+type sshSession struct {
+	container  garden.Container
+	channel    ssh.Channel
+	sftpServer string
+
+	mu      sync.Mutex
+	env     []string
+	tty     *garden.TTYSpec
+	process garden.Process
+}
+
+func (s *sshSession) handle(requests <-chan *ssh.Request) {
+	for req := range requests {
+		ok := false
+
+		switch req.Type {
+		case "env":
+			var kv struct{ Name, Value string }
+			if ssh.Unmarshal(req.Payload, &kv) == nil {
+				s.env = append(s.env, kv.Name+"="+kv.Value)
+				ok = true
+			}
+		case "pty-req":
+			var pty struct {
+				Term          string
+				Columns, Rows uint32
+				Width, Height uint32
+				Modes         string
+			}
+			if ssh.Unmarshal(req.Payload, &pty) == nil {
+				s.env = append(s.env, "TERM="+pty.Term)
+				s.tty = &garden.TTYSpec{
+					WindowSize: &garden.WindowSize{
+						Columns: int(pty.Columns),
+						Rows:    int(pty.Rows),
+					},
+				}
+				ok = true
+			}
+		case "window-change":
+			var size struct{ Columns, Rows, Width, Height uint32 }
+			if ssh.Unmarshal(req.Payload, &size) == nil {
+				s.resize(int(size.Columns), int(size.Rows))
+				ok = true
+			}
+		case "shell":
+			ok = s.start("/bin/sh", []string{"-l"}) == nil
+		case "exec":
+			var command struct{ Command string }
+			if ssh.Unmarshal(req.Payload, &command) == nil {
+				ok = s.start("/bin/sh", []string{"-c", command.Command}) == nil
+			}
+		case "subsystem":
+			var subsystem struct{ Name string }
+			if ssh.Unmarshal(req.Payload, &subsystem) == nil && subsystem.Name == "sftp" {
+				ok = s.start(s.sftpServer, nil) == nil
+			}
+		}
+
+		if req.WantReply {
+			req.Reply(ok, nil)
+		}
+	}
+}
+
+func (s *sshSession) resize(columns, rows int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	size := &garden.WindowSize{Columns: columns, Rows: rows}
+
+	if s.tty != nil {
+		s.tty.WindowSize = size
+	}
+
+	if s.process != nil {
+		s.process.SetTTY(garden.TTYSpec{WindowSize: size})
+	}
+}
+
+func (s *sshSession) start(path string, args []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.process != nil {
+		return errors.New("session already has a process")
+	}
+
+	processIO := garden.ProcessIO{
+		Stdin:  s.channel,
+		Stdout: s.channel,
+		Stderr: s.channel.Stderr(),
+	}
+
+	if s.tty != nil {
+		processIO.Stderr = s.channel
+	}
+
+	process, err := s.container.Run(garden.ProcessSpec{
+		Path: path,
+		Args: args,
+		Env:  s.env,
+		TTY:  s.tty,
+	}, processIO)
+	if err != nil {
+		fmt.Fprintln(s.channel.Stderr(), "failed:", err)
+		return err
+	}
+
+	s.process = process
+
+	go func() {
+		status, err := process.Wait()
+		if err != nil {
+			status = 255
+		}
+
+		payload := make([]byte, 4)
+		binary.BigEndian.PutUint32(payload, uint32(status))
+
+		s.channel.SendRequest("exit-status", false, payload)
+		s.channel.Close()
+	}()
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestSSHDRefusesNoAuthOffLoopback(t *testing.T) {
+	for _, listen := range []string{":2222", "0.0.0.0:2222", "10.0.0.1:2222", "[::]:2222"} {
+		err := SSHD(new(fakes.FakeClient), SSHDOptions{Listen: listen, NoAuth: true}, ioutil.Discard)
+		if err == nil || !strings.Contains(err.Error(), "loopback") {
+			t.Errorf("listening on %s without authenticating: got %v", listen, err)
+		}
+	}
+}
+
+func TestIsLoopbackAddress(t *testing.T) {
+	for addr, loopback := range map[string]bool{
+		"127.0.0.1:2222": true,
+		"[::1]:2222":     true,
+		"localhost:2222": true,
+		":2222":          false,
+		"0.0.0.0:2222":   false,
+		"bastion:2222":   false,
+		"2222":           false,
+	} {
+		if isLoopbackAddress(addr) != loopback {
+			t.Errorf("%s: expected loopback %v", addr, loopback)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// maxStatsdPacket keeps each packet within a typical MTU so that it is not
+// fragmented.
+const maxStatsdPacket = 1432
+
+// PushMetrics periodically sends the usage of every container to a statsd
+// server as gauges. Properties are sent as tags in the DogStatsD format.
+// Failures to list the containers are reported to log and retried on the
+// next interval.
+func PushMetrics(client garden.Client, address string, interval time.Duration, prefix string, filter garden.Properties, tagProperties []string, log io.Writer) error {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for {
+		containers, err := client.Containers(filter)
+		if err != nil {
+			fmt.Fprintln(log, "failed to list containers:", err)
+			time.Sleep(interval)
+			continue
+		}
+
+		infos, _ := bulkInfo(containers)
+
+		var packet bytes.Buffer
+		for handle, info := range infos {
+			tags := statsdTags(handle, info, tagProperties)
+
+			for _, m := range containerMetrics {
+				value := strconv.FormatFloat(m.get(info), 'f', -1, 64)
+				line := fmt.Sprintf("%scontainer.%s:%s|g|#%s\n", prefix, m.statsd, value, tags)
+
+				if packet.Len()+len(line) > maxStatsdPacket && packet.Len() > 0 {
+					conn.Write(packet.Bytes())
+					packet.Reset()
+				}
+
+				packet.WriteString(line)
+			}
+		}
+
+		if packet.Len() > 0 {
+			conn.Write(packet.Bytes())
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+func statsdTags(handle string, info garden.ContainerInfo, tagProperties []string) string {
+	tags := []string{"handle:" + statsdEscape(handle)}
+
+	for _, property := range tagProperties {
+		value, found := info.Properties[property]
+		if !found {
+			continue
+		}
+
+		tags = append(tags, statsdEscape(property)+":"+statsdEscape(value))
+	}
+
+	return strings.Join(tags, ",")
+}
+
+// statsdEscape replaces the characters which separate parts of a statsd
+// line.
+func statsdEscape(s string) string {
+	return strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_").Replace(s)
+}
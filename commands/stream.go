@@ -0,0 +1,339 @@
+package commands
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// streamChunkSize bounds the memory used to stream in input whose size is not
+// known up front.
+const streamChunkSize = 8 << 20
+
+// StreamIn writes the contents of r to the file dst in the container. If
+// size is negative it is not known: input which fits in a single chunk is
+// written as it is, and longer input is written in chunks which a shell in
+// the container joins together. The file is given the attributes.
+func StreamIn(client garden.Client, handle string, dst string, r io.Reader, size int64, attrs Attributes) error {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	if size >= 0 {
+		return streamFile(container, dst, r, size, attrs)
+	}
+
+	chunk := make([]byte, streamChunkSize)
+	n, err := io.ReadFull(r, chunk)
+	switch err {
+	case io.EOF, io.ErrUnexpectedEOF:
+		return streamFile(container, dst, bytes.NewReader(chunk[:n]), int64(n), attrs)
+	case nil:
+		err := streamChunks(container, dst, io.MultiReader(bytes.NewReader(chunk), r), chunk)
+		if err != nil {
+			return err
+		}
+
+		return attrs.applyIn(container, dst)
+	}
+
+	return err
+}
+
+// StreamInArchive extracts the tar archive r, which may be gzipped, into the
+// directory dst in the container. The archive is streamed as it is, rather
+// than wrapped in another, so it is only ever decompressed, never compressed
+// again; only if the attributes change anything are its entries rewritten.
+func StreamInArchive(client garden.Client, handle string, dst string, r io.Reader, attrs Attributes) error {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	archive, err := openArchive(r)
+	if err != nil {
+		return err
+	}
+
+	if attrs.IsZero() {
+		return container.StreamIn(dst, archive)
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(rewriteArchive(writer, archive, attrs))
+	}()
+
+	return container.StreamIn(dst, reader)
+}
+
+// rewriteArchive copies the tar archive r to w, giving its entries the
+// attributes.
+func rewriteArchive(w io.Writer, r io.Reader, attrs Attributes) error {
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return tw.Close()
+		}
+
+		if err != nil {
+			return err
+		}
+
+		attrs.apply(header)
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// openArchive checks that r holds a tar archive, gunzipping it if needed, so
+// that anything else is refused before it reaches the container.
+func openArchive(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReaderSize(r, 512)
+
+	if magic, _ := buffered.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gunzipped, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+
+		return openArchive(gunzipped)
+	}
+
+	block, _ := buffered.Peek(512)
+	if _, err := tar.NewReader(bytes.NewReader(block)).Next(); err != nil && err != io.EOF {
+		return nil, errors.New("input is not a tar archive")
+	}
+
+	return buffered, nil
+}
+
+// streamFile writes size bytes of r to dst, tarring them as they are read
+// with the attributes.
+func streamFile(container garden.Container, dst string, r io.Reader, size int64, attrs Attributes) error {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(tarFile(writer, path.Base(dst), r, size, attrs))
+	}()
+
+	return container.StreamIn(path.Dir(dst), reader)
+}
+
+func tarFile(w io.Writer, name string, r io.Reader, size int64, attrs Attributes) error {
+	tw := tar.NewWriter(w)
+
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+	}
+	attrs.apply(header)
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	if n, err := io.CopyN(tw, r, size); err == io.EOF {
+		return fmt.Errorf("input ended after %d of %d bytes", n, size)
+	} else if err != nil {
+		return err
+	}
+
+	if n, _ := r.Read(make([]byte, 1)); n > 0 {
+		return fmt.Errorf("input is longer than %d bytes", size)
+	}
+
+	return tw.Close()
+}
+
+// transferID tells apart the parts of one transfer from those another left
+// behind; tests replace it.
+var transferID = func() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// streamChunks writes r to dst as numbered parts, each the size of buf at
+// most, and then joins them together with a shell in the container.
+func streamChunks(container garden.Container, dst string, r io.Reader, buf []byte) error {
+	prefix := "." + path.Base(dst) + ".gaol-part-" + transferID() + "-"
+
+	reader, writer := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(writer)
+
+		for i := 0; ; i++ {
+			n, err := io.ReadFull(r, buf)
+			if n > 0 {
+				err := tw.WriteHeader(&tar.Header{
+					Name:    fmt.Sprintf("%s%08d", prefix, i),
+					Mode:    0600,
+					Size:    int64(n),
+					ModTime: time.Now(),
+				})
+				if err != nil {
+					writer.CloseWithError(err)
+					return
+				}
+
+				if _, err := tw.Write(buf[:n]); err != nil {
+					writer.CloseWithError(err)
+					return
+				}
+			}
+
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+		}
+
+		writer.CloseWithError(tw.Close())
+	}()
+
+	if err := container.StreamIn(path.Dir(dst), reader); err != nil {
+		reader.CloseWithError(err)
+
+		// the parts which made it are no use to anything else
+		runScript(container, "removing the parts of "+dst, nil, `cd "$1" && rm -f "$2"*`, path.Dir(dst), prefix)
+		return err
+	}
+
+	return runScript(container, "joining the parts of "+dst, nil, `cd "$1" && cat "$2"* > "$3" && rm -f "$2"*`, path.Dir(dst), prefix, path.Base(dst))
+}
+
+// StreamOut writes the contents of the file src in the container to w. It
+// refuses anything other than a regular file, of which it could write
+// nothing useful; StreamOutTo streams out the rest.
+func StreamOut(client garden.Client, handle string, src string, w io.Writer) error {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	_, contents, closer, err := openFile(container, src)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	_, err = io.Copy(w, contents)
+	return err
+}
+
+// StreamOutTo extracts src, a file or directory in the container, into the
+// local directory dir, where it keeps its name. If preserve is set, what is
+// extracted keeps its mode and, when run as root, its owner. Device nodes
+// which are not created are reported to warnings.
+func StreamOutTo(client garden.Client, handle string, src string, dir string, preserve bool, warnings io.Writer) error {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	output, err := container.StreamOut(src)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	return extractTar(output, dir, preserve, warnings)
+}
+
+// Cat writes the contents of the file src in the container to w, as
+// StreamOut does.
+func Cat(client garden.Client, handle string, src string, w io.Writer) error {
+	return StreamOut(client, handle, src, w)
+}
+
+// openFile streams the regular file src out of the container, returning its
+// header and contents, which are read until the closer is closed.
+func openFile(container garden.Container, src string) (*tar.Header, io.Reader, io.Closer, error) {
+	output, err := container.StreamOut(src)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	tr := tar.NewReader(output)
+
+	header, err := tr.Next()
+	if err == io.EOF {
+		err = fmt.Errorf("%s: no such file", src)
+	}
+
+	if err == nil {
+		switch header.Typeflag {
+		case tar.TypeReg:
+			return header, tr, output, nil
+		case tar.TypeDir:
+			err = fmt.Errorf("%s is a directory", src)
+		case tar.TypeSymlink:
+			err = fmt.Errorf("%s is a symlink to %s", src, header.Linkname)
+		default:
+			err = fmt.Errorf("%s is not a regular file", src)
+		}
+	}
+
+	output.Close()
+	return nil, nil, nil, err
+}
+
+// Tail runs tail in the container to write the last lines of the file to
+// processIO and, when following, whatever is written to it afterwards, even
+// if it is replaced as logs are rotated. The tail is stopped when gaol
+// exits.
+func Tail(client garden.Client, handle string, file string, lines int, follow bool, processIO garden.ProcessIO) error {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"-n", strconv.Itoa(lines)}
+	if follow {
+		args = append(args, "-F")
+	}
+
+	process, err := container.Run(garden.ProcessSpec{
+		Path: "tail",
+		Args: append(args, file),
+	}, processIO)
+	if err != nil {
+		return fmt.Errorf("running tail: %s", err)
+	}
+
+	defer AtExit(func() { process.Signal(garden.SignalTerminate) })()
+
+	return waitForExit(process, "tail "+file)
+}
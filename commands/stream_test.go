@@ -0,0 +1,296 @@
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+// recordEntries makes the container remember the names and sizes of the
+// entries last streamed into it.
+func recordEntries(container *fakes.FakeContainer) map[string]int64 {
+	entries := map[string]int64{}
+
+	container.StreamInStub = func(dst string, r io.Reader) error {
+		for name := range entries {
+			delete(entries, name)
+		}
+
+		tr := tar.NewReader(r)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			entries[header.Name], err = io.Copy(ioutil.Discard, tr)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return entries
+}
+
+func streamInClient(container *fakes.FakeContainer) *fakes.FakeClient {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+	return fakeClient
+}
+
+func TestStreamIn(t *testing.T) {
+	container := fakeContainer("web")
+	entries := recordEntries(container)
+
+	for _, size := range []int64{5, -1} {
+		err := StreamIn(streamInClient(container), "web", "/etc/motd", strings.NewReader("hello"), size, KeepAttributes)
+		if err != nil {
+			t.Fatalf("size %d: %s", size, err)
+		}
+
+		if entries["motd"] != 5 || len(entries) != 1 {
+			t.Errorf("size %d: streamed %v", size, entries)
+		}
+	}
+
+	if dir, _ := container.StreamInArgsForCall(0); dir != "/etc" {
+		t.Errorf("streamed into %q", dir)
+	}
+
+	if container.RunCallCount() != 0 {
+		t.Error("expected input in one chunk not to need joining")
+	}
+}
+
+func TestStreamInWrongSize(t *testing.T) {
+	for _, size := range []int64{3, 10} {
+		container := fakeContainer("web")
+		recordEntries(container)
+
+		if err := StreamIn(streamInClient(container), "web", "/etc/motd", strings.NewReader("hello"), size, KeepAttributes); err == nil {
+			t.Errorf("expected 5 bytes of input to be refused as %d", size)
+		}
+	}
+}
+
+func TestStreamInChunks(t *testing.T) {
+	defer func(original func() string) { transferID = original }(transferID)
+	transferID = func() string { return "1234" }
+
+	container := fakeContainer("web")
+	entries := recordEntries(container)
+
+	var joined garden.ProcessSpec
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		joined = spec
+		return new(fakes.FakeProcess), nil
+	}
+
+	input := bytes.NewReader(make([]byte, 2*streamChunkSize+1))
+	if err := StreamIn(streamInClient(container), "web", "/data/rootfs.tar", input, -1, KeepAttributes); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int64{
+		".rootfs.tar.gaol-part-1234-00000000": streamChunkSize,
+		".rootfs.tar.gaol-part-1234-00000001": streamChunkSize,
+		".rootfs.tar.gaol-part-1234-00000002": 1,
+	}
+
+	for name, size := range want {
+		if entries[name] != size {
+			t.Errorf("streamed %v, want %v", entries, want)
+			break
+		}
+	}
+
+	args := joined.Args[len(joined.Args)-3:]
+	if strings.Join(args, " ") != "/data .rootfs.tar.gaol-part-1234- rootfs.tar" {
+		t.Errorf("joined the parts with %q", joined.Args)
+	}
+}
+
+func TestStreamInChunksRemovesPartsOfFailedTransfers(t *testing.T) {
+	container := fakeContainer("web")
+	container.StreamInReturns(errors.New("connection reset by peer"))
+
+	scripts := []string{}
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		scripts = append(scripts, spec.Args[1])
+		return new(fakes.FakeProcess), nil
+	}
+
+	input := bytes.NewReader(make([]byte, 2*streamChunkSize+1))
+	if err := StreamIn(streamInClient(container), "web", "/data/rootfs.tar", input, -1, KeepAttributes); err == nil {
+		t.Fatal("streamed in over a broken connection")
+	}
+
+	if len(scripts) != 1 || !strings.Contains(scripts[0], "rm -f") {
+		t.Errorf("ran %q, want the parts removed and not joined", scripts)
+	}
+}
+
+func TestStreamInArchive(t *testing.T) {
+	archive, _ := ioutil.ReadAll(artifactTar(map[string]string{"app/run.sh": "#!/bin/sh", "app/VERSION": "1.2"}))
+
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	gw.Write(archive)
+	gw.Close()
+
+	for name, input := range map[string][]byte{"tar": archive, "tgz": gzipped.Bytes()} {
+		container := fakeContainer("web")
+		entries := recordEntries(container)
+
+		if err := StreamInArchive(streamInClient(container), "web", "/srv", bytes.NewReader(input), KeepAttributes); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		if entries["app/run.sh"] != 9 || entries["app/VERSION"] != 3 || len(entries) != 2 {
+			t.Errorf("%s: streamed %v, want the archive's own entries", name, entries)
+		}
+
+		if dir, _ := container.StreamInArgsForCall(0); dir != "/srv" {
+			t.Errorf("%s: streamed into %q", name, dir)
+		}
+	}
+}
+
+func TestStreamInArchiveNotATar(t *testing.T) {
+	container := fakeContainer("web")
+
+	err := StreamInArchive(streamInClient(container), "web", "/srv", strings.NewReader("just some text"), KeepAttributes)
+	if err == nil || err.Error() != "input is not a tar archive" {
+		t.Errorf("got %v", err)
+	}
+
+	if container.StreamInCallCount() != 0 {
+		t.Error("streamed something which is not an archive")
+	}
+}
+
+func TestStreamOutToPreserve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gaol-stream-out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	container := fakeContainer("web")
+	container.StreamOutStub = func(src string) (io.ReadCloser, error) {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		tw.WriteHeader(&tar.Header{Name: "app/", Mode: 0555, Uid: 1000, Gid: 1000, Typeflag: tar.TypeDir})
+		tw.WriteHeader(&tar.Header{Name: "app/run.sh", Mode: 04750, Uid: 1000, Gid: 1000, Size: 9, Typeflag: tar.TypeReg})
+		tw.Write([]byte("#!/bin/sh"))
+		tw.Close()
+		return ioutil.NopCloser(&buf), nil
+	}
+
+	if err := StreamOutTo(streamInClient(container), "web", "/srv/app", dir, true, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(filepath.Join(dir, "app"), 0755)
+
+	for name, want := range map[string]os.FileMode{
+		"app":        os.ModeDir | 0555,
+		"app/run.sh": os.ModeSetuid | 0750,
+	} {
+		info, err := os.Lstat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if info.Mode() != want {
+			t.Errorf("%s: got mode %s, want %s", name, info.Mode(), want)
+		}
+	}
+}
+
+func TestCat(t *testing.T) {
+	container := fakeContainer("web")
+	container.StreamOutStub = func(src string) (io.ReadCloser, error) {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+
+		switch src {
+		case "/etc/hosts":
+			tw.WriteHeader(&tar.Header{Name: "hosts", Mode: 0644, Size: 9, Typeflag: tar.TypeReg})
+			tw.Write([]byte("127.0.0.1"))
+		case "/etc":
+			tw.WriteHeader(&tar.Header{Name: "etc", Mode: 0755, Typeflag: tar.TypeDir})
+		case "/etc/localtime":
+			tw.WriteHeader(&tar.Header{Name: "localtime", Typeflag: tar.TypeSymlink, Linkname: "/usr/share/zoneinfo/UTC"})
+		}
+
+		tw.Close()
+		return ioutil.NopCloser(&buf), nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	var out bytes.Buffer
+	if err := Cat(fakeClient, "web", "/etc/hosts", &out); err != nil || out.String() != "127.0.0.1" {
+		t.Errorf("wrote %q (%v)", out.String(), err)
+	}
+
+	for src, want := range map[string]string{
+		"/etc":           "/etc is a directory",
+		"/etc/localtime": "/etc/localtime is a symlink to /usr/share/zoneinfo/UTC",
+		"/missing":       "/missing: no such file",
+	} {
+		if err := Cat(fakeClient, "web", src, new(bytes.Buffer)); err == nil || err.Error() != want {
+			t.Errorf("got %v for %s, want %q", err, src, want)
+		}
+	}
+}
+
+func TestTail(t *testing.T) {
+	process := new(fakes.FakeProcess)
+	process.WaitReturns(0, nil)
+
+	container := fakeContainer("web")
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		processIO.Stdout.Write([]byte("started\n"))
+		return process, nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	var out bytes.Buffer
+	if err := Tail(fakeClient, "web", "/var/log/app.log", 20, true, garden.ProcessIO{Stdout: &out}); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, _ := container.RunArgsForCall(0)
+	if spec.Path != "tail" || strings.Join(spec.Args, " ") != "-n 20 -F /var/log/app.log" {
+		t.Errorf("ran %s %v", spec.Path, spec.Args)
+	}
+
+	if out.String() != "started\n" {
+		t.Errorf("wrote %q", out.String())
+	}
+
+	// the tail is stopped if gaol exits while it runs, and not after
+	RunCleanups()
+	if process.SignalCallCount() != 0 {
+		t.Error("signalled the tail after it exited")
+	}
+}
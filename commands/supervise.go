@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/mattn/go-shellwords"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// restartsPropertyPrefix is prepended to the name of a supervised process to
+// give the property counting how many times it has been restarted.
+const restartsPropertyPrefix = "gaol:restarts:"
+
+// restartBackoff is how long to wait before the first restart; each restart
+// after it waits twice as long as the last, up to maxRestartBackoff.
+var (
+	restartBackoff    = time.Second
+	maxRestartBackoff = time.Minute
+)
+
+// Supervision says when a supervised process is run again after it exits.
+type Supervision struct {
+	// Name is what the process is recorded as in the container's
+	// properties; empty names it after the command.
+	Name string
+
+	// Restart is a restart policy as in a manifest: no, on-failure or
+	// always.
+	Restart string
+
+	// MaxRestarts is how many times the process is restarted before giving
+	// up; zero never gives up.
+	MaxRestarts int
+}
+
+// ParseRestart checks a restart policy.
+func ParseRestart(policy string) (string, error) {
+	switch policy {
+	case restartNever, restartOnFailure, restartAlways:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown restart policy: %s (must be %s, %s or %s)", policy, restartNever, restartOnFailure, restartAlways)
+	}
+}
+
+// Supervise runs command in the container and runs it again whenever it exits
+// as the supervision's restart policy says, backing off between restarts. The
+// PID of the latest run and the number of restarts are recorded in the
+// container's properties, as for manifest processes. Each restart is noted on
+// log.
+func Supervise(client garden.Client, handle string, command string, opts RunOptions, supervision Supervision, processIO garden.ProcessIO, log io.Writer) error {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	args, err := shellwords.Parse(command)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return errors.New("missing command to run")
+	}
+
+	name := supervision.ProcessName(command)
+
+	if !opts.Attach {
+		processIO = garden.ProcessIO{}
+	}
+
+	// a supervised process has no stdin, as it would only be read by the
+	// first run
+	processIO.Stdin = nil
+
+	restarts := 0
+	backoff := restartBackoff
+
+	for {
+		err := container.SetProperty(restartsPropertyPrefix+name, strconv.Itoa(restarts))
+		if err != nil {
+			return err
+		}
+
+		process, err := container.Run(garden.ProcessSpec{
+			Path:       args[0],
+			Args:       args[1:],
+			Dir:        opts.Dir,
+			Privileged: opts.Privileged,
+			User:       opts.User,
+			Env:        mergeEnv(append(append([]string{}, opts.Env...), processNameVariable+"="+name)),
+		}, processIO)
+		if err != nil {
+			return err
+		}
+
+		err = container.SetProperty(processPropertyPrefix+name, strconv.FormatUint(uint64(process.ID()), 10))
+		if err != nil {
+			return err
+		}
+
+		status, err := process.Wait()
+		if err != nil {
+			return err
+		}
+
+		if supervision.Restart == restartNever || (supervision.Restart == restartOnFailure && status == 0) {
+			if status != 0 {
+				return ProcessExitError{command, status}
+			}
+
+			return nil
+		}
+
+		if supervision.MaxRestarts > 0 && restarts >= supervision.MaxRestarts {
+			return fmt.Errorf("%s: gave up after %d restarts: %s", name, restarts, ProcessExitError{command, status})
+		}
+
+		fmt.Fprintf(log, "%s exited with status %d, restarting in %s\n", name, status, backoff)
+		time.Sleep(backoff)
+
+		restarts++
+
+		backoff *= 2
+		if backoff > maxRestartBackoff {
+			backoff = maxRestartBackoff
+		}
+	}
+}
+
+// ProcessName is the name the process running command is recorded as.
+func (s Supervision) ProcessName(command string) string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	args, err := shellwords.Parse(command)
+	if err != nil || len(args) == 0 {
+		return ""
+	}
+
+	return path.Base(args[0])
+}
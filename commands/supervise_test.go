@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+// supervisedContainer runs processes which exit with each of the statuses in
+// turn, with PIDs counting up from 1, and keeps the properties it is given.
+func supervisedContainer(statuses ...int) (*fakes.FakeContainer, map[string][]string) {
+	properties := map[string][]string{}
+
+	container := fakeContainer("web")
+	container.SetPropertyStub = func(key string, value string) error {
+		properties[key] = append(properties[key], value)
+		return nil
+	}
+
+	runs := 0
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		process := new(fakes.FakeProcess)
+		process.IDReturns(uint32(runs + 1))
+		process.WaitReturns(statuses[runs], nil)
+		runs++
+
+		return process, nil
+	}
+
+	return container, properties
+}
+
+func TestSuperviseRestartsOnFailure(t *testing.T) {
+	defer func(delay time.Duration) { restartBackoff = delay }(restartBackoff)
+	restartBackoff = 0
+
+	container, properties := supervisedContainer(1, 2, 0)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	var log bytes.Buffer
+	err := Supervise(fakeClient, "web", "/bin/server --port 8080", RunOptions{}, Supervision{Restart: "on-failure"}, garden.ProcessIO{}, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if container.RunCallCount() != 3 {
+		t.Errorf("ran the process %d times, want 3", container.RunCallCount())
+	}
+
+	if pids := properties["gaol:process:server"]; !reflect.DeepEqual(pids, []string{"1", "2", "3"}) {
+		t.Errorf("recorded pids %q", pids)
+	}
+
+	if restarts := properties["gaol:restarts:server"]; !reflect.DeepEqual(restarts, []string{"0", "1", "2"}) {
+		t.Errorf("recorded restarts %q", restarts)
+	}
+
+	if lines := strings.Split(strings.TrimSpace(log.String()), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[0], "server exited with status 1, restarting") {
+		t.Errorf("logged %q", log.String())
+	}
+}
+
+func TestSuperviseGivesUp(t *testing.T) {
+	defer func(delay time.Duration) { restartBackoff = delay }(restartBackoff)
+	restartBackoff = 0
+
+	container, properties := supervisedContainer(0, 0, 0, 0)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	supervision := Supervision{Name: "ticker", Restart: "always", MaxRestarts: 2}
+	err := Supervise(fakeClient, "web", "date", RunOptions{}, supervision, garden.ProcessIO{}, ioutil.Discard)
+	if err == nil || !strings.Contains(err.Error(), "gave up after 2 restarts") {
+		t.Errorf("got %v, want it to give up", err)
+	}
+
+	if container.RunCallCount() != 3 {
+		t.Errorf("ran the process %d times, want 3", container.RunCallCount())
+	}
+
+	if pids := properties["gaol:process:ticker"]; len(pids) != 3 {
+		t.Errorf("recorded pids %q under the process's name", pids)
+	}
+}
+
+func TestSuperviseNever(t *testing.T) {
+	container, _ := supervisedContainer(3)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	err := Supervise(fakeClient, "web", "false", RunOptions{}, Supervision{Restart: "no"}, garden.ProcessIO{}, ioutil.Discard)
+	if err != (ProcessExitError{"false", 3}) {
+		t.Errorf("got %v, want the exit status", err)
+	}
+
+	if container.RunCallCount() != 1 {
+		t.Errorf("ran the process %d times, want once", container.RunCallCount())
+	}
+}
+
+func TestParseRestart(t *testing.T) {
+	for _, policy := range []string{"no", "on-failure", "always"} {
+		if parsed, err := ParseRestart(policy); err != nil || parsed != policy {
+			t.Errorf("%s: got %q (%v)", policy, parsed, err)
+		}
+	}
+
+	if _, err := ParseRestart("sometimes"); err == nil {
+		t.Error("expected an unknown policy to be rejected")
+	}
+}
@@ -0,0 +1,175 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// taskHandlePrefix starts the handles of the containers tasks are run in.
+const taskHandlePrefix = "gaol-task-"
+
+// Task is a one-off job run in a container of its own: its inputs are staged
+// in, its command is run to completion and the artifacts it leaves are
+// collected out, and the container is destroyed. The container is made from
+// a docker image or a rootfs, or the server's default rootfs if neither is
+// given.
+type Task struct {
+	Image      string         `yaml:"image"`
+	RootFS     string         `yaml:"rootfs"`
+	Command    string         `yaml:"command"`
+	Dir        string         `yaml:"dir"`
+	User       string         `yaml:"user"`
+	Privileged bool           `yaml:"privileged"`
+	Env        []string       `yaml:"env"`
+	Timeout    time.Duration  `yaml:"timeout"`
+	Limits     ManifestLimits `yaml:"limits"`
+	Inputs     []ManifestFile `yaml:"inputs"`
+	Artifacts  []string       `yaml:"artifacts"`
+}
+
+// LoadTask reads the task at path, rendering it with the data, and
+// validates it. The sources of its inputs are relative to the task rather
+// than the working directory.
+func LoadTask(path string, data TemplateData) (*Task, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err = render(path, contents, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var task Task
+	if err := yaml.Unmarshal(contents, &task); err != nil {
+		return nil, fmt.Errorf("invalid task %s: %s", path, err)
+	}
+
+	if task.Command == "" {
+		return nil, fmt.Errorf("task %s has no command", path)
+	}
+
+	if task.Image != "" && task.RootFS != "" {
+		return nil, fmt.Errorf("task %s gives both an image and a rootfs", path)
+	}
+
+	base := filepath.Dir(path)
+
+	for i, input := range task.Inputs {
+		if input.Src == "" || input.Dst == "" {
+			return nil, fmt.Errorf("task %s: inputs need both a src and a dst", path)
+		}
+
+		if !filepath.IsAbs(input.Src) {
+			task.Inputs[i].Src = filepath.Join(base, input.Src)
+		}
+	}
+
+	for _, artifact := range task.Artifacts {
+		if !strings.HasPrefix(artifact, "/") {
+			return nil, fmt.Errorf("task %s: artifact %s is not an absolute path", path, artifact)
+		}
+	}
+
+	return &task, nil
+}
+
+// Spec is the container the task is run in.
+func (task *Task) Spec() garden.ContainerSpec {
+	return garden.ContainerSpec{
+		RootFSPath: task.RootFS,
+		Env:        task.Env,
+		Privileged: task.Privileged,
+	}
+}
+
+// TaskOptions describe how a task is run. Populate, if given, is called
+// with the container before the inputs are staged, for example to stream the
+// task's image into it. Artifacts are collected into ArtifactsDir, and
+// Collect is collected along with them. Keep leaves the container behind, to
+// look into what the task did.
+type TaskOptions struct {
+	Populate     func(garden.Container) error
+	ArtifactsDir string
+	Collect      []Collection
+	Keep         bool
+	KillAfter    time.Duration
+}
+
+// RunTask creates a container from the spec, runs the task in it attached
+// to processIO and destroys it, writing what it is doing to progress. The
+// artifacts are collected whether or not the command succeeds, as a failed
+// task's are often the most interesting; one which is missing fails the task
+// only if its command succeeded.
+func RunTask(client garden.Client, task *Task, spec garden.ContainerSpec, opts TaskOptions, processIO garden.ProcessIO, progress io.Writer) (err error) {
+	container, err := create(client, spec, CreateOptions{
+		HandlePrefix: taskHandlePrefix,
+		Limits:       task.Limits,
+		Populate: func(container garden.Container) error {
+			if opts.Populate != nil {
+				if err := opts.Populate(container); err != nil {
+					return err
+				}
+			}
+
+			for _, input := range task.Inputs {
+				if err := streamInPath(container, input.Src, input.Dst); err != nil {
+					return fmt.Errorf("input %s: %s", input.Src, err)
+				}
+			}
+
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	handle := container.Handle()
+	fmt.Fprintf(progress, "running task in %s\n", handle)
+
+	if opts.Keep {
+		defer fmt.Fprintf(progress, "kept %s\n", handle)
+	} else {
+		unregister := AtExit(func() { client.Destroy(handle) })
+		defer func() {
+			unregister()
+
+			if destroyErr := client.Destroy(handle); err == nil {
+				err = destroyErr
+			}
+		}()
+	}
+
+	runErr := Run(client, handle, task.Command, RunOptions{
+		Attach:     true,
+		Dir:        task.Dir,
+		CreateDir:  task.Dir != "",
+		User:       task.User,
+		Privileged: task.Privileged,
+		Timeout:    task.Timeout,
+		KillAfter:  opts.KillAfter,
+	}, processIO, nil)
+
+	collections := []Collection{}
+	for _, artifact := range task.Artifacts {
+		collections = append(collections, Collection{artifact, opts.ArtifactsDir})
+	}
+
+	collectErr := collect(container, append(collections, opts.Collect...), progress)
+
+	if runErr != nil {
+		return runErr
+	}
+
+	return collectErr
+}
@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestLoadTask(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gaol-task")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "task.yml")
+	ioutil.WriteFile(path, []byte(`
+image: golang:1.5
+command: make test
+dir: /src
+env: [CI=true]
+timeout: 10m
+inputs:
+  - {src: ., dst: /src}
+artifacts: [/src/coverage.out]
+`), 0644)
+
+	task, err := LoadTask(path, TemplateData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if task.Command != "make test" || task.Timeout != 10*time.Minute || task.Inputs[0].Src != dir || task.Artifacts[0] != "/src/coverage.out" {
+		t.Errorf("loaded %#v", task)
+	}
+
+	for _, invalid := range []string{
+		"dir: /src",
+		"command: ls\nimage: busybox\nrootfs: /var/rootfs",
+		"command: ls\ninputs: [{src: .}]",
+		"command: ls\nartifacts: [coverage.out]",
+	} {
+		ioutil.WriteFile(path, []byte(invalid), 0644)
+		if _, err := LoadTask(path, TemplateData{}); err == nil {
+			t.Errorf("loaded %q", invalid)
+		}
+	}
+}
+
+func TestRunTask(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gaol-task")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	originalSuffix := handleSuffix
+	handleSuffix = func() string { return "1" }
+	defer func() { handleSuffix = originalSuffix }()
+
+	input := filepath.Join(dir, "input.txt")
+	ioutil.WriteFile(input, []byte("input"), 0644)
+
+	process := new(fakes.FakeProcess)
+	process.WaitReturns(1, nil)
+
+	container := fakeContainer("gaol-task-1")
+	container.RunReturns(process, nil)
+	container.StreamOutStub = func(src string) (io.ReadCloser, error) {
+		return artifactTar(map[string]string{"report.txt": "1 failure"}), nil
+	}
+
+	created := false
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.CreateStub = func(spec garden.ContainerSpec) (garden.Container, error) {
+		created = true
+		return container, nil
+	}
+	fakeClient.LookupStub = func(handle string) (garden.Container, error) {
+		if !created {
+			return nil, garden.ContainerNotFoundError{Handle: handle}
+		}
+		return container, nil
+	}
+
+	task := &Task{
+		Command:   "make test",
+		Inputs:    []ManifestFile{{Src: input, Dst: "/src/input.txt"}},
+		Artifacts: []string{"/src/report.txt"},
+	}
+
+	artifacts := filepath.Join(dir, "artifacts")
+
+	var progress bytes.Buffer
+	err = RunTask(fakeClient, task, task.Spec(), TaskOptions{ArtifactsDir: artifacts}, garden.ProcessIO{}, &progress)
+	if err != (ProcessExitError{"make test", 1}) {
+		t.Errorf("got %v, want the command's exit status", err)
+	}
+
+	if container.StreamInCallCount() != 1 {
+		t.Errorf("staged %d inputs, want 1", container.StreamInCallCount())
+	}
+
+	// the failed task's artifacts are still collected
+	if contents, _ := ioutil.ReadFile(filepath.Join(artifacts, "report.txt")); string(contents) != "1 failure" {
+		t.Errorf("collected %q", contents)
+	}
+
+	if fakeClient.DestroyCallCount() != 1 || fakeClient.DestroyArgsForCall(0) != "gaol-task-1" {
+		t.Error("did not destroy the task's container")
+	}
+
+	if !strings.Contains(progress.String(), "running task in gaol-task-1\n") {
+		t.Errorf("printed %q", progress.String())
+	}
+}
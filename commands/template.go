@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// TemplateData is what manifests and tasks are rendered with as
+// text/templates before they are parsed, so that one file can describe many
+// environments: the environment variables as Env, the number given to
+// --index as Index and the variables given with --var as Vars, such as
+// "web-{{ .Vars.branch }}". Referring to a variable which is not set is an
+// error; {{ index .Env "FOO" }} gives an empty string instead.
+type TemplateData struct {
+	Env   map[string]string
+	Index int
+	Vars  map[string]string
+}
+
+// NewTemplateData returns the data for rendering with the index and the
+// variables, given as key=value, along with the current environment.
+func NewTemplateData(index int, vars []string) (TemplateData, error) {
+	data := TemplateData{
+		Env:   map[string]string{},
+		Index: index,
+		Vars:  map[string]string{},
+	}
+
+	for _, variable := range os.Environ() {
+		kv := strings.SplitN(variable, "=", 2)
+		if len(kv) == 2 {
+			data.Env[kv[0]] = kv[1]
+		}
+	}
+
+	for _, variable := range vars {
+		kv := strings.SplitN(variable, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return TemplateData{}, fmt.Errorf("invalid variable %q: must be key=value", variable)
+		}
+
+		data.Vars[kv[0]] = kv[1]
+	}
+
+	return data, nil
+}
+
+// render renders the contents of the file at path with the data.
+func render(path string, contents []byte, data TemplateData) ([]byte, error) {
+	tmpl, err := template.New(filepath.Base(path)).Option("missingkey=error").Parse(string(contents))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %s: %s", path, err)
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return nil, fmt.Errorf("rendering %s: %s", path, err)
+	}
+
+	return rendered.Bytes(), nil
+}
@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewTemplateData(t *testing.T) {
+	data, err := NewTemplateData(1, []string{"branch=main", "flags=a=b", "empty="})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(data.Vars, map[string]string{"branch": "main", "flags": "a=b", "empty": ""}) {
+		t.Errorf("parsed %v", data.Vars)
+	}
+
+	for _, invalid := range []string{"branch", "=main"} {
+		if _, err := NewTemplateData(0, []string{invalid}); err == nil {
+			t.Errorf("parsed %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+package commands
+
+// fionread is the ioctl asking how many bytes are waiting to be read, which
+// is missing from the syscall package.
+const fionread = 0x4004667f
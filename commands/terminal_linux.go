@@ -0,0 +1,6 @@
+package commands
+
+import "syscall"
+
+// fionread is the ioctl asking how many bytes are waiting to be read.
+const fionread = syscall.TIOCINQ
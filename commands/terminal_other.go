@@ -0,0 +1,39 @@
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+package commands
+
+import (
+	"errors"
+	"os"
+	"runtime"
+)
+
+// IsTerminal reports whether the file is a character device, which is as
+// close as can be told here to its being a terminal.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminal cannot be opened here, so shells, pickers and top are not
+// available, though everything else is.
+type terminal struct{}
+
+func openTerminal() (*terminal, error) {
+	return nil, errors.New("interactive terminals are not supported on " + runtime.GOOS)
+}
+
+func (t *terminal) SetRaw() error { return nil }
+
+func (t *terminal) Restore() error { return nil }
+
+func (t *terminal) Read(p []byte) (int, error) { return os.Stdin.Read(p) }
+
+func (t *terminal) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+
+func (t *terminal) Available() (int, error) { return 0, nil }
+
+func (t *terminal) Size() (int, int, error) { return 0, 0, errors.New("no terminal") }
+
+func (t *terminal) NotifyResize(resized chan<- struct{}) func() { return func() {} }
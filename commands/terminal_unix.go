@@ -0,0 +1,78 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package commands
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+
+	"github.com/kr/pty"
+	"github.com/pkg/term"
+	"github.com/pkg/term/termios"
+)
+
+// IsTerminal reports whether the file is a terminal.
+func IsTerminal(f *os.File) bool {
+	var attr syscall.Termios
+	return termios.Tcgetattr(f.Fd(), &attr) == nil
+}
+
+// terminal is the terminal connected to stdin.
+type terminal struct {
+	*term.Term
+}
+
+func openTerminal() (*terminal, error) {
+	t, err := term.Open(os.Stdin.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	return &terminal{t}, nil
+}
+
+// Size returns the rows and columns of the terminal.
+func (t *terminal) Size() (int, int, error) {
+	return pty.Getsize(os.Stdin)
+}
+
+// Available returns how many bytes have been typed and not yet read.
+func (t *terminal) Available() (int, error) {
+	var n int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, os.Stdin.Fd(), fionread, uintptr(unsafe.Pointer(&n)))
+	if errno != 0 {
+		return 0, errno
+	}
+
+	return int(n), nil
+}
+
+// NotifyResize sends on resized whenever the terminal changes size until
+// the returned function is called.
+func (t *terminal) NotifyResize(resized chan<- struct{}) func() {
+	signals := make(chan os.Signal, 10)
+	signal.Notify(signals, syscall.SIGWINCH)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-signals:
+				select {
+				case resized <- struct{}{}:
+				default:
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
@@ -0,0 +1,218 @@
+package commands
+
+import (
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+
+	procGetConsoleMode             = kernel32.NewProc("GetConsoleMode")
+	procSetConsoleMode             = kernel32.NewProc("SetConsoleMode")
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+	procPeekConsoleInput           = kernel32.NewProc("PeekConsoleInputW")
+	procReadConsoleInput           = kernel32.NewProc("ReadConsoleInputW")
+)
+
+// console modes, named as in the Win32 api
+const (
+	enableProcessedInput            = 0x0001
+	enableLineInput                 = 0x0002
+	enableEchoInput                 = 0x0004
+	enableVirtualTerminalInput      = 0x0200
+	enableVirtualTerminalProcessing = 0x0004
+
+	keyEvent = 0x0001
+)
+
+// resizePollInterval is how often the console is checked for a change of
+// size, which unlike a unix terminal it does not signal.
+const resizePollInterval = 250 * time.Millisecond
+
+type coord struct {
+	x, y int16
+}
+
+type consoleScreenBufferInfo struct {
+	size              coord
+	cursorPosition    coord
+	attributes        uint16
+	left, top         int16
+	right, bottom     int16
+	maximumWindowSize coord
+}
+
+// inputRecord is an INPUT_RECORD holding a KEY_EVENT_RECORD.
+type inputRecord struct {
+	eventType       uint16
+	_               uint16
+	keyDown         int32
+	repeatCount     uint16
+	virtualKeyCode  uint16
+	virtualScanCode uint16
+	unicodeChar     uint16
+	controlKeyState uint32
+}
+
+// terminal is the console connected to stdin and stdout. Raw mode turns on
+// virtual terminal sequences in both directions, so the container's
+// terminal is driven as it would be from a unix one.
+type terminal struct {
+	in, out         syscall.Handle
+	inMode, outMode uint32
+}
+
+// IsTerminal reports whether the file is a console.
+func IsTerminal(f *os.File) bool {
+	var mode uint32
+	return getConsoleMode(syscall.Handle(f.Fd()), &mode) == nil
+}
+
+func openTerminal() (*terminal, error) {
+	t := &terminal{
+		in:  syscall.Handle(os.Stdin.Fd()),
+		out: syscall.Handle(os.Stdout.Fd()),
+	}
+
+	if err := getConsoleMode(t.in, &t.inMode); err != nil {
+		return nil, err
+	}
+
+	if err := getConsoleMode(t.out, &t.outMode); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *terminal) SetRaw() error {
+	raw := t.inMode&^(enableEchoInput|enableLineInput|enableProcessedInput) | enableVirtualTerminalInput
+	if err := setConsoleMode(t.in, raw); err != nil {
+		return err
+	}
+
+	return setConsoleMode(t.out, t.outMode|enableVirtualTerminalProcessing)
+}
+
+func (t *terminal) Restore() error {
+	if err := setConsoleMode(t.in, t.inMode); err != nil {
+		return err
+	}
+
+	return setConsoleMode(t.out, t.outMode)
+}
+
+func (t *terminal) Read(p []byte) (int, error) {
+	return os.Stdin.Read(p)
+}
+
+func (t *terminal) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+// Available returns how many keys have been typed and not yet read. Other
+// input, such as keys being released, is discarded so that reading does not
+// block on it.
+func (t *terminal) Available() (int, error) {
+	var records [64]inputRecord
+
+	for {
+		var n uint32
+		r, _, err := procPeekConsoleInput.Call(uintptr(t.in), uintptr(unsafe.Pointer(&records[0])), uintptr(len(records)), uintptr(unsafe.Pointer(&n)))
+		if r == 0 {
+			return 0, err
+		}
+
+		if n == 0 {
+			return 0, nil
+		}
+
+		if !typed(records[0]) {
+			var discarded uint32
+			r, _, err := procReadConsoleInput.Call(uintptr(t.in), uintptr(unsafe.Pointer(&records[0])), 1, uintptr(unsafe.Pointer(&discarded)))
+			if r == 0 {
+				return 0, err
+			}
+
+			continue
+		}
+
+		available := 0
+		for _, record := range records[:n] {
+			if typed(record) {
+				available++
+			}
+		}
+
+		return available, nil
+	}
+}
+
+func typed(record inputRecord) bool {
+	return record.eventType == keyEvent && record.keyDown != 0 && record.unicodeChar != 0
+}
+
+// Size returns the rows and columns of the console window.
+func (t *terminal) Size() (int, int, error) {
+	var info consoleScreenBufferInfo
+	r, _, err := procGetConsoleScreenBufferInfo.Call(uintptr(t.out), uintptr(unsafe.Pointer(&info)))
+	if r == 0 {
+		return 0, 0, err
+	}
+
+	return int(info.bottom-info.top) + 1, int(info.right-info.left) + 1, nil
+}
+
+// NotifyResize sends on resized whenever the console window changes size
+// until the returned function is called.
+func (t *terminal) NotifyResize(resized chan<- struct{}) func() {
+	rows, cols, _ := t.Size()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(resizePollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r, c, err := t.Size()
+				if err != nil || (r == rows && c == cols) {
+					continue
+				}
+
+				rows, cols = r, c
+
+				select {
+				case resized <- struct{}{}:
+				default:
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+func getConsoleMode(handle syscall.Handle, mode *uint32) error {
+	r, _, err := procGetConsoleMode.Call(uintptr(handle), uintptr(unsafe.Pointer(mode)))
+	if r == 0 {
+		return err
+	}
+
+	return nil
+}
+
+func setConsoleMode(handle syscall.Handle, mode uint32) error {
+	r, _, err := procSetConsoleMode.Call(uintptr(handle), uintptr(mode))
+	if r == 0 {
+		return err
+	}
+
+	return nil
+}
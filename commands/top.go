@@ -0,0 +1,445 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+const topHelp = "j/k move  c/m/d/n sort  / filter  enter shell  s stop  x destroy  q quit"
+
+// containerStats is a single row of the top display.
+type containerStats struct {
+	Handle string
+	State  string
+	CPU    float64
+	Memory uint64
+	Disk   uint64
+
+	// Alerts are the resources it uses more of than the thresholds allow.
+	Alerts []string
+}
+
+// top is an interactive, periodically refreshed table of the containers on
+// the server and their resource usage.
+type top struct {
+	client     garden.Client
+	term       *terminal
+	interval   time.Duration
+	thresholds Thresholds
+
+	stats     []containerStats
+	cpuUsage  map[string]uint64
+	sampledAt time.Time
+
+	sortBy    string
+	filter    string
+	filtering bool
+	selected  int
+
+	confirm string
+	message string
+}
+
+// Top takes over the terminal to show a table of the containers on the
+// server until the user quits. Rows are sorted by cpu, memory, disk or
+// handle, and those of containers over the thresholds are shown in red.
+func Top(client garden.Client, interval time.Duration, sortBy string, thresholds Thresholds) error {
+	switch sortBy {
+	case "cpu", "memory", "disk", "handle":
+	default:
+		return fmt.Errorf("cannot sort by %s", sortBy)
+	}
+
+	t, err := openTerminal()
+	if err != nil {
+		return err
+	}
+
+	if err := t.SetRaw(); err != nil {
+		return err
+	}
+
+	restore := func() {
+		fmt.Fprint(t, "\033[H\033[2J\033[?25h")
+		t.Restore()
+	}
+	defer AtExit(restore)()
+
+	top := &top{
+		client:     client,
+		term:       t,
+		interval:   interval,
+		thresholds: thresholds,
+		cpuUsage:   map[string]uint64{},
+		sortBy:     sortBy,
+	}
+
+	fmt.Fprint(t, "\033[?25l")
+
+	err = top.loop()
+	restore()
+
+	return err
+}
+
+func (t *top) loop() error {
+	var refreshed time.Time
+
+	for {
+		if time.Since(refreshed) >= t.interval {
+			t.refresh()
+			t.render()
+			refreshed = time.Now()
+		}
+
+		available, err := t.term.Available()
+		if err != nil {
+			return err
+		}
+
+		if available == 0 {
+			time.Sleep(50 * time.Millisecond)
+			continue
+		}
+
+		input := make([]byte, available)
+		n, err := t.term.Read(input)
+		if err != nil {
+			return err
+		}
+
+		quit, forceRefresh := t.handleInput(input[:n])
+		if quit {
+			return nil
+		}
+
+		if forceRefresh {
+			refreshed = time.Time{}
+		}
+
+		t.render()
+	}
+}
+
+func (t *top) refresh() {
+	containers, err := t.client.Containers(nil)
+	if err != nil {
+		t.message = err.Error()
+		return
+	}
+
+	infos, _ := bulkInfo(containers)
+
+	byHandle := map[string]garden.Container{}
+	for _, container := range containers {
+		byHandle[container.Handle()] = container
+	}
+
+	now := time.Now()
+	elapsed := now.Sub(t.sampledAt)
+
+	stats := make([]containerStats, 0, len(infos))
+	usage := map[string]uint64{}
+
+	for handle, info := range infos {
+		row := containerStats{
+			Handle: handle,
+			State:  info.State,
+			Memory: info.MemoryStat.TotalRss,
+			Disk:   info.DiskStat.BytesUsed,
+		}
+
+		previous, seen := t.cpuUsage[handle]
+		if seen && !t.sampledAt.IsZero() && info.CPUStat.Usage >= previous {
+			row.CPU = float64(info.CPUStat.Usage-previous) / float64(elapsed.Nanoseconds()) * 100
+		}
+
+		if container, found := byHandle[handle]; found && !t.thresholds.IsZero() {
+			for _, alert := range CheckThresholds(container, info, t.thresholds) {
+				row.Alerts = append(row.Alerts, alert.Resource)
+			}
+		}
+
+		usage[handle] = info.CPUStat.Usage
+		stats = append(stats, row)
+	}
+
+	t.stats = stats
+	t.cpuUsage = usage
+	t.sampledAt = now
+}
+
+// visible returns the rows which match the filter in display order.
+func (t *top) visible() []containerStats {
+	rows := []containerStats{}
+	for _, row := range t.stats {
+		if strings.Contains(row.Handle, t.filter) {
+			rows = append(rows, row)
+		}
+	}
+
+	sort.Sort(statsSorter{rows, t.sortBy})
+
+	if t.selected >= len(rows) {
+		t.selected = len(rows) - 1
+	}
+
+	if t.selected < 0 {
+		t.selected = 0
+	}
+
+	return rows
+}
+
+func (t *top) current() (containerStats, bool) {
+	rows := t.visible()
+	if len(rows) == 0 {
+		return containerStats{}, false
+	}
+
+	return rows[t.selected], true
+}
+
+// handleInput acts on a chunk of keyboard input. It reports whether top
+// should exit and whether the containers should be fetched again
+// immediately.
+func (t *top) handleInput(input []byte) (bool, bool) {
+	if t.filtering {
+		for _, b := range input {
+			switch b {
+			case '\r', '\n', 0x1b:
+				t.filtering = false
+			case 0x7f, 0x08:
+				if len(t.filter) > 0 {
+					t.filter = t.filter[:len(t.filter)-1]
+				}
+			default:
+				if b >= 0x20 && b < 0x7f {
+					t.filter += string(b)
+				}
+			}
+		}
+
+		return false, false
+	}
+
+	if t.confirm != "" {
+		handle := t.confirm
+		t.confirm = ""
+
+		if input[0] != 'y' && input[0] != 'Y' {
+			t.message = ""
+			return false, false
+		}
+
+		t.message = "destroyed " + handle
+		if err := t.client.Destroy(handle); err != nil {
+			t.message = err.Error()
+		}
+
+		return false, true
+	}
+
+	switch {
+	case bytes.Equal(input, []byte("\033[A")):
+		t.selected--
+		return false, false
+	case bytes.Equal(input, []byte("\033[B")):
+		t.selected++
+		return false, false
+	}
+
+	switch input[0] {
+	case 'q', 0x03:
+		return true, false
+	case 'k':
+		t.selected--
+	case 'j':
+		t.selected++
+	case 'c':
+		t.sortBy = "cpu"
+	case 'm':
+		t.sortBy = "memory"
+	case 'd':
+		t.sortBy = "disk"
+	case 'n':
+		t.sortBy = "handle"
+	case '/':
+		t.filtering = true
+		t.filter = ""
+	case '\r', '\n':
+		row, ok := t.current()
+		if !ok {
+			break
+		}
+
+		t.message = ""
+		if err := t.shell(row.Handle); err != nil {
+			t.message = err.Error()
+		}
+
+		return false, true
+	case 's':
+		row, ok := t.current()
+		if !ok {
+			break
+		}
+
+		t.message = "stopped " + row.Handle
+		if err := t.stop(row.Handle); err != nil {
+			t.message = err.Error()
+		}
+
+		return false, true
+	case 'x':
+		row, ok := t.current()
+		if !ok {
+			break
+		}
+
+		t.confirm = row.Handle
+		t.message = fmt.Sprintf("destroy %s? (y/n)", row.Handle)
+	}
+
+	return false, false
+}
+
+func (t *top) stop(handle string) error {
+	container, err := t.client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	return container.Stop(false)
+}
+
+// shell hands the terminal over to a shell in the container and takes it
+// back once the shell exits.
+func (t *top) shell(handle string) error {
+	container, err := t.client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(t.term, "\033[H\033[2J\033[?25h")
+	t.term.Restore()
+
+	// no reconnecting, as its relay would keep reading the terminal from top
+	err = Shell(container, "", Reconnect{})
+
+	t.term.SetRaw()
+	fmt.Fprint(t.term, "\033[?25l")
+
+	return err
+}
+
+func (t *top) render() {
+	height, width, err := t.term.Size()
+	if err != nil {
+		height, width = 24, 80
+	}
+
+	rows := t.visible()
+
+	handleWidth := len("HANDLE")
+	for _, row := range rows {
+		if len(row.Handle) > handleWidth {
+			handleWidth = len(row.Handle)
+		}
+	}
+
+	if limit := width - 40; handleWidth > limit && limit > 8 {
+		handleWidth = limit
+	}
+
+	var screen bytes.Buffer
+	screen.WriteString("\033[H\033[2J")
+
+	alerting := 0
+	for _, row := range t.stats {
+		if len(row.Alerts) > 0 {
+			alerting++
+		}
+	}
+
+	header := fmt.Sprintf("gaol top - %d containers - sorted by %s - every %s", len(t.stats), t.sortBy, t.interval)
+	if alerting > 0 {
+		header += fmt.Sprintf(" - %d over thresholds", alerting)
+	}
+	if t.filtering || t.filter != "" {
+		header += " - filter: " + t.filter
+	}
+	screen.WriteString(truncate(header, width) + "\r\n\r\n")
+
+	fmt.Fprintf(&screen, "%-*s %-8s %7s %10s %10s\r\n", handleWidth, "HANDLE", "STATE", "CPU%", "MEMORY", "DISK")
+
+	// leave room for the header, column names, message and help
+	space := height - 6
+	offset := 0
+	if t.selected >= space && space > 0 {
+		offset = t.selected - space + 1
+	}
+
+	for i := offset; i < len(rows) && i-offset < space; i++ {
+		row := rows[i]
+		line := fmt.Sprintf("%-*s %-8s %7.1f %10s %10s",
+			handleWidth, truncate(row.Handle, handleWidth), row.State, row.CPU, HumanBytes(row.Memory), HumanBytes(row.Disk))
+
+		switch {
+		case i == t.selected && len(row.Alerts) > 0:
+			line = "\033[7;31m" + line + "\033[0m"
+		case i == t.selected:
+			line = "\033[7m" + line + "\033[0m"
+		case len(row.Alerts) > 0:
+			line = "\033[31m" + line + "\033[0m"
+		}
+
+		screen.WriteString(line + "\r\n")
+	}
+
+	fmt.Fprintf(&screen, "\033[%d;1H%s\r\n%s", height-1, truncate(t.message, width), truncate(topHelp, width))
+
+	t.term.Write(screen.Bytes())
+}
+
+func truncate(s string, width int) string {
+	if width > 0 && len(s) > width {
+		return s[:width]
+	}
+
+	return s
+}
+
+type statsSorter struct {
+	rows   []containerStats
+	sortBy string
+}
+
+func (s statsSorter) Len() int      { return len(s.rows) }
+func (s statsSorter) Swap(i, j int) { s.rows[i], s.rows[j] = s.rows[j], s.rows[i] }
+
+func (s statsSorter) Less(i, j int) bool {
+	a, b := s.rows[i], s.rows[j]
+
+	switch s.sortBy {
+	case "cpu":
+		if a.CPU != b.CPU {
+			return a.CPU > b.CPU
+		}
+	case "memory":
+		if a.Memory != b.Memory {
+			return a.Memory > b.Memory
+		}
+	case "disk":
+		if a.Disk != b.Disk {
+			return a.Disk > b.Disk
+		}
+	}
+
+	return a.Handle < b.Handle
+}
@@ -0,0 +1,289 @@
+package commands
+
+import (
+	"archive/tar"
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// TreeOptions say how a local directory is streamed into a container.
+type TreeOptions struct {
+	// Dereference streams what symlinks point to rather than the links.
+	Dereference bool
+
+	// Exclude are glob patterns of what to leave out, as in a .gaolignore.
+	Exclude []string
+}
+
+// IgnoreFile is the file in the root of a directory streamed in listing
+// what to leave out of it, one pattern per line.
+const IgnoreFile = ".gaolignore"
+
+// CheckExcludes checks that the patterns can be matched. A pattern such as
+// node_modules or *.pyc without a slash matches the name of anything in the
+// tree, whereas one such as build/cache or /vendor matches its path from the
+// root. Patterns ending in a slash only match directories, and what is in
+// a directory which is left out is left out with it.
+func CheckExcludes(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(strings.Trim(pattern, "/"), ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q", pattern)
+		}
+	}
+
+	return nil
+}
+
+// readIgnoreFile returns the patterns in the ignore file in dir, skipping
+// blank lines and comments, if there is one.
+func readIgnoreFile(dir string) ([]string, error) {
+	file, err := os.Open(filepath.Join(dir, IgnoreFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	patterns := []string{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := CheckExcludes(patterns); err != nil {
+		return nil, fmt.Errorf("%s: %s", filepath.Join(dir, IgnoreFile), err)
+	}
+
+	return patterns, nil
+}
+
+// excluded reports whether a pattern matches rel, the path of a file or
+// directory from the root of the tree.
+func excluded(patterns []string, rel string, dir bool) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/") && !dir {
+			continue
+		}
+
+		pattern = strings.TrimSuffix(pattern, "/")
+
+		name := path.Base(rel)
+		if strings.Contains(pattern, "/") {
+			pattern, name = strings.TrimPrefix(pattern, "/"), rel
+		}
+
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StreamInTree streams the local directory src into the directory dst in
+// the container, where it keeps its name. Symlinks, hardlinks, empty
+// directories, fifos and device nodes are streamed as they are, rather than
+// as copies, and everything is given the attributes. What matches the
+// patterns to exclude, or those in the directory's ignore file, is left out.
+func StreamInTree(client garden.Client, handle string, src string, dst string, opts TreeOptions, attrs Attributes) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", src)
+	}
+
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(tarTree(writer, src, opts, attrs))
+	}()
+
+	return container.StreamIn(dst, reader)
+}
+
+// treeWriter tars a local directory.
+type treeWriter struct {
+	tw    *tar.Writer
+	opts  TreeOptions
+	attrs Attributes
+
+	// base is the name of the directory, under which everything is streamed
+	base string
+
+	// links is the name of the first of each file with several hardlinks
+	links map[fileID]string
+
+	// parents are the directories being walked, resolved, so that following
+	// a symlink to one of them is not followed forever
+	parents []string
+}
+
+func tarTree(w io.Writer, src string, opts TreeOptions, attrs Attributes) error {
+	root, err := filepath.Abs(src)
+	if err != nil {
+		return err
+	}
+
+	ignored, err := readIgnoreFile(root)
+	if err != nil {
+		return err
+	}
+
+	opts.Exclude = append(append([]string{}, opts.Exclude...), ignored...)
+
+	t := &treeWriter{
+		tw:    tar.NewWriter(w),
+		opts:  opts,
+		attrs: attrs,
+		base:  filepath.Base(root),
+		links: map[fileID]string{},
+	}
+
+	if err := t.add(root, ""); err != nil {
+		return err
+	}
+
+	return t.tw.Close()
+}
+
+// add streams the file, whose path from the root of the tree is rel.
+func (t *treeWriter) add(file string, rel string) error {
+	info, err := os.Lstat(file)
+	if err != nil {
+		return err
+	}
+
+	link := ""
+	if info.Mode()&os.ModeSymlink != 0 {
+		if t.opts.Dereference {
+			info, err = os.Stat(file)
+			if err != nil {
+				return fmt.Errorf("following symlink %s: %s", file, err)
+			}
+		} else if link, err = os.Readlink(file); err != nil {
+			return err
+		}
+	}
+
+	// as tar does, sockets are left out, there being no way to stream them
+	if info.Mode()&os.ModeSocket != 0 {
+		return nil
+	}
+
+	name := t.base
+	if rel != "" {
+		if excluded(t.opts.Exclude, rel, info.IsDir()) {
+			return nil
+		}
+
+		name += "/" + rel
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return fmt.Errorf("%s: %s", file, err)
+	}
+
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+	}
+
+	if header.Typeflag == tar.TypeReg {
+		if id, ok := fileIdentity(info); ok {
+			if first, seen := t.links[id]; seen {
+				header.Typeflag = tar.TypeLink
+				header.Linkname = first
+				header.Size = 0
+			} else {
+				t.links[id] = name
+			}
+		}
+	}
+
+	t.attrs.apply(header)
+
+	if err := t.tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	switch {
+	case header.Typeflag == tar.TypeReg:
+		return t.copyFile(file, header.Size)
+	case info.IsDir():
+		return t.addDir(file, rel)
+	}
+
+	return nil
+}
+
+func (t *treeWriter) copyFile(file string, size int64) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.CopyN(t.tw, f, size); err != nil {
+		return fmt.Errorf("%s changed while being streamed: %s", file, err)
+	}
+
+	return nil
+}
+
+func (t *treeWriter) addDir(dir string, rel string) error {
+	if t.opts.Dereference {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return err
+		}
+
+		for _, parent := range t.parents {
+			if parent == resolved {
+				return fmt.Errorf("symlink loop at %s", dir)
+			}
+		}
+
+		t.parents = append(t.parents, resolved)
+		defer func() { t.parents = t.parents[:len(t.parents)-1] }()
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := t.add(filepath.Join(dir, entry.Name()), path.Join(rel, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package commands
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// trickyTree makes a directory holding a file, a hardlink to it, a symlink
+// to it, an empty directory and a fifo.
+func trickyTree(t *testing.T) string {
+	root, err := ioutil.TempDir("", "gaol-tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := filepath.Join(root, "app")
+	for _, err := range []error{
+		os.MkdirAll(filepath.Join(app, "bin"), 0755),
+		os.MkdirAll(filepath.Join(app, "tmp"), 0700),
+		ioutil.WriteFile(filepath.Join(app, "bin", "run"), []byte("#!/bin/sh\n"), 0755),
+		os.Link(filepath.Join(app, "bin", "run"), filepath.Join(app, "bin", "start")),
+		os.Symlink("bin/run", filepath.Join(app, "run")),
+		syscall.Mkfifo(filepath.Join(app, "control"), 0600),
+	} {
+		if err != nil {
+			os.RemoveAll(root)
+			t.Fatal(err)
+		}
+	}
+
+	return root
+}
+
+// roundTrip streams the tree in, and extracts what was streamed into a new
+// directory.
+func roundTrip(t *testing.T, src string, opts TreeOptions) (string, error) {
+	var archive bytes.Buffer
+
+	container := fakeContainer("web")
+	container.StreamInStub = func(dst string, r io.Reader) error {
+		_, err := io.Copy(&archive, r)
+		return err
+	}
+
+	if err := StreamInTree(streamInClient(container), "web", src, "/srv", opts, KeepAttributes); err != nil {
+		return "", err
+	}
+
+	dir, err := ioutil.TempDir("", "gaol-tree-out")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir, extractTar(&archive, dir, true, ioutil.Discard)
+}
+
+func TestStreamInTreeRoundTrips(t *testing.T) {
+	root := trickyTree(t)
+	defer os.RemoveAll(root)
+
+	out, err := roundTrip(t, filepath.Join(root, "app"), TreeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+
+	app := filepath.Join(out, "app")
+
+	if link, err := os.Readlink(filepath.Join(app, "run")); err != nil || link != "bin/run" {
+		t.Errorf("symlink points to %q (%v)", link, err)
+	}
+
+	run, err := os.Stat(filepath.Join(app, "bin", "run"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if start, err := os.Stat(filepath.Join(app, "bin", "start")); err != nil || !os.SameFile(run, start) {
+		t.Errorf("hardlink became a copy (%v)", err)
+	}
+
+	if run.Mode() != 0755 {
+		t.Errorf("file has mode %s", run.Mode())
+	}
+
+	if info, err := os.Stat(filepath.Join(app, "tmp")); err != nil || info.Mode() != os.ModeDir|0700 {
+		t.Errorf("empty directory is %v (%v)", info, err)
+	}
+
+	if info, err := os.Lstat(filepath.Join(app, "control")); err != nil || info.Mode()&os.ModeNamedPipe == 0 {
+		t.Errorf("fifo is %v (%v)", info, err)
+	}
+}
+
+func TestStreamInTreeDereference(t *testing.T) {
+	root := trickyTree(t)
+	defer os.RemoveAll(root)
+
+	out, err := roundTrip(t, filepath.Join(root, "app"), TreeOptions{Dereference: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+
+	info, err := os.Lstat(filepath.Join(out, "app", "run"))
+	if err != nil || !info.Mode().IsRegular() {
+		t.Errorf("symlink streamed as %v (%v), want a copy of what it points to", info, err)
+	}
+}
+
+func TestStreamInTreeDereferenceLoop(t *testing.T) {
+	root := trickyTree(t)
+	defer os.RemoveAll(root)
+
+	os.Symlink("..", filepath.Join(root, "app", "bin", "up"))
+
+	_, err := roundTrip(t, filepath.Join(root, "app"), TreeOptions{Dereference: true})
+	if err == nil || !strings.Contains(err.Error(), "symlink loop") {
+		t.Errorf("got %v, want a symlink loop", err)
+	}
+}
+
+func TestStreamInTreeNotADirectory(t *testing.T) {
+	root := trickyTree(t)
+	defer os.RemoveAll(root)
+
+	container := fakeContainer("web")
+	err := StreamInTree(streamInClient(container), "web", filepath.Join(root, "app", "bin", "run"), "/srv", TreeOptions{}, KeepAttributes)
+	if err == nil || container.StreamInCallCount() != 0 {
+		t.Errorf("got %v, want a file to be refused", err)
+	}
+}
+
+func TestExcluded(t *testing.T) {
+	patterns := []string{"node_modules", "*.pyc", "/build/", "docs/*.md"}
+
+	for rel, want := range map[string]bool{
+		"node_modules":          true,
+		"web/node_modules":      true,
+		"lib/app.pyc":           true,
+		"lib/app.py":            false,
+		"build":                 true,
+		"web/build":             false,
+		"docs/README.md":        true,
+		"web/docs/README.md":    false,
+		"node_modules_and_more": false,
+	} {
+		if got := excluded(patterns, rel, !strings.Contains(rel, ".")); got != want {
+			t.Errorf("%s: got %v, want %v", rel, got, want)
+		}
+	}
+
+	if excluded([]string{"build/"}, "build", false) {
+		t.Error("a pattern for directories matched a file")
+	}
+}
+
+func TestStreamInTreeExcludes(t *testing.T) {
+	root := trickyTree(t)
+	defer os.RemoveAll(root)
+
+	app := filepath.Join(root, "app")
+	os.MkdirAll(filepath.Join(app, "node_modules", "left-pad"), 0755)
+	ioutil.WriteFile(filepath.Join(app, "node_modules", "left-pad", "index.js"), []byte("module.exports = 1"), 0644)
+	ioutil.WriteFile(filepath.Join(app, IgnoreFile), []byte("# dependencies are installed in the container\nnode_modules/\n"), 0644)
+
+	out, err := roundTrip(t, app, TreeOptions{Exclude: []string{"control", "/bin/start"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+
+	for name, want := range map[string]bool{
+		"node_modules": false,
+		"control":      false,
+		"bin/start":    false,
+		"bin/run":      true,
+		IgnoreFile:     true,
+	} {
+		if _, err := os.Lstat(filepath.Join(out, "app", name)); (err == nil) != want {
+			t.Errorf("%s: streamed %v, want %v", name, err == nil, want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package commands
+
+import (
+	"archive/tar"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// fileIdentity identifies a file with several hardlinks, so that all but the
+// first of them can be streamed as links to it.
+func fileIdentity(info os.FileInfo) (fileID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || stat.Nlink < 2 {
+		return fileID{}, false
+	}
+
+	return fileID{uint64(stat.Dev), uint64(stat.Ino)}, true
+}
+
+// makeNode creates the device node or fifo of the entry, which takes root
+// for device nodes.
+func makeNode(dst string, header *tar.Header) error {
+	mode := uint32(header.Mode & 07777)
+	switch header.Typeflag {
+	case tar.TypeChar:
+		mode |= syscall.S_IFCHR
+	case tar.TypeBlock:
+		mode |= syscall.S_IFBLK
+	default:
+		mode |= syscall.S_IFIFO
+	}
+
+	return syscall.Mknod(dst, mode, int(makedev(header.Devmajor, header.Devminor)))
+}
+
+func makedev(major, minor int64) uint64 {
+	if runtime.GOOS == "darwin" {
+		return uint64(major)<<24 | uint64(minor)
+	}
+
+	return uint64(minor&0xff) | uint64(major&0xfff)<<8 | uint64(minor&^0xff)<<12 | uint64(major&^0xfff)<<32
+}
@@ -0,0 +1,178 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// Update is a set of changes to make to a container together. Zero limits
+// are left alone.
+type Update struct {
+	Limits     ManifestLimits
+	Properties garden.Properties
+}
+
+// IsZero reports whether the update changes nothing.
+func (u Update) IsZero() bool {
+	return u.Limits == ManifestLimits{} && len(u.Properties) == 0
+}
+
+// UpdateResult is what became of one change of an update: whether the
+// server rejected it, or it was undone, or failed to be, because another
+// change was rejected.
+type UpdateResult struct {
+	Change     string
+	Err        error
+	RolledBack bool
+	UndoErr    error
+}
+
+// change is a single change, which can be undone once made.
+type change struct {
+	name string
+
+	// apply makes the change, returning how to undo it.
+	apply func(garden.Container) (func() error, error)
+}
+
+// ApplyUpdate makes every change of the update to the container. If the
+// server rejects any of them, those which were made are undone, so that the
+// container is changed either entirely or, unless undoing fails too, not at
+// all. The garden api has no transactions, so another client may see the
+// changes in between.
+func ApplyUpdate(client garden.Client, handle string, update Update) ([]UpdateResult, error) {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := updateChanges(update)
+
+	results := make([]UpdateResult, len(changes))
+	undos := make([]func() error, len(changes))
+	failed := false
+
+	for i, c := range changes {
+		results[i].Change = c.name
+
+		undos[i], results[i].Err = c.apply(container)
+		if results[i].Err != nil {
+			failed = true
+		}
+	}
+
+	if !failed {
+		return results, nil
+	}
+
+	// undo in reverse, in case changes build on each other
+	for i := len(changes) - 1; i >= 0; i-- {
+		if results[i].Err != nil {
+			continue
+		}
+
+		results[i].UndoErr = undos[i]()
+		results[i].RolledBack = results[i].UndoErr == nil
+	}
+
+	return results, nil
+}
+
+func updateChanges(update Update) []change {
+	changes := []change{}
+	limits := update.Limits
+
+	if limits.Memory > 0 {
+		changes = append(changes, change{"memory limit", func(container garden.Container) (func() error, error) {
+			previous, err := container.CurrentMemoryLimits()
+			if err != nil {
+				return nil, err
+			}
+
+			err = container.LimitMemory(garden.MemoryLimits{LimitInBytes: uint64(limits.Memory)})
+			return func() error { return container.LimitMemory(previous) }, err
+		}})
+	}
+
+	if limits.Disk > 0 {
+		changes = append(changes, change{"disk limit", func(container garden.Container) (func() error, error) {
+			previous, err := container.CurrentDiskLimits()
+			if err != nil {
+				return nil, err
+			}
+
+			err = container.LimitDisk(garden.DiskLimits{ByteHard: uint64(limits.Disk)})
+			return func() error { return container.LimitDisk(previous) }, err
+		}})
+	}
+
+	if limits.CPU > 0 {
+		changes = append(changes, change{"cpu shares", func(container garden.Container) (func() error, error) {
+			previous, err := container.CurrentCPULimits()
+			if err != nil {
+				return nil, err
+			}
+
+			err = container.LimitCPU(garden.CPULimits{LimitInShares: limits.CPU})
+			return func() error { return container.LimitCPU(previous) }, err
+		}})
+	}
+
+	keys := make([]string, 0, len(update.Properties))
+	for key := range update.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		key, value := key, update.Properties[key]
+
+		changes = append(changes, change{"property " + key, func(container garden.Container) (func() error, error) {
+			previous, err := container.GetProperty(key)
+			had := err == nil
+
+			err = container.SetProperty(key, value)
+			return func() error {
+				if had {
+					return container.SetProperty(key, previous)
+				}
+
+				return container.RemoveProperty(key)
+			}, err
+		}})
+	}
+
+	return changes
+}
+
+// RejectedChanges counts the changes the server rejected.
+func RejectedChanges(results []UpdateResult) int {
+	rejected := 0
+	for _, result := range results {
+		if result.Err != nil {
+			rejected++
+		}
+	}
+
+	return rejected
+}
+
+// PrintUpdateResults writes a tab-separated line for each change: what it
+// was and whether it was made, rejected or rolled back.
+func PrintUpdateResults(w io.Writer, results []UpdateResult) {
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			fmt.Fprintf(w, "%s\trejected: %s\n", result.Change, result.Err)
+		case result.RolledBack:
+			fmt.Fprintf(w, "%s\trolled back\n", result.Change)
+		case result.UndoErr != nil:
+			fmt.Fprintf(w, "%s\tmade, but not rolled back: %s\n", result.Change, result.UndoErr)
+		default:
+			fmt.Fprintf(w, "%s\tok\n", result.Change)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+// propertiesContainer keeps its properties in a map, failing to set those
+// in reject.
+func propertiesContainer(properties map[string]string, reject string) *fakes.FakeContainer {
+	container := fakeContainer("web")
+	container.GetPropertyStub = func(key string) (string, error) {
+		value, found := properties[key]
+		if !found {
+			return "", errors.New("no such property")
+		}
+
+		return value, nil
+	}
+	container.SetPropertyStub = func(key string, value string) error {
+		if key == reject {
+			return errors.New("property too long")
+		}
+
+		properties[key] = value
+		return nil
+	}
+	container.RemovePropertyStub = func(key string) error {
+		delete(properties, key)
+		return nil
+	}
+
+	return container
+}
+
+func TestApplyUpdate(t *testing.T) {
+	properties := map[string]string{"team": "db"}
+	container := propertiesContainer(properties, "")
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	results, err := ApplyUpdate(fakeClient, "web", Update{
+		Limits:     ManifestLimits{Memory: 1 << 30, CPU: 50},
+		Properties: garden.Properties{"team": "web", "ci": "true"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if RejectedChanges(results) != 0 {
+		t.Errorf("rejected %v", results)
+	}
+
+	var buf bytes.Buffer
+	PrintUpdateResults(&buf, results)
+
+	if buf.String() != "memory limit\tok\ncpu shares\tok\nproperty ci\tok\nproperty team\tok\n" {
+		t.Errorf("printed %q", buf.String())
+	}
+
+	if !reflect.DeepEqual(properties, map[string]string{"team": "web", "ci": "true"}) {
+		t.Errorf("left properties %v", properties)
+	}
+
+	if container.LimitMemoryArgsForCall(0).LimitInBytes != 1<<30 || container.LimitCPUArgsForCall(0).LimitInShares != 50 {
+		t.Error("did not apply the limits")
+	}
+
+	if container.LimitDiskCallCount() != 0 {
+		t.Error("limited the disk without being asked to")
+	}
+}
+
+func TestApplyUpdateRollsBack(t *testing.T) {
+	properties := map[string]string{"team": "db"}
+	container := propertiesContainer(properties, "owner")
+	container.CurrentMemoryLimitsReturns(garden.MemoryLimits{LimitInBytes: 256 << 20}, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	results, err := ApplyUpdate(fakeClient, "web", Update{
+		Limits:     ManifestLimits{Memory: 1 << 30},
+		Properties: garden.Properties{"team": "web", "ci": "true", "owner": "me"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	PrintUpdateResults(&buf, results)
+
+	want := "memory limit\trolled back\nproperty ci\trolled back\nproperty owner\trejected: property too long\nproperty team\trolled back\n"
+	if buf.String() != want {
+		t.Errorf("printed %q, want %q", buf.String(), want)
+	}
+
+	if RejectedChanges(results) != 1 {
+		t.Errorf("counted %d rejected changes", RejectedChanges(results))
+	}
+
+	if !reflect.DeepEqual(properties, map[string]string{"team": "db"}) {
+		t.Errorf("left properties %v, want them as they were", properties)
+	}
+
+	if container.LimitMemoryCallCount() != 2 || container.LimitMemoryArgsForCall(1).LimitInBytes != 256<<20 {
+		t.Error("did not put the memory limit back")
+	}
+}
+
+func TestApplyUpdateUndoFailure(t *testing.T) {
+	container := fakeContainer("web")
+	container.LimitMemoryStub = func(limits garden.MemoryLimits) error {
+		if limits.LimitInBytes == 0 {
+			return errors.New("connection reset")
+		}
+
+		return nil
+	}
+	container.LimitCPUReturns(errors.New("not supported"))
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	results, _ := ApplyUpdate(fakeClient, "web", Update{Limits: ManifestLimits{Memory: 1 << 30, CPU: 10}})
+
+	var buf bytes.Buffer
+	PrintUpdateResults(&buf, results)
+
+	if buf.String() != "memory limit\tmade, but not rolled back: connection reset\ncpu shares\trejected: not supported\n" {
+		t.Errorf("printed %q", buf.String())
+	}
+}
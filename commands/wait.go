@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// WaitForPort checks every interval whether something in the container
+// listens on port, giving up once timeout has passed. ViaHost connects to the
+// host port mapped to it instead of looking inside the container, which also
+// proves that the port can be reached from outside.
+func WaitForPort(client garden.Client, handle string, port uint32, viaHost bool, timeout, interval time.Duration) error {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return err
+	}
+
+	check := func() error {
+		if viaHost {
+			return checkPort(container, port)
+		}
+
+		return listening(container, port)
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		err := check()
+		if err == nil {
+			return nil
+		}
+
+		if timeout > 0 && time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("port %d not ready after %s: %s", port, timeout, err)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// listening reads the container's socket tables to find out whether
+// anything listens on the port, so that only cat is needed in the container.
+func listening(container garden.Container, port uint32) error {
+	var tables bytes.Buffer
+
+	process, err := container.Run(garden.ProcessSpec{
+		Path: "cat",
+		Args: []string{"/proc/net/tcp", "/proc/net/tcp6"},
+	}, garden.ProcessIO{Stdout: &tables})
+	if err != nil {
+		return err
+	}
+
+	// tcp6 is missing from containers without ipv6, which fails cat
+	// without keeping it from printing tcp
+	if _, err := process.Wait(); err != nil {
+		return err
+	}
+
+	if !listensOn(tables.String(), port) {
+		return errors.New("nothing is listening")
+	}
+
+	return nil
+}
+
+// listensOn reports whether a socket in the /proc/net/tcp tables is
+// listening on the port.
+func listensOn(tables string, port uint32) bool {
+	local := fmt.Sprintf(":%04X", port)
+
+	for _, line := range strings.Split(tables, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+
+		// local_address is address:port in hex, and state 0A is LISTEN
+		if strings.HasSuffix(fields[1], local) && fields[3] == "0A" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WaitForExit waits for a process in the container to exit, giving up once
+// timeout has passed unless it is zero, and returns its exit status.
+func WaitForExit(client garden.Client, handle string, pid uint32, timeout time.Duration) (int, error) {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return 0, err
+	}
+
+	process, err := container.Attach(pid, garden.ProcessIO{})
+	if err != nil {
+		return 0, err
+	}
+
+	type exit struct {
+		status int
+		err    error
+	}
+
+	exited := make(chan exit, 1)
+	go func() {
+		status, err := process.Wait()
+		exited <- exit{status, err}
+	}()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
+
+	select {
+	case e := <-exited:
+		return e.status, e.err
+	case <-expired:
+		return 0, fmt.Errorf("process %d still running after %s", pid, timeout)
+	}
+}
+
+// RecordedPID returns the PID recorded for a named process in the container,
+// by a manifest or by supervising it.
+func RecordedPID(client garden.Client, handle string, name string) (uint32, error) {
+	container, err := client.Lookup(handle)
+	if err != nil {
+		return 0, err
+	}
+
+	return processPID(container, name)
+}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+const tcpTable = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 12345 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:0016 0100007F:B1C2 01 00000000:00000000 00:00000000 00000000     0        0 12346 1 0000000000000000 20 4 30 10 -1
+`
+
+func TestListensOn(t *testing.T) {
+	if !listensOn(tcpTable, 8080) {
+		t.Error("expected 8080 to be listening")
+	}
+
+	// 22 has a connection, but nothing listening
+	if listensOn(tcpTable, 22) {
+		t.Error("expected 22 not to be listening")
+	}
+}
+
+func TestWaitForPort(t *testing.T) {
+	checks := 0
+
+	container := fakeContainer("web")
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		checks++
+		if checks == 3 {
+			io.WriteString(processIO.Stdout, tcpTable)
+		}
+
+		return new(fakes.FakeProcess), nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	if err := WaitForPort(fakeClient, "web", 8080, false, time.Second, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if checks != 3 {
+		t.Errorf("checked %d times, want 3", checks)
+	}
+
+	if spec, _ := container.RunArgsForCall(0); spec.Path != "cat" {
+		t.Errorf("checked with %#v", spec)
+	}
+
+	err := WaitForPort(fakeClient, "web", 9090, false, 10*time.Millisecond, time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "port 9090 not ready after 10ms") {
+		t.Errorf("got %v, want it to time out", err)
+	}
+}
+
+func TestWaitForPortViaHost(t *testing.T) {
+	defer func(dial func(string) (net.Conn, error)) { dialPort = dial }(dialPort)
+
+	dialed := ""
+	dialPort = func(address string) (net.Conn, error) {
+		dialed = address
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	}
+
+	container := fakeContainer("web")
+	container.InfoReturns(garden.ContainerInfo{
+		ExternalIP:  "10.0.0.1",
+		MappedPorts: []garden.PortMapping{{HostPort: 61001, ContainerPort: 8080}},
+	}, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	if err := WaitForPort(fakeClient, "web", 8080, true, time.Second, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if dialed != "10.0.0.1:61001" || container.RunCallCount() != 0 {
+		t.Errorf("dialed %q and ran %d processes", dialed, container.RunCallCount())
+	}
+}
+
+func TestWaitForExit(t *testing.T) {
+	process := new(fakes.FakeProcess)
+	process.WaitReturns(3, nil)
+
+	container := fakeContainer("web")
+	container.AttachReturns(process, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	status, err := WaitForExit(fakeClient, "web", 7, time.Second)
+	if err != nil || status != 3 {
+		t.Errorf("got %d (%v), want 3", status, err)
+	}
+
+	if pid, _ := container.AttachArgsForCall(0); pid != 7 {
+		t.Errorf("attached to %d", pid)
+	}
+
+	process.WaitStub = func() (int, error) { select {} }
+
+	_, err = WaitForExit(fakeClient, "web", 7, 10*time.Millisecond)
+	if err == nil || err.Error() != "process 7 still running after 10ms" {
+		t.Errorf("got %v, want it to time out", err)
+	}
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// watch repeatedly renders the lines returned by render, clearing the
+// screen in between. Lines which were not there on the previous refresh are
+// highlighted and lines which have gone away are shown once more, struck
+// out in red.
+func watch(w io.Writer, interval time.Duration, title string, format Format, render func() ([]string, error)) error {
+	var previous []string
+
+	for {
+		current, err := render()
+		if err != nil {
+			return err
+		}
+
+		present := map[string]bool{}
+		for _, line := range current {
+			present[line] = true
+		}
+
+		before := map[string]bool{}
+		for _, line := range previous {
+			before[line] = true
+		}
+
+		fmt.Fprint(w, "\033[H\033[2J")
+		fmt.Fprintf(w, "Every %s: %s\t%s\n\n", interval, title, time.Now().Format(time.RFC1123))
+
+		for _, line := range current {
+			if previous != nil && !before[line] {
+				fmt.Fprintln(w, format.paint(green, "+ "+line))
+			} else {
+				fmt.Fprintf(w, "  %s\n", line)
+			}
+		}
+
+		for _, line := range previous {
+			if !present[line] {
+				fmt.Fprintln(w, format.paint(red, "- "+line))
+			}
+		}
+
+		previous = current
+		time.Sleep(interval)
+	}
+}
+
+// watchJSON repeatedly renders the lines returned by render, writing a JSON
+// line of type added for each line which was not there on the previous
+// refresh, all of them at first, and of type removed for each which has
+// gone. Handle says which container a line is about.
+func watchJSON(lines *JSONLines, interval time.Duration, handle func(line string) string, render func() ([]string, error)) error {
+	before := map[string]bool{}
+
+	for {
+		current, err := render()
+		if err != nil {
+			return err
+		}
+
+		present := map[string]bool{}
+		for _, line := range current {
+			present[line] = true
+
+			if !before[line] {
+				lines.Write(handle(line), "added", line)
+			}
+		}
+
+		for line := range before {
+			if !present[line] {
+				lines.Write(handle(line), "removed", line)
+			}
+		}
+
+		before = present
+		time.Sleep(interval)
+	}
+}
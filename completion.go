@@ -0,0 +1,529 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/codegangsta/cli"
+)
+
+// completionFlag describes a flag for a completion script.
+type completionFlag struct {
+	Names      []string
+	Usage      string
+	TakesValue bool
+
+	// Values names what the flag's values are completed from, such as
+	// rootfses, if not files.
+	Values string
+}
+
+// completionCommand describes a command for a completion script.
+type completionCommand struct {
+	Name        string
+	Usage       string
+	Flags       []completionFlag
+	Subcommands []completionCommand
+	TakesHandle bool
+
+	// TakesPID is set for commands which take a PID after the handle,
+	// completed from the processes gaol knows of in the container.
+	TakesPID bool
+}
+
+// describeFlag returns the names of a flag, its usage and whether it takes a
+// value. Flags of types gaol does not use are described as having no names.
+func describeFlag(flag cli.Flag) completionFlag {
+	var names string
+	var described completionFlag
+
+	switch f := flag.(type) {
+	case cli.StringFlag:
+		names, described.Usage, described.TakesValue = f.Name, f.Usage, true
+	case cli.IntFlag:
+		names, described.Usage, described.TakesValue = f.Name, f.Usage, true
+	case cli.DurationFlag:
+		names, described.Usage, described.TakesValue = f.Name, f.Usage, true
+	case cli.Float64Flag:
+		names, described.Usage, described.TakesValue = f.Name, f.Usage, true
+	case cli.StringSliceFlag:
+		names, described.Usage, described.TakesValue = f.Name, f.Usage, true
+	case cli.BoolFlag:
+		names, described.Usage = f.Name, f.Usage
+	case cli.BoolTFlag:
+		names, described.Usage = f.Name, f.Usage
+	default:
+		return described
+	}
+
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			described.Names = append(described.Names, name)
+		}
+	}
+
+	if len(described.Names) > 0 {
+		described.Values = flagValues[described.Names[0]]
+	}
+
+	return described
+}
+
+// flagValues are the flags whose values are completed from something other
+// than files, by the name of what they are completed from.
+var flagValues = map[string]string{
+	"rootfs": "rootfses",
+}
+
+func describeFlags(flags []cli.Flag) []completionFlag {
+	described := []completionFlag{}
+	for _, flag := range flags {
+		if d := describeFlag(flag); len(d.Names) > 0 {
+			described = append(described, d)
+		}
+	}
+
+	return described
+}
+
+// takesPID are the commands which take a PID after the handle.
+var takesPID = map[string]bool{
+	"attach":        true,
+	"signal":        true,
+	"wait-for-exit": true,
+}
+
+func describeCommands(commands []cli.Command) []completionCommand {
+	described := []completionCommand{}
+	for _, command := range commands {
+		described = append(described, completionCommand{
+			Name:        command.Name,
+			Usage:       command.Usage,
+			Flags:       describeFlags(command.Flags),
+			Subcommands: describeCommands(command.Subcommands),
+			// the commands which take handles are the ones which complete them
+			TakesHandle: command.BashComplete != nil,
+			TakesPID:    takesPID[command.Name],
+		})
+	}
+
+	return described
+}
+
+// completionScript generates the completion script for the app in the given
+// shell.
+func completionScript(app *cli.App, shell string) (string, error) {
+	tmpl, found := completionTemplates[shell]
+	if !found {
+		return "", usageError(fmt.Sprintf("unknown shell %q: must be bash, zsh or fish", shell))
+	}
+
+	var script bytes.Buffer
+	err := tmpl.Execute(&script, map[string]interface{}{
+		"Name":        app.Name,
+		"GlobalFlags": describeFlags(app.Flags),
+		"Commands":    describeCommands(app.Commands),
+	})
+
+	return script.String(), err
+}
+
+// dashed renders a flag name as it is typed on the command line.
+func dashed(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+
+	return "--" + name
+}
+
+var completionFuncs = template.FuncMap{
+	// words lists the names of every flag, dashed, separated by spaces
+	"words": func(flags []completionFlag) string {
+		words := []string{}
+		for _, flag := range flags {
+			for _, name := range flag.Names {
+				words = append(words, dashed(name))
+			}
+		}
+
+		return strings.Join(words, " ")
+	},
+
+	// valueFlags lists the names of the flags which take values as a case
+	// pattern
+	"valueFlags": func(flags []completionFlag) string {
+		words := []string{}
+		for _, flag := range flags {
+			if !flag.TakesValue {
+				continue
+			}
+
+			for _, name := range flag.Names {
+				words = append(words, dashed(name))
+			}
+		}
+
+		if len(words) == 0 {
+			return "--"
+		}
+
+		return strings.Join(words, "|")
+	},
+
+	// fileFlags lists the names of the flags whose values are completed from
+	// files as a case pattern
+	"fileFlags": func(flags []completionFlag) string {
+		words := []string{}
+		for _, flag := range flags {
+			if !flag.TakesValue || flag.Values != "" {
+				continue
+			}
+
+			for _, name := range flag.Names {
+				words = append(words, dashed(name))
+			}
+		}
+
+		if len(words) == 0 {
+			return "--"
+		}
+
+		return strings.Join(words, "|")
+	},
+
+	// completedFlags lists the names of the flags whose values are completed
+	// from values as a case pattern, or nothing if there are none
+	"completedFlags": func(flags []completionFlag, values string) string {
+		words := []string{}
+		for _, flag := range flags {
+			if flag.Values != values {
+				continue
+			}
+
+			for _, name := range flag.Names {
+				words = append(words, dashed(name))
+			}
+		}
+
+		return strings.Join(words, "|")
+	},
+
+	"commandNames": func(commands []completionCommand) string {
+		names := []string{}
+		for _, command := range commands {
+			names = append(names, command.Name)
+		}
+
+		return strings.Join(names, " ")
+	},
+
+	// zshSpecs renders a flag as _arguments specs, one per name, each
+	// excluding the others
+	"zshSpecs": func(app string, flag completionFlag) []string {
+		usage := strings.NewReplacer(`'`, `'\''`, `[`, `\[`, `]`, `\]`, `:`, `\:`).Replace(flag.Usage)
+
+		exclusions := ""
+		if len(flag.Names) > 1 {
+			dashedNames := []string{}
+			for _, name := range flag.Names {
+				dashedNames = append(dashedNames, dashed(name))
+			}
+
+			exclusions = "(" + strings.Join(dashedNames, " ") + ")"
+		}
+
+		specs := []string{}
+		for _, name := range flag.Names {
+			spec := "'" + exclusions + dashed(name) + "[" + usage + "]"
+			if flag.Values != "" {
+				spec += ":" + flag.Names[0] + ":_" + app + "_" + flag.Values
+			} else if flag.TakesValue {
+				spec += ":" + flag.Names[0] + ":_files"
+			}
+
+			specs = append(specs, spec+"'")
+		}
+
+		return specs
+	},
+
+	// quote quotes s for a single quoted shell string
+	"quote": func(s string) string {
+		return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+	},
+
+	"zshDescribe": func(command completionCommand) string {
+		return "'" + strings.Replace(strings.Replace(command.Name, ":", `\:`, -1)+":"+command.Usage, "'", `'\''`, -1) + "'"
+	},
+
+	"fishFlag": func(app string, flag completionFlag) string {
+		parts := []string{}
+		for _, name := range flag.Names {
+			if len(name) == 1 {
+				parts = append(parts, "-s "+name)
+			} else {
+				parts = append(parts, "-l "+name)
+			}
+		}
+
+		if flag.TakesValue {
+			parts = append(parts, "-r")
+		}
+
+		if flag.Values != "" {
+			parts = append(parts, "-a '(__"+app+"_"+flag.Values+")'")
+		}
+
+		return strings.Join(parts, " ")
+	},
+}
+
+var completionTemplates = map[string]*template.Template{
+	"bash": template.Must(template.New("bash").Funcs(completionFuncs).Parse(bashCompletion)),
+	"zsh":  template.Must(template.New("zsh").Funcs(completionFuncs).Parse(zshCompletion)),
+	"fish": template.Must(template.New("fish").Funcs(completionFuncs).Parse(fishCompletion)),
+}
+
+const bashCompletion = `# bash completion for {{.Name}}, generated by "{{.Name}} completion bash"
+
+# _{{.Name}}_target prints the --target given on the command line, if any, so
+# that handles are listed from the server the command will be sent to.
+_{{.Name}}_target() {
+    local i
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        case "${COMP_WORDS[i]}" in
+        -t|--target) echo "--target=${COMP_WORDS[i+1]}" ;;
+        esac
+    done
+}
+
+_{{.Name}}_handles() {
+    {{.Name}} $(_{{.Name}}_target) list --cached 2>/dev/null
+}
+
+_{{.Name}}_pids() {
+    {{.Name}} $(_{{.Name}}_target) "$1" "$2" --generate-bash-completion 2>/dev/null
+}
+
+_{{.Name}}_rootfses() {
+    {{.Name}} $(_{{.Name}}_target) rootfs ls --cached 2>/dev/null
+}
+
+_{{.Name}}() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local prev="${COMP_WORDS[COMP_CWORD-1]}"
+    local command="" i=1
+
+    while [ $i -lt $COMP_CWORD ]; do
+        case "${COMP_WORDS[i]}" in
+        {{valueFlags .GlobalFlags}}) i=$((i + 1)) ;;
+        -*) ;;
+        *) command="${COMP_WORDS[i]}"; break ;;
+        esac
+        i=$((i + 1))
+    done
+
+    if [ -z "$command" ]; then
+        case "$prev" in
+        {{valueFlags .GlobalFlags}}) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+        esac
+
+        if [[ "$cur" == -* ]]; then
+            COMPREPLY=($(compgen -W "{{words .GlobalFlags}}" -- "$cur"))
+        else
+            COMPREPLY=($(compgen -W "{{commandNames .Commands}}" -- "$cur"))
+        fi
+        return
+    fi
+
+    case "$command" in
+{{- range .Commands}}
+    {{.Name}})
+        case "$prev" in
+{{- with completedFlags .Flags "rootfses"}}
+        {{.}}) COMPREPLY=($(compgen -W "$(_{{$.Name}}_rootfses)" -- "$cur")); return ;;
+{{- end}}
+        {{fileFlags .Flags}}) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+        esac
+
+        if [[ "$cur" == -* ]]; then
+            COMPREPLY=($(compgen -W "{{words .Flags}}" -- "$cur"))
+{{- if .Subcommands}}
+        elif [ $((COMP_CWORD - i)) -eq 1 ]; then
+            COMPREPLY=($(compgen -W "{{commandNames .Subcommands}}" -- "$cur"))
+{{- end}}
+{{- if .TakesPID}}
+        elif [ $COMP_CWORD -gt $((i + 1)) ] && [[ "$prev" != -* ]]; then
+            COMPREPLY=($(compgen -W "$(_{{$.Name}}_pids {{.Name}} "$prev")" -- "$cur"))
+{{- end}}
+{{- if .TakesHandle}}
+        else
+            COMPREPLY=($(compgen -W "$(_{{$.Name}}_handles)" -- "$cur"))
+{{- end}}
+        fi
+        ;;
+{{- end}}
+    esac
+}
+
+complete -o default -F _{{.Name}} {{.Name}}
+`
+
+const zshCompletion = `#compdef {{.Name}}
+# zsh completion for {{.Name}}, generated by "{{.Name}} completion zsh"
+
+_{{.Name}}_handles() {
+    local target=${opt_args[--target]:-${opt_args[-t]}}
+    local -a handles
+    handles=(${(f)"$({{.Name}} ${target:+--target=$target} list --cached 2>/dev/null)"})
+    _describe -t handles 'container handle' handles
+}
+
+_{{.Name}}_rootfses() {
+    local target=${opt_args[--target]:-${opt_args[-t]}}
+    local -a rootfses
+    rootfses=(${(f)"$({{.Name}} ${target:+--target=$target} rootfs ls --cached 2>/dev/null)"})
+    _describe -t rootfses 'rootfs' rootfses
+}
+
+_{{.Name}}_pids() {
+    local target=${opt_args[--target]:-${opt_args[-t]}}
+    local -a pids
+    pids=(${(f)"$({{.Name}} ${target:+--target=$target} $words[1] $line[1] --generate-bash-completion 2>/dev/null)"})
+    _describe -t pids 'process id' pids
+}
+
+_{{.Name}}() {
+    local curcontext="$curcontext" state line
+    typeset -A opt_args
+
+    local -a commands
+    commands=(
+{{- range .Commands}}
+        {{zshDescribe .}}
+{{- end}}
+    )
+
+    _arguments -C \
+{{- range .GlobalFlags}}{{range zshSpecs $.Name .}}
+        {{.}} \
+{{- end}}{{end}}
+        '1: :->command' \
+        '*:: :->args'
+
+    case $state in
+    command)
+        _describe -t commands '{{.Name}} command' commands
+        ;;
+    args)
+        case $line[1] in
+{{- range .Commands}}
+        {{.Name}})
+{{- if .Subcommands}}
+            local -a subcommands
+            subcommands=(
+{{- range .Subcommands}}
+                {{zshDescribe .}}
+{{- end}}
+            )
+            _describe -t commands '{{.Name}} command' subcommands
+{{- else}}
+            _arguments \
+{{- range .Flags}}{{range zshSpecs $.Name .}}
+                {{.}} \
+{{- end}}{{end}}
+{{- if .TakesPID}}
+                '1:handle:_{{$.Name}}_handles' \
+                '2:pid:_{{$.Name}}_pids'
+{{- else if .TakesHandle}}
+                '*:handle:_{{$.Name}}_handles'
+{{- else}}
+                '*:argument:_files'
+{{- end}}
+{{- end}}
+            ;;
+{{- end}}
+        esac
+        ;;
+    esac
+}
+
+_{{.Name}} "$@"
+`
+
+const fishCompletion = `# fish completion for {{.Name}}, generated by "{{.Name}} completion fish"
+
+function __{{.Name}}_handles
+    set -l target
+    set -l tokens (commandline -opc)
+    for i in (seq (count $tokens))
+        switch $tokens[$i]
+            case -t --target
+                set target --target=$tokens[(math $i + 1)]
+        end
+    end
+    {{.Name}} $target list --cached 2>/dev/null
+end
+
+function __{{.Name}}_rootfses
+    set -l target
+    set -l tokens (commandline -opc)
+    for i in (seq (count $tokens))
+        switch $tokens[$i]
+            case -t --target
+                set target --target=$tokens[(math $i + 1)]
+        end
+    end
+    {{.Name}} $target rootfs ls --cached 2>/dev/null
+end
+
+# __{{.Name}}_pids completes the PIDs in the container given to the command
+# in $argv[1] once its handle, and nothing else, has been given
+function __{{.Name}}_pids
+    set -l target
+    set -l args
+    set -l tokens (commandline -opc)
+    set -l command (contains -i -- $argv[1] $tokens)
+    for i in (seq 2 (count $tokens))
+        switch $tokens[$i]
+            case -t --target
+                set target --target=$tokens[(math $i + 1)]
+            case '-*'
+            case '*'
+                if test $i -gt $command
+                    set args $args $tokens[$i]
+                end
+        end
+    end
+    if test (count $args) -eq 1
+        {{.Name}} $target $argv[1] $args --generate-bash-completion 2>/dev/null
+    end
+end
+end
+
+complete -c {{.Name}} -f
+{{- range .GlobalFlags}}
+complete -c {{$.Name}} -n __fish_use_subcommand {{fishFlag $.Name .}} -d {{quote .Usage}}
+{{- end}}
+{{- range .Commands}}
+complete -c {{$.Name}} -n __fish_use_subcommand -a {{.Name}} -d {{quote .Usage}}
+{{- end}}
+{{- range .Commands}}
+{{- $command := .Name}}
+{{- range .Flags}}
+complete -c {{$.Name}} -n '__fish_seen_subcommand_from {{$command}}' {{fishFlag $.Name .}} -d {{quote .Usage}}
+{{- end}}
+{{- if .Subcommands}}
+complete -c {{$.Name}} -n '__fish_seen_subcommand_from {{.Name}}; and not __fish_seen_subcommand_from {{commandNames .Subcommands}}' -a '{{commandNames .Subcommands}}'
+{{- end}}
+{{- if .TakesHandle}}
+complete -c {{$.Name}} -n '__fish_seen_subcommand_from {{.Name}}' -a '(__{{$.Name}}_handles)'
+{{- end}}
+{{- if .TakesPID}}
+complete -c {{$.Name}} -n '__fish_seen_subcommand_from {{.Name}}' -a '(__{{$.Name}}_pids {{.Name}})'
+{{- end}}
+{{- end}}
+`
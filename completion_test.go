@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestCompletionScripts(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		res := runGaol(t, new(fakes.FakeClient), "completion", shell)
+		if res.code != 0 {
+			t.Errorf("%s: exited %d: %s", shell, res.code, res.stderr)
+			continue
+		}
+
+		for _, want := range []string{"stream-in", "rootfs", "_gaol_handles", "_gaol_rootfses"} {
+			if !strings.Contains(res.stdout, want) {
+				t.Errorf("%s: script does not contain %q", shell, want)
+			}
+		}
+
+		if shell != "bash" {
+			continue
+		}
+
+		if _, err := exec.LookPath("bash"); err != nil {
+			continue
+		}
+
+		check := exec.Command("bash", "-n")
+		check.Stdin = strings.NewReader(res.stdout)
+		if output, err := check.CombinedOutput(); err != nil {
+			t.Errorf("bash script is invalid: %s", output)
+		}
+	}
+
+	if res := runGaol(t, new(fakes.FakeClient), "completion", "ksh"); res.code != exitUsage {
+		t.Errorf("exited %d for an unknown shell, want %d", res.code, exitUsage)
+	}
+}
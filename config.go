@@ -0,0 +1,481 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"gopkg.in/yaml.v2"
+)
+
+const defaultTarget = "localhost:7777"
+
+// config is the contents of ~/.gaol/config.yml, which gives names to the
+// servers commands can be sent to.
+type config struct {
+	Current string                  `yaml:"current,omitempty"`
+	Targets map[string]targetConfig `yaml:"targets,omitempty"`
+
+	// Handles holds the container chosen with gaol use on each target, by
+	// address, for commands given no handle.
+	Handles map[string]string `yaml:"handles,omitempty"`
+
+	// Templates are the settings given to containers created with
+	// --template, by name.
+	Templates map[string]createTemplate `yaml:"templates,omitempty"`
+}
+
+// targetConfig describes a named server along with the defaults to use for
+// the commands which are sent to it.
+type targetConfig struct {
+	Address string `yaml:"address"`
+	RootFS  string `yaml:"rootfs,omitempty"`
+
+	// RootFSDir is the directory on the target's host holding its rootfses,
+	// which rootfs ls lists.
+	RootFSDir string `yaml:"rootfs_dir,omitempty"`
+
+	// CACert, ClientCert and ClientKey are paths to PEM files. Giving any
+	// of them connects to the target over TLS.
+	CACert     string `yaml:"ca_cert,omitempty"`
+	ClientCert string `yaml:"client_cert,omitempty"`
+	ClientKey  string `yaml:"client_key,omitempty"`
+
+	// Via is an SSH jump host, as user@host[:port], through which the
+	// target is reached.
+	Via string `yaml:"via,omitempty"`
+
+	// Defaults holds the default values of command flags, keyed by command
+	// and then by flag name.
+	Defaults map[string]map[string]string `yaml:"defaults,omitempty"`
+
+	// Protected targets are asked about before destroying any container.
+	Protected bool `yaml:"protected,omitempty"`
+
+	// Privileged is deny for targets on which --privileged may not be
+	// given; it is allowed otherwise.
+	Privileged string `yaml:"privileged,omitempty"`
+}
+
+// allowsPrivileged is whether privileged containers and processes may be
+// asked for on the target.
+func (target targetConfig) allowsPrivileged() bool {
+	return target.Privileged != "deny"
+}
+
+func configPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".gaol", "config.yml")
+}
+
+// loadConfig reads the config file. A missing file is an empty config.
+func loadConfig() (*config, error) {
+	cfg := &config{}
+
+	contents, err := ioutil.ReadFile(configPath())
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(contents, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %s", configPath(), err)
+	}
+
+	return cfg, nil
+}
+
+func (cfg *config) save() error {
+	contents, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(configPath()), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(configPath(), contents, 0600)
+}
+
+// Names returns the names of the configured targets in order.
+func (cfg *config) Names() []string {
+	names := []string{}
+	for name := range cfg.Targets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// resolve finds the target to send commands to. The requested target may be
+// the name of a configured target or an address; if none is requested the
+// config's current target is used, falling back to the local server.
+func (cfg *config) resolve(requested string) (targetConfig, error) {
+	if requested == "" {
+		if cfg.Current == "" {
+			return targetConfig{Address: defaultTarget}, nil
+		}
+
+		requested = cfg.Current
+		if _, found := cfg.Targets[requested]; !found {
+			return targetConfig{}, fmt.Errorf("current target %q is not configured", requested)
+		}
+	}
+
+	if target, found := cfg.Targets[requested]; found {
+		return target, nil
+	}
+
+	return targetConfig{Address: requested}, nil
+}
+
+// currentTarget returns the server selected with --target (or GAOL_TARGET),
+// or by the config file, with any settings given as flags applied.
+func currentTarget(c *cli.Context) targetConfig {
+	cfg, err := loadConfig()
+	failIf(err)
+
+	target, err := cfg.resolve(c.GlobalString("target"))
+	failIf(err)
+
+	if caCert := c.GlobalString("ca-cert"); caCert != "" {
+		target.CACert = caCert
+	}
+
+	if clientCert := c.GlobalString("client-cert"); clientCert != "" {
+		target.ClientCert = clientCert
+	}
+
+	if clientKey := c.GlobalString("client-key"); clientKey != "" {
+		target.ClientKey = clientKey
+	}
+
+	if via := c.GlobalString("via"); via != "" {
+		target.Via = via
+	}
+
+	return target
+}
+
+// skipsTargetDefaults are the commands run without the target's defaults,
+// so that those which are wrong cannot stop them being seen or fixed.
+var skipsTargetDefaults = map[string]bool{"target": true, "help": true, "h": true, "completion": true}
+
+// applyConfiguredDefaults applies the defaults of the current target, warning
+// about those which cannot be applied rather than failing. A config which
+// cannot be loaded is left for the commands which need it to fail with.
+func applyConfiguredDefaults(c *cli.Context) {
+	if skipsTargetDefaults[c.Args().First()] {
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return
+	}
+
+	target, err := cfg.resolve(c.GlobalString("target"))
+	if err != nil {
+		return
+	}
+
+	if err := applyTargetDefaults(c.App, target); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: ignoring defaults:", err)
+	}
+}
+
+// applyTargetDefaults makes the target's defaults the default values of the
+// app's command flags, so that they show up in help and can still be
+// overridden on the command line. The defaults of list flags, such as
+// run.env, are comma-separated and added to by those given on the command
+// line. Defaults which cannot be applied are skipped, and the first of them
+// returned.
+func applyTargetDefaults(app *cli.App, target targetConfig) error {
+	defaults := map[string]map[string]string{}
+	for command, flags := range target.Defaults {
+		defaults[command] = flags
+	}
+
+	if target.RootFS != "" {
+		create := map[string]string{"rootfs": target.RootFS}
+		for name, value := range defaults["create"] {
+			create[name] = value
+		}
+		defaults["create"] = create
+	}
+
+	var first error
+	for commandName, flags := range defaults {
+		command := app.Command(commandName)
+		if command == nil {
+			if first == nil {
+				first = fmt.Errorf("unknown command %q in defaults", commandName)
+			}
+			continue
+		}
+
+		for flagName, value := range flags {
+			if err := setFlagDefault(command, flagName, value); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+
+	return first
+}
+
+func setFlagDefault(command *cli.Command, name string, value string) error {
+	for i, flag := range command.Flags {
+		if !hasFlagName(flag, name) {
+			continue
+		}
+
+		switch f := flag.(type) {
+		case cli.StringFlag:
+			f.Value = value
+			command.Flags[i] = f
+		case cli.IntFlag:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid default for %s --%s: %s", command.Name, name, err)
+			}
+			f.Value = n
+			command.Flags[i] = f
+		case cli.DurationFlag:
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid default for %s --%s: %s", command.Name, name, err)
+			}
+			f.Value = d
+			command.Flags[i] = f
+		case cli.StringSliceFlag:
+			values := &cli.StringSlice{}
+			for _, v := range strings.Split(value, ",") {
+				values.Set(v)
+			}
+			f.Value = values
+			command.Flags[i] = f
+		case cli.BoolFlag:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid default for %s --%s: %s", command.Name, name, err)
+			}
+			if b {
+				command.Flags[i] = cli.BoolTFlag{Name: f.Name, Usage: f.Usage, EnvVar: f.EnvVar}
+			}
+		default:
+			return fmt.Errorf("%s --%s cannot be given a default", command.Name, name)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown flag %s --%s in defaults", command.Name, name)
+}
+
+func hasFlagName(flag cli.Flag, name string) bool {
+	for _, n := range describeFlag(flag).Names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseDefaults turns a list of command.flag=value settings into defaults.
+func parseDefaults(settings []string) (map[string]map[string]string, error) {
+	defaults := map[string]map[string]string{}
+
+	for _, setting := range settings {
+		kv := strings.SplitN(setting, "=", 2)
+		key := strings.SplitN(kv[0], ".", 2)
+		if len(kv) != 2 || len(key) != 2 || key[0] == "" || key[1] == "" {
+			return nil, fmt.Errorf("invalid default %q: must be command.flag=value", setting)
+		}
+
+		if defaults[key[0]] == nil {
+			defaults[key[0]] = map[string]string{}
+		}
+
+		defaults[key[0]][key[1]] = kv[1]
+	}
+
+	return defaults, nil
+}
+
+// setTarget adds the named target or, if it already exists, changes the
+// settings which are given in changes.
+func setTarget(name string, changes targetConfig) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if cfg.Targets == nil {
+		cfg.Targets = map[string]targetConfig{}
+	}
+
+	target := cfg.Targets[name]
+	target.Address = changes.Address
+
+	if changes.RootFS != "" {
+		target.RootFS = changes.RootFS
+	}
+
+	if changes.RootFSDir != "" {
+		target.RootFSDir = changes.RootFSDir
+	}
+
+	if changes.Via != "" {
+		target.Via = changes.Via
+	}
+
+	if changes.Privileged != "" {
+		target.Privileged = changes.Privileged
+	}
+
+	for _, path := range []struct{ from, to *string }{
+		{&changes.CACert, &target.CACert},
+		{&changes.ClientCert, &target.ClientCert},
+		{&changes.ClientKey, &target.ClientKey},
+	} {
+		if *path.from == "" {
+			continue
+		}
+
+		// the config is used from any directory
+		absolute, err := filepath.Abs(*path.from)
+		if err != nil {
+			return err
+		}
+
+		*path.to = absolute
+	}
+
+	for command, flags := range changes.Defaults {
+		if target.Defaults == nil {
+			target.Defaults = map[string]map[string]string{}
+		}
+
+		if target.Defaults[command] == nil {
+			target.Defaults[command] = map[string]string{}
+		}
+
+		for flag, value := range flags {
+			target.Defaults[command][flag] = value
+		}
+	}
+
+	cfg.Targets[name] = target
+
+	return cfg.save()
+}
+
+func useTarget(name string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if _, found := cfg.Targets[name]; !found {
+		return fmt.Errorf("unknown target %q", name)
+	}
+
+	cfg.Current = name
+
+	return cfg.save()
+}
+
+// useHandle makes the container the current one on the target, or forgets
+// the current one if handle is empty.
+func useHandle(address string, handle string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if handle == "" {
+		delete(cfg.Handles, address)
+		return cfg.save()
+	}
+
+	if cfg.Handles == nil {
+		cfg.Handles = map[string]string{}
+	}
+
+	cfg.Handles[address] = handle
+
+	return cfg.save()
+}
+
+// protectTarget marks the named target as protected, or not.
+func protectTarget(name string, protected bool) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	target, found := cfg.Targets[name]
+	if !found {
+		return fmt.Errorf("unknown target %q", name)
+	}
+
+	target.Protected = protected
+	cfg.Targets[name] = target
+
+	return cfg.save()
+}
+
+func removeTarget(name string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if _, found := cfg.Targets[name]; !found {
+		return fmt.Errorf("unknown target %q", name)
+	}
+
+	delete(cfg.Targets, name)
+	if cfg.Current == name {
+		cfg.Current = ""
+	}
+
+	return cfg.save()
+}
+
+func listTargets() ([]string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+	for _, name := range cfg.Names() {
+		marker := " "
+		if name == cfg.Current {
+			marker = "*"
+		}
+
+		line := fmt.Sprintf("%s %s\t%s", marker, name, cfg.Targets[name].Address)
+		if cfg.Targets[name].Protected {
+			line += "\tprotected"
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestResolve(t *testing.T) {
+	cfg := &config{
+		Current: "staging",
+		Targets: map[string]targetConfig{
+			"staging": {Address: "staging.example.com:7777"},
+			"local":   {Address: "unix:///var/run/garden.sock"},
+		},
+	}
+
+	tests := []struct {
+		requested string
+		address   string
+	}{
+		{"", "staging.example.com:7777"},
+		{"local", "unix:///var/run/garden.sock"},
+		{"10.0.0.1:7777", "10.0.0.1:7777"},
+	}
+
+	for _, test := range tests {
+		target, err := cfg.resolve(test.requested)
+		if err != nil {
+			t.Errorf("%q: %s", test.requested, err)
+			continue
+		}
+
+		if target.Address != test.address {
+			t.Errorf("%q resolved to %q, want %q", test.requested, target.Address, test.address)
+		}
+	}
+
+	empty := &config{}
+	if target, err := empty.resolve(""); err != nil || target.Address != defaultTarget {
+		t.Errorf("resolved to %q (%v) without a config, want %q", target.Address, err, defaultTarget)
+	}
+
+	missing := &config{Current: "gone"}
+	if _, err := missing.resolve(""); err == nil {
+		t.Error("resolved a current target which is not configured")
+	}
+}
+
+func TestParseDefaults(t *testing.T) {
+	defaults, err := parseDefaults([]string{"create.rootfs=/a=b", "create.privileged=true", "run.user=vcap"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]map[string]string{
+		"create": {"rootfs": "/a=b", "privileged": "true"},
+		"run":    {"user": "vcap"},
+	}
+
+	if !reflect.DeepEqual(defaults, want) {
+		t.Errorf("parsed %v, want %v", defaults, want)
+	}
+
+	for _, invalid := range []string{"create", "create=x", "create.=x", ".rootfs=x"} {
+		if _, err := parseDefaults([]string{invalid}); err == nil {
+			t.Errorf("parsed invalid default %q", invalid)
+		}
+	}
+}
+
+func TestApplyTargetDefaultsRejectsUnknownFlags(t *testing.T) {
+	tests := []map[string]map[string]string{
+		{"no-such-command": {"rootfs": "/"}},
+		{"create": {"no-such-flag": "x"}},
+		{"create": {"grace": "forever"}},
+		{"create": {"privileged": "maybe"}},
+	}
+
+	for _, defaults := range tests {
+		if err := applyTargetDefaults(newApp(), targetConfig{Defaults: defaults}); err == nil {
+			t.Errorf("applied invalid defaults %v", defaults)
+		}
+	}
+}
+
+func TestTargets(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	defer os.Setenv("HOME", originalHome)
+
+	if err := setTarget("a", targetConfig{Address: "a:7777", RootFS: "/rootfs"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := setTarget("a", targetConfig{Address: "b:7777"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := useTarget("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := useTarget("missing"); err == nil {
+		t.Error("used a target which is not configured")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := targetConfig{Address: "b:7777", RootFS: "/rootfs"}
+	if cfg.Current != "a" || !reflect.DeepEqual(cfg.Targets["a"], want) {
+		t.Errorf("saved %#v, want the current target to be %#v", cfg, want)
+	}
+
+	lines, err := listTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(lines, []string{"* a\tb:7777"}) {
+		t.Errorf("listed %q", lines)
+	}
+
+	if err := protectTarget("a", true); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err = listTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(lines, []string{"* a\tb:7777\tprotected"}) {
+		t.Errorf("listed %q after protecting the target", lines)
+	}
+
+	if err := removeTarget("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err = loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Current != "" || len(cfg.Targets) != 0 {
+		t.Errorf("still have %#v after removing the target", cfg)
+	}
+}
+
+func TestBadDefaultsWarn(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	writeConfig(t, home, `current: x
+targets:
+  x:
+    address: x:7777
+    defaults:
+      create: {nosuchflag: "1", grace: 2h}
+  y:
+    address: y:7777
+`)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.CreateReturns(fakeContainer("a"), nil)
+
+	res := runGaolIn(t, home, fakeClient, "create", "--no-stamp")
+	if res.code != 0 {
+		t.Fatalf("create exited %d: %s", res.code, res.stderr)
+	}
+
+	if !strings.Contains(res.stderr, "warning: ignoring defaults: unknown flag create --nosuchflag in defaults") {
+		t.Errorf("printed %q, want a warning about the bad default", res.stderr)
+	}
+
+	if grace := fakeClient.CreateArgsForCall(0).GraceTime; grace != 2*time.Hour {
+		t.Errorf("created with grace %s, want the good default applied all the same", grace)
+	}
+
+	// the target can be changed without hearing about its defaults
+	res = runGaolIn(t, home, fakeClient, "target", "use", "y")
+	if res.code != 0 || res.stderr != "" {
+		t.Errorf("target use exited %d: %q", res.code, res.stderr)
+	}
+}
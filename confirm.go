@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/codegangsta/cli"
+)
+
+// openAnswers opens where confirm reads answers from, which the tests
+// replace.
+var openAnswers = func() (io.ReadCloser, error) {
+	return os.Open(terminalInput)
+}
+
+// confirm asks a yes or no question on stderr and reads the answer from the
+// terminal, rather than stdin, which may be handles piped in. Anything but
+// yes is taken as no.
+func confirm(question string) (bool, error) {
+	answers, err := openAnswers()
+	if err != nil {
+		return false, errors.New("cannot ask for confirmation without a terminal: pass --force to go ahead without asking")
+	}
+	defer answers.Close()
+
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(answers).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(os.Stderr)
+		return false, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// confirmDestroy asks before destroying the containers, after listing them,
+// when asked to always or when there are more than --confirm-above of them
+// or the target is protected. Being told no fails; --force skips asking.
+func confirmDestroy(c *cli.Context, handles []string, always bool) {
+	if c.Bool("force") {
+		return
+	}
+
+	target := currentTarget(c)
+
+	if !always && !target.Protected && len(handles) <= c.GlobalInt("confirm-above") {
+		return
+	}
+
+	for _, handle := range handles {
+		fmt.Fprintln(os.Stderr, handle)
+	}
+
+	question := fmt.Sprintf("destroy these %d containers?", len(handles))
+	if target.Protected {
+		question = fmt.Sprintf("destroy these %d containers on protected target %s?", len(handles), target.Address)
+	}
+
+	confirmed, err := confirm(question)
+	failIf(err)
+
+	if !confirmed {
+		fail(errors.New("not destroying anything"))
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+// terminalInput is the terminal gaol was run from, whatever stdin is.
+const terminalInput = "/dev/tty"
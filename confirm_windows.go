@@ -0,0 +1,4 @@
+package main
+
+// terminalInput is the console gaol was run from, whatever stdin is.
+const terminalInput = "CONIN$"
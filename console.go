@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/cli"
+	"github.com/mattn/go-shellwords"
+	"github.com/peterh/liner"
+
+	gclient "github.com/cloudfoundry-incubator/garden/client"
+
+	"github.com/xoebus/gaol/commands"
+)
+
+const consoleHelp = `Any gaol command can be run without the "gaol" prefix. Commands which take
+a container handle use the current container when one has been chosen.
+
+  use [handle]     choose the current container (or forget it)
+  target [name]    show or change the server commands are sent to
+  help             show this help and the list of gaol commands
+  exit             leave the console
+`
+
+// console is an interactive prompt which runs gaol commands against a
+// target and, optionally, a current container.
+type console struct {
+	app     *cli.App
+	env     []string
+	started string
+	target  string
+	handle  string
+}
+
+// targetSettings are the variables of gaolEnv which belong to the target the
+// console started with, rather than to whichever it is changed to.
+var targetSettings = map[string]bool{
+	"GAOL_TARGET":      true,
+	"GAOL_CA_CERT":     true,
+	"GAOL_CLIENT_CERT": true,
+	"GAOL_CLIENT_KEY":  true,
+	"GAOL_VIA":         true,
+}
+
+func runConsole(c *cli.Context, handle string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	target := c.GlobalString("target")
+	if target == "" {
+		target = cfg.Current
+	}
+
+	if target == "" {
+		target = defaultTarget
+	}
+
+	// the commands typed at the console are the user's, so are kept in the
+	// history unless it was turned off
+	env := []string{}
+	for _, kv := range gaolEnv(c) {
+		if !strings.HasPrefix(kv, "GAOL_NO_HISTORY=") {
+			env = append(env, kv)
+		}
+	}
+
+	if c.GlobalBool("no-history") {
+		env = append(env, "GAOL_NO_HISTORY=true")
+	}
+
+	con := &console{
+		app:     c.App,
+		env:     env,
+		started: target,
+		target:  target,
+		handle:  handle,
+	}
+
+	line := liner.NewLiner()
+	defer line.Close()
+	defer commands.AtExit(func() { line.Close() })()
+
+	line.SetCtrlCAborts(true)
+	line.SetCompleter(con.complete)
+
+	historyPath := filepath.Join(os.Getenv("HOME"), ".gaol", "console_history")
+	if history, err := os.Open(historyPath); err == nil {
+		line.ReadHistory(history)
+		history.Close()
+	}
+
+	defer func() {
+		os.MkdirAll(filepath.Dir(historyPath), 0700)
+		if history, err := os.Create(historyPath); err == nil {
+			line.WriteHistory(history)
+			history.Close()
+		}
+	}()
+
+	for {
+		input, err := line.Prompt(con.prompt())
+		if err == io.EOF {
+			fmt.Println()
+			return nil
+		}
+
+		if err == liner.ErrPromptAborted {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if strings.TrimSpace(input) == "" {
+			continue
+		}
+
+		line.AppendHistory(input)
+
+		args, err := shellwords.Parse(input)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed:", err)
+			continue
+		}
+
+		if con.builtin(args) {
+			if args[0] == "exit" || args[0] == "quit" {
+				return nil
+			}
+
+			continue
+		}
+
+		con.run(args)
+	}
+}
+
+func (con *console) prompt() string {
+	if con.handle != "" {
+		return fmt.Sprintf("gaol %s [%s]> ", con.target, con.handle)
+	}
+
+	return fmt.Sprintf("gaol %s> ", con.target)
+}
+
+// builtin handles the commands which only make sense inside the console. It
+// reports whether the command was one of them.
+func (con *console) builtin(args []string) bool {
+	switch args[0] {
+	case "exit", "quit":
+		return true
+	case "use":
+		con.handle = ""
+		if len(args) > 1 {
+			con.handle = args[1]
+		}
+		return true
+	case "target":
+		if len(args) > 1 && con.targetSubcommand(args[1]) {
+			return false
+		}
+
+		if len(args) > 1 {
+			con.target = args[1]
+		} else {
+			fmt.Println(con.target)
+		}
+		return true
+	case "help":
+		if len(args) == 1 {
+			fmt.Print(consoleHelp)
+			fmt.Println()
+		}
+		return false
+	}
+
+	return false
+}
+
+// targetSubcommand reports whether name is one of the subcommands of the
+// target command, which manage the config file rather than the console.
+func (con *console) targetSubcommand(name string) bool {
+	command := con.app.Command("target")
+	if command == nil {
+		return false
+	}
+
+	for _, subcommand := range command.Subcommands {
+		if subcommand.HasName(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// takesHandle reports whether the command's first argument is a single
+// container handle. Destroy is left out so that the current container is
+// never destroyed by accident.
+func (con *console) takesHandle(name string) bool {
+	command := con.app.Command(name)
+	return command != nil && command.BashComplete != nil && command.Name != "destroy"
+}
+
+// environment is that of the commands the console runs. Once the target is
+// changed, the settings of the one the console started with are left out.
+func (con *console) environment() []string {
+	if con.target == con.started {
+		return con.env
+	}
+
+	env := []string{"GAOL_TARGET=" + con.target}
+	for _, kv := range con.env {
+		if !targetSettings[strings.SplitN(kv, "=", 2)[0]] {
+			env = append(env, kv)
+		}
+	}
+
+	return env
+}
+
+// run invokes gaol as a separate process so that commands which fail (and
+// exit) do not take the console down with them.
+func (con *console) run(args []string) {
+	if con.handle != "" && con.takesHandle(args[0]) {
+		args = append([]string{args[0], con.handle}, args[1:]...)
+	}
+
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = con.environment()
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// the command handles interrupts, which must not end the console
+	ignoreInterrupts(true)
+	defer ignoreInterrupts(false)
+
+	cmd.Run()
+}
+
+func (con *console) complete(input string) []string {
+	words := strings.Fields(input)
+	completing := !strings.HasSuffix(input, " ")
+
+	switch {
+	case len(words) == 0 || (len(words) == 1 && completing):
+		prefix := ""
+		if len(words) == 1 {
+			prefix = words[0]
+		}
+
+		names := []string{"use", "target", "exit"}
+		for _, command := range con.app.Commands {
+			names = append(names, command.Name)
+		}
+
+		return withPrefix(names, "", prefix, " ")
+	case (len(words) == 1 && !completing) || (len(words) == 2 && completing):
+		if words[0] != "use" && words[0] != "destroy" && !con.takesHandle(words[0]) {
+			return nil
+		}
+
+		prefix := ""
+		if len(words) == 2 {
+			prefix = words[1]
+		}
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return nil
+		}
+
+		target, err := cfg.resolve(con.target)
+		if err != nil {
+			return nil
+		}
+
+		handles, cached, err := readHandleCache(target.Address)
+		if err != nil {
+			handles, err = listAndCacheHandles(target)
+			if err != nil {
+				return nil
+			}
+		} else if time.Since(cached) > defaultCacheTTL {
+			// the console outlives the refresh, so it can be done here
+			go listAndCacheHandles(target)
+		}
+
+		return withPrefix(handles, words[0]+" ", prefix, " ")
+	}
+
+	return nil
+}
+
+func listAndCacheHandles(target targetConfig) ([]string, error) {
+	conn, err := connect(target, connectOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	handles, err := commands.ListHandles(gclient.New(conn))
+	if err != nil {
+		return nil, err
+	}
+
+	writeHandleCache(target.Address, handles)
+	return handles, nil
+}
+
+func withPrefix(candidates []string, before, prefix, after string) []string {
+	matches := []string{}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			matches = append(matches, before+candidate+after)
+		}
+	}
+
+	return matches
+}
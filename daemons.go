@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// daemon is a gaol left running in the background, such as a detached
+// monitor or supervisor, recorded in ~/.gaol/daemons so that it can be found
+// again and stopped along with the containers it looks after.
+type daemon struct {
+	PID      int       `json:"pid"`
+	Kind     string    `json:"kind"`
+	Target   string    `json:"target"`
+	Handles  []string  `json:"handles,omitempty"`
+	Manifest string    `json:"manifest,omitempty"`
+	Log      string    `json:"log"`
+	Started  time.Time `json:"started"`
+}
+
+func daemonsDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".gaol", "daemons")
+}
+
+func daemonPath(pid int) string {
+	return filepath.Join(daemonsDir(), strconv.Itoa(pid)+".json")
+}
+
+// daemonLockPath is the lock a daemon holds for as long as it runs, which
+// tells it apart from a process which has since been given its PID.
+func daemonLockPath(pid int) string {
+	return filepath.Join(daemonsDir(), strconv.Itoa(pid)+".lock")
+}
+
+// daemonEnv is set in the environment of a daemon, which forgets itself
+// when it exits.
+const daemonEnv = "GAOL_DAEMON"
+
+// processRunning and terminateProcess are replaced by the tests, which
+// record daemons which do not exist.
+var processRunning = isRunning
+var terminateProcess = terminate
+
+// runAsDaemon takes over the lock the daemon was started holding and has
+// it forget itself when it exits, if this gaol is a daemon.
+func runAsDaemon() {
+	if os.Getenv(daemonEnv) == "" {
+		return
+	}
+
+	// anything the daemon runs is not a daemon too
+	os.Unsetenv(daemonEnv)
+
+	keepDaemonLock()
+
+	pid := os.Getpid()
+	atExit(func() { forgetDaemon(pid) })
+}
+
+// forgetDaemon removes the record of the daemon with the pid.
+func forgetDaemon(pid int) {
+	os.Remove(daemonPath(pid))
+	os.Remove(daemonLockPath(pid))
+}
+
+func recordDaemon(d daemon) error {
+	contents, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(daemonsDir(), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(daemonPath(d.PID), contents, 0600)
+}
+
+// recordStartedDaemon records the daemon, moving the lock it was started
+// holding, if any, to where it is looked for.
+func recordStartedDaemon(d daemon, lock *os.File) error {
+	if lock != nil {
+		if err := os.Rename(lock.Name(), daemonLockPath(d.PID)); err != nil {
+			return err
+		}
+	}
+
+	return recordDaemon(d)
+}
+
+type byStarted []daemon
+
+func (d byStarted) Len() int           { return len(d) }
+func (d byStarted) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d byStarted) Less(i, j int) bool { return d[i].Started.Before(d[j].Started) }
+
+// loadDaemons returns the recorded daemons which are still running, oldest
+// first, forgetting those which have exited.
+func loadDaemons() ([]daemon, error) {
+	entries, err := ioutil.ReadDir(daemonsDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	daemons := []daemon{}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		path := filepath.Join(daemonsDir(), entry.Name())
+
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var d daemon
+		if err := json.Unmarshal(contents, &d); err != nil {
+			os.Remove(path)
+			continue
+		}
+
+		if !processRunning(d) {
+			forgetDaemon(d.PID)
+			continue
+		}
+
+		daemons = append(daemons, d)
+	}
+
+	sort.Sort(byStarted(daemons))
+
+	return daemons, nil
+}
+
+// stopDaemon terminates the daemon, if it is still the process with its
+// PID, and forgets it.
+func stopDaemon(d daemon) error {
+	if !processRunning(d) {
+		forgetDaemon(d.PID)
+		return nil
+	}
+
+	if err := terminateProcess(d.PID); err != nil && processRunning(d) {
+		return err
+	}
+
+	forgetDaemon(d.PID)
+	return nil
+}
+
+// looksAfter reports whether the daemon was started for the manifest on the
+// target, or for any of the containers there.
+func (d daemon) looksAfter(address string, manifest string, handles []string) bool {
+	if d.Target != address {
+		return false
+	}
+
+	if manifest != "" && d.Manifest == manifest {
+		return true
+	}
+
+	for _, handle := range d.Handles {
+		for _, other := range handles {
+			if handle == other {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// stopDaemonsFor stops the daemons looking after the manifest or the
+// containers on the target, writing which it stopped to w.
+func stopDaemonsFor(address string, manifest string, handles []string, w io.Writer) error {
+	daemons, err := loadDaemons()
+	if err != nil {
+		return err
+	}
+
+	for _, d := range daemons {
+		if !d.looksAfter(address, manifest, handles) {
+			continue
+		}
+
+		if err := stopDaemon(d); err != nil {
+			return fmt.Errorf("stopping %s %d: %s", d.Kind, d.PID, err)
+		}
+
+		fmt.Fprintf(w, "stopped %s %d\n", d.Kind, d.PID)
+	}
+
+	return nil
+}
+
+// line is how the daemon is listed: its PID, kind, target, the containers it
+// looks after, when it started and its log.
+func (d daemon) line() string {
+	return fmt.Sprintf("%d\t%s\t%s\t%s\t%s\t%s", d.PID, d.Kind, d.Target, strings.Join(d.Handles, ","), d.Started.Format(time.RFC3339), d.Log)
+}
+
+// stopDaemons stops the daemons with the given PIDs, or all of them, writing
+// which it stopped to w. It carries on past those it could not stop,
+// returning the first error.
+func stopDaemons(pids []string, all bool, w io.Writer) error {
+	daemons, err := loadDaemons()
+	if err != nil {
+		return err
+	}
+
+	byPID := map[int]daemon{}
+	for _, d := range daemons {
+		byPID[d.PID] = d
+	}
+
+	toStop := daemons
+	if !all {
+		toStop = []daemon{}
+		for _, arg := range pids {
+			pid, err := strconv.Atoi(arg)
+			if err != nil {
+				return usageError(fmt.Sprintf("invalid pid %q", arg))
+			}
+
+			d, found := byPID[pid]
+			if !found {
+				return fmt.Errorf("no daemon running with pid %d", pid)
+			}
+
+			toStop = append(toStop, d)
+		}
+	}
+
+	var firstErr error
+	for _, d := range toStop {
+		if err := stopDaemon(d); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("stopping %s %d: %s", d.Kind, d.PID, err)
+			}
+			continue
+		}
+
+		fmt.Fprintf(w, "stopped %s %d\n", d.Kind, d.PID)
+	}
+
+	return firstErr
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+// fakeProcesses stands in for the processes of daemons, which are running
+// until they are terminated.
+func fakeProcesses(running ...int) (terminated *[]int, restore func()) {
+	alive := map[int]bool{}
+	for _, pid := range running {
+		alive[pid] = true
+	}
+
+	terminated = &[]int{}
+
+	originalRunning, originalTerminate := processRunning, terminateProcess
+	processRunning = func(d daemon) bool { return alive[d.PID] }
+	terminateProcess = func(pid int) error {
+		*terminated = append(*terminated, pid)
+		alive[pid] = false
+		return nil
+	}
+
+	return terminated, func() {
+		processRunning, terminateProcess = originalRunning, originalTerminate
+	}
+}
+
+func TestLoadDaemonsForgetsExitedOnes(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	defer os.Setenv("HOME", originalHome)
+
+	_, restore := fakeProcesses(11, 12)
+	defer restore()
+
+	now := time.Now()
+	recordDaemon(daemon{PID: 12, Kind: "monitor", Started: now})
+	recordDaemon(daemon{PID: 11, Kind: "supervisor", Started: now.Add(-time.Minute)})
+	recordDaemon(daemon{PID: 13, Kind: "supervisor", Started: now})
+
+	daemons, err := loadDaemons()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pids := []int{}
+	for _, d := range daemons {
+		pids = append(pids, d.PID)
+	}
+
+	if !reflect.DeepEqual(pids, []int{11, 12}) {
+		t.Errorf("loaded %v, want those running, oldest first", pids)
+	}
+
+	if _, err := os.Stat(daemonPath(13)); !os.IsNotExist(err) {
+		t.Error("did not forget the daemon which has exited")
+	}
+}
+
+func TestDownClean(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	terminated, restore := fakeProcesses(1, 2, 3, 4)
+	defer restore()
+
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	recordDaemon(daemon{PID: 1, Kind: "monitor", Target: "localhost:7777", Manifest: "app"})
+	recordDaemon(daemon{PID: 2, Kind: "supervisor", Target: "localhost:7777", Handles: []string{"web"}})
+	recordDaemon(daemon{PID: 3, Kind: "supervisor", Target: "localhost:7777", Handles: []string{"other"}})
+	recordDaemon(daemon{PID: 4, Kind: "supervisor", Target: "prod:7777", Handles: []string{"web"}})
+	os.Setenv("HOME", originalHome)
+
+	manifest := filepath.Join(home, "gaol.yml")
+	ioutil.WriteFile(manifest, []byte("name: app\ncontainers:\n  web: {}\n"), 0644)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(fakeContainer("web"), nil)
+
+	runGaolIn(t, home, fakeClient, "alias", "add", "site", "web")
+	runGaolIn(t, home, fakeClient, "alias", "add", "theirs", "other")
+	runGaolIn(t, home, fakeClient, "use", "web")
+
+	res := runGaolIn(t, home, fakeClient, "down", "--clean", "--file", manifest)
+	if res.code != 0 {
+		t.Fatalf("down exited %d: %s", res.code, res.stderr)
+	}
+
+	if !reflect.DeepEqual(*terminated, []int{1, 2}) {
+		t.Errorf("stopped %v, want the monitor of the manifest and the supervisor of its container", *terminated)
+	}
+
+	for _, want := range []string{"stopped monitor 1\n", "stopped supervisor 2\n", "removed alias site\n", "forgot current container web\n"} {
+		if !strings.Contains(res.stderr, want) {
+			t.Errorf("printed %q, want %q", res.stderr, want)
+		}
+	}
+
+	if res := runGaolIn(t, home, fakeClient, "alias", "list"); res.stdout != "theirs\tother\n" {
+		t.Errorf("left aliases %q", res.stdout)
+	}
+
+	if res := runGaolIn(t, home, fakeClient, "use"); res.code == 0 {
+		t.Errorf("left %q as the current container", res.stdout)
+	}
+}
+
+func TestDaemonsListAndStop(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	terminated, restore := fakeProcesses(1, 2, 3)
+	defer restore()
+
+	started := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	recordDaemon(daemon{PID: 1, Kind: "monitor", Target: "localhost:7777", Handles: []string{"web", "db"}, Manifest: "app", Log: "/logs/app.log", Started: started})
+	recordDaemon(daemon{PID: 2, Kind: "supervisor", Target: "localhost:7777", Handles: []string{"web"}, Log: "/logs/web.log", Started: started.Add(time.Hour)})
+	recordDaemon(daemon{PID: 3, Kind: "supervisor", Target: "prod:7777", Handles: []string{"api"}, Log: "/logs/api.log", Started: started.Add(2 * time.Hour)})
+	os.Setenv("HOME", originalHome)
+
+	fakeClient := new(fakes.FakeClient)
+
+	res := runGaolIn(t, home, fakeClient, "daemons", "list")
+	want := "1\tmonitor\tlocalhost:7777\tweb,db\t2015-06-01T12:00:00Z\t/logs/app.log\n" +
+		"2\tsupervisor\tlocalhost:7777\tweb\t2015-06-01T13:00:00Z\t/logs/web.log\n" +
+		"3\tsupervisor\tprod:7777\tapi\t2015-06-01T14:00:00Z\t/logs/api.log\n"
+	if res.stdout != want {
+		t.Errorf("listed %q, want %q", res.stdout, want)
+	}
+
+	res = runGaolIn(t, home, fakeClient, "daemons", "stop", "3", "1")
+	if res.code != 0 || res.stderr != "stopped supervisor 3\nstopped monitor 1\n" {
+		t.Errorf("stop exited %d: %q", res.code, res.stderr)
+	}
+
+	if res := runGaolIn(t, home, fakeClient, "daemons", "stop", "1"); res.code != 1 {
+		t.Errorf("stopping a daemon which is not running exited %d", res.code)
+	}
+
+	runGaolIn(t, home, fakeClient, "daemons", "stop", "--all")
+
+	if !reflect.DeepEqual(*terminated, []int{3, 1, 2}) {
+		t.Errorf("stopped %v", *terminated)
+	}
+
+	if res := runGaolIn(t, home, fakeClient, "daemons", "list"); res.stdout != "" {
+		t.Errorf("listed %q after stopping them all", res.stdout)
+	}
+}
+
+func TestDaemonsStopLeavesReusedPIDsAlone(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	// the daemon has exited and its PID been given to another process
+	terminated, restore := fakeProcesses()
+	defer restore()
+
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	recordDaemon(daemon{PID: 1, Kind: "monitor", Target: "localhost:7777", Manifest: "app"})
+	defer os.Setenv("HOME", originalHome)
+
+	if err := stopDaemon(daemon{PID: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*terminated) != 0 {
+		t.Errorf("terminated %v", *terminated)
+	}
+
+	if _, err := os.Stat(daemonPath(1)); !os.IsNotExist(err) {
+		t.Error("did not forget the daemon")
+	}
+}
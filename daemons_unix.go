@@ -0,0 +1,57 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"syscall"
+)
+
+// daemonLockFD is where a daemon finds the lock it was started holding.
+const daemonLockFD = 3
+
+// isRunning reports whether the daemon is running, which it is for as long
+// as it holds its lock, whatever process now has its PID.
+func isRunning(d daemon) bool {
+	file, err := os.Open(daemonLockPath(d.PID))
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_SH|syscall.LOCK_NB) == syscall.EWOULDBLOCK
+}
+
+// lockDaemon creates and locks a file for a daemon about to be started,
+// which inherits the lock and so holds it until it exits.
+func lockDaemon() (*os.File, error) {
+	if err := os.MkdirAll(daemonsDir(), 0700); err != nil {
+		return nil, err
+	}
+
+	file, err := ioutil.TempFile(daemonsDir(), "starting-")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// keepDaemonLock stops the processes the daemon runs inheriting its lock,
+// which would keep it held after the daemon exits.
+func keepDaemonLock() {
+	syscall.CloseOnExec(daemonLockFD)
+}
+
+// terminate asks the process to exit.
+func terminate(pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
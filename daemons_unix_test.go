@@ -0,0 +1,52 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestDaemonLock(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	defer os.Setenv("HOME", originalHome)
+
+	lock, err := lockDaemon()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("sleep", "60")
+	cmd.ExtraFiles = []*os.File{lock}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	lock.Close()
+
+	d := daemon{PID: cmd.Process.Pid}
+	if err := recordStartedDaemon(d, lock); err != nil {
+		t.Fatal(err)
+	}
+
+	if !isRunning(d) {
+		t.Error("the daemon holding its lock is not running")
+	}
+
+	// as if the PID of a daemon which has exited had been reused
+	if isRunning(daemon{PID: os.Getpid()}) {
+		t.Error("a process without the lock of a daemon is taken for one")
+	}
+
+	cmd.Process.Kill()
+	cmd.Wait()
+
+	if isRunning(d) {
+		t.Error("the daemon is still running once it has exited")
+	}
+}
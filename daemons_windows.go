@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+const (
+	processQueryLimitedInformation = 0x1000
+	stillActive                    = 259
+)
+
+// isRunning reports whether the daemon is running. A process given its PID
+// after it exited was created after it started, and so is told apart.
+func isRunning(d daemon) bool {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(d.PID))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(handle)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(handle, &code); err != nil || code != stillActive {
+		return false
+	}
+
+	var created, exited, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(handle, &created, &exited, &kernel, &user); err != nil {
+		return false
+	}
+
+	return !time.Unix(0, created.Nanoseconds()).After(d.Started)
+}
+
+// lockDaemon does nothing here, where daemons are told apart by when they
+// were created instead.
+func lockDaemon() (*os.File, error) {
+	return nil, nil
+}
+
+func keepDaemonLock() {}
+
+// terminate ends the process; windows has no gentler way to ask.
+func terminate(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	return process.Kill()
+}
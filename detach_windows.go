@@ -0,0 +1,9 @@
+package main
+
+import "syscall"
+
+// detachedAttr starts a process in a group of its own, so that it is not
+// interrupted along with the console gaol was run from.
+func detachedAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden"
+	gconn "github.com/cloudfoundry-incubator/garden/client/connection"
+)
+
+// dryRunConnection prints the calls which would change anything on the
+// server instead of making them. Calls which only read state are passed
+// through so that commands can still decide what they would do.
+type dryRunConnection struct {
+	gconn.Connection
+
+	out io.Writer
+	mu  sync.Mutex
+}
+
+func newDryRunConnection(conn gconn.Connection, out io.Writer) gconn.Connection {
+	return &dryRunConnection{
+		Connection: conn,
+		out:        out,
+	}
+}
+
+func (d *dryRunConnection) print(call string, args ...interface{}) {
+	d.mu.Lock()
+	fmt.Fprintln(d.out, formatCall(call, args...))
+	d.mu.Unlock()
+}
+
+// formatCall renders a call as Call(arg, arg), with the arguments as JSON.
+func formatCall(call string, args ...interface{}) string {
+	formatted := make([]string, len(args))
+	for i, arg := range args {
+		encoded, err := json.Marshal(arg)
+		if err != nil {
+			formatted[i] = fmt.Sprintf("%v", arg)
+		} else {
+			formatted[i] = string(encoded)
+		}
+	}
+
+	return fmt.Sprintf("%s(%s)", call, strings.Join(formatted, ", "))
+}
+
+func (d *dryRunConnection) Create(spec garden.ContainerSpec) (string, error) {
+	d.print("Create", spec)
+
+	if spec.Handle == "" {
+		return "<generated>", nil
+	}
+
+	return spec.Handle, nil
+}
+
+func (d *dryRunConnection) Destroy(handle string) error {
+	d.print("Destroy", handle)
+	return nil
+}
+
+func (d *dryRunConnection) Stop(handle string, kill bool) error {
+	d.print("Stop", handle, kill)
+	return nil
+}
+
+func (d *dryRunConnection) StreamIn(handle string, dstPath string, reader io.Reader) error {
+	d.print("StreamIn", handle, dstPath)
+
+	// drain the stream so that whatever is producing it can finish
+	_, err := io.Copy(ioutil.Discard, reader)
+	return err
+}
+
+func (d *dryRunConnection) LimitBandwidth(handle string, limits garden.BandwidthLimits) (garden.BandwidthLimits, error) {
+	d.print("LimitBandwidth", handle, limits)
+	return limits, nil
+}
+
+func (d *dryRunConnection) LimitCPU(handle string, limits garden.CPULimits) (garden.CPULimits, error) {
+	d.print("LimitCPU", handle, limits)
+	return limits, nil
+}
+
+func (d *dryRunConnection) LimitDisk(handle string, limits garden.DiskLimits) (garden.DiskLimits, error) {
+	d.print("LimitDisk", handle, limits)
+	return limits, nil
+}
+
+func (d *dryRunConnection) LimitMemory(handle string, limits garden.MemoryLimits) (garden.MemoryLimits, error) {
+	d.print("LimitMemory", handle, limits)
+	return limits, nil
+}
+
+func (d *dryRunConnection) Run(handle string, spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+	d.print("Run", handle, spec)
+	return dryRunProcess{}, nil
+}
+
+func (d *dryRunConnection) NetIn(handle string, hostPort, containerPort uint32) (uint32, uint32, error) {
+	d.print("NetIn", handle, hostPort, containerPort)
+	return hostPort, containerPort, nil
+}
+
+func (d *dryRunConnection) NetOut(handle string, rule garden.NetOutRule) error {
+	d.print("NetOut", handle, rule)
+	return nil
+}
+
+func (d *dryRunConnection) SetProperty(handle string, name string, value string) error {
+	d.print("SetProperty", handle, name, value)
+	return nil
+}
+
+func (d *dryRunConnection) RemoveProperty(handle string, name string) error {
+	d.print("RemoveProperty", handle, name)
+	return nil
+}
+
+// dryRunProcess stands in for a process which was never started. It exits
+// successfully as soon as it is waited on.
+type dryRunProcess struct{}
+
+func (dryRunProcess) ID() uint32                  { return 0 }
+func (dryRunProcess) Wait() (int, error)          { return 0, nil }
+func (dryRunProcess) SetTTY(garden.TTYSpec) error { return nil }
+func (dryRunProcess) Signal(garden.Signal) error  { return nil }
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/mattn/go-shellwords"
+)
+
+// runEditor is replaced by the tests, which cannot open an editor.
+var runEditor = editFile
+
+// editFile opens the file in $VISUAL or $EDITOR, or vi if neither is set,
+// and waits for the editor to exit. The editor may be given with arguments,
+// such as "code --wait".
+func editFile(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+
+	if editor == "" {
+		editor = "vi"
+	}
+
+	args, err := shellwords.Parse(editor)
+	if err != nil {
+		return fmt.Errorf("invalid editor %q: %s", editor, err)
+	}
+
+	if len(args) == 0 {
+		return errors.New("no editor")
+	}
+
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed, so nothing was changed: %s", args[0], err)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/url"
+
+	"github.com/cloudfoundry-incubator/garden"
+	gconn "github.com/cloudfoundry-incubator/garden/client/connection"
+
+	"github.com/xoebus/gaol/commands"
+)
+
+// Exit codes, by the kind of failure. They are part of gaol's interface, so
+// existing codes must never change meaning.
+const (
+	exitFailure    = 1
+	exitUsage      = 2
+	exitConnection = 3
+	exitNotFound   = 4
+	exitProcess    = 5
+	exitTimeout    = 6
+)
+
+// errorCodes are the machine-readable names of the exit codes, as printed
+// with --json.
+var errorCodes = map[int]string{
+	exitFailure:    "failure",
+	exitUsage:      "usage",
+	exitConnection: "connection",
+	exitNotFound:   "not_found",
+	exitProcess:    "process_failed",
+	exitTimeout:    "timeout",
+}
+
+// usageError is a mistake in how gaol was invoked.
+type usageError string
+
+func (err usageError) Error() string {
+	return string(err)
+}
+
+// exitCode categorises err by its cause.
+func exitCode(err error) int {
+	switch e := err.(type) {
+	case usageError:
+		return exitUsage
+	case commands.ProcessExitError:
+		return exitProcess
+	case commands.ProcessTimeoutError:
+		return exitTimeout
+	case garden.ContainerNotFoundError:
+		return exitNotFound
+	case gconn.Error:
+		if e.StatusCode == 404 {
+			return exitNotFound
+		}
+	case requestTimeoutError, *url.Error, net.Error:
+		return exitConnection
+	}
+
+	return exitFailure
+}
+
+// jsonError is the error object printed with --json.
+type jsonError struct {
+	Error struct {
+		Code       string `json:"code"`
+		ExitCode   int    `json:"exit_code"`
+		Message    string `json:"message"`
+		ExitStatus *int   `json:"exit_status,omitempty"`
+	} `json:"error"`
+}
+
+func writeJSONError(w io.Writer, err error, code int) {
+	var out jsonError
+	out.Error.Code = errorCodes[code]
+	out.Error.ExitCode = code
+	out.Error.Message = err.Error()
+
+	if exit, ok := err.(commands.ProcessExitError); ok {
+		out.Error.ExitStatus = &exit.Status
+	}
+
+	json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	gconn "github.com/cloudfoundry-incubator/garden/client/connection"
+
+	"github.com/xoebus/gaol/commands"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{errors.New("boom"), exitFailure},
+		{usageError("must provide container handle"), exitUsage},
+		{commands.ProcessExitError{Command: "false", Status: 1}, exitProcess},
+		{commands.ProcessTimeoutError{Command: "make", Timeout: time.Minute}, exitTimeout},
+		{garden.ContainerNotFoundError{Handle: "a"}, exitNotFound},
+		{gconn.Error{StatusCode: 404, Message: "unknown handle: a"}, exitNotFound},
+		{gconn.Error{StatusCode: 500, Message: "failed"}, exitFailure},
+		{requestTimeoutError{time.Second}, exitConnection},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, exitConnection},
+	}
+
+	for _, test := range tests {
+		if code := exitCode(test.err); code != test.code {
+			t.Errorf("%#v: exit code %d, want %d", test.err, code, test.code)
+		}
+	}
+}
+
+func TestWriteJSONError(t *testing.T) {
+	var buf bytes.Buffer
+	writeJSONError(&buf, commands.ProcessExitError{Command: "false", Status: 7}, exitProcess)
+
+	var out jsonError
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid JSON %q: %s", buf.String(), err)
+	}
+
+	if out.Error.Code != "process_failed" || out.Error.ExitCode != exitProcess || out.Error.ExitStatus == nil || *out.Error.ExitStatus != 7 {
+		t.Errorf("wrote %q", buf.String())
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// forwarder accepts connections on a unix socket in a private directory and
+// connects each of them to the target using dial. The garden connection can
+// only dial plain addresses, so connections which need more than that (e.g.
+// TLS) are made through a forwarder, as are those whose requests are
+// stamped.
+type forwarder struct {
+	dir      string
+	listener net.Listener
+	dial     func() (net.Conn, error)
+	stamp    *requestStamp
+}
+
+func newForwarder(dial func() (net.Conn, error), stamp *requestStamp) (*forwarder, error) {
+	dir, err := ioutil.TempDir("", "gaol")
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", filepath.Join(dir, "target.sock"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	f := &forwarder{
+		dir:      dir,
+		listener: listener,
+		dial:     dial,
+		stamp:    stamp,
+	}
+
+	atExit(f.Close)
+
+	go f.serve()
+
+	return f, nil
+}
+
+// Address is the path of the socket to connect to instead of the target.
+func (f *forwarder) Address() string {
+	return f.listener.Addr().String()
+}
+
+func (f *forwarder) Close() {
+	f.listener.Close()
+	os.RemoveAll(f.dir)
+}
+
+func (f *forwarder) serve() {
+	for {
+		local, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer local.Close()
+
+			remote, err := f.dial()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "failed to connect to target:", err)
+				return
+			}
+			defer remote.Close()
+
+			// the connection is finished with as soon as either side is
+			done := make(chan struct{}, 2)
+
+			go func() {
+				if f.stamp != nil {
+					stampRequests(remote, local, f.stamp)
+				} else {
+					io.Copy(remote, local)
+				}
+				done <- struct{}{}
+			}()
+
+			go func() {
+				io.Copy(local, remote)
+				done <- struct{}{}
+			}()
+
+			<-done
+		}()
+	}
+}
@@ -1,26 +1,29 @@
 package main
 
 import (
-	"archive/tar"
+	"bufio"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net"
 	"os"
+	"os/exec"
 	"os/signal"
+	"os/user"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/codegangsta/cli"
-	"github.com/kr/pty"
-	"github.com/mattn/go-shellwords"
-	"github.com/pivotal-golang/archiver/compressor"
-	"github.com/pkg/term"
 
 	"github.com/cloudfoundry-incubator/garden"
 	gclient "github.com/cloudfoundry-incubator/garden/client"
-	gconn "github.com/cloudfoundry-incubator/garden/client/connection"
+
+	"github.com/xoebus/gaol/commands"
 )
 
 func handleComplete(c *cli.Context) {
@@ -29,391 +32,4099 @@ func handleComplete(c *cli.Context) {
 		return
 	}
 
-	containers, err := client(c).Containers(nil)
+	handles, err := cachedHandles(c, defaultCacheTTL)
 	failIf(err)
 
-	for _, container := range containers {
-		fmt.Println(container.Handle())
+	for _, handle := range handles {
+		fmt.Println(handle)
 	}
 }
 
-func fail(err error) {
-	fmt.Fprintln(os.Stderr, "failed:", err)
-	os.Exit(1)
+var processNameFlag = cli.StringFlag{
+	Name:  "name",
+	Usage: "the process recorded under this name by a manifest or run --restart",
 }
 
-func failIf(err error) {
-	if err != nil {
-		fail(err)
-	}
+var processCommandFlag = cli.StringFlag{
+	Name:  "command",
+	Usage: "the process gaol most recently started in the container whose command contains this text",
 }
 
-func client(c *cli.Context) garden.Client {
-	target := c.GlobalString("target")
-	return gclient.New(gconn.New("tcp", target))
-}
+// pickPID returns the process given to a command after the handle or by
+// whichever of its --pid, --last, --name and --command flags was given, or 0
+// if none was.
+func pickPID(c *cli.Context, handle string) uint32 {
+	given := []string{}
+	for _, flag := range []string{"pid", "last", "name", "command"} {
+		if c.IsSet(flag) {
+			given = append(given, "--"+flag)
+		}
+	}
 
-func handle(c *cli.Context) string {
-	if len(c.Args()) == 0 {
-		fail(errors.New("must provide container handle"))
+	if len(c.Args()) > 1 {
+		given = append(given, "a pid")
+	}
+
+	if len(given) > 1 {
+		fail(usageError(fmt.Sprintf("cannot give both %s and %s", given[0], given[1])))
 	}
-	return c.Args().First()
+
+	var pid uint32
+	var err error
+
+	switch {
+	case c.Bool("last"):
+		pid, err = commands.LastProcess(client(c), handle)
+	case c.String("name") != "":
+		pid, err = commands.RecordedPID(client(c), handle, c.String("name"))
+	case c.String("command") != "":
+		pid, err = commands.FindProcess(client(c), handle, c.String("command"))
+	case len(c.Args()) > 1:
+		n, parseErr := strconv.ParseUint(c.Args()[1], 10, 32)
+		if parseErr != nil {
+			fail(usageError(fmt.Sprintf("invalid pid %q", c.Args()[1])))
+		}
+		pid = uint32(n)
+	default:
+		pid = uint32(c.Int("pid"))
+	}
+
+	failIf(err)
+
+	return pid
 }
 
-func main() {
-	app := cli.NewApp()
-	app.Name = "gaol"
-	app.Usage = "a cli for garden"
-	app.Version = "0.0.1"
-	app.Author = "Chris Brown"
-	app.Email = "cbrown@pivotal.io"
-	app.EnableBashCompletion = true
+// pidComplete completes the handle given to commands which act on a process
+// and then the PIDs of the processes gaol knows of in the container.
+func pidComplete(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		handleComplete(c)
+		return
+	}
 
-	app.Flags = []cli.Flag{
-		cli.StringFlag{
-			Name:   "target, t",
-			Value:  "localhost:7777",
-			Usage:  "server to which commands are sent",
-			EnvVar: "GAOL_TARGET",
-		},
+	processes, err := commands.KnownProcesses(client(c), resolveHandles(c, c.Args().First())[0])
+	failIf(err)
+
+	for _, process := range processes {
+		fmt.Println(process.PID)
 	}
+}
 
-	app.Commands = []cli.Command{
-		{
-			Name:  "ping",
-			Usage: "check if the server is running",
-			Action: func(c *cli.Context) {
-				err := client(c).Ping()
-				failIf(err)
-			},
-		},
-		{
-			Name:  "create",
-			Usage: "create a container",
-			Flags: []cli.Flag{
-				cli.StringFlag{
-					Name:  "handle, n",
-					Usage: "name to give container",
-				},
-				cli.StringFlag{
-					Name:  "rootfs, r",
-					Usage: "rootfs image with which to create the container",
-				},
-				cli.DurationFlag{
-					Name:  "grace, g",
-					Usage: "grace time (resetting ttl) of container",
-				},
-				cli.BoolFlag{
-					Name:  "privileged, p",
-					Usage: "privileged user in container is privileged in host",
-				},
-			},
-			Action: func(c *cli.Context) {
-				handle := c.String("handle")
-				grace := c.Duration("grace")
-				rootfs := c.String("rootfs")
-				privileged := c.Bool("privileged")
+// atExit registers a cleanup to run, most recent first, when gaol exits or
+// is interrupted.
+func atExit(cleanup func()) {
+	commands.AtExit(cleanup)
+}
 
-				container, err := client(c).Create(garden.ContainerSpec{
-					Handle:     handle,
-					GraceTime:  grace,
-					RootFSPath: rootfs,
-					Privileged: privileged,
-				})
-				failIf(err)
+// osExit is replaced by the tests, which cannot let gaol exit.
+var osExit = os.Exit
 
-				fmt.Println(container.Handle())
-			},
-		},
-		{
-			Name:         "destroy",
-			Usage:        "destroy a container",
-			BashComplete: handleComplete,
-			Action: func(c *cli.Context) {
-				client := client(c)
-				handles := c.Args()
+func exit(code int) {
+	commands.RunCleanups()
+	osExit(code)
+}
 
-				for _, handle := range handles {
-					err := client.Destroy(handle)
-					failIf(err)
-				}
-			},
-		},
-		{
-			Name:  "list",
-			Usage: "get a list of running containers",
-			Action: func(c *cli.Context) {
-				containers, err := client(c).Containers(nil)
-				failIf(err)
+// ignoringInterrupts is set while another gaol, such as one run by the
+// console, has the terminal and handles interrupts itself.
+var ignoringInterrupts int32
 
-				for _, container := range containers {
-					fmt.Println(container.Handle())
-				}
-			},
-		},
-		{
-			Name:  "run",
-			Usage: "run a command in a container",
-			Flags: []cli.Flag{
-				cli.BoolFlag{
-					Name:  "attach, a",
-					Usage: "attach to the process after it is started",
-				},
-				cli.StringFlag{
-					Name:  "dir, d",
-					Usage: "current working directory of process",
-				},
-				cli.StringFlag{
-					Name:  "user, u",
-					Usage: "user to run the process as",
-				},
-				cli.BoolFlag{
-					Name:  "privileged, p",
-					Usage: "use privileged user in container",
-				},
-			},
-			BashComplete: handleComplete,
-			Action: func(c *cli.Context) {
-				attach := c.Bool("attach")
-				dir := c.String("dir")
-				user := c.String("user")
-				privileged := c.Bool("privileged")
+func ignoreInterrupts(ignore bool) {
+	if ignore {
+		atomic.StoreInt32(&ignoringInterrupts, 1)
+	} else {
+		atomic.StoreInt32(&ignoringInterrupts, 0)
+	}
+}
 
-				handle := handle(c)
-				container, err := client(c).Lookup(handle)
-				failIf(err)
+// handleInterrupts exits, running the cleanups, when gaol is interrupted,
+// terminated or hung up on, rather than dying with the terminal raw and
+// transfers, temporary files and canary containers left half-done.
+func handleInterrupts() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
-				var processIo garden.ProcessIO
-				if attach {
-					processIo = garden.ProcessIO{
-						Stdin:  os.Stdin,
-						Stdout: os.Stdout,
-						Stderr: os.Stderr,
-					}
-				} else {
-					processIo = garden.ProcessIO{}
-				}
+	go func() {
+		for sig := range signals {
+			if sig == syscall.SIGINT && atomic.LoadInt32(&ignoringInterrupts) == 1 {
+				continue
+			}
 
-				command := c.Args()[1]
-				args, err := shellwords.Parse(command)
-				failIf(err)
+			// a second interrupt while cleaning up kills gaol outright
+			signal.Stop(signals)
 
-				process, err := container.Run(garden.ProcessSpec{
-					Path:       args[0],
-					Args:       args[1:],
-					Dir:        dir,
-					Privileged: privileged,
-					User:       user,
-				}, processIo)
-				failIf(err)
+			fmt.Fprintln(os.Stderr, "interrupted")
+			exit(128 + int(sig.(syscall.Signal)))
+		}
+	}()
+}
 
-				if attach {
-					_, err = process.Wait()
-					failIf(err)
-				} else {
-					fmt.Println(process.ID())
-				}
-			},
-		},
-		{
-			Name:  "attach",
-			Usage: "attach to command running in the container",
-			Flags: []cli.Flag{
-				cli.IntFlag{
-					Name:  "pid, p",
-					Usage: "process id to connect to",
-				},
-			},
-			BashComplete: handleComplete,
-			Action: func(c *cli.Context) {
-				pid := uint32(c.Int("pid"))
+// jsonErrors makes fail print errors as JSON objects.
+var jsonErrors bool
 
-				handle := handle(c)
-				container, err := client(c).Lookup(handle)
-				failIf(err)
+func fail(err error) {
+	code := exitCode(err)
 
-				process, err := container.Attach(pid, garden.ProcessIO{
-					Stdin:  os.Stdin,
-					Stdout: os.Stdout,
-					Stderr: os.Stderr,
-				})
-				failIf(err)
+	if jsonErrors {
+		writeJSONError(os.Stderr, err, code)
+	} else {
+		fmt.Fprintln(os.Stderr, "failed:", err)
+	}
 
-				_, err = process.Wait()
-				failIf(err)
-			},
-		},
-		{
-			Name:         "shell",
-			Usage:        "open a shell inside the running container",
-			BashComplete: handleComplete,
-			Action: func(c *cli.Context) {
-				container, err := client(c).Lookup(handle(c))
-				failIf(err)
+	exit(code)
+}
 
-				term, err := term.Open(os.Stdin.Name())
-				failIf(err)
+func failIf(err error) {
+	if err != nil {
+		fail(err)
+	}
+}
 
-				err = term.SetRaw()
-				failIf(err)
+// client returns a client for the current target. The tests replace it with
+// one which talks to a fake.
+var client = dialClient
 
-				rows, cols, err := pty.Getsize(os.Stdin)
-				failIf(err)
+var (
+	dialed     garden.Client
+	dialedLock sync.Mutex
+)
 
-				process, err := container.Run(garden.ProcessSpec{
-					Path: "/bin/sh",
-					Args: []string{"-l"},
-					Env:  []string{"TERM=" + os.Getenv("TERM")},
-					TTY: &garden.TTYSpec{
-						WindowSize: &garden.WindowSize{
-							Rows:    rows,
-							Columns: cols,
-						},
-					},
-					Privileged: true,
-				}, garden.ProcessIO{
-					Stdin:  term,
-					Stdout: term,
-					Stderr: term,
-				})
-				if err != nil {
-					term.Restore()
-					failIf(err)
-				}
+// dialClient connects to the target once per invocation. Every command
+// shares the client, so that bulk operations reuse its connections, and any
+// jump host and TLS sessions, rather than each setting up their own.
+func dialClient(c *cli.Context) garden.Client {
+	dialedLock.Lock()
+	defer dialedLock.Unlock()
 
-				resized := make(chan os.Signal, 10)
-				signal.Notify(resized, syscall.SIGWINCH)
+	if dialed == nil {
+		dialed = newClient(c)
+	}
 
-				go func() {
-					for {
-						<-resized
+	return dialed
+}
 
-						rows, cols, err := pty.Getsize(os.Stdin)
-						if err == nil {
-							process.SetTTY(garden.TTYSpec{
-								WindowSize: &garden.WindowSize{
-									Rows:    rows,
-									Columns: cols,
-								},
-							})
-						}
-					}
-				}()
+func newClient(c *cli.Context) garden.Client {
+	opts := connectOptions{
+		ConnectTimeout: c.GlobalDuration("connect-timeout"),
+		RequestTimeout: c.GlobalDuration("request-timeout"),
+		Retries:        c.GlobalInt("retries"),
+		KeepAlive:      c.GlobalDuration("keepalive"),
+		Stamp:          newRequestStamp(c.Command.Name),
+	}
 
-				process.Wait()
-				term.Restore()
-			},
-		},
-		{
-			Name:  "stream-in",
-			Usage: "stream data into the container",
-			Flags: []cli.Flag{
-				cli.StringFlag{
-					Name:  "to-file, t",
-					Usage: "destination path in the container",
-				},
-			},
-			BashComplete: handleComplete,
-			Action: func(c *cli.Context) {
-				handle := handle(c)
+	if c.GlobalBool("verbose") {
+		opts.Log = os.Stderr
+	}
 
-				dst := c.String("to-file")
-				if dst == "" {
-					fail(errors.New("missing --to-file argument"))
-				}
+	if path := c.GlobalString("trace-file"); path != "" {
+		trace, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		failIf(err)
 
-				container, err := client(c).Lookup(handle)
-				failIf(err)
+		atExit(func() { trace.Close() })
+		opts.Trace = trace
+	}
 
-				// perform dance to get correct file names
-				tmpDir, err := ioutil.TempDir("", "gaol")
-				failIf(err)
-				defer os.RemoveAll(tmpDir)
+	if path := c.GlobalString("replay"); path != "" {
+		conn, err := newReplayConnection(path)
+		failIf(err)
 
-				tmp, err := os.Create(filepath.Join(tmpDir, filepath.Base(dst)))
-				failIf(err)
+		return gclient.New(conn)
+	}
 
-				_, err = io.Copy(tmp, os.Stdin)
-				failIf(err)
+	target := currentTarget(c)
 
-				err = tmp.Close()
-				failIf(err)
+	conn, err := connect(target, opts)
+	failIf(err)
 
-				reader, writer := io.Pipe()
-				go func(w io.WriteCloser) {
-					err := compressor.WriteTar(tmp.Name(), w)
-					failIf(err)
-					w.Close()
-				}(writer)
+	if path := c.GlobalString("record"); path != "" {
+		conn = newRecordingConnection(conn, path)
+	}
 
-				err = container.StreamIn(filepath.Dir(dst), reader)
-				failIf(err)
-			},
-		},
-		{
-			Name:  "stream-out",
-			Usage: "stream data out of the container",
-			Flags: []cli.Flag{
-				cli.StringFlag{
-					Name:  "from-file, f",
-					Usage: "source path in the container",
-				},
-			},
-			BashComplete: handleComplete,
-			Action: func(c *cli.Context) {
-				handle := handle(c)
+	if c.GlobalBool("audit") || c.GlobalString("audit-file") != "" {
+		path := c.GlobalString("audit-file")
+		if path == "" {
+			path = auditPath()
+			failIf(os.MkdirAll(filepath.Dir(path), 0700))
+		}
 
-				src := c.String("from-file")
-				if src == "" {
-					fail(errors.New("missing --from-file argument"))
-				}
+		audit, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		failIf(err)
 
-				container, err := client(c).Lookup(handle)
-				failIf(err)
+		atExit(func() { audit.Close() })
+		conn = newAuditingConnection(conn, audit, target.Address, opts.Stamp)
+	}
 
-				output, err := container.StreamOut(src)
-				failIf(err)
+	if c.GlobalBool("dry-run") {
+		conn = newDryRunConnection(conn, os.Stdout)
+	}
 
-				tr := tar.NewReader(output)
-				_, err = tr.Next()
-				failIf(err)
+	return gclient.New(conn)
+}
 
-				_, err = io.Copy(os.Stdout, tr)
-				failIf(err)
-			},
-		},
-		{
-			Name:  "net-in",
-			Usage: "map a port on the host to a port in the container",
-			Flags: []cli.Flag{
-				cli.IntFlag{
-					Name:  "port, p",
-					Usage: "container port",
-				},
-			},
-			BashComplete: handleComplete,
-			Action: func(c *cli.Context) {
-				target := c.GlobalString("target")
-				requestedContainerPort := uint32(c.Int("port"))
+func handle(c *cli.Context) string {
+	if len(c.Args()) == 0 {
+		return defaultHandle(c, "must provide container handle")
+	}
+	return resolveHandles(c, c.Args().First())[0]
+}
 
-				if target == "" {
-					fail(errors.New("target must be set"))
-				}
+// currentHandle returns the container given by GAOL_HANDLE or, failing that,
+// chosen with gaol use for the current target. It is empty if there is none.
+func currentHandle(c *cli.Context) string {
+	name := os.Getenv("GAOL_HANDLE")
+	if name == "" {
+		cfg, err := loadConfig()
+		failIf(err)
 
-				handle := handle(c)
-				container, err := client(c).Lookup(handle)
-				failIf(err)
+		name = cfg.Handles[currentTarget(c).Address]
+	}
 
-				hostPort, _, err := container.NetIn(0, requestedContainerPort)
-				failIf(err)
+	if name == "" {
+		return ""
+	}
 
-				host, _, err := net.SplitHostPort(target)
-				failIf(err)
+	return resolveHandles(c, name)[0]
+}
 
-				fmt.Println(net.JoinHostPort(host, fmt.Sprintf("%d", hostPort)))
+// defaultHandle returns the current container for commands given none, or
+// has the user pick one.
+func defaultHandle(c *cli.Context, usage string) string {
+	if handle := currentHandle(c); handle != "" {
+		return handle
+	}
+
+	return pickHandle(c, usage)
+}
+
+// handleAndCommand returns the container and command given to run. A single
+// argument is the command to run in the current container, if there is one.
+func handleAndCommand(c *cli.Context) (string, string) {
+	args := c.Args()
+	if len(args) == 1 {
+		if handle := currentHandle(c); handle != "" {
+			return handle, args[0]
+		}
+	}
+
+	handle := handle(c)
+	if len(args) < 2 {
+		fail(usageError("must provide command to run"))
+	}
+
+	return handle, args[1]
+}
+
+// interactive reports whether stdin is a terminal at which someone can be
+// asked things. It is replaced by the tests.
+var interactive = func() bool {
+	return commands.IsTerminal(os.Stdin)
+}
+
+// pickHandle has the user pick a container when none was given, if there is
+// a terminal to pick it on, and otherwise fails with the usage message.
+func pickHandle(c *cli.Context, usage string) string {
+	if !interactive() {
+		fail(usageError(usage))
+	}
+
+	handle, err := commands.PickContainer(client(c))
+	failIf(err)
+
+	return handle
+}
+
+// handles returns the container handles given as arguments or, with --stdin
+// or a single argument of "-", read from stdin one per line.
+func handles(c *cli.Context) []string {
+	args := c.Args()
+	if !c.Bool("stdin") && !(len(args) == 1 && args[0] == "-") {
+		return resolveHandles(c, args...)
+	}
+
+	if c.Bool("stdin") && len(args) > 0 {
+		fail(usageError("cannot give container handles along with --stdin"))
+	}
+
+	handles := []string{}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if handle := strings.TrimSpace(scanner.Text()); handle != "" {
+			handles = append(handles, handle)
+		}
+	}
+	failIf(scanner.Err())
+
+	return resolveHandles(c, handles...)
+}
+
+// containerFile splits a file given as <handle>:/path, or as /path in the
+// current container.
+func containerFile(c *cli.Context, arg string) (string, string) {
+	if strings.HasPrefix(arg, "/") {
+		return defaultHandle(c, "must provide container handle"), arg
+	}
+
+	i := strings.Index(arg, ":/")
+	if i <= 0 {
+		fail(usageError(fmt.Sprintf("invalid file %q: must be <handle>:/path", arg)))
+	}
+
+	return resolveHandles(c, arg[:i])[0], arg[i+1:]
+}
+
+// copyDestination splits where cp copies to into the containers and the
+// path: <handle>:/path, /path in the current container or in those chosen by
+// --all, --match and --filter, or @all:/path, @match:<glob>:/path or
+// @filter:<key=value,...>:/path.
+func copyDestination(c *cli.Context, arg string, selector commands.Selector) ([]string, string) {
+	if strings.HasPrefix(arg, "@") {
+		if !selector.IsZero() {
+			fail(usageError("cannot copy to " + arg + " along with --all, --match or --filter"))
+		}
+
+		i := strings.Index(arg, ":/")
+		if i < 0 {
+			fail(usageError(fmt.Sprintf("invalid destination %q: must be @all:/path, @match:<glob>:/path or @filter:<key=value>:/path", arg)))
+		}
+
+		kind, value := arg[1:i], ""
+		if j := strings.Index(kind, ":"); j >= 0 {
+			kind, value = kind[:j], kind[j+1:]
+		}
+
+		switch {
+		case kind == "all" && value == "":
+			selector.All = true
+		case kind == "match" && value != "":
+			selector.Match = value
+		case kind == "filter" && value != "":
+			filter, err := commands.ParseProperties(strings.Split(value, ","))
+			if err != nil {
+				fail(usageError(err.Error()))
+			}
+
+			selector.Filter = filter
+		default:
+			fail(usageError(fmt.Sprintf("invalid destination %q: must be @all:/path, @match:<glob>:/path or @filter:<key=value>:/path", arg)))
+		}
+
+		arg = arg[i+1:]
+	}
+
+	if selector.IsZero() {
+		handle, file := containerFile(c, arg)
+		return []string{handle}, file
+	}
+
+	if !strings.HasPrefix(arg, "/") {
+		fail(usageError(fmt.Sprintf("path %s is not absolute", arg)))
+	}
+
+	handles, err := commands.SelectHandles(client(c), selector)
+	failIf(err)
+
+	if len(handles) == 0 {
+		fail(errors.New("no containers to copy to"))
+	}
+
+	return handles, arg
+}
+
+// resolveHandles turns any aliases among the names into the handles they
+// stand for on the current target.
+func resolveHandles(c *cli.Context, names ...string) []string {
+	address := currentTarget(c).Address
+
+	handles := make([]string, len(names))
+	for i, name := range names {
+		handle, err := resolveAlias(address, name)
+		failIf(err)
+
+		handles[i] = handle
+	}
+
+	return handles
+}
+
+// stamp records in a container's properties when, by whom and from which
+// rootfs gaol created it, so that prune and people looking at the server can
+// tell where it came from.
+func stamp(spec garden.ContainerSpec) garden.ContainerSpec {
+	properties := garden.Properties{}
+	for key, value := range spec.Properties {
+		properties[key] = value
+	}
+
+	properties[commands.CreatedAtProperty] = time.Now().UTC().Format(time.RFC3339)
+	properties[commands.CreatedByProperty] = creator()
+
+	if spec.RootFSPath != "" {
+		properties[commands.RootFSProperty] = spec.RootFSPath
+	}
+
+	spec.Properties = properties
+
+	return spec
+}
+
+// creator identifies the person running gaol as user@host.
+func creator() string {
+	username := os.Getenv("USER")
+	if current, err := user.Current(); err == nil {
+		username = current.Username
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return username
+	}
+
+	return username + "@" + hostname
+}
+
+// unlessQuiet is w, or nowhere with --quiet. Messages which only tell people
+// what is going on are written to it.
+func unlessQuiet(c *cli.Context, w io.Writer) io.Writer {
+	if c.GlobalBool("quiet") {
+		return ioutil.Discard
+	}
+
+	return w
+}
+
+// printResults reports what happened to each container, or with --quiet
+// only the failures, on stderr.
+func printResults(c *cli.Context, action string, results []commands.Result) {
+	if c.GlobalBool("quiet") {
+		commands.PrintResults(os.Stderr, action, commands.Failures(results))
+		return
+	}
+
+	commands.PrintResults(os.Stdout, action, results)
+}
+
+// outputFormat is how values are rendered for people: in color on a
+// terminal, unless told otherwise with --no-color or NO_COLOR. With
+// --porcelain they are rendered for scripts instead.
+func outputFormat(c *cli.Context) commands.Format {
+	if c.GlobalString("porcelain") != "" {
+		return commands.Format{Porcelain: true}
+	}
+
+	if c.GlobalBool("no-color") || os.Getenv("NO_COLOR") != "" {
+		return commands.Format{}
+	}
+
+	stat, err := os.Stdout.Stat()
+	if err != nil {
+		return commands.Format{}
+	}
+
+	return commands.Format{Color: stat.Mode()&os.ModeCharDevice != 0}
+}
+
+// checkPorcelain checks that gaol can print the version of the porcelain
+// format asked for.
+func checkPorcelain(version string) error {
+	for _, known := range commands.PorcelainVersions {
+		if version == known {
+			return nil
+		}
+	}
+
+	return usageError(fmt.Sprintf("unknown porcelain version %q: must be one of %s", version, strings.Join(commands.PorcelainVersions, ", ")))
+}
+
+// listOrder returns how list is to sort and narrow down the containers, as
+// given by its flags.
+func listOrder(c *cli.Context) commands.ListOrder {
+	sortBy, err := commands.ParseSort(c.String("sort"))
+	if err != nil {
+		fail(usageError(err.Error()))
+	}
+
+	if c.Int("top") < 0 {
+		fail(usageError("--top must be at least 1"))
+	}
+
+	order := commands.ListOrder{Sort: sortBy, Top: c.Int("top")}
+
+	if since := c.String("since"); since != "" {
+		order.Since, err = commands.ParseAge(since)
+		if err != nil {
+			fail(usageError(err.Error()))
+		}
+	}
+
+	if before := c.String("before"); before != "" {
+		order.Before, err = commands.ParseAge(before)
+		if err != nil {
+			fail(usageError(err.Error()))
+		}
+	}
+
+	return order
+}
+
+// benchFlags are the flags of every benchmark, followed by extra.
+func benchFlags(extra ...cli.Flag) []cli.Flag {
+	return append([]cli.Flag{
+		cli.IntFlag{
+			Name:  "iterations, n",
+			Value: 100,
+			Usage: "number of times to do the operation",
+		},
+		cli.IntFlag{
+			Name:  "concurrency, c",
+			Value: 10,
+			Usage: "number of operations to do at once",
+		},
+		cli.StringFlag{
+			Name:  "rootfs, r",
+			Usage: "rootfs image with which to create containers",
+		},
+	}, extra...)
+}
+
+// benchSettings returns the iterations and concurrency of a benchmark.
+func benchSettings(c *cli.Context) (int, int) {
+	iterations := c.Int("iterations")
+	concurrency := c.Int("concurrency")
+	switch {
+	case iterations < 1:
+		fail(usageError("--iterations must be at least 1"))
+	case concurrency < 1:
+		fail(usageError("--concurrency must be at least 1"))
+	}
+
+	return iterations, concurrency
+}
+
+// benchContainer returns the container in which to run a benchmark, which is
+// destroyed on exit if it was made for the benchmark.
+func benchContainer(c *cli.Context) garden.Container {
+	handle := c.String("handle")
+	if handle != "" {
+		handle = resolveHandles(c, handle)[0]
+	}
+
+	container, cleanup, err := commands.BenchContainer(client(c), handle, c.String("rootfs"))
+	failIf(err)
+	atExit(func() { cleanup() })
+
+	return container
+}
+
+// finishBench prints the result of a benchmark, failing if every iteration
+// failed.
+func finishBench(result commands.BenchResult) {
+	commands.PrintBench(os.Stdout, result)
+
+	if result.Failures == result.Iterations {
+		fail(result.FirstErr)
+	}
+}
+
+var reconnectFlag = cli.BoolFlag{
+	Name:  "reconnect",
+	Usage: "re-attach to the process if the connection to the server drops",
+}
+
+var reconnectAttemptsFlag = cli.IntFlag{
+	Name:  "reconnect-attempts",
+	Value: 10,
+	Usage: "number of times in a row to try re-attaching, a second apart, before giving up",
+}
+
+// reconnect returns how to re-attach to a process, as given by the
+// --reconnect flags.
+func reconnect(c *cli.Context) commands.Reconnect {
+	if !c.Bool("reconnect") {
+		return commands.Reconnect{}
+	}
+
+	if c.Int("reconnect-attempts") < 1 {
+		fail(usageError("--reconnect-attempts must be at least 1"))
+	}
+
+	return commands.Reconnect{
+		Attempts: c.Int("reconnect-attempts"),
+		Delay:    time.Second,
+	}
+}
+
+var stdinFlag = cli.BoolFlag{
+	Name:  "stdin",
+	Usage: "read container handles from stdin, one per line (as does a handle of -)",
+}
+
+// limitFlags are the flags of create and update which limit a container
+// and set its properties.
+func limitFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "memory-limit",
+			Usage: "memory limit of the container (e.g. 512M or 2G)",
+		},
+		cli.StringFlag{
+			Name:  "disk-limit",
+			Usage: "disk limit of the container (e.g. 10G)",
+		},
+		cli.IntFlag{
+			Name:  "cpu-shares",
+			Usage: "cpu shares of the container, relative to other containers",
+		},
+		cli.StringSliceFlag{
+			Name:  "property",
+			Value: &cli.StringSlice{},
+			Usage: "property key=value to set on the container",
+		},
+	}
+}
+
+// containerUpdate returns the limits and properties given by limitFlags.
+func containerUpdate(c *cli.Context) commands.Update {
+	update := commands.Update{}
+
+	for flag, limit := range map[string]*commands.ByteSize{
+		"memory-limit": &update.Limits.Memory,
+		"disk-limit":   &update.Limits.Disk,
+	} {
+		if c.String(flag) == "" {
+			continue
+		}
+
+		size, err := commands.ParseByteSize(c.String(flag))
+		if err != nil {
+			fail(usageError(fmt.Sprintf("--%s: %s", flag, err)))
+		}
+
+		*limit = commands.ByteSize(size)
+	}
+
+	if c.Int("cpu-shares") < 0 {
+		fail(usageError("--cpu-shares cannot be negative"))
+	}
+	update.Limits.CPU = uint64(c.Int("cpu-shares"))
+
+	properties, err := commands.ParseProperties(c.StringSlice("property"))
+	if err != nil {
+		fail(usageError(err.Error()))
+	}
+
+	if len(properties) > 0 {
+		update.Properties = properties
+	}
+
+	return update
+}
+
+// checkPrivileged fails if --privileged is given, whether on the command line
+// or as a default, on a target which does not allow it.
+func checkPrivileged(c *cli.Context) {
+	if c.Bool("privileged") && !currentTarget(c).allowsPrivileged() {
+		fail(errors.New("--privileged is not allowed on this target"))
+	}
+}
+
+var registryUserFlag = cli.StringFlag{
+	Name:   "registry-user",
+	Usage:  "user to log in to the image's registry as",
+	EnvVar: "GAOL_REGISTRY_USER",
+}
+
+var registryPasswordFlag = cli.StringFlag{
+	Name:   "registry-password",
+	Usage:  "password of --registry-user",
+	EnvVar: "GAOL_REGISTRY_PASSWORD",
+}
+
+var insecureRegistryFlag = cli.BoolFlag{
+	Name:   "insecure-registry",
+	Usage:  "do not verify the certificate of the image's registry, and use plain http if it has none",
+	EnvVar: "GAOL_INSECURE_REGISTRY",
+}
+
+// pullImage pulls the docker image from its registry, logging in as the
+// registry flags say. The pulled image is removed when gaol exits.
+func pullImage(c *cli.Context, name string) *commands.PulledImage {
+	ref, err := commands.ParseImageRef(name)
+	if err != nil {
+		fail(usageError(err.Error()))
+	}
+
+	image, err := commands.PullImage(ref, commands.RegistryOptions{
+		Username: c.String("registry-user"),
+		Password: c.String("registry-password"),
+		Insecure: c.Bool("insecure-registry"),
+	}, unlessQuiet(c, os.Stderr))
+	failIf(err)
+	atExit(func() { image.Remove() })
+
+	return image
+}
+
+// collectFlag is made afresh for each command, as the values given to a
+// slice flag are added to its default.
+func collectFlag() cli.StringSliceFlag {
+	return cli.StringSliceFlag{
+		Name:  "collect",
+		Value: &cli.StringSlice{},
+		Usage: "once the process has exited, even unsuccessfully, stream this path out of the container into a local directory, as /path=./dir",
+	}
+}
+
+// collections returns the paths given to --collect.
+func collections(c *cli.Context) []commands.Collection {
+	collections, err := commands.ParseCollections(c.StringSlice("collect"))
+	if err != nil {
+		fail(usageError(err.Error()))
+	}
+
+	return collections
+}
+
+// attributes returns the owner and mode given to --chown and --chmod.
+func attributes(c *cli.Context) commands.Attributes {
+	attrs := commands.KeepAttributes
+
+	if c.String("chown") != "" {
+		user, group, err := commands.ParseChown(c.String("chown"))
+		if err != nil {
+			fail(usageError(err.Error()))
+		}
+
+		attrs.User, attrs.Group = user, group
+	}
+
+	if c.String("chmod") != "" {
+		mode, err := commands.ParseChmod(c.String("chmod"))
+		if err != nil {
+			fail(usageError(err.Error()))
+		}
+
+		attrs.Mode = mode
+	}
+
+	return attrs
+}
+
+var outputFlag = cli.StringFlag{
+	Name:  "output, o",
+	Value: "text",
+	Usage: "text, or jsonl for a JSON object per line with its time, handle, type and payload",
+}
+
+// jsonLines returns where a command given --output jsonl writes, or nil if
+// it writes text.
+func jsonLines(c *cli.Context) *commands.JSONLines {
+	switch c.String("output") {
+	case "", "text":
+		return nil
+	case "jsonl":
+		return commands.NewJSONLines(os.Stdout)
+	}
+
+	fail(usageError("--output must be text or jsonl"))
+	return nil
+}
+
+var junitFlag = cli.StringFlag{
+	Name:  "junit",
+	Usage: "write each command run to completion to this file as a JUnit XML test case",
+}
+
+// junitReport returns the report to record commands in for --junit, or nil
+// if it is not given.
+func junitReport(c *cli.Context) *commands.Report {
+	if c.String("junit") == "" {
+		return nil
+	}
+
+	return new(commands.Report)
+}
+
+// writeJUnit writes the report to the file given to --junit, if any, as a
+// suite of the given name.
+func writeJUnit(c *cli.Context, report *commands.Report, name string) {
+	if report != nil {
+		failIf(report.WriteJUnit(c.String("junit"), name))
+	}
+}
+
+func newApp() *cli.App {
+	app := cli.NewApp()
+	app.Name = "gaol"
+	app.Usage = "a cli for garden"
+	app.Version = versionString()
+	app.Author = "Chris Brown"
+	app.Email = "cbrown@pivotal.io"
+	app.EnableBashCompletion = true
+
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:   "target, t",
+			Usage:  "server (or name of a configured target) to which commands are sent",
+			EnvVar: "GAOL_TARGET",
+		},
+		cli.StringFlag{
+			Name:   "ca-cert",
+			Usage:  "CA certificate with which to verify the server, enabling TLS",
+			EnvVar: "GAOL_CA_CERT",
+		},
+		cli.StringFlag{
+			Name:   "client-cert",
+			Usage:  "certificate with which to authenticate to the server, enabling TLS",
+			EnvVar: "GAOL_CLIENT_CERT",
+		},
+		cli.StringFlag{
+			Name:   "client-key",
+			Usage:  "private key of the client certificate",
+			EnvVar: "GAOL_CLIENT_KEY",
+		},
+		cli.StringFlag{
+			Name:   "via",
+			Usage:  "SSH jump host, as user@host[:port], through which to reach the server",
+			EnvVar: "GAOL_VIA",
+		},
+		cli.DurationFlag{
+			Name:   "connect-timeout",
+			Value:  defaultConnectTimeout,
+			Usage:  "time to wait for the server to accept a connection",
+			EnvVar: "GAOL_CONNECT_TIMEOUT",
+		},
+		cli.DurationFlag{
+			Name:   "request-timeout",
+			Usage:  "time to wait for a response to each request (0 waits forever)",
+			EnvVar: "GAOL_REQUEST_TIMEOUT",
+		},
+		cli.IntFlag{
+			Name:   "retries",
+			Usage:  "times to retry requests which only read state when the server cannot be reached",
+			EnvVar: "GAOL_RETRIES",
+		},
+		cli.DurationFlag{
+			Name:   "keepalive",
+			Usage:  "period of TCP keepalives on connections to the server (0 disables them)",
+			EnvVar: "GAOL_KEEPALIVE",
+		},
+		cli.BoolFlag{
+			Name:   "verbose, debug",
+			Usage:  "log every call made to the server to stderr",
+			EnvVar: "GAOL_VERBOSE",
+		},
+		cli.StringFlag{
+			Name:   "trace-file",
+			Usage:  "append every call made to the server to this file as JSON lines",
+			EnvVar: "GAOL_TRACE_FILE",
+		},
+		cli.BoolFlag{
+			Name:   "audit",
+			Usage:  "append every create, destroy, stop and limit, who made it and how it turned out, to ~/.gaol/audit.log",
+			EnvVar: "GAOL_AUDIT",
+		},
+		cli.StringFlag{
+			Name:   "audit-file",
+			Usage:  "keep the audit log in this file instead, turning it on",
+			EnvVar: "GAOL_AUDIT_FILE",
+		},
+		cli.StringFlag{
+			Name:   "helper",
+			Usage:  "gaol-helper binary to stream into containers for commands which need tools their rootfs lacks",
+			EnvVar: "GAOL_HELPER",
+		},
+		cli.BoolFlag{
+			Name:   "no-history",
+			Usage:  "leave this command out of the history kept for gaol history and rerun",
+			EnvVar: "GAOL_NO_HISTORY",
+		},
+		cli.StringFlag{
+			Name:  "record",
+			Usage: "save every call made to the server, and its response, to this file",
+		},
+		cli.StringFlag{
+			Name:  "replay",
+			Usage: "answer calls from a file saved with --record instead of contacting a server",
+		},
+		cli.BoolFlag{
+			Name:   "json",
+			Usage:  "print errors as JSON objects with a machine-readable code, and the report of smoke as JSON",
+			EnvVar: "GAOL_JSON",
+		},
+		cli.BoolFlag{
+			Name:   "dry-run",
+			Usage:  "print the calls which would change the server instead of making them",
+			EnvVar: "GAOL_DRY_RUN",
+		},
+		cli.BoolFlag{
+			Name:   "quiet, q",
+			Usage:  "print only what a command produces, such as handles, without progress or other messages",
+			EnvVar: "GAOL_QUIET",
+		},
+		cli.StringFlag{
+			Name:   "porcelain",
+			Usage:  "print list, info and metrics in the stable, tab-separated format of this version (v1) for scripts",
+			EnvVar: "GAOL_PORCELAIN",
+		},
+		cli.StringFlag{
+			Name:  "output-file",
+			Usage: "also write what the command prints to stdout to this file, which is replaced only once gaol exits",
+		},
+		cli.BoolFlag{
+			Name:   "no-color",
+			Usage:  "never color output (as does setting NO_COLOR)",
+			EnvVar: "GAOL_NO_COLOR",
+		},
+		cli.IntFlag{
+			Name:   "confirm-above",
+			Value:  10,
+			Usage:  "ask before destroying more than this many containers at once",
+			EnvVar: "GAOL_CONFIRM_ABOVE",
+		},
+	}
+
+	app.CommandNotFound = func(c *cli.Context, command string) {
+		// help for a plugin is whatever the plugin says it is
+		if path, found := findPlugin(command); found {
+			failIf(runPlugin(c, path, []string{"--help"}))
+			return
+		}
+
+		fail(usageError(fmt.Sprintf("unknown command %q", command)))
+	}
+
+	app.Action = func(c *cli.Context) {
+		if !c.Args().Present() {
+			cli.ShowAppHelp(c)
+			return
+		}
+
+		name := c.Args().First()
+
+		path, found := findPlugin(name)
+		if !found {
+			fail(usageError(fmt.Sprintf("unknown command %q", name)))
+		}
+
+		err := runPlugin(c, path, c.Args().Tail())
+		failIf(err)
+	}
+
+	app.Before = func(c *cli.Context) error {
+		jsonErrors = c.GlobalBool("json")
+
+		if version := c.GlobalString("porcelain"); version != "" {
+			failIf(checkPorcelain(version))
+		}
+
+		if path := c.GlobalString("output-file"); path != "" {
+			failIf(teeStdout(path))
+		}
+
+		applyConfiguredDefaults(c)
+
+		recordHistory(c)
+
+		return nil
+	}
+
+	app.Commands = []cli.Command{
+		{
+			Name:  "ping",
+			Usage: "check if the server is running",
+			Flags: []cli.Flag{
+				cli.DurationFlag{
+					Name:  "wait, w",
+					Usage: "keep trying until the server is running or this much time has passed",
+				},
+				cli.DurationFlag{
+					Name:  "interval, i",
+					Value: time.Second,
+					Usage: "time between tries when waiting",
+				},
+			},
+			Action: func(c *cli.Context) {
+				err := commands.Ping(client(c), c.Duration("wait"), c.Duration("interval"))
+				failIf(err)
+			},
+		},
+		{
+			Name:  "version",
+			Usage: "print the version of gaol and which parts of the garden api the server has",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "client",
+					Usage: "print only the version of gaol, without asking the server",
+				},
+			},
+			Action: func(c *cli.Context) {
+				if c.Bool("client") {
+					printVersion(os.Stdout, os.Stderr, "", nil, nil)
+					return
+				}
+
+				target := currentTarget(c)
+				support, err := probeTarget(target, connectOptions{
+					ConnectTimeout: c.GlobalDuration("connect-timeout"),
+					KeepAlive:      c.GlobalDuration("keepalive"),
+				})
+				printVersion(os.Stdout, os.Stderr, target.Address, support, err)
+			},
+		},
+		{
+			Name:  "capacity",
+			Usage: "show the memory, disk and number of containers the server has room for",
+			Action: func(c *cli.Context) {
+				err := commands.Capacity(client(c), os.Stdout)
+				failIf(err)
+			},
+		},
+		{
+			Name:  "doctor",
+			Usage: "check the network, the server and a canary container, to tell where problems lie",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "rootfs, r",
+					Usage: "rootfs image with which to create the canary container",
+				},
+			},
+			Action: func(c *cli.Context) {
+				target := currentTarget(c)
+
+				checks := commands.Doctor(client(c), commands.DoctorOptions{
+					Reach: func() error {
+						return reachTarget(target, c.GlobalDuration("connect-timeout"))
+					},
+					RootFS: c.String("rootfs"),
+				})
+				commands.PrintChecks(os.Stdout, checks, outputFormat(c))
+
+				if failed := commands.FailedChecks(checks); failed > 0 {
+					fail(fmt.Errorf("%d of %d checks failed", failed, len(checks)))
+				}
+			},
+		},
+		{
+			Name:  "smoke",
+			Usage: "take a canary container through its whole life, timing each step, as a probe for monitoring",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "rootfs, r",
+					Usage: "rootfs image with which to create the canary container",
+				},
+			},
+			Action: func(c *cli.Context) {
+				checks := commands.Smoke(client(c), c.String("rootfs"))
+
+				if c.GlobalBool("json") {
+					failIf(commands.PrintChecksJSON(os.Stdout, checks))
+				} else {
+					commands.PrintChecks(os.Stdout, checks, outputFormat(c))
+				}
+
+				if failed := commands.FailedChecks(checks); failed > 0 {
+					fail(fmt.Errorf("%d of %d steps failed", failed, len(checks)))
+				}
+			},
+		},
+		{
+			Name:  "bench",
+			Usage: "measure the latency and throughput of the server",
+			Subcommands: []cli.Command{
+				{
+					Name:  "create",
+					Usage: "create and destroy containers, timing the creation",
+					Flags: benchFlags(),
+					Action: func(c *cli.Context) {
+						iterations, concurrency := benchSettings(c)
+
+						spec := garden.ContainerSpec{RootFSPath: c.String("rootfs")}
+						finishBench(commands.BenchCreate(client(c), spec, iterations, concurrency))
+					},
+				},
+				{
+					Name:  "run",
+					Usage: "run a command in a container until it exits: run [command]",
+					Flags: benchFlags(
+						cli.StringFlag{
+							Name:  "handle",
+							Usage: "container to run the command in, instead of a new one",
+						},
+					),
+					Action: func(c *cli.Context) {
+						iterations, concurrency := benchSettings(c)
+
+						command := "true"
+						if len(c.Args()) > 0 {
+							command = c.Args()[0]
+						}
+
+						result, err := commands.BenchRun(benchContainer(c), command, iterations, concurrency)
+						if err != nil {
+							fail(usageError(err.Error()))
+						}
+
+						finishBench(result)
+					},
+				},
+				{
+					Name:  "stream",
+					Usage: "stream a file into a container and back out",
+					Flags: benchFlags(
+						cli.StringFlag{
+							Name:  "handle",
+							Usage: "container to stream the file into, instead of a new one",
+						},
+						cli.IntFlag{
+							Name:  "size, s",
+							Value: 1 << 20,
+							Usage: "size of the file in bytes",
+						},
+					),
+					Action: func(c *cli.Context) {
+						iterations, concurrency := benchSettings(c)
+
+						if c.Int("size") < 0 {
+							fail(usageError("--size cannot be negative"))
+						}
+
+						finishBench(commands.BenchStream(benchContainer(c), int64(c.Int("size")), iterations, concurrency))
+					},
+				},
+			},
+		},
+		{
+			Name:  "create",
+			Usage: "create a container",
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "handle, n",
+					Usage: "name to give container",
+				},
+				cli.StringFlag{
+					Name:  "handle-prefix",
+					Usage: "give the container a handle of this prefix and random characters, such as ci-3f9a61c2",
+				},
+				cli.StringFlag{
+					Name:  "template, T",
+					Usage: "create the container with the settings of this template in the config file, unless overridden by other flags",
+				},
+				cli.StringFlag{
+					Name:  "rootfs, r",
+					Usage: "rootfs image with which to create the container",
+				},
+				cli.DurationFlag{
+					Name:  "grace, g",
+					Usage: "grace time (resetting ttl) of container",
+				},
+				cli.BoolFlag{
+					Name:  "privileged, p",
+					Usage: "privileged user in container is privileged in host",
+				},
+				cli.StringFlag{
+					Name:  "docker-image",
+					Usage: "pull this image from its registry and copy it into the container, on top of --rootfs",
+				},
+				cli.StringFlag{
+					Name:  "rootfs-tar",
+					Usage: "copy this tar archive, which may be gzipped, into the container, on top of --rootfs",
+				},
+				cli.StringFlag{
+					Name:  "oci-layout",
+					Usage: "copy the image in this OCI image layout directory into the container, on top of --rootfs",
+				},
+				registryUserFlag,
+				registryPasswordFlag,
+				insecureRegistryFlag,
+				cli.BoolFlag{
+					Name:  "if-not-exists",
+					Usage: "reuse the container with the handle if there is one",
+				},
+				cli.BoolFlag{
+					Name:  "replace",
+					Usage: "destroy the container with the handle, if there is one, before creating it",
+				},
+				cli.BoolFlag{
+					Name:  "no-stamp",
+					Usage: "do not record when, by whom and from which rootfs the container was created",
+				},
+				cli.IntFlag{
+					Name:  "count",
+					Value: 1,
+					Usage: "number of containers to create, with handles from a template such as 'worker-{{.Index}}'",
+				},
+				cli.IntFlag{
+					Name:  "concurrency, c",
+					Value: 10,
+					Usage: "number of containers to create at once",
+				},
+			}, limitFlags()...),
+			Action: func(c *cli.Context) {
+				checkPrivileged(c)
+
+				update := containerUpdate(c)
+
+				spec := garden.ContainerSpec{
+					Handle:     c.String("handle"),
+					GraceTime:  c.Duration("grace"),
+					RootFSPath: c.String("rootfs"),
+					Privileged: c.Bool("privileged"),
+					Properties: update.Properties,
+				}
+
+				spec, limits := applyTemplate(c, spec, update.Limits)
+
+				if !c.Bool("no-stamp") {
+					spec = stamp(spec)
+				}
+
+				opts := commands.CreateOptions{
+					HandlePrefix: c.String("handle-prefix"),
+					Limits:       limits,
+				}
+				switch {
+				case opts.HandlePrefix != "" && spec.Handle != "":
+					fail(usageError("cannot give both --handle and --handle-prefix"))
+				case c.Bool("if-not-exists") && c.Bool("replace"):
+					fail(usageError("cannot give both --if-not-exists and --replace"))
+				case c.Bool("if-not-exists"):
+					opts.IfExists = commands.ReuseIfExists
+				case c.Bool("replace"):
+					opts.IfExists = commands.ReplaceIfExists
+				}
+
+				if opts.IfExists != commands.FailIfExists && spec.Handle == "" {
+					fail(usageError("--if-not-exists and --replace need a --handle"))
+				}
+
+				count := c.Int("count")
+				concurrency := c.Int("concurrency")
+				switch {
+				case count < 1:
+					fail(usageError("--count must be at least 1"))
+				case concurrency < 1:
+					fail(usageError("--concurrency must be at least 1"))
+				}
+
+				sources := 0
+				for _, flag := range []string{"docker-image", "rootfs-tar", "oci-layout"} {
+					if c.String(flag) != "" {
+						sources++
+					}
+				}
+
+				if sources > 1 {
+					fail(usageError("give only one of --docker-image, --rootfs-tar and --oci-layout"))
+				}
+
+				var image *commands.PulledImage
+				switch {
+				case c.String("docker-image") != "":
+					image = pullImage(c, c.String("docker-image"))
+				case c.String("oci-layout") != "":
+					var err error
+					image, err = commands.LoadOCILayout(c.String("oci-layout"))
+					failIf(err)
+					atExit(func() { image.Remove() })
+				case c.String("rootfs-tar") != "":
+					_, err := os.Stat(c.String("rootfs-tar"))
+					failIf(err)
+
+					image = &commands.PulledImage{Path: c.String("rootfs-tar")}
+				}
+
+				if image != nil {
+					spec.Env = append(image.Env, spec.Env...)
+					opts.Populate = func(container garden.Container) error {
+						return commands.StreamArchive(container, image.Path)
+					}
+				}
+
+				if count == 1 {
+					err := commands.Create(client(c), spec, opts, os.Stdout)
+					forgetHandles(c)
+					failIf(err)
+					return
+				}
+
+				handles, err := commands.HandleTemplate(spec.Handle, count)
+				if err != nil {
+					fail(usageError(err.Error()))
+				}
+
+				results := commands.CreateMany(client(c), spec, handles, opts, concurrency)
+				forgetHandles(c)
+
+				failures := commands.Failures(results)
+				for _, result := range results {
+					if result.Err == nil {
+						fmt.Println(result.Handle)
+					}
+				}
+
+				for _, failure := range failures {
+					if failure.Handle == "" {
+						fmt.Fprintf(os.Stderr, "failed to create a container: %s\n", failure.Err)
+					} else {
+						fmt.Fprintf(os.Stderr, "failed to create %s: %s\n", failure.Handle, failure.Err)
+					}
+				}
+
+				if len(failures) > 0 {
+					fail(fmt.Errorf("failed to create %d of %d containers", len(failures), len(results)))
+				}
+			},
+		},
+		{
+			Name:         "update",
+			Usage:        "change a container's limits and properties together, undoing them all if the server rejects any",
+			Flags:        limitFlags(),
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				update := containerUpdate(c)
+				if update.IsZero() {
+					fail(usageError("must give a limit or property to change"))
+				}
+
+				results, err := commands.ApplyUpdate(client(c), handle(c), update)
+				failIf(err)
+
+				commands.PrintUpdateResults(os.Stdout, results)
+
+				if rejected := commands.RejectedChanges(results); rejected > 0 {
+					fail(fmt.Errorf("server rejected %d of %d changes", rejected, len(results)))
+				}
+			},
+		},
+		{
+			Name:  "destroy",
+			Usage: "destroy containers by handle, or every container matching a selection",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "all",
+					Usage: "destroy every container",
+				},
+				cli.StringFlag{
+					Name:  "match, m",
+					Usage: "destroy the containers whose handles match this glob (e.g. 'ci-*')",
+				},
+				cli.StringSliceFlag{
+					Name:  "filter",
+					Value: &cli.StringSlice{},
+					Usage: "destroy the containers with the property key=value",
+				},
+				cli.BoolFlag{
+					Name:  "force, f",
+					Usage: "do not ask before destroying selected or many containers, or any on a protected target",
+				},
+				cli.IntFlag{
+					Name:  "concurrency, c",
+					Value: 10,
+					Usage: "number of containers to destroy at once",
+				},
+				stdinFlag,
+			},
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				filter, err := commands.ParseProperties(c.StringSlice("filter"))
+				failIf(err)
+
+				selector := commands.Selector{
+					All:    c.Bool("all"),
+					Match:  c.String("match"),
+					Filter: filter,
+				}
+
+				handles := handles(c)
+
+				if selector.IsZero() && len(handles) == 0 {
+					handles = []string{pickHandle(c, "must provide container handles, --all, --match or --filter")}
+				}
+
+				switch {
+				case !selector.IsZero() && len(handles) > 0:
+					fail(usageError("cannot give container handles along with --all, --match or --filter"))
+				case !selector.IsZero():
+					handles, err = commands.SelectHandles(client(c), selector)
+					failIf(err)
+
+					if len(handles) == 0 {
+						fmt.Fprintln(unlessQuiet(c, os.Stderr), "no containers selected")
+						return
+					}
+
+				}
+
+				confirmDestroy(c, handles, !selector.IsZero())
+
+				concurrency := c.Int("concurrency")
+				if concurrency < 1 {
+					fail(usageError("--concurrency must be at least 1"))
+				}
+
+				if len(handles) == 1 {
+					err = commands.Destroy(client(c), handles)
+					forgetHandles(c)
+					failIf(err)
+					return
+				}
+
+				results := commands.DestroyConcurrently(client(c), handles, concurrency)
+				forgetHandles(c)
+
+				printResults(c, "destroyed", results)
+
+				if failures := commands.Failures(results); len(failures) > 0 {
+					fail(fmt.Errorf("failed to destroy %d of %d containers", len(failures), len(results)))
+				}
+			},
+		},
+		{
+			Name:  "prune",
+			Usage: "destroy old, idle or otherwise unwanted containers",
+			Flags: []cli.Flag{
+				cli.DurationFlag{
+					Name:  "older-than",
+					Usage: "prune containers gaol created longer ago than this",
+				},
+				cli.Float64Flag{
+					Name:  "idle-cpu",
+					Usage: "prune containers using less than this percentage of a CPU",
+				},
+				cli.DurationFlag{
+					Name:  "sample",
+					Value: 10 * time.Second,
+					Usage: "how long to measure CPU usage for with --idle-cpu",
+				},
+				cli.StringFlag{
+					Name:  "match, m",
+					Usage: "prune only containers whose handles match this glob",
+				},
+				cli.StringSliceFlag{
+					Name:  "filter",
+					Value: &cli.StringSlice{},
+					Usage: "prune only containers with the property key=value",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "print the containers which would be pruned without destroying them",
+				},
+				cli.BoolFlag{
+					Name:  "force, f",
+					Usage: "do not ask before destroying many containers or any on a protected target",
+				},
+				cli.IntFlag{
+					Name:  "concurrency, c",
+					Value: 10,
+					Usage: "number of containers to destroy at once",
+				},
+			},
+			Action: func(c *cli.Context) {
+				filter, err := commands.ParseProperties(c.StringSlice("filter"))
+				failIf(err)
+
+				criteria := commands.PruneCriteria{
+					Selector: commands.Selector{
+						Match:  c.String("match"),
+						Filter: filter,
+					},
+					OlderThan: c.Duration("older-than"),
+					IdleCPU:   c.Float64("idle-cpu"),
+					Sample:    c.Duration("sample"),
+				}
+
+				switch {
+				case len(c.Args()) > 0:
+					fail(usageError("prune selects containers itself; use destroy to destroy them by handle"))
+				case criteria.OlderThan < 0 || criteria.IdleCPU < 0 || criteria.Sample < 0:
+					fail(usageError("--older-than, --idle-cpu and --sample cannot be negative"))
+				case criteria.IsZero():
+					fail(usageError("must provide --older-than, --idle-cpu, --match or --filter"))
+				case c.Int("concurrency") < 1:
+					fail(usageError("--concurrency must be at least 1"))
+				}
+
+				handles, err := commands.Prunable(client(c), criteria)
+				failIf(err)
+
+				if c.Bool("dry-run") || c.GlobalBool("dry-run") {
+					for _, handle := range handles {
+						fmt.Println(handle)
+					}
+					return
+				}
+
+				if len(handles) == 0 {
+					fmt.Fprintln(unlessQuiet(c, os.Stderr), "nothing to prune")
+					return
+				}
+
+				confirmDestroy(c, handles, false)
+
+				results := commands.DestroyConcurrently(client(c), handles, c.Int("concurrency"))
+				forgetHandles(c)
+
+				printResults(c, "destroyed", results)
+
+				if failures := commands.Failures(results); len(failures) > 0 {
+					fail(fmt.Errorf("failed to destroy %d of %d containers", len(failures), len(results)))
+				}
+			},
+		},
+		{
+			Name:  "list",
+			Usage: "get a list of running containers",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "watch, w",
+					Usage: "keep refreshing the list, highlighting changes",
+				},
+				cli.DurationFlag{
+					Name:  "interval, i",
+					Value: 2 * time.Second,
+					Usage: "time between refreshes when watching",
+				},
+				outputFlag,
+				cli.StringFlag{
+					Name:  "match, m",
+					Usage: "only list the containers whose handles match this glob (e.g. 'ci-*')",
+				},
+				cli.StringSliceFlag{
+					Name:  "filter",
+					Value: &cli.StringSlice{},
+					Usage: "only list the containers with the property key=value",
+				},
+				cli.StringFlag{
+					Name:  "sort",
+					Usage: "sort the containers by age (oldest first), handle or memory (most first)",
+				},
+				cli.IntFlag{
+					Name:  "top",
+					Usage: "list only the first this many containers, after sorting",
+				},
+				cli.StringFlag{
+					Name:  "since",
+					Usage: "only list the containers gaol created since this long ago (e.g. 2h) or this RFC 3339 time",
+				},
+				cli.StringFlag{
+					Name:  "before",
+					Usage: "only list the containers gaol created before this long ago or this RFC 3339 time",
+				},
+				cli.BoolFlag{
+					Name:  "wide, l",
+					Usage: "also show each container's state and the events recorded for it, such as running out of memory",
+				},
+				cli.BoolFlag{
+					Name:  "cached",
+					Usage: "list the handles cached for completion, refreshing them in the background when stale",
+				},
+				cli.DurationFlag{
+					Name:   "cache-ttl",
+					Value:  defaultCacheTTL,
+					Usage:  "time for which cached handles are used before being refreshed",
+					EnvVar: "GAOL_CACHE_TTL",
+				},
+			},
+			Action: func(c *cli.Context) {
+				lines := jsonLines(c)
+				if lines != nil && !c.Bool("watch") {
+					fail(usageError("--output jsonl needs --watch"))
+				}
+
+				if c.Bool("watch") {
+					if outputFormat(c).Porcelain {
+						fail(usageError("cannot give --porcelain along with --watch"))
+					}
+
+					if c.Bool("wide") {
+						fail(usageError("cannot give --wide along with --watch"))
+					}
+
+					if c.Duration("interval") <= 0 {
+						fail(usageError("--interval must be positive"))
+					}
+
+					err := commands.WatchList(client(c), os.Stdout, c.Duration("interval"), outputFormat(c), lines)
+					failIf(err)
+					return
+				}
+
+				filter, err := commands.ParseProperties(c.StringSlice("filter"))
+				failIf(err)
+
+				selector := commands.Selector{
+					All:    true,
+					Match:  c.String("match"),
+					Filter: filter,
+				}
+
+				order := listOrder(c)
+
+				var handles []string
+
+				switch {
+				case !order.IsZero():
+					if c.Bool("cached") {
+						fail(usageError("cannot use --sort, --top, --since or --before with --cached"))
+					}
+
+					handles, err = commands.OrderedHandles(client(c), selector, order)
+					failIf(err)
+				case selector.Match != "" || len(selector.Filter) > 0:
+					if c.Bool("cached") {
+						fail(usageError("cannot use --match or --filter with --cached"))
+					}
+
+					handles, err = commands.SelectHandles(client(c), selector)
+					failIf(err)
+				case c.Bool("cached"):
+					handles, err = cachedHandles(c, c.Duration("cache-ttl"))
+					failIf(err)
+				default:
+					handles, err = commands.ListHandles(client(c))
+					failIf(err)
+
+					if c.GlobalString("replay") == "" {
+						writeHandleCache(currentTarget(c).Address, handles)
+					}
+				}
+
+				if c.Bool("wide") {
+					err = commands.ListWide(client(c), handles, os.Stdout, outputFormat(c))
+					failIf(err)
+					return
+				}
+
+				for _, handle := range handles {
+					fmt.Println(handle)
+				}
+			},
+		},
+		{
+			Name:  "info",
+			Usage: "show information about a container",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "watch, w",
+					Usage: "keep refreshing the information, highlighting changes",
+				},
+				cli.DurationFlag{
+					Name:  "interval, i",
+					Value: 2 * time.Second,
+					Usage: "time between refreshes when watching",
+				},
+				outputFlag,
+				stdinFlag,
+			},
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				handles := handles(c)
+				if len(handles) == 0 {
+					handles = []string{defaultHandle(c, "must provide container handle")}
+				}
+
+				lines := jsonLines(c)
+				if lines != nil && !c.Bool("watch") {
+					fail(usageError("--output jsonl needs --watch"))
+				}
+
+				porcelain := outputFormat(c).Porcelain
+
+				if c.Bool("watch") {
+					if porcelain {
+						fail(usageError("cannot give --porcelain along with --watch"))
+					}
+
+					if len(handles) > 1 {
+						fail(usageError("can only watch one container"))
+					}
+
+					if c.Duration("interval") <= 0 {
+						fail(usageError("--interval must be positive"))
+					}
+
+					err := commands.WatchInfo(client(c), handles[0], os.Stdout, c.Duration("interval"), outputFormat(c), lines)
+					failIf(err)
+					return
+				}
+
+				for i, handle := range handles {
+					// several containers are told apart by their handles,
+					// which start every line in porcelain
+					if len(handles) > 1 && !porcelain {
+						if i > 0 {
+							fmt.Println()
+						}
+
+						fmt.Println("handle: " + handle)
+					}
+
+					err := commands.Info(client(c), handle, os.Stdout, outputFormat(c))
+					failIf(err)
+				}
+			},
+		},
+		{
+			Name:  "events",
+			Usage: "show the events the server has recorded for a container, such as running out of memory",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "watch, w",
+					Usage: "keep printing new events, with when they were seen, until the container is gone",
+				},
+				cli.DurationFlag{
+					Name:  "interval, i",
+					Value: 2 * time.Second,
+					Usage: "time between checks for new events when watching",
+				},
+				outputFlag,
+			},
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				lines := jsonLines(c)
+				if lines != nil && !c.Bool("watch") {
+					fail(usageError("--output jsonl needs --watch"))
+				}
+
+				handle := handle(c)
+
+				if c.Bool("watch") {
+					if c.Duration("interval") <= 0 {
+						fail(usageError("--interval must be positive"))
+					}
+
+					err := commands.WatchEvents(client(c), handle, os.Stdout, c.Duration("interval"), lines)
+					failIf(err)
+					return
+				}
+
+				err := commands.Events(client(c), handle, os.Stdout)
+				failIf(err)
+			},
+		},
+		{
+			Name:  "stop",
+			Usage: "stop every process in containers, leaving the containers to be inspected",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "kill, k",
+					Usage: "kill the processes rather than asking them to terminate",
+				},
+				stdinFlag,
+			},
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				handles := handles(c)
+				if len(handles) == 0 {
+					handles = []string{defaultHandle(c, "must provide container handles")}
+				}
+
+				err := commands.Stop(client(c), handles, c.Bool("kill"))
+				failIf(err)
+			},
+		},
+		{
+			Name:  "run",
+			Usage: "run a command in a container",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "attach, a",
+					Usage: "attach to the process after it is started",
+				},
+				cli.StringFlag{
+					Name:  "dir, d",
+					Usage: "current working directory of process",
+				},
+				cli.BoolFlag{
+					Name:  "workdir-create",
+					Usage: "create the working directory, and its parents, if they do not exist",
+				},
+				cli.StringFlag{
+					Name:  "user, u",
+					Usage: "user to run the process as",
+				},
+				cli.StringSliceFlag{
+					Name:  "env, e",
+					Value: &cli.StringSlice{},
+					Usage: "environment variable to run the process with, as KEY=VALUE",
+				},
+				cli.BoolFlag{
+					Name:  "privileged, p",
+					Usage: "use privileged user in container",
+				},
+				cli.BoolFlag{
+					Name:  "all",
+					Usage: "run the command in every container, waiting for each",
+				},
+				cli.StringFlag{
+					Name:  "match, m",
+					Usage: "run the command in the containers whose handles match this glob",
+				},
+				cli.StringSliceFlag{
+					Name:  "filter",
+					Value: &cli.StringSlice{},
+					Usage: "run the command in the containers with the property key=value",
+				},
+				cli.IntFlag{
+					Name:  "concurrency, c",
+					Value: 10,
+					Usage: "number of containers to run the command in at once",
+				},
+				outputFlag,
+				cli.StringFlag{
+					Name:  "commands-file",
+					Usage: "file of commands to run one after another, one per line (- reads stdin)",
+				},
+				cli.BoolFlag{
+					Name:  "keep-going",
+					Usage: "carry on with the rest of the commands file after a command fails",
+				},
+				cli.StringFlag{
+					Name:  "restart",
+					Usage: "supervise the process, running it again when it exits: no, on-failure or always",
+				},
+				cli.IntFlag{
+					Name:  "max-restarts",
+					Usage: "give up supervising after this many restarts (0 never gives up)",
+				},
+				cli.StringFlag{
+					Name:  "name",
+					Usage: "name to record the supervised process under, instead of its command",
+				},
+				cli.BoolFlag{
+					Name:  "detach",
+					Usage: "supervise the process from gaol in the background, logging to ~/.gaol/supervise",
+				},
+				cli.DurationFlag{
+					Name:  "timeout",
+					Usage: "terminate the attached process if it is still running after this long (e.g. 5m)",
+				},
+				cli.DurationFlag{
+					Name:  "kill-after",
+					Value: 10 * time.Second,
+					Usage: "kill the process if it is still running this long after being terminated for its --timeout",
+				},
+				collectFlag(),
+				cli.StringFlag{
+					Name:  "result-file",
+					Usage: "write when the attached process started and finished, its exit status and the end of its output to this file as JSON",
+				},
+				junitFlag,
+			},
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				filter, err := commands.ParseProperties(c.StringSlice("filter"))
+				failIf(err)
+
+				selector := commands.Selector{
+					All:    c.Bool("all"),
+					Match:  c.String("match"),
+					Filter: filter,
+				}
+
+				opts := commands.RunOptions{
+					Attach:     c.Bool("attach"),
+					Dir:        c.String("dir"),
+					CreateDir:  c.Bool("workdir-create"),
+					User:       c.String("user"),
+					Privileged: c.Bool("privileged"),
+					Env:        c.StringSlice("env"),
+					Timeout:    c.Duration("timeout"),
+					KillAfter:  c.Duration("kill-after"),
+					Report:     junitReport(c),
+				}
+
+				checkPrivileged(c)
+
+				if opts.CreateDir && opts.Dir == "" {
+					fail(usageError("--workdir-create needs --dir"))
+				}
+
+				if opts.Timeout < 0 || opts.KillAfter <= 0 {
+					fail(usageError("--timeout and --kill-after must be positive"))
+				}
+
+				if opts.Timeout > 0 {
+					if c.String("restart") != "" {
+						fail(usageError("cannot give --timeout along with --restart"))
+					}
+
+					if !opts.Attach && selector.IsZero() && c.String("commands-file") == "" {
+						fail(usageError("--timeout needs --attach"))
+					}
+				}
+
+				if opts.Report != nil {
+					if c.String("restart") != "" {
+						fail(usageError("cannot give --junit along with --restart"))
+					}
+
+					if !opts.Attach && selector.IsZero() && c.String("commands-file") == "" {
+						fail(usageError("--junit needs --attach"))
+					}
+				}
+
+				if c.String("result-file") != "" || len(c.StringSlice("collect")) > 0 {
+					if !selector.IsZero() || c.String("commands-file") != "" || c.String("restart") != "" {
+						fail(usageError("cannot give --result-file or --collect along with --all, --match, --filter, --commands-file or --restart"))
+					}
+
+					if !opts.Attach {
+						fail(usageError("--result-file and --collect need --attach"))
+					}
+				}
+
+				lines := jsonLines(c)
+				if lines != nil && (selector.IsZero() || c.String("commands-file") != "" || c.String("restart") != "") {
+					fail(usageError("--output jsonl needs --all, --match or --filter"))
+				}
+
+				if c.String("restart") != "" {
+					if !selector.IsZero() || c.String("commands-file") != "" {
+						fail(usageError("cannot give --restart along with --all, --match, --filter or --commands-file"))
+					}
+
+					runSupervised(c, opts)
+					return
+				}
+
+				if c.Int("max-restarts") != 0 || c.String("name") != "" || c.Bool("detach") {
+					fail(usageError("--max-restarts, --name and --detach need --restart"))
+				}
+
+				if path := c.String("commands-file"); path != "" {
+					if !selector.IsZero() {
+						fail(usageError("cannot give --commands-file along with --all, --match or --filter"))
+					}
+
+					runCommandsFile(c, path, opts)
+					return
+				}
+
+				if !selector.IsZero() {
+					runSelected(c, selector, opts, lines)
+					return
+				}
+
+				handle, command := handleAndCommand(c)
+				runOne(c, handle, command, opts)
+			},
+		},
+		{
+			Name:  "attach",
+			Usage: "attach to command running in the container: attach <handle> [pid]",
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "pid, p",
+					Usage: "process id to connect to",
+				},
+				cli.BoolFlag{
+					Name:  "last",
+					Usage: "connect to the process gaol most recently started in the background in the container",
+				},
+				processNameFlag,
+				processCommandFlag,
+				reconnectFlag,
+				reconnectAttemptsFlag,
+			},
+			BashComplete: pidComplete,
+			Action: func(c *cli.Context) {
+				handle := handle(c)
+				pid := pickPID(c, handle)
+
+				err := commands.Attach(client(c), handle, pid, garden.ProcessIO{
+					Stdin:  os.Stdin,
+					Stdout: os.Stdout,
+					Stderr: os.Stderr,
+				}, reconnect(c))
+				failIf(err)
+			},
+		},
+		{
+			Name:  "wait-for-port",
+			Usage: "wait until something in the container listens on a port: wait-for-port <handle> <port>",
+			Flags: []cli.Flag{
+				cli.DurationFlag{
+					Name:  "timeout, t",
+					Value: time.Minute,
+					Usage: "give up after this much time has passed (0 never gives up)",
+				},
+				cli.DurationFlag{
+					Name:  "interval, i",
+					Value: time.Second,
+					Usage: "time between checks",
+				},
+				cli.BoolFlag{
+					Name:  "via-host",
+					Usage: "connect to the host port mapped to the port, instead of looking inside the container",
+				},
+			},
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				handle := handle(c)
+				if len(c.Args()) < 2 {
+					fail(usageError("must provide the port to wait for"))
+				}
+
+				port, err := strconv.ParseUint(c.Args()[1], 10, 16)
+				if err != nil || port == 0 {
+					fail(usageError(fmt.Sprintf("invalid port: %s", c.Args()[1])))
+				}
+
+				err = commands.WaitForPort(client(c), handle, uint32(port), c.Bool("via-host"), c.Duration("timeout"), c.Duration("interval"))
+				failIf(err)
+			},
+		},
+		{
+			Name:  "wait-for-exit",
+			Usage: "wait until a process in the container exits, printing its exit status",
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "pid, p",
+					Usage: "process id to wait for",
+				},
+				processNameFlag,
+				processCommandFlag,
+				cli.DurationFlag{
+					Name:  "timeout, t",
+					Value: time.Minute,
+					Usage: "give up after this much time has passed (0 never gives up)",
+				},
+			},
+			BashComplete: pidComplete,
+			Action: func(c *cli.Context) {
+				handle := handle(c)
+
+				pid := pickPID(c, handle)
+				if pid == 0 {
+					fail(usageError("must provide --pid, --name or --command of the process to wait for"))
+				}
+
+				status, err := commands.WaitForExit(client(c), handle, pid, c.Duration("timeout"))
+				failIf(err)
+
+				fmt.Println(status)
+
+				if status != 0 {
+					fail(commands.ProcessExitError{Command: fmt.Sprintf("process %d", pid), Status: status})
+				}
+			},
+		},
+		{
+			Name:  "signal",
+			Usage: "ask a process in the container to terminate: signal <handle> [pid]",
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "pid, p",
+					Usage: "process id to signal",
+				},
+				cli.BoolFlag{
+					Name:  "last",
+					Usage: "signal the process gaol most recently started in the background in the container",
+				},
+				processNameFlag,
+				processCommandFlag,
+				cli.BoolFlag{
+					Name:  "kill, k",
+					Usage: "kill the process rather than asking it to terminate",
+				},
+			},
+			BashComplete: pidComplete,
+			Action: func(c *cli.Context) {
+				handle := handle(c)
+
+				pid := pickPID(c, handle)
+				if pid == 0 {
+					fail(usageError("must provide the process to signal"))
+				}
+
+				err := commands.Signal(client(c), handle, pid, c.Bool("kill"))
+				failIf(err)
+			},
+		},
+		{
+			Name:         "procs",
+			Usage:        "list the processes gaol has started in a container, most recent last",
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				records, err := commands.Processes(client(c), handle(c))
+				failIf(err)
+
+				commands.PrintProcesses(os.Stdout, records)
+			},
+		},
+		{
+			Name:  "shell",
+			Usage: "open a shell inside the running container",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "command, C",
+					Usage: "shell command to run first, such as 'cd /app && . ./env.sh', before handing over the shell",
+				},
+				reconnectFlag,
+				reconnectAttemptsFlag,
+			},
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				container, err := client(c).Lookup(handle(c))
+				failIf(err)
+
+				err = commands.Shell(container, c.String("command"), reconnect(c))
+				failIf(err)
+			},
+		},
+		{
+			Name:  "stream-in",
+			Usage: "stream data into the container",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "to-file, t",
+					Usage: "destination path in the container",
+				},
+				cli.IntFlag{
+					Name:  "size",
+					Usage: "number of bytes on stdin, when it is a pipe, sparing the container from joining chunks of larger input",
+				},
+				cli.BoolFlag{
+					Name:  "resume",
+					Usage: "stream a file on stdin in chunks, retrying each, and carry on from the last complete chunk if run again",
+				},
+				cli.IntFlag{
+					Name:  "chunk-size",
+					Value: 64 << 20,
+					Usage: "size in bytes of the chunks streamed by --resume",
+				},
+				cli.BoolFlag{
+					Name:  "extract, x",
+					Usage: "extract a tar or gzipped tar on stdin into the directory --to-file, streaming it as it is",
+				},
+				cli.StringFlag{
+					Name:  "from-dir",
+					Usage: "stream this local directory into the directory --to-file, rather than stdin, keeping its links, empty directories and device nodes",
+				},
+				cli.BoolFlag{
+					Name:  "dereference, L",
+					Usage: "stream what symlinks under --from-dir point to rather than the links",
+				},
+				cli.StringSliceFlag{
+					Name:  "exclude",
+					Value: &cli.StringSlice{},
+					Usage: "leave out what under --from-dir matches this glob, such as node_modules, *.pyc or /build/; as are those in its .gaolignore",
+				},
+				cli.StringFlag{
+					Name:  "chown",
+					Usage: "owner of what is streamed in, as user:group, user or :group, by name or id",
+				},
+				cli.StringFlag{
+					Name:  "chmod",
+					Usage: "octal mode of what is streamed in, such as 0640; directories extracted by --extract can also be searched wherever they can be read",
+				},
+			},
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				handle := handle(c)
+
+				dst := c.String("to-file")
+				if dst == "" {
+					fail(usageError("missing --to-file argument"))
+				}
+
+				attrs := attributes(c)
+
+				if (c.Bool("dereference") || len(c.StringSlice("exclude")) > 0) && c.String("from-dir") == "" {
+					fail(usageError("--dereference and --exclude need --from-dir"))
+				}
+
+				if err := commands.CheckExcludes(c.StringSlice("exclude")); err != nil {
+					fail(usageError(err.Error()))
+				}
+
+				if src := c.String("from-dir"); src != "" {
+					if c.Bool("extract") || c.Bool("resume") || c.IsSet("size") {
+						fail(usageError("--from-dir cannot be used with --extract, --resume or --size"))
+					}
+
+					err := commands.StreamInTree(client(c), handle, src, dst, commands.TreeOptions{
+						Dereference: c.Bool("dereference"),
+						Exclude:     c.StringSlice("exclude"),
+					}, attrs)
+					failIf(err)
+					return
+				}
+
+				if c.Bool("extract") {
+					if c.Bool("resume") || c.IsSet("size") {
+						fail(usageError("--extract cannot be used with --resume or --size"))
+					}
+
+					err := commands.StreamInArchive(client(c), handle, dst, os.Stdin, attrs)
+					failIf(err)
+					return
+				}
+
+				if c.Bool("resume") {
+					stat, err := os.Stdin.Stat()
+					if err != nil || !stat.Mode().IsRegular() {
+						fail(usageError("--resume needs stdin to be a file, such as with < file"))
+					}
+
+					if c.Int("chunk-size") < 1 {
+						fail(usageError("--chunk-size must be at least 1"))
+					}
+
+					err = commands.StreamInResumable(client(c), handle, dst, os.Stdin, stat.Size(), int64(c.Int("chunk-size")), attrs, unlessQuiet(c, os.Stderr))
+					failIf(err)
+					return
+				}
+
+				size := int64(-1)
+				if c.IsSet("size") {
+					size = int64(c.Int("size"))
+					if size < 0 {
+						fail(usageError("--size cannot be negative"))
+					}
+				} else if stat, err := os.Stdin.Stat(); err == nil && stat.Mode().IsRegular() {
+					size = stat.Size()
+				}
+
+				err := commands.StreamIn(client(c), handle, dst, os.Stdin, size, attrs)
+				failIf(err)
+			},
+		},
+		{
+			Name:  "stream-out",
+			Usage: "stream data out of the container",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "from-file, f",
+					Usage: "source path in the container",
+				},
+				cli.StringFlag{
+					Name:  "to-dir, d",
+					Usage: "extract the file or directory into this local directory rather than write the file to stdout",
+				},
+				cli.BoolFlag{
+					Name:  "preserve, p",
+					Usage: "keep the modes of what --to-dir extracts and, when run as root, its owners",
+				},
+			},
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				handle := handle(c)
+
+				src := c.String("from-file")
+				if src == "" {
+					fail(usageError("missing --from-file argument"))
+				}
+
+				if dir := c.String("to-dir"); dir != "" {
+					err := commands.StreamOutTo(client(c), handle, src, dir, c.Bool("preserve"), os.Stderr)
+					failIf(err)
+					return
+				}
+
+				if c.Bool("preserve") {
+					fail(usageError("--preserve needs --to-dir"))
+				}
+
+				err := commands.StreamOut(client(c), handle, src, os.Stdout)
+				failIf(err)
+			},
+		},
+		{
+			Name:  "cp",
+			Usage: "copy a local file or directory into containers, reading it once however many: cp <src> <handle>:/path, @all:/path, @match:<glob>:/path or @filter:<key=value>:/path",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "all",
+					Usage: "copy into every container, the destination being just a path",
+				},
+				cli.StringFlag{
+					Name:  "match, m",
+					Usage: "copy into the containers whose handles match this glob (e.g. 'web-*')",
+				},
+				cli.StringSliceFlag{
+					Name:  "filter",
+					Value: &cli.StringSlice{},
+					Usage: "copy into the containers with the property key=value",
+				},
+				cli.StringFlag{
+					Name:  "chown",
+					Usage: "owner of what is copied, as user:group, user or :group, by name or id",
+				},
+				cli.StringFlag{
+					Name:  "chmod",
+					Usage: "octal mode of what is copied, such as 0640",
+				},
+				cli.BoolFlag{
+					Name:  "dereference, L",
+					Usage: "copy what symlinks in a directory point to rather than the links",
+				},
+				cli.StringSliceFlag{
+					Name:  "exclude",
+					Value: &cli.StringSlice{},
+					Usage: "leave out what in a directory matches this glob, as stream-in --exclude does",
+				},
+			},
+			Action: func(c *cli.Context) {
+				if len(c.Args()) != 2 {
+					fail(usageError("must provide source and destination"))
+				}
+
+				if err := commands.CheckExcludes(c.StringSlice("exclude")); err != nil {
+					fail(usageError(err.Error()))
+				}
+
+				attrs := attributes(c)
+
+				filter, err := commands.ParseProperties(c.StringSlice("filter"))
+				if err != nil {
+					fail(usageError(err.Error()))
+				}
+
+				handles, dst := copyDestination(c, c.Args()[1], commands.Selector{
+					All:    c.Bool("all"),
+					Match:  c.String("match"),
+					Filter: filter,
+				})
+
+				results := commands.CopyIn(client(c), handles, c.Args()[0], dst, commands.TreeOptions{
+					Dereference: c.Bool("dereference"),
+					Exclude:     c.StringSlice("exclude"),
+				}, attrs)
+
+				printResults(c, "copied", results)
+
+				if failures := commands.Failures(results); len(failures) > 0 {
+					fail(fmt.Errorf("failed to copy into %d of %d containers", len(failures), len(results)))
+				}
+			},
+		},
+		{
+			Name:  "cat",
+			Usage: "write files in containers to stdout: cat <handle>:/path..., or /path in the current container",
+			Action: func(c *cli.Context) {
+				if !c.Args().Present() {
+					fail(usageError("must provide file"))
+				}
+
+				for _, arg := range c.Args() {
+					handle, file := containerFile(c, arg)
+
+					err := commands.Cat(client(c), handle, file, os.Stdout)
+					failIf(err)
+				}
+			},
+		},
+		{
+			Name:  "tail",
+			Usage: "write the end of a file in a container to stdout: tail <handle>:/path, or /path in the current container",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "follow, f",
+					Usage: "keep writing what is added to the file, across log rotations, until interrupted",
+				},
+				cli.IntFlag{
+					Name:  "lines, n",
+					Value: 10,
+					Usage: "number of lines from the end to start with",
+				},
+			},
+			Action: func(c *cli.Context) {
+				if len(c.Args()) != 1 {
+					fail(usageError("must provide one file"))
+				}
+
+				if c.Int("lines") < 0 {
+					fail(usageError("--lines cannot be negative"))
+				}
+
+				handle, file := containerFile(c, c.Args().First())
+
+				err := commands.Tail(client(c), handle, file, c.Int("lines"), c.Bool("follow"), garden.ProcessIO{
+					Stdout: os.Stdout,
+					Stderr: os.Stderr,
+				})
+				failIf(err)
+			},
+		},
+		{
+			Name:  "edit",
+			Usage: "edit a file in a container with $EDITOR, keeping its mode and owner: edit <handle>:/path, or /path in the current container",
+			Action: func(c *cli.Context) {
+				if len(c.Args()) != 1 {
+					fail(usageError("must provide one file"))
+				}
+
+				handle, file := containerFile(c, c.Args().First())
+
+				err := commands.Edit(client(c), handle, file, runEditor, unlessQuiet(c, os.Stderr))
+				failIf(err)
+			},
+		},
+		{
+			Name:  "net-in",
+			Usage: "map a port on the host to a port in the container",
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "port, p",
+					Usage: "container port",
+				},
+			},
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				host, err := targetHost(currentTarget(c).Address)
+				failIf(err)
+
+				// the port is all a script needs
+				if c.GlobalBool("quiet") {
+					host = ""
+				}
+
+				err = commands.NetIn(client(c), handle(c), host, uint32(c.Int("port")), os.Stdout)
+				failIf(err)
+			},
+		},
+		{
+			Name:  "top",
+			Usage: "show a live table of containers and their resource usage",
+			Flags: []cli.Flag{
+				cli.DurationFlag{
+					Name:  "interval, i",
+					Value: 2 * time.Second,
+					Usage: "time between refreshes",
+				},
+				cli.StringFlag{
+					Name:  "sort, s",
+					Value: "cpu",
+					Usage: "column to sort by (cpu, memory, disk, or handle)",
+				},
+				cli.Float64Flag{
+					Name:  "memory-alert",
+					Usage: "show containers using at least this percentage of their memory limit in red",
+				},
+				cli.Float64Flag{
+					Name:  "disk-alert",
+					Usage: "show containers using at least this percentage of their disk limit in red",
+				},
+			},
+			Action: func(c *cli.Context) {
+				if c.Duration("interval") <= 0 {
+					fail(usageError("--interval must be positive"))
+				}
+
+				thresholds := commands.Thresholds{
+					Memory: c.Float64("memory-alert"),
+					Disk:   c.Float64("disk-alert"),
+				}
+
+				err := commands.Top(client(c), c.Duration("interval"), c.String("sort"), thresholds)
+				failIf(err)
+			},
+		},
+		{
+			Name:  "metrics",
+			Usage: "print the resource usage of every container once: its handle, memory, cache, swap, disk, inodes, cpu and processes",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "match, m",
+					Usage: "only print the containers whose handles match this glob (e.g. 'ci-*')",
+				},
+				cli.StringSliceFlag{
+					Name:  "filter",
+					Value: &cli.StringSlice{},
+					Usage: "only print the containers with the property key=value",
+				},
+				cli.StringFlag{
+					Name:  "sort, s",
+					Usage: "sort the containers by handle or by a metric (most first)",
+				},
+				cli.StringSliceFlag{
+					Name:  "where",
+					Value: &cli.StringSlice{},
+					Usage: "only print the containers for which this condition holds (e.g. 'mem>500M' or 'cpu>=1h')",
+				},
+				cli.IntFlag{
+					Name:  "top",
+					Usage: "print only the first this many containers, after sorting",
+				},
+			},
+			Action: func(c *cli.Context) {
+				sortBy, err := commands.ParseMetricsSort(c.String("sort"))
+				if err != nil {
+					fail(usageError(err.Error()))
+				}
+
+				if c.Int("top") < 0 {
+					fail(usageError("--top must be at least 1"))
+				}
+
+				query := commands.MetricsQuery{Sort: sortBy, Top: c.Int("top")}
+
+				for _, raw := range c.StringSlice("where") {
+					condition, err := commands.ParseCondition(raw)
+					if err != nil {
+						fail(usageError(err.Error()))
+					}
+
+					query.Where = append(query.Where, condition)
+				}
+
+				filter, err := commands.ParseProperties(c.StringSlice("filter"))
+				failIf(err)
+
+				selector := commands.Selector{
+					All:    true,
+					Match:  c.String("match"),
+					Filter: filter,
+				}
+
+				err = commands.Metrics(client(c), selector, query, os.Stdout, outputFormat(c))
+				failIf(err)
+			},
+		},
+		{
+			Name:  "snapshot",
+			Usage: "record the containers on the current target and their usage, to compare with later",
+			Subcommands: []cli.Command{
+				{
+					Name:  "save",
+					Usage: "record the containers and their usage now under a name: save <name>",
+					Action: func(c *cli.Context) {
+						if !c.Args().Present() {
+							fail(usageError("must provide snapshot name"))
+						}
+
+						snapshot, err := commands.TakeSnapshot(client(c))
+						failIf(err)
+
+						snapshot.Target = currentTarget(c).Address
+						failIf(saveSnapshot(c.Args().First(), snapshot))
+
+						fmt.Fprintf(unlessQuiet(c, os.Stderr), "saved %d containers as %s\n", len(snapshot.Containers), c.Args().First())
+					},
+				},
+				{
+					Name:  "list",
+					Usage: "list the snapshots: their names, when they were taken, targets and numbers of containers",
+					Action: func(c *cli.Context) {
+						lines, err := listSnapshots()
+						failIf(err)
+
+						for _, line := range lines {
+							fmt.Println(line)
+						}
+					},
+				},
+				{
+					Name:  "diff",
+					Usage: "show the containers created and destroyed and how their usage changed between two snapshots, or since one: diff <before> [after]",
+					Action: func(c *cli.Context) {
+						if len(c.Args()) != 1 && len(c.Args()) != 2 {
+							fail(usageError("must provide one or two snapshot names"))
+						}
+
+						before, err := loadSnapshot(c.Args()[0])
+						failIf(err)
+
+						var after commands.Snapshot
+						if len(c.Args()) == 2 {
+							after, err = loadSnapshot(c.Args()[1])
+						} else {
+							after, err = commands.TakeSnapshot(client(c))
+							after.Target = currentTarget(c).Address
+						}
+						failIf(err)
+
+						if before.Target != after.Target {
+							fmt.Fprintf(os.Stderr, "warning: comparing snapshots of %s and %s\n", before.Target, after.Target)
+						}
+
+						commands.DiffSnapshots(before, after, os.Stdout)
+					},
+				},
+			},
+		},
+		{
+			Name:  "graph",
+			Usage: "draw a rolling sparkline of a container's cpu, memory or disk use",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "metric, m",
+					Value: "cpu",
+					Usage: "what to graph (cpu, mem or disk)",
+				},
+				cli.DurationFlag{
+					Name:  "interval, i",
+					Value: time.Second,
+					Usage: "time between samples",
+				},
+				cli.IntFlag{
+					Name:  "width",
+					Value: 60,
+					Usage: "number of samples to show",
+				},
+			},
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				if c.Int("width") < 1 {
+					fail(usageError("--width must be at least 1"))
+				}
+
+				if c.Duration("interval") <= 0 {
+					fail(usageError("--interval must be positive"))
+				}
+
+				switch c.String("metric") {
+				case "cpu", "mem", "disk":
+				default:
+					fail(usageError("--metric must be cpu, mem or disk"))
+				}
+
+				err := commands.Graph(client(c), handle(c), c.String("metric"), c.Duration("interval"), c.Int("width"), os.Stdout)
+				failIf(err)
+			},
+		},
+		{
+			Name:  "export-metrics",
+			Usage: "serve container metrics for Prometheus to scrape",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "listen, l",
+					Value: ":9100",
+					Usage: "address to serve metrics on",
+				},
+				cli.DurationFlag{
+					Name:  "interval, i",
+					Value: 15 * time.Second,
+					Usage: "time between collections",
+				},
+				cli.StringSliceFlag{
+					Name:  "filter",
+					Value: &cli.StringSlice{},
+					Usage: "only export containers with the property key=value",
+				},
+				cli.StringSliceFlag{
+					Name:  "label-property",
+					Value: &cli.StringSlice{},
+					Usage: "property to add to every metric as a label",
+				},
+			},
+			Action: func(c *cli.Context) {
+				filter, err := commands.ParseProperties(c.StringSlice("filter"))
+				failIf(err)
+
+				if c.Duration("interval") <= 0 {
+					fail(usageError("--interval must be positive"))
+				}
+
+				err = commands.ExportMetrics(client(c), c.String("listen"), c.Duration("interval"), filter, c.StringSlice("label-property"))
+				failIf(err)
+			},
+		},
+		{
+			Name:  "push-metrics",
+			Usage: "periodically send container metrics to statsd",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "statsd, s",
+					Value: "localhost:8125",
+					Usage: "statsd server to send metrics to",
+				},
+				cli.DurationFlag{
+					Name:  "interval, i",
+					Value: 10 * time.Second,
+					Usage: "time between sends",
+				},
+				cli.StringFlag{
+					Name:  "prefix",
+					Value: "gaol.",
+					Usage: "prefix for the name of every metric",
+				},
+				cli.StringSliceFlag{
+					Name:  "filter",
+					Value: &cli.StringSlice{},
+					Usage: "only send metrics for containers with the property key=value",
+				},
+				cli.StringSliceFlag{
+					Name:  "tag-property",
+					Value: &cli.StringSlice{},
+					Usage: "property to add to every metric as a tag",
+				},
+			},
+			Action: func(c *cli.Context) {
+				filter, err := commands.ParseProperties(c.StringSlice("filter"))
+				failIf(err)
+
+				if c.Duration("interval") <= 0 {
+					fail(usageError("--interval must be positive"))
+				}
+
+				err = commands.PushMetrics(client(c), c.String("statsd"), c.Duration("interval"), c.String("prefix"), filter, c.StringSlice("tag-property"), unlessQuiet(c, os.Stderr))
+				failIf(err)
+			},
+		},
+		{
+			Name:  "port-forward",
+			Usage: "forward local ports to ports in the container",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "address, a",
+					Value: "127.0.0.1",
+					Usage: "local address to listen on",
+				},
+			},
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				handle := handle(c)
+
+				if len(c.Args()) < 2 {
+					fail(usageError("must provide at least one LOCAL:CONTAINER port mapping"))
+				}
+
+				mappings := []commands.PortMapping{}
+				for _, spec := range c.Args()[1:] {
+					mapping, err := commands.ParsePortMapping(spec)
+					failIf(err)
+
+					mappings = append(mappings, mapping)
+				}
+
+				host, err := targetHost(currentTarget(c).Address)
+				failIf(err)
+
+				container, err := client(c).Lookup(handle)
+				failIf(err)
+
+				err = commands.PortForward(container, host, c.String("address"), mappings, unlessQuiet(c, os.Stdout))
+				failIf(err)
+			},
+		},
+		{
+			Name:  "curl",
+			Usage: "make an http request to a server in the container: curl <handle> http://localhost:8080/health",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "request, X",
+					Usage: "request method (defaults to GET, or POST with --data)",
+				},
+				cli.StringSliceFlag{
+					Name:  "header, H",
+					Value: &cli.StringSlice{},
+					Usage: "header to send, as Name: value",
+				},
+				cli.StringFlag{
+					Name:  "data, d",
+					Usage: "body to send; @file reads it from a file",
+				},
+				cli.BoolFlag{
+					Name:  "include, i",
+					Usage: "print the response's status line and headers before its body",
+				},
+				cli.BoolFlag{
+					Name:  "fail, f",
+					Usage: "fail if the response's status is 400 or above",
+				},
+				cli.BoolFlag{
+					Name:  "insecure, k",
+					Usage: "do not verify the certificate of an https server",
+				},
+				cli.DurationFlag{
+					Name:  "max-time, m",
+					Value: 30 * time.Second,
+					Usage: "give up on the request after this much time",
+				},
+				cli.BoolFlag{
+					Name:  "inside",
+					Usage: "make the request from inside the container with gaol-helper, rather than through a mapped port",
+				},
+			},
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				handle := handle(c)
+				if len(c.Args()) < 2 {
+					fail(usageError("must provide the url to request"))
+				}
+
+				data := c.String("data")
+				if strings.HasPrefix(data, "@") {
+					contents, err := ioutil.ReadFile(data[1:])
+					failIf(err)
+
+					data = string(contents)
+				}
+
+				if c.Bool("inside") {
+					curlInside(c, handle, data)
+					return
+				}
+
+				host, err := targetHost(currentTarget(c).Address)
+				failIf(err)
+
+				container, err := client(c).Lookup(handle)
+				failIf(err)
+
+				response, err := commands.Curl(container, host, c.Args()[1], commands.CurlRequest{
+					Method:   c.String("request"),
+					Headers:  c.StringSlice("header"),
+					Data:     data,
+					Include:  c.Bool("include"),
+					Insecure: c.Bool("insecure"),
+					Timeout:  c.Duration("max-time"),
+				}, os.Stdout)
+				failIf(err)
+
+				if !c.Bool("include") {
+					fmt.Fprintln(unlessQuiet(c, os.Stderr), response.Status)
+				}
+
+				if c.Bool("fail") && response.StatusCode >= 400 {
+					fail(fmt.Errorf("%s responded %s", c.Args()[1], response.Status))
+				}
+			},
+		},
+		{
+			Name:  "diff",
+			Usage: "show the files added, removed or changed under a path between two containers: diff <handle> <other-handle> <path>",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "local, l",
+					Usage: "compare the container against this local directory instead: diff --local <dir> <handle> <path>",
+				},
+			},
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				local := c.String("local")
+
+				names := 2
+				if local != "" {
+					names = 1
+				}
+
+				if len(c.Args()) != names+1 {
+					if local != "" {
+						fail(usageError("must provide a container handle and a path"))
+					}
+
+					fail(usageError("must provide two container handles and a path"))
+				}
+
+				dir := c.Args()[names]
+				handles := resolveHandles(c, c.Args()[:names]...)
+
+				trees := []commands.Tree{}
+				if local != "" {
+					trees = append(trees, commands.Tree{Dir: local})
+				}
+
+				for _, handle := range handles {
+					container, err := client(c).Lookup(handle)
+					failIf(err)
+
+					trees = append(trees, commands.Tree{Container: container, Dir: dir})
+				}
+
+				differences, err := commands.Diff(trees[0], trees[1])
+				failIf(err)
+
+				commands.PrintDifferences(os.Stdout, differences)
+
+				// like diff(1), differing is a failure for scripts
+				if len(differences) > 0 {
+					exit(exitFailure)
+				}
+			},
+		},
+		{
+			Name:  "df",
+			Usage: "show how much disk a container uses, and which of its directories use it",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "dir, d",
+					Value: "/",
+					Usage: "directory in the container whose entries to break the usage down by",
+				},
+				cli.IntFlag{
+					Name:  "top",
+					Usage: "show only this many of the largest entries",
+				},
+			},
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				if c.Int("top") < 0 {
+					fail(usageError("--top cannot be negative"))
+				}
+
+				helper, err := loadHelper(c)
+				failIf(err)
+
+				usage, err := commands.DiskFree(client(c), handle(c), helper, c.String("dir"))
+				failIf(err)
+
+				if top := c.Int("top"); top > 0 && len(usage.Entries) > top {
+					usage.Entries = usage.Entries[:top]
+				}
+
+				commands.PrintDiskUsage(os.Stdout, usage)
+			},
+		},
+		{
+			Name:  "grep",
+			Usage: "search the files under a path in containers for lines matching an extended regular expression: grep <pattern> <path> [handle...]",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "ignore-case, i",
+					Usage: "match regardless of case",
+				},
+				cli.BoolFlag{
+					Name:  "all",
+					Usage: "search every container",
+				},
+				cli.StringFlag{
+					Name:  "match, m",
+					Usage: "search the containers whose handles match this glob (e.g. 'web-*')",
+				},
+				cli.StringSliceFlag{
+					Name:  "filter",
+					Value: &cli.StringSlice{},
+					Usage: "search the containers with the property key=value",
+				},
+				cli.IntFlag{
+					Name:  "concurrency, c",
+					Value: 10,
+					Usage: "number of containers to search at once",
+				},
+			},
+			Action: func(c *cli.Context) {
+				args := c.Args()
+				if len(args) < 2 {
+					fail(usageError("must provide pattern and path"))
+				}
+
+				pattern, dir := args[0], args[1]
+				if !strings.HasPrefix(dir, "/") {
+					fail(usageError(fmt.Sprintf("path %s is not absolute", dir)))
+				}
+
+				if c.Int("concurrency") < 1 {
+					fail(usageError("--concurrency must be at least 1"))
+				}
+
+				filter, err := commands.ParseProperties(c.StringSlice("filter"))
+				failIf(err)
+
+				selector := commands.Selector{
+					All:    c.Bool("all"),
+					Match:  c.String("match"),
+					Filter: filter,
+				}
+
+				handles := resolveHandles(c, args[2:]...)
+
+				switch {
+				case !selector.IsZero() && len(handles) > 0:
+					fail(usageError("cannot give container handles along with --all, --match or --filter"))
+				case !selector.IsZero():
+					handles, err = commands.SelectHandles(client(c), selector)
+					failIf(err)
+				case len(handles) == 0:
+					handles = []string{defaultHandle(c, "must provide container handles, --all, --match or --filter")}
+				}
+
+				// containers without grep are searched by streaming their
+				// files out if there is no helper either
+				helper, err := loadHelper(c)
+				if err != nil {
+					helper = nil
+				}
+
+				matched, results := commands.Grep(client(c), handles, pattern, dir, commands.GrepOptions{
+					IgnoreCase:  c.Bool("ignore-case"),
+					Helper:      helper,
+					Concurrency: c.Int("concurrency"),
+				}, os.Stdout)
+
+				failures := commands.Failures(results)
+				if len(failures) > 0 {
+					commands.PrintResults(os.Stderr, "searched", failures)
+					fail(fmt.Errorf("failed to search %d of %d containers", len(failures), len(results)))
+				}
+
+				// as grep does, finding nothing is a failure
+				if matched == 0 {
+					exit(exitFailure)
+				}
+			},
+		},
+		{
+			Name:         "helper",
+			Usage:        "run one of gaol-helper's tools in the container: helper <handle> connect|curl|ps|sha256 [args]",
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				handle := handle(c)
+				if len(c.Args()) < 2 {
+					fail(usageError("must provide the tool to run"))
+				}
+
+				helper, err := loadHelper(c)
+				failIf(err)
+
+				err = commands.RunHelperTool(client(c), handle, helper, c.Args()[1:], garden.ProcessIO{
+					Stdin:  os.Stdin,
+					Stdout: os.Stdout,
+					Stderr: os.Stderr,
+				})
+				failIf(err)
+			},
+		},
+		{
+			Name:  "proxy",
+			Usage: "run a local SOCKS5 and HTTP proxy into the container's network",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "listen, l",
+					Value: "127.0.0.1:1080",
+					Usage: "local address to listen on",
+				},
+				cli.StringFlag{
+					Name:  "netcat",
+					Value: "nc",
+					Usage: "command in the container which connects to a host and port",
+				},
+			},
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				container, err := client(c).Lookup(handle(c))
+				failIf(err)
+
+				err = commands.Proxy(container, c.String("listen"), c.String("netcat"), unlessQuiet(c, os.Stdout))
+				failIf(err)
+			},
+		},
+		{
+			Name:  "sshd",
+			Usage: "serve SSH sessions into containers named by the SSH user",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "listen, l",
+					Value: "127.0.0.1:2222",
+					Usage: "address to listen on",
+				},
+				cli.StringFlag{
+					Name:  "host-key",
+					Usage: "private key identifying the server (generated if not given)",
+				},
+				cli.StringFlag{
+					Name:  "authorized-keys",
+					Value: filepath.Join(os.Getenv("HOME"), ".ssh", "authorized_keys"),
+					Usage: "public keys which may connect",
+				},
+				cli.BoolFlag{
+					Name:  "no-auth",
+					Usage: "allow anyone to connect without authenticating, when listening on a loopback address",
+				},
+				cli.StringFlag{
+					Name:  "sftp-server",
+					Value: "/usr/lib/openssh/sftp-server",
+					Usage: "path of the sftp server in the containers",
+				},
+			},
+			Action: func(c *cli.Context) {
+				err := commands.SSHD(client(c), commands.SSHDOptions{
+					Listen:         c.String("listen"),
+					HostKey:        c.String("host-key"),
+					AuthorizedKeys: c.String("authorized-keys"),
+					NoAuth:         c.Bool("no-auth"),
+					SFTPServer:     c.String("sftp-server"),
+				}, unlessQuiet(c, os.Stdout))
+				failIf(err)
+			},
+		},
+		{
+			Name:         "console",
+			Usage:        "start an interactive prompt for running commands",
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				err := runConsole(c, c.Args().First())
+				failIf(err)
+			},
+		},
+		{
+			Name:  "history",
+			Usage: "list the last 100 commands, numbered for rerun",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "clear",
+					Usage: "forget the commands",
+				},
+			},
+			Action: func(c *cli.Context) {
+				if c.Bool("clear") {
+					err := saveHistory(nil)
+					failIf(err)
+					return
+				}
+
+				entries, err := loadHistory()
+				failIf(err)
+
+				printHistory(entries)
+			},
+		},
+		{
+			Name:  "rerun",
+			Usage: "run a command from the history again, the last one unless given its number",
+			Action: func(c *cli.Context) {
+				entries, err := loadHistory()
+				failIf(err)
+
+				n := len(entries)
+				if c.Args().Present() {
+					n, err = strconv.Atoi(c.Args().First())
+					if err != nil {
+						fail(usageError(fmt.Sprintf("invalid history number %q", c.Args().First())))
+					}
+				}
+
+				if n < 1 || n > len(entries) {
+					fail(fmt.Errorf("no command %d in the history", n))
+				}
+
+				rerun(c, entries[n-1])
+			},
+		},
+		{
+			Name:  "completion",
+			Usage: "print a completion script for bash, zsh or fish",
+			Action: func(c *cli.Context) {
+				if len(c.Args()) != 1 {
+					fail(usageError("must provide shell: bash, zsh or fish"))
+				}
+
+				script, err := completionScript(c.App, c.Args().First())
+				failIf(err)
+
+				fmt.Print(script)
+			},
+		},
+		{
+			Name:  "plugins",
+			Usage: "list the commands provided by gaol-<name> executables on PATH",
+			Action: func(c *cli.Context) {
+				names, paths := listPlugins()
+				for _, name := range names {
+					fmt.Printf("%s\t%s\n", name, paths[name])
+				}
+			},
+		},
+		{
+			Name:  "rootfs",
+			Usage: "list the rootfses containers can be created from",
+			Subcommands: []cli.Command{
+				{
+					Name:  "ls",
+					Usage: "list the rootfses in a directory on the target's host, by way of a privileged container",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "dir",
+							Usage: "directory on the host holding the rootfses, if not the target's --rootfs-dir",
+						},
+						cli.StringFlag{
+							Name:  "rootfs, r",
+							Usage: "rootfs of the container which lists them, if not the target's default",
+						},
+						cli.BoolFlag{
+							Name:  "cached",
+							Usage: "list the rootfses last listed, as completion does, without creating a container",
+						},
+					},
+					Action: func(c *cli.Context) {
+						target := currentTarget(c)
+
+						if c.Bool("cached") {
+							// completion must never wait for a container to be
+							// created, so nothing is listed if nothing is cached
+							rootfses, _, _ := readCache(rootfsCachePath(target.Address))
+							for _, rootfs := range rootfses {
+								fmt.Println(rootfs)
+							}
+							return
+						}
+
+						dir := c.String("dir")
+						if dir == "" {
+							dir = target.RootFSDir
+						}
+
+						if dir == "" {
+							fail(usageError("must provide --dir, or set the target's with target set --rootfs-dir"))
+						}
+
+						if !target.allowsPrivileged() {
+							fail(errors.New("listing rootfses takes a privileged container, which is not allowed on this target"))
+						}
+
+						utility := c.String("rootfs")
+						if utility == "" {
+							utility = target.RootFS
+						}
+
+						rootfses, err := commands.ListRootFSes(client(c), dir, utility)
+						failIf(err)
+
+						writeCache(rootfsCachePath(target.Address), rootfses)
+
+						for _, rootfs := range rootfses {
+							fmt.Println(rootfs)
+						}
+					},
+				},
+			},
+		},
+		{
+			Name:  "target",
+			Usage: "manage the named targets in ~/.gaol/config.yml",
+			Subcommands: []cli.Command{
+				{
+					Name:  "list",
+					Usage: "list the configured targets, marking the current one",
+					Action: func(c *cli.Context) {
+						lines, err := listTargets()
+						failIf(err)
+
+						for _, line := range lines {
+							fmt.Println(line)
+						}
+					},
+				},
+				{
+					Name:  "set",
+					Usage: "add or change a target: set <name> <address>",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "rootfs, r",
+							Usage: "default rootfs for containers created on the target",
+						},
+						cli.StringFlag{
+							Name:  "rootfs-dir",
+							Usage: "directory on the target's host holding its rootfses, listed by rootfs ls",
+						},
+						cli.StringFlag{
+							Name:  "ca-cert",
+							Usage: "CA certificate with which to verify the target, enabling TLS",
+						},
+						cli.StringFlag{
+							Name:  "client-cert",
+							Usage: "certificate with which to authenticate to the target, enabling TLS",
+						},
+						cli.StringFlag{
+							Name:  "client-key",
+							Usage: "private key of the client certificate",
+						},
+						cli.StringFlag{
+							Name:  "via",
+							Usage: "SSH jump host, as user@host[:port], through which to reach the target",
+						},
+						cli.StringSliceFlag{
+							Name:  "default, d",
+							Value: &cli.StringSlice{},
+							Usage: "default for a command flag on the target, as command.flag=value",
+						},
+						cli.StringFlag{
+							Name:  "privileged",
+							Usage: "whether --privileged may be given on the target: allow or deny",
+						},
+					},
+					Action: func(c *cli.Context) {
+						if len(c.Args()) != 2 {
+							fail(usageError("must provide target name and address"))
+						}
+
+						switch c.String("privileged") {
+						case "", "allow", "deny":
+						default:
+							fail(usageError("--privileged must be allow or deny"))
+						}
+
+						defaults, err := parseDefaults(c.StringSlice("default"))
+						failIf(err)
+
+						err = setTarget(c.Args()[0], targetConfig{
+							Address:    c.Args()[1],
+							RootFS:     c.String("rootfs"),
+							RootFSDir:  c.String("rootfs-dir"),
+							CACert:     c.String("ca-cert"),
+							ClientCert: c.String("client-cert"),
+							ClientKey:  c.String("client-key"),
+							Via:        c.String("via"),
+							Defaults:   defaults,
+							Privileged: c.String("privileged"),
+						})
+						failIf(err)
+					},
+				},
+				{
+					Name:  "protect",
+					Usage: "ask before destroying any container on a target",
+					Action: func(c *cli.Context) {
+						if len(c.Args()) == 0 {
+							fail(usageError("must provide target name"))
+						}
+
+						err := protectTarget(c.Args().First(), true)
+						failIf(err)
+					},
+				},
+				{
+					Name:  "unprotect",
+					Usage: "stop asking before destroying containers on a target",
+					Action: func(c *cli.Context) {
+						if len(c.Args()) == 0 {
+							fail(usageError("must provide target name"))
+						}
+
+						err := protectTarget(c.Args().First(), false)
+						failIf(err)
+					},
+				},
+				{
+					Name:  "use",
+					Usage: "make a target the one commands are sent to",
+					Action: func(c *cli.Context) {
+						if len(c.Args()) == 0 {
+							fail(usageError("must provide target name"))
+						}
+
+						err := useTarget(c.Args().First())
+						failIf(err)
+					},
+				},
+				{
+					Name:  "remove",
+					Usage: "forget a target",
+					Action: func(c *cli.Context) {
+						if len(c.Args()) == 0 {
+							fail(usageError("must provide target name"))
+						}
+
+						err := removeTarget(c.Args().First())
+						failIf(err)
+					},
+				},
+			},
+		},
+		{
+			Name:  "daemons",
+			Usage: "manage the gaols left running in the background, such as detached monitors and supervisors",
+			Subcommands: []cli.Command{
+				{
+					Name:  "list",
+					Usage: "list the daemons still running, oldest first",
+					Action: func(c *cli.Context) {
+						daemons, err := loadDaemons()
+						failIf(err)
+
+						for _, d := range daemons {
+							fmt.Println(d.line())
+						}
+					},
+				},
+				{
+					Name:  "stop",
+					Usage: "stop daemons by their pids: stop <pid>...",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "all",
+							Usage: "stop every daemon",
+						},
+					},
+					Action: func(c *cli.Context) {
+						if c.Bool("all") == c.Args().Present() {
+							fail(usageError("must provide either pids or --all"))
+						}
+
+						failIf(stopDaemons(c.Args(), c.Bool("all"), unlessQuiet(c, os.Stderr)))
+					},
+				},
+			},
+		},
+		{
+			Name:  "alias",
+			Usage: "give containers on the current target memorable names which commands accept in place of their handles",
+			Subcommands: []cli.Command{
+				{
+					Name:  "list",
+					Usage: "list the aliases on the current target and their handles",
+					Action: func(c *cli.Context) {
+						lines, err := listAliases(currentTarget(c).Address)
+						failIf(err)
+
+						for _, line := range lines {
+							fmt.Println(line)
+						}
+					},
+				},
+				{
+					Name:  "add",
+					Usage: "name a container: add <alias> <handle>",
+					Action: func(c *cli.Context) {
+						if len(c.Args()) != 2 {
+							fail(usageError("must provide alias and container handle"))
+						}
+
+						err := addAlias(currentTarget(c).Address, c.Args()[0], c.Args()[1])
+						failIf(err)
+					},
+				},
+				{
+					Name:  "remove",
+					Usage: "forget an alias, leaving its container be",
+					Action: func(c *cli.Context) {
+						if len(c.Args()) == 0 {
+							fail(usageError("must provide alias"))
+						}
+
+						err := removeAlias(currentTarget(c).Address, c.Args().First())
+						failIf(err)
+					},
+				},
+			},
+		},
+		{
+			Name:  "use",
+			Usage: "make a container the one commands given no handle act on, or print it with no arguments",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "clear",
+					Usage: "forget the current container",
+				},
+			},
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				address := currentTarget(c).Address
+
+				switch {
+				case c.Bool("clear"):
+					if c.Args().Present() {
+						fail(usageError("cannot give a container handle along with --clear"))
+					}
+
+					err := useHandle(address, "")
+					failIf(err)
+				case c.Args().Present():
+					_, err := client(c).Lookup(resolveHandles(c, c.Args().First())[0])
+					failIf(err)
+
+					err = useHandle(address, c.Args().First())
+					failIf(err)
+				default:
+					handle := currentHandle(c)
+					if handle == "" {
+						fail(errors.New("no current container"))
+					}
+
+					fmt.Println(handle)
+				}
+			},
+		},
+		{
+			Name:  "task",
+			Usage: "run one-off jobs in containers of their own",
+			Subcommands: []cli.Command{
+				{
+					Name:  "run",
+					Usage: "create a container, run the task in it, collect its artifacts and destroy it: task run <task.yml>",
+					Flags: append([]cli.Flag{
+						cli.StringFlag{
+							Name:  "artifacts-dir",
+							Value: "artifacts",
+							Usage: "directory to collect the task's artifacts into",
+						},
+						cli.BoolFlag{
+							Name:  "keep",
+							Usage: "leave the task's container behind rather than destroying it",
+						},
+						collectFlag(),
+						cli.DurationFlag{
+							Name:  "kill-after",
+							Value: 10 * time.Second,
+							Usage: "kill the task if it is still running this long after being terminated for its timeout",
+						},
+						registryUserFlag,
+						registryPasswordFlag,
+						insecureRegistryFlag,
+					}, templateFlags()...),
+					Action: func(c *cli.Context) {
+						if len(c.Args()) != 1 {
+							fail(usageError("must provide a task file"))
+						}
+
+						task, err := commands.LoadTask(c.Args()[0], templateData(c))
+						failIf(err)
+
+						if task.Privileged && !currentTarget(c).allowsPrivileged() {
+							fail(errors.New("privileged tasks are not allowed on this target"))
+						}
+
+						spec := stamp(task.Spec())
+						opts := commands.TaskOptions{
+							ArtifactsDir: c.String("artifacts-dir"),
+							Collect:      collections(c),
+							Keep:         c.Bool("keep"),
+							KillAfter:    c.Duration("kill-after"),
+						}
+
+						if task.Image != "" {
+							image := pullImage(c, task.Image)
+
+							spec.Env = append(image.Env, spec.Env...)
+							opts.Populate = func(container garden.Container) error {
+								return commands.StreamArchive(container, image.Path)
+							}
+						}
+
+						err = commands.RunTask(client(c), task, spec, opts, garden.ProcessIO{
+							Stdin:  os.Stdin,
+							Stdout: os.Stdout,
+							Stderr: os.Stderr,
+						}, unlessQuiet(c, os.Stderr))
+						failIf(err)
+					},
+				},
+			},
+		},
+		{
+			Name:  "up",
+			Usage: "create the containers described by a manifest",
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "file, f",
+					Value: "gaol.yml",
+					Usage: "manifest describing the containers",
+				},
+				junitFlag,
+			}, templateFlags()...),
+			Action: func(c *cli.Context) {
+				manifest := loadManifest(c)
+
+				report := junitReport(c)
+				err := commands.Up(client(c), manifest, os.Stdout, unlessQuiet(c, os.Stderr), report)
+				forgetHandles(c)
+				writeJUnit(c, report, "gaol up "+c.String("file"))
+				failIf(err)
+			},
+		},
+		{
+			Name:  "apply",
+			Usage: "reconcile the live containers with a manifest",
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "file, f",
+					Value: "gaol.yml",
+					Usage: "manifest describing the containers",
+				},
+				cli.BoolFlag{
+					Name:  "prune",
+					Usage: "destroy containers no longer described by the manifest",
+				},
+			}, templateFlags()...),
+			Action: func(c *cli.Context) {
+				manifest := loadManifest(c)
+
+				changes, err := commands.Apply(client(c), manifest, c.Bool("prune"), unlessQuiet(c, os.Stderr))
+				commands.PrintChanges(os.Stdout, changes)
+				forgetHandles(c)
+				failIf(err)
+			},
+		},
+		{
+			Name:  "ps",
+			Usage: "show the state of the processes described by a manifest, or the processes running in a container: ps [handle]",
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "file, f",
+					Value: "gaol.yml",
+					Usage: "manifest describing the containers",
+				},
+				cli.BoolFlag{
+					Name:  "named",
+					Usage: "list only the processes in the container which gaol started by name, and their children",
+				},
+			}, templateFlags()...),
+			BashComplete: handleComplete,
+			Action: func(c *cli.Context) {
+				if len(c.Args()) > 0 {
+					helper, err := loadHelper(c)
+					failIf(err)
+
+					processes, err := commands.ContainerProcesses(client(c), handle(c), helper, c.Bool("named"))
+					failIf(err)
+
+					commands.PrintContainerProcesses(os.Stdout, processes)
+					return
+				}
+
+				if c.Bool("named") {
+					fail(usageError("--named needs a container handle"))
+				}
+
+				manifest := loadManifest(c)
+
+				statuses, err := commands.Ps(client(c), manifest)
+				commands.PrintProcessStatuses(os.Stdout, statuses)
+				failIf(err)
+			},
+		},
+		{
+			Name:  "monitor",
+			Usage: "keep checking the containers and processes described by a manifest, restarting and recreating them as it says",
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "file, f",
+					Value: "gaol.yml",
+					Usage: "manifest describing the containers",
+				},
+				cli.BoolFlag{
+					Name:  "once",
+					Usage: "check once and exit, failing if anything was wrong",
+				},
+				cli.BoolFlag{
+					Name:  "detach",
+					Usage: "monitor from gaol in the background, logging to ~/.gaol/monitor",
+				},
+				outputFlag,
+			}, templateFlags()...),
+			Action: func(c *cli.Context) {
+				manifest := loadManifest(c)
+
+				if c.Bool("detach") {
+					if c.Bool("once") {
+						fail(usageError("cannot give --once along with --detach"))
+					}
+
+					// the monitor may not run from the same directory
+					path, err := filepath.Abs(c.String("file"))
+					failIf(err)
+
+					logPath := monitorLogPath(manifest.Name)
+					args := []string{"monitor", "--file", path, "--output", c.String("output"), "--index", strconv.Itoa(c.Int("index"))}
+					for _, variable := range c.StringSlice("var") {
+						args = append(args, "--var", variable)
+					}
+
+					handles := []string{}
+					for _, name := range manifest.Names() {
+						handles = append(handles, manifest.Containers[name].Handle)
+					}
+
+					detach(c, daemon{Kind: "monitor", Handles: handles, Manifest: manifest.Name, Log: logPath}, args)
+
+					fmt.Fprintf(unlessQuiet(c, os.Stderr), "monitoring %s, logging to %s\n", manifest.Name, logPath)
+					return
+				}
+
+				failIf(commands.Monitor(client(c), manifest, c.Bool("once"), os.Stdout, jsonLines(c)))
+			},
+		},
+		{
+			Name:  "down",
+			Usage: "destroy the containers described by a manifest",
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "file, f",
+					Value: "gaol.yml",
+					Usage: "manifest describing the containers",
+				},
+				cli.BoolFlag{
+					Name:  "force",
+					Usage: "do not ask before destroying many containers or any on a protected target",
+				},
+				cli.BoolFlag{
+					Name:  "clean",
+					Usage: "also stop the monitors and supervisors gaol left running for the containers, and forget aliases and the current container naming them",
+				},
+			}, templateFlags()...),
+			Action: func(c *cli.Context) {
+				manifest := loadManifest(c)
+
+				handles := []string{}
+				for _, name := range manifest.Names() {
+					handles = append(handles, manifest.Containers[name].Handle)
+				}
+
+				confirmDestroy(c, handles, false)
+
+				address := currentTarget(c).Address
+				progress := unlessQuiet(c, os.Stderr)
+
+				// a monitor left running would recreate the containers
+				if c.Bool("clean") {
+					failIf(stopDaemonsFor(address, manifest.Name, handles, progress))
+				}
+
+				err := commands.Down(client(c), manifest)
+				forgetHandles(c)
+				failIf(err)
+
+				if c.Bool("clean") {
+					forgetLocalState(address, handles, progress)
+				}
 			},
 		},
 	}
 
-	app.Run(os.Args)
+	return app
+}
+
+// templateFlags are the flags of the commands which read manifests and
+// tasks, giving what they are rendered with.
+func templateFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "var",
+			Value: &cli.StringSlice{},
+			Usage: "variable to render the file with, as key=value, which it refers to as {{ .Vars.key }}",
+		},
+		cli.IntFlag{
+			Name:  "index",
+			Usage: "number to render the file with, which it refers to as {{ .Index }}",
+		},
+	}
+}
+
+// templateData is what the file given to a command is rendered with.
+func templateData(c *cli.Context) commands.TemplateData {
+	data, err := commands.NewTemplateData(c.Int("index"), c.StringSlice("var"))
+	if err != nil {
+		fail(usageError(err.Error()))
+	}
+
+	return data
+}
+
+// loadManifest loads the manifest given to --file, failing if it asks for
+// anything privileged on a target which does not allow it.
+func loadManifest(c *cli.Context) *commands.Manifest {
+	manifest, err := commands.LoadManifest(c.String("file"), templateData(c))
+	failIf(err)
+
+	if manifest.Privileged() && !currentTarget(c).allowsPrivileged() {
+		fail(errors.New("the manifest is privileged, which is not allowed on this target"))
+	}
+
+	return manifest
+}
+
+// runCommandsFile runs each command in a file in the container given to run,
+// then summarizes how each exited.
+func runCommandsFile(c *cli.Context, path string, opts commands.RunOptions) {
+	handle := handle(c)
+	if len(c.Args()) > 1 {
+		fail(usageError("cannot give a command along with --commands-file"))
+	}
+
+	cmds, err := commands.LoadCommands(path)
+	failIf(err)
+
+	// stdin may be where the commands came from
+	results := commands.RunCommands(client(c), handle, cmds, opts, garden.ProcessIO{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}, c.Bool("keep-going"))
+
+	commands.PrintCommandStatuses(unlessQuiet(c, os.Stderr), results)
+	writeJUnit(c, opts.Report, "gaol run "+path)
+
+	if !c.Bool("keep-going") && len(results) > 0 {
+		// the commands stopped at the first failure, if any
+		failIf(results[len(results)-1].Err)
+		return
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		fail(fmt.Errorf("%d of %d commands failed", failed, len(cmds)))
+	}
+}
+
+// runSupervised runs a command which is restarted as --restart says, either
+// here or from a gaol started in the background with --detach.
+func runSupervised(c *cli.Context, opts commands.RunOptions) {
+	policy, err := commands.ParseRestart(c.String("restart"))
+	if err != nil {
+		fail(usageError(err.Error()))
+	}
+
+	if c.Int("max-restarts") < 0 {
+		fail(usageError("--max-restarts cannot be negative"))
+	}
+
+	handle, command := handleAndCommand(c)
+
+	supervision := commands.Supervision{
+		Name:        c.String("name"),
+		Restart:     policy,
+		MaxRestarts: c.Int("max-restarts"),
+	}
+
+	if c.Bool("detach") {
+		detachSupervisor(c, handle, command, opts, supervision)
+		return
+	}
+
+	err = commands.Supervise(client(c), handle, command, opts, supervision, garden.ProcessIO{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}, unlessQuiet(c, os.Stderr))
+	failIf(err)
+}
+
+// curlInside makes the request of gaol curl with the helper's curl, which
+// takes the same flags.
+func curlInside(c *cli.Context, handle string, data string) {
+	args := []string{"curl", "-m", c.Duration("max-time").String()}
+
+	if method := c.String("request"); method != "" {
+		args = append(args, "-X", method)
+	}
+
+	for _, header := range c.StringSlice("header") {
+		args = append(args, "-H", header)
+	}
+
+	if data != "" {
+		args = append(args, "-d", data)
+	}
+
+	if c.Bool("include") {
+		args = append(args, "-i")
+	}
+
+	if c.Bool("fail") {
+		args = append(args, "-f")
+	}
+
+	if c.Bool("insecure") {
+		args = append(args, "-k")
+	}
+
+	helper, err := loadHelper(c)
+	failIf(err)
+
+	err = commands.RunHelperTool(client(c), handle, helper, append(args, c.Args()[1]), garden.ProcessIO{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+	failIf(err)
+}
+
+// superviseLogPath is where a detached supervisor writes the output of the
+// process it supervises and its restarts.
+func superviseLogPath(handle string, name string) string {
+	return filepath.Join(os.Getenv("HOME"), ".gaol", "supervise", handle+"."+name+".log")
+}
+
+// detachSupervisor starts another gaol to supervise the process.
+func detachSupervisor(c *cli.Context, handle string, command string, opts commands.RunOptions, supervision commands.Supervision) {
+	name := supervision.ProcessName(command)
+	if name == "" {
+		fail(usageError("must provide command to run"))
+	}
+
+	args := []string{
+		"run", "--attach",
+		"--restart", supervision.Restart,
+		"--max-restarts", strconv.Itoa(supervision.MaxRestarts),
+		"--name", name,
+	}
+
+	if opts.Dir != "" {
+		args = append(args, "--dir", opts.Dir)
+	}
+
+	if opts.User != "" {
+		args = append(args, "--user", opts.User)
+	}
+
+	for _, env := range opts.Env {
+		args = append(args, "--env", env)
+	}
+
+	if opts.Privileged {
+		args = append(args, "--privileged")
+	}
+
+	logPath := superviseLogPath(handle, name)
+	detach(c, daemon{Kind: "supervisor", Handles: []string{handle}, Log: logPath}, append(args, handle, command))
+
+	fmt.Fprintf(unlessQuiet(c, os.Stderr), "supervising %s in %s, logging to %s\n", name, handle, logPath)
+}
+
+// detach runs gaol with args in the background, outliving this one, with its
+// output appended to the daemon's log, records it as a daemon and prints its
+// PID.
+func detach(c *cli.Context, d daemon, args []string) {
+	failIf(os.MkdirAll(filepath.Dir(d.Log), 0700))
+
+	log, err := os.OpenFile(d.Log, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	failIf(err)
+	defer log.Close()
+
+	lock, err := lockDaemon()
+	failIf(err)
+
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(gaolEnv(c), daemonEnv+"=1")
+	cmd.Stdout = log
+	cmd.Stderr = log
+	cmd.SysProcAttr = detachedAttr()
+	if lock != nil {
+		cmd.ExtraFiles = []*os.File{lock}
+		defer lock.Close()
+	}
+	failIf(cmd.Start())
+
+	d.PID = cmd.Process.Pid
+	d.Target = currentTarget(c).Address
+	d.Started = time.Now().UTC()
+
+	// the daemon is running whether or not it could be recorded
+	if err := recordStartedDaemon(d, lock); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to record daemon:", err)
+	}
+
+	fmt.Println(d.PID)
+}
+
+// forgetLocalState removes the aliases on the target naming the destroyed
+// containers and, if it is one of them, the current container.
+func forgetLocalState(address string, handles []string, w io.Writer) {
+	removed, err := removeAliasesFor(address, handles)
+	failIf(err)
+
+	for _, name := range removed {
+		fmt.Fprintf(w, "removed alias %s\n", name)
+	}
+
+	cfg, err := loadConfig()
+	failIf(err)
+
+	current := cfg.Handles[address]
+	for _, handle := range handles {
+		if current != "" && handle == current {
+			failIf(useHandle(address, ""))
+			fmt.Fprintf(w, "forgot current container %s\n", current)
+		}
+	}
+}
+
+// monitorLogPath is where a detached monitor writes the problems it finds
+// with the containers of a manifest.
+func monitorLogPath(manifest string) string {
+	return filepath.Join(os.Getenv("HOME"), ".gaol", "monitor", manifest+".log")
+}
+
+// runOne runs the command in the container. Once an attached process has
+// exited, successfully or not, it writes the --result-file and --junit
+// report and collects the --collect paths.
+func runOne(c *cli.Context, handle string, command string, opts commands.RunOptions) {
+	collections := collections(c)
+
+	stdout := new(commands.OutputTail)
+	stderr := new(commands.OutputTail)
+
+	processIO := garden.ProcessIO{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+
+	resultFile := c.String("result-file")
+	if resultFile != "" {
+		processIO.Stdout = io.MultiWriter(os.Stdout, stdout)
+		processIO.Stderr = io.MultiWriter(os.Stderr, stderr)
+	}
+
+	started := time.Now()
+	err := commands.Run(client(c), handle, command, opts, processIO, os.Stdout)
+	finished := time.Now()
+
+	writeJUnit(c, opts.Report, "gaol run")
+
+	if resultFile != "" {
+		failIf(commands.WriteRunResult(resultFile, commands.NewRunResult(handle, command, started, finished, err, stdout, stderr)))
+	}
+
+	var collectErr error
+	switch err.(type) {
+	case nil, commands.ProcessExitError, commands.ProcessTimeoutError:
+		collectErr = commands.Collect(client(c), handle, collections, unlessQuiet(c, os.Stderr))
+	}
+
+	if _, ok := err.(commands.MissingDirError); ok {
+		fail(fmt.Errorf("%s (--workdir-create makes it)", err))
+	}
+	failIf(err)
+	failIf(collectErr)
+}
+
+// runSelected runs the command given to run in every selected container,
+// then summarizes how it exited in each.
+func runSelected(c *cli.Context, selector commands.Selector, opts commands.RunOptions, lines *commands.JSONLines) {
+	switch {
+	case len(c.Args()) == 0:
+		fail(usageError("must provide command to run"))
+	case len(c.Args()) > 1:
+		fail(usageError("cannot give a container handle along with --all, --match or --filter"))
+	}
+
+	concurrency := c.Int("concurrency")
+	if concurrency < 1 {
+		fail(usageError("--concurrency must be at least 1"))
+	}
+
+	handles, err := commands.SelectHandles(client(c), selector)
+	failIf(err)
+
+	if len(handles) == 0 {
+		fmt.Fprintln(unlessQuiet(c, os.Stderr), "no containers selected")
+		return
+	}
+
+	var results []commands.Result
+
+	// the exit statuses are among the lines
+	if lines != nil {
+		results = commands.RunEachJSON(client(c), handles, c.Args()[0], opts, lines, concurrency)
+	} else {
+		results = commands.RunEach(client(c), handles, c.Args()[0], opts, os.Stdout, os.Stderr, concurrency)
+		commands.PrintExitStatuses(unlessQuiet(c, os.Stderr), results)
+	}
+
+	writeJUnit(c, opts.Report, "gaol run "+c.Args()[0])
+
+	if failures := commands.Failures(results); len(failures) > 0 {
+		fail(fmt.Errorf("command failed in %d of %d containers", len(failures), len(results)))
+	}
+}
+
+func main() {
+	handleInterrupts()
+	runAsDaemon()
+
+	app := newApp()
+
+	// the cli has already explained what was wrong with the arguments
+	if err := app.Run(os.Args); err != nil {
+		exit(exitUsage)
+	}
+
+	exit(0)
 }
@@ -0,0 +1,1480 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+
+	"github.com/xoebus/gaol/commands"
+)
+
+// exited is what exit panics with under test, so that the test can see the
+// code gaol would have exited with.
+type exited int
+
+// result is what running gaol under test produced.
+type result struct {
+	stdout string
+	stderr string
+	code   int
+}
+
+// runGaol runs gaol with args against the fake client, in a home directory
+// of its own, and returns what it printed and how it exited.
+func runGaol(t *testing.T, fakeClient garden.Client, args ...string) result {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	return runGaolIn(t, home, fakeClient, args...)
+}
+
+func runGaolIn(t *testing.T, home string, fakeClient garden.Client, args ...string) result {
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	defer os.Setenv("HOME", originalHome)
+
+	originalClient := client
+	client = func(*cli.Context) garden.Client { return fakeClient }
+	defer func() { client = originalClient }()
+
+	originalExit := osExit
+	osExit = func(code int) { panic(exited(code)) }
+	defer func() { osExit = originalExit }()
+
+	originalInteractive := interactive
+	interactive = func() bool { return false }
+	defer func() { interactive = originalInteractive }()
+
+	defer func() { jsonErrors = false }()
+
+	var res result
+	stdout := capture(&os.Stdout, &res.stdout)
+	stderr := capture(&os.Stderr, &res.stderr)
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				code, ok := r.(exited)
+				if !ok {
+					panic(r)
+				}
+
+				res.code = int(code)
+			}
+		}()
+
+		if err := newApp().Run(append([]string{"gaol"}, args...)); err != nil {
+			res.code = exitUsage
+		}
+	}()
+
+	stdout()
+	stderr()
+
+	return res
+}
+
+// capture replaces *file with a pipe until the returned function is called,
+// after which everything written to it is in *output.
+func capture(file **os.File, output *string) func() {
+	original := *file
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		panic(err)
+	}
+
+	*file = w
+
+	done := make(chan struct{})
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		*output = buf.String()
+		close(done)
+	}()
+
+	return func() {
+		*file = original
+		w.Close()
+		<-done
+		r.Close()
+	}
+}
+
+// withStdin replaces os.Stdin with a pipe from which input can be read
+// until the returned function is called.
+func withStdin(t *testing.T, input string) func() {
+	original := os.Stdin
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		io.WriteString(w, input)
+		w.Close()
+	}()
+
+	os.Stdin = r
+
+	return func() {
+		os.Stdin = original
+		r.Close()
+	}
+}
+
+// withAnswers has confirm read the input as if typed at the terminal until
+// the returned function is called.
+func withAnswers(input string) func() {
+	original := openAnswers
+	openAnswers = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(input)), nil
+	}
+
+	return func() { openAnswers = original }
+}
+
+func tempHome(t *testing.T) string {
+	home, err := ioutil.TempDir("", "gaol-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return home
+}
+
+func fakeContainer(handle string) *fakes.FakeContainer {
+	container := new(fakes.FakeContainer)
+	container.HandleReturns(handle)
+	return container
+}
+
+func TestCapacity(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.CapacityReturns(garden.Capacity{MemoryInBytes: 16 << 30, DiskInBytes: 100 << 30, MaxContainers: 256}, nil)
+
+	res := runGaol(t, fakeClient, "capacity")
+	if res.code != 0 {
+		t.Fatalf("exited %d: %s", res.code, res.stderr)
+	}
+
+	if res.stdout != "memory: 16.0 GiB\ndisk: 100.0 GiB\nmax containers: 256\n" {
+		t.Errorf("printed %q", res.stdout)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		args []string
+		spec garden.ContainerSpec
+	}{
+		{
+			args: []string{"create"},
+			spec: garden.ContainerSpec{},
+		},
+		{
+			args: []string{"create", "--handle", "web", "--rootfs", "docker:///busybox", "--grace", "1m", "--privileged"},
+			spec: garden.ContainerSpec{
+				Handle:     "web",
+				RootFSPath: "docker:///busybox",
+				GraceTime:  time.Minute,
+				Privileged: true,
+			},
+		},
+		{
+			args: []string{"create", "-n", "db", "-r", "/rootfs", "-g", "5s", "-p"},
+			spec: garden.ContainerSpec{
+				Handle:     "db",
+				RootFSPath: "/rootfs",
+				GraceTime:  5 * time.Second,
+				Privileged: true,
+			},
+		},
+		{
+			args: []string{"create", "--property", "team=web", "--property", "ci=true"},
+			spec: garden.ContainerSpec{
+				Properties: garden.Properties{"team": "web", "ci": "true"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		fakeClient := new(fakes.FakeClient)
+		fakeClient.CreateReturns(fakeContainer("created"), nil)
+
+		res := runGaol(t, fakeClient, test.args...)
+		if res.code != 0 {
+			t.Errorf("%v: exited %d: %s", test.args, res.code, res.stderr)
+			continue
+		}
+
+		if fakeClient.CreateCallCount() != 1 {
+			t.Errorf("%v: created %d containers", test.args, fakeClient.CreateCallCount())
+			continue
+		}
+
+		spec := fakeClient.CreateArgsForCall(0)
+
+		createdAt, err := time.Parse(time.RFC3339, spec.Properties[commands.CreatedAtProperty])
+		if err != nil || time.Since(createdAt) > time.Minute {
+			t.Errorf("%v: stamped the container as created at %q", test.args, spec.Properties[commands.CreatedAtProperty])
+		}
+
+		if spec.Properties[commands.CreatedByProperty] == "" {
+			t.Errorf("%v: did not stamp who created the container", test.args)
+		}
+
+		if spec.Properties[commands.RootFSProperty] != test.spec.RootFSPath {
+			t.Errorf("%v: stamped the rootfs as %q", test.args, spec.Properties[commands.RootFSProperty])
+		}
+
+		delete(spec.Properties, commands.CreatedAtProperty)
+		delete(spec.Properties, commands.CreatedByProperty)
+		delete(spec.Properties, commands.RootFSProperty)
+		if len(spec.Properties) == 0 {
+			spec.Properties = nil
+		}
+
+		if !reflect.DeepEqual(spec, test.spec) {
+			t.Errorf("%v: created %#v, want %#v", test.args, spec, test.spec)
+		}
+
+		if res.stdout != "created\n" {
+			t.Errorf("%v: printed %q", test.args, res.stdout)
+		}
+	}
+}
+
+func TestCreateWithLimits(t *testing.T) {
+	container := fakeContainer("web")
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.CreateReturns(container, nil)
+
+	res := runGaol(t, fakeClient, "create", "--memory-limit", "512M", "--disk-limit", "2G", "--cpu-shares", "100")
+	if res.code != 0 {
+		t.Fatalf("exited %d: %s", res.code, res.stderr)
+	}
+
+	if limits := container.LimitMemoryArgsForCall(0); limits.LimitInBytes != 512<<20 {
+		t.Errorf("limited memory to %d", limits.LimitInBytes)
+	}
+
+	if limits := container.LimitDiskArgsForCall(0); limits.ByteHard != 2<<30 {
+		t.Errorf("limited disk to %d", limits.ByteHard)
+	}
+
+	if limits := container.LimitCPUArgsForCall(0); limits.LimitInShares != 100 {
+		t.Errorf("limited cpu to %d shares", limits.LimitInShares)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	container := fakeContainer("web")
+	container.LimitDiskReturns(errors.New("quotas are disabled"))
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	res := runGaol(t, fakeClient, "update", "--memory-limit", "1G", "--disk-limit", "10G", "web")
+	if res.code != exitFailure {
+		t.Errorf("exited %d, want the rejected change to fail", res.code)
+	}
+
+	if res.stdout != "memory limit\trolled back\ndisk limit\trejected: quotas are disabled\n" {
+		t.Errorf("printed %q", res.stdout)
+	}
+
+	if !strings.Contains(res.stderr, "rejected 1 of 2 changes") {
+		t.Errorf("printed %q", res.stderr)
+	}
+}
+
+func TestCreateNoStamp(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.CreateReturns(fakeContainer("created"), nil)
+
+	res := runGaol(t, fakeClient, "create", "--no-stamp", "--rootfs", "/rootfs")
+	if res.code != 0 {
+		t.Fatalf("exited %d: %s", res.code, res.stderr)
+	}
+
+	if spec := fakeClient.CreateArgsForCall(0); spec.Properties != nil {
+		t.Errorf("stamped %v, want no properties", spec.Properties)
+	}
+}
+
+func TestCreateIfNotExists(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(fakeContainer("web"), nil)
+
+	res := runGaol(t, fakeClient, "create", "--if-not-exists", "--handle", "web")
+	if res.code != 0 || res.stdout != "web\n" {
+		t.Errorf("exited %d printing %q: %s", res.code, res.stdout, res.stderr)
+	}
+
+	if fakeClient.CreateCallCount() != 0 {
+		t.Error("expected the existing container to be reused")
+	}
+
+	for _, args := range [][]string{
+		{"create", "--if-not-exists"},
+		{"create", "--if-not-exists", "--replace", "--handle", "web"},
+	} {
+		if res := runGaol(t, fakeClient, args...); res.code != 2 {
+			t.Errorf("%v exited %d, want 2", args, res.code)
+		}
+	}
+}
+
+func TestCreateCount(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.CreateStub = func(spec garden.ContainerSpec) (garden.Container, error) {
+		if spec.Handle == "worker-2" {
+			return nil, errors.New("no space")
+		}
+
+		return fakeContainer(spec.Handle), nil
+	}
+
+	res := runGaol(t, fakeClient, "create", "--count", "3", "--handle", "worker-{{.Index}}", "--rootfs", "/rootfs")
+	if res.code != 1 {
+		t.Errorf("exited %d, want 1", res.code)
+	}
+
+	if res.stdout != "worker-1\nworker-3\n" {
+		t.Errorf("printed %q", res.stdout)
+	}
+
+	if !strings.Contains(res.stderr, "failed to create worker-2: no space") {
+		t.Errorf("reported %q", res.stderr)
+	}
+
+	for i := 0; i < fakeClient.CreateCallCount(); i++ {
+		if spec := fakeClient.CreateArgsForCall(i); spec.RootFSPath != "/rootfs" {
+			t.Errorf("created %#v, want every container to share the rootfs", spec)
+		}
+	}
+
+	res = runGaol(t, new(fakes.FakeClient), "create", "--count", "2", "--handle", "worker")
+	if res.code != 2 {
+		t.Errorf("creating two containers with one handle exited %d, want 2", res.code)
+	}
+}
+
+func TestCreateUsesTargetDefaults(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.CreateReturns(fakeContainer("created"), nil)
+
+	res := runGaolIn(t, home, fakeClient, "target", "set", "--rootfs", "/default", "-d", "create.privileged=true", "local", "localhost:7777")
+	if res.code != 0 {
+		t.Fatalf("target set exited %d: %s", res.code, res.stderr)
+	}
+
+	res = runGaolIn(t, home, fakeClient, "target", "use", "local")
+	if res.code != 0 {
+		t.Fatalf("target use exited %d: %s", res.code, res.stderr)
+	}
+
+	res = runGaolIn(t, home, fakeClient, "create")
+	if res.code != 0 {
+		t.Fatalf("create exited %d: %s", res.code, res.stderr)
+	}
+
+	spec := fakeClient.CreateArgsForCall(0)
+	if spec.RootFSPath != "/default" || !spec.Privileged {
+		t.Errorf("created %#v, want the target's defaults", spec)
+	}
+
+	res = runGaolIn(t, home, fakeClient, "create", "--rootfs", "/other")
+	if res.code != 0 {
+		t.Fatalf("create exited %d: %s", res.code, res.stderr)
+	}
+
+	if spec := fakeClient.CreateArgsForCall(1); spec.RootFSPath != "/other" {
+		t.Errorf("created with rootfs %q, want the flag to win", spec.RootFSPath)
+	}
+}
+
+func TestRunUsesTargetEnv(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	container := fakeContainer("a")
+	container.RunReturns(new(fakes.FakeProcess), nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	res := runGaolIn(t, home, fakeClient, "target", "set", "-d", "run.env=LANG=C,DEBUG=0", "-d", "run.user=vcap", "local", "localhost:7777")
+	if res.code != 0 {
+		t.Fatalf("target set exited %d: %s", res.code, res.stderr)
+	}
+
+	res = runGaolIn(t, home, fakeClient, "--target", "local", "run", "-e", "DEBUG=1", "a", "server")
+	if res.code != 0 {
+		t.Fatalf("run exited %d: %s", res.code, res.stderr)
+	}
+
+	spec, _ := container.RunArgsForCall(0)
+	if !reflect.DeepEqual(spec.Env, []string{"LANG=C", "DEBUG=1"}) || spec.User != "vcap" {
+		t.Errorf("ran %#v, want the target's env and user with DEBUG overridden", spec)
+	}
+}
+
+func TestPrivilegedDeniedByTarget(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.CreateReturns(fakeContainer("created"), nil)
+	fakeClient.LookupReturns(fakeContainer("a"), nil)
+
+	res := runGaolIn(t, home, fakeClient, "target", "set", "--privileged", "deny", "prod", "localhost:7777")
+	if res.code != 0 {
+		t.Fatalf("target set exited %d: %s", res.code, res.stderr)
+	}
+
+	for _, args := range [][]string{
+		{"--target", "prod", "create", "--privileged"},
+		{"--target", "prod", "run", "--privileged", "a", "true"},
+	} {
+		res = runGaolIn(t, home, fakeClient, args...)
+		if res.code != 1 || !strings.Contains(res.stderr, "not allowed") {
+			t.Errorf("%v exited %d: %s", args, res.code, res.stderr)
+		}
+	}
+
+	manifest := filepath.Join(home, "gaol.yml")
+	for _, container := range []string{
+		"{privileged: true}",
+		"{run: [{command: make, privileged: true}]}",
+		"{processes: [{name: server, command: /bin/server, privileged: true}]}",
+	} {
+		ioutil.WriteFile(manifest, []byte("name: app\ncontainers:\n  web: "+container+"\n"), 0644)
+
+		res = runGaolIn(t, home, fakeClient, "--target", "prod", "up", "--file", manifest)
+		if res.code != 1 || !strings.Contains(res.stderr, "not allowed") {
+			t.Errorf("up with %s exited %d: %s", container, res.code, res.stderr)
+		}
+	}
+
+	if fakeClient.CreateCallCount() != 0 {
+		t.Error("created a privileged container on a target which denies it")
+	}
+
+	res = runGaolIn(t, home, fakeClient, "--target", "prod", "create")
+	if res.code != 0 {
+		t.Errorf("creating an unprivileged container exited %d: %s", res.code, res.stderr)
+	}
+}
+
+func TestUse(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	container := fakeContainer("web")
+	container.RunReturns(new(fakes.FakeProcess), nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	res := runGaolIn(t, home, fakeClient, "use")
+	if res.code != 1 {
+		t.Errorf("printing the current container without one exited %d", res.code)
+	}
+
+	res = runGaolIn(t, home, fakeClient, "use", "web")
+	if res.code != 0 {
+		t.Fatalf("use exited %d: %s", res.code, res.stderr)
+	}
+
+	res = runGaolIn(t, home, fakeClient, "use")
+	if res.code != 0 || res.stdout != "web\n" {
+		t.Errorf("use exited %d printing %q, want the current container", res.code, res.stdout)
+	}
+
+	res = runGaolIn(t, home, fakeClient, "run", "ls -la")
+	if res.code != 0 {
+		t.Fatalf("run exited %d: %s", res.code, res.stderr)
+	}
+
+	if handle := fakeClient.LookupArgsForCall(fakeClient.LookupCallCount() - 1); handle != "web" {
+		t.Errorf("ran the command in %q, want the current container", handle)
+	}
+
+	if spec, _ := container.RunArgsForCall(0); spec.Path != "ls" {
+		t.Errorf("ran %#v, want the single argument to be the command", spec)
+	}
+
+	os.Setenv("GAOL_HANDLE", "db")
+	res = runGaolIn(t, home, fakeClient, "use")
+	os.Unsetenv("GAOL_HANDLE")
+	if res.stdout != "db\n" {
+		t.Errorf("printed %q, want GAOL_HANDLE to override the config", res.stdout)
+	}
+
+	res = runGaolIn(t, home, fakeClient, "--target", "other:7777", "use")
+	if res.code != 1 {
+		t.Errorf("another target had current container %q", res.stdout)
+	}
+
+	res = runGaolIn(t, home, fakeClient, "use", "--clear")
+	if res.code != 0 {
+		t.Fatalf("use --clear exited %d: %s", res.code, res.stderr)
+	}
+
+	res = runGaolIn(t, home, fakeClient, "run", "ls -la")
+	if res.code != 2 {
+		t.Errorf("running a lone command without a current container exited %d, want 2", res.code)
+	}
+
+	fakeClient.LookupReturns(nil, errors.New("container not found"))
+	res = runGaolIn(t, home, fakeClient, "use", "missing")
+	if res.code != 1 {
+		t.Errorf("using a missing container exited %d", res.code)
+	}
+}
+
+// destroyedHandles returns the handles of the containers destroyed through
+// the client, sorted, since several are destroyed at once.
+func destroyedHandles(fakeClient *fakes.FakeClient) []string {
+	destroyed := []string{}
+	for i := 0; i < fakeClient.DestroyCallCount(); i++ {
+		destroyed = append(destroyed, fakeClient.DestroyArgsForCall(i))
+	}
+	sort.Strings(destroyed)
+
+	return destroyed
+}
+
+func TestDestroy(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+
+	res := runGaol(t, fakeClient, "destroy", "a", "b")
+	if res.code != 0 {
+		t.Fatalf("exited %d: %s", res.code, res.stderr)
+	}
+
+	destroyed := destroyedHandles(fakeClient)
+
+	if !reflect.DeepEqual(destroyed, []string{"a", "b"}) {
+		t.Errorf("destroyed %v", destroyed)
+	}
+}
+
+func TestDestroyReportsFailures(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.DestroyStub = func(handle string) error {
+		if handle == "b" {
+			return errors.New("busy")
+		}
+
+		return nil
+	}
+
+	res := runGaol(t, fakeClient, "destroy", "a", "b", "c")
+	if res.code != 1 {
+		t.Errorf("exited %d, want 1", res.code)
+	}
+
+	if res.stdout != "destroyed\ta\nfailed\tb\tbusy\ndestroyed\tc\n" {
+		t.Errorf("printed %q", res.stdout)
+	}
+
+	if !strings.Contains(res.stderr, "failed to destroy 1 of 3 containers") {
+		t.Errorf("reported %q", res.stderr)
+	}
+
+	res = runGaol(t, fakeClient, "--quiet", "destroy", "a", "b", "c")
+	if res.stdout != "" || !strings.HasPrefix(res.stderr, "failed\tb\tbusy\n") {
+		t.Errorf("quietly printed %q and reported %q, want only the failure", res.stdout, res.stderr)
+	}
+}
+
+func TestDestroySelected(t *testing.T) {
+	tests := []struct {
+		args      []string
+		input     string
+		destroyed []string
+	}{
+		{[]string{"destroy", "--match", "ci-*", "--force"}, "", []string{"ci-1", "ci-2"}},
+		{[]string{"destroy", "--all"}, "y\n", []string{"ci-1", "ci-2", "web"}},
+		{[]string{"destroy", "--all"}, "n\n", []string{}},
+		{[]string{"destroy", "--all"}, "", []string{}},
+	}
+
+	for _, test := range tests {
+		fakeClient := new(fakes.FakeClient)
+		fakeClient.ContainersReturns([]garden.Container{fakeContainer("ci-1"), fakeContainer("ci-2"), fakeContainer("web")}, nil)
+
+		restore := withAnswers(test.input)
+		runGaol(t, fakeClient, test.args...)
+		restore()
+
+		destroyed := destroyedHandles(fakeClient)
+
+		if !reflect.DeepEqual(destroyed, test.destroyed) {
+			t.Errorf("%v with %q destroyed %v, want %v", test.args, test.input, destroyed, test.destroyed)
+		}
+	}
+}
+
+func TestDestroyConfirms(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	many := []string{"destroy"}
+	for i := 0; i < 11; i++ {
+		many = append(many, fmt.Sprintf("c-%d", i))
+	}
+
+	tests := []struct {
+		args      []string
+		input     string
+		destroyed int
+	}{
+		{many, "n\n", 0},
+		{many, "y\n", 11},
+		{append([]string{"--confirm-above", "20"}, many...), "", 11},
+		{append(many, "--force"), "", 11},
+		{[]string{"--target", "prod", "destroy", "a"}, "n\n", 0},
+		{[]string{"--target", "prod", "destroy", "a"}, "y\n", 1},
+		{[]string{"--target", "prod", "destroy", "--force", "a"}, "", 1},
+	}
+
+	res := runGaolIn(t, home, nil, "target", "set", "prod", "prod:7777")
+	if res.code != 0 {
+		t.Fatalf("target set exited %d: %s", res.code, res.stderr)
+	}
+
+	res = runGaolIn(t, home, nil, "target", "protect", "prod")
+	if res.code != 0 {
+		t.Fatalf("target protect exited %d: %s", res.code, res.stderr)
+	}
+
+	for _, test := range tests {
+		fakeClient := new(fakes.FakeClient)
+
+		restore := withAnswers(test.input)
+		runGaolIn(t, home, fakeClient, test.args...)
+		restore()
+
+		if fakeClient.DestroyCallCount() != test.destroyed {
+			t.Errorf("%v with %q destroyed %d containers, want %d", test.args, test.input, fakeClient.DestroyCallCount(), test.destroyed)
+		}
+	}
+}
+
+func TestDestroyConfirmsHandlesFromStdin(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	handles := ""
+	for i := 0; i < 11; i++ {
+		handles += fmt.Sprintf("c-%d\n", i)
+	}
+
+	fakeClient := new(fakes.FakeClient)
+
+	restoreStdin := withStdin(t, handles)
+	restoreAnswers := withAnswers("y\n")
+	res := runGaolIn(t, home, fakeClient, "destroy", "--stdin")
+	restoreAnswers()
+	restoreStdin()
+
+	if res.code != 0 || fakeClient.DestroyCallCount() != 11 {
+		t.Errorf("destroyed %d containers, exiting %d: %s", fakeClient.DestroyCallCount(), res.code, res.stderr)
+	}
+
+	// without a terminal to ask on, it is up to --force
+	original := openAnswers
+	openAnswers = func() (io.ReadCloser, error) { return nil, errors.New("no such device") }
+	defer func() { openAnswers = original }()
+
+	restoreStdin = withStdin(t, handles)
+	res = runGaolIn(t, home, fakeClient, "destroy", "--stdin")
+	restoreStdin()
+
+	if res.code == 0 || !strings.Contains(res.stderr, "pass --force") {
+		t.Errorf("exited %d: %s", res.code, res.stderr)
+	}
+}
+
+func TestHandlesFromStdin(t *testing.T) {
+	for _, args := range [][]string{{"destroy", "--stdin"}, {"destroy", "-"}} {
+		fakeClient := new(fakes.FakeClient)
+
+		restore := withStdin(t, "a\n\n  b \n")
+		res := runGaol(t, fakeClient, args...)
+		restore()
+
+		if res.code != 0 {
+			t.Errorf("%v: exited %d: %s", args, res.code, res.stderr)
+			continue
+		}
+
+		destroyed := destroyedHandles(fakeClient)
+
+		if !reflect.DeepEqual(destroyed, []string{"a", "b"}) {
+			t.Errorf("%v: destroyed %v", args, destroyed)
+		}
+	}
+}
+
+func TestStop(t *testing.T) {
+	container := fakeContainer("a")
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	restore := withStdin(t, "a\n")
+	res := runGaol(t, fakeClient, "stop", "--kill", "--stdin")
+	restore()
+
+	if res.code != 0 {
+		t.Fatalf("exited %d: %s", res.code, res.stderr)
+	}
+
+	if fakeClient.LookupArgsForCall(0) != "a" || container.StopCallCount() != 1 || !container.StopArgsForCall(0) {
+		t.Error("did not kill the processes in the container")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	old := fakeContainer("old")
+	old.InfoReturns(garden.ContainerInfo{Properties: garden.Properties{
+		commands.CreatedAtProperty: time.Now().Add(-2 * time.Hour).Format(time.RFC3339),
+	}}, nil)
+
+	fresh := fakeContainer("fresh")
+	fresh.InfoReturns(garden.ContainerInfo{Properties: garden.Properties{
+		commands.CreatedAtProperty: time.Now().Format(time.RFC3339),
+	}}, nil)
+
+	unstamped := fakeContainer("unstamped")
+	unstamped.InfoReturns(garden.ContainerInfo{}, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersReturns([]garden.Container{old, fresh, unstamped}, nil)
+
+	res := runGaol(t, fakeClient, "prune", "--older-than", "1h", "--dry-run")
+	if res.code != 0 || res.stdout != "old\n" {
+		t.Errorf("dry run exited %d printing %q: %s", res.code, res.stdout, res.stderr)
+	}
+
+	if fakeClient.DestroyCallCount() != 0 {
+		t.Error("expected a dry run not to destroy anything")
+	}
+
+	res = runGaol(t, fakeClient, "prune", "--older-than", "1h")
+	if res.code != 0 {
+		t.Fatalf("exited %d: %s", res.code, res.stderr)
+	}
+
+	if !reflect.DeepEqual(destroyedHandles(fakeClient), []string{"old"}) {
+		t.Errorf("destroyed %v, want only the old container", destroyedHandles(fakeClient))
+	}
+
+	res = runGaol(t, fakeClient, "prune")
+	if res.code != 2 {
+		t.Errorf("pruning without criteria exited %d, want 2", res.code)
+	}
+
+	for _, args := range [][]string{
+		{"prune", "--force", "--older-than", "-1h"},
+		{"prune", "--force", "--idle-cpu", "-5"},
+		{"prune", "--force", "--idle-cpu", "5", "--sample", "-1s"},
+	} {
+		destroyed := fakeClient.DestroyCallCount()
+		if res := runGaol(t, fakeClient, args...); res.code != 2 || fakeClient.DestroyCallCount() != destroyed {
+			t.Errorf("%v exited %d, want a usage error destroying nothing", args, res.code)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersReturns([]garden.Container{fakeContainer("a"), fakeContainer("b")}, nil)
+
+	res := runGaol(t, fakeClient, "list")
+	if res.code != 0 {
+		t.Fatalf("exited %d: %s", res.code, res.stderr)
+	}
+
+	if res.stdout != "a\nb\n" {
+		t.Errorf("printed %q", res.stdout)
+	}
+}
+
+func TestListFilter(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersReturns([]garden.Container{fakeContainer("ci-1"), fakeContainer("web")}, nil)
+
+	res := runGaol(t, fakeClient, "list", "--filter", "team=ci", "--match", "ci-*")
+	if res.code != 0 {
+		t.Fatalf("exited %d: %s", res.code, res.stderr)
+	}
+
+	if res.stdout != "ci-1\n" {
+		t.Errorf("printed %q", res.stdout)
+	}
+
+	if properties := fakeClient.ContainersArgsForCall(0); !reflect.DeepEqual(properties, garden.Properties{"team": "ci"}) {
+		t.Errorf("listed containers with %v", properties)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	container := fakeContainer("a")
+	container.InfoReturns(garden.ContainerInfo{
+		State:      "active",
+		ProcessIDs: []uint32{1, 2},
+		Properties: garden.Properties{"owner": "me"},
+	}, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	res := runGaol(t, fakeClient, "info", "a")
+	if res.code != 0 {
+		t.Fatalf("exited %d: %s", res.code, res.stderr)
+	}
+
+	if fakeClient.LookupArgsForCall(0) != "a" {
+		t.Errorf("looked up %q", fakeClient.LookupArgsForCall(0))
+	}
+
+	for _, line := range []string{"state: active\n", "processes: 1, 2\n", "property: owner=me\n"} {
+		if !strings.Contains(res.stdout, line) {
+			t.Errorf("printed %q, want it to contain %q", res.stdout, line)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	process := new(fakes.FakeProcess)
+	process.IDReturns(42)
+
+	container := fakeContainer("a")
+	container.RunReturns(process, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	res := runGaol(t, fakeClient, "run", "--dir", "/tmp", "--user", "vcap", "a", "echo 'hello world'")
+	if res.code != 0 {
+		t.Fatalf("exited %d: %s", res.code, res.stderr)
+	}
+
+	spec, _ := container.RunArgsForCall(0)
+	want := garden.ProcessSpec{
+		Path: "echo",
+		Args: []string{"hello world"},
+		Dir:  "/tmp",
+		User: "vcap",
+	}
+
+	if !reflect.DeepEqual(spec, want) {
+		t.Errorf("ran %#v, want %#v", spec, want)
+	}
+
+	if res.stdout != "42\n" {
+		t.Errorf("printed %q", res.stdout)
+	}
+}
+
+func TestRunSelected(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersReturns([]garden.Container{fakeContainer("ci-1"), fakeContainer("ci-2"), fakeContainer("web")}, nil)
+	fakeClient.LookupStub = func(handle string) (garden.Container, error) {
+		process := new(fakes.FakeProcess)
+		if handle == "ci-2" {
+			process.WaitReturns(1, nil)
+		}
+
+		container := fakeContainer(handle)
+		container.RunReturns(process, nil)
+		return container, nil
+	}
+
+	res := runGaol(t, fakeClient, "run", "--match", "ci-*", "true")
+	if res.code != 1 {
+		t.Errorf("exited %d, want 1", res.code)
+	}
+
+	if fakeClient.LookupCallCount() != 2 {
+		t.Errorf("ran in %d containers, want 2", fakeClient.LookupCallCount())
+	}
+
+	if !strings.Contains(res.stderr, "ci-1\t0\nci-2\t1\n") || !strings.Contains(res.stderr, "command failed in 1 of 2 containers") {
+		t.Errorf("reported %q", res.stderr)
+	}
+
+	res = runGaol(t, fakeClient, "run", "--all", "web", "true")
+	if res.code != 2 {
+		t.Errorf("giving a handle with --all exited %d, want 2", res.code)
+	}
+}
+
+func TestRunSelectedJUnit(t *testing.T) {
+	dir := tempHome(t)
+	defer os.RemoveAll(dir)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersReturns([]garden.Container{fakeContainer("ci-1"), fakeContainer("ci-2")}, nil)
+	fakeClient.LookupStub = func(handle string) (garden.Container, error) {
+		process := new(fakes.FakeProcess)
+		if handle == "ci-2" {
+			process.WaitReturns(1, nil)
+		}
+
+		container := fakeContainer(handle)
+		container.RunReturns(process, nil)
+		return container, nil
+	}
+
+	path := filepath.Join(dir, "report.xml")
+
+	if res := runGaol(t, fakeClient, "run", "--all", "--junit", path, "make test"); res.code != 1 {
+		t.Errorf("exited %d, want 1", res.code)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(contents), `<testsuite name="gaol run make test" tests="2" failures="1" errors="0"`) ||
+		!strings.Contains(string(contents), `<testcase classname="ci-2" name="make test"`) {
+		t.Errorf("wrote %s", contents)
+	}
+}
+
+func TestRunAttachedExitStatus(t *testing.T) {
+	process := new(fakes.FakeProcess)
+	process.WaitReturns(3, nil)
+
+	container := fakeContainer("a")
+	container.RunReturns(process, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	res := runGaol(t, fakeClient, "run", "--attach", "a", "false")
+	if res.code != exitProcess {
+		t.Errorf("exited %d, want %d", res.code, exitProcess)
+	}
+
+	if res.stderr != "failed: false exited with status 3\n" {
+		t.Errorf("printed %q", res.stderr)
+	}
+}
+
+func TestRunResultFile(t *testing.T) {
+	dir := tempHome(t)
+	defer os.RemoveAll(dir)
+
+	process := new(fakes.FakeProcess)
+	process.WaitReturns(3, nil)
+
+	container := fakeContainer("a")
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		fmt.Fprintln(processIO.Stdout, "3 tests failed")
+		return process, nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	path := filepath.Join(dir, "result.json")
+
+	res := runGaol(t, fakeClient, "run", "--attach", "--result-file", path, "a", "make test")
+	if res.code != exitProcess {
+		t.Errorf("exited %d, want %d", res.code, exitProcess)
+	}
+
+	if res.stdout != "3 tests failed\n" {
+		t.Errorf("printed %q", res.stdout)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result commands.RunResult
+	if err := json.Unmarshal(contents, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Handle != "a" || result.Command != "make test" || result.ExitStatus == nil || *result.ExitStatus != 3 || result.Stdout != "3 tests failed\n" || result.Started.IsZero() {
+		t.Errorf("wrote %s", contents)
+	}
+}
+
+func TestRunCollect(t *testing.T) {
+	dir := tempHome(t)
+	defer os.RemoveAll(dir)
+
+	process := new(fakes.FakeProcess)
+	process.WaitReturns(1, nil)
+
+	container := fakeContainer("a")
+	container.RunReturns(process, nil)
+	container.StreamOutStub = func(src string) (io.ReadCloser, error) {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		tw.WriteHeader(&tar.Header{Name: "test.log", Mode: 0644, Size: 4, Typeflag: tar.TypeReg})
+		tw.Write([]byte("FAIL"))
+		tw.Close()
+		return ioutil.NopCloser(&buf), nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	out := filepath.Join(dir, "out")
+
+	res := runGaol(t, fakeClient, "run", "--attach", "--collect", "/tmp/test.log="+out, "a", "make test")
+	if res.code != exitProcess {
+		t.Errorf("exited %d, want %d", res.code, exitProcess)
+	}
+
+	if src := container.StreamOutArgsForCall(0); src != "/tmp/test.log" {
+		t.Errorf("streamed out %s", src)
+	}
+
+	if contents, _ := ioutil.ReadFile(filepath.Join(out, "test.log")); string(contents) != "FAIL" {
+		t.Errorf("collected %q from a failed run", contents)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	exited := make(chan struct{})
+
+	process := new(fakes.FakeProcess)
+	process.WaitStub = func() (int, error) {
+		<-exited
+		return 137, nil
+	}
+	process.SignalStub = func(signal garden.Signal) error {
+		if signal == garden.SignalKill {
+			close(exited)
+		}
+		return nil
+	}
+
+	container := fakeContainer("a")
+	container.RunReturns(process, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	res := runGaol(t, fakeClient, "run", "--attach", "--timeout", "1ms", "--kill-after", "1ms", "a", "sleep 60")
+	if res.code != exitTimeout {
+		t.Errorf("exited %d, want %d", res.code, exitTimeout)
+	}
+
+	if res.stderr != "failed: sleep 60 did not exit within 1ms, so was killed\n" {
+		t.Errorf("printed %q", res.stderr)
+	}
+}
+
+func TestAttach(t *testing.T) {
+	process := new(fakes.FakeProcess)
+	process.WaitReturns(0, nil)
+
+	container := fakeContainer("a")
+	container.AttachReturns(process, nil)
+	container.InfoReturns(garden.ContainerInfo{Properties: garden.Properties{
+		"gaol:processes":   `[{"pid":4,"command":"make"},{"pid":9,"command":"server"}]`,
+		"gaol:process:web": "12",
+	}}, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	for _, test := range []struct {
+		args []string
+		pid  uint32
+	}{
+		{[]string{"attach", "--pid", "4", "a"}, 4},
+		{[]string{"attach", "a", "12"}, 12},
+		{[]string{"attach", "--last", "a"}, 9},
+	} {
+		res := runGaol(t, fakeClient, test.args...)
+		if res.code != 0 {
+			t.Errorf("%v exited %d: %s", test.args, res.code, res.stderr)
+			continue
+		}
+
+		if pid, _ := container.AttachArgsForCall(container.AttachCallCount() - 1); pid != test.pid {
+			t.Errorf("%v attached to %d, want %d", test.args, pid, test.pid)
+		}
+	}
+
+	res := runGaol(t, fakeClient, "attach", "a", "--generate-bash-completion")
+	if res.stdout != "9\n4\n12\n" {
+		t.Errorf("completed %q, want the known pids", res.stdout)
+	}
+}
+
+func TestProcsAndSignal(t *testing.T) {
+	process := new(fakes.FakeProcess)
+
+	container := fakeContainer("a")
+	container.AttachReturns(process, nil)
+	container.InfoReturns(garden.ContainerInfo{Properties: garden.Properties{
+		"gaol:processes": `[{"pid":4,"command":"server --port 80","started":"2026-10-16T09:00:00Z"},` +
+			`{"pid":9,"command":"worker","user":"vcap","started":"2026-10-16T09:05:00Z","attached":true}]`,
+	}}, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	res := runGaol(t, fakeClient, "procs", "a")
+	want := "4\t2026-10-16T09:00:00Z\tdetached\t-\tserver --port 80\n9\t2026-10-16T09:05:00Z\tattached\tvcap\tworker\n"
+	if res.code != 0 || res.stdout != want {
+		t.Errorf("procs exited %d printing %q, want %q", res.code, res.stdout, want)
+	}
+
+	res = runGaol(t, fakeClient, "signal", "--command", "server", "a")
+	if res.code != 0 {
+		t.Fatalf("signal exited %d: %s", res.code, res.stderr)
+	}
+
+	if pid, _ := container.AttachArgsForCall(0); pid != 4 {
+		t.Errorf("signalled %d, want the server", pid)
+	}
+
+	if process.SignalArgsForCall(0) != garden.SignalTerminate {
+		t.Errorf("sent %v, want terminate", process.SignalArgsForCall(0))
+	}
+
+	res = runGaol(t, fakeClient, "signal", "--kill", "a", "9")
+	if res.code != 0 || process.SignalArgsForCall(1) != garden.SignalKill {
+		t.Errorf("signal --kill exited %d sending %v", res.code, process.SignalArgsForCall(1))
+	}
+
+	res = runGaol(t, fakeClient, "signal", "--command", "deploy", "a")
+	if res.code != 1 {
+		t.Errorf("signalling an unknown process exited %d", res.code)
+	}
+}
+
+func TestRunSupervised(t *testing.T) {
+	process := new(fakes.FakeProcess)
+	process.IDReturns(42)
+
+	container := fakeContainer("a")
+	container.RunReturns(process, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	res := runGaol(t, fakeClient, "run", "--restart", "on-failure", "--name", "web", "a", "server --port 8080")
+	if res.code != 0 {
+		t.Fatalf("exited %d: %s", res.code, res.stderr)
+	}
+
+	if container.RunCallCount() != 1 {
+		t.Errorf("ran the process %d times, want once as it succeeded", container.RunCallCount())
+	}
+
+	if key, value := container.SetPropertyArgsForCall(1); key != "gaol:process:web" || value != "42" {
+		t.Errorf("recorded %s=%s", key, value)
+	}
+}
+
+func TestNetIn(t *testing.T) {
+	container := fakeContainer("a")
+	container.NetInReturns(61001, 8080, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	res := runGaol(t, fakeClient, "--target", "garden.example.com:7777", "net-in", "--port", "8080", "a")
+	if res.code != 0 {
+		t.Fatalf("exited %d: %s", res.code, res.stderr)
+	}
+
+	if hostPort, containerPort := container.NetInArgsForCall(0); hostPort != 0 || containerPort != 8080 {
+		t.Errorf("mapped %d -> %d", hostPort, containerPort)
+	}
+
+	if res.stdout != "garden.example.com:61001\n" {
+		t.Errorf("printed %q", res.stdout)
+	}
+
+	res = runGaol(t, fakeClient, "--target", "garden.example.com:7777", "--quiet", "net-in", "--port", "8080", "a")
+	if res.code != 0 || res.stdout != "61001\n" {
+		t.Errorf("quietly exited %d printing %q, want only the port", res.code, res.stdout)
+	}
+}
+
+func TestPing(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+
+	if res := runGaol(t, fakeClient, "ping"); res.code != 0 {
+		t.Errorf("exited %d: %s", res.code, res.stderr)
+	}
+
+	fakeClient.PingReturns(errors.New("connection refused"))
+
+	if res := runGaol(t, fakeClient, "ping"); res.code != exitFailure {
+		t.Errorf("exited %d, want %d", res.code, exitFailure)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	tests := [][]string{
+		{"info"},
+		{"destroy"},
+		{"destroy", "--all", "a"},
+		{"destroy", "--stdin", "a"},
+		{"info", "--watch", "a", "b"},
+		{"run", "a"},
+		{"run", "--restart", "sometimes", "a", "true"},
+		{"run", "--detach", "a", "true"},
+		{"run", "--timeout", "5m", "a", "true"},
+		{"run", "--timeout", "5m", "--restart", "always", "a", "true"},
+		{"run", "--attach", "--timeout", "-1s", "a", "true"},
+		{"run", "--result-file", "result.json", "a", "true"},
+		{"run", "--result-file", "result.json", "--all", "true"},
+		{"run", "--junit", "report.xml", "a", "true"},
+		{"task", "run"},
+		{"down", "--var", "branch"},
+		{"run", "--collect", "/tmp/log=out", "a", "true"},
+		{"run", "--attach", "--collect", "tmp/log=out", "a", "true"},
+		{"run", "--junit", "report.xml", "--restart", "always", "a", "true"},
+		{"daemons", "stop"},
+		{"daemons", "stop", "--all", "1"},
+		{"daemons", "stop", "one"},
+		{"metrics", "--sort", "network"},
+		{"metrics", "--where", "mem>lots"},
+		{"metrics", "--top", "-1"},
+		{"snapshot", "save"},
+		{"snapshot", "save", "../escape"},
+		{"snapshot", "diff"},
+		{"--porcelain", "v2", "list"},
+		{"--porcelain", "v1", "list", "--watch"},
+		{"list", "--watch", "--wide"},
+		{"list", "--watch", "--interval", "0"},
+		{"info", "--watch", "--interval", "-1s", "a"},
+		{"events", "--watch", "--interval", "0", "a"},
+		{"top", "--interval", "0"},
+		{"export-metrics", "--interval", "0"},
+		{"push-metrics", "--interval", "-10s"},
+		{"grep", "debug"},
+		{"grep", "debug", "etc", "a"},
+		{"grep", "--all", "debug", "/etc", "a"},
+		{"cat"},
+		{"cat", "web/etc/hosts"},
+		{"tail", "a:/x", "b:/y"},
+		{"tail", "--lines", "-1", "a:/x"},
+		{"edit"},
+		{"edit", "a:/x", "a:/y"},
+		{"--porcelain", "v1", "info", "--watch", "a"},
+		{"stream-in", "a"},
+		{"stream-in", "--to-file", "/srv", "--extract", "--resume", "a"},
+		{"stream-in", "--to-file", "/srv", "--chown", "app:staff:x", "a"},
+		{"stream-in", "--to-file", "/srv", "--chmod", "rwx", "a"},
+		{"stream-out", "--from-file", "/srv", "--preserve", "a"},
+		{"stream-in", "--to-file", "/srv", "--dereference", "a"},
+		{"stream-in", "--to-file", "/srv", "--exclude", ".git", "a"},
+		{"cp", "build.tgz"},
+		{"rootfs", "ls"},
+		{"cp", "build.tgz", "@team=web:/app/"},
+		{"cp", "build.tgz", "@filter:team:/app/"},
+		{"cp", "--all", "build.tgz", "@all:/app/"},
+		{"cp", "--all", "build.tgz", "app/"},
+		{"stream-in", "--to-file", "/srv", "--from-dir", ".", "--exclude", "[", "a"},
+		{"stream-in", "--to-file", "/srv", "--from-dir", ".", "--extract", "a"},
+		{"wait-for-port", "a"},
+		{"wait-for-port", "a", "http"},
+		{"wait-for-exit", "a"},
+		{"curl", "a"},
+		{"graph", "--metric", "network", "a"},
+		{"graph", "--interval", "0", "a"},
+		{"list", "--output", "jsonl"},
+		{"create", "--handle", "a", "--handle-prefix", "ci-"},
+		{"create", "--property", "novalue"},
+		{"update", "a"},
+		{"run", "--workdir-create", "a", "true"},
+		{"update", "--memory-limit", "lots", "a"},
+		{"list", "--watch", "--output", "xml"},
+		{"run", "--output", "jsonl", "a", "true"},
+		{"diff", "a", "/srv"},
+		{"diff", "--local", ".", "a", "b", "/srv"},
+		{"stream-out", "a"},
+		{"port-forward", "a"},
+		{"target", "set", "only-a-name"},
+		{"use", "--clear", "a"},
+		{"attach", "--last", "a", "4"},
+		{"attach", "--last", "--pid", "4", "a"},
+		{"attach", "a", "four"},
+		{"signal", "a"},
+		{"wait-for-exit", "a"},
+		{"wait-for-exit", "--pid", "4", "--command", "server", "a"},
+		{"target", "set", "--privileged", "sometimes", "prod", "prod:7777"},
+		{"target", "use"},
+		{"no-such-command"},
+		{"create", "--no-such-flag"},
+	}
+
+	for _, args := range tests {
+		res := runGaol(t, new(fakes.FakeClient), args...)
+		if res.code != exitUsage {
+			t.Errorf("%v: exited %d, want %d", args, res.code, exitUsage)
+		}
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(nil, garden.ContainerNotFoundError{Handle: "a"})
+
+	res := runGaol(t, fakeClient, "--json", "info", "a")
+	if res.code != exitNotFound {
+		t.Errorf("exited %d, want %d", res.code, exitNotFound)
+	}
+
+	if !strings.Contains(res.stderr, `"code":"not_found"`) {
+		t.Errorf("printed %q", res.stderr)
+	}
+}
+
+func TestCopyToFilter(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	src := filepath.Join(home, "build.tgz")
+	ioutil.WriteFile(src, []byte("artifact"), 0644)
+
+	web1, web2 := fakeContainer("web-1"), fakeContainer("web-2")
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersReturns([]garden.Container{web1, web2}, nil)
+	fakeClient.LookupStub = func(handle string) (garden.Container, error) {
+		if handle == "web-1" {
+			return web1, nil
+		}
+
+		return web2, nil
+	}
+
+	res := runGaolIn(t, home, fakeClient, "cp", src, "@filter:team=web:/app/")
+	if res.code != 0 || res.stdout != "copied\tweb-1\ncopied\tweb-2\n" {
+		t.Fatalf("cp exited %d: %q %s", res.code, res.stdout, res.stderr)
+	}
+
+	if filter := fakeClient.ContainersArgsForCall(0); !reflect.DeepEqual(filter, garden.Properties{"team": "web"}) {
+		t.Errorf("selected containers with %v", filter)
+	}
+
+	if dst, _ := web2.StreamInArgsForCall(0); dst != "/app/" {
+		t.Errorf("copied into %s", dst)
+	}
+}
+
+func TestRootFSLs(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	container := fakeContainer("lister")
+	container.RunStub = func(spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+		io.WriteString(processIO.Stdout, "cflinuxfs2\nbusybox\n")
+		return new(fakes.FakeProcess), nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.CreateReturns(container, nil)
+
+	if res := runGaolIn(t, home, fakeClient, "rootfs", "ls", "--cached"); res.code != 0 || res.stdout != "" {
+		t.Errorf("listed %q with nothing cached (exit %d)", res.stdout, res.code)
+	}
+
+	runGaolIn(t, home, fakeClient, "target", "set", "--rootfs-dir", "/var/lib/rootfs", "local", "127.0.0.1:7777")
+	runGaolIn(t, home, fakeClient, "target", "use", "local")
+
+	want := "/var/lib/rootfs/busybox\n/var/lib/rootfs/cflinuxfs2\n"
+
+	res := runGaolIn(t, home, fakeClient, "rootfs", "ls")
+	if res.code != 0 || res.stdout != want {
+		t.Fatalf("rootfs ls exited %d: %q %s", res.code, res.stdout, res.stderr)
+	}
+
+	if mount := fakeClient.CreateArgsForCall(0).BindMounts[0]; mount.SrcPath != "/var/lib/rootfs" {
+		t.Errorf("listed %s, want the target's rootfs dir", mount.SrcPath)
+	}
+
+	res = runGaolIn(t, home, fakeClient, "rootfs", "ls", "--cached")
+	if res.stdout != want || fakeClient.CreateCallCount() != 1 {
+		t.Errorf("listed %q from the cache", res.stdout)
+	}
+}
+
+func TestCatAndTailFiles(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	container := fakeContainer("web-1")
+	container.StreamOutStub = func(src string) (io.ReadCloser, error) {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		tw.WriteHeader(&tar.Header{Name: filepath.Base(src), Mode: 0644, Size: int64(len(src)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(src))
+		tw.Close()
+		return ioutil.NopCloser(&buf), nil
+	}
+
+	process := new(fakes.FakeProcess)
+	container.RunReturns(process, nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	runGaolIn(t, home, fakeClient, "alias", "add", "web", "web-1")
+
+	res := runGaolIn(t, home, fakeClient, "cat", "web:/etc/hosts", "web-1:/etc/hostname")
+	if res.code != 0 || res.stdout != "/etc/hosts/etc/hostname" {
+		t.Errorf("cat exited %d: %q", res.code, res.stdout)
+	}
+
+	if fakeClient.LookupArgsForCall(0) != "web-1" {
+		t.Errorf("looked up %s, want the alias resolved", fakeClient.LookupArgsForCall(0))
+	}
+
+	runGaolIn(t, home, fakeClient, "use", "web-1")
+
+	res = runGaolIn(t, home, fakeClient, "tail", "-f", "-n", "5", "/var/log/app.log")
+	if res.code != 0 {
+		t.Fatalf("tail exited %d: %s", res.code, res.stderr)
+	}
+
+	if spec, _ := container.RunArgsForCall(0); strings.Join(spec.Args, " ") != "-n 5 -F /var/log/app.log" {
+		t.Errorf("ran tail %v", spec.Args)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/xoebus/gaol/commands"
+)
+
+// embeddedHelper is the helper binary built into gaol with -tags
+// embedhelper, if it was.
+var embeddedHelper []byte
+
+// loadHelper reads the helper binary which is streamed into containers:
+// the one given with --helper, or else the one built into gaol, or else
+// gaol-helper next to gaol itself.
+func loadHelper(c *cli.Context) (*commands.Helper, error) {
+	path := c.GlobalString("helper")
+
+	if path == "" && embeddedHelper != nil {
+		return &commands.Helper{Binary: embeddedHelper}, nil
+	}
+
+	if path == "" {
+		executable, err := os.Executable()
+		if err != nil {
+			return nil, err
+		}
+
+		path = filepath.Join(filepath.Dir(executable), "gaol-helper")
+	}
+
+	binary, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no helper at %s: build one with `CGO_ENABLED=0 GOOS=linux go build -o %s ./helper` or give its path with --helper", path, path)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &commands.Helper{Binary: binary}, nil
+}
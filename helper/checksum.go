@@ -0,0 +1,43 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// checksum prints the SHA-256 of each file as sha256sum does:
+// sha256 <file>...
+func checksum(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: sha256 <file>...")
+	}
+
+	for _, path := range args {
+		sum, err := sha256File(path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s  %s\n", sum, path)
+	}
+
+	return nil
+}
+
+func sha256File(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
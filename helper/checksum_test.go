@@ -0,0 +1,23 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestSHA256File(t *testing.T) {
+	file, err := ioutil.TempFile("", "checksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	file.WriteString("hello\n")
+	file.Close()
+
+	sum, err := sha256File(file.Name())
+	if err != nil || sum != "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03" {
+		t.Errorf("got %s (%v)", sum, err)
+	}
+}
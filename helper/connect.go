@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+)
+
+// connect pipes stdin and stdout to a TCP connection, as netcat does:
+// connect <host> <port>
+func connect(args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: connect <host> <port>")
+	}
+
+	conn, err := net.Dial("tcp", net.JoinHostPort(args[0], args[1]))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	go func() {
+		io.Copy(conn, os.Stdin)
+
+		// let the other end know nothing more is coming
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			tcp.CloseWrite()
+		}
+	}()
+
+	_, err = io.Copy(os.Stdout, conn)
+	return err
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// curlFailed is the status curl exits with when --fail is given and the
+// server responds with an error, as curl does.
+const curlFailed = 22
+
+type headers []string
+
+func (h *headers) String() string { return strings.Join(*h, ", ") }
+
+func (h *headers) Set(value string) error {
+	*h = append(*h, value)
+	return nil
+}
+
+// curl makes an HTTP request and prints the response's body, taking the same
+// flags as gaol curl: curl [-X method] [-H header]... [-d data] [-i] [-f] [-k]
+// [-m time] <url>
+func curl(args []string) error {
+	flags := flag.NewFlagSet("curl", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+
+	var requestHeaders headers
+	method := flags.String("X", "", "")
+	flags.Var(&requestHeaders, "H", "")
+	data := flags.String("d", "", "")
+	include := flags.Bool("i", false, "")
+	fail := flags.Bool("f", false, "")
+	insecure := flags.Bool("k", false, "")
+	maxTime := flags.Duration("m", 30*time.Second, "")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: curl [-X method] [-H header]... [-d data] [-i] [-f] [-k] [-m time] <url>")
+	}
+
+	req, err := newCurlRequest(*method, flags.Arg(0), requestHeaders, *data)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{
+		Timeout: *maxTime,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: *insecure},
+		},
+	}
+
+	response, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if *include {
+		fmt.Printf("%s %s\r\n", response.Proto, response.Status)
+		response.Header.Write(os.Stdout)
+		fmt.Print("\r\n")
+	}
+
+	if _, err := io.Copy(os.Stdout, response.Body); err != nil {
+		return err
+	}
+
+	if *fail && response.StatusCode >= 400 {
+		fmt.Fprintf(os.Stderr, "gaol-helper curl: %s responded %s\n", flags.Arg(0), response.Status)
+		return exitError(curlFailed)
+	}
+
+	return nil
+}
+
+func newCurlRequest(method string, url string, requestHeaders []string, data string) (*http.Request, error) {
+	if method == "" {
+		method = "GET"
+		if data != "" {
+			method = "POST"
+		}
+	}
+
+	var body io.Reader
+	if data != "" {
+		body = strings.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range requestHeaders {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid header %q: must be Name: value", header)
+		}
+
+		req.Header.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+
+	if data != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	return req, nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestNewCurlRequest(t *testing.T) {
+	req, err := newCurlRequest("", "http://localhost:8080/health", []string{"Accept: text/plain"}, "check=deep")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := ioutil.ReadAll(req.Body)
+	if req.Method != "POST" || req.Header.Get("Accept") != "text/plain" || string(body) != "check=deep" {
+		t.Errorf("made %s %s with %v and %q", req.Method, req.URL, req.Header, body)
+	}
+
+	req, err = newCurlRequest("", "http://localhost:8080/health", nil, "")
+	if err != nil || req.Method != "GET" {
+		t.Errorf("made %v (%v), want a GET without data", req, err)
+	}
+
+	if _, err := newCurlRequest("", "http://localhost/", []string{"no colon"}, ""); err == nil {
+		t.Error("expected an invalid header to be rejected")
+	}
+}
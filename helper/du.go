@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// virtualDirs hold no files on disk, so are left out of usage of /.
+var virtualDirs = map[string]bool{
+	"/dev":  true,
+	"/proc": true,
+	"/sys":  true,
+}
+
+// du prints a tab-separated line for each entry in a directory, / unless
+// given another, with the bytes of files in it: du [dir]
+func du(args []string) error {
+	dir := "/"
+	if len(args) > 1 {
+		return errors.New("usage: du [dir]")
+	}
+
+	if len(args) == 1 {
+		dir = args[0]
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if virtualDirs[path] {
+			continue
+		}
+
+		fmt.Printf("%d\t%s\n", bytesUnder(path), path)
+	}
+
+	return nil
+}
+
+// bytesUnder adds up the sizes of the files under path, skipping those which
+// cannot be read.
+func bytesUnder(path string) uint64 {
+	var total uint64
+
+	filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if info.Mode().IsRegular() {
+			total += uint64(info.Size())
+		}
+
+		return nil
+	})
+
+	return total
+}
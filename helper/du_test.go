@@ -0,0 +1,27 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBytesUnder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "du")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(filepath.Join(dir, "a", "b"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "a", "one"), make([]byte, 100), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "a", "b", "two"), make([]byte, 23), 0644)
+
+	// links are not followed
+	os.Symlink(filepath.Join(dir, "a"), filepath.Join(dir, "link"))
+
+	if n := bytesUnder(dir); n != 123 {
+		t.Errorf("counted %d bytes, want 123", n)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// grep prints a tab-separated line for each line of the files under path
+// which matches the pattern: the file, the line number and the line. Binary
+// files are skipped. As grep does, it exits 1 if nothing matched:
+// grep [-i] <pattern> <path>
+func grep(args []string) error {
+	prefix := ""
+	if len(args) == 3 && args[0] == "-i" {
+		prefix = "(?i)"
+		args = args[1:]
+	}
+
+	if len(args) != 2 {
+		return errors.New("usage: grep [-i] <pattern> <path>")
+	}
+
+	pattern, err := regexp.Compile(prefix + args[0])
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	matched := false
+
+	err = filepath.Walk(args[1], func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if info.IsDir() && virtualDirs[path] {
+			return filepath.SkipDir
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		defer file.Close()
+
+		if grepFile(pattern, path, file, w) {
+			matched = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !matched {
+		w.Flush()
+		return exitError(1)
+	}
+
+	return nil
+}
+
+// grepFile writes the lines of r which match the pattern, stopping at the
+// first line which looks binary, and reports whether any did.
+func grepFile(pattern *regexp.Regexp, path string, r io.Reader, w io.Writer) bool {
+	reader := bufio.NewReader(r)
+	matched := false
+
+	for n := 1; ; n++ {
+		line, err := reader.ReadBytes('\n')
+		if len(line) == 0 && err != nil || bytes.IndexByte(line, 0) >= 0 {
+			return matched
+		}
+
+		line = bytes.TrimRight(line, "\r\n")
+		if pattern.Match(line) {
+			fmt.Fprintf(w, "%s\t%d\t%s\n", path, n, line)
+			matched = true
+		}
+
+		if err != nil {
+			return matched
+		}
+	}
+}
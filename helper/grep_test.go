@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGrepFile(t *testing.T) {
+	var out bytes.Buffer
+
+	input := "listen 80;\nserver_name example.com;\r\nlisten 443 ssl;"
+	if !grepFile(regexp.MustCompile(`listen \d+`), "/etc/nginx.conf", strings.NewReader(input), &out) {
+		t.Error("did not report the match")
+	}
+
+	if want := "/etc/nginx.conf\t1\tlisten 80;\n/etc/nginx.conf\t3\tlisten 443 ssl;\n"; out.String() != want {
+		t.Errorf("printed %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if grepFile(regexp.MustCompile(`ELF`), "/bin/sh", strings.NewReader("\x7fELF\x00\x01"), &out) || out.Len() != 0 {
+		t.Errorf("matched a binary file: %q", out.String())
+	}
+}
@@ -0,0 +1,64 @@
+// gaol-helper is streamed into containers by gaol to do what their rootfs
+// may have no tools for. It must be built statically, for the platform the
+// containers run on:
+//
+//	CGO_ENABLED=0 GOOS=linux go build -o gaol-helper ./helper
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// tools are what the helper can do, by name.
+var tools = map[string]func(args []string) error{
+	"connect": connect,
+	"curl":    curl,
+	"du":      du,
+	"grep":    grep,
+	"ps":      ps,
+	"sha256":  checksum,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	tool, found := tools[os.Args[1]]
+	if !found {
+		usage()
+	}
+
+	err := tool(os.Args[2:])
+	if exit, ok := err.(exitError); ok {
+		os.Exit(int(exit))
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gaol-helper %s: %s\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	names := []string{}
+	for name := range tools {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "usage: gaol-helper %s [args]\n", strings.Join(names, "|"))
+	os.Exit(2)
+}
+
+// exitError is a failure which the tool has already reported, exiting with
+// a particular status.
+type exitError int
+
+func (err exitError) Error() string {
+	return fmt.Sprintf("exit status %d", int(err))
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// procRoot is where the processes are read from, and passwdPath the users;
+// tests replace them.
+var (
+	procRoot   = "/proc"
+	passwdPath = "/etc/passwd"
+)
+
+// clockTicks is USER_HZ, which the times in /proc are counted in. It is 100
+// on every platform Linux supports.
+const clockTicks = 100
+
+// processNameVariable is set by gaol on the processes it starts by name.
+const processNameVariable = "GAOL_PROCESS"
+
+// ps prints a tab-separated line for every process in the container: its
+// pid, user, cpu usage as a percentage of its lifetime, resident memory in
+// bytes, the name gaol started it as, if any, and its command line.
+func ps(args []string) error {
+	processes, err := listProcesses()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range processes {
+		fmt.Printf("%d\t%s\t%.1f\t%d\t%s\t%s\n", p.pid, p.user, p.cpu, p.rss, p.name, p.command)
+	}
+
+	return nil
+}
+
+type process struct {
+	pid     int
+	user    string
+	cpu     float64
+	rss     uint64
+	name    string
+	command string
+}
+
+// listProcesses reads the processes from /proc in order of pid. Processes
+// which exit while they are being read are left out.
+func listProcesses() ([]process, error) {
+	entries, err := ioutil.ReadDir(procRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	uptime, err := readUptime()
+	if err != nil {
+		return nil, err
+	}
+
+	users := readUsers()
+
+	processes := []process{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+
+		p, err := readProcess(pid, uptime, users)
+		if err != nil {
+			continue
+		}
+
+		processes = append(processes, p)
+	}
+
+	sort.Sort(byPID(processes))
+
+	return processes, nil
+}
+
+func readProcess(pid int, uptime float64, users map[string]string) (process, error) {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+	p := process{pid: pid}
+
+	cmdline, err := ioutil.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil {
+		return p, err
+	}
+
+	stat, err := ioutil.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return p, err
+	}
+
+	// the command name is in parentheses and may hold spaces of its own
+	end := strings.LastIndex(string(stat), ")")
+	start := strings.Index(string(stat), "(")
+	if start == -1 || end == -1 {
+		return p, errors.New("malformed stat")
+	}
+
+	fields := strings.Fields(string(stat)[end+1:])
+	if len(fields) < 20 {
+		return p, errors.New("malformed stat")
+	}
+
+	p.command = strings.TrimSpace(strings.Replace(string(cmdline), "\x00", " ", -1))
+	if p.command == "" {
+		// kernel threads have no command line, only a name
+		p.command = "[" + string(stat)[start+1:end] + "]"
+	}
+
+	utime, _ := strconv.ParseFloat(fields[11], 64)
+	stime, _ := strconv.ParseFloat(fields[12], 64)
+	started, _ := strconv.ParseFloat(fields[19], 64)
+
+	if lifetime := uptime - started/clockTicks; lifetime > 0 {
+		p.cpu = (utime + stime) / clockTicks / lifetime * 100
+	}
+
+	if statm, err := ioutil.ReadFile(filepath.Join(dir, "statm")); err == nil {
+		if fields := strings.Fields(string(statm)); len(fields) > 1 {
+			pages, _ := strconv.ParseUint(fields[1], 10, 64)
+			p.rss = pages * uint64(os.Getpagesize())
+		}
+	}
+
+	uid := readUID(filepath.Join(dir, "status"))
+	p.user = uid
+	if name, found := users[uid]; found {
+		p.user = name
+	}
+
+	// only readable for processes of the same user, or by root
+	if environ, err := ioutil.ReadFile(filepath.Join(dir, "environ")); err == nil {
+		for _, variable := range strings.Split(string(environ), "\x00") {
+			if strings.HasPrefix(variable, processNameVariable+"=") {
+				p.name = strings.TrimPrefix(variable, processNameVariable+"=")
+			}
+		}
+	}
+
+	return p, nil
+}
+
+func readUptime() (float64, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(procRoot, "uptime"))
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(contents))
+	if len(fields) == 0 {
+		return 0, errors.New("malformed uptime")
+	}
+
+	return strconv.ParseFloat(fields[0], 64)
+}
+
+// readUID returns the real user id from a process's status.
+func readUID(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return "?"
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 1 && fields[0] == "Uid:" {
+			return fields[1]
+		}
+	}
+
+	return "?"
+}
+
+// readUsers returns the names of the users in the container by uid. A
+// rootfs without /etc/passwd leaves users as numbers.
+func readUsers() map[string]string {
+	users := map[string]string{}
+
+	contents, err := ioutil.ReadFile(passwdPath)
+	if err != nil {
+		return users
+	}
+
+	for _, line := range strings.Split(string(contents), "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) > 2 {
+			users[fields[2]] = fields[0]
+		}
+	}
+
+	return users
+}
+
+type byPID []process
+
+func (p byPID) Len() int           { return len(p) }
+func (p byPID) Less(i, j int) bool { return p[i].pid < p[j].pid }
+func (p byPID) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
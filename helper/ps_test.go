@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListProcesses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(root string, passwd string) { procRoot, passwdPath = root, passwd }(procRoot, passwdPath)
+	procRoot = filepath.Join(dir, "proc")
+	passwdPath = filepath.Join(dir, "passwd")
+
+	write := func(path string, contents string) {
+		os.MkdirAll(filepath.Dir(filepath.Join(procRoot, path)), 0755)
+		ioutil.WriteFile(filepath.Join(procRoot, path), []byte(contents), 0644)
+	}
+
+	ioutil.WriteFile(passwdPath, []byte("root:x:0:0:root:/root:/bin/sh\nvcap:x:1000:1000::/home/vcap:/bin/sh\n"), 0644)
+
+	// up for 100s, nginx started at 50s and has used 10s of cpu
+	write("uptime", "100.00 180.00\n")
+
+	write("12/cmdline", "nginx\x00-g\x00daemon off;\x00")
+	write("12/stat", "12 (nginx) S 1 12 12 0 -1 4194560 1 0 0 0 600 400 0 0 20 0 1 0 5000 1000 3 0 0 0 0 0 0 0 0 0 0 0 0 0 0\n")
+	write("12/statm", "250 3 2 1 0 10 0\n")
+	write("12/status", "Name:\tnginx\nUid:\t1000\t1000\t1000\t1000\n")
+	write("12/environ", "PATH=/bin\x00GAOL_PROCESS=web\x00")
+
+	write("2/cmdline", "")
+	write("2/stat", "2 (kworker/0:1 H) S 0 0 0 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 2 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0\n")
+	write("2/status", "Uid:\t0\t0\t0\t0\n")
+
+	write("self/cmdline", "ps\x00")
+
+	processes, err := listProcesses()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(processes) != 2 {
+		t.Fatalf("listed %v, want 2 processes", processes)
+	}
+
+	if p := processes[0]; p.pid != 2 || p.command != "[kworker/0:1 H]" || p.user != "root" {
+		t.Errorf("listed %#v", p)
+	}
+
+	want := process{
+		pid:     12,
+		user:    "vcap",
+		cpu:     20,
+		rss:     3 * uint64(os.Getpagesize()),
+		name:    "web",
+		command: "nginx -g daemon off;",
+	}
+
+	if processes[1] != want {
+		t.Errorf("listed %#v, want %#v", processes[1], want)
+	}
+}
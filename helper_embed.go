@@ -0,0 +1,15 @@
+//go:build embedhelper
+// +build embedhelper
+
+package main
+
+import _ "embed"
+
+// the helper must be built into helper/gaol-helper first
+//
+//go:embed helper/gaol-helper
+var builtInHelper []byte
+
+func init() {
+	embeddedHelper = builtInHelper
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/codegangsta/cli"
+)
+
+// historySize is how many invocations are kept in the history.
+const historySize = 100
+
+func historyPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".gaol", "history")
+}
+
+// historyEntry is an invocation of gaol: the target it was sent to and the
+// global flags saying how, the command and its arguments and the current
+// container, if there was one, which the command acts on when given no
+// handle. Redacted entries had secrets left out of their arguments.
+type historyEntry struct {
+	Time       time.Time `json:"time"`
+	Target     string    `json:"target"`
+	Connection []string  `json:"connection,omitempty"`
+	Handle     string    `json:"handle,omitempty"`
+	Args       []string  `json:"args"`
+	Redacted   bool      `json:"redacted,omitempty"`
+}
+
+// connectionFlags are the global flags, other than the target, which say how
+// the server is reached, along with their values when not given.
+var connectionFlags = []struct {
+	name  string
+	unset string
+}{
+	{"ca-cert", ""},
+	{"client-cert", ""},
+	{"client-key", ""},
+	{"via", ""},
+	{"connect-timeout", defaultConnectTimeout.String()},
+	{"request-timeout", "0s"},
+	{"retries", "0"},
+	{"keepalive", "0s"},
+}
+
+// connectionArgs are the connection flags given, whether on the command line
+// or in the environment, so that a rerun reaches the server the same way.
+func connectionArgs(c *cli.Context) []string {
+	args := []string{}
+	for _, flag := range connectionFlags {
+		if value := c.GlobalString(flag.name); value != flag.unset {
+			args = append(args, "--"+flag.name, value)
+		}
+	}
+
+	return args
+}
+
+// commandLine is the entry's command as it would be typed.
+func (entry historyEntry) commandLine() string {
+	words := []string{"gaol"}
+	for _, arg := range append(append([]string{}, entry.Connection...), entry.Args...) {
+		words = append(words, shellQuote(arg))
+	}
+
+	return strings.Join(words, " ")
+}
+
+// shellQuote quotes the argument for a POSIX shell if it needs it.
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=+:,./@%") == "" {
+		return arg
+	}
+
+	return "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+}
+
+// loadHistory reads the history, oldest first. A missing file is an empty
+// history.
+func loadHistory() ([]historyEntry, error) {
+	contents, err := ioutil.ReadFile(historyPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []historyEntry{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		var entry historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s: %s", historyPath(), err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// saveHistory replaces the history with its last historySize entries.
+func saveHistory(entries []historyEntry) error {
+	if len(entries) > historySize {
+		entries = entries[len(entries)-historySize:]
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(historyPath()), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(historyPath(), buf.Bytes(), 0600)
+}
+
+// notRecorded are the commands left out of the history, which look at or
+// repeat it, or do not talk to a server.
+var notRecorded = map[string]bool{
+	"history":    true,
+	"rerun":      true,
+	"help":       true,
+	"h":          true,
+	"completion": true,
+}
+
+// recordHistory adds the invocation, with its secrets redacted, to the
+// history unless --no-history is given. The history is only a convenience,
+// so failing to keep it does not stop the command.
+func recordHistory(c *cli.Context) {
+	args := c.Args()
+	if c.GlobalBool("no-history") || !args.Present() || notRecorded[args.First()] {
+		return
+	}
+
+	for _, arg := range args {
+		if arg == "--generate-bash-completion" || arg == "--help" || arg == "-h" {
+			return
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return
+	}
+
+	target := c.GlobalString("target")
+	if target == "" {
+		target = cfg.Current
+	}
+
+	if target == "" {
+		target = defaultTarget
+	}
+
+	entries, err := loadHistory()
+	if err != nil {
+		return
+	}
+
+	redacted := redactArgs(args)
+
+	entries = append(entries, historyEntry{
+		Time:       time.Now().UTC(),
+		Target:     target,
+		Connection: connectionArgs(c),
+		Handle:     currentHandle(c),
+		Args:       redacted,
+		Redacted:   !reflect.DeepEqual(redacted, []string(args)),
+	})
+
+	saveHistory(entries)
+}
+
+// printHistory writes a tab-separated line for each entry with its number,
+// time, target, the current container (or -) and command line.
+func printHistory(entries []historyEntry) {
+	for i, entry := range entries {
+		handle := entry.Handle
+		if handle == "" {
+			handle = "-"
+		}
+
+		fmt.Printf("%d\t%s\t%s\t%s\t%s\n", i+1, entry.Time.Format(time.RFC3339), entry.Target, handle, entry.commandLine())
+	}
+}
+
+// rerun runs the entry's command again against its target, reached as it
+// was then, and, if it had one, current container. Commands which fail exit
+// here, as they would have when first run. Those which had secrets left out
+// cannot be rerun.
+func rerun(c *cli.Context, entry historyEntry) {
+	if entry.Redacted {
+		fail(fmt.Errorf("cannot rerun %s, as its secrets were not kept", entry.commandLine()))
+	}
+
+	fmt.Fprintln(unlessQuiet(c, os.Stderr), entry.commandLine())
+
+	if entry.Handle != "" {
+		original, set := os.LookupEnv("GAOL_HANDLE")
+		os.Setenv("GAOL_HANDLE", entry.Handle)
+		defer func() {
+			if set {
+				os.Setenv("GAOL_HANDLE", original)
+			} else {
+				os.Unsetenv("GAOL_HANDLE")
+			}
+		}()
+	}
+
+	args := append(append([]string{"gaol", "--target", entry.Target}, entry.Connection...), entry.Args...)
+	if err := newApp().Run(args); err != nil {
+		exit(exitUsage)
+	}
+}
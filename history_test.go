@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestHistory(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	container := fakeContainer("a")
+	container.RunReturns(new(fakes.FakeProcess), nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	runGaolIn(t, home, fakeClient, "run", "a", "echo 'hi there'")
+	runGaolIn(t, home, fakeClient, "--no-history", "run", "a", "true")
+	runGaolIn(t, home, fakeClient, "use", "a")
+	runGaolIn(t, home, fakeClient, "--target", "other:7777", "info", "b")
+
+	res := runGaolIn(t, home, fakeClient, "history")
+	if res.code != 0 {
+		t.Fatalf("history exited %d: %s", res.code, res.stderr)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(res.stdout, "\n"), "\n")
+	want := []string{
+		`1 localhost:7777 - gaol run a 'echo '\''hi there'\'''`,
+		"2 localhost:7777 - gaol use a",
+		"3 other:7777 - gaol info b",
+	}
+
+	if len(lines) != len(want) {
+		t.Fatalf("printed %q, want %d commands", res.stdout, len(want))
+	}
+
+	for i, line := range lines {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 {
+			t.Errorf("printed %q", line)
+			continue
+		}
+
+		if got := strings.Join(append(fields[:1], fields[2:]...), " "); got != want[i] {
+			t.Errorf("printed %q, want %q", got, want[i])
+		}
+	}
+
+	res = runGaolIn(t, home, fakeClient, "rerun", "1")
+	if res.code != 0 {
+		t.Fatalf("rerun exited %d: %s", res.code, res.stderr)
+	}
+
+	if spec, _ := container.RunArgsForCall(container.RunCallCount() - 1); spec.Path != "echo" || spec.Args[0] != "hi there" {
+		t.Errorf("reran %#v", spec)
+	}
+
+	// reruns are recorded as the command they ran, and the current
+	// container is recorded along with commands
+	res = runGaolIn(t, home, fakeClient, "history")
+	if lines := strings.Split(strings.TrimSuffix(res.stdout, "\n"), "\n"); len(lines) != 4 || !strings.HasSuffix(lines[3], "\ta\tgaol run a 'echo '\\''hi there'\\'''") {
+		t.Errorf("printed %q after rerunning", res.stdout)
+	}
+
+	if res := runGaolIn(t, home, fakeClient, "rerun", "9"); res.code != 1 {
+		t.Errorf("rerunning a missing command exited %d", res.code)
+	}
+
+	if res := runGaolIn(t, home, fakeClient, "rerun", "last"); res.code != 2 {
+		t.Errorf("rerunning with an invalid number exited %d", res.code)
+	}
+
+	runGaolIn(t, home, fakeClient, "history", "--clear")
+	if res := runGaolIn(t, home, fakeClient, "history"); res.stdout != "" {
+		t.Errorf("printed %q after clearing the history", res.stdout)
+	}
+}
+
+func TestHistoryRedactsSecrets(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	runGaolIn(t, home, new(fakes.FakeClient), "create", "--count", "0", "--registry-user", "ci", "--registry-password", "hunter2")
+
+	res := runGaolIn(t, home, nil, "history")
+	if strings.Contains(res.stdout, "hunter2") || !strings.Contains(res.stdout, "--registry-password REDACTED") {
+		t.Errorf("printed %q, want the password redacted", res.stdout)
+	}
+}
+
+func TestRerunUsesTheCurrentContainerOfTheTime(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	container := fakeContainer("a")
+	container.RunReturns(new(fakes.FakeProcess), nil)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(container, nil)
+
+	runGaolIn(t, home, fakeClient, "use", "web")
+	runGaolIn(t, home, fakeClient, "run", "ls")
+	runGaolIn(t, home, fakeClient, "use", "db")
+
+	res := runGaolIn(t, home, fakeClient, "rerun", "2")
+	if res.code != 0 {
+		t.Fatalf("rerun exited %d: %s", res.code, res.stderr)
+	}
+
+	if handle := fakeClient.LookupArgsForCall(fakeClient.LookupCallCount() - 1); handle != "web" {
+		t.Errorf("reran in %q, want the container which was current", handle)
+	}
+
+	if handle := os.Getenv("GAOL_HANDLE"); handle != "" {
+		t.Errorf("left GAOL_HANDLE set to %q", handle)
+	}
+}
+
+func TestRerunRefusesRedactedCommands(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	fakeClient := new(fakes.FakeClient)
+
+	runGaolIn(t, home, fakeClient, "create", "--count", "0", "--registry-user", "ci", "--registry-password", "hunter2")
+
+	res := runGaolIn(t, home, fakeClient, "rerun")
+	if res.code != 1 || !strings.Contains(res.stderr, "secrets were not kept") {
+		t.Errorf("rerun exited %d: %s", res.code, res.stderr)
+	}
+
+	if fakeClient.CreateCallCount() != 0 {
+		t.Error("reran the command with the password redacted")
+	}
+}
+
+func TestRerunReachesTheServerTheSameWay(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.LookupReturns(fakeContainer("a"), nil)
+
+	runGaolIn(t, home, fakeClient, "--via", "ops@jump", "--retries", "3", "info", "a")
+
+	res := runGaolIn(t, home, fakeClient, "rerun")
+	if res.code != 0 {
+		t.Fatalf("rerun exited %d: %s", res.code, res.stderr)
+	}
+
+	if want := "gaol --via ops@jump --retries 3 info a\n"; !strings.HasPrefix(res.stderr, want) {
+		t.Errorf("reran %q, want %q", res.stderr, want)
+	}
+}
+
+func TestSaveHistoryKeepsTheLastCommands(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	defer os.Setenv("HOME", originalHome)
+
+	entries := []historyEntry{}
+	for i := 0; i < historySize+5; i++ {
+		entries = append(entries, historyEntry{Target: "local", Args: []string{"info", fmt.Sprint(i)}})
+	}
+
+	if err := saveHistory(entries); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := loadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(saved) != historySize || saved[0].Args[1] != "5" {
+		t.Errorf("kept %d commands from %v, want the last %d", len(saved), saved[0].Args, historySize)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"web-1":      "web-1",
+		"--env=A=1":  "--env=A=1",
+		"/tmp/a.txt": "/tmp/a.txt",
+		"":           "''",
+		"ls -la":     "'ls -la'",
+		"it's":       `'it'\''s'`,
+		"echo $HOME": "'echo $HOME'",
+		"ci-*":       "'ci-*'",
+	}
+
+	for arg, want := range tests {
+		if got := shellQuote(arg); got != want {
+			t.Errorf("quoted %q as %s, want %s", arg, got, want)
+		}
+	}
+}
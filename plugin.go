@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/codegangsta/cli"
+)
+
+// pluginPrefix is the prefix of the executables on PATH which extend gaol:
+// running `gaol foo` runs gaol-foo when foo is not one of gaol's commands.
+const pluginPrefix = "gaol-"
+
+// findPlugin returns the path of the plugin providing the command name.
+func findPlugin(name string) (string, bool) {
+	if name == "" || strings.ContainsRune(name, os.PathSeparator) {
+		return "", false
+	}
+
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return "", false
+	}
+
+	return path, true
+}
+
+// listPlugins returns the names of the commands provided by plugins, along
+// with the path of each. Plugins earlier on PATH shadow later ones.
+func listPlugins() ([]string, map[string]string) {
+	paths := map[string]string{}
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		matches, _ := filepath.Glob(filepath.Join(dir, pluginPrefix+"*"))
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+				continue
+			}
+
+			name := strings.TrimPrefix(filepath.Base(match), pluginPrefix)
+			if _, found := paths[name]; !found {
+				paths[name] = match
+			}
+		}
+	}
+
+	names := []string{}
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, paths
+}
+
+// gaolEnv is the environment of the programs gaol runs, such as plugins. The
+// resolved target and the global flags are passed in the variables gaol
+// reads them from, so that a program which runs gaol talks to the same
+// server in the same way.
+func gaolEnv(c *cli.Context) []string {
+	target := currentTarget(c)
+
+	settings := map[string]string{
+		"GAOL_TARGET":          target.Address,
+		"GAOL_CA_CERT":         target.CACert,
+		"GAOL_CLIENT_CERT":     target.ClientCert,
+		"GAOL_CLIENT_KEY":      target.ClientKey,
+		"GAOL_VIA":             target.Via,
+		"GAOL_CONNECT_TIMEOUT": c.GlobalDuration("connect-timeout").String(),
+		"GAOL_REQUEST_TIMEOUT": c.GlobalDuration("request-timeout").String(),
+		"GAOL_RETRIES":         strconv.Itoa(c.GlobalInt("retries")),
+		"GAOL_KEEPALIVE":       c.GlobalDuration("keepalive").String(),
+		"GAOL_VERBOSE":         strconv.FormatBool(c.GlobalBool("verbose")),
+		"GAOL_TRACE_FILE":      c.GlobalString("trace-file"),
+		"GAOL_HELPER":          c.GlobalString("helper"),
+		"GAOL_JSON":            strconv.FormatBool(c.GlobalBool("json")),
+		"GAOL_DRY_RUN":         strconv.FormatBool(c.GlobalBool("dry-run")),
+		"GAOL_NO_COLOR":        strconv.FormatBool(c.GlobalBool("no-color")),
+		"GAOL_QUIET":           strconv.FormatBool(c.GlobalBool("quiet")),
+		"GAOL_CONFIRM_ABOVE":   strconv.Itoa(c.GlobalInt("confirm-above")),
+		"GAOL_AUDIT":           strconv.FormatBool(c.GlobalBool("audit")),
+		"GAOL_AUDIT_FILE":      c.GlobalString("audit-file"),
+		"GAOL_PORCELAIN":       c.GlobalString("porcelain"),
+
+		// the commands gaol runs itself are not the user's to rerun
+		"GAOL_NO_HISTORY": "true",
+	}
+
+	env := []string{}
+	for _, kv := range os.Environ() {
+		if _, overridden := settings[strings.SplitN(kv, "=", 2)[0]]; !overridden {
+			env = append(env, kv)
+		}
+	}
+
+	for key, value := range settings {
+		if value != "" {
+			env = append(env, key+"="+value)
+		}
+	}
+
+	return env
+}
+
+// runPlugin runs the plugin at path with the arguments following its name,
+// connected to gaol's stdin, stdout and stderr. If the plugin fails gaol
+// exits with the plugin's exit status.
+func runPlugin(c *cli.Context, path string, args []string) error {
+	cmd := exec.Command(path, args...)
+	cmd.Env = gaolEnv(c)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// the plugin handles interrupts and gaol exits as it does
+	ignoreInterrupts(true)
+	err := cmd.Run()
+	ignoreInterrupts(false)
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		exit(exitErr.Sys().(syscall.WaitStatus).ExitStatus())
+	}
+
+	return err
+}
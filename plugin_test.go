@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestPlugins(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gaol-plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := "#!/bin/sh\necho \"$@\"\necho $GAOL_TARGET $GAOL_RETRIES $GAOL_PORCELAIN\nexit 3\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "gaol-hello"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	originalPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(filepath.ListSeparator)+originalPath)
+	defer os.Setenv("PATH", originalPath)
+
+	res := runGaol(t, new(fakes.FakeClient), "--target", "garden:7777", "--retries", "2", "--porcelain", "v1", "hello", "--name", "world")
+	if res.code != 3 {
+		t.Errorf("exited %d, want the plugin's status", res.code)
+	}
+
+	if res.stdout != "--name world\ngarden:7777 2 v1\n" {
+		t.Errorf("printed %q", res.stdout)
+	}
+
+	res = runGaol(t, new(fakes.FakeClient), "plugins")
+	if !strings.HasPrefix(res.stdout, "hello\t") {
+		t.Errorf("listed %q", res.stdout)
+	}
+}
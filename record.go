@@ -0,0 +1,631 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden"
+	gconn "github.com/cloudfoundry-incubator/garden/client/connection"
+)
+
+// session is a recording of the calls made to the server, which can be
+// replayed in place of the server.
+type session struct {
+	Calls []*recordedCall `json:"calls"`
+}
+
+// recordedCall is a single call and how the server responded to it. The
+// arguments are kept as canonical JSON so that calls can be matched up when
+// replaying.
+type recordedCall struct {
+	Call   string          `json:"call"`
+	Args   json.RawMessage `json:"args"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Status int             `json:"status,omitempty"`
+
+	replayed bool
+}
+
+// recordedProcess is the result of running or attaching to a process: its
+// ID, its output and how it exited.
+type recordedProcess struct {
+	ID         uint32 `json:"id"`
+	Stdout     []byte `json:"stdout,omitempty"`
+	Stderr     []byte `json:"stderr,omitempty"`
+	ExitStatus int    `json:"exit_status"`
+	WaitError  string `json:"wait_error,omitempty"`
+}
+
+// canonicalArgs encodes the arguments of a call as JSON with the keys of
+// every object sorted, so that the same call always encodes the same way.
+func canonicalArgs(args ...interface{}) (json.RawMessage, error) {
+	if args == nil {
+		args = []interface{}{}
+	}
+
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(decoded)
+}
+
+// recordingConnection records every call made to the server, and the
+// response, to a session file which is written when gaol exits. The output
+// of processes and the contents of streamed out files are recorded as well
+// so that they can be replayed.
+type recordingConnection struct {
+	gconn.Connection
+
+	session session
+	mu      sync.Mutex
+}
+
+func newRecordingConnection(conn gconn.Connection, path string) gconn.Connection {
+	r := &recordingConnection{
+		Connection: conn,
+	}
+
+	atExit(func() {
+		if err := r.save(path); err != nil {
+			fmt.Fprintln(os.Stderr, "failed to save recording:", err)
+		}
+	})
+
+	return r
+}
+
+func (r *recordingConnection) save(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	contents, err := json.MarshalIndent(r.session, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, contents, 0600)
+}
+
+func (r *recordingConnection) record(result interface{}, err error, call string, args ...interface{}) *recordedCall {
+	recorded := &recordedCall{Call: call}
+
+	recorded.Args, _ = canonicalArgs(args...)
+
+	if err != nil {
+		recorded.Error = err.Error()
+		if gerr, ok := err.(gconn.Error); ok {
+			recorded.Status = gerr.StatusCode
+		}
+	} else if result != nil {
+		recorded.Result, _ = json.Marshal(result)
+	}
+
+	r.mu.Lock()
+	r.session.Calls = append(r.session.Calls, recorded)
+	r.mu.Unlock()
+
+	return recorded
+}
+
+// update re-encodes the result of a call which was still in progress when
+// it was recorded, such as a process which has since exited.
+func (r *recordingConnection) update(recorded *recordedCall, result interface{}) {
+	r.mu.Lock()
+	recorded.Result, _ = json.Marshal(result)
+	r.mu.Unlock()
+}
+
+func (r *recordingConnection) Ping() error {
+	err := r.Connection.Ping()
+	r.record(nil, err, "Ping")
+	return err
+}
+
+func (r *recordingConnection) Capacity() (garden.Capacity, error) {
+	capacity, err := r.Connection.Capacity()
+	r.record(capacity, err, "Capacity")
+	return capacity, err
+}
+
+func (r *recordingConnection) Create(spec garden.ContainerSpec) (string, error) {
+	handle, err := r.Connection.Create(spec)
+	r.record(handle, err, "Create", spec)
+	return handle, err
+}
+
+func (r *recordingConnection) List(properties garden.Properties) ([]string, error) {
+	handles, err := r.Connection.List(properties)
+	r.record(handles, err, "List", properties)
+	return handles, err
+}
+
+func (r *recordingConnection) Destroy(handle string) error {
+	err := r.Connection.Destroy(handle)
+	r.record(nil, err, "Destroy", handle)
+	return err
+}
+
+func (r *recordingConnection) Stop(handle string, kill bool) error {
+	err := r.Connection.Stop(handle, kill)
+	r.record(nil, err, "Stop", handle, kill)
+	return err
+}
+
+func (r *recordingConnection) Info(handle string) (garden.ContainerInfo, error) {
+	info, err := r.Connection.Info(handle)
+	r.record(info, err, "Info", handle)
+	return info, err
+}
+
+func (r *recordingConnection) StreamIn(handle string, dstPath string, reader io.Reader) error {
+	err := r.Connection.StreamIn(handle, dstPath, reader)
+	r.record(nil, err, "StreamIn", handle, dstPath)
+	return err
+}
+
+func (r *recordingConnection) StreamOut(handle string, srcPath string) (io.ReadCloser, error) {
+	stream, err := r.Connection.StreamOut(handle, srcPath)
+	recorded := r.record(nil, err, "StreamOut", handle, srcPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recordingStream{
+		ReadCloser: stream,
+		done: func(contents []byte) {
+			r.update(recorded, contents)
+		},
+	}, nil
+}
+
+func (r *recordingConnection) LimitBandwidth(handle string, limits garden.BandwidthLimits) (garden.BandwidthLimits, error) {
+	result, err := r.Connection.LimitBandwidth(handle, limits)
+	r.record(result, err, "LimitBandwidth", handle, limits)
+	return result, err
+}
+
+func (r *recordingConnection) LimitCPU(handle string, limits garden.CPULimits) (garden.CPULimits, error) {
+	result, err := r.Connection.LimitCPU(handle, limits)
+	r.record(result, err, "LimitCPU", handle, limits)
+	return result, err
+}
+
+func (r *recordingConnection) LimitDisk(handle string, limits garden.DiskLimits) (garden.DiskLimits, error) {
+	result, err := r.Connection.LimitDisk(handle, limits)
+	r.record(result, err, "LimitDisk", handle, limits)
+	return result, err
+}
+
+func (r *recordingConnection) LimitMemory(handle string, limits garden.MemoryLimits) (garden.MemoryLimits, error) {
+	result, err := r.Connection.LimitMemory(handle, limits)
+	r.record(result, err, "LimitMemory", handle, limits)
+	return result, err
+}
+
+func (r *recordingConnection) CurrentBandwidthLimits(handle string) (garden.BandwidthLimits, error) {
+	limits, err := r.Connection.CurrentBandwidthLimits(handle)
+	r.record(limits, err, "CurrentBandwidthLimits", handle)
+	return limits, err
+}
+
+func (r *recordingConnection) CurrentCPULimits(handle string) (garden.CPULimits, error) {
+	limits, err := r.Connection.CurrentCPULimits(handle)
+	r.record(limits, err, "CurrentCPULimits", handle)
+	return limits, err
+}
+
+func (r *recordingConnection) CurrentDiskLimits(handle string) (garden.DiskLimits, error) {
+	limits, err := r.Connection.CurrentDiskLimits(handle)
+	r.record(limits, err, "CurrentDiskLimits", handle)
+	return limits, err
+}
+
+func (r *recordingConnection) CurrentMemoryLimits(handle string) (garden.MemoryLimits, error) {
+	limits, err := r.Connection.CurrentMemoryLimits(handle)
+	r.record(limits, err, "CurrentMemoryLimits", handle)
+	return limits, err
+}
+
+func (r *recordingConnection) Run(handle string, spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+	output := &recordingProcessIO{}
+	process, err := r.Connection.Run(handle, spec, output.wrap(processIO))
+	recorded := r.record(nil, err, "Run", handle, spec)
+	return r.recordProcess(recorded, process, output), err
+}
+
+func (r *recordingConnection) Attach(handle string, processID uint32, processIO garden.ProcessIO) (garden.Process, error) {
+	output := &recordingProcessIO{}
+	process, err := r.Connection.Attach(handle, processID, output.wrap(processIO))
+	recorded := r.record(nil, err, "Attach", handle, processID)
+	return r.recordProcess(recorded, process, output), err
+}
+
+func (r *recordingConnection) recordProcess(recorded *recordedCall, process garden.Process, output *recordingProcessIO) garden.Process {
+	if process == nil {
+		return nil
+	}
+
+	r.update(recorded, recordedProcess{ID: process.ID()})
+
+	return &recordingProcess{
+		Process: process,
+		done: func(status int, err error) {
+			result := recordedProcess{
+				ID:         process.ID(),
+				Stdout:     output.stdout.Bytes(),
+				Stderr:     output.stderr.Bytes(),
+				ExitStatus: status,
+			}
+
+			if err != nil {
+				result.WaitError = err.Error()
+			}
+
+			r.update(recorded, result)
+		},
+	}
+}
+
+func (r *recordingConnection) NetIn(handle string, hostPort, containerPort uint32) (uint32, uint32, error) {
+	mappedHostPort, mappedContainerPort, err := r.Connection.NetIn(handle, hostPort, containerPort)
+	r.record([]uint32{mappedHostPort, mappedContainerPort}, err, "NetIn", handle, hostPort, containerPort)
+	return mappedHostPort, mappedContainerPort, err
+}
+
+func (r *recordingConnection) NetOut(handle string, rule garden.NetOutRule) error {
+	err := r.Connection.NetOut(handle, rule)
+	r.record(nil, err, "NetOut", handle, rule)
+	return err
+}
+
+func (r *recordingConnection) GetProperty(handle string, name string) (string, error) {
+	value, err := r.Connection.GetProperty(handle, name)
+	r.record(value, err, "GetProperty", handle, name)
+	return value, err
+}
+
+func (r *recordingConnection) SetProperty(handle string, name string, value string) error {
+	err := r.Connection.SetProperty(handle, name, value)
+	r.record(nil, err, "SetProperty", handle, name, value)
+	return err
+}
+
+func (r *recordingConnection) RemoveProperty(handle string, name string) error {
+	err := r.Connection.RemoveProperty(handle, name)
+	r.record(nil, err, "RemoveProperty", handle, name)
+	return err
+}
+
+// recordingStream keeps a copy of everything read from a streamed out file.
+type recordingStream struct {
+	io.ReadCloser
+
+	contents bytes.Buffer
+	done     func([]byte)
+}
+
+func (s *recordingStream) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	s.contents.Write(p[:n])
+	return n, err
+}
+
+func (s *recordingStream) Close() error {
+	s.done(s.contents.Bytes())
+	return s.ReadCloser.Close()
+}
+
+// recordingProcessIO keeps a copy of the output of a process.
+type recordingProcessIO struct {
+	stdout lockedBuffer
+	stderr lockedBuffer
+}
+
+func (o *recordingProcessIO) wrap(processIO garden.ProcessIO) garden.ProcessIO {
+	if processIO.Stdout != nil {
+		processIO.Stdout = io.MultiWriter(processIO.Stdout, &o.stdout)
+	}
+
+	if processIO.Stderr != nil {
+		processIO.Stderr = io.MultiWriter(processIO.Stderr, &o.stderr)
+	}
+
+	return processIO
+}
+
+type lockedBuffer struct {
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+// recordingProcess records how a process exited once it has been waited on.
+type recordingProcess struct {
+	garden.Process
+
+	done func(int, error)
+}
+
+func (p *recordingProcess) Wait() (int, error) {
+	status, err := p.Process.Wait()
+	p.done(status, err)
+	return status, err
+}
+
+// replayConnection answers calls from a recorded session instead of
+// contacting a server. Each call is answered by the first recorded call with
+// the same name and arguments which has not been replayed yet.
+type replayConnection struct {
+	session session
+	mu      sync.Mutex
+}
+
+func newReplayConnection(path string) (gconn.Connection, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &replayConnection{}
+	if err := json.Unmarshal(contents, &r.session); err != nil {
+		return nil, fmt.Errorf("invalid recording %s: %s", path, err)
+	}
+
+	// the recording is indented, so bring the arguments back to the form
+	// in which calls are compared
+	for _, recorded := range r.session.Calls {
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, recorded.Args); err != nil {
+			return nil, fmt.Errorf("invalid recording %s: %s", path, err)
+		}
+
+		recorded.Args = compacted.Bytes()
+	}
+
+	return r, nil
+}
+
+// replay finds the recorded call, decodes its result into result (unless it
+// is nil) and returns the error the server responded with.
+func (r *replayConnection) replay(result interface{}, call string, args ...interface{}) error {
+	encoded, err := canonicalArgs(args...)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, recorded := range r.session.Calls {
+		if recorded.replayed || recorded.Call != call || !bytes.Equal(recorded.Args, encoded) {
+			continue
+		}
+
+		recorded.replayed = true
+
+		if recorded.Error != "" {
+			if recorded.Status != 0 {
+				return gconn.Error{StatusCode: recorded.Status, Message: recorded.Error}
+			}
+
+			return errors.New(recorded.Error)
+		}
+
+		if result != nil && recorded.Result != nil {
+			return json.Unmarshal(recorded.Result, result)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("no recorded call matches %s", formatCall(call, args...))
+}
+
+func (r *replayConnection) Ping() error {
+	return r.replay(nil, "Ping")
+}
+
+func (r *replayConnection) Capacity() (garden.Capacity, error) {
+	var capacity garden.Capacity
+	err := r.replay(&capacity, "Capacity")
+	return capacity, err
+}
+
+func (r *replayConnection) Create(spec garden.ContainerSpec) (string, error) {
+	var handle string
+	err := r.replay(&handle, "Create", spec)
+	return handle, err
+}
+
+func (r *replayConnection) List(properties garden.Properties) ([]string, error) {
+	var handles []string
+	err := r.replay(&handles, "List", properties)
+	return handles, err
+}
+
+func (r *replayConnection) Destroy(handle string) error {
+	return r.replay(nil, "Destroy", handle)
+}
+
+func (r *replayConnection) Stop(handle string, kill bool) error {
+	return r.replay(nil, "Stop", handle, kill)
+}
+
+func (r *replayConnection) Info(handle string) (garden.ContainerInfo, error) {
+	var info garden.ContainerInfo
+	err := r.replay(&info, "Info", handle)
+	return info, err
+}
+
+func (r *replayConnection) StreamIn(handle string, dstPath string, reader io.Reader) error {
+	io.Copy(ioutil.Discard, reader)
+	return r.replay(nil, "StreamIn", handle, dstPath)
+}
+
+func (r *replayConnection) StreamOut(handle string, srcPath string) (io.ReadCloser, error) {
+	var contents []byte
+	if err := r.replay(&contents, "StreamOut", handle, srcPath); err != nil {
+		return nil, err
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(contents)), nil
+}
+
+func (r *replayConnection) LimitBandwidth(handle string, limits garden.BandwidthLimits) (garden.BandwidthLimits, error) {
+	var result garden.BandwidthLimits
+	err := r.replay(&result, "LimitBandwidth", handle, limits)
+	return result, err
+}
+
+func (r *replayConnection) LimitCPU(handle string, limits garden.CPULimits) (garden.CPULimits, error) {
+	var result garden.CPULimits
+	err := r.replay(&result, "LimitCPU", handle, limits)
+	return result, err
+}
+
+func (r *replayConnection) LimitDisk(handle string, limits garden.DiskLimits) (garden.DiskLimits, error) {
+	var result garden.DiskLimits
+	err := r.replay(&result, "LimitDisk", handle, limits)
+	return result, err
+}
+
+func (r *replayConnection) LimitMemory(handle string, limits garden.MemoryLimits) (garden.MemoryLimits, error) {
+	var result garden.MemoryLimits
+	err := r.replay(&result, "LimitMemory", handle, limits)
+	return result, err
+}
+
+func (r *replayConnection) CurrentBandwidthLimits(handle string) (garden.BandwidthLimits, error) {
+	var limits garden.BandwidthLimits
+	err := r.replay(&limits, "CurrentBandwidthLimits", handle)
+	return limits, err
+}
+
+func (r *replayConnection) CurrentCPULimits(handle string) (garden.CPULimits, error) {
+	var limits garden.CPULimits
+	err := r.replay(&limits, "CurrentCPULimits", handle)
+	return limits, err
+}
+
+func (r *replayConnection) CurrentDiskLimits(handle string) (garden.DiskLimits, error) {
+	var limits garden.DiskLimits
+	err := r.replay(&limits, "CurrentDiskLimits", handle)
+	return limits, err
+}
+
+func (r *replayConnection) CurrentMemoryLimits(handle string) (garden.MemoryLimits, error) {
+	var limits garden.MemoryLimits
+	err := r.replay(&limits, "CurrentMemoryLimits", handle)
+	return limits, err
+}
+
+func (r *replayConnection) Run(handle string, spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+	var recorded recordedProcess
+	if err := r.replay(&recorded, "Run", handle, spec); err != nil {
+		return nil, err
+	}
+
+	return newReplayProcess(recorded, processIO), nil
+}
+
+func (r *replayConnection) Attach(handle string, processID uint32, processIO garden.ProcessIO) (garden.Process, error) {
+	var recorded recordedProcess
+	if err := r.replay(&recorded, "Attach", handle, processID); err != nil {
+		return nil, err
+	}
+
+	return newReplayProcess(recorded, processIO), nil
+}
+
+func (r *replayConnection) NetIn(handle string, hostPort, containerPort uint32) (uint32, uint32, error) {
+	var ports []uint32
+	if err := r.replay(&ports, "NetIn", handle, hostPort, containerPort); err != nil {
+		return 0, 0, err
+	}
+
+	if len(ports) != 2 {
+		return 0, 0, errors.New("recorded NetIn is missing its ports")
+	}
+
+	return ports[0], ports[1], nil
+}
+
+func (r *replayConnection) NetOut(handle string, rule garden.NetOutRule) error {
+	return r.replay(nil, "NetOut", handle, rule)
+}
+
+func (r *replayConnection) GetProperty(handle string, name string) (string, error) {
+	var value string
+	err := r.replay(&value, "GetProperty", handle, name)
+	return value, err
+}
+
+func (r *replayConnection) SetProperty(handle string, name string, value string) error {
+	return r.replay(nil, "SetProperty", handle, name, value)
+}
+
+func (r *replayConnection) RemoveProperty(handle string, name string) error {
+	return r.replay(nil, "RemoveProperty", handle, name)
+}
+
+// replayProcess writes the recorded output of a process and exits as it
+// did.
+type replayProcess struct {
+	recorded  recordedProcess
+	processIO garden.ProcessIO
+}
+
+func newReplayProcess(recorded recordedProcess, processIO garden.ProcessIO) garden.Process {
+	return &replayProcess{
+		recorded:  recorded,
+		processIO: processIO,
+	}
+}
+
+func (p *replayProcess) ID() uint32 {
+	return p.recorded.ID
+}
+
+func (p *replayProcess) Wait() (int, error) {
+	if p.processIO.Stdout != nil {
+		p.processIO.Stdout.Write(p.recorded.Stdout)
+	}
+
+	if p.processIO.Stderr != nil {
+		p.processIO.Stderr.Write(p.recorded.Stderr)
+	}
+
+	if p.recorded.WaitError != "" {
+		return 0, errors.New(p.recorded.WaitError)
+	}
+
+	return p.recorded.ExitStatus, nil
+}
+
+func (p *replayProcess) SetTTY(garden.TTYSpec) error { return nil }
+func (p *replayProcess) Signal(garden.Signal) error  { return nil }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	gconn "github.com/cloudfoundry-incubator/garden/client/connection"
+	"github.com/cloudfoundry-incubator/garden/client/connection/fakes"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gaol-record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "session.json")
+
+	fakeConn := new(fakes.FakeConnection)
+	fakeConn.CreateReturns("generated", nil)
+	fakeConn.ListReturns([]string{"generated"}, nil)
+	fakeConn.InfoReturns(garden.ContainerInfo{}, gconn.Error{StatusCode: 404, Message: "unknown handle: gone"})
+	fakeConn.NetInReturns(61001, 80, nil)
+
+	spec := garden.ContainerSpec{Properties: garden.Properties{"b": "2", "a": "1"}}
+
+	recording := newRecordingConnection(fakeConn, path)
+	recording.Create(spec)
+	recording.List(nil)
+	recording.Info("gone")
+	recording.NetIn("generated", 0, 80)
+
+	originalExit := osExit
+	osExit = func(int) {}
+	defer func() { osExit = originalExit }()
+
+	// the recording is saved when gaol exits
+	exit(0)
+
+	replay, err := newReplayConnection(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handle, err := replay.Create(spec)
+	if err != nil || handle != "generated" {
+		t.Errorf("replayed Create as %q (%v)", handle, err)
+	}
+
+	handles, err := replay.List(nil)
+	if err != nil || !reflect.DeepEqual(handles, []string{"generated"}) {
+		t.Errorf("replayed List as %v (%v)", handles, err)
+	}
+
+	_, err = replay.Info("gone")
+	if err != (gconn.Error{StatusCode: 404, Message: "unknown handle: gone"}) {
+		t.Errorf("replayed Info with %#v", err)
+	}
+
+	hostPort, containerPort, err := replay.NetIn("generated", 0, 80)
+	if err != nil || hostPort != 61001 || containerPort != 80 {
+		t.Errorf("replayed NetIn as %d -> %d (%v)", hostPort, containerPort, err)
+	}
+
+	if _, err := replay.List(nil); err == nil {
+		t.Error("replayed a call more times than it was recorded")
+	}
+
+	if err := replay.Destroy("generated"); err == nil {
+		t.Error("replayed a call which was never recorded")
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	gconn "github.com/cloudfoundry-incubator/garden/client/connection"
+)
+
+const (
+	initialBackoff = 250 * time.Millisecond
+	maxBackoff     = 5 * time.Second
+)
+
+// resilientConnection gives up on requests which take longer than the
+// request timeout and retries the requests which only read state when the
+// server could not be reached, backing off exponentially between attempts.
+// Requests which stream (e.g. running processes) are passed through as they
+// can take arbitrarily long.
+type resilientConnection struct {
+	gconn.Connection
+
+	requestTimeout time.Duration
+	retries        int
+}
+
+func newResilientConnection(conn gconn.Connection, requestTimeout time.Duration, retries int) gconn.Connection {
+	return &resilientConnection{
+		Connection:     conn,
+		requestTimeout: requestTimeout,
+		retries:        retries,
+	}
+}
+
+type requestTimeoutError struct {
+	timeout time.Duration
+}
+
+func (err requestTimeoutError) Error() string {
+	return fmt.Sprintf("request timed out after %s", err.timeout)
+}
+
+// retryable reports whether err means that no response was received, as
+// opposed to the server responding with an error.
+func retryable(err error) bool {
+	_, responded := err.(gconn.Error)
+	return !responded
+}
+
+func (r *resilientConnection) call(idempotent bool, request func() (interface{}, error)) (interface{}, error) {
+	attempts := 1
+	if idempotent {
+		attempts += r.retries
+	}
+
+	backoff := initialBackoff
+
+	var result interface{}
+	var err error
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+
+		result, err = r.withTimeout(request)
+		if err == nil || !retryable(err) {
+			return result, err
+		}
+	}
+
+	return result, err
+}
+
+func (r *resilientConnection) withTimeout(request func() (interface{}, error)) (interface{}, error) {
+	if r.requestTimeout == 0 {
+		return request()
+	}
+
+	type response struct {
+		result interface{}
+		err    error
+	}
+
+	responses := make(chan response, 1)
+	go func() {
+		result, err := request()
+		responses <- response{result, err}
+	}()
+
+	select {
+	case res := <-responses:
+		return res.result, res.err
+	case <-time.After(r.requestTimeout):
+		return nil, requestTimeoutError{r.requestTimeout}
+	}
+}
+
+func (r *resilientConnection) Ping() error {
+	_, err := r.call(true, func() (interface{}, error) {
+		return nil, r.Connection.Ping()
+	})
+	return err
+}
+
+func (r *resilientConnection) Capacity() (garden.Capacity, error) {
+	result, err := r.call(true, func() (interface{}, error) {
+		return r.Connection.Capacity()
+	})
+	if err != nil {
+		return garden.Capacity{}, err
+	}
+
+	return result.(garden.Capacity), nil
+}
+
+func (r *resilientConnection) Create(spec garden.ContainerSpec) (string, error) {
+	result, err := r.call(false, func() (interface{}, error) {
+		return r.Connection.Create(spec)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return result.(string), nil
+}
+
+func (r *resilientConnection) List(properties garden.Properties) ([]string, error) {
+	result, err := r.call(true, func() (interface{}, error) {
+		return r.Connection.List(properties)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]string), nil
+}
+
+func (r *resilientConnection) Destroy(handle string) error {
+	_, err := r.call(false, func() (interface{}, error) {
+		return nil, r.Connection.Destroy(handle)
+	})
+	return err
+}
+
+func (r *resilientConnection) Stop(handle string, kill bool) error {
+	_, err := r.call(false, func() (interface{}, error) {
+		return nil, r.Connection.Stop(handle, kill)
+	})
+	return err
+}
+
+func (r *resilientConnection) Info(handle string) (garden.ContainerInfo, error) {
+	result, err := r.call(true, func() (interface{}, error) {
+		return r.Connection.Info(handle)
+	})
+	if err != nil {
+		return garden.ContainerInfo{}, err
+	}
+
+	return result.(garden.ContainerInfo), nil
+}
+
+func (r *resilientConnection) LimitBandwidth(handle string, limits garden.BandwidthLimits) (garden.BandwidthLimits, error) {
+	result, err := r.call(false, func() (interface{}, error) {
+		return r.Connection.LimitBandwidth(handle, limits)
+	})
+	if err != nil {
+		return garden.BandwidthLimits{}, err
+	}
+
+	return result.(garden.BandwidthLimits), nil
+}
+
+func (r *resilientConnection) LimitCPU(handle string, limits garden.CPULimits) (garden.CPULimits, error) {
+	result, err := r.call(false, func() (interface{}, error) {
+		return r.Connection.LimitCPU(handle, limits)
+	})
+	if err != nil {
+		return garden.CPULimits{}, err
+	}
+
+	return result.(garden.CPULimits), nil
+}
+
+func (r *resilientConnection) LimitDisk(handle string, limits garden.DiskLimits) (garden.DiskLimits, error) {
+	result, err := r.call(false, func() (interface{}, error) {
+		return r.Connection.LimitDisk(handle, limits)
+	})
+	if err != nil {
+		return garden.DiskLimits{}, err
+	}
+
+	return result.(garden.DiskLimits), nil
+}
+
+func (r *resilientConnection) LimitMemory(handle string, limits garden.MemoryLimits) (garden.MemoryLimits, error) {
+	result, err := r.call(false, func() (interface{}, error) {
+		return r.Connection.LimitMemory(handle, limits)
+	})
+	if err != nil {
+		return garden.MemoryLimits{}, err
+	}
+
+	return result.(garden.MemoryLimits), nil
+}
+
+func (r *resilientConnection) CurrentBandwidthLimits(handle string) (garden.BandwidthLimits, error) {
+	result, err := r.call(true, func() (interface{}, error) {
+		return r.Connection.CurrentBandwidthLimits(handle)
+	})
+	if err != nil {
+		return garden.BandwidthLimits{}, err
+	}
+
+	return result.(garden.BandwidthLimits), nil
+}
+
+func (r *resilientConnection) CurrentCPULimits(handle string) (garden.CPULimits, error) {
+	result, err := r.call(true, func() (interface{}, error) {
+		return r.Connection.CurrentCPULimits(handle)
+	})
+	if err != nil {
+		return garden.CPULimits{}, err
+	}
+
+	return result.(garden.CPULimits), nil
+}
+
+func (r *resilientConnection) CurrentDiskLimits(handle string) (garden.DiskLimits, error) {
+	result, err := r.call(true, func() (interface{}, error) {
+		return r.Connection.CurrentDiskLimits(handle)
+	})
+	if err != nil {
+		return garden.DiskLimits{}, err
+	}
+
+	return result.(garden.DiskLimits), nil
+}
+
+func (r *resilientConnection) CurrentMemoryLimits(handle string) (garden.MemoryLimits, error) {
+	result, err := r.call(true, func() (interface{}, error) {
+		return r.Connection.CurrentMemoryLimits(handle)
+	})
+	if err != nil {
+		return garden.MemoryLimits{}, err
+	}
+
+	return result.(garden.MemoryLimits), nil
+}
+
+func (r *resilientConnection) NetIn(handle string, hostPort, containerPort uint32) (uint32, uint32, error) {
+	result, err := r.call(false, func() (interface{}, error) {
+		mappedHostPort, mappedContainerPort, err := r.Connection.NetIn(handle, hostPort, containerPort)
+		return [2]uint32{mappedHostPort, mappedContainerPort}, err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	ports := result.([2]uint32)
+	return ports[0], ports[1], nil
+}
+
+func (r *resilientConnection) NetOut(handle string, rule garden.NetOutRule) error {
+	_, err := r.call(false, func() (interface{}, error) {
+		return nil, r.Connection.NetOut(handle, rule)
+	})
+	return err
+}
+
+func (r *resilientConnection) GetProperty(handle string, name string) (string, error) {
+	result, err := r.call(true, func() (interface{}, error) {
+		return r.Connection.GetProperty(handle, name)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return result.(string), nil
+}
+
+func (r *resilientConnection) SetProperty(handle string, name string, value string) error {
+	_, err := r.call(false, func() (interface{}, error) {
+		return nil, r.Connection.SetProperty(handle, name, value)
+	})
+	return err
+}
+
+func (r *resilientConnection) RemoveProperty(handle string, name string) error {
+	_, err := r.call(false, func() (interface{}, error) {
+		return nil, r.Connection.RemoveProperty(handle, name)
+	})
+	return err
+}
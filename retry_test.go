@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	gconn "github.com/cloudfoundry-incubator/garden/client/connection"
+	"github.com/cloudfoundry-incubator/garden/client/connection/fakes"
+)
+
+func TestRetriesIdempotentRequests(t *testing.T) {
+	fakeConn := new(fakes.FakeConnection)
+
+	calls := 0
+	fakeConn.ListStub = func(garden.Properties) ([]string, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("connection refused")
+		}
+
+		return []string{"a"}, nil
+	}
+
+	handles, err := newResilientConnection(fakeConn, 0, 1).List(nil)
+	if err != nil || len(handles) != 1 {
+		t.Errorf("listed %v (%v) after retrying", handles, err)
+	}
+
+	if fakeConn.ListCallCount() != 2 {
+		t.Errorf("listed %d times, want 2", fakeConn.ListCallCount())
+	}
+}
+
+func TestDoesNotRetryResponses(t *testing.T) {
+	fakeConn := new(fakes.FakeConnection)
+	fakeConn.ListReturns(nil, gconn.Error{StatusCode: 500, Message: "failed"})
+
+	if _, err := newResilientConnection(fakeConn, 0, 3).List(nil); err == nil {
+		t.Error("expected the server's error")
+	}
+
+	if fakeConn.ListCallCount() != 1 {
+		t.Errorf("listed %d times, want 1", fakeConn.ListCallCount())
+	}
+}
+
+func TestDoesNotRetryChanges(t *testing.T) {
+	fakeConn := new(fakes.FakeConnection)
+	fakeConn.CreateReturns("", errors.New("connection refused"))
+
+	if _, err := newResilientConnection(fakeConn, 0, 3).Create(garden.ContainerSpec{}); err == nil {
+		t.Error("expected the connection error")
+	}
+
+	if fakeConn.CreateCallCount() != 1 {
+		t.Errorf("created %d times, want 1", fakeConn.CreateCallCount())
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	fakeConn := new(fakes.FakeConnection)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	fakeConn.PingStub = func() error {
+		<-release
+		return nil
+	}
+
+	err := newResilientConnection(fakeConn, 10*time.Millisecond, 0).Ping()
+	if _, ok := err.(requestTimeoutError); !ok {
+		t.Errorf("pinged with %v, want a timeout", err)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xoebus/gaol/commands"
+)
+
+// Snapshots are kept in ~/.gaol/snapshots, one JSON file per name.
+func snapshotsDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".gaol", "snapshots")
+}
+
+func snapshotPath(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", usageError(fmt.Sprintf("invalid snapshot name %q", name))
+	}
+
+	return filepath.Join(snapshotsDir(), name+".json"), nil
+}
+
+func saveSnapshot(name string, snapshot commands.Snapshot) error {
+	path, err := snapshotPath(name)
+	if err != nil {
+		return err
+	}
+
+	contents, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(snapshotsDir(), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, contents, 0600)
+}
+
+func loadSnapshot(name string) (commands.Snapshot, error) {
+	path, err := snapshotPath(name)
+	if err != nil {
+		return commands.Snapshot{}, err
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return commands.Snapshot{}, fmt.Errorf("no snapshot named %s", name)
+	}
+
+	if err != nil {
+		return commands.Snapshot{}, err
+	}
+
+	var snapshot commands.Snapshot
+	if err := json.Unmarshal(contents, &snapshot); err != nil {
+		return commands.Snapshot{}, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return snapshot, nil
+}
+
+// listSnapshots returns a line for each snapshot, in order of name: its
+// name, when it was taken, its target and how many containers it holds.
+func listSnapshots() ([]string, error) {
+	entries, err := ioutil.ReadDir(snapshotsDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".json")
+		if name == entry.Name() {
+			continue
+		}
+
+		snapshot, err := loadSnapshot(name)
+		if err != nil {
+			return nil, err
+		}
+
+		lines = append(lines, fmt.Sprintf("%s\t%s\t%s\t%d", name, snapshot.Taken.Format(time.RFC3339), snapshot.Target, len(snapshot.Containers)))
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+func TestSnapshotSaveAndDiff(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	rss := uint64(100 << 20)
+
+	web := new(fakes.FakeContainer)
+	web.HandleReturns("web")
+	web.InfoStub = func() (garden.ContainerInfo, error) {
+		return garden.ContainerInfo{State: "active", MemoryStat: garden.ContainerMemoryStat{TotalRss: rss}}, nil
+	}
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.ContainersReturns([]garden.Container{web}, nil)
+
+	res := runGaolIn(t, home, fakeClient, "snapshot", "save", "before")
+	if res.code != 0 || res.stderr != "saved 1 containers as before\n" {
+		t.Fatalf("save exited %d: %q", res.code, res.stderr)
+	}
+
+	rss = 300 << 20
+	runGaolIn(t, home, fakeClient, "snapshot", "save", "after")
+
+	res = runGaolIn(t, home, fakeClient, "snapshot", "list")
+	lines := strings.Split(strings.TrimSpace(res.stdout), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "after\t") || !strings.HasSuffix(lines[1], "\tlocalhost:7777\t1") {
+		t.Errorf("listed %q", res.stdout)
+	}
+
+	want := "changed\tweb\tmemory\t100.0 MiB\t300.0 MiB\t+200.0 MiB\n"
+
+	if res := runGaolIn(t, home, fakeClient, "snapshot", "diff", "before", "after"); res.stdout != want {
+		t.Errorf("diffed %q, want %q", res.stdout, want)
+	}
+
+	// with one snapshot, it is compared with the containers now
+	fakeClient.ContainersReturns(nil, nil)
+	if res := runGaolIn(t, home, fakeClient, "snapshot", "diff", "before"); res.stdout != "destroyed\tweb\n" {
+		t.Errorf("diffed %q with now", res.stdout)
+	}
+
+	if res := runGaolIn(t, home, fakeClient, "snapshot", "diff", "before", "missing"); res.code != 1 || !strings.Contains(res.stderr, "no snapshot named missing") {
+		t.Errorf("diffing a missing snapshot exited %d: %q", res.code, res.stderr)
+	}
+}
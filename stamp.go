@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// requestStamp marks every request one invocation of gaol makes to the
+// server, so that they can be picked out of the server's logs.
+type requestStamp struct {
+	ID        string
+	UserAgent string
+}
+
+func newRequestStamp(command string) *requestStamp {
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	userAgent := "gaol/" + version
+	if command != "" {
+		userAgent += fmt.Sprintf(" (%s)", command)
+	}
+
+	return &requestStamp{
+		ID:        hex.EncodeToString(id),
+		UserAgent: userAgent,
+	}
+}
+
+// stampRequests copies the requests read from local to remote with the
+// stamp's headers set on each. Requests which run or attach to a process
+// turn the connection into the process's stream, after which everything is
+// copied as it is.
+func stampRequests(remote io.Writer, local io.Reader, stamp *requestStamp) error {
+	br := bufio.NewReader(local)
+
+	for {
+		request, err := http.ReadRequest(br)
+		if err != nil {
+			return err
+		}
+
+		request.Header.Set("User-Agent", stamp.UserAgent)
+		request.Header.Set("X-Request-Id", stamp.ID)
+
+		if err := request.Write(remote); err != nil {
+			return err
+		}
+
+		if streamsProcess(request) {
+			_, err := io.Copy(remote, br)
+			return err
+		}
+	}
+}
+
+// streamsProcess reports whether the request is to run or attach to a
+// process, i.e. is to /containers/:handle/processes[/:pid].
+func streamsProcess(request *http.Request) bool {
+	parts := strings.Split(strings.Trim(request.URL.Path, "/"), "/")
+	return len(parts) >= 3 && len(parts) <= 4 && parts[0] == "containers" && parts[2] == "processes"
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestStampRequests(t *testing.T) {
+	stamp := &requestStamp{ID: "abc123", UserAgent: "gaol/0.0.1 (run)"}
+
+	var local bytes.Buffer
+	for _, request := range []*http.Request{
+		newRequest(t, "GET", "/ping", ""),
+		newRequest(t, "PUT", "/containers/web/properties/owner", "ci"),
+		newRequest(t, "POST", "/containers/web/processes", `{"path":"sh"}`),
+	} {
+		request.Write(&local)
+	}
+
+	// once the process is running the connection carries its stream
+	local.WriteString("GET /not/a/request\n")
+
+	var remote bytes.Buffer
+	err := stampRequests(&remote, &local, stamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(&remote)
+	for _, want := range []struct{ path, body string }{
+		{"/ping", ""},
+		{"/containers/web/properties/owner", "ci"},
+		{"/containers/web/processes", `{"path":"sh"}`},
+	} {
+		request, err := http.ReadRequest(br)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, _ := ioutil.ReadAll(request.Body)
+		if request.URL.Path != want.path || string(body) != want.body {
+			t.Errorf("forwarded %s with %q, want %s with %q", request.URL.Path, body, want.path, want.body)
+		}
+
+		if request.Header.Get("X-Request-Id") != "abc123" || request.Header.Get("User-Agent") != "gaol/0.0.1 (run)" {
+			t.Errorf("%s not stamped: %v", request.URL.Path, request.Header)
+		}
+	}
+
+	rest, _ := ioutil.ReadAll(br)
+	if string(rest) != "GET /not/a/request\n" {
+		t.Errorf("forwarded stream %q", rest)
+	}
+}
+
+func newRequest(t *testing.T, method, path, body string) *http.Request {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+
+	request, err := http.NewRequest(method, "http://api"+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return request
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	gconn "github.com/cloudfoundry-incubator/garden/client/connection"
+)
+
+// parseTarget turns a target address into the network and address to dial.
+// Targets may be given as tcp://host:port, unix:///path/to/garden.sock or a
+// bare host:port.
+func parseTarget(target string) (string, string, error) {
+	if !strings.Contains(target, "://") {
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			return "", "", fmt.Errorf("invalid target %q: %s", target, err)
+		}
+
+		return "tcp", target, nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid target %q: %s", target, err)
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		if _, _, err := net.SplitHostPort(u.Host); err != nil {
+			return "", "", fmt.Errorf("invalid target %q: %s", target, err)
+		}
+
+		return "tcp", u.Host, nil
+	case "unix":
+		path := u.Host + u.Path
+		if path == "" {
+			return "", "", fmt.Errorf("invalid target %q: missing socket path", target)
+		}
+
+		return "unix", path, nil
+	default:
+		return "", "", fmt.Errorf("invalid target %q: unknown scheme %q", target, u.Scheme)
+	}
+}
+
+// targetHost returns the host on which ports mapped into containers on the
+// target can be reached.
+func targetHost(target string) (string, error) {
+	network, address, err := parseTarget(target)
+	if err != nil {
+		return "", err
+	}
+
+	if network == "unix" {
+		return "127.0.0.1", nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	return host, err
+}
+
+// defaultConnectTimeout is how long the garden connection waits for the
+// server to accept a connection.
+const defaultConnectTimeout = time.Second
+
+// connectOptions tune how connections to the target are made.
+type connectOptions struct {
+	ConnectTimeout time.Duration
+	RequestTimeout time.Duration
+	Retries        int
+	KeepAlive      time.Duration
+
+	// Log and Trace, if given, receive a record of every call made to the
+	// server as text and as JSON lines respectively.
+	Log   io.Writer
+	Trace io.Writer
+
+	// Stamp, if given, is set on every request made to the server.
+	Stamp *requestStamp
+}
+
+func connect(target targetConfig, opts connectOptions) (gconn.Connection, error) {
+	conn, err := dialTarget(target, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Log != nil && opts.Stamp != nil {
+		fmt.Fprintf(opts.Log, "request id %s, user agent %s\n", opts.Stamp.ID, opts.Stamp.UserAgent)
+	}
+
+	if opts.Log != nil || opts.Trace != nil {
+		conn = newTracingConnection(conn, opts.Log, opts.Trace, opts.Stamp)
+	}
+
+	if opts.RequestTimeout > 0 || opts.Retries > 0 {
+		conn = newResilientConnection(conn, opts.RequestTimeout, opts.Retries)
+	}
+
+	return conn, nil
+}
+
+func dialTarget(target targetConfig, opts connectOptions) (gconn.Connection, error) {
+	network, address, err := parseTarget(target.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ConnectTimeout == 0 {
+		opts.ConnectTimeout = defaultConnectTimeout
+	}
+
+	// the garden connection already dials plain addresses with the default
+	// timeout and no keepalive, but cannot set headers
+	if target.Via == "" && !target.usesTLS() && opts.ConnectTimeout == defaultConnectTimeout && opts.KeepAlive == 0 && opts.Stamp == nil {
+		return gconn.New(network, address), nil
+	}
+
+	dial, err := targetDialer(target, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	forwarder, err := newForwarder(dial, opts.Stamp)
+	if err != nil {
+		return nil, err
+	}
+
+	return gconn.New("unix", forwarder.Address()), nil
+}
+
+// targetDialer returns a function which opens a connection to the target,
+// through its jump host and over TLS if it has them.
+func targetDialer(target targetConfig, opts connectOptions) (func() (net.Conn, error), error) {
+	network, address, err := parseTarget(target.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ConnectTimeout == 0 {
+		opts.ConnectTimeout = defaultConnectTimeout
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   opts.ConnectTimeout,
+		KeepAlive: opts.KeepAlive,
+	}
+
+	dial := func() (net.Conn, error) {
+		return dialer.Dial(network, address)
+	}
+
+	if target.Via != "" {
+		jump, err := newJumpHost(target.Via, opts.ConnectTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		dial = func() (net.Conn, error) {
+			return jump.Dial(network, address)
+		}
+	}
+
+	if target.usesTLS() {
+		config, err := target.tlsConfig(address)
+		if err != nil {
+			return nil, err
+		}
+
+		dialPlain := dial
+		dial = func() (net.Conn, error) {
+			conn, err := dialPlain()
+			if err != nil {
+				return nil, err
+			}
+
+			tlsConn := tls.Client(conn, config)
+			if err := tlsConn.Handshake(); err != nil {
+				conn.Close()
+				return nil, err
+			}
+
+			return tlsConn, nil
+		}
+	}
+
+	return dial, nil
+}
+
+// reachTarget opens and closes a plain connection to the target, through
+// its jump host if it has one.
+func reachTarget(target targetConfig, timeout time.Duration) error {
+	network, address, err := parseTarget(target.Address)
+	if err != nil {
+		return err
+	}
+
+	if timeout == 0 {
+		timeout = defaultConnectTimeout
+	}
+
+	dial := func() (net.Conn, error) {
+		return net.DialTimeout(network, address, timeout)
+	}
+
+	if target.Via != "" {
+		jump, err := newJumpHost(target.Via, timeout)
+		if err != nil {
+			return err
+		}
+
+		dial = func() (net.Conn, error) {
+			return jump.Dial(network, address)
+		}
+	}
+
+	conn, err := dial()
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+func (target targetConfig) usesTLS() bool {
+	return target.CACert != "" || target.ClientCert != "" || target.ClientKey != ""
+}
+
+func (target targetConfig) tlsConfig(address string) (*tls.Config, error) {
+	// resuming sessions spares the full handshake on each of the many
+	// connections a bulk operation makes
+	config := &tls.Config{
+		ClientSessionCache: tls.NewLRUClientSessionCache(0),
+	}
+
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		config.ServerName = host
+	}
+
+	if target.CACert != "" {
+		pem, err := ioutil.ReadFile(target.CACert)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", target.CACert)
+		}
+	}
+
+	if target.ClientCert != "" || target.ClientKey != "" {
+		if target.ClientCert == "" || target.ClientKey == "" {
+			return nil, errors.New("client certificate and key must be given together")
+		}
+
+		cert, err := tls.LoadX509KeyPair(target.ClientCert, target.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		target  string
+		network string
+		address string
+	}{
+		{"localhost:7777", "tcp", "localhost:7777"},
+		{"tcp://10.0.0.1:7777", "tcp", "10.0.0.1:7777"},
+		{"unix:///var/run/garden.sock", "unix", "/var/run/garden.sock"},
+	}
+
+	for _, test := range tests {
+		network, address, err := parseTarget(test.target)
+		if err != nil {
+			t.Errorf("%q: %s", test.target, err)
+			continue
+		}
+
+		if network != test.network || address != test.address {
+			t.Errorf("%q parsed as %s %s, want %s %s", test.target, network, address, test.network, test.address)
+		}
+	}
+
+	for _, invalid := range []string{"localhost", "tcp://localhost", "unix://", "http://localhost:7777"} {
+		if _, _, err := parseTarget(invalid); err == nil {
+			t.Errorf("parsed invalid target %q", invalid)
+		}
+	}
+}
+
+func TestTargetHost(t *testing.T) {
+	tests := map[string]string{
+		"garden.example.com:7777":     "garden.example.com",
+		"tcp://10.0.0.1:7777":         "10.0.0.1",
+		"unix:///var/run/garden.sock": "127.0.0.1",
+	}
+
+	for target, want := range tests {
+		host, err := targetHost(target)
+		if err != nil || host != want {
+			t.Errorf("%q: host %q (%v), want %q", target, host, err, want)
+		}
+	}
+}
+
+func TestParseVia(t *testing.T) {
+	tests := []struct {
+		via     string
+		user    string
+		address string
+	}{
+		{"admin@bastion", "admin", "bastion:22"},
+		{"admin@bastion:2222", "admin", "bastion:2222"},
+	}
+
+	for _, test := range tests {
+		user, address, err := parseVia(test.via)
+		if err != nil {
+			t.Errorf("%q: %s", test.via, err)
+			continue
+		}
+
+		if user != test.user || address != test.address {
+			t.Errorf("%q parsed as %s %s, want %s %s", test.via, user, address, test.user, test.address)
+		}
+	}
+}
+
+func TestDialClientIsShared(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	defer os.Setenv("HOME", originalHome)
+
+	defer func() { dialed = nil }()
+
+	var first, second garden.Client
+
+	app := newApp()
+	app.Commands = append(app.Commands, cli.Command{
+		Name: "dial-twice",
+		Action: func(c *cli.Context) {
+			first = dialClient(c)
+			second = dialClient(c)
+		},
+	})
+
+	if err := app.Run([]string{"gaol", "--target", "127.0.0.1:7777", "dial-twice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if first == nil || first != second {
+		t.Error("expected every command in an invocation to share one client")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// teeWriter writes to stdout and to the file a copy of it is kept in,
+// carrying on with stdout if the file cannot be written.
+type teeWriter struct {
+	stdout  io.Writer
+	file    io.Writer
+	fileErr error
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	if t.fileErr == nil {
+		_, t.fileErr = t.file.Write(p)
+	}
+
+	return t.stdout.Write(p)
+}
+
+// teeStdout copies everything written to stdout into a temporary file
+// beside path, which is renamed to path when gaol exits so that readers of
+// path never see it half-written.
+func teeStdout(path string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	original := os.Stdout
+	os.Stdout = w
+
+	tee := &teeWriter{stdout: original, file: tmp}
+
+	done := make(chan struct{})
+	go func() {
+		io.Copy(tee, r)
+
+		// stdout has gone, but whatever writes to it must not block
+		io.Copy(ioutil.Discard, r)
+		close(done)
+	}()
+
+	atExit(func() {
+		os.Stdout = original
+		w.Close()
+		<-done
+		r.Close()
+
+		err := tee.fileErr
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+
+		if err == nil {
+			err = os.Chmod(tmp.Name(), 0644)
+		}
+
+		if err == nil {
+			err = os.Rename(tmp.Name(), path)
+		}
+
+		if err != nil {
+			os.Remove(tmp.Name())
+			fmt.Fprintln(os.Stderr, "failed to write output file:", err)
+		}
+	})
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xoebus/gaol/commands"
+)
+
+func TestTeeStdout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gaol-tee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "handles.txt")
+	ioutil.WriteFile(path, []byte("previous\n"), 0644)
+
+	var printed string
+	stdout := capture(&os.Stdout, &printed)
+
+	if err := teeStdout(path); err != nil {
+		t.Fatal(err)
+	}
+
+	fmt.Println("web")
+	fmt.Println("db")
+
+	if contents, _ := ioutil.ReadFile(path); string(contents) != "previous\n" {
+		t.Errorf("replaced the file with %q before exiting", contents)
+	}
+
+	commands.RunCleanups()
+	stdout()
+
+	if printed != "web\ndb\n" {
+		t.Errorf("printed %q", printed)
+	}
+
+	if contents, _ := ioutil.ReadFile(path); string(contents) != "web\ndb\n" {
+		t.Errorf("wrote %q", contents)
+	}
+
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("left %d files behind", len(entries))
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/codegangsta/cli"
+
+	"github.com/xoebus/gaol/commands"
+)
+
+// createTemplate is a named set of settings for create --template, kept
+// under templates in the config file so that a team's containers are all
+// created alike. Anything also given on the command line overrides it.
+type createTemplate struct {
+	RootFS     string                   `yaml:"rootfs,omitempty"`
+	Grace      time.Duration            `yaml:"grace,omitempty"`
+	Privileged bool                     `yaml:"privileged,omitempty"`
+	Network    string                   `yaml:"network,omitempty"`
+	Env        []string                 `yaml:"env,omitempty"`
+	Properties map[string]string        `yaml:"properties,omitempty"`
+	Limits     commands.ManifestLimits  `yaml:"limits,omitempty"`
+	BindMounts []commands.ManifestMount `yaml:"bind_mounts,omitempty"`
+}
+
+// loadTemplate finds the named template in the config file.
+func loadTemplate(name string) (createTemplate, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return createTemplate{}, err
+	}
+
+	template, found := cfg.Templates[name]
+	if !found {
+		return createTemplate{}, fmt.Errorf("unknown template %q", name)
+	}
+
+	return template, nil
+}
+
+// applyTemplate gives the spec and limits of a container being created the
+// settings of the template given to --template, other than those given on
+// the command line. Its environment comes before any other, and its
+// properties are added to.
+func applyTemplate(c *cli.Context, spec garden.ContainerSpec, limits commands.ManifestLimits) (garden.ContainerSpec, commands.ManifestLimits) {
+	name := c.String("template")
+	if name == "" {
+		return spec, limits
+	}
+
+	template, err := loadTemplate(name)
+	failIf(err)
+
+	if template.Privileged && !currentTarget(c).allowsPrivileged() {
+		fail(errors.New("template " + name + " is privileged, which is not allowed on this target"))
+	}
+
+	mounts, err := commands.BindMounts(template.BindMounts)
+	if err != nil {
+		fail(fmt.Errorf("template %s: %s", name, err))
+	}
+
+	if !c.IsSet("rootfs") && template.RootFS != "" {
+		spec.RootFSPath = template.RootFS
+	}
+
+	if !c.IsSet("grace") && template.Grace != 0 {
+		spec.GraceTime = template.Grace
+	}
+
+	spec.Privileged = spec.Privileged || template.Privileged
+	spec.Network = template.Network
+	spec.Env = append(append([]string{}, template.Env...), spec.Env...)
+	spec.BindMounts = append(mounts, spec.BindMounts...)
+
+	properties := garden.Properties{}
+	for key, value := range template.Properties {
+		properties[key] = value
+	}
+
+	for key, value := range spec.Properties {
+		properties[key] = value
+	}
+
+	if len(properties) > 0 {
+		spec.Properties = properties
+	}
+
+	for _, limit := range []struct{ given, template *commands.ByteSize }{
+		{&limits.Memory, &template.Limits.Memory},
+		{&limits.Disk, &template.Limits.Disk},
+		{&limits.Bandwidth, &template.Limits.Bandwidth},
+		{&limits.Burst, &template.Limits.Burst},
+	} {
+		if *limit.given == 0 {
+			*limit.given = *limit.template
+		}
+	}
+
+	if limits.CPU == 0 {
+		limits.CPU = template.Limits.CPU
+	}
+
+	return spec, limits
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden/fakes"
+)
+
+const templatesConfig = `templates:
+  golang-ci:
+    rootfs: docker:///golang
+    grace: 1h
+    env: [GOPATH=/go, CGO_ENABLED=0]
+    properties:
+      team: ci
+      purpose: build
+    limits:
+      memory: 2G
+      disk: 10G
+    bind_mounts:
+    - src: /var/cache/go
+      dst: /go/pkg
+      mode: rw
+`
+
+func writeConfig(t *testing.T, home string, contents string) {
+	path := filepath.Join(home, ".gaol", "config.yml")
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateFromTemplate(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	writeConfig(t, home, templatesConfig)
+
+	container := fakeContainer("ci-1")
+
+	fakeClient := new(fakes.FakeClient)
+	fakeClient.CreateReturns(container, nil)
+
+	res := runGaolIn(t, home, fakeClient, "create", "--template", "golang-ci", "--no-stamp", "--memory-limit", "4G", "--property", "purpose=release")
+	if res.code != 0 {
+		t.Fatalf("create exited %d: %s", res.code, res.stderr)
+	}
+
+	spec := fakeClient.CreateArgsForCall(0)
+
+	if spec.RootFSPath != "docker:///golang" || spec.GraceTime != time.Hour {
+		t.Errorf("created from %s with grace %s", spec.RootFSPath, spec.GraceTime)
+	}
+
+	if !reflect.DeepEqual(spec.Env, []string{"GOPATH=/go", "CGO_ENABLED=0"}) {
+		t.Errorf("created with env %v", spec.Env)
+	}
+
+	if !reflect.DeepEqual(spec.Properties, garden.Properties{"team": "ci", "purpose": "release"}) {
+		t.Errorf("created with properties %v, want the command line's to win", spec.Properties)
+	}
+
+	wantMounts := []garden.BindMount{{SrcPath: "/var/cache/go", DstPath: "/go/pkg", Mode: garden.BindMountModeRW, Origin: garden.BindMountOriginHost}}
+	if !reflect.DeepEqual(spec.BindMounts, wantMounts) {
+		t.Errorf("created with bind mounts %v", spec.BindMounts)
+	}
+
+	if limits := container.LimitMemoryArgsForCall(0); limits.LimitInBytes != 4<<30 {
+		t.Errorf("limited memory to %d, want the command line's limit", limits.LimitInBytes)
+	}
+
+	if limits := container.LimitDiskArgsForCall(0); limits.ByteHard != 10<<30 {
+		t.Errorf("limited disk to %d, want the template's limit", limits.ByteHard)
+	}
+
+	res = runGaolIn(t, home, fakeClient, "create", "--template", "golang-ci", "--rootfs", "docker:///golang:1.5", "--no-stamp")
+	if spec := fakeClient.CreateArgsForCall(1); res.code != 0 || spec.RootFSPath != "docker:///golang:1.5" {
+		t.Errorf("created from %s, want the command line's rootfs", spec.RootFSPath)
+	}
+}
+
+func TestCreateFromUnknownTemplate(t *testing.T) {
+	home := tempHome(t)
+	defer os.RemoveAll(home)
+
+	writeConfig(t, home, templatesConfig)
+
+	fakeClient := new(fakes.FakeClient)
+
+	res := runGaolIn(t, home, fakeClient, "create", "--template", "rails")
+	if res.code != exitFailure || fakeClient.CreateCallCount() != 0 {
+		t.Errorf("exited %d: %s", res.code, res.stderr)
+	}
+}
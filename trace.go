@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	gconn "github.com/cloudfoundry-incubator/garden/client/connection"
+)
+
+// tracingConnection logs every call made to the server, along with how long
+// it took and how it turned out. Calls are logged as text to log and as JSON
+// lines to trace; either may be nil.
+type tracingConnection struct {
+	gconn.Connection
+
+	log   io.Writer
+	trace io.Writer
+	stamp *requestStamp
+	mu    sync.Mutex
+}
+
+// traceEntry is a single line of the trace file.
+type traceEntry struct {
+	Time       time.Time     `json:"time"`
+	Call       string        `json:"call"`
+	Args       []interface{} `json:"args"`
+	DurationMS float64       `json:"duration_ms"`
+	Status     int           `json:"status,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	RequestID  string        `json:"request_id,omitempty"`
+}
+
+func newTracingConnection(conn gconn.Connection, log io.Writer, trace io.Writer, stamp *requestStamp) gconn.Connection {
+	return &tracingConnection{
+		Connection: conn,
+		log:        log,
+		trace:      trace,
+		stamp:      stamp,
+	}
+}
+
+func (t *tracingConnection) record(start time.Time, err error, call string, args ...interface{}) {
+	duration := time.Since(start)
+
+	entry := traceEntry{
+		Time:       start,
+		Call:       call,
+		Args:       args,
+		DurationMS: float64(duration) / float64(time.Millisecond),
+		Status:     200,
+	}
+
+	if t.stamp != nil {
+		entry.RequestID = t.stamp.ID
+	}
+
+	outcome := "ok"
+
+	if err != nil {
+		entry.Status = 0
+		entry.Error = err.Error()
+		outcome = "error: " + err.Error()
+
+		if gerr, ok := err.(gconn.Error); ok {
+			entry.Status = gerr.StatusCode
+			outcome = fmt.Sprintf("%d: %s", gerr.StatusCode, gerr.Message)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.log != nil {
+		fmt.Fprintf(t.log, "%s %s (%s)\n", formatCall(call, args...), outcome, duration)
+	}
+
+	if t.trace != nil {
+		json.NewEncoder(t.trace).Encode(entry)
+	}
+}
+
+func (t *tracingConnection) Ping() error {
+	start := time.Now()
+	err := t.Connection.Ping()
+	t.record(start, err, "Ping")
+	return err
+}
+
+func (t *tracingConnection) Capacity() (garden.Capacity, error) {
+	start := time.Now()
+	capacity, err := t.Connection.Capacity()
+	t.record(start, err, "Capacity")
+	return capacity, err
+}
+
+func (t *tracingConnection) Create(spec garden.ContainerSpec) (string, error) {
+	start := time.Now()
+	handle, err := t.Connection.Create(spec)
+	t.record(start, err, "Create", spec)
+	return handle, err
+}
+
+func (t *tracingConnection) List(properties garden.Properties) ([]string, error) {
+	start := time.Now()
+	handles, err := t.Connection.List(properties)
+	t.record(start, err, "List", properties)
+	return handles, err
+}
+
+func (t *tracingConnection) Destroy(handle string) error {
+	start := time.Now()
+	err := t.Connection.Destroy(handle)
+	t.record(start, err, "Destroy", handle)
+	return err
+}
+
+func (t *tracingConnection) Stop(handle string, kill bool) error {
+	start := time.Now()
+	err := t.Connection.Stop(handle, kill)
+	t.record(start, err, "Stop", handle, kill)
+	return err
+}
+
+func (t *tracingConnection) Info(handle string) (garden.ContainerInfo, error) {
+	start := time.Now()
+	info, err := t.Connection.Info(handle)
+	t.record(start, err, "Info", handle)
+	return info, err
+}
+
+func (t *tracingConnection) StreamIn(handle string, dstPath string, reader io.Reader) error {
+	start := time.Now()
+	err := t.Connection.StreamIn(handle, dstPath, reader)
+	t.record(start, err, "StreamIn", handle, dstPath)
+	return err
+}
+
+func (t *tracingConnection) StreamOut(handle string, srcPath string) (io.ReadCloser, error) {
+	start := time.Now()
+	stream, err := t.Connection.StreamOut(handle, srcPath)
+	t.record(start, err, "StreamOut", handle, srcPath)
+	return stream, err
+}
+
+func (t *tracingConnection) LimitBandwidth(handle string, limits garden.BandwidthLimits) (garden.BandwidthLimits, error) {
+	start := time.Now()
+	result, err := t.Connection.LimitBandwidth(handle, limits)
+	t.record(start, err, "LimitBandwidth", handle, limits)
+	return result, err
+}
+
+func (t *tracingConnection) LimitCPU(handle string, limits garden.CPULimits) (garden.CPULimits, error) {
+	start := time.Now()
+	result, err := t.Connection.LimitCPU(handle, limits)
+	t.record(start, err, "LimitCPU", handle, limits)
+	return result, err
+}
+
+func (t *tracingConnection) LimitDisk(handle string, limits garden.DiskLimits) (garden.DiskLimits, error) {
+	start := time.Now()
+	result, err := t.Connection.LimitDisk(handle, limits)
+	t.record(start, err, "LimitDisk", handle, limits)
+	return result, err
+}
+
+func (t *tracingConnection) LimitMemory(handle string, limits garden.MemoryLimits) (garden.MemoryLimits, error) {
+	start := time.Now()
+	result, err := t.Connection.LimitMemory(handle, limits)
+	t.record(start, err, "LimitMemory", handle, limits)
+	return result, err
+}
+
+func (t *tracingConnection) CurrentBandwidthLimits(handle string) (garden.BandwidthLimits, error) {
+	start := time.Now()
+	limits, err := t.Connection.CurrentBandwidthLimits(handle)
+	t.record(start, err, "CurrentBandwidthLimits", handle)
+	return limits, err
+}
+
+func (t *tracingConnection) CurrentCPULimits(handle string) (garden.CPULimits, error) {
+	start := time.Now()
+	limits, err := t.Connection.CurrentCPULimits(handle)
+	t.record(start, err, "CurrentCPULimits", handle)
+	return limits, err
+}
+
+func (t *tracingConnection) CurrentDiskLimits(handle string) (garden.DiskLimits, error) {
+	start := time.Now()
+	limits, err := t.Connection.CurrentDiskLimits(handle)
+	t.record(start, err, "CurrentDiskLimits", handle)
+	return limits, err
+}
+
+func (t *tracingConnection) CurrentMemoryLimits(handle string) (garden.MemoryLimits, error) {
+	start := time.Now()
+	limits, err := t.Connection.CurrentMemoryLimits(handle)
+	t.record(start, err, "CurrentMemoryLimits", handle)
+	return limits, err
+}
+
+func (t *tracingConnection) Run(handle string, spec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+	start := time.Now()
+	process, err := t.Connection.Run(handle, spec, processIO)
+	t.record(start, err, "Run", handle, spec)
+	return process, err
+}
+
+func (t *tracingConnection) Attach(handle string, processID uint32, processIO garden.ProcessIO) (garden.Process, error) {
+	start := time.Now()
+	process, err := t.Connection.Attach(handle, processID, processIO)
+	t.record(start, err, "Attach", handle, processID)
+	return process, err
+}
+
+func (t *tracingConnection) NetIn(handle string, hostPort, containerPort uint32) (uint32, uint32, error) {
+	start := time.Now()
+	mappedHostPort, mappedContainerPort, err := t.Connection.NetIn(handle, hostPort, containerPort)
+	t.record(start, err, "NetIn", handle, hostPort, containerPort)
+	return mappedHostPort, mappedContainerPort, err
+}
+
+func (t *tracingConnection) NetOut(handle string, rule garden.NetOutRule) error {
+	start := time.Now()
+	err := t.Connection.NetOut(handle, rule)
+	t.record(start, err, "NetOut", handle, rule)
+	return err
+}
+
+func (t *tracingConnection) GetProperty(handle string, name string) (string, error) {
+	start := time.Now()
+	value, err := t.Connection.GetProperty(handle, name)
+	t.record(start, err, "GetProperty", handle, name)
+	return value, err
+}
+
+func (t *tracingConnection) SetProperty(handle string, name string, value string) error {
+	start := time.Now()
+	err := t.Connection.SetProperty(handle, name, value)
+	t.record(start, err, "SetProperty", handle, name, value)
+	return err
+}
+
+func (t *tracingConnection) RemoveProperty(handle string, name string) error {
+	start := time.Now()
+	err := t.Connection.RemoveProperty(handle, name)
+	t.record(start, err, "RemoveProperty", handle, name)
+	return err
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+// version is the version of gaol; commit is the commit it was built from,
+// set when building with -ldflags "-X main.commit=<sha>".
+var (
+	version = "0.0.1"
+	commit  = ""
+)
+
+func versionString() string {
+	if commit == "" {
+		return version
+	}
+
+	return fmt.Sprintf("%s (%s)", version, commit)
+}
+
+// probeHandle is the handle of the container which does not exist whose
+// parts of the api the server is asked for.
+const probeHandle = "gaol-version-probe"
+
+// serverAPI is a part of the garden api which servers may or may not have.
+type serverAPI struct {
+	Name   string
+	Method string
+	Path   string
+
+	// UsedBy are the commands which need the api, or nothing if gaol does
+	// not use it yet.
+	UsedBy string
+}
+
+var serverAPIs = []serverAPI{
+	{"info", "GET", "/containers/" + probeHandle + "/info", "info, list, top and the metrics commands"},
+	{"files", "GET", "/containers/" + probeHandle + "/files?source=/", "stream-in and stream-out"},
+	{"attach", "GET", "/containers/" + probeHandle + "/processes/1", "attach and --reconnect"},
+	{"properties", "GET", "/containers/" + probeHandle + "/properties/gaol", "apply, up, down and ps"},
+	{"limits", "GET", "/containers/" + probeHandle + "/limits/memory", "apply and up"},
+	{"net-in", "POST", "/containers/" + probeHandle + "/net/in", "net-in and port-forward"},
+	{"bulk info", "GET", "/containers/bulk_info?handles=", ""},
+	{"bulk metrics", "GET", "/containers/bulk_metrics?handles=", ""},
+}
+
+// apiSupport is whether the server has an api.
+type apiSupport struct {
+	API       serverAPI
+	Supported bool
+}
+
+// probeServer asks the server for each of its apis, only ever with GET so
+// that nothing changes. A route which exists answers with an error about the
+// container rather than the router's own not found or, if it is not a GET
+// route, that the method is not allowed.
+func probeServer(client *http.Client) ([]apiSupport, error) {
+	support := []apiSupport{}
+
+	for _, api := range serverAPIs {
+		response, err := client.Get("http://garden" + api.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		supported := true
+		switch {
+		case response.StatusCode == http.StatusNotFound && string(body) == "404 page not found\n":
+			supported = false
+		case response.StatusCode == http.StatusMethodNotAllowed:
+			// another route with a parameter where this one has a name
+			supported = api.Method != "GET"
+		}
+
+		support = append(support, apiSupport{API: api, Supported: supported})
+	}
+
+	return support, nil
+}
+
+// probeTarget probes the apis of the target over its own connection, as the
+// garden client has no way to make arbitrary requests.
+func probeTarget(target targetConfig, opts connectOptions) ([]apiSupport, error) {
+	dial, err := targetDialer(target, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Dial: func(string, string) (net.Conn, error) {
+				return dial()
+			},
+		},
+		Timeout: 10 * time.Second,
+	}
+
+	return probeServer(client)
+}
+
+// printVersion writes gaol's version and, if the server was probed, which
+// apis it has. Apis which gaol needs and the server lacks are warned about
+// on warnings.
+func printVersion(w io.Writer, warnings io.Writer, target string, support []apiSupport, probeErr error) {
+	fmt.Fprintf(w, "gaol %s\n", versionString())
+
+	if target == "" {
+		return
+	}
+
+	if probeErr != nil {
+		fmt.Fprintf(w, "server %s: unreachable (%s)\n", target, probeErr)
+		return
+	}
+
+	fmt.Fprintf(w, "server %s:\n", target)
+
+	for _, s := range support {
+		answer := "no"
+		if s.Supported {
+			answer = "yes"
+		}
+
+		fmt.Fprintf(w, "  %-14s%s\n", s.API.Name, answer)
+	}
+
+	for _, s := range support {
+		if !s.Supported && s.API.UsedBy != "" {
+			fmt.Fprintf(warnings, "warning: the server has no %s api, which %s need\n", s.API.Name, s.API.UsedBy)
+		}
+	}
+}
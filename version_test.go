@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cloudfoundry-incubator/garden/routes"
+	"github.com/tedsuo/rata"
+)
+
+// gardenRouter routes as a garden server does, answering every request as
+// if the container did not exist. Routes are left out by name.
+func gardenRouter(t *testing.T, without ...string) http.Handler {
+	unknown := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"unknown handle"}`, http.StatusNotFound)
+	})
+
+	left := rata.Routes{}
+	handlers := rata.Handlers{}
+
+	for _, route := range routes.Routes {
+		skip := false
+		for _, name := range without {
+			skip = skip || route.Name == name
+		}
+
+		if !skip {
+			left = append(left, route)
+			handlers[route.Name] = unknown
+		}
+	}
+
+	router, err := rata.NewRouter(left, handlers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return router
+}
+
+func TestProbeServer(t *testing.T) {
+	server := httptest.NewServer(gardenRouter(t, routes.NetIn, routes.GetProperty, routes.SetProperty, routes.RemoveProperty))
+	defer server.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		Dial: func(string, string) (net.Conn, error) {
+			return net.Dial("tcp", server.Listener.Addr().String())
+		},
+	}}
+
+	support, err := probeServer(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	missing := map[string]bool{"net-in": true, "properties": true, "bulk info": true, "bulk metrics": true}
+	for _, s := range support {
+		if s.Supported == missing[s.API.Name] {
+			t.Errorf("%s: supported %t", s.API.Name, s.Supported)
+		}
+	}
+
+	var stdout, warnings bytes.Buffer
+	printVersion(&stdout, &warnings, "localhost:7777", support, nil)
+
+	if !strings.Contains(stdout.String(), "  info          yes\n") || !strings.Contains(stdout.String(), "  bulk info     no\n") {
+		t.Errorf("printed:\n%s", stdout.String())
+	}
+
+	want := "warning: the server has no properties api, which apply, up, down and ps need\n" +
+		"warning: the server has no net-in api, which net-in and port-forward need\n"
+	if warnings.String() != want {
+		t.Errorf("warned:\n%s", warnings.String())
+	}
+}
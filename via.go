@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// defaultIdentities are the keys in ~/.ssh which are offered to the jump
+// host, in addition to any in the SSH agent.
+var defaultIdentities = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// jumpHost dials the target through an SSH connection to a bastion, which
+// is opened the first time it is needed and then shared by every
+// connection.
+type jumpHost struct {
+	address string
+	config  *ssh.ClientConfig
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// parseVia splits user@host[:port] into the user and address of the jump
+// host. The user defaults to $USER and the port to 22.
+func parseVia(via string) (string, string, error) {
+	user := os.Getenv("USER")
+	host := via
+
+	if at := strings.LastIndex(via, "@"); at != -1 {
+		user, host = via[:at], via[at+1:]
+	}
+
+	if host == "" {
+		return "", "", fmt.Errorf("invalid jump host %q", via)
+	}
+
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "22")
+	}
+
+	return user, host, nil
+}
+
+func newJumpHost(via string, timeout time.Duration) (*jumpHost, error) {
+	user, address, err := parseVia(via)
+	if err != nil {
+		return nil, err
+	}
+
+	sshDir := filepath.Join(os.Getenv("HOME"), ".ssh")
+
+	hostKeyCallback, err := knownhosts.New(filepath.Join(sshDir, "known_hosts"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot verify jump host: %s", err)
+	}
+
+	auth := []ssh.AuthMethod{}
+
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+
+	signers := []ssh.Signer{}
+	for _, name := range defaultIdentities {
+		contents, err := ioutil.ReadFile(filepath.Join(sshDir, name))
+		if err != nil {
+			continue
+		}
+
+		// keys with passphrases need to be added to the agent instead
+		signer, err := ssh.ParsePrivateKey(contents)
+		if err != nil {
+			continue
+		}
+
+		signers = append(signers, signer)
+	}
+
+	if len(signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(signers...))
+	}
+
+	if len(auth) == 0 {
+		return nil, errors.New("no SSH agent or keys with which to authenticate to the jump host")
+	}
+
+	return &jumpHost{
+		address: address,
+		config: &ssh.ClientConfig{
+			User:            user,
+			Auth:            auth,
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         timeout,
+		},
+	}, nil
+}
+
+// Dial connects to address (a TCP address or unix socket path) as seen
+// from the jump host.
+func (j *jumpHost) Dial(network, address string) (net.Conn, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.client == nil {
+		client, err := ssh.Dial("tcp", j.address, j.config)
+		if err != nil {
+			return nil, fmt.Errorf("jump host %s: %s", j.address, err)
+		}
+
+		j.client = client
+		atExit(func() { client.Close() })
+	}
+
+	return j.client.Dial(network, address)
+}